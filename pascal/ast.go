@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Visitor обходит узлы AST, как ast.Visitor из go/ast: Walk вызывает Visit для каждого
+// узла и, если результат w не nil, обходит дочерние узлы посетителем w, а затем вызывает
+// w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk обходит AST в глубину, начиная с узла node. Дочерние узлы обходятся в порядке их записи
+// в исходном тексте. Модули из USES представлены узлами UnitRef; сами модули не обходятся.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Программа, модули и описания
+	case *Program:
+		walkUnitRefs(v, n.Uses)
+		walkDeclarations(v, &n.Declarations)
+		walkStatements(v, n.Statements)
+	case *Unit:
+		walkUnitRefs(v, n.Uses)
+		walkDeclarations(v, &n.Interface)
+		walkUnitRefs(v, n.ImplUses)
+		walkDeclarations(v, &n.Implementation)
+		walkStatements(v, n.Statements)
+	case *UnitRef:
+		// модуль не обходится
+	case *ConstDecl:
+		Walk(v, n.Value)
+	case *TypeDecl:
+		Walk(v, n.Type)
+	case *VarDecl:
+		Walk(v, n.Type)
+	case *RoutineDecl:
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+		walkDeclarations(v, &n.Declarations)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *ParamDecl:
+		Walk(v, n.Type)
+
+	// Типы
+	case *NamedType, *EnumType, *PointerType, *ClassType:
+		// нет дочерних узлов
+	case *SubrangeType:
+		Walk(v, n.Low)
+		Walk(v, n.High)
+	case *SetType:
+		Walk(v, n.Elem)
+	case *RecordType:
+		for _, field := range n.Fields {
+			Walk(v, field)
+		}
+	case *FieldDecl:
+		Walk(v, n.Type)
+
+	// Операторы
+	case *Assignment:
+		if n.Target != nil {
+			Walk(v, n.Target)
+		}
+		Walk(v, n.Value)
+	case *Block:
+		walkStatements(v, n.Statements)
+	case *IfStatement:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *WhileStatement:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+	case *RepeatStatement:
+		Walk(v, n.Body)
+		Walk(v, n.Cond)
+	case *ForStatement:
+		Walk(v, n.Start)
+		Walk(v, n.End)
+		Walk(v, n.Body)
+	case *CallStatement:
+		walkExpressions(v, n.Args)
+	case *CaseStatement:
+		Walk(v, n.Expr)
+		for _, branch := range n.Branches {
+			Walk(v, branch)
+		}
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *CaseBranch:
+		for _, label := range n.Labels {
+			Walk(v, label)
+		}
+		Walk(v, n.Body)
+	case *CaseLabel:
+		Walk(v, n.Low)
+		if n.High != nil {
+			Walk(v, n.High)
+		}
+	case *TryStatement:
+		Walk(v, n.Body)
+		for _, handler := range n.Handlers {
+			Walk(v, handler)
+		}
+		if n.Default != nil {
+			Walk(v, n.Default)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+	case *ExceptHandler:
+		Walk(v, n.Body)
+	case *RaiseStatement:
+		if n.Exception != nil {
+			Walk(v, n.Exception)
+		}
+	case *BreakStatement, *ContinueStatement:
+		// нет дочерних узлов
+	case *ExitStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	// Выражения
+	case *Number, *Identifier, *Literal:
+		// нет дочерних узлов
+	case *BinaryOp:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *UnaryOp:
+		Walk(v, n.Operand)
+	case *Dereference:
+		Walk(v, n.Pointer)
+	case *FieldAccess:
+		Walk(v, n.Record)
+	case *SetConstructor:
+		for _, element := range n.Elements {
+			Walk(v, element)
+		}
+	case *SetElement:
+		Walk(v, n.Low)
+		if n.High != nil {
+			Walk(v, n.High)
+		}
+	case *CallExpr:
+		walkExpressions(v, n.Args)
+	case *CreateExpr:
+		walkExpressions(v, n.Args)
+	case *FormatExpr:
+		Walk(v, n.Value)
+		Walk(v, n.Width)
+		if n.Precision != nil {
+			Walk(v, n.Precision)
+		}
+
+	default:
+		panic(fmt.Sprintf("Walk: неизвестный тип узла %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkUnitRefs(v Visitor, refs []*UnitRef) {
+	for _, ref := range refs {
+		Walk(v, ref)
+	}
+}
+
+// walkDeclarations обходит описания в порядке разделов: константы, типы, переменные, подпрограммы
+func walkDeclarations(v Visitor, declarations *Declarations) {
+	for _, decl := range declarations.Consts {
+		Walk(v, decl)
+	}
+	for _, decl := range declarations.Types {
+		Walk(v, decl)
+	}
+	for _, decl := range declarations.Vars {
+		Walk(v, decl)
+	}
+	for _, decl := range declarations.Routines {
+		Walk(v, decl)
+	}
+}
+
+func walkStatements(v Visitor, statements []Statement) {
+	for _, stmt := range statements {
+		Walk(v, stmt)
+	}
+}
+
+func walkExpressions(v Visitor, expressions []Expression) {
+	for _, expr := range expressions {
+		Walk(v, expr)
+	}
+}
+
+// inspector - посетитель для Inspect
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect обходит AST в глубину, как ast.Inspect из go/ast: вызывает f(node) и, если
+// результат истинен, обходит дочерние узлы, а после них вызывает f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// StatementVisitor - посетитель операторов с результатом типа R. В отличие от Visitor,
+// который только обходит дерево, VisitStatement вызывает метод для конкретного типа
+// оператора и возвращает его результат. Проход, который реализует StatementVisitor, не
+// повторяет переключатель по типам операторов, а новый тип оператора требует метода
+// во всех таких проходах.
+type StatementVisitor[R any] interface {
+	VisitAssignment(*Assignment) R
+	VisitBlock(*Block) R
+	VisitIfStatement(*IfStatement) R
+	VisitWhileStatement(*WhileStatement) R
+	VisitRepeatStatement(*RepeatStatement) R
+	VisitForStatement(*ForStatement) R
+	VisitCallStatement(*CallStatement) R
+	VisitCaseStatement(*CaseStatement) R
+	VisitTryStatement(*TryStatement) R
+	VisitRaiseStatement(*RaiseStatement) R
+	VisitBreakStatement(*BreakStatement) R
+	VisitContinueStatement(*ContinueStatement) R
+	VisitExitStatement(*ExitStatement) R
+	// VisitOtherStatement получает оператор типа, описанного вне пакета
+	VisitOtherStatement(Statement) R
+}
+
+// VisitStatement вызывает метод посетителя v для типа оператора stmt
+func VisitStatement[R any](v StatementVisitor[R], stmt Statement) R {
+	switch s := stmt.(type) {
+	case *Assignment:
+		return v.VisitAssignment(s)
+	case *Block:
+		return v.VisitBlock(s)
+	case *IfStatement:
+		return v.VisitIfStatement(s)
+	case *WhileStatement:
+		return v.VisitWhileStatement(s)
+	case *RepeatStatement:
+		return v.VisitRepeatStatement(s)
+	case *ForStatement:
+		return v.VisitForStatement(s)
+	case *CallStatement:
+		return v.VisitCallStatement(s)
+	case *CaseStatement:
+		return v.VisitCaseStatement(s)
+	case *TryStatement:
+		return v.VisitTryStatement(s)
+	case *RaiseStatement:
+		return v.VisitRaiseStatement(s)
+	case *BreakStatement:
+		return v.VisitBreakStatement(s)
+	case *ContinueStatement:
+		return v.VisitContinueStatement(s)
+	case *ExitStatement:
+		return v.VisitExitStatement(s)
+	default:
+		return v.VisitOtherStatement(stmt)
+	}
+}
+
+// ExpressionVisitor - посетитель выражений с результатом типа R, как StatementVisitor
+type ExpressionVisitor[R any] interface {
+	VisitNumber(*Number) R
+	VisitIdentifier(*Identifier) R
+	VisitLiteral(*Literal) R
+	VisitBinaryOp(*BinaryOp) R
+	VisitUnaryOp(*UnaryOp) R
+	VisitDereference(*Dereference) R
+	VisitFieldAccess(*FieldAccess) R
+	VisitSetConstructor(*SetConstructor) R
+	VisitCallExpr(*CallExpr) R
+	VisitCreateExpr(*CreateExpr) R
+	VisitFormatExpr(*FormatExpr) R
+	// VisitOtherExpression получает выражение типа, описанного вне пакета
+	VisitOtherExpression(Expression) R
+}
+
+// VisitExpression вызывает метод посетителя v для типа выражения expr
+func VisitExpression[R any](v ExpressionVisitor[R], expr Expression) R {
+	switch e := expr.(type) {
+	case *Number:
+		return v.VisitNumber(e)
+	case *Identifier:
+		return v.VisitIdentifier(e)
+	case *Literal:
+		return v.VisitLiteral(e)
+	case *BinaryOp:
+		return v.VisitBinaryOp(e)
+	case *UnaryOp:
+		return v.VisitUnaryOp(e)
+	case *Dereference:
+		return v.VisitDereference(e)
+	case *FieldAccess:
+		return v.VisitFieldAccess(e)
+	case *SetConstructor:
+		return v.VisitSetConstructor(e)
+	case *CallExpr:
+		return v.VisitCallExpr(e)
+	case *CreateExpr:
+		return v.VisitCreateExpr(e)
+	case *FormatExpr:
+		return v.VisitFormatExpr(e)
+	default:
+		return v.VisitOtherExpression(expr)
+	}
+}
+
+// nodeLabel возвращает краткую запись узла без дочерних узлов, например "IF", ":=" или "x"
+func nodeLabel(node Node) string {
+	switch n := node.(type) {
+	case *Program:
+		if n.Name == "" {
+			return "PROGRAM"
+		}
+		return "PROGRAM " + n.Name
+	case *Unit:
+		return "UNIT " + n.Name
+	case *UnitRef:
+		return "USES " + n.Name
+	case *ConstDecl:
+		return "CONST " + n.Name
+	case *TypeDecl:
+		return "TYPE " + n.Name
+	case *VarDecl:
+		return "VAR " + n.Name
+	case *RoutineDecl:
+		if n.IsFunction() {
+			return "FUNCTION " + n.Name
+		}
+		return "PROCEDURE " + n.Name
+	case *ParamDecl:
+		if n.ByRef {
+			return "VAR " + n.Name
+		}
+		return n.Name
+	case *NamedType, *EnumType, *PointerType, *ClassType:
+		return n.String()
+	case *SubrangeType:
+		return ".."
+	case *SetType:
+		return "SET OF"
+	case *RecordType:
+		return "RECORD"
+	case *FieldDecl:
+		return n.Name
+	case *Block:
+		return "BEGIN END"
+	case *Assignment:
+		if n.Target != nil {
+			return ":="
+		}
+		return n.Variable + " :="
+	case *CaseBranch:
+		return "ветвь"
+	case *CaseLabel:
+		if n.High != nil {
+			return ".."
+		}
+		return "метка"
+	case *ExceptHandler:
+		if n.Variable == "" {
+			return "ON " + n.Class
+		}
+		return fmt.Sprintf("ON %s: %s", n.Variable, n.Class)
+	case *SetElement:
+		if n.High != nil {
+			return ".."
+		}
+		return "элемент"
+	case *FormatExpr:
+		return "формат"
+	case Statement:
+		return describeStatement(n)
+	case Expression:
+		return strings.TrimSuffix(describeExpression(n), "(…)")
+	default:
+		return n.String()
+	}
+}
+
+// describeStatement возвращает краткую запись оператора для отчета
+func describeStatement(stmt Statement) string {
+	return VisitStatement[string](describer{}, stmt)
+}
+
+// describeExpression возвращает краткую запись выражения для отчета
+func describeExpression(expr Expression) string {
+	return VisitExpression[string](describer{}, expr)
+}
+
+// describer строит краткие записи операторов и выражений для отчетов: заголовок оператора
+// или знак операции без операндов
+type describer struct{}
+
+func (describer) VisitAssignment(s *Assignment) string {
+	if s.Target != nil {
+		return designatorName(s.Target) + " := …"
+	}
+	return s.Variable + " := …"
+}
+
+func (describer) VisitBlock(s *Block) string                       { return s.String() }
+func (describer) VisitIfStatement(*IfStatement) string             { return "IF" }
+func (describer) VisitWhileStatement(*WhileStatement) string       { return "WHILE" }
+func (describer) VisitRepeatStatement(*RepeatStatement) string     { return "REPEAT" }
+func (describer) VisitForStatement(s *ForStatement) string         { return "FOR " + s.Variable }
+func (describer) VisitCallStatement(s *CallStatement) string       { return s.Name }
+func (describer) VisitCaseStatement(*CaseStatement) string         { return "CASE" }
+func (describer) VisitTryStatement(*TryStatement) string           { return "TRY" }
+func (describer) VisitRaiseStatement(*RaiseStatement) string       { return "RAISE" }
+func (describer) VisitBreakStatement(*BreakStatement) string       { return "Break" }
+func (describer) VisitContinueStatement(*ContinueStatement) string { return "Continue" }
+func (describer) VisitExitStatement(*ExitStatement) string         { return "Exit" }
+func (describer) VisitOtherStatement(s Statement) string           { return s.String() }
+
+func (describer) VisitNumber(e *Number) string {
+	if e.IsReal {
+		return fmt.Sprint(e.Value)
+	}
+	return fmt.Sprint(e.IntValue())
+}
+
+func (describer) VisitIdentifier(e *Identifier) string       { return e.Name }
+func (describer) VisitLiteral(e *Literal) string             { return e.Value.String() }
+func (describer) VisitBinaryOp(e *BinaryOp) string           { return operatorSymbol(e.Operator) }
+func (describer) VisitUnaryOp(e *UnaryOp) string             { return operatorSymbol(e.Operator) }
+func (describer) VisitDereference(e *Dereference) string     { return designatorName(e) }
+func (describer) VisitFieldAccess(e *FieldAccess) string     { return designatorName(e) }
+func (describer) VisitSetConstructor(*SetConstructor) string { return "[…]" }
+func (describer) VisitCallExpr(e *CallExpr) string           { return e.Name + "(…)" }
+func (describer) VisitCreateExpr(e *CreateExpr) string       { return e.Class + ".Create(…)" }
+func (describer) VisitFormatExpr(e *FormatExpr) string       { return e.String() }
+func (describer) VisitOtherExpression(e Expression) string   { return e.String() }
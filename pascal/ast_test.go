@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// inspectLabels возвращает записи узлов в порядке обхода Inspect
+func inspectLabels(node Node) []string {
+	var labels []string
+	Inspect(node, func(n Node) bool {
+		if n != nil {
+			labels = append(labels, nodeLabel(n))
+		}
+		return true
+	})
+	return labels
+}
+
+// TestWalkOrder тестирует порядок обхода узлов
+func TestWalkOrder(t *testing.T) {
+	program := parseCode(t, `PROGRAM P;
+CONST N = 2;
+TYPE TRange = 1..N; TPoint = RECORD x: INTEGER END;
+VAR p: TPoint; s: SET OF TRange;
+FUNCTION Twice(VAR k: INTEGER): INTEGER;
+BEGIN
+  Twice := k * 2
+END;
+BEGIN
+  FOR i := 1 TO N DO p.x := Twice(i);
+  s := [1..N];
+  CASE p.x OF
+    2: WriteLn(p.x:4)
+  ELSE Exit
+  END
+END.`)
+	expected := []string{
+		"PROGRAM P", "CONST N", "2", "TYPE TRange", "..", "1", "N", "TYPE TPoint", "RECORD", "x", "INTEGER",
+		"VAR p", "TPoint", "VAR s", "SET OF", "TRange",
+		"FUNCTION Twice", "VAR k", "INTEGER", "INTEGER", "BEGIN END", "Twice :=", "*", "k", "2",
+		"FOR i", "1", "N", ":=", "p.x", "p", "Twice", "i",
+		"s :=", "[…]", "..", "1", "N",
+		"CASE", "p.x", "p", "ветвь", "метка", "2", "WriteLn", "формат", "p.x", "p", "4", "BEGIN END", "Exit",
+	}
+	labels := inspectLabels(program)
+	if strings.Join(labels, " | ") != strings.Join(expected, " | ") {
+		t.Errorf("ожидался порядок обхода:\n%s\nполучено:\n%s", strings.Join(expected, " | "), strings.Join(labels, " | "))
+	}
+}
+
+// TestInspectPrune тестирует пропуск дочерних узлов, когда функция возвращает false
+func TestInspectPrune(t *testing.T) {
+	program := parseCode(t, `PROCEDURE P; BEGIN x := 1 END;
+BEGIN IF y > 0 THEN y := 2 ELSE P END.`)
+	var labels []string
+	Inspect(program, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		labels = append(labels, nodeLabel(n))
+		switch n.(type) {
+		case *RoutineDecl, Expression:
+			return false
+		}
+		return true
+	})
+	expected := "PROGRAM | PROCEDURE P | IF | > | y := | 2 | P"
+	if strings.Join(labels, " | ") != expected {
+		t.Errorf("ожидались узлы %q, получено %q", expected, strings.Join(labels, " | "))
+	}
+}
+
+// depthVisitor проверяет, что каждому узлу соответствует завершающий вызов Visit(nil)
+type depthVisitor struct {
+	depth, maxDepth, nodes int
+}
+
+func (v *depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		v.depth--
+		return nil
+	}
+	v.nodes++
+	v.depth++
+	v.maxDepth = max(v.maxDepth, v.depth)
+	return v
+}
+
+// TestWalkCorpus тестирует обход всех программ из тестов: Walk знает все типы узлов,
+// а вызовы Visit(nil) уравновешивают вызовы Visit для узлов
+func TestWalkCorpus(t *testing.T) {
+	for _, code := range goCorpus(t) {
+		lexer := NewLexer(code)
+		tokens, err := lexer.Tokenize()
+		if err != nil {
+			continue
+		}
+		program, err := NewParser(tokens).Parse()
+		if err != nil {
+			continue
+		}
+		v := &depthVisitor{}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s\nпаника при обходе: %v", code, r)
+				}
+			}()
+			Walk(v, program)
+		}()
+		if v.depth != 0 || v.nodes == 0 {
+			t.Errorf("%s\nнеуравновешенный обход: глубина %d, узлов %d", code, v.depth, v.nodes)
+		}
+	}
+}
+
+// TestWalkTry тестирует обход обработчиков исключений и блока FINALLY
+func TestWalkTry(t *testing.T) {
+	program := parseCode(t, `BEGIN
+  TRY
+    TRY x := 1 FINALLY y := 2 END
+  EXCEPT
+    ON E: EDivByZero DO RAISE;
+    ON EConvertError DO z := 3
+  ELSE z := 4
+  END
+END.`)
+	expected := "PROGRAM | TRY | BEGIN END | TRY | BEGIN END | x := | 1 | BEGIN END | y := | 2 | " +
+		"ON E: EDivByZero | RAISE | ON EConvertError | z := | 3 | BEGIN END | z := | 4"
+	if labels := strings.Join(inspectLabels(program), " | "); labels != expected {
+		t.Errorf("ожидались узлы:\n%s\nполучено:\n%s", expected, labels)
+	}
+}
+
+// TestWalkUnit тестирует обход модуля: модули из USES представлены узлами без дочерних
+func TestWalkUnit(t *testing.T) {
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{"greet.pas": `UNIT Greet;
+INTERFACE
+PROCEDURE Hello;
+IMPLEMENTATION
+PROCEDURE Hello;
+BEGIN WriteLn('hi') END;
+END.`})
+	program, err := parseWithUnits(t, "USES Greet; BEGIN Hello END.", dir)
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if labels := strings.Join(inspectLabels(program), " | "); labels != "PROGRAM | USES Greet | Hello" {
+		t.Errorf("неожиданные узлы программы: %s", labels)
+	}
+	unit := program.Uses[0].Unit
+	expected := "UNIT Greet | PROCEDURE Hello | PROCEDURE Hello | BEGIN END | WriteLn | 'hi'"
+	if labels := strings.Join(inspectLabels(unit), " | "); labels != expected {
+		t.Errorf("ожидались узлы модуля %q, получено %q", expected, labels)
+	}
+}
+
+// TestWriteDOT тестирует вывод AST на языке DOT
+func TestWriteDOT(t *testing.T) {
+	var graph bytes.Buffer
+	if err := writeDOT(&graph, parseCode(t, `BEGIN s := 'a"b\c' + 'd' END.`)); err != nil {
+		t.Fatalf("ошибка записи графа: %v", err)
+	}
+	expected := `digraph AST {
+	ordering=out;
+	node [shape=box, fontname="Helvetica"];
+	n1 [label="PROGRAM"];
+	n2 [label="s :="];
+	n1 -> n2;
+	n3 [label="+"];
+	n2 -> n3;
+	n4 [label="'a\"b\\c'"];
+	n3 -> n4;
+	n5 [label="'d'"];
+	n3 -> n5;
+}
+`
+	if graph.String() != expected {
+		t.Errorf("ожидался граф:\n%s\nполучено:\n%s", expected, graph.String())
+	}
+}
+
+// TestMainDOT тестирует флаг -dot
+func TestMainDOT(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "prog.pas")
+	if err := os.WriteFile(program, []byte("CONST N = 1 + 2;\nBEGIN x := N END."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.pas")
+	output := filepath.Join(dir, "prog.dot")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"-dot", output, program}, 0, "", ""},
+		// Граф строится до семантического анализа: константа не свернута
+		{[]string{"-dot", "-", program}, 0, `digraph AST {
+	ordering=out;
+	node [shape=box, fontname="Helvetica"];
+	n1 [label="PROGRAM"];
+	n2 [label="CONST N"];
+	n1 -> n2;
+	n3 [label="+"];
+	n2 -> n3;
+	n4 [label="1"];
+	n3 -> n4;
+	n5 [label="2"];
+	n3 -> n5;
+	n6 [label="x :="];
+	n1 -> n6;
+	n7 [label="N"];
+	n6 -> n7;
+}
+`, ""},
+		{[]string{"-dot", "-", missing}, 1, "", "ошибка чтения файла: open " + missing + ": no such file or directory\n"},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+
+	graph, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("граф не записан: %v", err)
+	}
+	if !strings.HasPrefix(string(graph), "digraph AST {") || !strings.Contains(string(graph), `[label="CONST N"]`) {
+		t.Errorf("неожиданный граф в файле:\n%s", graph)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// BigIntValue представляет целое значение вне диапазона int64 в режиме длинных целых
+// (Options.BigInt). Значения, которые помещаются в int64, всегда представлены IntegerValue,
+// поэтому длинная арифметика нужна только там, где обычная переполнилась бы.
+// Значение Int не изменяется после создания.
+type BigIntValue struct {
+	Int *big.Int
+}
+
+func (v BigIntValue) Kind() ValueKind { return KindInteger }
+func (v BigIntValue) String() string  { return v.Int.String() }
+
+// normalizeBig возвращает целое значение x: IntegerValue, если x помещается в int64
+func normalizeBig(x *big.Int) Value {
+	if x.IsInt64() {
+		return IntegerValue(x.Int64())
+	}
+	return BigIntValue{Int: x}
+}
+
+// parseBigInteger разбирает десятичную запись целого числа любой длины
+func parseBigInteger(text string) (Value, bool) {
+	x, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, false
+	}
+	return normalizeBig(x), true
+}
+
+// bigOf возвращает целое значение в виде big.Int; результат нельзя изменять
+func bigOf(v Value) *big.Int {
+	switch n := v.(type) {
+	case IntegerValue:
+		return big.NewInt(int64(n))
+	case BigIntValue:
+		return n.Int
+	default:
+		return nil
+	}
+}
+
+// bigOperands возвращает целые операнды в виде big.Int, если хотя бы один из них - BigIntValue
+func bigOperands(left, right Value) (*big.Int, *big.Int, bool) {
+	_, lbig := left.(BigIntValue)
+	_, rbig := right.(BigIntValue)
+	if !lbig && !rbig || left.Kind() != KindInteger || right.Kind() != KindInteger {
+		return nil, nil, false
+	}
+	return bigOf(left), bigOf(right), true
+}
+
+// bigArithmetic вычисляет '+', '-' или '*' над длинными целыми
+func bigArithmetic(e *BinaryOp, l, r *big.Int) (Value, error) {
+	result := new(big.Int)
+	switch e.Operator {
+	case TokenPLUS:
+		result.Add(l, r)
+	case TokenMINUS:
+		result.Sub(l, r)
+	case TokenMULTIPLY:
+		result.Mul(l, r)
+	default:
+		return nil, fmt.Errorf("неизвестный оператор: %v", e.Operator)
+	}
+	return normalizeBig(result), nil
+}
+
+// bigDivision вычисляет DIV и MOD над длинными целыми с теми же знаками, что и для
+// обычных: частное округляется к нулю, остаток имеет знак делимого
+func bigDivision(e *BinaryOp, l, r *big.Int) (Value, error) {
+	if r.Sign() == 0 {
+		return nil, raiseError(divByZeroClass, e.Pos, "деление на ноль")
+	}
+	if e.Operator == TokenDIV {
+		return normalizeBig(new(big.Int).Quo(l, r)), nil
+	}
+	return normalizeBig(new(big.Int).Rem(l, r)), nil
+}
+
+// bigOrdinalError возвращает ошибку диапазона для длинного целого там, где нужен
+// порядковый номер (границы FOR, элемент множества); nil для других значений
+func bigOrdinalError(value Value, pos Position) error {
+	if _, ok := value.(BigIntValue); ok {
+		return raiseError(rangeErrorClass, pos, "значение %s вне диапазона порядковых номеров %d..%d", value, int64(math.MinInt64), int64(math.MaxInt64))
+	}
+	return nil
+}
+
+// bigInRange сообщает, лежит ли целое value в диапазоне low..high, сравнивая их как big.Int;
+// ok ложно, если среди значений нет длинных целых и подходит обычное сравнение
+func bigInRange(value, low, high Value) (in, ok bool) {
+	for _, v := range []Value{value, low, high} {
+		if _, big := v.(BigIntValue); big {
+			ok = true
+		}
+	}
+	if !ok {
+		return false, false
+	}
+	return bigOf(low).Cmp(bigOf(value)) <= 0 && bigOf(value).Cmp(bigOf(high)) <= 0, true
+}
+
+// bigFunctions - варианты стандартных функций для режима длинных целых: целый результат,
+// который не помещается в int64, становится BigIntValue вместо переполнения
+var bigFunctions = map[string]func(args []Value) (Value, error){
+	"abs": promote(builtinAbs, func(x *big.Int) (Value, error) {
+		return normalizeBig(new(big.Int).Abs(x)), nil
+	}),
+	"sqr": promote(builtinSqr, func(x *big.Int) (Value, error) {
+		return normalizeBig(new(big.Int).Mul(x, x)), nil
+	}),
+	"trunc": bigRealToInteger(math.Trunc),
+	"round": bigRealToInteger(math.Round),
+	"odd": promote(builtinOdd, func(x *big.Int) (Value, error) {
+		return BooleanValue(x.Bit(0) == 1), nil
+	}),
+	"ord": promote(builtinOrd, func(x *big.Int) (Value, error) {
+		return normalizeBig(x), nil
+	}),
+	"succ": promote(stepOrdinal(1), func(x *big.Int) (Value, error) {
+		return normalizeBig(new(big.Int).Add(x, big.NewInt(1))), nil
+	}),
+	"pred": promote(stepOrdinal(-1), func(x *big.Int) (Value, error) {
+		return normalizeBig(new(big.Int).Sub(x, big.NewInt(1))), nil
+	}),
+	"chr": promote(builtinChr, func(x *big.Int) (Value, error) {
+		return nil, classifiedErrorf(rangeErrorClass, "код символа %s вне диапазона 0..%d", x, maxSetOrdinal)
+	}),
+}
+
+// promote возвращает вариант стандартной функции f, который вычисляет результат функцией
+// bigF, если аргумент - длинное целое или f сообщила о целочисленном переполнении
+func promote(f func(args []Value) (Value, error), bigF func(x *big.Int) (Value, error)) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if _, ok := args[0].(BigIntValue); !ok {
+			value, err := f(args)
+			if err == nil || classOf(err) != intOverflowClass {
+				return value, err
+			}
+		}
+		return bigF(bigOf(args[0]))
+	}
+}
+
+// bigRealToInteger - Trunc и Round, результат которых может выходить за пределы int64;
+// целый аргумент возвращается без изменений
+func bigRealToInteger(round func(float64) float64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if args[0].Kind() == KindInteger {
+			return args[0], nil
+		}
+		x, err := realArgument(args[0])
+		if err != nil {
+			return nil, err
+		}
+		x = round(x)
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, classifiedErrorf(rangeErrorClass, "значение %g вне диапазона INTEGER", x)
+		}
+		result, _ := big.NewFloat(x).Int(nil)
+		return normalizeBig(result), nil
+	}
+}
+
+// lookupBuiltin находит стандартную функцию по имени; в режиме длинных целых ее вычисляет
+// вариант из bigFunctions, если он есть
+func lookupBuiltin(name string, bigint bool) (builtinFunction, bool) {
+	name = strings.ToLower(name)
+	fn, ok := builtinFunctions[name]
+	if call, found := bigFunctions[name]; ok && found && bigint {
+		fn.call = call
+	}
+	return fn, ok
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runBigInt проверяет и выполняет программу в режиме длинных целых
+func runBigInt(t *testing.T, code string) (*Interpreter, error) {
+	t.Helper()
+	program := parseCode(t, code)
+	checker := NewChecker()
+	checker.BigInt = true
+	if err := checker.Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	interpreter := NewInterpreterWithOptions(Options{BigInt: true})
+	return interpreter, interpreter.Interpret(program)
+}
+
+// TestBigIntArithmetic тестирует целые операции, результат которых не помещается в int64
+func TestBigIntArithmetic(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`FUNCTION Fact(n: INTEGER): INTEGER;
+BEGIN IF n <= 1 THEN Fact := 1 ELSE Fact := n * Fact(n - 1) END;
+BEGIN x := Fact(30) END.`, "{x: 265252859812191058636308480000000}"},
+		{`BEGIN p := 1; FOR i := 1 TO 100 DO p := p * 2 END.`, "{i: 100, p: 1267650600228229401496703205376}"},
+		{`BEGIN x := MAXINT + 1; y := -MAXINT - 2; z := x - 1 END.`, "{x: 9223372036854775808, y: -9223372036854775809, z: 9223372036854775807}"},
+		// DIV округляет к нулю, MOD имеет знак делимого
+		{`BEGIN x := MAXINT * 10; q := x DIV 7; r := x MOD 7; nq := -x DIV 7; nr := -x MOD 7 END.`,
+			"{nq: -13176245766935394010, nr: 0, q: 13176245766935394010, r: 0, x: 92233720368547758070}"},
+		{`BEGIN x := (MAXINT + 1) * 3; q := x DIV 5; r := x MOD 5; nr := -x MOD 5 END.`,
+			"{nr: -4, q: 5534023222112865484, r: 4, x: 27670116110564327424}"},
+		// Результат, который снова помещается в int64, - обычное целое
+		{`BEGIN x := MAXINT * MAXINT DIV MAXINT; y := (MAXINT + 1) - 1 END.`, "{x: 9223372036854775807, y: 9223372036854775807}"},
+		{`BEGIN x := -MAXINT - 1; q := x DIV -1; r := x MOD -1 END.`, "{q: 9223372036854775808, r: 0, x: -9223372036854775808}"},
+		// Сравнения длинных целых между собой, с обычными и с вещественными
+		{`BEGIN x := MAXINT + 1; a := x > MAXINT; b := x = MAXINT + 1; c := x <> x + 1; d := -x < -MAXINT; e := x < 1e19 END.`,
+			"{a: TRUE, b: TRUE, c: TRUE, d: TRUE, e: TRUE, x: 9223372036854775808}"},
+		{`VAR r: REAL; BEGIN r := MAXINT * 4; s := (MAXINT + 1) / 2 END.`, "{r: 3.6893488147419103e+19, s: 4.611686018427388e+18}"},
+	}
+	for _, tt := range tests {
+		interpreter, err := runBigInt(t, tt.code)
+		if err != nil {
+			t.Errorf("%s\nОшибка выполнения: %v", tt.code, err)
+			continue
+		}
+		if result := formatVariables(interpreter.Values()); result != tt.expected {
+			t.Errorf("%s\nожидалось %s, получено %s", tt.code, tt.expected, result)
+		}
+	}
+}
+
+// TestBigIntDisabled тестирует, что без режима длинных целых переполнение остается ошибкой
+func TestBigIntDisabled(t *testing.T) {
+	for _, code := range []string{
+		`BEGIN x := MAXINT; x := x + 1 END.`,
+		`BEGIN x := -MAXINT - 1; x := x DIV -1 END.`,
+		`BEGIN x := MAXINT; x := Sqr(x) END.`,
+	} {
+		_, err := runWithOptions(t, code, Options{})
+		if err == nil || classOf(err) != intOverflowClass {
+			t.Errorf("%s: ожидалось целочисленное переполнение, получено %v", code, err)
+		}
+	}
+}
+
+// TestBigIntFunctions тестирует стандартные функции в режиме длинных целых
+func TestBigIntFunctions(t *testing.T) {
+	interpreter, err := runBigInt(t, `BEGIN
+  x := MAXINT + 1;
+  a := Abs(-MAXINT - 1); b := Abs(-x * x); c := Sqr(MAXINT); d := Sqr(x);
+  e := Succ(MAXINT); f := Pred(-MAXINT - 1); g := Pred(x);
+  h := Odd(x); k := Odd(x + 1); m := Ord(x);
+  n := Trunc(1e20); o := Round(-2.5e19); p := Trunc(x); q := Round(2.5); r := Abs(-2.5)
+END.`)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	expected := "{a: 9223372036854775808, b: 85070591730234615865843651857942052864, " +
+		"c: 85070591730234615847396907784232501249, d: 85070591730234615865843651857942052864, " +
+		"e: 9223372036854775808, f: -9223372036854775809, g: 9223372036854775807, h: FALSE, k: TRUE, " +
+		"m: 9223372036854775808, n: 100000000000000000000, o: -25000000000000000000, p: 9223372036854775808, " +
+		"q: 3, r: 2.5, x: 9223372036854775808}"
+	if result := formatVariables(interpreter.Values()); result != expected {
+		t.Errorf("ожидалось\n%s\nполучено\n%s", expected, result)
+	}
+}
+
+// TestBigIntOrdinals тестирует длинные целые там, где нужен порядковый номер
+func TestBigIntOrdinals(t *testing.T) {
+	tests := []struct {
+		code  string
+		error string
+	}{
+		{`BEGIN FOR i := 1 TO MAXINT + 1 DO x := i END.`, "вне диапазона порядковых номеров"},
+		{`BEGIN x := MAXINT * 2; s := [1, x] END.`, "вне диапазона порядковых номеров"},
+		{`BEGIN c := Chr(MAXINT + 1) END.`, "код символа 9223372036854775808 вне диапазона"},
+		{`TYPE TSmall = 0..10; VAR s: TSmall; x: INTEGER; BEGIN x := MAXINT; s := x * 2 END.`, "нарушение диапазона"},
+	}
+	for _, tt := range tests {
+		_, err := runBigInt(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.error) {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", tt.code, tt.error, err)
+			continue
+		}
+		if classOf(err) != rangeErrorClass {
+			t.Errorf("%s: ожидалось исключение ERangeError, получено %v", tt.code, classOf(err))
+		}
+	}
+
+	interpreter, err := runBigInt(t, `BEGIN x := MAXINT + 1; a := x IN [0..255]; b := x - x IN [0] END.`)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if result := formatVariables(interpreter.Values()); result != "{a: FALSE, b: TRUE, x: 9223372036854775808}" {
+		t.Errorf("неожиданная проверка принадлежности множеству: %s", result)
+	}
+}
+
+// TestBigIntCase тестирует выражение CASE с длинным целым: оно сравнивается с метками
+// точно и, не совпав ни с одной, выбирает ветвь ELSE
+func TestBigIntCase(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`BEGIN CASE MAXINT + 1 OF 0: x := 1 ELSE x := 2 END END.`, "{x: 2}"},
+		{`BEGIN CASE -MAXINT - 2 OF -MAXINT - 1..MAXINT: x := 1 OTHERWISE x := 2 END END.`, "{x: 2}"},
+		{`BEGIN CASE MAXINT * 2 DIV 2 OF 0: x := 1; MAXINT: x := 3 ELSE x := 2 END END.`, "{x: 3}"},
+	}
+	for _, tt := range tests {
+		interpreter, err := runBigInt(t, tt.code)
+		if err != nil {
+			t.Errorf("%s\nОшибка выполнения: %v", tt.code, err)
+			continue
+		}
+		if result := formatVariables(interpreter.Values()); result != tt.expected {
+			t.Errorf("%s\nожидалось %s, получено %s", tt.code, tt.expected, result)
+		}
+	}
+
+	// Без ветви ELSE - обычная ошибка CASE без подходящей метки
+	if _, err := runBigInt(t, `BEGIN CASE MAXINT + 1 OF 0..MAXINT: x := 1 END END.`); err == nil ||
+		!strings.Contains(err.Error(), "значение 9223372036854775808 не соответствует ни одной метке CASE") {
+		t.Errorf("ожидалась ошибка CASE без подходящей метки, получено %v", err)
+	}
+}
+
+// TestBigIntConstants тестирует свертку константных выражений в режиме длинных целых
+func TestBigIntConstants(t *testing.T) {
+	const code = `CONST Big = MAXINT * MAXINT; Half = Big DIV 2; Bits = Sqr(MAXINT + 1);
+BEGIN x := Half; y := Bits END.`
+
+	// Без режима длинных целых константа с переполнением - ошибка
+	if _, err := checkCode(t, code); err == nil || !strings.Contains(err.Error(), "целочисленное переполнение") {
+		t.Errorf("ожидалась ошибка переполнения в константе, получено %v", err)
+	}
+
+	program := parseCode(t, code)
+	checker := NewChecker()
+	checker.BigInt = true
+	if err := checker.Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	if literal, ok := program.Declarations.Consts[0].Value.(*Literal); !ok || literal.Value.String() != "85070591730234615847396907784232501249" {
+		t.Errorf("ожидалась свернутая константа Big, получено %s", program.Declarations.Consts[0].Value)
+	}
+	interpreter := NewInterpreterWithOptions(Options{BigInt: true})
+	if err := interpreter.Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	expected := "{x: 42535295865117307923698453892116250624, y: 85070591730234615865843651857942052864}"
+	if result := formatVariables(interpreter.Values()); result != expected {
+		t.Errorf("ожидалось %s, получено %s", expected, result)
+	}
+}
+
+// TestBigIntLiterals тестирует целые литералы за пределами int64 в программе и во входных данных
+func TestBigIntLiterals(t *testing.T) {
+	const code = `CONST Big = 99999999999999999999; BEGIN x := Big - 99999999999999999998; y := -18446744073709551616 DIV 2 END.`
+
+	// Без режима длинных целых такой литерал - ошибка разбора
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	if _, err := NewParser(tokens).Parse(); err == nil {
+		t.Errorf("ожидалась ошибка разбора литерала без режима длинных целых")
+	}
+
+	parser := NewParser(tokens)
+	parser.BigInt = true
+	program, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	checker := NewChecker()
+	checker.BigInt = true
+	if err := checker.Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	interpreter := NewInterpreterWithOptions(Options{BigInt: true})
+	if err := interpreter.Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if result := formatVariables(interpreter.Values()); result != "{x: 1, y: -9223372036854775808}" {
+		t.Errorf("неожиданные переменные %s", result)
+	}
+
+	if binding, err := parseDefine("n=99999999999999999999", true); err != nil || binding.Value.String() != "Literal(99999999999999999999)" {
+		t.Errorf("-D: получено %v, %v", binding.Value, err)
+	}
+	if _, err := parseDefine("n=99999999999999999999", false); err == nil {
+		t.Errorf("-D: ожидалась ошибка без режима длинных целых")
+	}
+	if bindings, err := parseBindingsJSON([]byte(`{"n": -18446744073709551616}`), true); err != nil || bindings[0].Value.String() != "Literal(-18446744073709551616)" {
+		t.Errorf("-input: получено %v, %v", bindings, err)
+	}
+	if _, err := parseBindingsJSON([]byte(`{"n": -18446744073709551616}`), false); err == nil {
+		t.Errorf("-input: ожидалась ошибка без режима длинных целых")
+	}
+}
+
+// TestBigIntWrite тестирует вывод длинных целых процедурой Write с шириной поля
+func TestBigIntWrite(t *testing.T) {
+	var output bytes.Buffer
+	program := parseCode(t, `BEGIN WriteLn(MAXINT + 1:22, '|', -MAXINT * 2:5) END.`)
+	if err := NewInterpreterWithOptions(Options{Stdout: &output, BigInt: true}).Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if output.String() != "   9223372036854775808|-18446744073709551614\n" {
+		t.Errorf("неожиданный вывод %q", output.String())
+	}
+}
+
+// TestMainBigInt тестирует флаг -bigint
+func TestMainBigInt(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "power.pas")
+	if err := os.WriteFile(program, []byte("BEGIN\n  p := 1;\n  FOR i := 1 TO 70 DO p := p * 2\nEND."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"-bigint", program}, 0, "{i: 70, p: 1180591620717411303424}\n", ""},
+		{[]string{program}, 1, "", "ошибка выполнения: строка 3, столбец 30: целочисленное переполнение (EIntOverflow)\n"},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
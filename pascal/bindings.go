@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Binding задает начальное значение глобальной переменной программы (Options.Bindings).
+// Значение вычисляется после раздела описаний программы, поэтому в нем доступны ее константы
+// и значения перечислений, и присваивается, как оператором присваивания: описанная переменная
+// сохраняет свой тип, а неописанная создается.
+type Binding struct {
+	Name  string
+	Value Expression
+}
+
+// bind присваивает глобальным переменным начальные значения из Options.Bindings
+func (i *Interpreter) bind() error {
+	// Значения выражений не входят в программу и не нужны продолжению со снимка
+	defer func(mark int) { i.memo = i.memo[:mark] }(len(i.memo))
+	for _, binding := range i.bindings {
+		value, err := i.evaluateExpression(binding.Value)
+		if err == nil {
+			err = i.assign(binding.Name, Position{}, value, true)
+		}
+		if err != nil {
+			return fmt.Errorf("начальное значение %s: %v", binding.Name, err)
+		}
+	}
+	return nil
+}
+
+// Evaluate вычисляет выражение в области видимости программы, например после Interpret,
+// чтобы получить значение из итогового состояния переменных
+func (i *Interpreter) Evaluate(expr Expression) (Value, error) {
+	return i.evaluateExpression(expr)
+}
+
+// parseDefine разбирает значение флага -D вида имя=значение, где значение - выражение Pascal
+func parseDefine(define string, bigint bool) (Binding, error) {
+	name, code, ok := strings.Cut(define, "=")
+	name = strings.TrimSpace(name)
+	if !ok {
+		return Binding{}, fmt.Errorf("-D %s: ожидалось имя=значение", define)
+	}
+	if !isIdentifier(name) {
+		return Binding{}, fmt.Errorf("-D %s: некорректное имя переменной %q", define, name)
+	}
+	value, err := ParseExpression(code, bigint)
+	if err != nil {
+		return Binding{}, fmt.Errorf("-D %s: %v", define, err)
+	}
+	return Binding{Name: name, Value: value}, nil
+}
+
+// isIdentifier сообщает, является ли строка идентификатором Pascal
+func isIdentifier(name string) bool {
+	tokens, err := NewLexer(name).Tokenize()
+	return err == nil && len(tokens) == 2 && tokens[0].Type == TokenIDENTIFIER && tokens[0].Value == name
+}
+
+// parseBindingsJSON разбирает объект JSON вида {"имя": значение, ...} с числами, строками и
+// логическими значениями. Целое число JSON становится INTEGER, дробное - REAL, строка из
+// одного символа - CHAR, как литерал в программе. Привязки упорядочены по имени.
+func parseBindingsJSON(data []byte, bigint bool) ([]Binding, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("некорректный JSON: %v", err)
+	}
+	if values == nil {
+		return nil, fmt.Errorf("ожидался объект JSON с значениями переменных")
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bindings := make([]Binding, len(names))
+	for n, name := range names {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("некорректное имя переменной %q", name)
+		}
+		value, err := jsonValue(values[name], bigint)
+		if err != nil {
+			return nil, fmt.Errorf("значение %s: %v", name, err)
+		}
+		bindings[n] = Binding{Name: name, Value: &Literal{Value: value}}
+	}
+	return bindings, nil
+}
+
+// jsonValue преобразует значение JSON в значение Pascal; в режиме длинных целых bigint
+// целое число может быть вне диапазона int64
+func jsonValue(v interface{}, bigint bool) (Value, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			n, err := strconv.ParseInt(v.String(), 10, 64)
+			if err != nil && bigint {
+				if big, ok := parseBigInteger(v.String()); ok {
+					return big, nil
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("целое число %s вне допустимого диапазона", v)
+			}
+			return IntegerValue(n), nil
+		}
+		x, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("некорректное вещественное число %s", v)
+		}
+		return RealValue(x), nil
+	case string:
+		if utf8.RuneCountInString(v) == 1 {
+			r, _ := utf8.DecodeRuneInString(v)
+			return CharValue(r), nil
+		}
+		return StringValue(v), nil
+	case bool:
+		return BooleanValue(v), nil
+	default:
+		return nil, fmt.Errorf("ожидалось число, строка или логическое значение")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// defines разбирает значения флагов -D
+func defines(t *testing.T, values ...string) []Binding {
+	t.Helper()
+	bindings := make([]Binding, len(values))
+	for n, value := range values {
+		binding, err := parseDefine(value, false)
+		if err != nil {
+			t.Fatalf("Ошибка разбора %s: %v", value, err)
+		}
+		bindings[n] = binding
+	}
+	return bindings
+}
+
+// bindingsProgram - программа, параметры которой задаются начальными значениями переменных
+const bindingsProgram = `TYPE TColor = (Red, Green, Blue); TDigit = 0..9;
+VAR n: INTEGER; r: REAL; c: TColor; d: TDigit; s: STRING; ch: CHAR;
+BEGIN
+  square := n * n;
+  half := r / 2
+END.`
+
+// TestBindings тестирует начальные значения переменных
+func TestBindings(t *testing.T) {
+	tests := []struct {
+		defines  []string
+		expected string
+	}{
+		{nil, "{c: Red, ch: #0, d: 0, half: 0, n: 0, r: 0, s: '', square: 0}"},
+		// Описанная переменная сохраняет свой тип: целое значение переменной REAL становится вещественным
+		{[]string{"n=7", "r=3", "c=Blue", "d=9", "s='abc'", "ch='x'"}, "{c: Blue, ch: 'x', d: 9, half: 1.5, n: 7, r: 3, s: 'abc', square: 49}"},
+		// Значение - выражение в области видимости программы; последнее значение заменяет предыдущие
+		{[]string{"n=MAXINT DIV 2", "n = 2 + 3", "c=Succ(Red)", "s='x'", "extra=TRUE"}, "{c: Green, ch: #0, d: 0, extra: TRUE, half: 0, n: 5, r: 0, s: 'x', square: 25}"},
+	}
+	for _, tt := range tests {
+		interpreter, err := runWithOptions(t, bindingsProgram, Options{Bindings: defines(t, tt.defines...)})
+		if err != nil {
+			t.Errorf("%v: Ошибка выполнения: %v", tt.defines, err)
+			continue
+		}
+		if result := formatVariables(interpreter.Values()); result != tt.expected {
+			t.Errorf("%v: ожидалось %s, получено %s", tt.defines, tt.expected, result)
+		}
+	}
+}
+
+// TestBindingErrors тестирует ошибки начальных значений переменных
+func TestBindingErrors(t *testing.T) {
+	tests := []struct {
+		define string
+		error  string
+	}{
+		{"c=3", "начальное значение c: несовместимые типы: нельзя присвоить INTEGER переменной типа TColor"},
+		{"d=10", "начальное значение d: нарушение диапазона: значение 10 переменной d вне диапазона 0..9"},
+		{"TColor=1", "начальное значение TColor: TColor - имя типа, а не переменной"},
+		{"Red=1", "начальное значение Red: присваивание константе Red"},
+		{"MAXINT=1", "начальное значение MAXINT: присваивание константе MAXINT"},
+		{"n=1 DIV 0", "начальное значение n: деление на ноль"},
+	}
+	for _, tt := range tests {
+		_, err := runWithOptions(t, bindingsProgram, Options{Bindings: defines(t, tt.define)})
+		if err == nil || err.Error() != tt.error {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", tt.define, tt.error, err)
+		}
+	}
+
+	for define, expected := range map[string]string{
+		"n":       "-D n: ожидалось имя=значение",
+		"=1":      `-D =1: некорректное имя переменной ""`,
+		"1n=1":    `-D 1n=1: некорректное имя переменной "1n"`,
+		"begin=1": `-D begin=1: некорректное имя переменной "begin"`,
+		"n=1 2":   "-D n=1 2: лишний текст после выражения на позиции 2",
+	} {
+		if _, err := parseDefine(define, false); err == nil || err.Error() != expected {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", define, expected, err)
+		}
+	}
+}
+
+// TestBindingsJSON тестирует начальные значения переменных из JSON
+func TestBindingsJSON(t *testing.T) {
+	bindings, err := parseBindingsJSON([]byte(`{"s": "abc", "n": 9007199254740993, "r": 2.5, "ch": "ж", "b": false}`), false)
+	if err != nil {
+		t.Fatalf("Ошибка разбора JSON: %v", err)
+	}
+	var values []string
+	for _, binding := range bindings {
+		values = append(values, binding.Name+"="+binding.Value.(*Literal).Value.String())
+	}
+	// Целое число JSON не теряет точность, строка из одного символа - CHAR
+	if strings.Join(values, " ") != "b=FALSE ch='ж' n=9007199254740993 r=2.5 s='abc'" {
+		t.Errorf("неожиданные значения: %v", values)
+	}
+	if _, ok := bindings[1].Value.(*Literal).Value.(CharValue); !ok {
+		t.Errorf("ожидалось значение CHAR, получено %T", bindings[1].Value.(*Literal).Value)
+	}
+
+	for data, expected := range map[string]string{
+		`[1, 2]`:                      "некорректный JSON: json: cannot unmarshal array into Go value of type map[string]interface {}",
+		`null`:                        "ожидался объект JSON с значениями переменных",
+		`{"n": [1]}`:                  "значение n: ожидалось число, строка или логическое значение",
+		`{"n": null}`:                 "значение n: ожидалось число, строка или логическое значение",
+		`{"n": 99999999999999999999}`: "значение n: целое число 99999999999999999999 вне допустимого диапазона",
+		`{"my var": 1}`:               `некорректное имя переменной "my var"`,
+	} {
+		if _, err := parseBindingsJSON([]byte(data), false); err == nil || err.Error() != expected {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", data, expected, err)
+		}
+	}
+}
+
+// TestEvaluate тестирует вычисление выражения в итоговом состоянии программы
+func TestEvaluate(t *testing.T) {
+	interpreter, err := runWithOptions(t, `FUNCTION Twice(k: INTEGER): INTEGER; BEGIN Twice := 2 * k END;
+BEGIN x := 20; y := 'ok' END.`, Options{})
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	for code, expected := range map[string]string{
+		"x":            "20",
+		"Twice(x) + 2": "42",
+		"y + '!'":      "'ok!'",
+		"x > 10":       "TRUE",
+		"undefined":    "0",
+	} {
+		expr, err := ParseExpression(code, false)
+		if err != nil {
+			t.Errorf("%s: Ошибка синтаксического анализа: %v", code, err)
+			continue
+		}
+		value, err := interpreter.Evaluate(expr)
+		if err != nil || value.String() != expected {
+			t.Errorf("%s: ожидалось %s, получено %v (ошибка %v)", code, expected, value, err)
+		}
+	}
+}
+
+// TestMainBindings тестирует флаги -D, -input и -e
+func TestMainBindings(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "sum.pas")
+	if err := os.WriteFile(program, []byte("VAR a, b: INTEGER;\nBEGIN\n  sum := a + b;\n  WriteLn(greeting)\nEND."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "vars.json")
+	if err := os.WriteFile(input, []byte(`{"a": 1, "b": 2, "greeting": "hi"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"a": }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{program}, 0, "0\n{a: 0, b: 0, sum: 0}\n", ""},
+		{[]string{"-D", "a=40", "-D", "b=a + 2", program}, 0, "0\n{a: 40, b: 42, sum: 82}\n", ""},
+		{[]string{"-input", input, program}, 0, "hi\n{a: 1, b: 2, greeting: 'hi', sum: 3}\n", ""},
+		// -D заменяет значение из файла; -e выводит значения выражений вместо словаря
+		{[]string{"-input", input, "-D", "b=10", "-e", "sum", "-e", "sum * 2 > 20", program}, 0, "hi\n11\nTRUE\n", ""},
+		{[]string{"-D", "a", program}, 1, "", "-D a: ожидалось имя=значение\n"},
+		{[]string{"-e", "sum 1", program}, 1, "", "-e sum 1: лишний текст после выражения на позиции 4\n"},
+		{[]string{"-e", "sum DIV a", program}, 1, "0\n", "-e sum DIV a: строка 1, столбец 5: деление на ноль (EDivByZero)\n"},
+		{[]string{"-input", broken, program}, 1, "", broken + ": некорректный JSON: invalid character '}' looking for beginning of value\n"},
+		{[]string{"-D", "a='x'", program}, 1, "", "ошибка выполнения: начальное значение a: несовместимые типы: нельзя присвоить CHAR переменной типа INTEGER\n"},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
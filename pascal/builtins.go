@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// builtinFunction описывает встроенную функцию стандартной библиотеки.
+// result вычисляет тип результата по типам аргументов для статической проверки;
+// nil среди типов аргументов означает, что тип неизвестен до выполнения.
+type builtinFunction struct {
+	arity  int
+	call   func(args []Value) (Value, error)
+	result func(args []*Type) (*Type, error)
+}
+
+// builtinFunctions содержит стандартные функции ISO Pascal, ключ - имя в нижнем регистре
+var builtinFunctions = map[string]builtinFunction{
+	"abs":    {1, builtinAbs, sameNumericResult},
+	"sqr":    {1, builtinSqr, sameNumericResult},
+	"sqrt":   {1, builtinSqrt, numericResult(realType)},
+	"sin":    {1, realFunction(math.Sin), numericResult(realType)},
+	"cos":    {1, realFunction(math.Cos), numericResult(realType)},
+	"arctan": {1, realFunction(math.Atan), numericResult(realType)},
+	"exp":    {1, builtinExp, numericResult(realType)},
+	"ln":     {1, builtinLn, numericResult(realType)},
+	"trunc":  {1, builtinTrunc, numericResult(integerType)},
+	"round":  {1, builtinRound, numericResult(integerType)},
+	"odd":    {1, builtinOdd, oddResult},
+	"ord":    {1, builtinOrd, ordinalResult(integerType)},
+	"succ":   {1, stepOrdinal(1), ordinalResult(nil)},
+	"pred":   {1, stepOrdinal(-1), ordinalResult(nil)},
+	"chr":    {1, builtinChr, chrResult},
+	"length": {1, builtinLength, lengthResult},
+}
+
+// isNumeric сообщает, является ли тип числовым; неизвестный тип считается допустимым
+func isNumeric(t *Type) bool {
+	return t == nil || t.Kind == TypeInteger || t.Kind == TypeReal
+}
+
+// sameNumericResult - результат того же типа, что и числовой аргумент
+func sameNumericResult(args []*Type) (*Type, error) {
+	if !isNumeric(args[0]) {
+		return nil, fmt.Errorf("ожидался числовой аргумент, получен %s", args[0])
+	}
+	return args[0], nil
+}
+
+// numericResult - числовой аргумент и результат фиксированного типа
+func numericResult(result *Type) func(args []*Type) (*Type, error) {
+	return func(args []*Type) (*Type, error) {
+		if !isNumeric(args[0]) {
+			return nil, fmt.Errorf("ожидался числовой аргумент, получен %s", args[0])
+		}
+		return result, nil
+	}
+}
+
+func oddResult(args []*Type) (*Type, error) {
+	if args[0] != nil && args[0].Kind != TypeInteger {
+		return nil, fmt.Errorf("ожидался целый аргумент, получен %s", args[0])
+	}
+	return booleanType, nil
+}
+
+// ordinalResult - аргумент порядкового типа и результат типа result (nil - тот же тип, что и аргумент)
+func ordinalResult(result *Type) func(args []*Type) (*Type, error) {
+	return func(args []*Type) (*Type, error) {
+		if !isOrdinal(args[0]) {
+			return nil, fmt.Errorf("ожидался аргумент порядкового типа, получен %s", args[0])
+		}
+		if result == nil {
+			return baseType(args[0]), nil
+		}
+		return result, nil
+	}
+}
+
+func chrResult(args []*Type) (*Type, error) {
+	if args[0] != nil && args[0].Kind != TypeInteger {
+		return nil, fmt.Errorf("ожидался целый аргумент, получен %s", args[0])
+	}
+	return charType, nil
+}
+
+func lengthResult(args []*Type) (*Type, error) {
+	if args[0] != nil && !isText(args[0]) {
+		return nil, fmt.Errorf("ожидалась строка, получен %s", args[0])
+	}
+	return integerType, nil
+}
+
+// builtinAbs возвращает модуль; тип результата совпадает с типом аргумента
+func builtinAbs(args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case IntegerValue:
+		if v == math.MinInt64 {
+			return nil, classifiedErrorf(intOverflowClass, "целочисленное переполнение")
+		}
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	case RealValue:
+		return RealValue(math.Abs(float64(v))), nil
+	default:
+		return nil, fmt.Errorf("ожидался числовой аргумент, получен %s", args[0].Kind())
+	}
+}
+
+// builtinSqr возвращает квадрат; тип результата совпадает с типом аргумента
+func builtinSqr(args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case IntegerValue:
+		result, ok := mulInt(int64(v), int64(v))
+		if !ok {
+			return nil, classifiedErrorf(intOverflowClass, "целочисленное переполнение")
+		}
+		return IntegerValue(result), nil
+	case RealValue:
+		return checkReal(float64(v) * float64(v))
+	default:
+		return nil, fmt.Errorf("ожидался числовой аргумент, получен %s", args[0].Kind())
+	}
+}
+
+func builtinSqrt(args []Value) (Value, error) {
+	x, err := realArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if x < 0 {
+		return nil, classifiedErrorf(invalidOpClass, "корень из отрицательного числа %g", x)
+	}
+	return RealValue(math.Sqrt(x)), nil
+}
+
+func builtinExp(args []Value) (Value, error) {
+	x, err := realArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return checkReal(math.Exp(x))
+}
+
+func builtinLn(args []Value) (Value, error) {
+	x, err := realArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if x <= 0 {
+		return nil, classifiedErrorf(invalidOpClass, "логарифм неположительного числа %g", x)
+	}
+	return RealValue(math.Log(x)), nil
+}
+
+func builtinTrunc(args []Value) (Value, error) {
+	return realToInteger(args[0], math.Trunc)
+}
+
+// builtinRound округляет половины от нуля, как требует стандарт
+func builtinRound(args []Value) (Value, error) {
+	return realToInteger(args[0], math.Round)
+}
+
+func builtinOdd(args []Value) (Value, error) {
+	v, ok := args[0].(IntegerValue)
+	if !ok {
+		return nil, fmt.Errorf("ожидался целый аргумент, получен %s", args[0].Kind())
+	}
+	return BooleanValue(v%2 != 0), nil
+}
+
+func builtinOrd(args []Value) (Value, error) {
+	ordinal, ok := ordinalOf(args[0])
+	if !ok {
+		return nil, fmt.Errorf("ожидался аргумент порядкового типа, получен %s", args[0].Kind())
+	}
+	return IntegerValue(ordinal), nil
+}
+
+// stepOrdinal возвращает Succ (delta = 1) или Pred (delta = -1): значение того же типа,
+// порядковый номер которого отличается на delta
+func stepOrdinal(delta int64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		ordinal, ok := ordinalOf(args[0])
+		if !ok {
+			return nil, fmt.Errorf("ожидался аргумент порядкового типа, получен %s", args[0].Kind())
+		}
+		t := typeOfValue(args[0])
+		low, high := ordinalBounds(t)
+		if (delta > 0 && ordinal == high) || (delta < 0 && ordinal == low) {
+			if t.Kind == TypeInteger {
+				return nil, classifiedErrorf(intOverflowClass, "целочисленное переполнение")
+			}
+			if delta > 0 {
+				return nil, classifiedErrorf(rangeErrorClass, "у значения %s нет следующего", args[0])
+			}
+			return nil, classifiedErrorf(rangeErrorClass, "у значения %s нет предыдущего", args[0])
+		}
+		return ordinalValue(t, ordinal+delta), nil
+	}
+}
+
+// realFunction оборачивает вещественную функцию одного аргумента
+func realFunction(f func(float64) float64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		x, err := realArgument(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return checkReal(f(x))
+	}
+}
+
+// realArgument приводит числовой аргумент к вещественному типу
+func realArgument(v Value) (float64, error) {
+	x, ok := toReal(v)
+	if !ok {
+		return 0, fmt.Errorf("ожидался числовой аргумент, получен %s", v.Kind())
+	}
+	return x, nil
+}
+
+// realToInteger преобразует вещественный аргумент в целое функцией round
+func realToInteger(v Value, round func(float64) float64) (Value, error) {
+	x, err := realArgument(v)
+	if err != nil {
+		return nil, err
+	}
+	x = round(x)
+	if math.IsNaN(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return nil, classifiedErrorf(rangeErrorClass, "значение %g вне диапазона INTEGER", x)
+	}
+	return IntegerValue(int64(x)), nil
+}
+
+// checkReal не допускает попадания бесконечности и NaN в переменные
+func checkReal(x float64) (Value, error) {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return nil, classifiedErrorf(overflowClass, "вещественное переполнение")
+	}
+	return RealValue(x), nil
+}
+
+// builtinChr возвращает символ с заданным кодом
+func builtinChr(args []Value) (Value, error) {
+	n, ok := args[0].(IntegerValue)
+	if !ok {
+		return nil, fmt.Errorf("ожидался целый аргумент, получен %s", args[0].Kind())
+	}
+	if n < 0 || n > maxSetOrdinal {
+		return nil, classifiedErrorf(rangeErrorClass, "код символа %d вне диапазона 0..%d", n, maxSetOrdinal)
+	}
+	return CharValue(n), nil
+}
+
+// builtinLength возвращает число символов строки
+func builtinLength(args []Value) (Value, error) {
+	text, ok := textOf(args[0])
+	if !ok {
+		return nil, fmt.Errorf("ожидалась строка, получен %s", args[0].Kind())
+	}
+	return IntegerValue(utf8.RuneCountInString(text)), nil
+}
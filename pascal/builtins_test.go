@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// interpretCode выполняет полный цикл лексер -> парсер -> интерпретатор
+func interpretCode(t *testing.T, code string) (*Interpreter, error) {
+	t.Helper()
+	lexer := NewLexer(code)
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	parser := NewParser(tokens)
+	program, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	interpreter := NewInterpreter()
+	return interpreter, interpreter.Interpret(program)
+}
+
+// mustInterpret выполняет программу и возвращает значения переменных
+func mustInterpret(t *testing.T, code string) map[string]Value {
+	t.Helper()
+	interpreter, err := interpretCode(t, code)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	return interpreter.Values()
+}
+
+// TestBuiltinResultTypes тестирует значения и типы результатов стандартных функций
+func TestBuiltinResultTypes(t *testing.T) {
+	values := mustInterpret(t, `BEGIN
+	a := Abs(-7);
+	b := Abs(-2.5);
+	c := Sqr(9);
+	d := Sqr(1.5);
+	e := Sqrt(16);
+	f := Trunc(-3.7);
+	g := Round(2.5);
+	h := Round(-2.5);
+	i := Odd(7);
+	j := Odd(10);
+	k := Exp(0);
+	l := Ln(1);
+	m := Sin(0) + Cos(0);
+	n := ArcTan(0);
+	o := Trunc(7)
+END.`)
+
+	tests := []struct {
+		name string
+		want Value
+	}{
+		{"a", IntegerValue(7)},
+		{"b", RealValue(2.5)},
+		{"c", IntegerValue(81)},
+		{"d", RealValue(2.25)},
+		{"e", RealValue(4)},
+		{"f", IntegerValue(-3)},
+		{"g", IntegerValue(3)},
+		{"h", IntegerValue(-3)},
+		{"i", BooleanValue(true)},
+		{"j", BooleanValue(false)},
+		{"k", RealValue(1)},
+		{"l", RealValue(0)},
+		{"m", RealValue(1)},
+		{"n", RealValue(0)},
+		{"o", IntegerValue(7)},
+	}
+	for _, tt := range tests {
+		if got := values[tt.name]; got != tt.want {
+			t.Errorf("%s: ожидалось %v (%s), получено %v (%T)", tt.name, tt.want, tt.want.Kind(), got, got)
+		}
+	}
+}
+
+// TestBuiltinCaseInsensitive тестирует вызов функций в любом регистре
+func TestBuiltinCaseInsensitive(t *testing.T) {
+	values := mustInterpret(t, `BEGIN x := ABS(-1) + abs(-2) + Sqr(SQRT(4)) END.`)
+	if got := values["x"]; got != RealValue(7) {
+		t.Errorf("x: ожидалось 7, получено %v", got)
+	}
+}
+
+// TestBuiltinDomainErrors тестирует ошибки области определения с позицией вызова
+func TestBuiltinDomainErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		pos  Position
+	}{
+		{"BEGIN x := Sqrt(-1) END.", Position{Line: 1, Column: 12}},
+		{"BEGIN\n  x := 1;\n  y := 2 + Ln(0)\nEND.", Position{Line: 3, Column: 12}},
+		{"BEGIN x := Ln(-5.5) END.", Position{Line: 1, Column: 12}},
+		{"BEGIN x := Exp(1000) END.", Position{Line: 1, Column: 12}},
+		{"BEGIN x := Trunc(1E30) END.", Position{Line: 1, Column: 12}},
+		{"BEGIN x := Odd(1.5) END.", Position{Line: 1, Column: 12}},
+		{"BEGIN x := Abs(Odd(1)) END.", Position{Line: 1, Column: 12}},
+	}
+	for _, tt := range tests {
+		interpreter, err := interpretCode(t, tt.code)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("%q: ожидалась ошибка выполнения, получено %v", tt.code, err)
+			continue
+		}
+		if runtimeErr.Pos != tt.pos {
+			t.Errorf("%q: ожидалась позиция %v, получена %v", tt.code, tt.pos, runtimeErr.Pos)
+		}
+		if _, ok := interpreter.Values()["x"]; ok && tt.pos.Line == 1 {
+			t.Errorf("%q: переменная x не должна получить значение", tt.code)
+		}
+	}
+}
+
+// TestBuiltinCallErrors тестирует неизвестные функции и неверное число аргументов
+func TestBuiltinCallErrors(t *testing.T) {
+	for _, code := range []string{
+		"BEGIN x := Foo(1) END.",
+		"BEGIN x := Abs() END.",
+		"BEGIN x := Sqr(1, 2) END.",
+		"BEGIN x := Sqrt(Foo(1)) END.",
+	} {
+		if _, err := interpretCode(t, code); err == nil {
+			t.Errorf("%q: ожидалась ошибка", code)
+		}
+	}
+}
+
+// TestParserCallErrors тестирует синтаксические ошибки в вызовах
+func TestParserCallErrors(t *testing.T) {
+	for _, code := range []string{
+		"BEGIN x := Abs(1 END.",
+		"BEGIN x := Abs(1,) END.",
+		"BEGIN x := Abs(; END.",
+		"BEGIN x := 99999999999999999999 END.",
+	} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("Ошибка лексического анализа: %v", err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestIntegerAndRealArithmetic тестирует типы результатов арифметики
+func TestIntegerAndRealArithmetic(t *testing.T) {
+	values := mustInterpret(t, `BEGIN
+	a := 2 + 3 * 4;
+	b := 6 / 3;
+	c := 1.5 + 1;
+	d := 2.5E1;
+	e := 1e-1 * 10
+END.`)
+	if values["a"] != IntegerValue(14) {
+		t.Errorf("a: ожидалось целое 14, получено %v (%T)", values["a"], values["a"])
+	}
+	if values["b"] != RealValue(2) {
+		t.Errorf("b: ожидалось вещественное 2, получено %v (%T)", values["b"], values["b"])
+	}
+	if values["c"] != RealValue(2.5) {
+		t.Errorf("c: ожидалось 2.5, получено %v", values["c"])
+	}
+	if values["d"] != RealValue(25) {
+		t.Errorf("d: ожидалось 25, получено %v", values["d"])
+	}
+	if values["e"] != RealValue(1) {
+		t.Errorf("e: ожидалось 1, получено %v", values["e"])
+	}
+}
+
+// TestIntegerOverflow тестирует контроль целочисленного переполнения
+func TestIntegerOverflow(t *testing.T) {
+	for _, code := range []string{
+		"BEGIN x := 9223372036854775807 + 1 END.",
+		"BEGIN x := -9223372036854775807 - 2 END.",
+		"BEGIN x := 4611686018427387904 * 2 END.",
+		"BEGIN x := Sqr(4294967296) END.",
+		"BEGIN x := Abs(-9223372036854775807 - 1) END.",
+	} {
+		_, err := interpretCode(t, code)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("%q: ожидалась ошибка переполнения, получено %v", code, err)
+		}
+	}
+}
+
+// TestLexerRealNumbers тестирует лексемы вещественных чисел
+func TestLexerRealNumbers(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"3.14", []string{"3.14"}},
+		{"1E5", []string{"1E5"}},
+		{"2.5e-3", []string{"2.5e-3"}},
+		{"7.", []string{"7", ""}},
+		{"1Ex", []string{"1", "Ex"}},
+		{"f(1, 2)", []string{"f", "", "1", "", "2", ""}},
+	}
+	for _, tt := range tests {
+		tokens, err := NewLexer(tt.code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", tt.code, err)
+		}
+		tokens = tokens[:len(tokens)-1]
+		if len(tokens) != len(tt.want) {
+			t.Fatalf("%q: ожидалось %d токенов, получено %d", tt.code, len(tt.want), len(tokens))
+		}
+		for n, want := range tt.want {
+			if tokens[n].Value != want {
+				t.Errorf("%q: токен %d: ожидалось %q, получено %q", tt.code, n, want, tokens[n].Value)
+			}
+		}
+	}
+}
+
+// TestLexerPositions тестирует вычисление строки и столбца токенов
+func TestLexerPositions(t *testing.T) {
+	tokens, err := NewLexer("BEGIN\n  x := 1;\n\ty := Ln(x)\nEND.").Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	// Индексы токенов BEGIN, x, y, Ln, x, END
+	want := map[int]Position{
+		0:  {Line: 1, Column: 1},
+		1:  {Line: 2, Column: 3},
+		5:  {Line: 3, Column: 2},
+		7:  {Line: 3, Column: 7},
+		9:  {Line: 3, Column: 10},
+		11: {Line: 4, Column: 1},
+	}
+	for n, pos := range want {
+		if tokens[n].Position() != pos {
+			t.Errorf("токен %d (%q): ожидалась позиция %v, получена %v", n, tokens[n].Value, pos, tokens[n].Position())
+		}
+	}
+}
+
+// TestFormatVariables тестирует вывод словаря переменных разных типов
+func TestFormatVariables(t *testing.T) {
+	got := formatVariables(map[string]Value{
+		"b": RealValue(2.5),
+		"a": IntegerValue(3),
+		"c": BooleanValue(true),
+		"d": RealValue(4),
+	})
+	want := "{a: 3, b: 2.5, c: TRUE, d: 4}"
+	if got != want {
+		t.Errorf("ожидалось %s, получено %s", want, got)
+	}
+	if formatVariables(map[string]Value{}) != "{}" {
+		t.Error("пустой словарь должен выводиться как {}")
+	}
+}
@@ -0,0 +1,1476 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SymbolKind представляет вид описанного имени
+type SymbolKind int
+
+const (
+	SymbolConst SymbolKind = iota
+	SymbolType
+	SymbolVar
+	SymbolProcedure
+	SymbolFunction
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case SymbolConst:
+		return "константа"
+	case SymbolType:
+		return "тип"
+	case SymbolProcedure:
+		return "процедура"
+	case SymbolFunction:
+		return "функция"
+	default:
+		return "переменная"
+	}
+}
+
+// Symbol представляет описанное имя
+type Symbol struct {
+	Name     string
+	Kind     SymbolKind
+	Type     *Type    // тип значения, для SymbolType - сам тип, для функции - тип результата; nil если тип неизвестен
+	Value    Value    // значение константы
+	Pos      Position // позиция описания, нулевая для стандартных имен
+	Implicit bool     // переменная без описания, созданная первым присваиванием
+
+	Signature *Signature // параметры процедуры или функции; nil, если их типы не удалось вычислить
+	Forward   bool       // подпрограмма описана заголовком в разделе INTERFACE, но еще не реализована
+}
+
+// isRoutine сообщает, обозначает ли имя процедуру или функцию
+func (s *Symbol) isRoutine() bool {
+	return s.Kind == SymbolProcedure || s.Kind == SymbolFunction
+}
+
+// Scope представляет область видимости имен
+type Scope struct {
+	symbols map[string]*Symbol
+	parent  *Scope
+}
+
+// NewScope создает область видимости, вложенную в parent
+func NewScope(parent *Scope) *Scope {
+	return &Scope{symbols: make(map[string]*Symbol), parent: parent}
+}
+
+// Lookup ищет имя в этой и объемлющих областях видимости
+func (s *Scope) Lookup(name string) *Symbol {
+	key := strings.ToLower(name)
+	for scope := s; scope != nil; scope = scope.parent {
+		if symbol, ok := scope.symbols[key]; ok {
+			return symbol
+		}
+	}
+	return nil
+}
+
+// Insert добавляет имя в область видимости; повторное описание является ошибкой
+func (s *Scope) Insert(symbol *Symbol) error {
+	key := strings.ToLower(symbol.Name)
+	if previous, exists := s.symbols[key]; exists {
+		return fmt.Errorf("повторное описание %s (имя уже описано как %s в позиции %s)", symbol.Name, previous.Kind, previous.Pos)
+	}
+	s.symbols[key] = symbol
+	return nil
+}
+
+// universeScope создает область видимости стандартных имен
+func universeScope() *Scope {
+	scope := NewScope(nil)
+	for name, t := range predeclaredTypes {
+		scope.symbols[name] = &Symbol{Name: t.Name, Kind: SymbolType, Type: t}
+	}
+	for name, value := range predeclaredConstants {
+		scope.symbols[name] = &Symbol{Name: strings.ToUpper(name), Kind: SymbolConst, Type: typeOfValue(value), Value: value}
+	}
+	return scope
+}
+
+// CheckError представляет ошибку семантического анализа
+type CheckError struct {
+	Message string
+	Pos     Position
+}
+
+func (e *CheckError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+	}
+	return e.Message
+}
+
+// Info содержит результаты семантического анализа для инструментов, которым нужен
+// проверенный AST: разрешенные имена и типы выражений
+type Info struct {
+	// Types - типы выражений после свертки констант; nil, если тип неизвестен
+	Types map[Expression]*Type
+	// Symbols - описанные имена, к которым относятся узлы: Identifier, CallExpr и
+	// CallStatement (подпрограмма программы; для стандартных нет записи), Assignment без
+	// Target и ForStatement (переменная), ExceptHandler (его переменная или, если ее нет,
+	// класс), а также описания ConstDecl, VarDecl, TypeDecl, RoutineDecl и ParamDecl
+	Symbols map[Node]*Symbol
+	// Scopes - области видимости программы (Program), модулей (Unit) и подпрограмм
+	// (RoutineDecl) после проверки
+	Scopes map[Node]*Scope
+}
+
+// NewInfo создает пустой Info
+func NewInfo() *Info {
+	return &Info{Types: make(map[Expression]*Type), Symbols: make(map[Node]*Symbol), Scopes: make(map[Node]*Scope)}
+}
+
+// Checker выполняет семантический анализ программы: разрешает имена,
+// проверяет типы, запрещает присваивание константам и сворачивает
+// константные выражения в AST
+type Checker struct {
+	Info   *Info // если задан, заполняется при проверке
+	BigInt bool  // свертывать константы в режиме длинных целых, как при выполнении с Options.BigInt
+
+	// Dialect - диалект программы: в DialectISO неописанные переменные и нестандартные
+	// типы отмечаются в Deviations
+	Dialect Dialect
+	// Deviations - отклонения от стандарта, найденные Check; Check возвращает их вместе
+	// с ошибками
+	Deviations []*DialectError
+
+	scope  *Scope
+	errors []*CheckError
+
+	function *Symbol // функция, тело которой проверяется: ее имени присваивается результат
+	handlers int     // глубина вложенности обработчиков EXCEPT, в которых допустим RAISE без выражения
+	loops    int     // глубина вложенности циклов, в которых допустимы Break и Continue
+	finally  bool    // проверяется блок FINALLY, который нельзя покинуть Break, Continue или Exit
+}
+
+// NewChecker создает новый семантический анализатор
+func NewChecker() *Checker {
+	return &Checker{scope: NewScope(universeScope())}
+}
+
+// Check проверяет программу и возвращает все найденные ошибки
+func (c *Checker) Check(program *Program) error {
+	c.scope.parent = c.imports(program.Uses, c.scope.parent)
+	c.recordScope(program)
+	c.declarations(&program.Declarations)
+	c.statements(program.Statements)
+
+	errs := make([]error, len(c.errors))
+	for n, err := range c.errors {
+		errs[n] = err
+	}
+	return errors.Join(append(errs, dialectErrors(c.Deviations))...)
+}
+
+// Errors возвращает найденные ошибки в порядке обнаружения
+func (c *Checker) Errors() []*CheckError {
+	return c.errors
+}
+
+// Scope возвращает область видимости программы после проверки
+func (c *Checker) Scope() *Scope {
+	return c.scope
+}
+
+func (c *Checker) errorf(pos Position, format string, args ...interface{}) {
+	c.errors = append(c.errors, &CheckError{Message: fmt.Sprintf(format, args...), Pos: pos})
+}
+
+// deviate запоминает отклонение от правила rule стандарта в позиции pos
+func (c *Checker) deviate(rule isoRule, pos Position, format string, args ...interface{}) {
+	c.Deviations = append(c.Deviations, deviation(rule, pos, format, args...))
+}
+
+// implicit описывает неописанную переменную, созданную присваиванием, чтением или
+// передачей в VAR-параметр; в диалекте ISO это отклонение от стандарта
+func (c *Checker) implicit(symbol *Symbol) {
+	if c.Dialect == DialectISO {
+		c.deviate(ruleDefiningPoint, symbol.Pos, "переменная %s не описана", symbol.Name)
+	}
+	c.insert(symbol)
+}
+
+// typeName отмечает в диалекте ISO стандартные типы, которых нет в стандарте, например STRING
+func (c *Checker) typeName(name string, pos Position, t *Type) {
+	key := strings.ToLower(name)
+	if c.Dialect == DialectISO && t == predeclaredTypes[key] && !isoTypes[key] {
+		c.deviate(ruleRequiredTypes, pos, "тип %s не предусмотрен стандартом", name)
+	}
+}
+
+func (c *Checker) insert(symbol *Symbol) {
+	if err := c.scope.Insert(symbol); err != nil {
+		c.errorf(symbol.Pos, "%v", err)
+	}
+}
+
+// record запоминает в Info имя, к которому относится узел
+func (c *Checker) record(node Node, symbol *Symbol) {
+	if c.Info != nil && symbol != nil {
+		c.Info.Symbols[node] = symbol
+	}
+}
+
+// recordScope запоминает в Info текущую область видимости программы, модуля или подпрограммы
+func (c *Checker) recordScope(node Node) {
+	if c.Info != nil {
+		c.Info.Scopes[node] = c.scope
+	}
+}
+
+// insertFor описывает имя узла node и запоминает его в Info
+func (c *Checker) insertFor(node Node, symbol *Symbol) {
+	c.insert(symbol)
+	c.record(node, symbol)
+}
+
+// lookupType ищет тип по имени для typeResolver
+func (c *Checker) lookupType(name string) *Type {
+	if symbol := c.scope.Lookup(name); symbol != nil && symbol.Kind == SymbolType {
+		return symbol.Type
+	}
+	return nil
+}
+
+// declarations проверяет раздел описаний
+func (c *Checker) declarations(declarations *Declarations) {
+	for _, decl := range declarations.Consts {
+		value, t := c.expression(decl.Value)
+		decl.Value = value
+		constant, ok := constantValue(value)
+		if !ok {
+			if _, err := c.evaluator().evaluateExpression(value); err != nil {
+				c.errorf(decl.Pos, "значение константы %s: %v", decl.Name, err)
+			} else {
+				c.errorf(decl.Pos, "значение константы %s должно быть константным выражением", decl.Name)
+			}
+			continue
+		}
+		c.insertFor(decl, &Symbol{Name: decl.Name, Kind: SymbolConst, Type: t, Value: constant, Pos: decl.Pos})
+	}
+	resolver := &typeResolver{lookup: c.lookupType, evaluate: c.constant, typeName: c.typeName}
+	for _, decl := range declarations.Types {
+		t, err := resolver.resolve(decl.Type)
+		if err != nil {
+			c.errorf(decl.Pos, "%v", err)
+			continue
+		}
+		if _, alias := decl.Type.(*NamedType); !alias {
+			t.Name = decl.Name
+		}
+		c.insertFor(decl, &Symbol{Name: decl.Name, Kind: SymbolType, Type: t, Pos: decl.Pos})
+		c.enum(decl.Type, t)
+	}
+	// Указатели на типы, описанные ниже в том же разделе TYPE, разрешаются в его конце
+	if pos, err := resolver.finish(); err != nil {
+		c.errorf(pos, "%v", err)
+	}
+	var spec TypeSpec
+	var t *Type
+	for _, decl := range declarations.Vars {
+		if decl.Type != spec {
+			var err error
+			spec = decl.Type
+			if t, err = resolver.resolve(spec); err != nil {
+				c.errorf(decl.Pos, "%v", err)
+			} else if pos, err := resolver.finish(); err != nil {
+				c.errorf(pos, "%v", err)
+			} else {
+				c.enum(spec, t)
+			}
+		}
+		c.insertFor(decl, &Symbol{Name: decl.Name, Kind: SymbolVar, Type: t, Pos: decl.Pos})
+	}
+	for _, decl := range declarations.Routines {
+		c.routine(decl, resolver)
+	}
+}
+
+// routine проверяет процедуру или функцию. Параметры и локальные описания образуют
+// область видимости, вложенную в область видимости программы; имя подпрограммы
+// описывается до проверки тела, что допускает рекурсию.
+func (c *Checker) routine(decl *RoutineDecl, resolver *typeResolver) {
+	symbol := &Symbol{Name: decl.Name, Kind: SymbolProcedure, Pos: decl.Pos}
+	if decl.IsFunction() {
+		symbol.Kind = SymbolFunction
+	}
+	signature, pos, err := resolveSignature(decl, resolver)
+	if err != nil {
+		c.errorf(pos, "%v", err)
+	} else {
+		symbol.Signature = signature
+		symbol.Type = signature.Result
+	}
+	key := strings.ToLower(decl.Name)
+	if forward := c.scope.symbols[key]; forward != nil && forward.Forward && decl.Body != nil {
+		// Реализация подпрограммы, заголовок которой описан в разделе INTERFACE
+		if forward.Kind != symbol.Kind || forward.Signature != nil && signature != nil && !sameSignature(forward.Signature, signature) {
+			c.errorf(decl.Pos, "заголовок %s не совпадает с описанием в разделе INTERFACE (позиция %s)", decl.Name, forward.Pos)
+		}
+		forward.Forward = false
+		symbol = forward
+	} else {
+		c.insert(symbol)
+	}
+	c.record(decl, symbol)
+	if decl.Body == nil {
+		symbol.Forward = true
+		return
+	}
+
+	outer, function, handlers, loops, finally := c.scope, c.function, c.handlers, c.loops, c.finally
+	defer func() {
+		c.scope, c.function, c.handlers, c.loops, c.finally = outer, function, handlers, loops, finally
+	}()
+	c.scope = NewScope(outer)
+	c.recordScope(decl)
+	c.function, c.handlers, c.loops, c.finally = nil, 0, 0, false
+	if symbol.Kind == SymbolFunction {
+		// Имя функции в ее теле обозначает результат и не может совпадать с параметром
+		c.function = symbol
+		c.insert(symbol)
+	}
+	if signature != nil {
+		for n, param := range signature.Params {
+			c.insertFor(decl.Params[n], &Symbol{Name: param.Name, Kind: SymbolVar, Type: param.Type, Pos: decl.Params[n].Pos})
+		}
+	}
+	c.declarations(&decl.Declarations)
+	c.statements(decl.Body.Statements)
+}
+
+// enum описывает имена значений перечисления как константы
+func (c *Checker) enum(spec TypeSpec, t *Type) {
+	if set, ok := spec.(*SetType); ok {
+		c.enum(set.Elem, t.Elem)
+	}
+	if record, ok := spec.(*RecordType); ok {
+		for n, field := range record.Fields {
+			c.enum(field.Type, t.Fields[n].Type)
+		}
+	}
+	if enum, ok := spec.(*EnumType); ok {
+		for n, name := range enum.Names {
+			value := EnumValue{Type: t, Ordinal: int64(n)}
+			c.insert(&Symbol{Name: name, Kind: SymbolConst, Type: t, Value: value, Pos: enum.Pos})
+		}
+	}
+}
+
+// evaluator возвращает интерпретатор для свертки константных выражений
+func (c *Checker) evaluator() *Interpreter {
+	return NewInterpreterWithOptions(Options{BigInt: c.BigInt})
+}
+
+// constant вычисляет константное выражение, например границу диапазона
+func (c *Checker) constant(expr Expression) (Value, error) {
+	expr, _ = c.expression(expr)
+	if value, ok := constantValue(expr); ok {
+		return value, nil
+	}
+	if _, err := c.evaluator().evaluateExpression(expr); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("ожидалось константное выражение")
+}
+
+// statements проверяет список операторов
+func (c *Checker) statements(statements []Statement) {
+	for _, stmt := range statements {
+		c.statement(stmt)
+	}
+}
+
+// statement проверяет оператор
+func (c *Checker) statement(stmt Statement) {
+	VisitStatement[struct{}](statementChecker{c}, stmt)
+}
+
+// statementChecker проверяет операторы как StatementVisitor
+type statementChecker struct {
+	*Checker
+}
+
+func (c statementChecker) VisitAssignment(s *Assignment) struct{} {
+	value, t := c.expression(s.Value)
+	s.Value = value
+	if s.Target != nil {
+		c.targetAssignment(s, t)
+	} else {
+		c.assignment(s.Variable, s.Pos, s.Value, t)
+		c.record(s, c.scope.Lookup(s.Variable))
+	}
+	return struct{}{}
+}
+
+func (c statementChecker) VisitBlock(s *Block) struct{} {
+	c.statements(s.Statements)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitIfStatement(s *IfStatement) struct{} {
+	c.condition(&s.Cond, "IF", s.Pos)
+	c.statement(s.Then)
+	if s.Else != nil {
+		c.statement(s.Else)
+	}
+	return struct{}{}
+}
+
+func (c statementChecker) VisitWhileStatement(s *WhileStatement) struct{} {
+	c.condition(&s.Cond, "WHILE", s.Pos)
+	c.loops++
+	c.statement(s.Body)
+	c.loops--
+	return struct{}{}
+}
+
+func (c statementChecker) VisitRepeatStatement(s *RepeatStatement) struct{} {
+	c.loops++
+	c.statements(s.Body.Statements)
+	c.loops--
+	c.condition(&s.Cond, "UNTIL", s.Pos)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitForStatement(s *ForStatement) struct{} {
+	c.forStatement(s)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitCallStatement(s *CallStatement) struct{} {
+	c.callStatement(s)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitCaseStatement(s *CaseStatement) struct{} {
+	c.caseStatement(s)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitTryStatement(s *TryStatement) struct{} {
+	c.tryStatement(s)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitRaiseStatement(s *RaiseStatement) struct{} {
+	c.raiseStatement(s)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitBreakStatement(s *BreakStatement) struct{} {
+	c.jump("Break", s.Pos)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitContinueStatement(s *ContinueStatement) struct{} {
+	c.jump("Continue", s.Pos)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitExitStatement(s *ExitStatement) struct{} {
+	c.exitStatement(s)
+	return struct{}{}
+}
+
+func (c statementChecker) VisitOtherStatement(stmt Statement) struct{} {
+	c.errorf(Position{}, "неизвестный тип оператора: %T", stmt)
+	return struct{}{}
+}
+
+// tryStatement проверяет оператор TRY: классы обработчиков ON должны быть классами исключений,
+// а переменная обработчика описывается только в его теле
+func (c *Checker) tryStatement(s *TryStatement) {
+	c.statements(s.Body.Statements)
+	if s.Finally != nil {
+		// Циклы, окружающие TRY, недоступны для Break и Continue из FINALLY
+		loops, finally := c.loops, c.finally
+		c.loops, c.finally = 0, true
+		c.statements(s.Finally.Statements)
+		c.loops, c.finally = loops, finally
+		return
+	}
+	c.handlers++
+	defer func() { c.handlers-- }()
+	for _, handler := range s.Handlers {
+		class := c.lookupType(handler.Class)
+		switch {
+		case class == nil:
+			c.errorf(handler.Pos, "неизвестный класс исключения %s", handler.Class)
+		case class.Kind != TypeClass:
+			c.errorf(handler.Pos, "%s не является классом исключения", handler.Class)
+			class = nil
+		}
+		if handler.Variable == "" || class == nil {
+			if class != nil {
+				c.record(handler, c.scope.Lookup(handler.Class))
+			}
+			c.statement(handler.Body)
+			continue
+		}
+		outer := c.scope
+		c.scope = NewScope(outer)
+		c.insertFor(handler, &Symbol{Name: handler.Variable, Kind: SymbolVar, Type: class, Pos: handler.Pos})
+		c.statement(handler.Body)
+		c.scope = outer
+	}
+	if s.Default != nil {
+		c.statements(s.Default.Statements)
+	}
+}
+
+// jump проверяет, что Break или Continue находится в цикле
+func (c *Checker) jump(name string, pos Position) {
+	switch {
+	case c.loops > 0:
+	case c.finally:
+		c.errorf(pos, "%s не может покидать блок FINALLY", name)
+	default:
+		c.errorf(pos, "%s допустим только в цикле", name)
+	}
+}
+
+// exitStatement проверяет Exit: значение допустимо только в функции и должно быть
+// совместимо с типом ее результата
+func (c *Checker) exitStatement(s *ExitStatement) {
+	if c.finally {
+		c.errorf(s.Pos, "Exit не может покидать блок FINALLY")
+	}
+	if s.Value == nil {
+		return
+	}
+	var t *Type
+	s.Value, t = c.expression(s.Value)
+	if c.function == nil {
+		c.errorf(s.Pos, "Exit со значением допустим только в функции")
+		return
+	}
+	result := c.function.Type
+	if t == nil || result == nil {
+		return
+	}
+	if !assignable(result, t) {
+		c.errorf(s.Pos, "Exit: нельзя вернуть %s из функции %s типа %s", t, c.function.Name, result)
+	} else if constant, ok := constantValue(s.Value); ok && !inRange(result, constant) {
+		c.errorf(s.Pos, "Exit: значение %s вне диапазона %s результата функции %s", constant, result.Range(), c.function.Name)
+	}
+}
+
+// raiseStatement проверяет RAISE: выражение должно быть исключением, а RAISE без выражения
+// допустим только в обработчике исключения
+func (c *Checker) raiseStatement(s *RaiseStatement) {
+	if s.Exception == nil {
+		if c.handlers == 0 {
+			c.errorf(s.Pos, "RAISE без исключения допустим только в обработчике исключения")
+		}
+		return
+	}
+	var t *Type
+	s.Exception, t = c.expression(s.Exception)
+	if t != nil && t.Kind != TypeClass {
+		c.errorf(s.Pos, "RAISE: ожидалось исключение, получено %s", t)
+	}
+}
+
+// condition проверяет, что условие оператора statement имеет логический тип
+func (c *Checker) condition(expr *Expression, statement string, pos Position) {
+	var t *Type
+	*expr, t = c.expression(*expr)
+	if t != nil && t.Kind != TypeBoolean {
+		c.errorf(pos, "условие оператора %s должно быть логического типа, получено %s", statement, t)
+	}
+}
+
+// forStatement проверяет цикл FOR: переменная и границы должны быть одного порядкового типа
+func (c *Checker) forStatement(s *ForStatement) {
+	// Переменная цикла проверяется один раз, до границ: константа, тип или подпрограмма
+	// вместо переменной - одна ошибка, а не по одной на каждую границу
+	variable := c.scope.Lookup(s.Variable)
+	assignable := variable == nil || variable.Kind == SymbolVar || variable == c.function
+	if !assignable {
+		c.assignment(s.Variable, s.Pos, nil, nil)
+	}
+	for _, bound := range []*Expression{&s.Start, &s.End} {
+		var t *Type
+		*bound, t = c.expression(*bound)
+		if t != nil && !isOrdinal(t) {
+			c.errorf(s.Pos, "границы цикла FOR должны быть порядкового типа, получено %s", t)
+			continue
+		}
+		if assignable {
+			c.assignment(s.Variable, s.Pos, *bound, t)
+		}
+	}
+	if symbol := c.scope.Lookup(s.Variable); symbol != nil && symbol.Kind == SymbolVar &&
+		symbol.Type != nil && !isOrdinal(symbol.Type) {
+		c.errorf(s.Pos, "переменная цикла FOR %s должна быть порядкового типа, получено %s", s.Variable, symbol.Type)
+	}
+	c.record(s, c.scope.Lookup(s.Variable))
+	c.loops++
+	c.statement(s.Body)
+	c.loops--
+}
+
+// callStatement проверяет вызов процедуры программы или стандартной процедуры
+func (c *Checker) callStatement(s *CallStatement) {
+	if symbol := c.scope.Lookup(s.Name); symbol != nil && symbol.isRoutine() {
+		c.record(s, symbol)
+		c.arguments(symbol, s.Args, s.Pos)
+		return
+	}
+	switch strings.ToLower(s.Name) {
+	case "new", "dispose":
+		c.memoryProcedure(s)
+	case "write", "writeln":
+		c.writeProcedure(s)
+	case "read", "readln":
+		c.readProcedure(s)
+	case "assign", "reset", "rewrite", "append", "close":
+		c.fileProcedure(s)
+	case "assert":
+		c.assertProcedure(s)
+	case "halt":
+		c.haltProcedure(s)
+	default:
+		c.errorf(s.Pos, "неизвестная процедура %s", s.Name)
+	}
+}
+
+// arguments проверяет аргументы вызова подпрограммы: их число, совместимость значений
+// с типами параметров и то, что в VAR-параметр передается переменная того же типа
+func (c *Checker) arguments(symbol *Symbol, args []Expression, pos Position) {
+	if symbol.Signature == nil {
+		for n, arg := range args {
+			args[n], _ = c.expression(arg)
+		}
+		return
+	}
+	params := symbol.Signature.Params
+	if len(args) != len(params) {
+		c.errorf(pos, "%s %s ожидает %d аргумент(ов), получено %d", symbol.Kind, symbol.Name, len(params), len(args))
+		return
+	}
+	for n, param := range params {
+		if param.ByRef {
+			c.varArgument(symbol, param, args[n], pos)
+			continue
+		}
+		var t *Type
+		args[n], t = c.expression(args[n])
+		if t == nil {
+			continue
+		}
+		if !assignable(param.Type, t) {
+			c.errorf(pos, "%s: нельзя передать %s в параметр %s типа %s", symbol.Name, t, param.Name, param.Type)
+		} else if constant, ok := constantValue(args[n]); ok && !inRange(param.Type, constant) {
+			c.errorf(pos, "%s: значение %s вне диапазона %s параметра %s", symbol.Name, constant, param.Type.Range(), param.Name)
+		}
+	}
+}
+
+// varArgument проверяет аргумент VAR-параметра: это должна быть переменная, поле или p^
+// того же типа, что и параметр. Неописанная переменная получает тип параметра.
+func (c *Checker) varArgument(symbol *Symbol, param Param, arg Expression, pos Position) {
+	if id, ok := arg.(*Identifier); ok {
+		variable := c.scope.Lookup(id.Name)
+		if variable == nil {
+			symbol := &Symbol{Name: id.Name, Kind: SymbolVar, Type: param.Type, Pos: id.Pos, Implicit: true}
+			c.implicit(symbol)
+			c.record(id, symbol)
+			return
+		}
+		c.record(id, variable)
+		if variable.Kind != SymbolVar {
+			c.errorf(pos, "%s: в VAR-параметр %s можно передать только переменную, %s не является переменной", symbol.Name, param.Name, id.Name)
+			return
+		}
+		if variable.Implicit && variable.Type == nil {
+			variable.Type = param.Type
+			return
+		}
+	} else if !isDesignator(arg) {
+		c.errorf(pos, "%s: в VAR-параметр %s можно передать только переменную", symbol.Name, param.Name)
+		return
+	}
+	if t := c.target(arg); t != nil && !sameVarType(t, param.Type) {
+		c.errorf(pos, "%s: тип переменной %s (%s) не совпадает с типом VAR-параметра %s (%s)",
+			symbol.Name, designatorName(arg), t, param.Name, param.Type)
+	}
+}
+
+// memoryProcedure проверяет New и Dispose: аргумент должен быть переменной-указателем
+func (c *Checker) memoryProcedure(s *CallStatement) {
+	if len(s.Args) != 1 {
+		c.errorf(s.Pos, "процедура %s ожидает 1 аргумент(ов), получено %d", s.Name, len(s.Args))
+		return
+	}
+	if t, ok := c.variable(s, s.Args[0]); ok && t != nil && t.Kind != TypePointer {
+		c.errorf(s.Pos, "%s: переменная %s должна быть указателем, получено %s", s.Name, designatorName(s.Args[0]), t)
+	}
+}
+
+// variable проверяет, что аргумент процедуры обозначает описанную переменную,
+// и возвращает ее тип; ok ложно, если об ошибке уже сообщено
+func (c *Checker) variable(s *CallStatement, arg Expression) (*Type, bool) {
+	if !isDesignator(arg) {
+		c.errorf(s.Pos, "%s: аргумент %s должен быть переменной", s.Name, arg)
+		return nil, false
+	}
+	if id, ok := arg.(*Identifier); ok {
+		symbol := c.scope.Lookup(id.Name)
+		switch {
+		case symbol == nil:
+			c.errorf(s.Pos, "%s: переменная %s не описана", s.Name, id.Name)
+			return nil, false
+		case symbol.Kind != SymbolVar:
+			c.errorf(s.Pos, "%s: %s не является переменной", s.Name, id.Name)
+			return nil, false
+		}
+	}
+	return c.target(arg), true
+}
+
+// fileArgument сообщает, является ли первый аргумент процедуры ввода-вывода файловой переменной
+func (c *Checker) fileArgument(args []Expression) bool {
+	if len(args) == 0 || !isDesignator(args[0]) {
+		return false
+	}
+	if id, ok := args[0].(*Identifier); ok && c.scope.Lookup(id.Name) == nil {
+		return false
+	}
+	t := baseType(c.reference(args[0]))
+	return t != nil && t.Kind == TypeText
+}
+
+// writeProcedure проверяет Write и WriteLn: выводятся числа, логические значения,
+// перечисления, символы и строки; точность задается только для вещественных чисел
+func (c *Checker) writeProcedure(s *CallStatement) {
+	args := s.Args
+	if c.fileArgument(args) {
+		args = args[1:]
+	}
+	for n, arg := range args {
+		var t *Type
+		format, _ := arg.(*FormatExpr)
+		if format == nil {
+			args[n], t = c.expression(arg)
+		} else {
+			format.Value, t = c.expression(format.Value)
+			c.formatParameter(s, &format.Width, "ширина поля")
+			if format.Precision != nil {
+				c.formatParameter(s, &format.Precision, "точность")
+				if t != nil && baseType(t).Kind != TypeReal {
+					c.errorf(s.Pos, "%s: точность задается только для вещественных значений, получено %s", s.Name, t)
+				}
+			}
+		}
+		if t != nil && !isOrdinal(t) && !isText(t) && baseType(t).Kind != TypeReal {
+			c.errorf(s.Pos, "%s: нельзя вывести значение типа %s", s.Name, t)
+		}
+	}
+}
+
+// formatParameter проверяет ширину поля или точность параметра Write
+func (c *Checker) formatParameter(s *CallStatement, expr *Expression, what string) {
+	var t *Type
+	*expr, t = c.expression(*expr)
+	if t != nil && t.Kind != TypeInteger {
+		c.errorf(s.Pos, "%s: %s должна быть целой, получено %s", s.Name, what, t)
+	}
+}
+
+// readProcedure проверяет Read и ReadLn: читаются переменные числовых, символьного и строкового типов
+func (c *Checker) readProcedure(s *CallStatement) {
+	args := s.Args
+	if c.fileArgument(args) {
+		args = args[1:]
+	}
+	for _, arg := range args {
+		if id, ok := arg.(*Identifier); ok && c.scope.Lookup(id.Name) == nil {
+			// Неописанная переменная создается чтением и получает тип прочитанного числа
+			symbol := &Symbol{Name: id.Name, Kind: SymbolVar, Pos: id.Pos, Implicit: true}
+			c.implicit(symbol)
+			c.record(id, symbol)
+			continue
+		}
+		t, ok := c.variable(s, arg)
+		if !ok || t == nil {
+			continue
+		}
+		if !isNumeric(baseType(t)) && !isText(t) {
+			c.errorf(s.Pos, "%s: нельзя прочитать значение типа %s", s.Name, t)
+		}
+	}
+}
+
+// fileProcedure проверяет Assign, Reset, Rewrite, Append и Close: первый аргумент -
+// переменная типа TEXT, второй аргумент Assign - имя файла
+func (c *Checker) fileProcedure(s *CallStatement) {
+	arity := 1
+	if strings.EqualFold(s.Name, "assign") {
+		arity = 2
+	}
+	if len(s.Args) != arity {
+		c.errorf(s.Pos, "процедура %s ожидает %d аргумент(ов), получено %d", s.Name, arity, len(s.Args))
+		return
+	}
+	if t, ok := c.variable(s, s.Args[0]); ok && t != nil && baseType(t).Kind != TypeText {
+		c.errorf(s.Pos, "%s: %s не является файловой переменной", s.Name, designatorName(s.Args[0]))
+	}
+	if arity == 2 {
+		var t *Type
+		s.Args[1], t = c.expression(s.Args[1])
+		if t != nil && !isText(t) {
+			c.errorf(s.Pos, "Assign: имя файла должно быть строкой, получено %s", t)
+		}
+	}
+}
+
+// assertProcedure проверяет Assert(условие [, сообщение]): условие логическое, сообщение - строка
+func (c *Checker) assertProcedure(s *CallStatement) {
+	if len(s.Args) < 1 || len(s.Args) > 2 {
+		c.errorf(s.Pos, "процедура Assert ожидает 1 или 2 аргумента, получено %d", len(s.Args))
+		return
+	}
+	c.condition(&s.Args[0], "Assert", s.Pos)
+	if len(s.Args) == 2 {
+		var t *Type
+		s.Args[1], t = c.expression(s.Args[1])
+		if t != nil && !isText(t) {
+			c.errorf(s.Pos, "Assert: сообщение должно быть строкой, получено %s", t)
+		}
+	}
+}
+
+// haltProcedure проверяет Halt и Halt(код): код выхода - целое число
+func (c *Checker) haltProcedure(s *CallStatement) {
+	if len(s.Args) > 1 {
+		c.errorf(s.Pos, "процедура Halt ожидает не более 1 аргумента, получено %d", len(s.Args))
+		return
+	}
+	if len(s.Args) == 1 {
+		var t *Type
+		s.Args[0], t = c.expression(s.Args[0])
+		if t != nil && baseType(t).Kind != TypeInteger {
+			c.errorf(s.Pos, "Halt: код выхода должен быть целым, получено %s", t)
+		}
+	}
+}
+
+// fileFunction проверяет Eof и Eoln: аргумент необязателен и должен иметь тип TEXT
+func (c *Checker) fileFunction(e *CallExpr) (Expression, *Type) {
+	if len(e.Args) > 1 {
+		c.errorf(e.Pos, "функция %s ожидает не более 1 аргумента, получено %d", e.Name, len(e.Args))
+		return e, nil
+	}
+	if len(e.Args) == 1 {
+		var t *Type
+		e.Args[0], t = c.expression(e.Args[0])
+		if t != nil && t.Kind != TypeText {
+			c.errorf(e.Pos, "%s: ожидалась файловая переменная, получен %s", e.Name, t)
+		}
+	}
+	return e, booleanType
+}
+
+// isDesignator сообщает, обозначает ли выражение место хранения: переменную, поле или p^
+func isDesignator(expr Expression) bool {
+	switch expr.(type) {
+	case *Identifier, *Dereference, *FieldAccess:
+		return true
+	default:
+		return false
+	}
+}
+
+// reference проверяет обращение к переменной, полю записи или p^ и возвращает
+// описанный тип места хранения (nil, если тип неизвестен)
+func (c *Checker) reference(expr Expression) *Type {
+	switch e := expr.(type) {
+	case *Identifier:
+		if symbol := c.scope.Lookup(e.Name); symbol != nil && symbol.Kind == SymbolVar {
+			c.record(e, symbol)
+			return symbol.Type
+		}
+		_, t := c.expression(e)
+		return t
+	case *Dereference:
+		var t *Type
+		e.Pointer, t = c.expression(e.Pointer)
+		switch {
+		case t == nil:
+			return nil
+		case t.Kind != TypePointer:
+			c.errorf(e.Pos, "операция ^ неприменима к типу %s", t)
+			return nil
+		case t.Elem == nil:
+			c.errorf(e.Pos, "разыменование указателя NIL")
+			return nil
+		}
+		return t.Elem
+	case *FieldAccess:
+		var t *Type
+		e.Record, t = c.expression(e.Record)
+		return c.fieldType(e, t)
+	default:
+		_, t := c.expression(expr)
+		return t
+	}
+}
+
+// fieldType возвращает тип поля записи или исключения, имеющего тип t
+func (c *Checker) fieldType(e *FieldAccess, t *Type) *Type {
+	if t == nil {
+		return nil
+	}
+	if t.Kind == TypeClass {
+		if !exceptionFields[strings.ToLower(e.Field)] {
+			c.errorf(e.Pos, "у исключения %s нет поля %s", t, e.Field)
+			return nil
+		}
+		return stringType
+	}
+	if t.Kind != TypeRecord {
+		c.errorf(e.Pos, "%s не является записью", designatorName(e.Record))
+		return nil
+	}
+	n := t.Field(e.Field)
+	if n < 0 {
+		c.errorf(e.Pos, "у записи %s нет поля %s", t, e.Field)
+		return nil
+	}
+	return t.Fields[n].Type
+}
+
+// target проверяет место, в которое записывается значение: в отличие от reference,
+// поля исключения доступны только для чтения
+func (c *Checker) target(expr Expression) *Type {
+	if field, ok := expr.(*FieldAccess); ok && exceptionFields[strings.ToLower(field.Field)] {
+		var t *Type
+		field.Record, t = c.expression(field.Record)
+		if t != nil && t.Kind == TypeClass {
+			c.errorf(field.Pos, "поле %s исключения доступно только для чтения", field.Field)
+			return nil
+		}
+		return c.fieldType(field, t)
+	}
+	return c.reference(expr)
+}
+
+// caseRange представляет диапазон значений метки CASE для поиска пересечений
+type caseRange struct {
+	low, high int64
+	label     *CaseLabel
+}
+
+// caseStatement проверяет оператор CASE: метки должны быть константами типа
+// выражения, а их диапазоны не должны повторяться и пересекаться
+func (c *Checker) caseStatement(s *CaseStatement) {
+	var selector *Type
+	s.Expr, selector = c.expression(s.Expr)
+	if !isOrdinal(selector) {
+		c.errorf(s.Pos, "выражение CASE должно быть порядкового типа, получено %s", selector)
+		selector = nil
+	}
+
+	var ranges []caseRange
+	for _, branch := range s.Branches {
+		for _, label := range branch.Labels {
+			r, ok := c.caseLabel(label, &selector)
+			if !ok {
+				continue
+			}
+			for _, previous := range ranges {
+				if r.low <= previous.high && previous.low <= r.high {
+					if label.High == nil && previous.label.High == nil {
+						c.errorf(label.Pos, "метка CASE %s повторяется (уже использована в позиции %s)", labelText(label), previous.label.Pos)
+					} else {
+						c.errorf(label.Pos, "метка CASE %s пересекается с меткой %s в позиции %s", labelText(label), labelText(previous.label), previous.label.Pos)
+					}
+					break
+				}
+			}
+			ranges = append(ranges, r)
+		}
+		c.statement(branch.Body)
+	}
+
+	if s.Else != nil {
+		c.statements(s.Else.Statements)
+	}
+}
+
+// caseLabel сворачивает метку CASE и вычисляет ее диапазон. Если тип выражения
+// неизвестен, его задает первая метка.
+func (c *Checker) caseLabel(label *CaseLabel, selector **Type) (caseRange, bool) {
+	bounds := []*Expression{&label.Low}
+	if label.High != nil {
+		bounds = append(bounds, &label.High)
+	}
+	r := caseRange{label: label}
+	for n, bound := range bounds {
+		var t *Type
+		*bound, t = c.expression(*bound)
+		value, ok := constantValue(*bound)
+		if !ok {
+			c.errorf(label.Pos, "метка CASE должна быть константой")
+			return r, false
+		}
+		if *selector == nil && isOrdinal(t) {
+			*selector = t
+		}
+		if !isOrdinal(t) || !sameType(t, *selector) {
+			c.errorf(label.Pos, "тип метки CASE %s не совпадает с типом выражения %s", t, *selector)
+			return r, false
+		}
+		ordinal, _ := ordinalOf(value)
+		if n == 0 {
+			r.low, r.high = ordinal, ordinal
+		} else {
+			r.high = ordinal
+		}
+	}
+	if r.low > r.high {
+		c.errorf(label.Pos, "пустой диапазон меток CASE %s", labelText(label))
+		return r, false
+	}
+	return r, true
+}
+
+// labelText записывает свернутую метку CASE так, как она выглядит в программе: 1, 'a', 5..3
+func labelText(label *CaseLabel) string {
+	text := func(bound Expression) string {
+		if value, ok := constantValue(bound); ok {
+			return value.String()
+		}
+		return bound.String()
+	}
+	if label.High == nil {
+		return text(label.Low)
+	}
+	return text(label.Low) + ".." + text(label.High)
+}
+
+// assignment проверяет присваивание значения value типа t переменной name.
+// Константа вне диапазона переменной является ошибкой независимо от директивы {$R-}.
+func (c *Checker) assignment(name string, pos Position, value Expression, t *Type) {
+	symbol := c.scope.Lookup(name)
+	if symbol == nil {
+		// Неописанная переменная создается первым присваиванием
+		c.implicit(&Symbol{Name: name, Kind: SymbolVar, Type: t, Pos: pos, Implicit: true})
+		return
+	}
+	switch symbol.Kind {
+	case SymbolConst:
+		c.errorf(pos, "присваивание константе %s", name)
+		return
+	case SymbolType:
+		c.errorf(pos, "%s - имя типа, а не переменной", name)
+		return
+	case SymbolProcedure, SymbolFunction:
+		// Присваивание имени функции в ее теле задает результат
+		if symbol != c.function {
+			c.errorf(pos, "%s - имя подпрограммы, а не переменной", name)
+			return
+		}
+	}
+	if symbol.Type == nil || t == nil {
+		if symbol.Implicit && symbol.Type == nil {
+			symbol.Type = t
+		}
+		return
+	}
+	if assignable(symbol.Type, t) {
+		if constant, ok := constantValue(value); ok && !inRange(symbol.Type, constant) {
+			c.errorf(pos, "значение %s вне диапазона %s переменной %s", constant, symbol.Type.Range(), name)
+		}
+		return
+	}
+	if symbol.Implicit && assignable(t, symbol.Type) {
+		// Неописанная целая переменная, получившая вещественное значение, становится вещественной
+		symbol.Type = t
+		return
+	}
+	c.errorf(pos, "несовместимые типы: нельзя присвоить %s переменной %s типа %s", t, name, symbol.Type)
+}
+
+// targetAssignment проверяет присваивание полю записи или динамической переменной
+func (c *Checker) targetAssignment(s *Assignment, t *Type) {
+	target := c.target(s.Target)
+	if target == nil || t == nil {
+		return
+	}
+	name := designatorName(s.Target)
+	if !assignable(target, t) {
+		c.errorf(s.Pos, "несовместимые типы: нельзя присвоить %s переменной %s типа %s", t, name, target)
+		return
+	}
+	if constant, ok := constantValue(s.Value); ok && !inRange(target, constant) {
+		c.errorf(s.Pos, "значение %s вне диапазона %s переменной %s", constant, target.Range(), name)
+	}
+}
+
+// expression проверяет выражение, сворачивает его константные части и
+// возвращает новое выражение и его тип (nil, если тип неизвестен)
+func (c *Checker) expression(expr Expression) (Expression, *Type) {
+	expr, t := c.typeOf(expr)
+	if c.Info != nil {
+		c.Info.Types[expr] = t
+	}
+	return expr, t
+}
+
+// typeOf проверяет выражение для expression
+func (c *Checker) typeOf(expr Expression) (Expression, *Type) {
+	result := VisitExpression[typed](expressionChecker{c}, expr)
+	return result.expr, result.t
+}
+
+// typed - проверенное выражение после свертки констант и его тип
+type typed struct {
+	expr Expression
+	t    *Type
+}
+
+// expressionChecker проверяет выражения как ExpressionVisitor
+type expressionChecker struct {
+	*Checker
+}
+
+func (c expressionChecker) VisitNumber(e *Number) typed {
+	if e.IsReal {
+		return typed{e, realType}
+	}
+	return typed{e, integerType}
+}
+
+func (c expressionChecker) VisitLiteral(e *Literal) typed {
+	return typed{e, typeOfValue(e.Value)}
+}
+
+func (c expressionChecker) VisitIdentifier(e *Identifier) typed {
+	symbol := c.scope.Lookup(e.Name)
+	if symbol == nil && fileFunctions[strings.ToLower(e.Name)] {
+		// Eof и Eoln без скобок
+		return checked(c.fileFunction(&CallExpr{Name: e.Name, Pos: e.Pos}))
+	}
+	if symbol == nil {
+		// Неинициализированная переменная равна 0
+		return typed{e, nil}
+	}
+	c.record(e, symbol)
+	switch symbol.Kind {
+	case SymbolConst:
+		return typed{&Literal{Value: symbol.Value, Pos: e.Pos}, symbol.Type}
+	case SymbolType:
+		c.errorf(e.Pos, "%s - имя типа, а не значение", e.Name)
+		return typed{e, nil}
+	case SymbolProcedure:
+		c.errorf(e.Pos, "процедура %s не возвращает значения", e.Name)
+		return typed{e, nil}
+	case SymbolFunction:
+		// Вне тела функции ее имя без скобок - вызов без аргументов
+		if symbol != c.function && symbol.Signature != nil && len(symbol.Signature.Params) > 0 {
+			c.errorf(e.Pos, "функция %s ожидает %d аргумент(ов), получено 0", e.Name, len(symbol.Signature.Params))
+			return typed{e, nil}
+		}
+		return typed{e, baseType(symbol.Type)}
+	default:
+		return typed{e, baseType(symbol.Type)}
+	}
+}
+
+func (c expressionChecker) VisitBinaryOp(e *BinaryOp) typed             { return checked(c.binary(e)) }
+func (c expressionChecker) VisitUnaryOp(e *UnaryOp) typed               { return checked(c.unary(e)) }
+func (c expressionChecker) VisitSetConstructor(e *SetConstructor) typed { return checked(c.set(e)) }
+func (c expressionChecker) VisitCallExpr(e *CallExpr) typed             { return checked(c.call(e)) }
+func (c expressionChecker) VisitCreateExpr(e *CreateExpr) typed         { return checked(c.create(e)) }
+
+func (c expressionChecker) VisitDereference(e *Dereference) typed {
+	return typed{e, baseType(c.reference(e))}
+}
+
+func (c expressionChecker) VisitFieldAccess(e *FieldAccess) typed {
+	return typed{e, baseType(c.reference(e))}
+}
+
+// VisitFormatExpr: формат ширины и точности допустим только в параметрах Write и WriteLn,
+// которые проверяют его сами
+func (c expressionChecker) VisitFormatExpr(e *FormatExpr) typed { return c.VisitOtherExpression(e) }
+
+func (c expressionChecker) VisitOtherExpression(expr Expression) typed {
+	c.errorf(Position{}, "неизвестный тип выражения: %T", expr)
+	return typed{expr, nil}
+}
+
+// checked собирает результат проверки выражения в typed
+func checked(expr Expression, t *Type) typed {
+	return typed{expr, t}
+}
+
+// binary проверяет бинарную операцию и сворачивает ее, если оба операнда константны
+func (c *Checker) binary(e *BinaryOp) (Expression, *Type) {
+	var lt, rt *Type
+	e.Left, lt = c.expression(e.Left)
+	e.Right, rt = c.expression(e.Right)
+
+	result, err := binaryType(e.Operator, lt, rt)
+	if err != nil {
+		c.errorf(e.Pos, "%v", err)
+		return e, nil
+	}
+
+	left, lok := constantValue(e.Left)
+	right, rok := constantValue(e.Right)
+	if lok && rok {
+		// Ошибки вычисления (например, деление на ноль) остаются до выполнения
+		if value, err := c.evaluator().evaluateBinary(e, left, right); err == nil {
+			return &Literal{Value: value, Pos: e.Pos}, typeOfValue(value)
+		}
+	}
+	return e, result
+}
+
+// binaryType вычисляет тип результата бинарной операции; nil означает, что тип неизвестен
+func binaryType(op TokenType, lt, rt *Type) (*Type, error) {
+	lt, rt = baseType(lt), baseType(rt)
+	known := lt != nil && rt != nil
+	switch op {
+	case TokenEQUAL, TokenNOTEQUAL, TokenLESS, TokenLESSEQUAL, TokenGREATER, TokenGREATEREQUAL:
+		if !known {
+			return booleanType, nil
+		}
+		if lt.Kind == TypeSet || rt.Kind == TypeSet {
+			if op == TokenLESS || op == TokenGREATER {
+				return nil, fmt.Errorf("операция %s неприменима к множествам", operatorSymbol(op))
+			}
+		}
+		if lt.Kind == TypeRecord || rt.Kind == TypeRecord {
+			return nil, fmt.Errorf("операция %s неприменима к записям", operatorSymbol(op))
+		}
+		if (lt.Kind == TypePointer || rt.Kind == TypePointer) && op != TokenEQUAL && op != TokenNOTEQUAL {
+			return nil, fmt.Errorf("операция %s неприменима к указателям", operatorSymbol(op))
+		}
+		if lt.Kind == TypeText || rt.Kind == TypeText {
+			return nil, fmt.Errorf("операция %s неприменима к файлам", operatorSymbol(op))
+		}
+		if lt.Kind == TypeClass || rt.Kind == TypeClass {
+			return nil, fmt.Errorf("операция %s неприменима к исключениям", operatorSymbol(op))
+		}
+		if !sameType(lt, rt) && !(isNumeric(lt) && isNumeric(rt)) && !(isText(lt) && isText(rt)) {
+			return nil, fmt.Errorf("несравнимые типы операндов: %s и %s", lt, rt)
+		}
+		return booleanType, nil
+	case TokenIN:
+		if rt != nil && rt.Kind != TypeSet || !isOrdinal(lt) {
+			return nil, fmt.Errorf("операция IN неприменима к типам %s и %s", lt, rt)
+		}
+		if known && rt.Elem != nil && !sameType(lt, rt.Elem) {
+			return nil, fmt.Errorf("тип %s не совпадает с типом элементов множества %s", lt, rt.Elem)
+		}
+		return booleanType, nil
+	case TokenAND, TokenOR:
+		for _, t := range []*Type{lt, rt} {
+			if t != nil && t.Kind != TypeBoolean {
+				return nil, fmt.Errorf("операция %s неприменима к типу %s", operatorSymbol(op), t)
+			}
+		}
+		return booleanType, nil
+	case TokenDIV, TokenMOD:
+		for _, t := range []*Type{lt, rt} {
+			if t != nil && t.Kind != TypeInteger {
+				return nil, fmt.Errorf("операция %s неприменима к типу %s", operatorSymbol(op), t)
+			}
+		}
+		return integerType, nil
+	}
+
+	if lt != nil && lt.Kind == TypeSet || rt != nil && rt.Kind == TypeSet {
+		if op == TokenDIVIDE {
+			return nil, fmt.Errorf("операция / неприменима к множествам")
+		}
+		if !known {
+			return nil, nil
+		}
+		if !sameType(lt, rt) {
+			return nil, fmt.Errorf("несовместимые типы операндов: %s и %s", lt, rt)
+		}
+		if lt.Elem == nil {
+			return rt, nil
+		}
+		return lt, nil
+	}
+	if isText(lt) || isText(rt) {
+		// Сложение строк и символов - конкатенация
+		if op != TokenPLUS {
+			return nil, fmt.Errorf("операция %s неприменима к строкам", operatorSymbol(op))
+		}
+		if lt != nil && !isText(lt) || rt != nil && !isText(rt) {
+			return nil, fmt.Errorf("несовместимые типы операндов: %s и %s", lt, rt)
+		}
+		return stringType, nil
+	}
+	for _, t := range []*Type{lt, rt} {
+		if !isNumeric(t) {
+			return nil, fmt.Errorf("арифметическая операция неприменима к типу %s", t)
+		}
+	}
+	switch {
+	case !known:
+		return nil, nil
+	case lt.Kind == TypeInteger && rt.Kind == TypeInteger && op != TokenDIVIDE:
+		return integerType, nil
+	default:
+		return realType, nil
+	}
+}
+
+// unary проверяет операцию NOT и сворачивает ее над константой
+func (c *Checker) unary(e *UnaryOp) (Expression, *Type) {
+	var t *Type
+	e.Operand, t = c.expression(e.Operand)
+	if t != nil && t.Kind != TypeBoolean {
+		c.errorf(e.Pos, "операция %s неприменима к типу %s", operatorSymbol(e.Operator), t)
+		return e, nil
+	}
+	if operand, ok := constantValue(e.Operand); ok {
+		if value, err := c.evaluator().evaluateUnary(e, operand); err == nil {
+			return &Literal{Value: value, Pos: e.Pos}, booleanType
+		}
+	}
+	return e, booleanType
+}
+
+// set проверяет конструктор множества: элементы должны быть одного порядкового типа.
+// Конструктор из констант сворачивается в значение множества.
+func (c *Checker) set(e *SetConstructor) (Expression, *Type) {
+	var elem *Type
+	constant := true
+	for _, element := range e.Elements {
+		bounds := []*Expression{&element.Low}
+		if element.High != nil {
+			bounds = append(bounds, &element.High)
+		}
+		for _, bound := range bounds {
+			var t *Type
+			*bound, t = c.expression(*bound)
+			if _, ok := constantValue(*bound); !ok {
+				constant = false
+			}
+			switch {
+			case t == nil:
+			case !isOrdinal(t):
+				c.errorf(e.Pos, "элемент множества должен быть порядкового типа, получено %s", t)
+				return e, nil
+			case elem == nil:
+				elem = baseType(t)
+			case !sameType(elem, t):
+				c.errorf(e.Pos, "элементы множества имеют разные типы %s и %s", elem, t)
+				return e, nil
+			}
+		}
+	}
+
+	if constant {
+		value, err := c.evaluator().evaluateSet(e)
+		if err != nil {
+			c.errorf(e.Pos, "%v", err)
+			return e, nil
+		}
+		return &Literal{Value: value, Pos: e.Pos}, typeOfValue(value)
+	}
+	if elem == nil && len(e.Elements) > 0 {
+		return e, nil
+	}
+	return e, &Type{Kind: TypeSet, Elem: elem}
+}
+
+// call проверяет вызов функции программы или стандартной функции; вызов стандартной
+// функции с константными аргументами сворачивается
+func (c *Checker) call(e *CallExpr) (Expression, *Type) {
+	if symbol := c.scope.Lookup(e.Name); symbol != nil && symbol.isRoutine() {
+		c.record(e, symbol)
+		c.arguments(symbol, e.Args, e.Pos)
+		if symbol.Kind == SymbolProcedure {
+			c.errorf(e.Pos, "процедура %s не возвращает значения", e.Name)
+			return e, nil
+		}
+		return e, baseType(symbol.Type)
+	}
+	if fileFunctions[strings.ToLower(e.Name)] {
+		return c.fileFunction(e)
+	}
+	argTypes := make([]*Type, len(e.Args))
+	for n, arg := range e.Args {
+		e.Args[n], argTypes[n] = c.expression(arg)
+	}
+
+	fn, ok := lookupBuiltin(e.Name, c.BigInt)
+	if !ok {
+		c.errorf(e.Pos, "неизвестная функция %s", e.Name)
+		return e, nil
+	}
+	if len(e.Args) != fn.arity {
+		c.errorf(e.Pos, "функция %s ожидает %d аргумент(ов), получено %d", e.Name, fn.arity, len(e.Args))
+		return e, nil
+	}
+	result, err := fn.result(argTypes)
+	if err != nil {
+		c.errorf(e.Pos, "%s: %v", e.Name, err)
+		return e, nil
+	}
+
+	args := make([]Value, len(e.Args))
+	for n, arg := range e.Args {
+		value, ok := constantValue(arg)
+		if !ok {
+			return e, result
+		}
+		args[n] = value
+	}
+	if value, err := fn.call(args); err == nil {
+		return &Literal{Value: value, Pos: e.Pos}, typeOfValue(value)
+	}
+	return e, result
+}
+
+// constantValue возвращает значение выражения, если оно известно при компиляции
+func constantValue(expr Expression) (Value, bool) {
+	switch e := expr.(type) {
+	case *Number:
+		if e.IsReal {
+			return RealValue(e.Value), true
+		}
+		return IntegerValue(e.IntValue()), true
+	case *Literal:
+		return e.Value, true
+	default:
+		return nil, false
+	}
+}
+
+// create проверяет создание исключения Класс.Create(сообщение)
+func (c *Checker) create(e *CreateExpr) (Expression, *Type) {
+	argTypes := make([]*Type, len(e.Args))
+	for n, arg := range e.Args {
+		e.Args[n], argTypes[n] = c.expression(arg)
+	}
+	class := c.lookupType(e.Class)
+	if class == nil || class.Kind != TypeClass {
+		c.errorf(e.Pos, "%s не является классом исключения", e.Class)
+		return e, nil
+	}
+	if len(e.Args) != 1 {
+		c.errorf(e.Pos, "%s.Create ожидает 1 аргумент(ов), получено %d", e.Class, len(e.Args))
+	} else if argTypes[0] != nil && !isText(argTypes[0]) {
+		c.errorf(e.Pos, "%s.Create: сообщение должно быть строкой, получено %s", e.Class, argTypes[0])
+	}
+	return e, class
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseCode выполняет лексический и синтаксический анализ
+func parseCode(t *testing.T, code string) *Program {
+	t.Helper()
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	program, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	return program
+}
+
+// checkCode разбирает и проверяет программу
+func checkCode(t *testing.T, code string) (*Program, error) {
+	t.Helper()
+	program := parseCode(t, code)
+	return program, NewChecker().Check(program)
+}
+
+// TestProgramHeading тестирует заголовок PROGRAM с параметрами и без
+func TestProgramHeading(t *testing.T) {
+	program := parseCode(t, `PROGRAM Hello(input, output); BEGIN x := 1 END.`)
+	if program.Name != "Hello" {
+		t.Errorf("Ожидалось имя Hello, получено %q", program.Name)
+	}
+	if len(program.Params) != 2 || program.Params[0] != "input" || program.Params[1] != "output" {
+		t.Errorf("Ожидались параметры [input output], получено %v", program.Params)
+	}
+
+	program = parseCode(t, `program Short; begin end.`)
+	if program.Name != "Short" || len(program.Params) != 0 {
+		t.Errorf("Ожидалось имя Short без параметров, получено %q %v", program.Name, program.Params)
+	}
+}
+
+// TestDeclarationSections тестирует разбор разделов CONST, TYPE и VAR
+func TestDeclarationSections(t *testing.T) {
+	program := parseCode(t, `PROGRAM P;
+CONST
+  N = 10;
+  Half = N / 2;
+TYPE
+  Count = INTEGER;
+VAR
+  a, b: Count;
+  r: REAL;
+BEGIN
+END.`)
+	if len(program.Consts) != 2 || program.Consts[1].Name != "Half" {
+		t.Errorf("Ожидалось 2 константы, получено %v", program.Consts)
+	}
+	if len(program.Types) != 1 || program.Types[0].Name != "Count" {
+		t.Errorf("Ожидался 1 тип, получено %v", program.Types)
+	}
+	if len(program.Vars) != 3 {
+		t.Fatalf("Ожидалось 3 переменные, получено %d", len(program.Vars))
+	}
+	if program.Vars[1].Name != "b" || program.Vars[1].Type.String() != "Count" {
+		t.Errorf("Ожидалась переменная b: Count, получено %v", program.Vars[1])
+	}
+	if program.Vars[2].Pos != (Position{Line: 9, Column: 3}) {
+		t.Errorf("Неверная позиция описания r: %v", program.Vars[2].Pos)
+	}
+}
+
+// TestDeclarationErrors тестирует синтаксические ошибки в описаниях
+func TestDeclarationErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`PROGRAM; BEGIN END.`, "имя программы"},
+		{`PROGRAM P BEGIN END.`, "';'"},
+		{`PROGRAM P(input; BEGIN END.`, "скобка"},
+		{`PROGRAM P(; BEGIN END.`, "идентификатор"},
+		{`VAR x: INTEGER; CONST N = 1; BEGIN END.`, "порядок"},
+		{`VAR x: INTEGER; VAR y: INTEGER; BEGIN END.`, "порядок"},
+		{`CONST N 1; BEGIN END.`, "'='"},
+		{`CONST N = ; BEGIN END.`, "неожиданный токен"},
+		{`CONST N = 1 BEGIN END.`, "';'"},
+		{`CONST = 1; BEGIN END.`, "имя константы"},
+		{`TYPE T INTEGER; BEGIN END.`, "'='"},
+		{`TYPE T = ; BEGIN END.`, "тип"},
+		{`TYPE T = INTEGER BEGIN END.`, "';'"},
+		{`TYPE = INTEGER; BEGIN END.`, "имя типа"},
+		{`VAR x INTEGER; BEGIN END.`, "':'"},
+		{`VAR x, : INTEGER; BEGIN END.`, "имя переменной"},
+		{`VAR x: ; BEGIN END.`, "тип"},
+		{`VAR x: INTEGER BEGIN END.`, "';'"},
+	}
+	for _, tt := range tests {
+		tokens, err := NewLexer(tt.code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", tt.code, err)
+		}
+		_, err = NewParser(tokens).Parse()
+		if err == nil {
+			t.Errorf("%q: ожидалась ошибка", tt.code)
+		} else if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка с %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestLexerKeywordsAndComments тестирует ключевые слова в любом регистре и комментарии
+func TestLexerKeywordsAndComments(t *testing.T) {
+	code := "program { комментарий } Var (* еще\nкомментарий *) x_1 : = // до конца строки\nConSt"
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	want := []TokenType{TokenPROGRAM, TokenVAR, TokenIDENTIFIER, TokenASSIGN, TokenCONST, TokenEOF}
+	if len(tokens) != len(want) {
+		t.Fatalf("Ожидалось %d токенов, получено %d: %v", len(want), len(tokens), tokens)
+	}
+	for n, tt := range want {
+		if tokens[n].Type != tt {
+			t.Errorf("Токен %d: ожидался тип %v, получен %v", n, tt, tokens[n].Type)
+		}
+	}
+	if tokens[2].Value != "x_1" {
+		t.Errorf("Ожидался идентификатор x_1, получен %q", tokens[2].Value)
+	}
+
+	for _, code := range []string{"{ без конца", "(* без конца", "(*)"} {
+		if _, err := NewLexer(code).Tokenize(); err == nil {
+			t.Errorf("%q: ожидалась ошибка незакрытого комментария", code)
+		}
+	}
+	if _, err := NewLexer("x // комментарий в конце").Tokenize(); err != nil {
+		t.Errorf("Комментарий // в конце файла: %v", err)
+	}
+}
+
+// TestInterpretDeclarations тестирует выполнение программы с описаниями
+func TestInterpretDeclarations(t *testing.T) {
+	values := mustInterpret(t, `Program Circle;
+Const
+  R = 10;
+  Diameter = R * 2;
+Type
+  Length = Real;
+Var
+  d: Length;
+  count: Integer;
+  flag: Boolean;
+  unused: Real;
+Begin
+  d := Diameter;
+  COUNT := r + 1;
+  flag := Odd(Count)
+End.`)
+	want := map[string]Value{
+		"d":      RealValue(20),
+		"count":  IntegerValue(11),
+		"flag":   BooleanValue(true),
+		"unused": RealValue(0),
+	}
+	if len(values) != len(want) {
+		t.Errorf("Ожидалось %d переменных (без констант), получено %v", len(want), values)
+	}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s: ожидалось %v (%T), получено %v (%T)", name, value, value, values[name], values[name])
+		}
+	}
+	if _, ok := values["d"].(RealValue); !ok {
+		t.Errorf("d: целое значение должно быть приведено к REAL, получено %T", values["d"])
+	}
+}
+
+// TestIdentifiersCaseInsensitive тестирует, что регистр букв в именах не различается
+func TestIdentifiersCaseInsensitive(t *testing.T) {
+	values := mustInterpret(t, `BEGIN Total := 1; TOTAL := total + 1; x := MAXINT - maxint + TRUE_COUNT END.`)
+	if len(values) != 2 || values["Total"] != IntegerValue(2) || values["x"] != IntegerValue(0) {
+		t.Errorf("Ожидалось {Total: 2, x: 0}, получено %v", values)
+	}
+}
+
+// TestInterpreterDeclarationErrors тестирует ошибки описаний при выполнении без проверки
+func TestInterpreterDeclarationErrors(t *testing.T) {
+	for _, code := range []string{
+		`CONST N = 1; BEGIN N := 2 END.`,
+		`BEGIN TRUE := 2 END.`,
+		`TYPE T = INTEGER; BEGIN T := 2 END.`,
+		`TYPE T = INTEGER; BEGIN x := T END.`,
+		`VAR b: BOOLEAN; BEGIN b := 1 END.`,
+		`VAR i: INTEGER; BEGIN i := 2.5 END.`,
+		`VAR x: Unknown; BEGIN END.`,
+		`TYPE T = Unknown; BEGIN END.`,
+		`TYPE T = INTEGER; T = REAL; BEGIN END.`,
+		`VAR x: INTEGER; x: REAL; BEGIN END.`,
+		`CONST N = 1; VAR n: INTEGER; BEGIN END.`,
+		`CONST N = 1 / 0; BEGIN END.`,
+	} {
+		if _, err := interpretCode(t, code); err == nil {
+			t.Errorf("%q: ожидалась ошибка выполнения", code)
+		}
+	}
+}
+
+// TestCheckerConstantsReadOnly тестирует запрет присваивания константам
+func TestCheckerConstantsReadOnly(t *testing.T) {
+	for _, code := range []string{
+		`CONST N = 1; BEGIN N := 2 END.`,
+		`CONST N = 1; BEGIN BEGIN n := 2 END END.`,
+		`BEGIN True := FALSE END.`,
+		`BEGIN MaxInt := 1 END.`,
+	} {
+		_, err := checkCode(t, code)
+		if err == nil || !strings.Contains(err.Error(), "присваивание константе") {
+			t.Errorf("%q: ожидалась ошибка присваивания константе, получено %v", code, err)
+		}
+	}
+}
+
+// TestCheckerConstantFolding тестирует свертку констант в выражения
+func TestCheckerConstantFolding(t *testing.T) {
+	program, err := checkCode(t, `CONST
+  N = 10;
+  M = N * 2 + Sqr(3);
+  Flag = Odd(N);
+BEGIN
+  x := M - 1;
+  y := x + N;
+  z := 1 / 0
+END.`)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+
+	literal, ok := program.Consts[1].Value.(*Literal)
+	if !ok || literal.Value != IntegerValue(29) {
+		t.Errorf("M: ожидалась свертка в 29, получено %v", program.Consts[1].Value)
+	}
+	if literal, ok := program.Consts[2].Value.(*Literal); !ok || literal.Value != BooleanValue(false) {
+		t.Errorf("Flag: ожидалась свертка в FALSE, получено %v", program.Consts[2].Value)
+	}
+
+	x := program.Statements[0].(*Assignment)
+	if literal, ok := x.Value.(*Literal); !ok || literal.Value != IntegerValue(28) {
+		t.Errorf("x: ожидалась свертка в 28, получено %v", x.Value)
+	}
+
+	// Переменная не сворачивается, но константа в выражении заменяется значением
+	y := program.Statements[1].(*Assignment).Value.(*BinaryOp)
+	if literal, ok := y.Right.(*Literal); !ok || literal.Value != IntegerValue(10) {
+		t.Errorf("y: ожидалась подстановка N = 10, получено %v", y.Right)
+	}
+
+	// Деление на ноль не сворачивается и остается ошибкой выполнения
+	if _, ok := program.Statements[2].(*Assignment).Value.(*BinaryOp); !ok {
+		t.Errorf("z: деление на ноль не должно сворачиваться")
+	}
+	err = NewInterpreter().Interpret(program)
+	if err == nil || err.Error() != "деление на ноль" {
+		t.Errorf("Ожидалась ошибка деления на ноль, получено %v", err)
+	}
+}
+
+// TestCheckerErrors тестирует ошибки семантического анализа
+func TestCheckerErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`CONST N = x; BEGIN END.`, "константным выражением"},
+		{`CONST N = 1 / 0; BEGIN END.`, "деление на ноль"},
+		{`CONST N = 1; N = 2; BEGIN END.`, "повторное описание"},
+		{`VAR x: INTEGER; x: REAL; BEGIN END.`, "повторное описание"},
+		{`TYPE T = INTEGER; T = REAL; BEGIN END.`, "повторное описание T (имя уже описано как тип в позиции строка 1, столбец 6)"},
+		{`VAR x: Foo; BEGIN END.`, "неизвестный тип Foo"},
+		{`TYPE T = Foo; BEGIN END.`, "неизвестный тип Foo"},
+		{`TYPE T = INTEGER; BEGIN T := 1 END.`, "имя типа"},
+		{`BEGIN x := REAL END.`, "имя типа"},
+		{`VAR b: BOOLEAN; BEGIN b := 1.5 END.`, "несовместимые типы"},
+		{`VAR i: INTEGER; BEGIN i := 7 / 2 END.`, "несовместимые типы"},
+		{`BEGIN x := 1; x := Odd(1) END.`, "несовместимые типы"},
+		{`BEGIN x := Foo(1) END.`, "неизвестная функция"},
+		{`BEGIN x := Abs(1, 2) END.`, "ожидает 1"},
+		{`BEGIN x := Odd(1.5) END.`, "целый аргумент"},
+		{`BEGIN x := Sqrt(TRUE) END.`, "числовой аргумент"},
+		{`BEGIN x := TRUE + 1 END.`, "арифметическая операция"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil {
+			t.Errorf("%q: ожидалась ошибка", tt.code)
+		} else if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка с %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// Проверяются все ошибки, а не только первая
+	checker := NewChecker()
+	if err := checker.Check(parseCode(t, `CONST N = 1; BEGIN N := 1; x := Foo(2) END.`)); err == nil {
+		t.Fatal("Ожидались ошибки")
+	}
+	if len(checker.Errors()) != 2 {
+		t.Errorf("Ожидалось 2 ошибки, получено %v", checker.Errors())
+	}
+}
+
+// TestCheckerImplicitVariables тестирует вывод типов неописанных переменных
+func TestCheckerImplicitVariables(t *testing.T) {
+	checker := NewChecker()
+	if err := checker.Check(parseCode(t, `BEGIN x := 1; x := x / 2; y := z; b := Odd(1) END.`)); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	tests := map[string]*Type{"x": realType, "y": nil, "b": booleanType}
+	for name, want := range tests {
+		symbol := checker.Scope().Lookup(name)
+		if symbol == nil || !symbol.Implicit || symbol.Type != want {
+			t.Errorf("%s: ожидалась неописанная переменная типа %v, получено %+v", name, want, symbol)
+		}
+	}
+}
+
+// TestCheckerUnknownNodes тестирует неизвестные узлы AST
+func TestCheckerUnknownNodes(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&FakeStatement{},
+		&Assignment{Variable: "x", Value: &FakeExpression{}},
+	}}
+	checker := NewChecker()
+	if err := checker.Check(program); err == nil || len(checker.Errors()) != 2 {
+		t.Errorf("Ожидалось 2 ошибки для неизвестных узлов, получено %v", err)
+	}
+}
+
+// TestCheckerCaseLabels тестирует обнаружение повторяющихся и пересекающихся меток CASE
+func TestCheckerCaseLabels(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`BEGIN CASE x OF 1: y := 1; 1: y := 2 END END.`, "метка CASE 1 повторяется"},
+		{`BEGIN CASE x OF 1, 2: y := 1; 3, 2: y := 2 END END.`, "повторяется"},
+		{`BEGIN CASE c OF 'a': y := 1; 'a': y := 2 END END.`, "метка CASE 'a' повторяется"},
+		{`BEGIN CASE x OF 1..5: y := 1; 3: y := 2 END END.`, "метка CASE 3 пересекается с меткой 1..5"},
+		{`BEGIN CASE x OF 1..5: y := 1; 5..9: y := 2 END END.`, "пересекается"},
+		{`CONST Low = 3; BEGIN CASE x OF 3: y := 1; Low..4: y := 2 END END.`, "метка CASE 3..4 пересекается с меткой 3"},
+		{`BEGIN CASE x OF 5..1: y := 1 END END.`, "пустой диапазон меток CASE 5..1"},
+		{`BEGIN CASE x OF z: y := 1 END END.`, "должна быть константой"},
+		{`BEGIN CASE 1.5 OF 1: y := 1 END END.`, "порядкового типа"},
+		{`BEGIN CASE 1 OF TRUE: y := 1 END END.`, "не совпадает"},
+		{`BEGIN CASE x OF 1: y := 1; FALSE: y := 2 END END.`, "не совпадает"},
+		{`BEGIN CASE x OF 1.5: y := 1 END END.`, "не совпадает"},
+		{`CONST N = 1; BEGIN CASE x OF 1: N := 1 ELSE N := 2 END END.`, "присваивание константе"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil {
+			t.Errorf("%q: ожидалась ошибка", tt.code)
+		} else if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка с %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	program, err := checkCode(t, `CONST A = 1; B = A + 1; BEGIN CASE x OF A: y := 1; B..B + 2: y := 2; -1, 0: y := 3 END END.`)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	label := program.Statements[0].(*CaseStatement).Branches[1].Labels[0]
+	if literal, ok := label.High.(*Literal); !ok || literal.Value != IntegerValue(4) {
+		t.Errorf("Ожидалась свертка верхней границы в 4, получено %v", label.High)
+	}
+}
@@ -0,0 +1,816 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Continuation - образ остановленного выполнения, с которого интерпретатор продолжает
+// программу, не выполняя ее заново. Стек интерпретатора - рекурсивный обход дерева
+// программы - нельзя сохранить напрямую, поэтому продолжение запоминает путь: выполняемые
+// операторы от внешнего к внутреннему, и уже вычисленные части этих операторов - значения
+// выражений, номер итерации FOR, исход тела TRY. При продолжении интерпретатор проходит
+// путь заново: операторы вне пути уже выполнены и пропускаются, а вычисленные части
+// берутся из продолжения, поэтому по пути не выполняется ни один оператор программы
+// и не повторяется ни один побочный эффект. Переменные, куча, файлы и позиции ввода
+// и вывода восстанавливаются из продолжения, а состояние перед первым оператором
+// сверяется с State.
+//
+// Узлы программы задаются номерами при обходе программы и ее модулей (numberNodes),
+// типы и объекты - номерами в Types и Objects; номера начинаются с 1, 0 - ссылки нет.
+type Continuation struct {
+	State   *State
+	Path    []int         // выполняемые операторы от внешнего к внутреннему
+	Memo    []MemoImage   // вычисленные части выполняемых операторов в порядке вычисления
+	Types   []TypeImage   // типы значений и переменных
+	Objects []ObjectImage // записи, файлы и исключения
+	Units   []FrameImage  // глобальные переменные модулей в порядке инициализации
+	Globals FrameImage    // глобальные переменные программы
+	Stack   []FrameImage  // активации подпрограмм, начиная с первой вызванной
+	Heap    []BlockImage  // все динамические переменные, включая освобожденные
+	Open    []int         // открытые файлы в порядке открытия
+}
+
+// MemoImage - вычисленная часть выполняемого оператора
+type MemoImage struct {
+	Node    int
+	Kind    int         // memoKind
+	Value   ValueImage  // значение выражения или переменной обработчика
+	Ordinal int64       // порядковый номер текущей итерации FOR
+	Error   *ErrorImage // исключение тела TRY; nil, если тело выполнено без исключения
+	Flow    int         // передача управления из тела TRY
+}
+
+// ErrorImage - ошибка тела TRY: ошибка выполнения или внутренняя ошибка интерпретатора
+type ErrorImage struct {
+	Message string
+	Runtime bool // ошибка выполнения, которая возбуждает исключение
+	Pos     Position
+	Class   int
+	Trace   []StackFrame
+}
+
+// TypeImage - тип. Стандартный тип задается именем, а перечисление, запись и класс из
+// описания в программе - еще и узлом записи типа, чтобы после продолжения значения
+// и переменные имели тот же тип, что и описания программы.
+type TypeImage struct {
+	Kind        TypeKind
+	Name        string
+	Predeclared bool
+	Node        int
+	Values      []string
+	Base        int
+	Low, High   int64
+	Elem        int
+	Fields      []FieldImage
+	Parent      int
+}
+
+// FieldImage - поле записи
+type FieldImage struct {
+	Name string
+	Type int
+}
+
+// ObjectImage - запись, файл или исключение. Объект записывается один раз, а значения
+// ссылаются на него, поэтому общие объекты остаются общими и после продолжения.
+type ObjectImage struct {
+	Kind   ValueKind
+	Type   int          // тип записи или класс исключения
+	Fields []ValueImage // поля записи
+	Text   string       // имя файла или сообщение исключения
+	Mode   int          // fileMode
+	Data   []byte       // непрочитанная часть файла, открытого для чтения, или записанное в файл
+	Offset int64        // число байтов, прочитанных из файла
+}
+
+// ValueImage - значение; Kind определяет, какие поля заданы
+type ValueImage struct {
+	Kind   ValueKind // noValue - значения нет
+	Int    int64     // целое, логическое, символ, порядковый номер перечисления или адрес
+	Real   float64
+	Text   string // строка или длинное целое в десятичной записи
+	Type   int    // тип перечисления, элементов множества или динамической переменной
+	Bits   [(maxSetOrdinal + 1) / 64]uint64
+	Object int // запись, файл или исключение; 0 - исключение NIL
+}
+
+// noValue - вид отсутствующего значения, например освобожденной динамической переменной
+const noValue ValueKind = -1
+
+// FrameImage - переменные области видимости, кроме констант и VAR-параметров
+type FrameImage struct {
+	Name      string
+	Variables []VariableImage
+}
+
+// VariableImage - переменная; Type равен 0 для неописанной переменной
+type VariableImage struct {
+	Name  string
+	Type  int
+	Value ValueImage
+}
+
+// BlockImage - динамическая переменная; адрес - номер в Continuation.Heap
+type BlockImage struct {
+	Type                int
+	Value               ValueImage
+	Allocated, Disposed Position
+	Freed               bool
+}
+
+// memoKind - вид вычисленной части оператора
+type memoKind int
+
+const (
+	memoValue   memoKind = iota // значение выражения
+	memoOrdinal                 // порядковый номер текущей итерации FOR
+	memoOutcome                 // исход тела TRY: исключение и передача управления
+	memoHandler                 // переменная обработчика исключения ON E: Класс
+	memoRead                    // аргумент Read, значение которого уже прочитано
+)
+
+// memoEntry - вычисленная часть выполняемого оператора
+// Запись создается для каждого вычисленного выражения, поэтому редкие поля вынесены в memoDetail
+type memoEntry struct {
+	node    Node
+	kind    memoKind
+	value   Value
+	ordinal int64       // только для memoOrdinal
+	detail  *memoDetail // только для memoOutcome и memoHandler
+}
+
+// memoDetail - исход тела TRY или переменная обработчика исключения
+type memoDetail struct {
+	err     error
+	flow    flow
+	handler *Variable // переменная обработчика
+	hidden  *Variable // переменная, которую скрывает переменная обработчика; nil, если ее нет
+}
+
+// remember запоминает вычисленную часть выполняемого оператора
+func (i *Interpreter) remember(entry memoEntry) {
+	if i.indivisible {
+		return
+	}
+	i.memo = append(i.memo, entry)
+}
+
+// recall при продолжении со снимка возвращает вычисленную часть оператора, если она
+// следующая в продолжении: тогда ее не нужно вычислять заново
+func (i *Interpreter) recall(node Node, kind memoKind) (memoEntry, bool) {
+	if i.resume == nil || len(i.resume.memo) == 0 {
+		return memoEntry{}, false
+	}
+	entry := i.resume.memo[0]
+	if entry.node != node || entry.kind != kind {
+		return memoEntry{}, false
+	}
+	i.resume.memo = i.resume.memo[1:]
+	return entry, true
+}
+
+// isIndivisible сообщает, что оператор неделим: это присваивание без вызовов подпрограмм,
+// поэтому выполнение не может остановиться внутри него. Вычисленные части неделимого
+// оператора не нужны продолжению со снимка и не запоминаются.
+func (i *Interpreter) isIndivisible(stmt Statement) bool {
+	s, ok := stmt.(*Assignment)
+	if !ok {
+		return false
+	}
+	indivisible, ok := i.indivisibles[s]
+	if ok {
+		return indivisible
+	}
+	indivisible = true
+	Inspect(s, func(node Node) bool {
+		switch n := node.(type) {
+		case nil, *Assignment, *Number, *Literal, *BinaryOp, *UnaryOp, *Dereference, *FieldAccess,
+			*SetConstructor, *SetElement, *FormatExpr:
+		case *Identifier:
+			// Имя подпрограммы без скобок - вызов функции без параметров
+			if _, call := i.routine(strings.ToLower(n.Name)); call {
+				indivisible = false
+			}
+		case *CallExpr:
+			// Стандартные подпрограммы не выполняют операторов
+			if _, call := i.routine(strings.ToLower(n.Name)); call {
+				indivisible = false
+			}
+		default:
+			indivisible = false
+		}
+		return indivisible
+	})
+	i.indivisibles[s] = indivisible
+	return indivisible
+}
+
+// numberNodes возвращает узлы программы и модулей, которые она подключает, в порядке
+// обхода; номер узла в продолжении - его место в этом порядке, начиная с 1
+func numberNodes(program *Program) []Node {
+	var nodes []Node
+	number := func(node Node) bool {
+		if node != nil {
+			nodes = append(nodes, node)
+		}
+		return true
+	}
+	Inspect(program, number)
+	for _, unit := range usedUnits(program) {
+		Inspect(unit, number)
+	}
+	return nodes
+}
+
+// imageEncoder составляет образы узлов, типов, объектов и значений продолжения
+type imageEncoder struct {
+	c       *Continuation
+	nodes   map[Node]int
+	anchors map[*Type]int // типы описаний программы и узлы их записей
+	types   map[*Type]int
+	objects map[Value]int
+	err     error
+}
+
+// continuation составляет продолжение выполнения, остановленного в состоянии state;
+// nil, если выполнение нельзя продолжить
+func (i *Interpreter) continuation(state *State) *Continuation {
+	if i.program == nil {
+		return nil
+	}
+	e := &imageEncoder{
+		c:       &Continuation{State: state},
+		nodes:   make(map[Node]int),
+		anchors: make(map[*Type]int),
+		types:   make(map[*Type]int),
+		objects: make(map[Value]int),
+	}
+	for n, node := range numberNodes(i.program) {
+		if _, ok := e.nodes[node]; !ok {
+			e.nodes[node] = n + 1
+		}
+		if enum, ok := node.(*EnumType); ok && enum.resolved != nil {
+			e.anchors[enum.resolved] = n + 1
+		}
+	}
+	for spec, t := range i.resolved {
+		e.anchors[t] = e.nodes[spec]
+	}
+
+	for _, stmt := range i.active {
+		e.c.Path = append(e.c.Path, e.node(stmt))
+	}
+	// Переменная обработчика скрывает одноименную переменную только до конца обработчика;
+	// при продолжении обработчик создает ее заново, поэтому в образ области видимости
+	// записывается скрытая переменная
+	hidden := make(map[*Variable]*Variable)
+	for _, entry := range i.memo {
+		image := MemoImage{Node: e.node(entry.node), Kind: int(entry.kind), Value: e.value(entry.value), Ordinal: entry.ordinal}
+		switch entry.kind {
+		case memoOutcome:
+			image.Flow = int(entry.detail.flow)
+			if entry.detail.err != nil {
+				image.Error = e.error(entry.detail.err)
+			}
+		case memoHandler:
+			image.Value = e.value(entry.detail.handler.Value)
+			hidden[entry.detail.handler] = entry.detail.hidden
+		}
+		e.c.Memo = append(e.c.Memo, image)
+	}
+	for _, unit := range i.unitGlobals {
+		e.c.Units = append(e.c.Units, e.frame(unit.Name, unit.variables, hidden))
+	}
+	e.c.Globals = e.frame(i.program.Name, i.variables, hidden)
+	var stack []FrameImage
+	for frame := i.frame; frame != nil; frame = frame.Caller {
+		stack = append(stack, e.frame(frame.Routine.Decl.Name, frame.variables, hidden))
+	}
+	for n := len(stack) - 1; n >= 0; n-- {
+		e.c.Stack = append(e.c.Stack, stack[n])
+	}
+	for _, block := range i.heap.blocks {
+		e.c.Heap = append(e.c.Heap, BlockImage{
+			Type:      e.typ(block.Type),
+			Value:     e.value(block.Value),
+			Allocated: block.Allocated,
+			Disposed:  block.Disposed,
+			Freed:     block.Freed,
+		})
+	}
+	for _, file := range i.open {
+		e.c.Open = append(e.c.Open, e.object(file))
+	}
+	if e.err != nil {
+		return nil
+	}
+	return e.c
+}
+
+// node возвращает номер узла программы
+func (e *imageEncoder) node(node Node) int {
+	n, ok := e.nodes[node]
+	if !ok && e.err == nil {
+		e.err = fmt.Errorf("узел %s не входит в программу", node)
+	}
+	return n
+}
+
+// frame составляет образ переменных области видимости; hidden - переменные, скрытые
+// переменными обработчиков
+func (e *imageEncoder) frame(name string, variables map[string]*Variable, hidden map[*Variable]*Variable) FrameImage {
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	image := FrameImage{Name: name}
+	for _, key := range keys {
+		variable := variables[key]
+		for variable != nil {
+			outer, ok := hidden[variable]
+			if !ok {
+				break
+			}
+			variable = outer
+		}
+		if variable == nil || variable.Const || variable.ref != nil {
+			continue
+		}
+		image.Variables = append(image.Variables, VariableImage{Name: variable.Name, Type: e.typ(variable.Type), Value: e.value(variable.Value)})
+	}
+	return image
+}
+
+// typ возвращает номер образа типа
+func (e *imageEncoder) typ(t *Type) int {
+	if t == nil {
+		return 0
+	}
+	if n, ok := e.types[t]; ok {
+		return n
+	}
+	// Номер назначается до составляющих типа: запись может ссылаться на себя через указатель
+	e.c.Types = append(e.c.Types, TypeImage{})
+	n := len(e.c.Types)
+	e.types[t] = n
+	image := TypeImage{Kind: t.Kind, Name: t.Name}
+	if predeclaredTypes[strings.ToLower(t.Name)] == t {
+		image.Predeclared = true
+	} else {
+		image.Node = e.anchors[t]
+		image.Values = t.Values
+		image.Base = e.typ(t.Base)
+		image.Low, image.High = t.Low, t.High
+		image.Elem = e.typ(t.Elem)
+		for _, field := range t.Fields {
+			image.Fields = append(image.Fields, FieldImage{Name: field.Name, Type: e.typ(field.Type)})
+		}
+		image.Parent = e.typ(t.Parent)
+	}
+	e.c.Types[n-1] = image
+	return n
+}
+
+// value возвращает образ значения
+func (e *imageEncoder) value(value Value) ValueImage {
+	switch v := value.(type) {
+	case nil:
+		return ValueImage{Kind: noValue}
+	case IntegerValue:
+		return ValueImage{Kind: KindInteger, Int: int64(v)}
+	case BigIntValue:
+		return ValueImage{Kind: KindInteger, Text: v.Int.String()}
+	case RealValue:
+		return ValueImage{Kind: KindReal, Real: float64(v)}
+	case BooleanValue:
+		image := ValueImage{Kind: KindBoolean}
+		if v {
+			image.Int = 1
+		}
+		return image
+	case EnumValue:
+		return ValueImage{Kind: KindEnum, Type: e.typ(v.Type), Int: v.Ordinal}
+	case CharValue:
+		return ValueImage{Kind: KindChar, Int: int64(v)}
+	case StringValue:
+		return ValueImage{Kind: KindString, Text: string(v)}
+	case SetValue:
+		return ValueImage{Kind: KindSet, Type: e.typ(v.Elem), Bits: v.Bits}
+	case PointerValue:
+		return ValueImage{Kind: KindPointer, Type: e.typ(v.Elem), Int: int64(v.Addr)}
+	case *ExceptionValue:
+		if v == nil {
+			return ValueImage{Kind: KindException}
+		}
+		return ValueImage{Kind: KindException, Object: e.object(v)}
+	case *RecordValue, *FileValue:
+		return ValueImage{Kind: v.Kind(), Object: e.object(v)}
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("значение %s нельзя записать в снимок", value)
+		}
+		return ValueImage{Kind: noValue}
+	}
+}
+
+// object возвращает номер образа записи, файла или исключения
+func (e *imageEncoder) object(value Value) int {
+	if n, ok := e.objects[value]; ok {
+		return n
+	}
+	e.c.Objects = append(e.c.Objects, ObjectImage{})
+	n := len(e.c.Objects)
+	e.objects[value] = n
+	image := ObjectImage{Kind: value.Kind()}
+	switch v := value.(type) {
+	case *RecordValue:
+		image.Type = e.typ(v.Type)
+		for _, field := range v.Fields {
+			image.Fields = append(image.Fields, e.value(field))
+		}
+	case *FileValue:
+		image.Text, image.Mode = v.Name, int(v.mode)
+		switch v.mode {
+		case fileReading:
+			image.Data, image.Offset = v.reader.unread(), v.reader.offset()
+		case fileWriting:
+			image.Data = bytes.Clone(v.writer.Bytes())
+		}
+	case *ExceptionValue:
+		image.Type, image.Text = e.typ(v.Class), v.Message
+	}
+	e.c.Objects[n-1] = image
+	return n
+}
+
+// error возвращает образ ошибки тела TRY
+func (e *imageEncoder) error(err error) *ErrorImage {
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		return &ErrorImage{Message: err.Error()}
+	}
+	return &ErrorImage{
+		Message: runtimeErr.Message,
+		Runtime: true,
+		Pos:     runtimeErr.Pos,
+		Class:   e.typ(runtimeErr.Class),
+		Trace:   runtimeErr.Trace,
+	}
+}
+
+// resumption - продолжение со снимка, путь которого интерпретатор еще проходит
+type resumption struct {
+	state  *State
+	path   []Statement                 // еще не пройденные операторы пути
+	memo   []memoEntry                 // еще не использованные вычисленные части операторов
+	scopes [scopeKinds][]restoredScope // еще не восстановленные области видимости
+}
+
+// Виды областей видимости: при продолжении каждая восстанавливается, когда создана
+// и ее раздел описаний выполнен, в том же порядке, в котором они создавались
+const (
+	scopeUnit = iota
+	scopeGlobals
+	scopeRoutine
+	scopeKinds
+)
+
+// restoredScope - переменные области видимости из продолжения
+type restoredScope struct {
+	name      string
+	variables []*Variable
+}
+
+// imageDecoder восстанавливает узлы, типы, объекты и значения из образов продолжения
+type imageDecoder struct {
+	i       *Interpreter
+	c       *Continuation
+	nodes   []Node
+	types   []*Type
+	objects []Value
+	err     error
+}
+
+// prepareResume готовит продолжение c: восстанавливает кучу, файлы, позиции ввода
+// и вывода и число выполненных операторов, а переменные откладывает до создания их
+// областей видимости
+func (i *Interpreter) prepareResume(c *Continuation) error {
+	d := &imageDecoder{i: i, c: c, nodes: numberNodes(i.program)}
+	d.decodeTypes()
+	d.decodeObjects()
+	r := &resumption{state: c.State}
+	for _, n := range c.Path {
+		stmt, ok := d.node(n).(Statement)
+		if !ok {
+			d.fail("узел %d не является оператором", n)
+		}
+		r.path = append(r.path, stmt)
+	}
+	for _, image := range c.Memo {
+		entry := memoEntry{node: d.node(image.Node), kind: memoKind(image.Kind), value: d.value(image.Value), ordinal: image.Ordinal}
+		if entry.kind == memoOutcome {
+			entry.detail = &memoDetail{flow: flow(image.Flow)}
+			if image.Error != nil {
+				entry.detail.err = d.error(image.Error)
+			}
+		}
+		r.memo = append(r.memo, entry)
+	}
+	for _, image := range c.Units {
+		r.scopes[scopeUnit] = append(r.scopes[scopeUnit], d.scope(image))
+	}
+	r.scopes[scopeGlobals] = []restoredScope{d.scope(c.Globals)}
+	for _, image := range c.Stack {
+		r.scopes[scopeRoutine] = append(r.scopes[scopeRoutine], d.scope(image))
+	}
+	var blocks []*HeapBlock
+	for n, image := range c.Heap {
+		blocks = append(blocks, &HeapBlock{
+			Addr:      n + 1,
+			Type:      d.typ(image.Type),
+			Value:     d.value(image.Value),
+			Allocated: image.Allocated,
+			Disposed:  image.Disposed,
+			Freed:     image.Freed,
+		})
+	}
+	var open []*FileValue
+	for _, n := range c.Open {
+		file, ok := d.object(KindFile, n).(*FileValue)
+		if ok && file.mode == fileClosed {
+			d.fail("файл %s не открыт", file)
+		}
+		open = append(open, file)
+	}
+	switch {
+	case c.State == nil:
+		d.fail("нет состояния")
+	case len(r.path) == 0:
+		d.fail("нет оператора, перед которым остановлено выполнение")
+	}
+	if d.err != nil {
+		return fmt.Errorf("снимок не соответствует программе: %v", d.err)
+	}
+
+	// Стандартный ввод продолжения - тот же, что у остановленного выполнения: прочитанное
+	// до остановки пропускается
+	stdin := i.input.source.r
+	if skipped, err := io.CopyN(io.Discard, stdin, c.State.Stdin); err != nil {
+		return fmt.Errorf("стандартный ввод короче, чем прочитано до остановки: %d байт вместо %d", skipped, c.State.Stdin)
+	}
+
+	i.resume = r
+	i.heap.blocks = blocks
+	i.open = open
+	i.input = resumeTextReader(stdin, c.State.Stdin)
+	i.output.n = c.State.Stdout
+	i.steps = c.State.Steps
+	return nil
+}
+
+// reenter проходит оператор stmt при продолжении со снимка и сообщает, начат ли он:
+// оператор вне пути к точке остановки уже выполнен и пропускается. Перед последним
+// оператором пути продолжение завершается, и дальше выполнение идет обычным образом.
+func (i *Interpreter) reenter(stmt Statement) (bool, error) {
+	r := i.resume
+	if r.path[0] != stmt {
+		return false, nil
+	}
+	if r.path = r.path[1:]; len(r.path) > 0 {
+		return true, nil
+	}
+	i.resume = nil
+	switch {
+	case len(r.memo) > 0:
+		i.halt = fmt.Errorf("снимок не соответствует программе: вычисленные части операторов не использованы")
+	case len(r.scopes[scopeUnit]) > 0 || len(r.scopes[scopeRoutine]) > 0 ||
+		len(r.scopes[scopeGlobals]) > 0 && len(r.scopes[scopeGlobals][0].variables) > 0:
+		i.halt = fmt.Errorf("снимок не соответствует программе: области видимости не восстановлены")
+	default:
+		i.restored = r.state
+		return true, nil
+	}
+	return false, i.halt
+}
+
+// restoreScope при продолжении со снимка восстанавливает переменные области видимости
+// вида kind, которая только что создана и описана
+func (i *Interpreter) restoreScope(kind int, name string, variables map[string]*Variable) error {
+	if i.resume == nil {
+		return nil
+	}
+	scopes := i.resume.scopes[kind]
+	if len(scopes) == 0 || scopes[0].name != name {
+		i.halt = fmt.Errorf("снимок не соответствует программе: в нем нет области видимости %s", name)
+		return i.halt
+	}
+	i.resume.scopes[kind] = scopes[1:]
+	for _, variable := range scopes[0].variables {
+		key := strings.ToLower(variable.Name)
+		current, ok := variables[key]
+		switch {
+		case !ok:
+			variables[key] = variable
+		case current.Const:
+			i.halt = fmt.Errorf("снимок не соответствует программе: %s - константа", current.Name)
+			return i.halt
+		case current.ref == nil:
+			current.Value = variable.Value
+		}
+	}
+	return nil
+}
+
+// fail запоминает первую ошибку восстановления
+func (d *imageDecoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+// node возвращает узел программы по номеру
+func (d *imageDecoder) node(n int) Node {
+	if n < 1 || n > len(d.nodes) {
+		d.fail("нет узла %d", n)
+		return nil
+	}
+	return d.nodes[n-1]
+}
+
+// typ возвращает тип по номеру образа
+func (d *imageDecoder) typ(n int) *Type {
+	if n == 0 {
+		return nil
+	}
+	if n < 0 || n > len(d.types) {
+		d.fail("нет типа %d", n)
+		return nil
+	}
+	return d.types[n-1]
+}
+
+// decodeTypes восстанавливает типы в два прохода: сначала создаются сами типы,
+// затем заполняются их составляющие, которые могут ссылаться на любой тип
+func (d *imageDecoder) decodeTypes() {
+	d.types = make([]*Type, len(d.c.Types))
+	for n, image := range d.c.Types {
+		switch {
+		case image.Predeclared:
+			if d.types[n] = predeclaredTypes[strings.ToLower(image.Name)]; d.types[n] == nil {
+				d.fail("нет стандартного типа %s", image.Name)
+			}
+		case image.Node != 0:
+			switch spec := d.node(image.Node).(type) {
+			case *EnumType:
+				if spec.resolved == nil {
+					spec.resolved = &Type{Kind: TypeEnum, Values: spec.Names}
+				}
+				d.types[n] = spec.resolved
+			case *RecordType:
+				d.types[n] = &Type{Kind: TypeRecord}
+				d.i.resolved[spec] = d.types[n]
+			case *ClassType:
+				d.types[n] = &Type{Kind: TypeClass}
+				d.i.resolved[spec] = d.types[n]
+			}
+			if d.types[n] == nil || d.types[n].Kind != image.Kind {
+				d.fail("тип %s не соответствует описанию в программе", image.Name)
+			}
+		default:
+			d.types[n] = &Type{}
+		}
+	}
+	for n, image := range d.c.Types {
+		t := d.types[n]
+		if t == nil || image.Predeclared {
+			continue
+		}
+		t.Kind, t.Name = image.Kind, image.Name
+		if image.Kind != TypeEnum {
+			t.Values = image.Values
+		}
+		t.Base = d.typ(image.Base)
+		t.Low, t.High = image.Low, image.High
+		t.Elem = d.typ(image.Elem)
+		t.Fields = nil
+		for _, field := range image.Fields {
+			t.Fields = append(t.Fields, Field{Name: field.Name, Type: d.typ(field.Type)})
+		}
+		t.Parent = d.typ(image.Parent)
+	}
+}
+
+// decodeObjects восстанавливает записи, файлы и исключения в два прохода, как типы
+func (d *imageDecoder) decodeObjects() {
+	d.objects = make([]Value, len(d.c.Objects))
+	for n, image := range d.c.Objects {
+		switch image.Kind {
+		case KindRecord:
+			d.objects[n] = &RecordValue{}
+		case KindFile:
+			d.objects[n] = &FileValue{}
+		case KindException:
+			d.objects[n] = &ExceptionValue{}
+		default:
+			d.fail("объект %d вида %s", n+1, image.Kind)
+		}
+	}
+	for n, image := range d.c.Objects {
+		switch v := d.objects[n].(type) {
+		case *RecordValue:
+			v.Type = d.typ(image.Type)
+			if v.Type == nil || v.Type.Kind != TypeRecord || len(v.Type.Fields) != len(image.Fields) {
+				d.fail("запись %d не соответствует своему типу", n+1)
+				continue
+			}
+			for _, field := range image.Fields {
+				v.Fields = append(v.Fields, d.value(field))
+			}
+		case *FileValue:
+			v.Name, v.mode = image.Text, fileMode(image.Mode)
+			switch v.mode {
+			case fileReading:
+				v.reader = resumeTextReader(bytes.NewReader(image.Data), image.Offset)
+			case fileWriting:
+				v.writer = bytes.NewBuffer(image.Data)
+			}
+		case *ExceptionValue:
+			v.Class, v.Message = d.typ(image.Type), image.Text
+			if v.Class == nil || v.Class.Kind != TypeClass {
+				d.fail("исключение %d без класса", n+1)
+			}
+		}
+	}
+}
+
+// object возвращает запись, файл или исключение вида kind по номеру образа
+func (d *imageDecoder) object(kind ValueKind, n int) Value {
+	if n == 0 && kind == KindException {
+		return (*ExceptionValue)(nil)
+	}
+	if n < 1 || n > len(d.objects) || d.objects[n-1] == nil || d.objects[n-1].Kind() != kind {
+		d.fail("нет объекта %d вида %s", n, kind)
+		return nil
+	}
+	return d.objects[n-1]
+}
+
+// value восстанавливает значение
+func (d *imageDecoder) value(image ValueImage) Value {
+	switch image.Kind {
+	case noValue:
+		return nil
+	case KindInteger:
+		if image.Text == "" {
+			return IntegerValue(image.Int)
+		}
+		n, ok := new(big.Int).SetString(image.Text, 10)
+		if !ok {
+			d.fail("неверное длинное целое %q", image.Text)
+		}
+		return BigIntValue{Int: n}
+	case KindReal:
+		return RealValue(image.Real)
+	case KindBoolean:
+		return BooleanValue(image.Int != 0)
+	case KindEnum:
+		return EnumValue{Type: d.typ(image.Type), Ordinal: image.Int}
+	case KindChar:
+		return CharValue(rune(image.Int))
+	case KindString:
+		return StringValue(image.Text)
+	case KindSet:
+		return SetValue{Elem: d.typ(image.Type), Bits: image.Bits}
+	case KindPointer:
+		return PointerValue{Elem: d.typ(image.Type), Addr: int(image.Int)}
+	case KindRecord, KindFile, KindException:
+		return d.object(image.Kind, image.Object)
+	default:
+		d.fail("значение вида %s", image.Kind)
+		return nil
+	}
+}
+
+// scope восстанавливает переменные области видимости
+func (d *imageDecoder) scope(image FrameImage) restoredScope {
+	scope := restoredScope{name: image.Name}
+	for _, variable := range image.Variables {
+		scope.variables = append(scope.variables, &Variable{Name: variable.Name, Type: d.typ(variable.Type), Value: d.value(variable.Value)})
+	}
+	return scope
+}
+
+// error восстанавливает ошибку тела TRY
+func (d *imageDecoder) error(image *ErrorImage) error {
+	if !image.Runtime {
+		return errors.New(image.Message)
+	}
+	return &RuntimeError{Message: image.Message, Pos: image.Pos, Class: d.typ(image.Class), Trace: image.Trace}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Dialect задает вариант языка, который принимают лексер, парсер и семантический анализатор
+type Dialect int
+
+const (
+	// DialectLenient принимает все послабления синтаксиса: знак присваивания с пробелом
+	// между ':' и '=', пропущенные ';' между операторами и ';' перед ELSE
+	DialectLenient Dialect = iota
+	// DialectTurbo запрещает послабления синтаксиса, но допускает расширения Turbo Pascal
+	// и Delphi: модули, исключения, Break, Continue и Exit, комментарии // и т. п.
+	DialectTurbo
+	// DialectISO - строгий стандарт ISO 7185: любое отклонение является ошибкой
+	DialectISO
+)
+
+// dialectNames - имена диалектов для флага -dialect
+var dialectNames = map[Dialect]string{
+	DialectLenient: "lenient",
+	DialectTurbo:   "turbo",
+	DialectISO:     "iso",
+}
+
+func (d Dialect) String() string {
+	return dialectNames[d]
+}
+
+// Set разбирает значение флага -dialect
+func (d *Dialect) Set(value string) error {
+	for dialect, name := range dialectNames {
+		if strings.EqualFold(value, name) {
+			*d = dialect
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный диалект %q: ожидался iso, turbo или lenient", value)
+}
+
+// strictSyntax сообщает, запрещены ли послабления синтаксиса
+func (d Dialect) strictSyntax() bool {
+	return d != DialectLenient
+}
+
+// isoRule - правило стандарта ISO 7185: номер пункта и синтаксис или требование
+type isoRule struct {
+	Clause string
+	Text   string
+}
+
+// Правила стандарта, нарушения которых обнаруживают лексер, парсер и семантический анализатор
+var (
+	ruleIdentifier        = isoRule{"6.1.3", `identifier = letter { letter | digit }`}
+	ruleTokenSeparators   = isoRule{"6.1.8", "внутри лексемы разделители не допускаются"}
+	ruleComment           = isoRule{"6.1.8", `комментарий записывается как "{" commentary "}" или "(*" commentary "*)"`}
+	ruleDefiningPoint     = isoRule{"6.2.2.9", "каждое имя в блоке программы должно быть описано"}
+	ruleTypeDenoter       = isoRule{"6.4.1", `type-denoter = type-identifier | new-type`}
+	ruleRequiredTypes     = isoRule{"6.4.2.2", "стандартные типы - integer, real, Boolean, char и text (п. 6.4.3.5)"}
+	ruleStatement         = isoRule{"6.8.1", `statement = [ label ":" ] ( simple-statement | structured-statement )`}
+	ruleStatementSequence = isoRule{"6.8.3.1", `statement-sequence = statement { ";" statement }`}
+	ruleIfStatement       = isoRule{"6.8.3.4", `if-statement = "if" Boolean-expression "then" statement [ else-part ]`}
+	ruleCaseStatement     = isoRule{"6.8.3.5", `case-statement = "case" case-index "of" case-list-element { ";" case-list-element } [ ";" ] "end"`}
+	ruleProgram           = isoRule{"6.10", `program = program-heading ";" program-block "."`}
+)
+
+// DialectError - отклонение от стандарта, недопустимое в выбранном диалекте
+type DialectError struct {
+	Pos     Position
+	Message string
+	Rule    isoRule
+}
+
+func (e *DialectError) Error() string {
+	return fmt.Sprintf("%s: %s (нарушено правило ISO 7185, п. %s: %s)", e.Pos, e.Message, e.Rule.Clause, e.Rule.Text)
+}
+
+// deviation создает ошибку отклонения от правила rule в позиции pos
+func deviation(rule isoRule, pos Position, format string, args ...interface{}) *DialectError {
+	return &DialectError{Pos: pos, Message: fmt.Sprintf(format, args...), Rule: rule}
+}
+
+// dialectErrors объединяет отклонения в одну ошибку в порядке их позиций; nil, если
+// отклонений нет
+func dialectErrors(deviations []*DialectError) error {
+	sorted := slices.Clone(deviations)
+	slices.SortStableFunc(sorted, func(a, b *DialectError) int { return comparePositions(a.Pos, b.Pos) })
+	errs := make([]error, len(sorted))
+	for n, deviation := range sorted {
+		errs[n] = deviation
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseDialect разбирает программу в диалекте dialect
+func parseDialect(code string, dialect Dialect) (*Program, error) {
+	lexer := NewLexer(code)
+	lexer.Dialect = dialect
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	parser := NewParser(tokens)
+	parser.Dialect = dialect
+	parser.Deviations = lexer.Deviations
+	return parser.Parse()
+}
+
+// TestDialects тестирует послабления синтаксиса и расширения в разных диалектах.
+// Пустая ошибка означает, что программа принимается.
+func TestDialects(t *testing.T) {
+	const (
+		missingSemicolon = "пропущена ';' между операторами (нарушено правило ISO 7185, п. 6.8.3.1: statement-sequence"
+		semicolonElse    = "точка с запятой перед ELSE завершает оператор IF (нарушено правило ISO 7185, п. 6.8.3.4: if-statement"
+		spacedAssign     = "знак присваивания := записан с разделителем между ':' и '=' (нарушено правило ISO 7185, п. 6.1.8: "
+		noHeading        = "программа должна начинаться с заголовка PROGRAM (нарушено правило ISO 7185, п. 6.10: program"
+	)
+	tests := []struct {
+		code    string
+		dialect Dialect
+		err     string
+	}{
+		// Послабления принимаются только в диалекте lenient
+		{"BEGIN a : = 1 END.", DialectLenient, ""},
+		{"BEGIN a : = 1 END.", DialectTurbo, "строка 1, столбец 9: " + spacedAssign},
+		{"PROGRAM P;\nBEGIN a :\n= 1 END.", DialectISO, "строка 2, столбец 9: " + spacedAssign},
+		{"BEGIN a := 1 b := 2 END.", DialectLenient, ""},
+		{"BEGIN a := 1 b := 2 END.", DialectTurbo, "строка 1, столбец 14: " + missingSemicolon},
+		{"PROGRAM P;\nBEGIN\n  WHILE a < 3 DO BEGIN a := a + 1 END\n  WriteLn(a)\nEND.", DialectISO, "строка 4, столбец 3: " + missingSemicolon},
+		{"BEGIN REPEAT a := 1 IF a > 0 THEN b := 2 UNTIL TRUE END.", DialectTurbo, "строка 1, столбец 21: " + missingSemicolon},
+		{"BEGIN IF a > 0 THEN BEGIN b := 1 END; ELSE b := 2 END.", DialectLenient, ""},
+		{"BEGIN IF a > 0 THEN BEGIN b := 1 END; ELSE b := 2 END.", DialectTurbo, "строка 1, столбец 37: " + semicolonElse},
+		{"PROGRAM P;\nBEGIN\n  IF a > 0 THEN b := 1;\n  ELSE b := 2\nEND.", DialectISO, "строка 3, столбец 23: " + semicolonElse},
+		{"BEGIN CASE a OF 1: b := 1 2: b := 2 END END.", DialectLenient, ""},
+		{"BEGIN CASE a OF 1: b := 1 2: b := 2 END END.", DialectTurbo,
+			"строка 1, столбец 27: пропущена ';' между ветвями CASE (нарушено правило ISO 7185, п. 6.8.3.5: case-statement"},
+
+		// Точка с запятой перед END и UNTIL - пустой оператор, директивы не мешают разделителям
+		{"PROGRAM P;\nBEGIN\n  a := 1 {$R-};\n  REPEAT a := a + 1; UNTIL a > 3;\n  BEGIN b := a; END;\nEND.", DialectISO, ""},
+		{"BEGIN a := 1; {$R+} b := 2 END.", DialectTurbo, ""},
+
+		// Расширения Turbo Pascal и Delphi допускаются в диалекте turbo, но не в iso
+		{"// комментарий\nBEGIN my_var := 1 END.", DialectTurbo, ""},
+		{"PROGRAM P;\n// комментарий\nBEGIN END.", DialectISO,
+			"строка 2, столбец 1: комментарий // не предусмотрен стандартом (нарушено правило ISO 7185, п. 6.1.8: комментарий"},
+		{"PROGRAM P; BEGIN my_var := 1 END.", DialectISO,
+			"строка 1, столбец 18: знак подчеркивания в идентификаторе my_var (нарушено правило ISO 7185, п. 6.1.3: identifier"},
+		{"BEGIN END.", DialectISO, "строка 1, столбец 1: " + noHeading},
+		{"{$R-} BEGIN END.", DialectISO, "строка 1, столбец 7: " + noHeading},
+		{"PROGRAM P; USES Strings; BEGIN END.", DialectISO,
+			"строка 1, столбец 12: предложение USES не предусмотрено стандартом (нарушено правило ISO 7185, п. 6.10: program"},
+		{"PROGRAM P; BEGIN CASE a OF 1: b := 1; OTHERWISE b := 2 END END.", DialectISO,
+			"строка 1, столбец 39: ветвь OTHERWISE оператора CASE не предусмотрена стандартом (нарушено правило ISO 7185, п. 6.8.3.5: case-statement"},
+		{"BEGIN CASE a OF 1: b := 1; ELSE b := 2 END END.", DialectTurbo, ""},
+		{"PROGRAM P; BEGIN TRY a := 1 FINALLY a := 2 END END.", DialectISO,
+			"строка 1, столбец 18: оператор TRY не предусмотрен стандартом (нарушено правило ISO 7185, п. 6.8.1: statement"},
+		{"PROGRAM P; BEGIN RAISE Exception.Create('x') END.", DialectISO,
+			"строка 1, столбец 18: оператор RAISE не предусмотрен стандартом (нарушено правило ISO 7185, п. 6.8.1: statement"},
+		{"PROGRAM P; BEGIN WHILE TRUE DO break END.", DialectISO,
+			"строка 1, столбец 32: оператор Break не предусмотрен стандартом (нарушено правило ISO 7185, п. 6.8.1: statement"},
+		{"PROGRAM P; TYPE E = CLASS(Exception) END; BEGIN END.", DialectISO,
+			"строка 1, столбец 21: классы не предусмотрены стандартом (нарушено правило ISO 7185, п. 6.4.1: type-denoter"},
+		{"TYPE E = CLASS(Exception) END; BEGIN TRY Exit EXCEPT ON E DO RAISE END END.", DialectTurbo, ""},
+		// Процедура программы с именем Exit - обычный вызов
+		{"PROGRAM P; PROCEDURE Exit; BEGIN END; BEGIN Exit END.", DialectISO, ""},
+	}
+	for _, tt := range tests {
+		_, err := parseDialect(tt.code, tt.dialect)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s %q: неожиданная ошибка: %v", tt.dialect, tt.code, err)
+		case tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)):
+			t.Errorf("%s %q: ожидалась ошибка %q, получено %v", tt.dialect, tt.code, tt.err, err)
+		}
+	}
+}
+
+// TestDialectDeviations тестирует, что лексер и парсер сообщают обо всех отклонениях сразу
+// в порядке их позиций
+func TestDialectDeviations(t *testing.T) {
+	code := "BEGIN\n  my_a : = 1\n  TRY my_a := 2 FINALLY my_a := 3 END;\n  IF my_a > 0 THEN my_a := 4;\n  ELSE my_a := 5\nEND."
+	want := []string{
+		"строка 1, столбец 1: программа должна начинаться с заголовка PROGRAM",
+		"строка 2, столбец 3: знак подчеркивания в идентификаторе my_a",
+		"строка 2, столбец 8: знак присваивания := записан с разделителем между ':' и '='",
+		"строка 3, столбец 3: пропущена ';' между операторами",
+		"строка 3, столбец 3: оператор TRY не предусмотрен стандартом",
+		"строка 3, столбец 7: знак подчеркивания в идентификаторе my_a",
+		"строка 3, столбец 25: знак подчеркивания в идентификаторе my_a",
+		"строка 4, столбец 6: знак подчеркивания в идентификаторе my_a",
+		"строка 4, столбец 20: знак подчеркивания в идентификаторе my_a",
+		"строка 4, столбец 29: точка с запятой перед ELSE завершает оператор IF",
+		"строка 5, столбец 8: знак подчеркивания в идентификаторе my_a",
+	}
+	_, err := parseDialect(code, DialectISO)
+	if err == nil {
+		t.Fatal("ожидались отклонения от стандарта")
+	}
+	got := strings.Split(err.Error(), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("ожидалось отклонений: %d, получено %d:\n%v", len(want), len(got), err)
+	}
+	for n := range want {
+		if !strings.HasPrefix(got[n], want[n]) {
+			t.Errorf("отклонение %d: ожидалось %q, получено %q", n+1, want[n], got[n])
+		}
+	}
+
+	// Синтаксическая ошибка прекращает разбор: сообщается только она
+	if _, err := parseDialect("BEGIN my_a := END.", DialectISO); err == nil || strings.Contains(err.Error(), "ISO 7185") {
+		t.Errorf("ожидалась только синтаксическая ошибка, получено %v", err)
+	}
+}
+
+// TestCheckerDeviations тестирует отклонения, которые находит семантический анализатор:
+// неописанные переменные и нестандартные типы отмечаются только в диалекте ISO
+func TestCheckerDeviations(t *testing.T) {
+	code := "PROGRAM P;\nTYPE S = STRING;\n  R = RECORD name: String END;\nVAR p: ^string;\n  x: S;\n" +
+		"PROCEDURE Q(VAR v: INTEGER); BEGIN v := 1 END;\nBEGIN\n  a := 1;\n  Q(b);\n  ReadLn(c);\n  a := 2\nEND."
+	want := []string{
+		"строка 2, столбец 10: тип STRING не предусмотрен стандартом (нарушено правило ISO 7185, п. 6.4.2.2: ",
+		"строка 3, столбец 20: тип String не предусмотрен стандартом",
+		"строка 4, столбец 8: тип string не предусмотрен стандартом",
+		"строка 8, столбец 3: переменная a не описана (нарушено правило ISO 7185, п. 6.2.2.9: ",
+		"строка 9, столбец 5: переменная b не описана",
+		"строка 10, столбец 10: переменная c не описана",
+	}
+	program, err := parseDialect(code, DialectISO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := NewChecker()
+	checker.Dialect = DialectISO
+	err = checker.Check(program)
+	if err == nil {
+		t.Fatal("ожидались отклонения от стандарта")
+	}
+	got := strings.Split(err.Error(), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("ожидалось отклонений: %d, получено %d:\n%v", len(want), len(got), err)
+	}
+	for n := range want {
+		if !strings.HasPrefix(got[n], want[n]) {
+			t.Errorf("отклонение %d: ожидалось %q, получено %q", n+1, want[n], got[n])
+		}
+	}
+
+	// В других диалектах и для описанного пользователем типа String отклонений нет
+	if err := NewChecker().Check(program); err != nil {
+		t.Errorf("lenient: неожиданная ошибка: %v", err)
+	}
+	program, err = parseDialect("PROGRAM P;\nTYPE String = CHAR;\nVAR s: String;\nBEGIN s := 'a' END.", DialectISO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker = NewChecker()
+	checker.Dialect = DialectISO
+	if err := checker.Check(program); err != nil {
+		t.Errorf("iso: неожиданная ошибка: %v", err)
+	}
+}
+
+// TestDialectElse тестирует, что в строгих диалектах ELSE после ';' относится к CASE,
+// а не к вложенному IF
+func TestDialectElse(t *testing.T) {
+	code := "BEGIN CASE a OF 1: IF a > 1 THEN c := 1; ELSE c := 2 END END."
+	for _, dialect := range []Dialect{DialectLenient, DialectTurbo} {
+		program, err := parseDialect(code, dialect)
+		if err != nil {
+			t.Fatalf("%s: неожиданная ошибка: %v", dialect, err)
+		}
+		stmt := program.Statements[0].(*CaseStatement)
+		inner := stmt.Branches[0].Body.(*IfStatement)
+		if strict := dialect == DialectTurbo; (inner.Else == nil) != strict || (stmt.Else != nil) != strict {
+			t.Errorf("%s: ELSE отнесен не к тому оператору: %s", dialect, stmt)
+		}
+	}
+}
+
+// TestMainDialect тестирует флаг -dialect, в том числе для модулей из USES
+func TestMainDialect(t *testing.T) {
+	dir := t.TempDir()
+	writeSuite(t, dir, map[string]string{
+		"prog.pas":  "PROGRAM Prog;\nBEGIN\n  a : = 1;\n  b := a\nEND.",
+		"uses.pas":  "USES Lib;\nBEGIN Inc2(x) END.",
+		"lib.pas":   "UNIT Lib;\nINTERFACE\nPROCEDURE Inc2(VAR n: INTEGER);\nIMPLEMENTATION\nPROCEDURE Inc2(VAR n: INTEGER);\nBEGIN n := n + 1 n := n + 1 END;\nEND.",
+		"clean.pas": "PROGRAM Clean;\nVAR a: INTEGER;\nBEGIN\n  a := 1;\nEND.",
+		"loose.pas": "PROGRAM Loose;\nVAR s: STRING;\nBEGIN\n  s := 'x';\n  a := 1\nEND.",
+	})
+	prog, uses, lib, clean := filepath.Join(dir, "prog.pas"), filepath.Join(dir, "uses.pas"), filepath.Join(dir, "lib.pas"), filepath.Join(dir, "clean.pas")
+	loose := filepath.Join(dir, "loose.pas")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{prog}, 0, "{a: 1, b: 1}\n", ""},
+		{[]string{"-dialect", "lenient", uses}, 0, "{x: 2}\n", ""},
+		{[]string{"-dialect=ISO", clean}, 0, "{a: 1}\n", ""},
+		{[]string{"-dialect", "turbo", loose}, 0, "{a: 1, s: 'x'}\n", ""},
+		{[]string{"-dialect", "iso", loose}, 1, "", "ошибка семантического анализа: строка 2, столбец 8: тип STRING не предусмотрен стандартом (нарушено правило ISO 7185, п. 6.4.2.2: " +
+			"стандартные типы - integer, real, Boolean, char и text (п. 6.4.3.5))\nстрока 5, столбец 3: переменная a не описана"},
+		{[]string{"-dialect", "iso", prog}, 1, "", "ошибка синтаксического анализа: строка 3, столбец 5: знак присваивания := записан"},
+		{[]string{"-dialect", "turbo", uses}, 1, "", "ошибка загрузки модуля: " + lib + ": строка 6, столбец 18: пропущена ';' между операторами"},
+		{[]string{"-dialect", "turbo", "-dot", "-", prog}, 1, "", "ошибка синтаксического анализа: строка 3, столбец 5: "},
+		{[]string{"-dialect", "pascal", prog}, 1, "", "invalid value \"pascal\" for flag -dialect: неизвестный диалект \"pascal\": ожидался iso, turbo или lenient\n"},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code || stdout.String() != tt.stdout || !strings.HasPrefix(stderr.String(), tt.stderr) || (tt.stderr == "") != (stderr.Len() == 0) {
+			t.Errorf("%v: ожидались код %d, вывод %q, ошибки %q; получено %d, %q, %q", tt.args, tt.code, tt.stdout, tt.stderr, code, stdout.String(), stderr.String())
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext - число строк контекста вокруг изменений в unified diff
+const diffContext = 3
+
+// diffOp - строка построчной разницы: ' ' - общая, '-' - только в ожидаемом тексте,
+// '+' - только в полученном
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff возвращает построчную разницу ожидаемого и полученного текстов в формате
+// unified diff; пустая строка - тексты совпадают
+func unifiedDiff(expectedName, actualName, expected, actual string) string {
+	if expected == actual {
+		return ""
+	}
+	ops := diffLines(splitLines(expected), splitLines(actual))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", expectedName, actualName)
+
+	// before[k] - число строк ожидаемого и полученного текстов перед ops[k]
+	type position struct{ expected, actual int }
+	before := make([]position, len(ops)+1)
+	for k, op := range ops {
+		before[k+1] = before[k]
+		if op.kind != '+' {
+			before[k+1].expected++
+		}
+		if op.kind != '-' {
+			before[k+1].actual++
+		}
+	}
+
+	changed := false
+	for start := 0; start < len(ops); {
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		changed = true
+		// Фрагмент продолжается, пока общих строк между изменениями не больше двух контекстов
+		end := first + 1
+		for k := first + 1; k < len(ops) && k-end <= 2*diffContext; k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			}
+		}
+		begin, end := max(start, first-diffContext), min(len(ops), end+diffContext)
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(before[begin].expected, before[end].expected-before[begin].expected),
+			hunkRange(before[begin].actual, before[end].actual-before[begin].actual))
+		for _, op := range ops[begin:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+		}
+		start = end
+	}
+	if !changed {
+		// Строки совпадают, а тексты различаются переводом строки в конце
+		out.WriteString("\\ тексты различаются переводом строки в конце\n")
+	}
+	return out.String()
+}
+
+// hunkRange форматирует диапазон строк фрагмента: первая строка и число строк
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines разбивает текст на строки; перевод строки в конце не дает пустой строки
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines строит построчную разницу по наибольшей общей подпоследовательности строк;
+// удаленные строки предшествуют добавленным
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] - длина наибольшей общей подпоследовательности a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
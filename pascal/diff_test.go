@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUnifiedDiff тестирует построчную разницу текстов
+func TestUnifiedDiff(t *testing.T) {
+	numbers := func(from, to int, replace map[int]string) string {
+		var lines strings.Builder
+		for n := from; n <= to; n++ {
+			if line, ok := replace[n]; ok {
+				lines.WriteString(line + "\n")
+			} else {
+				lines.WriteString(strings.Repeat("x", n) + "\n")
+			}
+		}
+		return lines.String()
+	}
+	tests := []struct {
+		name             string
+		expected, actual string
+		diff             string
+	}{
+		{"совпадают", "a\nb\n", "a\nb\n", ""},
+		{"замена", "a\nb\nc\n", "a\nB\nc\n", "--- want\n+++ got\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"добавление в конец", "a\n", "a\nb\nc\n", "--- want\n+++ got\n@@ -1 +1,3 @@\n a\n+b\n+c\n"},
+		{"из пустого", "", "a\n", "--- want\n+++ got\n@@ -0,0 +1 @@\n+a\n"},
+		{"в пустой", "a\nb\n", "", "--- want\n+++ got\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{"перевод строки", "a\n", "a", "--- want\n+++ got\n\\ тексты различаются переводом строки в конце\n"},
+		// Изменения, между которыми больше шести общих строк, - отдельные фрагменты
+		{"два фрагмента", numbers(1, 12, nil), numbers(1, 12, map[int]string{2: "two", 11: "eleven"}), "--- want\n+++ got\n" +
+			"@@ -1,5 +1,5 @@\n x\n-xx\n+two\n xxx\n xxxx\n xxxxx\n" +
+			"@@ -8,5 +8,5 @@\n xxxxxxxx\n xxxxxxxxx\n xxxxxxxxxx\n-xxxxxxxxxxx\n+eleven\n xxxxxxxxxxxx\n"},
+		{"один фрагмент", numbers(1, 9, nil), numbers(1, 9, map[int]string{1: "one", 8: "eight"}), "--- want\n+++ got\n" +
+			"@@ -1,9 +1,9 @@\n-x\n+one\n xx\n xxx\n xxxx\n xxxxx\n xxxxxx\n xxxxxxx\n-xxxxxxxx\n+eight\n xxxxxxxxx\n"},
+	}
+	for _, tt := range tests {
+		if diff := unifiedDiff("want", "got", tt.expected, tt.actual); diff != tt.diff {
+			t.Errorf("%s: ожидалось\n%s\nполучено\n%s", tt.name, tt.diff, diff)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotWriter - посетитель, который записывает каждый узел AST вершиной графа DOT и
+// соединяет его дугой с родительским узлом
+type dotWriter struct {
+	w       *bufio.Writer
+	next    int   // номер следующей вершины
+	parents []int // номера вершин на пути от корня к текущему узлу
+}
+
+// Visit записывает вершину узла и дугу от родителя; Visit(nil) завершает обход дочерних узлов
+func (d *dotWriter) Visit(node Node) Visitor {
+	if node == nil {
+		d.parents = d.parents[:len(d.parents)-1]
+		return nil
+	}
+	d.next++
+	fmt.Fprintf(d.w, "\tn%d [label=%s];\n", d.next, dotQuote(nodeLabel(node)))
+	if len(d.parents) > 0 {
+		fmt.Fprintf(d.w, "\tn%d -> n%d;\n", d.parents[len(d.parents)-1], d.next)
+	}
+	d.parents = append(d.parents, d.next)
+	return d
+}
+
+// writeDOT записывает AST с корнем node в w на языке DOT программы Graphviz
+func writeDOT(w io.Writer, node Node) error {
+	d := &dotWriter{w: bufio.NewWriter(w)}
+	d.w.WriteString("digraph AST {\n\tordering=out;\n\tnode [shape=box, fontname=\"Helvetica\"];\n")
+	Walk(d, node)
+	d.w.WriteString("}\n")
+	return d.w.Flush()
+}
+
+// dotQuote заключает строку в кавычки по правилам DOT
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,21 @@
+PROGRAM Grades;
+CONST
+  Pass = 3;
+VAR
+  mark, points: INTEGER;
+  passed: BOOLEAN;
+BEGIN
+  mark := 4;
+  CASE mark OF
+    5: points := 100;
+    Pass..4: points := 50 + (mark - Pass) * 25;
+    1, 2: points := 0
+  ELSE
+    points := -1
+  END;
+  CASE points OF
+    0..49: passed := FALSE
+  OTHERWISE
+    passed := TRUE
+  END
+END.
@@ -0,0 +1,15 @@
+PROGRAM Circle(input, output);
+{ Длина окружности и площадь круга }
+CONST
+    Pi = 3.14159;
+    Radius = 10;
+TYPE
+    Measure = REAL;
+VAR
+    length, area: Measure;
+    whole: INTEGER;
+BEGIN
+    length := 2 * Pi * Radius;
+    area := Pi * Sqr(Radius);
+    whole := Trunc(area)
+END.
@@ -0,0 +1,21 @@
+PROGRAM Colors;
+TYPE
+  TColor = (Red, Yellow, Green, Blue);
+  TWarm = Red..Yellow;
+  Percent = 0..100;
+VAR
+  color: TColor;
+  warm: TWarm;
+  level: Percent;
+  index: INTEGER;
+BEGIN
+  color := Succ(Yellow);
+  warm := Pred(Pred(color));
+  index := Ord(color);
+  level := index * 25;
+  CASE color OF
+    Red..Yellow: level := level + 10
+  ELSE
+    level := level + 1
+  END
+END.
@@ -0,0 +1,50 @@
+{ Процедуры и функции, VAR-параметры и обработка исключений }
+PROGRAM Exceptions;
+TYPE
+  ENegative = CLASS(ERangeError) END;
+VAR
+  a, b, f, q: INTEGER;
+  log: STRING;
+
+PROCEDURE Swap(VAR x, y: INTEGER);
+VAR t: INTEGER;
+BEGIN
+  t := x; x := y; y := t
+END;
+
+FUNCTION Fact(n: INTEGER): INTEGER;
+BEGIN
+  IF n < 0 THEN RAISE ENegative.Create('факториал отрицательного числа');
+  IF n <= 1 THEN Fact := 1 ELSE Fact := n * Fact(n - 1)
+END;
+
+FUNCTION SafeDiv(x, y: INTEGER): INTEGER;
+BEGIN
+  TRY
+    SafeDiv := x DIV y
+  EXCEPT
+    ON EDivByZero DO SafeDiv := 0
+  END
+END;
+
+BEGIN
+  a := 3; b := 7;
+  Swap(a, b);
+  f := Fact(5);
+  q := SafeDiv(a, 0) + SafeDiv(a, 2);
+  TRY
+    f := Fact(-1)
+  EXCEPT
+    ON E: ERangeError DO log := E.ClassName + ': ' + E.Message
+  END;
+  TRY
+    TRY
+      WriteLn('вычисление...');
+      q := q DIV (a - 7)
+    FINALLY
+      WriteLn('FINALLY выполняется и при исключении')
+    END
+  EXCEPT
+    ON E: Exception DO WriteLn('перехвачено: ', E.Message)
+  END
+END.
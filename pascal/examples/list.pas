@@ -0,0 +1,38 @@
+{ Связный список: построение, обход и освобождение }
+PROGRAM LinkedList;
+TYPE
+  PNode = ^TNode;
+  TNode = RECORD
+    value: INTEGER;
+    next: PNode
+  END;
+VAR
+  head, node: PNode;
+  i, sum, count: INTEGER;
+BEGIN
+  head := NIL;
+  FOR i := 1 TO 5 DO
+  BEGIN
+    New(node);
+    node^.value := i * i;
+    node^.next := head;
+    head := node
+  END;
+
+  sum := 0;
+  count := 0;
+  node := head;
+  WHILE node <> NIL DO
+  BEGIN
+    sum := sum + node^.value;
+    count := count + 1;
+    node := node^.next
+  END;
+
+  WHILE head <> NIL DO
+  BEGIN
+    node := head;
+    head := head^.next;
+    Dispose(node)
+  END
+END.
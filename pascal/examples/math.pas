@@ -0,0 +1,7 @@
+BEGIN
+    hyp := Sqrt(Sqr(3) + Sqr(4));
+    area := Round(3.14159 * Sqr(2.5));
+    half := Trunc(-7 / 2);
+    odd := Odd(area);
+    e := Exp(1)
+END.
@@ -0,0 +1,63 @@
+{ Вложенные подпрограммы: быстрая сортировка, вложенная в процедуру сортировки }
+PROGRAM Nested;
+TYPE
+  TArray = RECORD a0, a1, a2, a3, a4, a5: INTEGER END;
+VAR
+  data: TArray;
+  swaps, calls: INTEGER;
+
+PROCEDURE Sort(VAR items: TArray);
+
+  FUNCTION Get(n: INTEGER): INTEGER;
+  BEGIN
+    CASE n OF
+      0: Get := items.a0;
+      1: Get := items.a1;
+      2: Get := items.a2;
+      3: Get := items.a3;
+      4: Get := items.a4;
+      5: Get := items.a5
+    END
+  END;
+
+  PROCEDURE Put(n, value: INTEGER);
+  BEGIN
+    CASE n OF
+      0: items.a0 := value;
+      1: items.a1 := value;
+      2: items.a2 := value;
+      3: items.a3 := value;
+      4: items.a4 := value;
+      5: items.a5 := value
+    END
+  END;
+
+  PROCEDURE QuickSort(low, high: INTEGER);
+  VAR i, j, pivot, t: INTEGER;
+  BEGIN
+    calls := calls + 1;
+    i := low; j := high;
+    pivot := Get((low + high) DIV 2);
+    REPEAT
+      WHILE Get(i) < pivot DO i := i + 1;
+      WHILE Get(j) > pivot DO j := j - 1;
+      IF i <= j THEN
+      BEGIN
+        t := Get(i); Put(i, Get(j)); Put(j, t);
+        swaps := swaps + 1;
+        i := i + 1; j := j - 1
+      END
+    UNTIL i > j;
+    IF low < j THEN QuickSort(low, j);
+    IF i < high THEN QuickSort(i, high)
+  END;
+
+BEGIN
+  QuickSort(0, 5)
+END;
+
+BEGIN
+  data.a0 := 42; data.a1 := 7; data.a2 := 19;
+  data.a3 := 3; data.a4 := 25; data.a5 := 11;
+  Sort(data)
+END.
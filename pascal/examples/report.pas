@@ -0,0 +1,28 @@
+{ Ведомость: чтение текстового файла и форматированный вывод.
+  Запуск: pascal -root examples examples/report.pas }
+PROGRAM Report(input, output);
+VAR
+  scores: TEXT;
+  name, best: STRING;
+  score, top, count, total: INTEGER;
+BEGIN
+  Assign(scores, 'scores.txt');
+  Reset(scores);
+  WriteLn('Фамилия':10, 'Балл':6);
+  WHILE NOT Eof(scores) DO
+  BEGIN
+    ReadLn(scores, name);
+    ReadLn(scores, score);
+    WriteLn(name:10, score:6);
+    IF score > top THEN
+    BEGIN
+      top := score;
+      best := name
+    END;
+    count := count + 1;
+    total := total + score
+  END;
+  Close(scores);
+  WriteLn('Средний балл: ', total / count:0:2);
+  WriteLn('Лучший: ', best)
+END.
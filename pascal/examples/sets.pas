@@ -0,0 +1,19 @@
+PROGRAM Sets;
+TYPE
+  TDay = (Mon, Tue, Wed, Thu, Fri, Sat, Sun);
+  TDays = SET OF TDay;
+VAR
+  week, work, meetings: TDays;
+  primes: SET OF 0..30;
+  n, count: INTEGER;
+  busy, allWork: BOOLEAN;
+BEGIN
+  week := [Mon..Sun];
+  work := week - [Sat, Sun];
+  meetings := [Tue, Thu] * work;
+  busy := Thu IN meetings;
+  allWork := meetings <= work;
+  primes := [2, 3, 5, 7, 11, 13] + [17, 19, 23, 29];
+  n := 29;
+  count := Ord(n IN primes) + Ord(n MOD 2 = 1) + Ord(NOT (n DIV 10 IN [1, 3]))
+END.
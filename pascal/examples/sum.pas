@@ -0,0 +1,15 @@
+{ Сумма чисел из стандартного ввода, по одному в строке.
+  Запуск: pascal examples/sum.pas < examples/sum.in }
+PROGRAM Sum(input, output);
+VAR n, total, count: INTEGER;
+BEGIN
+  total := 0;
+  count := 0;
+  WHILE NOT Eof DO
+  BEGIN
+    ReadLn(n);
+    total := total + n;
+    count := count + 1
+  END;
+  WriteLn('чисел: ', count, ', сумма: ', total)
+END.
@@ -0,0 +1,10 @@
+{ Модули: Stats подключает Logger, модули инициализируются до тела программы }
+PROGRAM Units;
+USES Logger, Stats;
+VAR
+  average: REAL;
+BEGIN
+  Add(3); Add(4); Add(8);
+  average := Mean;
+  Log('среднее вычислено')
+END.
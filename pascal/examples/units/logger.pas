@@ -0,0 +1,21 @@
+{ Модуль протокола: нумерует выведенные сообщения }
+UNIT Logger;
+
+INTERFACE
+
+VAR
+  Lines: INTEGER;
+
+PROCEDURE Log(message: STRING);
+
+IMPLEMENTATION
+
+PROCEDURE Log(message: STRING);
+BEGIN
+  Lines := Lines + 1;
+  WriteLn(Lines:3, ': ', message)
+END;
+
+INITIALIZATION
+  Log('Logger готов')
+END.
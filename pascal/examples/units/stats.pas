@@ -0,0 +1,31 @@
+{ Модуль статистики: накапливает сумму и число значений }
+UNIT Stats;
+
+INTERFACE
+
+USES Logger;
+
+PROCEDURE Add(value: INTEGER);
+FUNCTION Mean: REAL;
+
+IMPLEMENTATION
+
+VAR
+  sum, count: INTEGER; { скрыты от программы }
+
+PROCEDURE Add(value: INTEGER);
+BEGIN
+  sum := sum + value;
+  count := count + 1
+END;
+
+FUNCTION Mean: REAL;
+BEGIN
+  IF count = 0 THEN
+    RAISE Exception.Create('нет значений');
+  Mean := sum / count
+END;
+
+INITIALIZATION
+  Log('Stats готов')
+END.
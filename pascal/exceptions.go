@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Стандартные классы исключений. Ошибки выполнения возбуждают исключения этих классов,
+// а программа может описать свои классы, унаследовав их от Exception.
+var (
+	exceptionClass       = &Type{Kind: TypeClass, Name: "Exception"}
+	intErrorClass        = &Type{Kind: TypeClass, Name: "EIntError", Parent: exceptionClass}
+	divByZeroClass       = &Type{Kind: TypeClass, Name: "EDivByZero", Parent: intErrorClass}
+	rangeErrorClass      = &Type{Kind: TypeClass, Name: "ERangeError", Parent: intErrorClass}
+	intOverflowClass     = &Type{Kind: TypeClass, Name: "EIntOverflow", Parent: intErrorClass}
+	mathErrorClass       = &Type{Kind: TypeClass, Name: "EMathError", Parent: exceptionClass}
+	invalidOpClass       = &Type{Kind: TypeClass, Name: "EInvalidOp", Parent: mathErrorClass}
+	overflowClass        = &Type{Kind: TypeClass, Name: "EOverflow", Parent: mathErrorClass}
+	inOutErrorClass      = &Type{Kind: TypeClass, Name: "EInOutError", Parent: exceptionClass}
+	accessViolationClass = &Type{Kind: TypeClass, Name: "EAccessViolation", Parent: exceptionClass}
+	invalidPointerClass  = &Type{Kind: TypeClass, Name: "EInvalidPointer", Parent: exceptionClass}
+	stackOverflowClass   = &Type{Kind: TypeClass, Name: "EStackOverflow", Parent: exceptionClass}
+	assertionClass       = &Type{Kind: TypeClass, Name: "EAssertionFailed", Parent: exceptionClass}
+)
+
+// isSubclass сообщает, совпадает ли класс t с классом base или унаследован от него
+func isSubclass(t, base *Type) bool {
+	for ; t != nil; t = t.Parent {
+		if t == base {
+			return true
+		}
+	}
+	return false
+}
+
+// ExceptionValue представляет объект исключения. Исключения, как и файлы, не копируются:
+// переменная класса ссылается на объект; нулевое значение переменной - nil.
+type ExceptionValue struct {
+	Class   *Type
+	Message string
+}
+
+func (v *ExceptionValue) Kind() ValueKind { return KindException }
+func (v *ExceptionValue) String() string {
+	if v == nil {
+		return "NIL"
+	}
+	return fmt.Sprintf("%s(%s)", v.Class, StringValue(v.Message))
+}
+
+// exceptionFields содержит поля исключения, доступные только для чтения
+var exceptionFields = map[string]bool{"message": true, "classname": true}
+
+// field возвращает поле Message или ClassName исключения
+func (v *ExceptionValue) field(name string) Value {
+	if strings.EqualFold(name, "classname") {
+		return StringValue(v.Class.Name)
+	}
+	return StringValue(v.Message)
+}
+
+// StackFrame представляет строку стека вызовов: подпрограмму и позицию в ней
+type StackFrame struct {
+	Routine string
+	Pos     Position
+}
+
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s (%s)", f.Routine, f.Pos)
+}
+
+// maxTraceLines ограничивает число строк стека вызовов в сообщении об ошибке
+const maxTraceLines = 20
+
+// traceLines форматирует стек вызовов по строке на кадр. Подряд идущие одинаковые кадры
+// рекурсии сворачиваются в строку с числом кадров, а из слишком длинного стека выводятся
+// начало и конец.
+func traceLines(trace []StackFrame) []string {
+	var lines []string
+	var counts []int // число кадров в каждой строке
+	for n := 0; n < len(trace); {
+		count := 1
+		for n+count < len(trace) && trace[n+count] == trace[n] {
+			count++
+		}
+		if count > 1 {
+			lines = append(lines, fmt.Sprintf("%s × %d", trace[n], count))
+		} else {
+			lines = append(lines, trace[n].String())
+		}
+		counts = append(counts, count)
+		n += count
+	}
+	if len(lines) <= maxTraceLines {
+		return lines
+	}
+	half := maxTraceLines / 2
+	skipped := 0
+	for _, count := range counts[half : len(counts)-half] {
+		skipped += count
+	}
+	shown := append(lines[:half:half], fmt.Sprintf("… пропущено кадров: %d", skipped))
+	return append(shown, lines[len(lines)-half:]...)
+}
+
+// classifiedError представляет ошибку стандартной функции или кучи с известным классом исключения
+type classifiedError struct {
+	class   *Type
+	message string
+}
+
+func (e *classifiedError) Error() string {
+	return e.message
+}
+
+// classifiedErrorf создает ошибку класса class
+func classifiedErrorf(class *Type, format string, args ...interface{}) error {
+	return &classifiedError{class: class, message: fmt.Sprintf(format, args...)}
+}
+
+// classOf возвращает класс исключения, соответствующий ошибке; nil, если класс не задан
+func classOf(err error) *Type {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr.Class
+	}
+	return nil
+}
+
+// raiseError создает ошибку выполнения, которая возбуждает исключение класса class
+func raiseError(class *Type, pos Position, format string, args ...interface{}) *RuntimeError {
+	err := runtimeError(pos, format, args...)
+	err.Class = class
+	return err
+}
+
+// assertionMessage - сообщение исключения Assert без собственного сообщения
+const assertionMessage = "утверждение не выполнено"
+
+// executeAssert выполняет Assert(условие [, сообщение]). Ложное условие возбуждает исключение
+// EAssertionFailed в позиции вызова; сообщение вычисляется, только если условие ложно.
+func (i *Interpreter) executeAssert(s *CallStatement) error {
+	if len(s.Args) < 1 || len(s.Args) > 2 {
+		return runtimeError(s.Pos, "процедура Assert ожидает 1 или 2 аргумента, получено %d", len(s.Args))
+	}
+	cond, err := i.condition(s.Args[0], "Assert", s.Pos)
+	if err != nil || cond {
+		return err
+	}
+	message := assertionMessage
+	if len(s.Args) == 2 {
+		value, err := i.evaluateExpression(s.Args[1])
+		if err != nil {
+			return err
+		}
+		text, ok := textOf(value)
+		if !ok {
+			return runtimeError(s.Pos, "Assert: сообщение должно быть строкой, получено %s", typeOfValue(value))
+		}
+		message = text
+	}
+	return raiseError(assertionClass, s.Pos, "%s", message)
+}
+
+// executeTry выполняет оператор TRY. Блок FINALLY выполняется всегда, а исключение из него
+// заменяет исключение тела. Обработчики EXCEPT просматриваются по порядку; если ни один
+// не подходит и ветви ELSE нет, исключение передается дальше.
+func (i *Interpreter) executeTry(s *TryStatement) error {
+	var err error
+	if entry, ok := i.recall(s, memoOutcome); ok {
+		// Продолжение со снимка: тело уже выполнено, выполняется FINALLY или обработчик
+		err, i.flow = entry.detail.err, entry.detail.flow
+	} else {
+		err = i.executeStatements(s.Body.Statements)
+		if i.halt != nil {
+			// Остановленное выполнение не продолжается ни в FINALLY, ни в обработчиках
+			return err
+		}
+	}
+	i.remember(memoEntry{node: s, kind: memoOutcome, detail: &memoDetail{err: err, flow: i.flow}})
+	if s.Finally != nil {
+		// Break, Continue и Exit из тела также выполняют FINALLY и продолжаются после него
+		pending := i.flow
+		i.flow = flowNormal
+		if finallyErr := i.executeStatements(s.Finally.Statements); finallyErr != nil {
+			return finallyErr
+		}
+		if i.flow == flowNormal {
+			i.flow = pending
+		}
+		return err
+	}
+
+	// Перехватываются только исключения программы, но не внутренние ошибки интерпретатора
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		return err
+	}
+	for _, handler := range s.Handlers {
+		class := i.namedType(handler.Class)
+		if class == nil || class.Kind != TypeClass {
+			return runtimeError(handler.Pos, "%s не является классом исключения", handler.Class)
+		}
+		if isSubclass(runtimeErr.class(), class) {
+			return i.handle(runtimeErr, handler.Variable, class, handler.Body)
+		}
+	}
+	if s.Default != nil {
+		return i.handle(runtimeErr, "", nil, s.Default)
+	}
+	return err
+}
+
+// handle выполняет обработчик исключения. Переменная обработчика ON E: Класс существует
+// только в его теле и скрывает одноименную переменную.
+func (i *Interpreter) handle(err *RuntimeError, name string, class *Type, body Statement) error {
+	i.handling = append(i.handling, err)
+	defer func() { i.handling = i.handling[:len(i.handling)-1] }()
+	if name == "" {
+		return i.executeStatement(body)
+	}
+
+	variables := i.scope().variables
+	key := strings.ToLower(name)
+	hidden, exists := variables[key]
+	handler := &Variable{Name: name, Type: class, Value: err.exception()}
+	if entry, ok := i.recall(body, memoHandler); ok {
+		// Продолжение со снимка: переменная обработчика получает значение из снимка
+		handler.Value = entry.value
+	}
+	i.remember(memoEntry{node: body, kind: memoHandler, detail: &memoDetail{handler: handler, hidden: hidden}})
+	variables[key] = handler
+	defer func() {
+		if exists {
+			variables[key] = hidden
+		} else {
+			delete(variables, key)
+		}
+	}()
+	return i.executeStatement(body)
+}
+
+// executeRaise выполняет оператор RAISE. Без выражения повторно возбуждается обрабатываемое
+// исключение вместе с его стеком вызовов.
+func (i *Interpreter) executeRaise(s *RaiseStatement) error {
+	if s.Exception == nil {
+		if len(i.handling) == 0 {
+			return runtimeError(s.Pos, "RAISE без исключения вне обработчика исключения")
+		}
+		return i.handling[len(i.handling)-1]
+	}
+	value, err := i.evaluateExpression(s.Exception)
+	if err != nil {
+		return err
+	}
+	exception, ok := value.(*ExceptionValue)
+	if !ok {
+		return runtimeError(s.Pos, "RAISE: ожидалось исключение, получено %s", typeOfValue(value))
+	}
+	if exception == nil {
+		return raiseError(accessViolationClass, s.Pos, "RAISE: исключение NIL")
+	}
+	return raiseError(exception.Class, s.Pos, "%s", exception.Message)
+}
+
+// evaluateCreate создает исключение Класс.Create(сообщение)
+func (i *Interpreter) evaluateCreate(e *CreateExpr) (Value, error) {
+	class := i.namedType(e.Class)
+	if class == nil || class.Kind != TypeClass {
+		return nil, runtimeError(e.Pos, "%s не является классом исключения", e.Class)
+	}
+	if len(e.Args) != 1 {
+		return nil, runtimeError(e.Pos, "%s.Create ожидает 1 аргумент(ов), получено %d", e.Class, len(e.Args))
+	}
+	value, err := i.evaluateExpression(e.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	message, ok := textOf(value)
+	if !ok {
+		return nil, runtimeError(e.Pos, "%s.Create: сообщение должно быть строкой, получено %s", e.Class, typeOfValue(value))
+	}
+	return &ExceptionValue{Class: class, Message: message}, nil
+}
+
+// class возвращает класс исключения, которое возбуждает ошибка
+func (e *RuntimeError) class() *Type {
+	if e.Class == nil {
+		return exceptionClass
+	}
+	return e.Class
+}
+
+// exception возвращает объект исключения для переменной обработчика
+func (e *RuntimeError) exception() *ExceptionValue {
+	return &ExceptionValue{Class: e.class(), Message: e.Message}
+}
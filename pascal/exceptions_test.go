@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTryExcept тестирует перехват исключений обработчиками ON, ELSE и RAISE
+func TestTryExcept(t *testing.T) {
+	code := `TYPE EMy = CLASS(Exception) END;
+VAR n, zero: INTEGER; msg, cls, trail: STRING; p: ^INTEGER;
+BEGIN
+  TRY
+    n := 1 DIV zero
+  EXCEPT
+    ON E: EDivByZero DO BEGIN msg := E.Message; cls := E.ClassName END
+  END;
+  TRY
+    p^ := 1
+  EXCEPT
+    ON EIntError DO trail := 'int';
+    ON E: Exception DO trail := E.ClassName
+  END;
+  TRY
+    RAISE EMy.Create('свое')
+  EXCEPT
+    ON E: EDivByZero DO trail := 'wrong'
+  ELSE
+    trail := trail + ' else'
+  END;
+  TRY
+    TRY
+      RAISE EMy.Create('внутреннее')
+    EXCEPT
+      ON E: EMy DO BEGIN trail := trail + ' ' + E.Message; RAISE END
+    END
+  EXCEPT
+    ON E: Exception DO trail := trail + ' ' + E.ClassName
+  END;
+  TRY
+    n := Ord(Chr(300))
+  EXCEPT
+    ON ERangeError DO n := -1
+  END
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		values := interpreter.Values()
+		want := map[string]Value{
+			"msg":   StringValue("деление на ноль"),
+			"cls":   StringValue("EDivByZero"),
+			"trail": StringValue("EAccessViolation else внутреннее EMy"),
+			"n":     IntegerValue(-1),
+		}
+		for name, value := range want {
+			if got := values[name]; got != value {
+				t.Errorf("%s: ожидалось %v, получено %v", name, value, got)
+			}
+		}
+		if _, ok := values["e"]; ok {
+			t.Errorf("Переменная обработчика не должна существовать вне его тела: %v", values)
+		}
+	}
+}
+
+// TestTryFinally тестирует, что FINALLY выполняется всегда, а исключение из него заменяет исходное
+func TestTryFinally(t *testing.T) {
+	interpreter, err := runChecked(t, `VAR steps: INTEGER;
+BEGIN
+  TRY steps := 1 FINALLY steps := steps + 10 END;
+  TRY
+    RAISE Exception.Create('тело')
+  FINALLY
+    steps := steps + 100
+  END;
+  steps := 0
+END.`)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "тело" || runtimeErr.class() != exceptionClass {
+		t.Fatalf("Ожидалось исключение Exception('тело'), получено %v", err)
+	}
+	if got := interpreter.Values()["steps"]; got != IntegerValue(111) {
+		t.Errorf("Ожидалось steps = 111, получено %v", got)
+	}
+
+	_, err = runChecked(t, `BEGIN
+  TRY
+    RAISE Exception.Create('тело')
+  FINALLY
+    RAISE EInOutError.Create('finally')
+  END
+END.`)
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "finally" || runtimeErr.Class != inOutErrorClass {
+		t.Errorf("Ожидалось исключение EInOutError('finally'), получено %v", err)
+	}
+}
+
+// TestRuntimeErrorClasses тестирует классы исключений, возбуждаемых ошибками выполнения
+func TestRuntimeErrorClasses(t *testing.T) {
+	tests := []struct {
+		code  string
+		class *Type
+	}{
+		{"VAR n: INTEGER; BEGIN n := 1 DIV n END.", divByZeroClass},
+		{"VAR x: REAL; BEGIN x := 1 / x END.", divByZeroClass},
+		{"VAR n: 1..5; m: INTEGER; BEGIN m := 9; n := m END.", rangeErrorClass},
+		{"VAR n: INTEGER; BEGIN n := 9223372036854775807; n := n + 1 END.", intOverflowClass},
+		{"VAR x: REAL; BEGIN x := -1; x := Sqrt(x) END.", invalidOpClass},
+		{"VAR x: REAL; BEGIN x := 1e300; x := x * x END.", overflowClass},
+		{"VAR f: TEXT; BEGIN Assign(f, 'missing.txt'); Reset(f) END.", inOutErrorClass},
+		{"VAR p: ^INTEGER; BEGIN p^ := 1 END.", accessViolationClass},
+		{"VAR p: ^INTEGER; BEGIN Dispose(p) END.", invalidPointerClass},
+		{"PROCEDURE P; BEGIN P END; BEGIN P END.", stackOverflowClass},
+		{"BEGIN RAISE Exception.Create('x') END.", exceptionClass},
+		{"BEGIN Assert(1 > 2) END.", assertionClass},
+	}
+	for _, tt := range tests {
+		_, err := runChecked(t, tt.code)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("%q: ожидалась ошибка выполнения, получено %v", tt.code, err)
+			continue
+		}
+		if runtimeErr.class() != tt.class {
+			t.Errorf("%q: ожидалось исключение %s, получено %s (%v)", tt.code, tt.class, runtimeErr.class(), err)
+		}
+	}
+}
+
+// TestStackTrace тестирует стек вызовов необработанного исключения
+func TestStackTrace(t *testing.T) {
+	code := `PROGRAM Trace;
+FUNCTION Inner(n: INTEGER): INTEGER;
+BEGIN
+  Inner := 10 DIV n
+END;
+PROCEDURE Outer(n: INTEGER);
+BEGIN
+  x := Inner(n)
+END;
+BEGIN
+  Outer(2);
+  Outer(0)
+END.`
+	_, err := runChecked(t, code)
+	want := `строка 4, столбец 15: деление на ноль (EDivByZero)
+стек вызовов:
+  Inner (строка 4, столбец 15)
+  Outer (строка 8, столбец 8)
+  Trace (строка 12, столбец 3)`
+	if got := describeError(err); got != want {
+		t.Errorf("Ожидалось\n%s\nполучено\n%s", want, got)
+	}
+
+	// Повторно возбужденное исключение сохраняет стек вызовов
+	_, err = runChecked(t, `PROCEDURE Fail; BEGIN RAISE Exception.Create('сбой') END;
+BEGIN
+  TRY Fail EXCEPT ON Exception DO RAISE END
+END.`)
+	want = "строка 1, столбец 23: сбой (Exception)\nстек вызовов:\n  Fail (строка 1, столбец 23)\n  основная программа (строка 3, столбец 7)"
+	if got := describeError(err); got != want {
+		t.Errorf("Ожидалось\n%s\nполучено\n%s", want, got)
+	}
+}
+
+// TestLongStackTrace тестирует сокращение стека вызовов: одинаковые кадры рекурсии
+// сворачиваются, а из длинного стека выводятся начало и конец
+func TestLongStackTrace(t *testing.T) {
+	_, err := runChecked(t, "PROCEDURE P; BEGIN P END; BEGIN P END.")
+	want := "строка 1, столбец 20: переполнение стека: глубина вызовов превысила 10000 (EStackOverflow)\n" +
+		"стек вызовов:\n  P (строка 1, столбец 20) × 10000\n  основная программа (строка 1, столбец 33)"
+	if got := describeError(err); got != want {
+		t.Errorf("Ожидалось\n%s\nполучено\n%s", want, got)
+	}
+
+	_, err = runChecked(t, `PROCEDURE R(n: INTEGER);
+BEGIN
+  IF n MOD 2 = 0 THEN R(n + 1)
+  ELSE IF n < 30 THEN R(n + 1)
+  ELSE RAISE Exception.Create('глубоко')
+END;
+BEGIN R(0) END.`)
+	lines := strings.Split(describeError(err), "\n")
+	if len(lines) != maxTraceLines+3 || lines[2] != "  R (строка 5, столбец 8)" || lines[12] != "  … пропущено кадров: 13" ||
+		lines[len(lines)-1] != "  основная программа (строка 7, столбец 7)" {
+		t.Errorf("Неожиданный стек вызовов:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+// TestAssert тестирует Assert: ложное условие возбуждает EAssertionFailed в позиции вызова,
+// а сообщение вычисляется, только если условие ложно
+func TestAssert(t *testing.T) {
+	code := `VAR n: INTEGER; msg, cls: STRING;
+FUNCTION Count: STRING;
+BEGIN
+  n := n + 1;
+  Count := 'вычислено'
+END;
+BEGIN
+  Assert(TRUE);
+  Assert(n = 0, Count);
+  TRY
+    Assert(n > 0)
+  EXCEPT
+    ON E: EAssertionFailed DO BEGIN msg := E.Message; cls := E.ClassName END
+  END;
+  TRY
+    Assert(n > 0, Count)
+  EXCEPT
+    ON E: Exception DO msg := msg + ', ' + E.Message
+  END
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		want := "{cls: 'EAssertionFailed', msg: 'утверждение не выполнено, вычислено', n: 1}"
+		if got := formatVariables(interpreter.Values()); got != want {
+			t.Errorf("Ожидалось %s, получено %s", want, got)
+		}
+	}
+
+	_, err := runChecked(t, `PROCEDURE Check(n: INTEGER);
+BEGIN
+  Assert(n MOD 2 = 0, 'нечетное число')
+END;
+BEGIN
+  Check(2);
+  Check(3)
+END.`)
+	want := "строка 3, столбец 3: нечетное число (EAssertionFailed)\nстек вызовов:\n  Check (строка 3, столбец 3)\n  основная программа (строка 7, столбец 3)"
+	if got := describeError(err); got != want {
+		t.Errorf("Ожидалось\n%s\nполучено\n%s", want, got)
+	}
+}
+
+// TestExceptionRuntimeErrors тестирует ошибки исключений при выполнении без семантического анализа
+func TestExceptionRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN RAISE END.", "RAISE без исключения вне обработчика исключения"},
+		{"BEGIN RAISE 1 END.", "RAISE: ожидалось исключение, получено INTEGER"},
+		{"VAR e: Exception; BEGIN RAISE e END.", "RAISE: исключение NIL"},
+		{"BEGIN TRY x := 1 DIV 0 EXCEPT ON INTEGER DO x := 1 END END.", "INTEGER не является классом исключения"},
+		{"BEGIN x := INTEGER.Create('a') END.", "INTEGER не является классом исключения"},
+		{"BEGIN x := Exception.Create(1) END.", "Exception.Create: сообщение должно быть строкой, получено INTEGER"},
+		{"BEGIN x := Exception.Create() END.", "Exception.Create ожидает 1 аргумент(ов), получено 0"},
+		{"VAR e: Exception; BEGIN x := e.Message END.", "NIL"},
+		{"BEGIN TRY RAISE Exception.Create('a') EXCEPT ON E: Exception DO E.Message := 'b' END END.", "поле Message исключения доступно только для чтения"},
+		{"TYPE E = CLASS(INTEGER) END; BEGIN END.", "базовый класс INTEGER не является классом исключения"},
+		{"BEGIN Assert(1) END.", "условие оператора Assert должно быть логического типа, получено INTEGER"},
+		{"BEGIN Assert(FALSE, 1) END.", "Assert: сообщение должно быть строкой, получено INTEGER"},
+		{"BEGIN Assert() END.", "процедура Assert ожидает 1 или 2 аргумента, получено 0"},
+	}
+	for _, tt := range tests {
+		_, err := interpretCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestCheckerExceptions тестирует семантические ошибки исключений
+func TestCheckerExceptions(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN RAISE END.", "RAISE без исключения допустим только в обработчике исключения"},
+		{"BEGIN TRY x := 1 EXCEPT ON Exception DO x := 2 END; RAISE END.", "RAISE без исключения допустим только в обработчике"},
+		{"PROCEDURE P; BEGIN RAISE END; BEGIN TRY P EXCEPT ON Exception DO P END END.", "RAISE без исключения допустим только в обработчике"},
+		{"BEGIN RAISE 1 END.", "RAISE: ожидалось исключение, получено INTEGER"},
+		{"BEGIN TRY x := 1 EXCEPT ON INTEGER DO x := 2 END END.", "INTEGER не является классом исключения"},
+		{"BEGIN TRY x := 1 EXCEPT ON E: EMissing DO x := 2 END END.", "неизвестный класс исключения EMissing"},
+		{"BEGIN x := INTEGER.Create('a') END.", "INTEGER не является классом исключения"},
+		{"BEGIN x := Exception.Create(1) END.", "Exception.Create: сообщение должно быть строкой, получено INTEGER"},
+		{"BEGIN x := Exception.Create('a', 'b') END.", "Exception.Create ожидает 1 аргумент(ов), получено 2"},
+		{"VAR e: Exception; BEGIN x := e.Code END.", "у исключения Exception нет поля Code"},
+		{"VAR e: Exception; BEGIN e.Message := 'a' END.", "поле Message исключения доступно только для чтения"},
+		{"VAR e: Exception; BEGIN x := e = e END.", "операция = неприменима к исключениям"},
+		{"VAR e: EIntError; BEGIN e := Exception.Create('a') END.", "нельзя присвоить Exception переменной e типа EIntError"},
+		{"BEGIN TRY x := 1 EXCEPT ON E: Exception DO x := E.Message + 1 END END.", "несовместимые типы операндов: STRING и INTEGER"},
+		{"TYPE E = CLASS(INTEGER) END; BEGIN END.", "базовый класс INTEGER не является классом исключения"},
+		{"VAR e: Exception; BEGIN WriteLn(e) END.", "WriteLn"},
+		{"BEGIN Assert(1) END.", "условие оператора Assert должно быть логического типа, получено INTEGER"},
+		{"BEGIN Assert(TRUE, 1) END.", "Assert: сообщение должно быть строкой, получено INTEGER"},
+		{"BEGIN Assert(TRUE, 'a', 'b') END.", "процедура Assert ожидает 1 или 2 аргумента, получено 3"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// Переменной базового класса можно присвоить исключение производного класса
+	if _, err := checkCode(t, "VAR e: Exception; BEGIN e := EDivByZero.Create('a'); RAISE e END."); err != nil {
+		t.Errorf("Неожиданная ошибка: %v", err)
+	}
+}
+
+// TestParseExceptions тестирует разбор TRY, RAISE, CLASS и Create
+func TestParseExceptions(t *testing.T) {
+	program := parseCode(t, `TYPE EMy = CLASS(Exception) END;
+BEGIN
+  TRY x := 1; y := 2 EXCEPT ON E: EMy DO x := 3; ON Exception DO x := 4 ELSE x := 5 END;
+  TRY x := 1 FINALLY x := 2; y := 3 END;
+  RAISE EMy.Create('a');
+  TRY x := 1 EXCEPT x := 2; RAISE END
+END.`)
+	wants := []string{
+		"Try(2 statements, except: 2 handlers, else: true)",
+		"Try(1 statements, finally: 2 statements)",
+		"Raise(Create(EMy(Literal('a'))))",
+		"Try(1 statements, except: 0 handlers, else: true)",
+	}
+	for n, want := range wants {
+		if got := program.Statements[n].String(); got != want {
+			t.Errorf("Ожидалось %s, получено %s", want, got)
+		}
+	}
+	if got := program.Types[0].Type.(*ClassType).String(); got != "CLASS(Exception) END" {
+		t.Errorf("Ожидалось CLASS(Exception) END, получено %s", got)
+	}
+	if handler := program.Statements[0].(*TryStatement).Handlers[0]; handler.String() != "On(E: EMy)" {
+		t.Errorf("Ожидалось On(E: EMy), получено %s", handler)
+	}
+
+	errorTests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN TRY x := 1 END.", "ожидалось EXCEPT или FINALLY"},
+		{"BEGIN TRY x := 1 EXCEPT ON E: DO x := 2 END END.", "ожидалось имя класса исключения"},
+		{"TYPE E = CLASS() END; BEGIN END.", "ожидалось имя базового класса"},
+		{"BEGIN TRY x := 1 EXCEPT ON E x := 2 END END.", "ожидалось DO"},
+		{"BEGIN TRY x := 1 FINALLY x := 2", "ожидался END оператора TRY"},
+		{"TYPE E = CLASS(Exception; BEGIN END.", "ожидалась закрывающая скобка"},
+		{"TYPE E = CLASS(Exception) BEGIN END.", "ожидался END описания класса"},
+	}
+	for _, tt := range errorTests {
+		tokens, err := NewLexer(tt.code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", tt.code, err)
+		}
+		_, err = NewParser(tokens).Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestMainUncaughtException тестирует вывод необработанного исключения со стеком вызовов
+func TestMainUncaughtException(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "fail.pas")
+	code := "PROCEDURE Check(n: INTEGER);\nBEGIN\n  IF n < 0 THEN RAISE ERangeError.Create('отрицательное значение')\nEND;\nBEGIN\n  Check(-1)\nEND."
+	if err := os.WriteFile(program, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs, oldStderr := os.Args, os.Stderr
+	defer func() { os.Args, os.Stderr = oldArgs, oldStderr }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	os.Args = []string{"pascal", program}
+	exitCode := mainWithExitCode()
+	w.Close()
+	var out bytes.Buffer
+	out.ReadFrom(r)
+
+	if exitCode != 1 {
+		t.Errorf("Ожидался код выхода 1, получено %d", exitCode)
+	}
+	want := "ошибка выполнения: строка 3, столбец 17: отрицательное значение (ERangeError)\n" +
+		"стек вызовов:\n  Check (строка 3, столбец 17)\n  основная программа (строка 6, столбец 3)\n"
+	if out.String() != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out.String())
+	}
+}
@@ -0,0 +1,679 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FileSystem представляет файлы, доступные программе через Assign, Reset, Rewrite и Append.
+// Файлы читаются через fs.FS, а содержимое файла, открытого для записи, сохраняется
+// методом WriteFile при закрытии. Имена файлов - пути в формате fs.ValidPath.
+type FileSystem interface {
+	fs.FS
+	WriteFile(name string, data []byte) error
+}
+
+// OverlayFS - файловая система в памяти поверх базовой fs.FS, доступной только для чтения.
+// Записанные файлы хранятся в памяти и закрывают одноименные файлы базовой системы.
+type OverlayFS struct {
+	base  fs.FS
+	files map[string][]byte
+}
+
+// NewOverlayFS создает файловую систему в памяти поверх base; base может быть nil
+func NewOverlayFS(base fs.FS) *OverlayFS {
+	return &OverlayFS{base: base, files: make(map[string][]byte)}
+}
+
+// Open открывает файл: сначала записанный в память, затем из базовой файловой системы
+func (o *OverlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := o.files[name]; ok {
+		return &memoryFile{Reader: bytes.NewReader(data), name: path.Base(name)}, nil
+	}
+	if o.base == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return o.base.Open(name)
+}
+
+// WriteFile сохраняет содержимое файла в памяти
+func (o *OverlayFS) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	o.files[name] = bytes.Clone(data)
+	return nil
+}
+
+// memoryFile - открытый для чтения файл OverlayFS; он же описывает себя как fs.FileInfo
+type memoryFile struct {
+	*bytes.Reader
+	name string
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *memoryFile) Close() error               { return nil }
+func (f *memoryFile) Name() string               { return f.name }
+func (f *memoryFile) Mode() fs.FileMode          { return 0o444 }
+func (f *memoryFile) ModTime() time.Time         { return time.Time{} }
+func (f *memoryFile) IsDir() bool                { return false }
+func (f *memoryFile) Sys() any                   { return nil }
+
+// DirFS - файловая система каталога на диске. Программа не может обратиться к файлам вне
+// каталога: имена проверяются fs.ValidPath, а путь с раскрытыми символическими ссылками
+// должен оставаться внутри каталога.
+type DirFS struct {
+	root string
+}
+
+// NewDirFS создает файловую систему с корнем в каталоге root
+func NewDirFS(root string) *DirFS {
+	return &DirFS{root: root}
+}
+
+// Open открывает файл каталога для чтения
+func (d *DirFS) Open(name string) (fs.File, error) {
+	filename, err := d.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filename)
+}
+
+// WriteFile записывает файл в каталог
+func (d *DirFS) WriteFile(name string, data []byte) error {
+	filename, err := d.resolve("write", name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
+
+// resolve переводит имя файла в путь на диске внутри корневого каталога
+func (d *DirFS) resolve(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	filename, err := resolveLinks(d.root, name)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return filename, nil
+}
+
+// resolveLinks раскрывает символические ссылки в пути name внутри каталога root и
+// проверяет, что файл остается внутри каталога. Несуществующий файл допустим, чтобы его
+// можно было создать, а ссылка на несуществующий файл - нет: запись создала бы его там,
+// куда ведет ссылка.
+func resolveLinks(root, name string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(root, filepath.FromSlash(name))
+	resolved, err := filepath.EvalSymlinks(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Lstat(filename); err == nil {
+			return "", fs.ErrPermission
+		}
+		dir, err := filepath.EvalSymlinks(filepath.Dir(filename))
+		if err != nil {
+			return "", err
+		}
+		resolved = filepath.Join(dir, filepath.Base(filename))
+	} else if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fs.ErrPermission
+	}
+	return resolved, nil
+}
+
+// fileMode представляет состояние файловой переменной
+type fileMode int
+
+const (
+	fileClosed fileMode = iota
+	fileReading
+	fileWriting
+)
+
+// FileValue представляет файловую переменную типа TEXT. Файловые переменные не копируются:
+// все обращения к переменной работают с одним и тем же открытым файлом.
+type FileValue struct {
+	Name   string // имя внешнего файла, заданное Assign
+	mode   fileMode
+	reader *textReader
+	writer *bytes.Buffer // содержимое файла, открытого для записи; сохраняется при закрытии
+}
+
+func (v *FileValue) Kind() ValueKind { return KindFile }
+func (v *FileValue) String() string {
+	if v.Name == "" {
+		return "TEXT"
+	}
+	return fmt.Sprintf("TEXT(%s)", StringValue(v.Name))
+}
+
+// textReader читает текст по символам, строкам и числам, как процедуры Read и ReadLn
+type textReader struct {
+	r      *bufio.Reader
+	source *countingReader // источник r; по нему вычисляется позиция чтения
+}
+
+func newTextReader(r io.Reader) *textReader {
+	source := &countingReader{r: r}
+	return &textReader{r: bufio.NewReader(source), source: source}
+}
+
+// offset возвращает число байтов, прочитанных из текста
+func (t *textReader) offset() int64 {
+	return t.source.n - int64(t.r.Buffered())
+}
+
+// buffered возвращает байты, которые уже прочитаны из источника, но еще не использованы
+func (t *textReader) buffered() []byte {
+	data, _ := t.r.Peek(t.r.Buffered())
+	return bytes.Clone(data)
+}
+
+// unread возвращает непрочитанную часть текста: буферизованные байты и, если источник -
+// текст в памяти, его остаток
+func (t *textReader) unread() []byte {
+	data := t.buffered()
+	if rest, ok := t.source.r.(*bytes.Reader); ok {
+		tail := make([]byte, rest.Len())
+		rest.ReadAt(tail, rest.Size()-int64(rest.Len()))
+		data = append(data, tail...)
+	}
+	return data
+}
+
+// resumeTextReader создает текст, из которого уже прочитано offset байтов, а остальные
+// читаются из r
+func resumeTextReader(r io.Reader, offset int64) *textReader {
+	t := newTextReader(r)
+	t.source.n = offset
+	return t
+}
+
+// peek возвращает следующий символ, не извлекая его; ok ложно в конце файла
+func (t *textReader) peek() (r rune, ok bool) {
+	r, _, err := t.r.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	t.r.UnreadRune()
+	return r, true
+}
+
+// eof сообщает, прочитан ли файл до конца
+func (t *textReader) eof() bool {
+	_, ok := t.peek()
+	return !ok
+}
+
+// eoln сообщает, достигнут ли конец строки или файла
+func (t *textReader) eoln() bool {
+	r, ok := t.peek()
+	return !ok || r == '\n' || r == '\r'
+}
+
+// readChar читает символ; в конце строки, как требует стандарт, возвращается пробел,
+// а чтение переходит на следующую строку
+func (t *textReader) readChar() (rune, bool) {
+	if t.eof() {
+		return 0, false
+	}
+	if t.eoln() {
+		t.skipLine()
+		return ' ', true
+	}
+	r, _, _ := t.r.ReadRune()
+	return r, true
+}
+
+// readLine читает остаток строки, не переходя на следующую
+func (t *textReader) readLine() string {
+	var line strings.Builder
+	for !t.eoln() {
+		r, _, _ := t.r.ReadRune()
+		line.WriteRune(r)
+	}
+	return line.String()
+}
+
+// skipLine пропускает остаток строки вместе с ее концом
+func (t *textReader) skipLine() {
+	t.readLine()
+	if r, ok := t.peek(); ok && r == '\r' {
+		t.r.ReadRune()
+	}
+	if r, ok := t.peek(); ok && r == '\n' {
+		t.r.ReadRune()
+	}
+}
+
+// word пропускает пробельные символы, включая концы строк, и читает слово до следующего пробела
+func (t *textReader) word() string {
+	for r, ok := t.peek(); ok && unicode.IsSpace(r); r, ok = t.peek() {
+		t.r.ReadRune()
+	}
+	var word strings.Builder
+	for r, ok := t.peek(); ok && !unicode.IsSpace(r); r, ok = t.peek() {
+		t.r.ReadRune()
+		word.WriteRune(r)
+	}
+	return word.String()
+}
+
+// filePath приводит имя внешнего файла к пути внутри корневого каталога файловой системы
+func filePath(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if !fs.ValidPath(clean) || clean == "." {
+		return "", fmt.Errorf("недопустимое имя файла %s: путь должен быть относительным и не выходить за пределы корневого каталога", StringValue(name))
+	}
+	return clean, nil
+}
+
+// describeFileError переводит ошибку файловой системы в сообщение для программы
+func describeFileError(name string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("файл %s не найден", StringValue(name))
+	case errors.Is(err, fs.ErrInvalid), errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("нет доступа к файлу %s", StringValue(name))
+	default:
+		return err
+	}
+}
+
+// fileFunctions содержит стандартные функции, которые читают состояние файла;
+// без аргумента они относятся к стандартному вводу, и их можно вызывать без скобок
+var fileFunctions = map[string]bool{"eof": true, "eoln": true}
+
+// fileArgument возвращает файловую переменную, если она передана первым аргументом
+// процедуры ввода-вывода, и остальные аргументы
+func (i *Interpreter) fileArgument(args []Expression) (*FileValue, []Expression, error) {
+	if len(args) == 0 || !isDesignator(args[0]) {
+		return nil, args, nil
+	}
+	if id, ok := args[0].(*Identifier); ok {
+		if _, declared := i.variable(strings.ToLower(id.Name)); !declared {
+			return nil, args, nil
+		}
+	}
+	value, err := i.evaluateExpression(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if file, ok := value.(*FileValue); ok {
+		return file, args[1:], nil
+	}
+	return nil, args, nil
+}
+
+// executeWrite выполняет Write и WriteLn в файл, заданный первым аргументом, или в стандартный вывод
+func (i *Interpreter) executeWrite(s *CallStatement) error {
+	file, args, err := i.fileArgument(s.Args)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = i.output
+	if file != nil {
+		if file.mode != fileWriting {
+			return raiseError(inOutErrorClass, s.Pos, "%s: файл %s не открыт для записи", s.Name, file)
+		}
+		w = file.writer
+	}
+
+	var text strings.Builder
+	for _, arg := range args {
+		formatted, err := i.formatArgument(s, arg)
+		if err != nil {
+			return err
+		}
+		text.WriteString(formatted)
+	}
+	if strings.EqualFold(s.Name, "writeln") {
+		text.WriteByte('\n')
+	}
+	if _, err := io.WriteString(w, text.String()); err != nil {
+		return raiseError(inOutErrorClass, s.Pos, "%s: %v", s.Name, err)
+	}
+	return nil
+}
+
+// formatArgument вычисляет параметр Write и форматирует его с учетом ширины поля и точности
+func (i *Interpreter) formatArgument(s *CallStatement, arg Expression) (string, error) {
+	format, _ := arg.(*FormatExpr)
+	if format != nil {
+		arg = format.Value
+	}
+	value, err := i.evaluateExpression(arg)
+	if err != nil {
+		return "", err
+	}
+
+	var width, precision int64 = 0, -1
+	if format != nil {
+		if width, err = i.formatParameter(s, format.Width, "ширина поля"); err != nil {
+			return "", err
+		}
+		if format.Precision != nil {
+			if _, real := value.(RealValue); !real {
+				return "", runtimeError(s.Pos, "%s: точность задается только для вещественных значений, получено %s", s.Name, typeOfValue(value))
+			}
+			if precision, err = i.formatParameter(s, format.Precision, "точность"); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	var text string
+	switch v := value.(type) {
+	case IntegerValue, BigIntValue, BooleanValue, EnumValue:
+		text = v.String()
+	case RealValue:
+		if precision >= 0 {
+			text = strconv.FormatFloat(float64(v), 'f', int(precision), 64)
+		} else {
+			text = v.String()
+		}
+	case CharValue, StringValue:
+		text, _ = textOf(v)
+	default:
+		return "", runtimeError(s.Pos, "%s: нельзя вывести значение типа %s", s.Name, typeOfValue(value))
+	}
+	if pad := int(width) - utf8.RuneCountInString(text); pad > 0 {
+		text = strings.Repeat(" ", pad) + text
+	}
+	return text, nil
+}
+
+// formatParameter вычисляет ширину поля или точность параметра Write
+func (i *Interpreter) formatParameter(s *CallStatement, expr Expression, what string) (int64, error) {
+	value, err := i.evaluateExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := value.(IntegerValue)
+	if !ok || n < 0 {
+		return 0, runtimeError(s.Pos, "%s: %s должна быть неотрицательным целым, получено %s", s.Name, what, value)
+	}
+	return int64(n), nil
+}
+
+// executeRead выполняет Read и ReadLn из файла, заданного первым аргументом, или из стандартного ввода
+func (i *Interpreter) executeRead(s *CallStatement) error {
+	file, args, err := i.fileArgument(s.Args)
+	if err != nil {
+		return err
+	}
+	reader, name := i.input, "INPUT"
+	if file != nil {
+		if file.mode != fileReading {
+			return raiseError(inOutErrorClass, s.Pos, "%s: файл %s не открыт для чтения", s.Name, file)
+		}
+		reader, name = file.reader, file.Name
+	}
+
+	for _, arg := range args {
+		// При продолжении со снимка уже прочитанные аргументы не читаются повторно
+		if _, read := i.recall(arg, memoRead); !read {
+			mark := len(i.memo)
+			if err := i.readArgument(s, reader, name, arg); err != nil {
+				return err
+			}
+			i.memo = i.memo[:mark]
+		}
+		i.remember(memoEntry{node: arg, kind: memoRead})
+	}
+	if strings.EqualFold(s.Name, "readln") {
+		reader.skipLine()
+	}
+	return nil
+}
+
+// readArgument читает значение аргумента arg процедуры Read или ReadLn из текста name
+func (i *Interpreter) readArgument(s *CallStatement, reader *textReader, name string, arg Expression) error {
+	if id, ok := arg.(*Identifier); ok {
+		if _, declared := i.variable(strings.ToLower(id.Name)); !declared {
+			// Неописанная переменная создается чтением так же, как присваиванием
+			value, err := i.readValue(s, reader, name, nil)
+			if err != nil {
+				return err
+			}
+			return i.assign(id.Name, s.Pos, value, true)
+		}
+	}
+	ref, err := i.locate(arg)
+	if err != nil {
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			return err
+		}
+		return runtimeError(s.Pos, "%s: %v", s.Name, err)
+	}
+	value, err := i.readValue(s, reader, name, ref.typ)
+	if err != nil {
+		return err
+	}
+	return i.store(ref, s.Pos, value, true)
+}
+
+// readValue читает значение типа t; неописанная переменная (t = nil) читается как число
+func (i *Interpreter) readValue(s *CallStatement, reader *textReader, name string, t *Type) (Value, error) {
+	kind := TypeInteger
+	if t != nil {
+		kind = baseType(t).Kind
+	}
+	switch kind {
+	case TypeChar:
+		r, ok := reader.readChar()
+		if !ok {
+			return nil, raiseError(inOutErrorClass, s.Pos, "%s: чтение за концом файла %s", s.Name, name)
+		}
+		return CharValue(r), nil
+	case TypeString:
+		return StringValue(reader.readLine()), nil
+	case TypeInteger, TypeReal:
+	default:
+		return nil, runtimeError(s.Pos, "%s: нельзя прочитать значение типа %s", s.Name, t)
+	}
+
+	word := reader.word()
+	if word == "" {
+		return nil, raiseError(inOutErrorClass, s.Pos, "%s: чтение за концом файла %s", s.Name, name)
+	}
+	if n, err := strconv.ParseInt(word, 10, 64); err == nil {
+		return IntegerValue(n), nil
+	}
+	if kind == TypeInteger && t != nil {
+		return nil, raiseError(inOutErrorClass, s.Pos, "%s: ожидалось целое число, прочитано %s", s.Name, StringValue(word))
+	}
+	x, err := strconv.ParseFloat(word, 64)
+	if err != nil || math.IsInf(x, 0) || math.IsNaN(x) {
+		return nil, raiseError(inOutErrorClass, s.Pos, "%s: ожидалось число, прочитано %s", s.Name, StringValue(word))
+	}
+	return RealValue(x), nil
+}
+
+// executeFileProcedure выполняет Assign, Reset, Rewrite, Append и Close
+func (i *Interpreter) executeFileProcedure(s *CallStatement) error {
+	procedure := strings.ToLower(s.Name)
+	arity := 1
+	if procedure == "assign" {
+		arity = 2
+	}
+	if len(s.Args) != arity {
+		return runtimeError(s.Pos, "процедура %s ожидает %d аргумент(ов), получено %d", s.Name, arity, len(s.Args))
+	}
+	value, err := i.evaluateExpression(s.Args[0])
+	if err != nil {
+		return err
+	}
+	file, ok := value.(*FileValue)
+	if !ok {
+		return runtimeError(s.Pos, "%s: %s не является файловой переменной", s.Name, designatorName(s.Args[0]))
+	}
+
+	switch procedure {
+	case "assign":
+		value, err := i.evaluateExpression(s.Args[1])
+		if err != nil {
+			return err
+		}
+		name, ok := textOf(value)
+		if !ok {
+			return runtimeError(s.Pos, "Assign: имя файла должно быть строкой, получено %s", typeOfValue(value))
+		}
+		if file.mode != fileClosed {
+			return raiseError(inOutErrorClass, s.Pos, "Assign: файл %s открыт", file)
+		}
+		file.Name = name
+		return nil
+	case "close":
+		if file.mode == fileClosed {
+			return raiseError(inOutErrorClass, s.Pos, "Close: файл %s не открыт", file)
+		}
+		if err := i.closeFile(file); err != nil {
+			return raiseError(inOutErrorClass, s.Pos, "Close: %v", err)
+		}
+		return nil
+	}
+
+	if file.Name == "" {
+		return raiseError(inOutErrorClass, s.Pos, "%s: файлу %s не назначено имя процедурой Assign", s.Name, designatorName(s.Args[0]))
+	}
+	filename, err := filePath(file.Name)
+	if err != nil {
+		return raiseError(inOutErrorClass, s.Pos, "%s: %v", s.Name, err)
+	}
+	// Повторное открытие сначала закрывает файл
+	if file.mode != fileClosed {
+		if err := i.closeFile(file); err != nil {
+			return raiseError(inOutErrorClass, s.Pos, "%s: %v", s.Name, err)
+		}
+	}
+
+	switch procedure {
+	case "reset":
+		data, err := fs.ReadFile(i.files, filename)
+		if err != nil {
+			return raiseError(inOutErrorClass, s.Pos, "Reset: %v", describeFileError(file.Name, err))
+		}
+		file.reader = newTextReader(bytes.NewReader(data))
+		file.mode = fileReading
+	case "rewrite":
+		// Файл создается или очищается сразу, а записанное сохраняется при закрытии
+		if err := i.files.WriteFile(filename, nil); err != nil {
+			return raiseError(inOutErrorClass, s.Pos, "Rewrite: %v", describeFileError(file.Name, err))
+		}
+		file.writer = &bytes.Buffer{}
+		file.mode = fileWriting
+	case "append":
+		// Несуществующий файл создается, как при Rewrite
+		data, err := fs.ReadFile(i.files, filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = i.files.WriteFile(filename, nil)
+		}
+		if err != nil {
+			return raiseError(inOutErrorClass, s.Pos, "Append: %v", describeFileError(file.Name, err))
+		}
+		file.writer = bytes.NewBuffer(data)
+		file.mode = fileWriting
+	}
+	i.open = append(i.open, file)
+	return nil
+}
+
+// closeFile закрывает файл; содержимое файла, открытого для записи, сохраняется в файловой системе
+func (i *Interpreter) closeFile(file *FileValue) error {
+	for n, open := range i.open {
+		if open == file {
+			i.open = append(i.open[:n], i.open[n+1:]...)
+			break
+		}
+	}
+	mode := file.mode
+	file.mode, file.reader = fileClosed, nil
+	if mode != fileWriting {
+		return nil
+	}
+	data := file.writer.Bytes()
+	file.writer = nil
+	filename, err := filePath(file.Name)
+	if err != nil {
+		return err
+	}
+	if err := i.files.WriteFile(filename, data); err != nil {
+		return describeFileError(file.Name, err)
+	}
+	return nil
+}
+
+// closeFiles закрывает файлы, оставшиеся открытыми к концу программы
+func (i *Interpreter) closeFiles() error {
+	for len(i.open) > 0 {
+		file := i.open[0]
+		if err := i.closeFile(file); err != nil {
+			return raiseError(inOutErrorClass, Position{}, "закрытие файла %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// flushFiles закрывает открытые файлы после ошибки выполнения: записанное до ошибки
+// сохраняется, а ошибки закрытия не заменяют ошибку программы
+func (i *Interpreter) flushFiles() {
+	for len(i.open) > 0 {
+		i.closeFile(i.open[0])
+	}
+}
+
+// evaluateFileFunction вычисляет Eof или Eoln для файла, заданного аргументом, или для стандартного ввода
+func (i *Interpreter) evaluateFileFunction(e *CallExpr) (Value, error) {
+	if len(e.Args) > 1 {
+		return nil, runtimeError(e.Pos, "функция %s ожидает не более 1 аргумента, получено %d", e.Name, len(e.Args))
+	}
+	reader := i.input
+	if len(e.Args) == 1 {
+		value, err := i.evaluateExpression(e.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		file, ok := value.(*FileValue)
+		if !ok {
+			return nil, runtimeError(e.Pos, "%s: ожидалась файловая переменная, получен %s", e.Name, typeOfValue(value))
+		}
+		if file.mode != fileReading {
+			return nil, raiseError(inOutErrorClass, e.Pos, "%s: файл %s не открыт для чтения", e.Name, file)
+		}
+		reader = file.reader
+	}
+	if strings.EqualFold(e.Name, "eof") {
+		return BooleanValue(reader.eof()), nil
+	}
+	return BooleanValue(reader.eoln()), nil
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// runWithOptions выполняет программу после семантического анализа в заданном окружении
+func runWithOptions(t *testing.T, code string, options Options) (*Interpreter, error) {
+	t.Helper()
+	program, err := checkCode(t, code)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	interpreter := NewInterpreterWithOptions(options)
+	return interpreter, interpreter.Interpret(program)
+}
+
+// TestLexerStrings тестирует строковые литералы с удвоенными апострофами
+func TestLexerStrings(t *testing.T) {
+	tokens, err := NewLexer(`x := 'it''s' + ''`).Tokenize()
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if tokens[2].Type != TokenSTRING || tokens[2].Value != `'it''s'` {
+		t.Errorf("Ожидался токен строки 'it''s', получено %v", tokens[2])
+	}
+	if tokens[4].Type != TokenSTRING || tokens[4].Value != `''` {
+		t.Errorf("Ожидался токен пустой строки, получено %v", tokens[4])
+	}
+
+	for _, code := range []string{"x := 'abc", "x := 'abc\n'"} {
+		if _, err := NewLexer(code).Tokenize(); err == nil || !strings.Contains(err.Error(), "незакрытая строка") {
+			t.Errorf("%q: ожидалась ошибка незакрытой строки, получено %v", code, err)
+		}
+	}
+}
+
+// TestParseStringsAndWrite тестирует разбор литералов, параметров Write и вызовов без скобок
+func TestParseStringsAndWrite(t *testing.T) {
+	program := parseCode(t, `BEGIN c := 'a'; s := 'don''t'; e := ''; WriteLn(x:8:2, 'y':3); WriteLn; ReadLn END.`)
+	want := []string{
+		"Assignment(c := Literal('a'))",
+		"Assignment(s := Literal('don''t'))",
+		"Assignment(e := Literal(''))",
+		"CallStatement(WriteLn(Format(Identifier(x):Number(8):Number(2)), Format(Literal('y'):Number(3))))",
+		"CallStatement(WriteLn())",
+		"CallStatement(ReadLn())",
+	}
+	for n, stmt := range program.Statements {
+		if stmt.String() != want[n] {
+			t.Errorf("Оператор %d: ожидалось %q, получено %q", n, want[n], stmt.String())
+		}
+	}
+	if _, ok := program.Statements[0].(*Assignment).Value.(*Literal).Value.(CharValue); !ok {
+		t.Error("Литерал из одного символа должен иметь тип CHAR")
+	}
+
+	// Ширина поля допустима только в Write и WriteLn
+	for _, code := range []string{"BEGIN x := Abs(1:2) END.", "BEGIN Write(x:) END.", "BEGIN Foo END."} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", code, err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestStringsAndChars тестирует операции над строками и символами
+func TestStringsAndChars(t *testing.T) {
+	code := `CONST Greeting = 'Hello';
+TYPE Letters = 'a'..'z';
+VAR s: STRING; c: CHAR; l: Letters; vowels: SET OF CHAR; kind: INTEGER;
+BEGIN
+  s := Greeting + ', ' + 'world' + '!';
+  c := 'q';
+  l := Succ(c);
+  vowels := ['a', 'e', 'i', 'o', 'u'];
+  isVowel := 'e' IN vowels;
+  less := 'abc' < 'abd';
+  equal := s = 'Hello, world!';
+  n := Length(s) + Ord('A');
+  d := Chr(Ord('0') + 7);
+  str := c;
+  CASE l OF
+    'a'..'m': kind := 1;
+    'n'..'z': kind := 2
+  END
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		values := interpreter.Values()
+		want := map[string]string{
+			"s":       "'Hello, world!'",
+			"c":       "'q'",
+			"l":       "'r'",
+			"vowels":  "['a', 'e', 'i', 'o', 'u']",
+			"isVowel": "TRUE",
+			"less":    "TRUE",
+			"equal":   "TRUE",
+			"n":       "78",
+			"d":       "'7'",
+			"str":     "'q'",
+			"kind":    "2",
+		}
+		for name, value := range want {
+			if values[name] == nil || values[name].String() != value {
+				t.Errorf("Ожидалось %s = %s, получено %v", name, value, values[name])
+			}
+		}
+	}
+}
+
+// TestWrite тестирует вывод Write и WriteLn с шириной поля и точностью
+func TestWrite(t *testing.T) {
+	code := `TYPE T = (Red, Green);
+VAR x: REAL; n: INTEGER; b: BOOLEAN; c: T;
+BEGIN
+  x := 3.14159; n := 42; b := TRUE; c := Green;
+  Write('n=', n, ' x=', x);
+  WriteLn;
+  WriteLn(n:5, x:8:3, x:5:1, b:6, c:7, 'ab':4, 'Я':2);
+  WriteLn(1 / 4, ' ', 2.0, ' ', -7 DIV 2)
+END.`
+	var out bytes.Buffer
+	if _, err := runWithOptions(t, code, Options{Stdout: &out}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	want := "n=42 x=3.14159\n   42   3.142  3.1  TRUE  Green  ab Я\n0.25 2 -3\n"
+	if out.String() != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out.String())
+	}
+}
+
+// TestReadStandardInput тестирует Read, ReadLn, Eof и Eoln для стандартного ввода
+func TestReadStandardInput(t *testing.T) {
+	code := `VAR a, b: INTEGER; x: REAL; c: CHAR; line: STRING;
+BEGIN
+  Read(a, b);
+  ReadLn(x);
+  Read(c);
+  ReadLn(line);
+  lineEnd := Eoln;
+  sum := 0;
+  WHILE NOT Eof DO
+  BEGIN
+    ReadLn(k);
+    sum := sum + k
+  END
+END.`
+	input := "3 4\n 2.5 ignored\n>rest of line\n1\n2\n3\n"
+	interpreter, err := runWithOptions(t, code, Options{Stdin: strings.NewReader(input)})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	values := interpreter.Values()
+	want := map[string]string{"a": "3", "b": "4", "x": "2.5", "c": "'>'", "line": "'rest of line'", "lineEnd": "FALSE", "sum": "6"}
+	for name, value := range want {
+		if values[name] == nil || values[name].String() != value {
+			t.Errorf("Ожидалось %s = %s, получено %v", name, value, values[name])
+		}
+	}
+}
+
+// TestTextFiles тестирует чтение и запись файлов в файловой системе в памяти
+func TestTextFiles(t *testing.T) {
+	base := fstest.MapFS{
+		"data/numbers.txt": {Data: []byte("Numbers\n1\n2\n3\n")},
+		"log.txt":          {Data: []byte("first\n")},
+	}
+	files := NewOverlayFS(base)
+	code := `VAR f, out, log: TEXT; title: STRING; n, sum: INTEGER;
+BEGIN
+  Assign(f, 'data/numbers.txt');
+  Reset(f);
+  ReadLn(f, title);
+  WHILE NOT Eof(f) DO
+  BEGIN
+    ReadLn(f, n);
+    sum := sum + n
+  END;
+  Close(f);
+
+  Assign(out, 'data\report.txt');
+  Rewrite(out);
+  WriteLn(out, title, ': ', sum);
+  Write(out, 'end');
+  Close(out);
+
+  Assign(log, 'log.txt');
+  Append(log);
+  WriteLn(log, 'second');
+
+  { Перечитываем только что записанный файл }
+  Reset(f);
+  ReadLn(f, title)
+END.`
+	interpreter, err := runWithOptions(t, code, Options{Files: files})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	values := interpreter.Values()
+	if values["sum"] != IntegerValue(6) || values["title"] != StringValue("Numbers") {
+		t.Errorf("Ожидалось sum = 6 и title = 'Numbers', получено %v и %v", values["sum"], values["title"])
+	}
+	if values["out"].String() != `TEXT('data\report.txt')` {
+		t.Errorf("Неожиданное значение файловой переменной: %v", values["out"])
+	}
+
+	tests := map[string]string{
+		"data/report.txt": "Numbers: 6\nend",
+		// Файл, не закрытый программой, сохраняется при ее завершении
+		"log.txt": "first\nsecond\n",
+	}
+	for name, want := range tests {
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			t.Fatalf("Ошибка чтения %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: ожидалось %q, получено %q", name, want, data)
+		}
+	}
+	// Базовая файловая система не изменяется
+	if string(base["log.txt"].Data) != "first\n" {
+		t.Errorf("Базовая файловая система изменена: %q", base["log.txt"].Data)
+	}
+}
+
+// TestFilesAfterError тестирует, что Rewrite сразу очищает файл, а записанное до
+// ошибки выполнения сохраняется
+func TestFilesAfterError(t *testing.T) {
+	files := NewOverlayFS(fstest.MapFS{"log.txt": {Data: []byte("old\n")}})
+	code := `VAR f, g: TEXT; z: INTEGER; empty: BOOLEAN;
+BEGIN
+  Assign(f, 'log.txt');
+  Rewrite(f);
+  Assign(g, 'log.txt');
+  Reset(g);
+  empty := Eof(g);
+  WriteLn(f, 'step 1');
+  z := 1 DIV z
+END.`
+	interpreter, err := runWithOptions(t, code, Options{Files: files})
+	if err == nil || !strings.Contains(err.Error(), "деление на ноль") {
+		t.Fatalf("Ожидалась ошибка деления на ноль, получено %v", err)
+	}
+	if interpreter.Values()["empty"] != BooleanValue(true) {
+		t.Error("Ожидалось, что Rewrite очистит файл сразу")
+	}
+	data, err := fs.ReadFile(files, "log.txt")
+	if err != nil || string(data) != "step 1\n" {
+		t.Errorf("Ожидалось содержимое \"step 1\\n\", получено %q, %v", data, err)
+	}
+}
+
+// TestFileRuntimeErrors тестирует ошибки файловых процедур при выполнении
+func TestFileRuntimeErrors(t *testing.T) {
+	base := fstest.MapFS{"in.txt": {Data: []byte("12 abc\n")}}
+	decls := "VAR f: TEXT; n: INTEGER;\n"
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN Assign(f, 'missing.txt'); Reset(f) END.", "Reset: файл 'missing.txt' не найден"},
+		{"BEGIN Assign(f, '../secret.txt'); Rewrite(f) END.", "недопустимое имя файла '../secret.txt'"},
+		{"BEGIN Assign(f, '/etc/passwd'); Reset(f) END.", "недопустимое имя файла '/etc/passwd'"},
+		{"BEGIN Reset(f) END.", "Reset: файлу f не назначено имя процедурой Assign"},
+		{"BEGIN Close(f) END.", "Close: файл TEXT не открыт"},
+		{"BEGIN Assign(f, 'in.txt'); WriteLn(f, 1) END.", "WriteLn: файл TEXT('in.txt') не открыт для записи"},
+		{"BEGIN Assign(f, 'out.txt'); Rewrite(f); Read(f, n) END.", "Read: файл TEXT('out.txt') не открыт для чтения"},
+		{"BEGIN Assign(f, 'out.txt'); Rewrite(f); x := Eof(f) END.", "Eof: файл TEXT('out.txt') не открыт для чтения"},
+		{"BEGIN Assign(f, 'in.txt'); Reset(f); Assign(f, 'x') END.", "Assign: файл TEXT('in.txt') открыт"},
+		{"BEGIN Assign(f, 'in.txt'); Reset(f); Read(f, n, n) END.", "Read: ожидалось целое число, прочитано 'abc'"},
+		{"BEGIN Assign(f, 'in.txt'); Reset(f); ReadLn(f); Read(f, n) END.", "Read: чтение за концом файла in.txt"},
+	}
+	for _, tt := range tests {
+		_, err := runWithOptions(t, decls+tt.code, Options{Files: NewOverlayFS(base)})
+		if _, ok := err.(*RuntimeError); !ok || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка выполнения %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestCheckerStringsAndFiles тестирует семантические ошибки строк и ввода-вывода
+func TestCheckerStringsAndFiles(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"VAR c: CHAR; BEGIN c := 'ab' END.", "нельзя присвоить STRING переменной c типа CHAR"},
+		{"VAR s: STRING; BEGIN s := 1 END.", "нельзя присвоить INTEGER переменной s типа STRING"},
+		{"BEGIN x := 'a' - 'b' END.", "операция - неприменима к строкам"},
+		{"BEGIN x := 'ab' + 1 END.", "несовместимые типы операндов: STRING и INTEGER"},
+		{"BEGIN x := 'ab' < 1 END.", "несравнимые типы операндов: STRING и INTEGER"},
+		{"VAR f, g: TEXT; BEGIN f := g END.", "нельзя присвоить TEXT переменной f типа TEXT"},
+		{"VAR f, g: TEXT; BEGIN x := f = g END.", "операция = неприменима к файлам"},
+		{"VAR n: INTEGER; BEGIN Reset(n) END.", "Reset: n не является файловой переменной"},
+		{"VAR f: TEXT; BEGIN Assign(f, 1) END.", "Assign: имя файла должно быть строкой, получено INTEGER"},
+		{"VAR f: TEXT; BEGIN Assign(f) END.", "процедура Assign ожидает 2 аргумент(ов), получено 1"},
+		{"BEGIN Rewrite(f) END.", "Rewrite: переменная f не описана"},
+		{"VAR b: BOOLEAN; BEGIN Read(b) END.", "Read: нельзя прочитать значение типа BOOLEAN"},
+		{"BEGIN Read(1) END.", "Read: аргумент Number(1) должен быть переменной"},
+		{"TYPE TSet = SET OF 1..3; VAR s: TSet; BEGIN WriteLn(s) END.", "WriteLn: нельзя вывести значение типа TSet"},
+		{"BEGIN WriteLn(1:2:3) END.", "точность задается только для вещественных значений"},
+		{"BEGIN WriteLn(1.5:'a') END.", "ширина поля должна быть целой, получено CHAR"},
+		{"VAR n: INTEGER; BEGIN x := Eof(n) END.", "Eof: ожидалась файловая переменная, получен INTEGER"},
+		{"BEGIN x := Chr('a') END.", "Chr: ожидался целый аргумент, получен CHAR"},
+		{"BEGIN x := Length(5) END.", "Length: ожидалась строка, получен INTEGER"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestDirFS тестирует запись в каталог на диске и запрет выхода за его пределы
+func TestDirFS(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "in.txt"), []byte("7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code := `VAR f: TEXT; n: INTEGER;
+BEGIN
+  Assign(f, 'in.txt'); Reset(f); Read(f, n); Close(f);
+  Assign(f, 'out.txt'); Rewrite(f); WriteLn(f, n * 6); Close(f)
+END.`
+	if _, err := runWithOptions(t, code, Options{Files: NewDirFS(root)}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "out.txt"))
+	if err != nil || string(data) != "42\n" {
+		t.Errorf("Ожидалось содержимое \"42\\n\", получено %q, %v", data, err)
+	}
+	if err := NewDirFS(root).WriteFile("../escape.txt", nil); err == nil {
+		t.Error("Ожидалась ошибка записи за пределы каталога")
+	}
+}
+
+// TestDirFSSymlinks тестирует, что символические ссылки не выводят за пределы каталога
+func TestDirFSSymlinks(t *testing.T) {
+	outside, root := t.TempDir(), t.TempDir()
+	for name, data := range map[string]string{
+		filepath.Join(outside, "secret.txt"): "секрет\n",
+		filepath.Join(root, "in.txt"):        "7\n",
+	} {
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"link.txt":     filepath.Join(outside, "secret.txt"),
+		"new.txt":      filepath.Join(outside, "new.txt"),
+		"dir":          outside,
+		"relative.txt": filepath.Join("..", filepath.Base(outside), "secret.txt"),
+		"inner.txt":    "in.txt",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skipf("символические ссылки не поддерживаются: %v", err)
+		}
+	}
+
+	tests := []struct {
+		code string
+		err  string
+	}{
+		{"VAR f: TEXT; s: STRING; BEGIN Assign(f, 'link.txt'); Reset(f); ReadLn(f, s) END.", "Reset: нет доступа к файлу 'link.txt'"},
+		{"VAR f: TEXT; s: STRING; BEGIN Assign(f, 'relative.txt'); Reset(f); ReadLn(f, s) END.", "Reset: нет доступа к файлу 'relative.txt'"},
+		{"VAR f: TEXT; s: STRING; BEGIN Assign(f, 'dir/secret.txt'); Reset(f); ReadLn(f, s) END.", "Reset: нет доступа к файлу 'dir/secret.txt'"},
+		{"VAR f: TEXT; BEGIN Assign(f, 'link.txt'); Append(f); WriteLn(f, 1) END.", "Append: нет доступа к файлу 'link.txt'"},
+		{"VAR f: TEXT; BEGIN Assign(f, 'new.txt'); Rewrite(f); WriteLn(f, 1); Close(f) END.", "Rewrite: нет доступа к файлу 'new.txt'"},
+		{"VAR f: TEXT; BEGIN Assign(f, 'dir/new.txt'); Rewrite(f); WriteLn(f, 1); Close(f) END.", "Rewrite: нет доступа к файлу 'dir/new.txt'"},
+		// Ссылка внутри каталога допустима
+		{"VAR f: TEXT; n: INTEGER; BEGIN Assign(f, 'inner.txt'); Reset(f); Read(f, n); Close(f) END.", ""},
+	}
+	for _, tt := range tests {
+		_, err := runWithOptions(t, tt.code, Options{Files: NewDirFS(root)})
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%q: неожиданная ошибка: %v", tt.code, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.err, err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(outside, "secret.txt"))
+	if err != nil || string(data) != "секрет\n" {
+		t.Errorf("Файл вне каталога изменен: %q, %v", data, err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 1 {
+		t.Errorf("В каталоге вне корня созданы файлы: %v", entries)
+	}
+}
+
+// TestMainRootFlag тестирует флаг -root и вывод программы перед словарем переменных
+func TestMainRootFlag(t *testing.T) {
+	root := t.TempDir()
+	program := filepath.Join(root, "prog.pas")
+	code := "VAR f: TEXT; BEGIN WriteLn('hi'); Assign(f, 'result.txt'); Rewrite(f); Write(f, 'ok'); Close(f) END."
+	if err := os.WriteFile(program, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs, oldStdout := os.Args, os.Stdout
+	defer func() { os.Args, os.Stdout = oldArgs, oldStdout }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	os.Args = []string{"pascal", "-root", root, program}
+	exitCode := mainWithExitCode()
+	w.Close()
+	var out bytes.Buffer
+	out.ReadFrom(r)
+
+	if exitCode != 0 {
+		t.Fatalf("Ожидался код выхода 0, получено %d", exitCode)
+	}
+	if want := "hi\n{f: TEXT('result.txt')}\n"; out.String() != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out.String())
+	}
+	data, err := os.ReadFile(filepath.Join(root, "result.txt"))
+	if err != nil || string(data) != "ok" {
+		t.Errorf("Ожидалось содержимое \"ok\", получено %q, %v", data, err)
+	}
+}
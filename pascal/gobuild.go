@@ -0,0 +1,2184 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	goast "go/ast"
+	goformat "go/format"
+	goparser "go/parser"
+	gotoken "go/token"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// goRuntime - среда выполнения транслированных программ: исключения, контроль
+// переполнения и диапазонов, множества, куча, файлы и вывод переменных. Генератор
+// дописывает ее описания после кода программы.
+//
+//go:embed goruntime/runtime.go
+var goRuntime string
+
+// goRuntimeParts разделяет среду выполнения на список импорта и описания
+func goRuntimeParts() (imports, body string) {
+	start := strings.Index(goRuntime, "import (")
+	end := start + strings.Index(goRuntime[start:], "\n)\n") + len("\n)\n")
+	return goRuntime[start:end], goRuntime[end:]
+}
+
+// Ключевые слова и стандартные имена Go, которые не могут получить имена программы
+const (
+	goKeywords = "break case chan const continue default defer else fallthrough for func go goto if " +
+		"import interface map package range return select struct switch type var"
+	goPredeclared = "any append bool byte cap clear close comparable complex complex64 complex128 copy " +
+		"delete error false float32 float64 imag int int8 int16 int32 int64 iota len make max min new nil " +
+		"panic print println real recover rune string true uint uint8 uint16 uint32 uint64 uintptr"
+)
+
+// goReservedNames возвращает имена, занятые в транслированной программе: ключевые слова
+// и стандартные имена Go, импортированные пакеты и описания среды выполнения
+func goReservedNames() map[string]bool {
+	names := map[string]bool{"_": true, "main": true, "init": true}
+	for _, name := range strings.Fields(goKeywords + " " + goPredeclared) {
+		names[name] = true
+	}
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "runtime.go", goRuntime, 0)
+	if err != nil {
+		panic(fmt.Sprintf("среда выполнения Go не разбирается: %v", err))
+	}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		names[path.Base(importPath)] = true
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *goast.FuncDecl:
+			if d.Recv == nil {
+				names[d.Name.Name] = true
+			}
+		case *goast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *goast.TypeSpec:
+					names[s.Name.Name] = true
+				case *goast.ValueSpec:
+					for _, name := range s.Names {
+						names[name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// goScope - область видимости имен транслированной программы. Имя не совпадает ни с одним
+// именем объемлющих областей, поэтому сгенерированный код не скрывает одни имена другими.
+type goScope struct {
+	names  map[string]bool
+	parent *goScope
+}
+
+func newGoScope(parent *goScope) *goScope {
+	return &goScope{names: make(map[string]bool), parent: parent}
+}
+
+func (s *goScope) taken(name string) bool {
+	for scope := s; scope != nil; scope = scope.parent {
+		if scope.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// declare описывает имя в области видимости; занятое имя дополняется номером: x2, x3, ...
+func (s *goScope) declare(name string) string {
+	candidate := name
+	for n := 2; s.taken(candidate); n++ {
+		candidate = name + strconv.Itoa(n)
+	}
+	s.names[candidate] = true
+	return candidate
+}
+
+// Приоритеты операций Go для расстановки скобок
+const (
+	goPrecOr = iota + 1
+	goPrecAnd
+	goPrecCompare
+	goPrecAdd
+	goPrecMultiply
+	goPrecUnary
+	goPrecPrimary
+)
+
+// goCode - выражение Go и приоритет его внешней операции
+type goCode struct {
+	text string
+	prec int
+}
+
+func goPrimary(text string) goCode {
+	return goCode{text: text, prec: goPrecPrimary}
+}
+
+// wrap заключает выражение в скобки, если его операция связывает слабее prec
+func (c goCode) wrap(prec int) string {
+	if c.prec < prec {
+		return "(" + c.text + ")"
+	}
+	return c.text
+}
+
+// goFunction - транслируемая подпрограмма, тело программы или раздел инициализации модуля
+type goFunction struct {
+	decl     *RoutineDecl // nil для тела программы и модуля
+	symbol   *Symbol      // функция, имени которой присваивается результат; nil для процедуры
+	result   string       // имя результата функции в Go
+	scope    *goScope
+	parent   *goFunction
+	implicit map[string]*Symbol // неописанные переменные подпрограммы, ключ - имя в нижнем регистре
+	loops    []*goLoop
+	closures []*goClosure
+	handlers []string // имена обрабатываемых исключений для RAISE без выражения
+	switches int      // глубина вложенности switch, из которого break не выходит из цикла
+	labels   int
+}
+
+// goLoop - цикл, в котором транслируются Break и Continue
+type goLoop struct {
+	label    string // метка цикла; задается, когда она нужна break из switch
+	switches int    // глубина вложенности switch в начале цикла
+	last     string // для FOR: условие последней итерации, которое проверяет Continue
+}
+
+// goClosure - функция, в которой выполняется тело или обработчик оператора TRY: Break,
+// Continue и Exit из нее возвращают flow, который обрабатывается после вызова
+type goClosure struct {
+	loops   int // число циклов подпрограммы вне функции
+	escapes [flowExit + 1]bool
+}
+
+// goGenerator транслирует проверенную программу в исходный текст на Go
+type goGenerator struct {
+	info   *Info
+	global *goScope
+	fields *goScope // имена, которые не могут получить поля записей
+
+	names     map[*Symbol]string // имена переменных, параметров и подпрограмм
+	types     map[*Type]string   // имена перечислений, записей и классов исключений
+	values    map[*Type][]string // имена значений перечислений
+	fieldName map[*Type][]string // имена полей записей
+	typeDecls []*Type            // перечисления, записи и классы в порядке описания
+	byRef     map[*Symbol]bool   // VAR-параметры, которые в Go являются указателями
+	marked    map[*Symbol]bool   // неописанные переменные программы, присваивание которым отмечается в assigned
+	used      map[*Symbol]bool   // переменные и вложенные подпрограммы, значение которых используется
+	implicit  map[string]*Symbol // неописанные глобальные переменные транслируемого модуля или программы
+	inferred  map[*Symbol]*Type  // типы неописанных переменных, которые не определил семантический анализ
+	fn        *goFunction
+	pre       []string // операторы, которые выполняются перед текущим оператором
+	funcs     strings.Builder
+	err       error
+}
+
+func newGoGenerator(info *Info) *goGenerator {
+	reserved := newGoScope(nil)
+	reserved.names = goReservedNames()
+	fields := newGoScope(nil)
+	for _, name := range strings.Fields(goKeywords + " String _") {
+		fields.names[name] = true
+	}
+	g := &goGenerator{
+		info:      info,
+		global:    newGoScope(reserved),
+		fields:    fields,
+		names:     make(map[*Symbol]string),
+		types:     make(map[*Type]string),
+		values:    make(map[*Type][]string),
+		fieldName: make(map[*Type][]string),
+		byRef:     make(map[*Symbol]bool),
+		marked:    make(map[*Symbol]bool),
+		used:      make(map[*Symbol]bool),
+		inferred:  make(map[*Symbol]*Type),
+	}
+	for _, t := range predeclaredTypes {
+		if t.Kind == TypeClass {
+			// Стандартные классы описаны в среде выполнения под своими именами
+			g.types[t] = t.Name
+		}
+	}
+	return g
+}
+
+// buildGo транслирует программу, проверенную семантическим анализатором с заполнением
+// info, в отформатированный исходный текст на Go. source - имя исходного файла для заголовка.
+func buildGo(program *Program, info *Info, source string) ([]byte, error) {
+	g := newGoGenerator(info)
+	code := g.program(program, source)
+	if g.err != nil {
+		return nil, g.err
+	}
+	formatted, err := goformat.Source([]byte(code))
+	if err != nil {
+		return nil, fmt.Errorf("внутренняя ошибка: сгенерированный код не разбирается: %v", err)
+	}
+	return formatted, nil
+}
+
+// fail запоминает первую ошибку трансляции
+func (g *goGenerator) fail(pos Position, format string, args ...interface{}) {
+	if g.err != nil {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	if pos.IsValid() {
+		g.err = fmt.Errorf("%s: %s", pos, message)
+	} else {
+		g.err = fmt.Errorf("%s", message)
+	}
+}
+
+// unsupported сообщает о конструкции, которую транслятор не поддерживает
+func (g *goGenerator) unsupported(pos Position, what string) {
+	g.fail(pos, "%s не поддерживается", what)
+}
+
+// where возвращает позицию для сообщений об ошибках среды выполнения
+func (g *goGenerator) where(pos Position) string {
+	if !pos.IsValid() {
+		return `""`
+	}
+	return strconv.Quote(pos.String())
+}
+
+// goUnits возвращает модули в порядке инициализации: модуль следует за модулями, которые он использует
+func goUnits(uses []*UnitRef, seen map[*Unit]bool, order []*Unit) []*Unit {
+	for _, ref := range uses {
+		if ref.Unit == nil || seen[ref.Unit] {
+			continue
+		}
+		seen[ref.Unit] = true
+		order = goUnits(ref.Unit.allUses(), seen, order)
+		order = append(order, ref.Unit)
+	}
+	return order
+}
+
+// program транслирует программу вместе с ее модулями
+func (g *goGenerator) program(program *Program, source string) string {
+	units := goUnits(program.Uses, make(map[*Unit]bool), nil)
+	blocks := []*Declarations{&program.Declarations}
+	for _, unit := range units {
+		blocks = append(blocks, &unit.Interface, &unit.Implementation)
+	}
+	for _, named := range []bool{true, false} {
+		for _, block := range blocks {
+			g.declareTypes(block, named)
+		}
+	}
+
+	// Имена глобальных переменных и подпрограмм: сначала программы, затем модулей
+	programImplicit := g.declareGlobals(program.Statements, &program.Declarations)
+	unitImplicit := make([]map[string]*Symbol, len(units))
+	for n, unit := range units {
+		unitImplicit[n] = g.declareGlobals(unit.Statements, &unit.Interface, &unit.Implementation)
+	}
+	g.markAssignments(program.Statements, programImplicit)
+	programName := g.global.declare("program")
+
+	var inits []string
+	for n, unit := range units {
+		g.implicit = unitImplicit[n]
+		g.routines(&unit.Interface)
+		g.routines(&unit.Implementation)
+		if len(unit.Statements) > 0 {
+			name := g.global.declare("init" + upperFirst(unit.Name))
+			g.funcs.WriteString(g.body("// "+name+" выполняет раздел инициализации модуля "+unit.Name, name, unit.Statements))
+			inits = append(inits, name)
+		}
+	}
+	g.implicit = programImplicit
+	g.routines(&program.Declarations)
+	g.funcs.WriteString(g.body("// "+programName+" выполняет тело программы", programName, program.Statements))
+
+	imports, runtime := goRuntimeParts()
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by pascal build from %s. DO NOT EDIT.\n\npackage main\n\n%s\n", source, imports)
+	g.typeDeclarations(&b)
+	b.WriteString("var (\n")
+	g.variables(&b, &program.Declarations, program.Statements)
+	for _, unit := range units {
+		fmt.Fprintf(&b, "\n// модуль %s\n", unit.Name)
+		g.variables(&b, &unit.Interface, nil)
+		g.variables(&b, &unit.Implementation, unit.Statements)
+	}
+	b.WriteString(")\n\n")
+	b.WriteString(g.funcs.String())
+
+	b.WriteString("\nfunc main() {\ndefer finish()\nrun(func() {\n")
+	for _, name := range inits {
+		b.WriteString(name + "()\n")
+	}
+	fmt.Fprintf(&b, "%s()\n})\ncloseFiles()\n", programName)
+	g.dump(&b, &program.Declarations, program.Statements)
+	b.WriteString("}\n")
+	b.WriteString(runtime)
+	return b.String()
+}
+
+// upperFirst делает первую букву имени заглавной
+func upperFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// declareTypes дает имена Go перечислениям, записям и классам раздела описаний и вложенных
+// подпрограмм: сначала описанным в разделах TYPE (named), затем анонимным типам переменных
+func (g *goGenerator) declareTypes(d *Declarations, named bool) {
+	if named {
+		for _, decl := range d.Types {
+			if symbol := g.info.Symbols[decl]; symbol != nil {
+				g.nameType(symbol.Type, decl.Name)
+			}
+		}
+	} else {
+		for _, decl := range d.Vars {
+			if symbol := g.info.Symbols[decl]; symbol != nil {
+				g.nameType(symbol.Type, decl.Name+"Type")
+			}
+		}
+	}
+	for _, decl := range d.Routines {
+		g.declareTypes(&decl.Declarations, named)
+	}
+}
+
+// nameType дает имя типу t и типам, из которых он состоит; hint - имя анонимного типа
+func (g *goGenerator) nameType(t *Type, hint string) {
+	if t == nil {
+		return
+	}
+	switch t.Kind {
+	case TypeEnum, TypeRecord, TypeClass:
+		if _, ok := g.types[t]; ok {
+			return
+		}
+		name := t.Name
+		if name == "" {
+			name = hint
+		}
+		name = g.global.declare(name)
+		g.types[t] = name
+		g.typeDecls = append(g.typeDecls, t)
+		switch t.Kind {
+		case TypeEnum:
+			for _, value := range t.Values {
+				g.values[t] = append(g.values[t], g.global.declare(value))
+			}
+		case TypeRecord:
+			scope := newGoScope(g.fields)
+			for _, field := range t.Fields {
+				g.fieldName[t] = append(g.fieldName[t], scope.declare(field.Name))
+			}
+			for _, field := range t.Fields {
+				g.nameType(field.Type, name+upperFirst(field.Name))
+			}
+		case TypeClass:
+			g.nameType(t.Parent, "")
+		}
+	case TypeSet:
+		g.nameType(t.Elem, hint+"Element")
+	case TypeSubrange:
+		g.nameType(t.Base, hint)
+	case TypePointer:
+		g.nameType(t.Elem, hint+"Elem")
+	}
+}
+
+// typeName возвращает имя Go перечисления, записи или класса
+func (g *goGenerator) typeName(t *Type) string {
+	if _, ok := g.types[t]; !ok {
+		g.nameType(t, "type")
+	}
+	return g.types[t]
+}
+
+// declareGlobals дает имена глобальным переменным и подпрограммам программы или модуля
+// и возвращает неописанные переменные его операторов
+func (g *goGenerator) declareGlobals(statements []Statement, blocks ...*Declarations) map[string]*Symbol {
+	for _, d := range blocks {
+		for _, decl := range d.Vars {
+			if symbol := g.info.Symbols[decl]; symbol != nil {
+				g.names[symbol] = g.global.declare(decl.Name)
+			}
+		}
+	}
+	implicit := make(map[string]*Symbol)
+	for _, symbol := range g.implicitSymbols(statements) {
+		g.names[symbol] = g.global.declare(symbol.Name)
+		implicit[strings.ToLower(symbol.Name)] = symbol
+	}
+	for _, d := range blocks {
+		for _, decl := range d.Routines {
+			if symbol := g.info.Symbols[decl]; symbol != nil && g.names[symbol] == "" {
+				g.names[symbol] = g.global.declare(decl.Name)
+			}
+		}
+	}
+	return implicit
+}
+
+// implicitSymbols возвращает неописанные переменные, созданные операторами блока,
+// в порядке их появления; операторы вложенных подпрограмм не просматриваются
+func (g *goGenerator) implicitSymbols(statements []Statement) []*Symbol {
+	var symbols []*Symbol
+	seen := make(map[*Symbol]bool)
+	for _, stmt := range statements {
+		Inspect(stmt, func(node Node) bool {
+			switch node.(type) {
+			case *Assignment, *ForStatement, *Identifier:
+			default:
+				return true
+			}
+			symbol := g.info.Symbols[node]
+			if symbol == nil || !symbol.Implicit || symbol.Kind != SymbolVar {
+				return true
+			}
+			if s, ok := node.(*Assignment); ok && symbol.Type == nil {
+				g.infer(symbol, s)
+			}
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+			return true
+		})
+	}
+	return symbols
+}
+
+// infer определяет тип неописанной переменной по присваиваемому значению, тип которого
+// семантический анализ не определил, например, [z] или z < 1 с неописанной z
+func (g *goGenerator) infer(symbol *Symbol, s *Assignment) {
+	t := g.typeOf(s.Value)
+	if previous := g.inferred[symbol]; previous == nil {
+		g.inferred[symbol] = t
+	} else if previous.Kind != t.Kind {
+		g.unsupported(s.Pos, "неописанная переменная "+symbol.Name+", меняющая тип,")
+	}
+}
+
+// varType возвращает тип переменной с учетом типов, определенных infer
+func (g *goGenerator) varType(symbol *Symbol) *Type {
+	if symbol.Type == nil && symbol.Implicit {
+		return g.inferred[symbol]
+	}
+	return symbol.Type
+}
+
+// markAssignments выбирает неописанные переменные программы, присваивание которым нужно
+// отмечать в assigned: в выводе переменных интерпретатора есть только переменные,
+// которым было присвоено значение. Присваивания в начале тела программы до первого
+// оператора, содержащего Exit, выполняются всегда, и их переменные не отмечаются.
+func (g *goGenerator) markAssignments(statements []Statement, implicit map[string]*Symbol) {
+	surely := make(map[*Symbol]bool)
+	var collect func(statements []Statement) bool
+	collect = func(statements []Statement) bool {
+		for _, stmt := range statements {
+			if goContains(stmt, func(node Node) bool { _, ok := node.(*ExitStatement); return ok }) {
+				if block, ok := stmt.(*Block); ok {
+					collect(block.Statements)
+				}
+				return false
+			}
+			switch s := stmt.(type) {
+			case *Block:
+				collect(s.Statements)
+			case *Assignment:
+				if s.Target == nil {
+					surely[g.info.Symbols[s]] = true
+				}
+			case *ForStatement:
+				start, startConstant := constantValue(s.Start)
+				end, endConstant := constantValue(s.End)
+				if startConstant && endConstant && !goEmptyFor(s, start, end) {
+					surely[g.info.Symbols[s]] = true
+				}
+			case *CallStatement:
+				if name := strings.ToLower(s.Name); g.info.Symbols[s] == nil && (name == "read" || name == "readln") {
+					for _, arg := range s.Args {
+						if symbol := g.info.Symbols[arg]; symbol != nil {
+							surely[symbol] = true
+						}
+					}
+				}
+			}
+		}
+		return true
+	}
+	collect(statements)
+	for _, symbol := range implicit {
+		if !surely[symbol] {
+			g.marked[symbol] = true
+		}
+	}
+}
+
+// goEmptyFor сообщает, что цикл FOR с константными границами не выполняется ни разу
+func goEmptyFor(s *ForStatement, start, end Value) bool {
+	low, _ := ordinalOf(start)
+	high, _ := ordinalOf(end)
+	if s.Down {
+		low, high = high, low
+	}
+	return low > high
+}
+
+// goContains сообщает, есть ли в узле node узел, для которого match истинно
+func goContains(node Node, match func(Node) bool) bool {
+	found := false
+	Inspect(node, func(n Node) bool {
+		if n != nil && match(n) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// goHasContinue сообщает, есть ли в операторах Continue, относящийся к объемлющему их циклу
+func goHasContinue(statements []Statement) bool {
+	found := false
+	for _, stmt := range statements {
+		Inspect(stmt, func(node Node) bool {
+			switch node.(type) {
+			case *ContinueStatement:
+				found = true
+			case *WhileStatement, *RepeatStatement, *ForStatement:
+				// Continue во вложенном цикле относится к нему
+				return false
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// Типы
+
+// goType возвращает тип Go для типа Pascal; неизвестный тип - целый
+func (g *goGenerator) goType(t *Type) string {
+	t = baseType(t)
+	if t == nil {
+		return "int64"
+	}
+	switch t.Kind {
+	case TypeReal:
+		return "float64"
+	case TypeBoolean:
+		return "bool"
+	case TypeChar:
+		return "rune"
+	case TypeString:
+		return "string"
+	case TypeText:
+		return "*textFile"
+	case TypeClass:
+		return "*exception"
+	case TypeSet:
+		return "set"
+	case TypePointer:
+		return "pointer"
+	case TypeEnum, TypeRecord:
+		return g.typeName(t)
+	default:
+		return "int64"
+	}
+}
+
+// zeroIsDefault сообщает, совпадает ли начальное значение переменной типа t с нулевым значением Go
+func zeroIsDefault(t *Type) bool {
+	if t == nil {
+		return true
+	}
+	switch t.Kind {
+	case TypeSubrange:
+		return t.Low == 0
+	case TypeText:
+		return false
+	case TypeRecord:
+		for _, field := range t.Fields {
+			if !zeroIsDefault(field.Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// initial возвращает начальное значение переменной типа t, отличное от нулевого значения Go
+func (g *goGenerator) initial(t *Type) string {
+	switch t.Kind {
+	case TypeSubrange:
+		return g.literal(ordinalValue(t.Base, t.Low)).text
+	case TypeText:
+		return "new(textFile)"
+	case TypeRecord:
+		var fields []string
+		for n, field := range t.Fields {
+			if !zeroIsDefault(field.Type) {
+				fields = append(fields, g.fieldName[t][n]+": "+g.initial(field.Type))
+			}
+		}
+		return g.typeName(t) + "{" + strings.Join(fields, ", ") + "}"
+	}
+	return ""
+}
+
+// zero возвращает типизированное начальное значение динамической переменной типа t
+func (g *goGenerator) zero(t *Type) string {
+	if !zeroIsDefault(t) {
+		if t.Kind == TypeSubrange && baseType(t).Kind != TypeEnum {
+			return g.goType(t) + "(" + g.initial(t) + ")"
+		}
+		return g.initial(t)
+	}
+	switch baseType(t).Kind {
+	case TypeReal:
+		return "float64(0)"
+	case TypeBoolean:
+		return "false"
+	case TypeChar:
+		return "rune(0)"
+	case TypeString:
+		return `""`
+	case TypeClass:
+		return "(*exception)(nil)"
+	case TypeSet:
+		return "set{}"
+	case TypePointer:
+		return "null"
+	case TypeEnum:
+		return g.values[baseType(t)][0]
+	case TypeRecord:
+		return g.typeName(t) + "{}"
+	default:
+		return "int64(0)"
+	}
+}
+
+// typeDeclarations описывает перечисления, записи и классы исключений программы
+func (g *goGenerator) typeDeclarations(b *strings.Builder) {
+	var classes []string
+	for _, t := range g.typeDecls {
+		name := g.types[t]
+		switch t.Kind {
+		case TypeEnum:
+			fmt.Fprintf(b, "type %s int64\n\nconst (\n", name)
+			quoted := make([]string, len(t.Values))
+			for n, value := range g.values[t] {
+				if n == 0 {
+					fmt.Fprintf(b, "%s %s = iota\n", value, name)
+				} else {
+					b.WriteString(value + "\n")
+				}
+				quoted[n] = strconv.Quote(t.Values[n])
+			}
+			fmt.Fprintf(b, ")\n\nfunc (v %s) String() string {\nreturn [...]string{%s}[v]\n}\n\n", name, strings.Join(quoted, ", "))
+		case TypeRecord:
+			fmt.Fprintf(b, "type %s struct {\n", name)
+			for n, field := range t.Fields {
+				fmt.Fprintf(b, "%s %s\n", g.fieldName[t][n], g.goType(field.Type))
+			}
+			parts := make([]string, len(t.Fields))
+			for n, field := range t.Fields {
+				separator := "; "
+				if n == 0 {
+					separator = "("
+				}
+				parts[n] = strconv.Quote(separator+field.Name+": ") + " + " + g.format("r."+g.fieldName[t][n], field.Type)
+			}
+			text := `"()"`
+			if len(parts) > 0 {
+				text = strings.Join(parts, " + ") + ` + ")"`
+			}
+			fmt.Fprintf(b, "}\n\nfunc (r %s) String() string {\nreturn %s\n}\n\n", name, text)
+		case TypeClass:
+			classes = append(classes, fmt.Sprintf("%s = &class{name: %q, parent: %s}\n", name, t.Name, g.typeName(t.Parent)))
+		}
+	}
+	if len(classes) > 0 {
+		b.WriteString("// Классы исключений программы\nvar (\n" + strings.Join(classes, "") + ")\n\n")
+	}
+}
+
+// format возвращает выражение, форматирующее значение code типа t, как в выводе переменных
+func (g *goGenerator) format(code string, t *Type) string {
+	base := baseType(t)
+	if base == nil {
+		return "formatInt(" + code + ")"
+	}
+	switch base.Kind {
+	case TypeReal:
+		return "formatReal(" + code + ")"
+	case TypeBoolean:
+		return "formatBool(" + code + ")"
+	case TypeChar:
+		return "formatChar(" + code + ")"
+	case TypeString:
+		return "formatString(" + code + ")"
+	case TypeSet:
+		return code + ".format(" + g.elementFormat(base) + ")"
+	case TypeEnum, TypePointer, TypeRecord, TypeText, TypeClass:
+		return code + ".String()"
+	default:
+		return "formatInt(" + code + ")"
+	}
+}
+
+// elementFormat возвращает функцию, форматирующую элементы множества типа t
+func (g *goGenerator) elementFormat(t *Type) string {
+	elem := baseType(t.Elem)
+	if elem == nil {
+		return "intElement"
+	}
+	switch elem.Kind {
+	case TypeChar:
+		return "charElement"
+	case TypeBoolean:
+		return "boolElement"
+	case TypeEnum:
+		return "element[" + g.typeName(elem) + "]"
+	default:
+		return "intElement"
+	}
+}
+
+// Переменные и подпрограммы
+
+// declaration возвращает описание переменной symbol без слова var
+func (g *goGenerator) declaration(symbol *Symbol) string {
+	name, t := g.names[symbol], g.varType(symbol)
+	if t != nil && !zeroIsDefault(t) {
+		if t.Kind == TypeSubrange && baseType(t).Kind != TypeEnum || t.Kind == TypeText {
+			return name + " " + g.goType(t) + " = " + g.initial(t)
+		}
+		return name + " = " + g.initial(t)
+	}
+	return name + " " + g.goType(t)
+}
+
+// variables описывает глобальные переменные раздела описаний и неописанные переменные его операторов
+func (g *goGenerator) variables(b *strings.Builder, d *Declarations, statements []Statement) {
+	for _, decl := range d.Vars {
+		if symbol := g.info.Symbols[decl]; symbol != nil {
+			b.WriteString(g.declaration(symbol) + "\n")
+		}
+	}
+	for _, symbol := range g.implicitSymbols(statements) {
+		b.WriteString(g.declaration(symbol) + "\n")
+	}
+}
+
+// dump выводит значения переменных программы, как runInterpreter
+func (g *goGenerator) dump(b *strings.Builder, d *Declarations, statements []Statement) {
+	var always, conditional []*Symbol
+	for _, decl := range d.Vars {
+		if symbol := g.info.Symbols[decl]; symbol != nil {
+			always = append(always, symbol)
+		}
+	}
+	for _, symbol := range g.implicitSymbols(statements) {
+		if g.marked[symbol] {
+			conditional = append(conditional, symbol)
+		} else {
+			always = append(always, symbol)
+		}
+	}
+	entry := func(symbol *Symbol) string {
+		return fmt.Sprintf("{%q, %s}", symbol.Name, g.format(g.names[symbol], g.varType(symbol)))
+	}
+	if len(conditional) == 0 {
+		b.WriteString("printVariables([]variable{\n")
+		for _, symbol := range always {
+			b.WriteString(entry(symbol) + ",\n")
+		}
+		b.WriteString("})\n")
+		return
+	}
+	b.WriteString("variables := []variable{\n")
+	for _, symbol := range always {
+		b.WriteString(entry(symbol) + ",\n")
+	}
+	b.WriteString("}\n")
+	for _, symbol := range conditional {
+		fmt.Fprintf(b, "if assigned[%q] {\nvariables = append(variables, variable%s)\n}\n", symbol.Name, entry(symbol))
+	}
+	b.WriteString("printVariables(variables)\n")
+}
+
+// routines транслирует подпрограммы раздела описаний верхнего уровня
+func (g *goGenerator) routines(d *Declarations) {
+	for _, decl := range d.Routines {
+		if decl.Body == nil {
+			continue
+		}
+		_, literal := g.routine(decl)
+		g.funcs.WriteString("func " + g.names[g.info.Symbols[decl]] + strings.TrimPrefix(literal, "func") + "\n\n")
+	}
+}
+
+// body транслирует тело программы или раздел инициализации модуля в функцию name
+func (g *goGenerator) body(comment, name string, statements []Statement) string {
+	g.fn = &goFunction{scope: newGoScope(g.global)}
+	code := g.statements(statements)
+	g.fn = nil
+	return fmt.Sprintf("%s\nfunc %s() {\n%s}\n\n", comment, name, code)
+}
+
+// routine транслирует процедуру или функцию в функциональный литерал и возвращает также
+// его тип. Вложенные подпрограммы - переменные объемлющей функции, что дает им доступ
+// к ее локальным переменным.
+func (g *goGenerator) routine(decl *RoutineDecl) (signature, literal string) {
+	symbol := g.info.Symbols[decl]
+	outer := g.fn
+	scope := g.global
+	if outer != nil {
+		scope = outer.scope
+	}
+	fn := &goFunction{decl: decl, scope: newGoScope(scope), parent: outer, implicit: make(map[string]*Symbol)}
+	if symbol.Kind == SymbolFunction {
+		fn.symbol = symbol
+	}
+	g.fn = fn
+	defer func() { g.fn = outer }()
+
+	var params, paramTypes []string
+	for n, param := range symbol.Signature.Params {
+		paramSymbol := g.info.Symbols[decl.Params[n]]
+		name := fn.scope.declare(param.Name)
+		g.names[paramSymbol] = name
+		t := g.goType(param.Type)
+		if param.ByRef && baseType(param.Type).Kind != TypeText {
+			// Файловая переменная и так ссылается на файл, остальные VAR-параметры - указатели
+			g.byRef[paramSymbol] = true
+			t = "*" + t
+		}
+		params = append(params, name+" "+t)
+		paramTypes = append(paramTypes, t)
+	}
+	var locals []*Symbol
+	for _, v := range decl.Vars {
+		local := g.info.Symbols[v]
+		g.names[local] = fn.scope.declare(v.Name)
+		locals = append(locals, local)
+	}
+	for _, local := range g.implicitSymbols(decl.Body.Statements) {
+		key := strings.ToLower(local.Name)
+		if g.implicitVariable(key) != nil {
+			g.fail(local.Pos, "неописанная переменная %s в нескольких областях видимости не поддерживается", local.Name)
+		}
+		g.names[local] = fn.scope.declare(local.Name)
+		fn.implicit[key] = local
+		locals = append(locals, local)
+	}
+	var nested []*RoutineDecl
+	for _, r := range decl.Routines {
+		g.names[g.info.Symbols[r]] = fn.scope.declare(r.Name)
+		nested = append(nested, r)
+	}
+	var nestedCode strings.Builder
+	for _, r := range nested {
+		name := g.names[g.info.Symbols[r]]
+		nestedSignature, nestedLiteral := g.routine(r)
+		fmt.Fprintf(&nestedCode, "var %s %s\n%s = %s\n", name, nestedSignature, name, nestedLiteral)
+	}
+	var result string
+	if fn.symbol != nil {
+		fn.result = fn.scope.declare("result")
+		result = " (" + fn.result + " " + g.goType(symbol.Type) + ")"
+	}
+	body := g.statements(decl.Body.Statements)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "func(%s)%s {\ndefer enter()()\n", strings.Join(params, ", "), result)
+	if fn.symbol != nil && !zeroIsDefault(symbol.Type) {
+		fmt.Fprintf(&b, "%s = %s\n", fn.result, g.initial(symbol.Type))
+	}
+	for _, local := range locals {
+		b.WriteString("var " + g.declaration(local) + "\n")
+	}
+	b.WriteString(nestedCode.String())
+	var unused []string
+	for _, local := range locals {
+		if !g.used[local] {
+			unused = append(unused, g.names[local])
+		}
+	}
+	for _, r := range nested {
+		if !g.used[g.info.Symbols[r]] {
+			unused = append(unused, g.names[g.info.Symbols[r]])
+		}
+	}
+	for _, name := range unused {
+		b.WriteString("_ = " + name + "\n")
+	}
+	b.WriteString(body)
+	if fn.symbol != nil {
+		b.WriteString("return\n")
+	}
+	b.WriteString("}")
+
+	signature = "func(" + strings.Join(paramTypes, ", ") + ")"
+	if fn.symbol != nil {
+		signature += " " + g.goType(symbol.Type)
+	}
+	return signature, b.String()
+}
+
+// implicitVariable ищет неописанную переменную по статическим связям, как интерпретатор
+func (g *goGenerator) implicitVariable(key string) *Symbol {
+	for fn := g.fn; fn != nil; fn = fn.parent {
+		if symbol := fn.implicit[key]; symbol != nil {
+			return symbol
+		}
+	}
+	return g.implicit[key]
+}
+
+// Операторы
+
+// statements транслирует последовательность операторов
+func (g *goGenerator) statements(statements []Statement) string {
+	var b strings.Builder
+	for _, stmt := range statements {
+		g.statement(&b, stmt)
+	}
+	return b.String()
+}
+
+// statement транслирует оператор; операторы, которые понадобились его выражениям
+// (g.pre), выполняются перед ним
+func (g *goGenerator) statement(b *strings.Builder, stmt Statement) {
+	outer := g.pre
+	g.pre = nil
+	var code strings.Builder
+	g.statementCode(&code, stmt)
+	for _, pre := range g.pre {
+		b.WriteString(pre)
+	}
+	b.WriteString(code.String())
+	g.pre = outer
+}
+
+func (g *goGenerator) statementCode(b *strings.Builder, stmt Statement) {
+	VisitStatement[struct{}](goStatement{g, b}, stmt)
+}
+
+// goStatement транслирует операторы в b как StatementVisitor
+type goStatement struct {
+	*goGenerator
+	b *strings.Builder
+}
+
+func (g goStatement) VisitAssignment(s *Assignment) struct{} {
+	g.assignment(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitBlock(s *Block) struct{} {
+	for _, inner := range s.Statements {
+		g.statement(g.b, inner)
+	}
+	return struct{}{}
+}
+
+func (g goStatement) VisitIfStatement(s *IfStatement) struct{} {
+	g.ifStatement(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitWhileStatement(s *WhileStatement) struct{} {
+	g.loop(g.b, func(loop *goLoop) string {
+		cond := g.expr(s.Cond).text
+		body := g.nested(s.Body)
+		if cond == "true" {
+			return "for {\n" + body + "}\n"
+		}
+		return "for " + cond + " {\n" + body + "}\n"
+	})
+	return struct{}{}
+}
+
+func (g goStatement) VisitRepeatStatement(s *RepeatStatement) struct{} {
+	g.repeatStatement(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitForStatement(s *ForStatement) struct{} {
+	g.forStatement(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitCaseStatement(s *CaseStatement) struct{} {
+	g.caseStatement(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitCallStatement(s *CallStatement) struct{} {
+	g.callStatement(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitTryStatement(s *TryStatement) struct{} {
+	g.tryStatement(g.b, s)
+	return struct{}{}
+}
+
+func (g goStatement) VisitRaiseStatement(s *RaiseStatement) struct{} {
+	if s.Exception == nil {
+		g.b.WriteString("panic(" + g.fn.handlers[len(g.fn.handlers)-1] + ")\n")
+	} else {
+		fmt.Fprintf(g.b, "raiseException(%s, %s)\n", g.expr(s.Exception).text, g.where(s.Pos))
+	}
+	return struct{}{}
+}
+
+func (g goStatement) VisitBreakStatement(*BreakStatement) struct{} {
+	g.b.WriteString(g.jump(flowBreak))
+	return struct{}{}
+}
+
+func (g goStatement) VisitContinueStatement(*ContinueStatement) struct{} {
+	g.b.WriteString(g.jump(flowContinue))
+	return struct{}{}
+}
+
+func (g goStatement) VisitExitStatement(s *ExitStatement) struct{} {
+	if s.Value != nil {
+		fmt.Fprintf(g.b, "%s = %s\n", g.fn.result, g.store(s.Value, g.fn.symbol.Type, g.fn.decl.Name, s.Pos, true))
+	}
+	g.b.WriteString(g.jump(flowExit))
+	return struct{}{}
+}
+
+func (g goStatement) VisitOtherStatement(stmt Statement) struct{} {
+	g.fail(Position{}, "оператор %T не поддерживается", stmt)
+	return struct{}{}
+}
+
+// nested транслирует оператор в новой области видимости имен Go
+func (g *goGenerator) nested(stmt Statement) string {
+	var b strings.Builder
+	outer := g.fn.scope
+	g.fn.scope = newGoScope(outer)
+	g.statement(&b, stmt)
+	g.fn.scope = outer
+	return b.String()
+}
+
+// block транслирует операторы в новой области видимости имен Go
+func (g *goGenerator) block(statements []Statement) string {
+	return g.nested(&Block{Statements: statements})
+}
+
+func (g *goGenerator) ifStatement(b *strings.Builder, s *IfStatement) {
+	fmt.Fprintf(b, "if %s {\n%s", g.expr(s.Cond).text, g.nested(s.Then))
+	for s.Else != nil {
+		next, ok := s.Else.(*IfStatement)
+		if !ok {
+			fmt.Fprintf(b, "} else {\n%s", g.nested(s.Else))
+			break
+		}
+		s = next
+		fmt.Fprintf(b, "} else if %s {\n%s", g.expr(s.Cond).text, g.nested(s.Then))
+	}
+	b.WriteString("}\n")
+}
+
+// assignment транслирует присваивание переменной, результату функции, полю или p^
+func (g *goGenerator) assignment(b *strings.Builder, s *Assignment) {
+	if s.Target != nil {
+		t := g.designatorType(s.Target)
+		value := g.store(s.Value, t, designatorName(s.Target), s.Pos, !s.Unchecked)
+		fmt.Fprintf(b, "%s = %s\n", g.target(s.Target), value)
+		return
+	}
+	symbol := g.info.Symbols[s]
+	if symbol == nil {
+		g.fail(s.Pos, "присваивание %s: неизвестная переменная", s.Variable)
+		return
+	}
+	if symbol.isRoutine() {
+		fmt.Fprintf(b, "%s = %s\n", g.fn.result, g.store(s.Value, symbol.Type, g.fn.decl.Name, s.Pos, !s.Unchecked))
+		return
+	}
+	if id, ok := s.Value.(*Identifier); ok && g.info.Symbols[id] == symbol {
+		// Присваивание переменной самой себе не изменяет ее
+		g.markAssigned(b, symbol)
+		return
+	}
+	fmt.Fprintf(b, "%s = %s\n", g.variableTarget(symbol), g.store(s.Value, g.varType(symbol), symbol.Name, s.Pos, !s.Unchecked))
+	g.markAssigned(b, symbol)
+}
+
+// markAssigned отмечает присваивание неописанной переменной программы
+func (g *goGenerator) markAssigned(b *strings.Builder, symbol *Symbol) {
+	if g.marked[symbol] {
+		fmt.Fprintf(b, "assigned[%q] = true\n", symbol.Name)
+	}
+}
+
+// store возвращает значение value, приведенное к типу переменной t и, если check, проверенное
+// на принадлежность ее диапазону; name - запись переменной для сообщения об ошибке
+func (g *goGenerator) store(value Expression, t *Type, name string, pos Position, check bool) string {
+	code := g.convert(value, t)
+	if !check {
+		return code.text
+	}
+	return g.checked(code, value, t, name, pos).text
+}
+
+// checked дополняет значение проверкой диапазона переменной типа t. Константы проверяет
+// семантический анализ, поэтому проверка нужна только вычисляемым значениям.
+func (g *goGenerator) checked(code goCode, value Expression, t *Type, name string, pos Position) goCode {
+	if _, constant := constantValue(value); constant || t == nil {
+		return code
+	}
+	switch {
+	case t.Kind == TypeSubrange:
+		function := "checkRange"
+		if t.Base.Kind == TypeBoolean {
+			function = "checkBoolRange"
+		}
+		return goPrimary(fmt.Sprintf("%s(%s, %d, %d, %q, %q, %s)", function, code.text, t.Low, t.High, name, t.Range(), g.where(pos)))
+	case t.Kind == TypeSet && t.Elem != nil && t.Elem.Kind == TypeSubrange:
+		return goPrimary(fmt.Sprintf("checkSetRange(%s, %d, %d, %s, %q, %q, %s)", code.text, t.Elem.Low, t.Elem.High,
+			g.elementFormat(t), name, t.Range(), g.where(pos)))
+	}
+	return code
+}
+
+// loop транслирует цикл: generate возвращает его код, когда известно, нужна ли метка
+func (g *goGenerator) loop(b *strings.Builder, generate func(loop *goLoop) string) {
+	loop := &goLoop{switches: g.fn.switches}
+	g.fn.loops = append(g.fn.loops, loop)
+	code := generate(loop)
+	g.fn.loops = g.fn.loops[:len(g.fn.loops)-1]
+	if loop.label != "" {
+		b.WriteString(loop.label + ":\n")
+	}
+	b.WriteString(code)
+}
+
+func (g *goGenerator) repeatStatement(b *strings.Builder, s *RepeatStatement) {
+	if !goHasContinue(s.Body.Statements) {
+		g.loop(b, func(loop *goLoop) string {
+			body := g.block(s.Body.Statements)
+			return fmt.Sprintf("for {\n%sif %s {\nbreak\n}\n}\n", body, g.expr(s.Cond).text)
+		})
+		return
+	}
+	// Continue переходит к проверке условия UNTIL
+	outer := g.fn.scope
+	g.fn.scope = newGoScope(outer)
+	repeat := g.fn.scope.declare("repeat")
+	g.loop(b, func(loop *goLoop) string {
+		body := g.block(s.Body.Statements)
+		return fmt.Sprintf("for %s := true; %s; %s = !%s {\n%s}\n", repeat, repeat, repeat, g.expr(s.Cond).wrap(goPrecUnary), body)
+	})
+	g.fn.scope = outer
+}
+
+func (g *goGenerator) forStatement(b *strings.Builder, s *ForStatement) {
+	symbol := g.info.Symbols[s]
+	if symbol == nil {
+		g.fail(s.Pos, "цикл FOR: неизвестная переменная %s", s.Variable)
+		return
+	}
+	t := g.varType(symbol)
+	if t == nil {
+		t = integerType
+	}
+	if baseType(t).Kind == TypeBoolean {
+		g.unsupported(s.Pos, "цикл FOR с логической переменной")
+		return
+	}
+	start, startConstant := constantValue(s.Start)
+	end, endConstant := constantValue(s.End)
+	if startConstant && endConstant && goEmptyFor(s, start, end) {
+		return
+	}
+	variable := g.variableTarget(symbol)
+	g.used[symbol] = true
+	compare, step := "<=", "++"
+	if s.Down {
+		compare, step = ">=", "--"
+	}
+
+	outer := g.fn.scope
+	g.fn.scope = newGoScope(outer)
+	defer func() { g.fn.scope = outer }()
+	var names, values []string
+	first, last := g.literalCode(start, startConstant), g.literalCode(end, endConstant)
+	if !startConstant {
+		first = g.fn.scope.declare("first")
+		names, values = append(names, first), append(values, g.convert(s.Start, t).text)
+	} else if baseType(t).Kind == TypeInteger {
+		first = "int64(" + first + ")"
+	}
+	if !endConstant {
+		last = g.fn.scope.declare("last")
+		names, values = append(names, last), append(values, g.convert(s.End, t).text)
+	}
+	// Как и в интерпретаторе, итерации считает отдельный счетчик: присваивание переменной
+	// цикла в теле не изменяет число итераций
+	counter := g.fn.scope.declare("counter")
+	value := goPrimary(counter)
+	if !(startConstant && endConstant) {
+		value = g.checked(value, nil, t, symbol.Name, s.Pos)
+	}
+	var code strings.Builder
+	g.loop(&code, func(loop *goLoop) string {
+		loop.last = counter + " == " + last
+		var body strings.Builder
+		fmt.Fprintf(&body, "%s = %s\n", variable, value.text)
+		g.markAssigned(&body, symbol)
+		body.WriteString(g.nested(s.Body))
+		return fmt.Sprintf("for %s := %s; ; %s%s {\n%sif %s {\nbreak\n}\n}\n", counter, first, counter, step, body.String(), loop.last)
+	})
+	if len(names) == 0 {
+		b.WriteString(code.String())
+		return
+	}
+	fmt.Fprintf(b, "if %s := %s; %s %s %s {\n%s}\n", strings.Join(names, ", "), strings.Join(values, ", "), first, compare, last, code.String())
+}
+
+// literalCode возвращает запись константы или пустую строку, если значение не константа
+func (g *goGenerator) literalCode(value Value, constant bool) string {
+	if !constant {
+		return ""
+	}
+	return g.literal(value).text
+}
+
+// jump транслирует Break, Continue или Exit. Из функции блока TRY они возвращают flow.
+func (g *goGenerator) jump(kind flow) string {
+	fn := g.fn
+	closure := (*goClosure)(nil)
+	if n := len(fn.closures); n > 0 {
+		closure = fn.closures[n-1]
+	}
+	if kind == flowExit {
+		if closure != nil {
+			closure.escapes[kind] = true
+			return "return flowExit\n"
+		}
+		return "return\n"
+	}
+	if closure != nil && closure.loops >= len(fn.loops) {
+		closure.escapes[kind] = true
+		if kind == flowBreak {
+			return "return flowBreak\n"
+		}
+		return "return flowContinue\n"
+	}
+	loop := fn.loops[len(fn.loops)-1]
+	label := ""
+	if fn.switches > loop.switches {
+		// break в switch завершил бы только switch
+		if loop.label == "" {
+			fn.labels++
+			loop.label = "loop" + strconv.Itoa(fn.labels)
+		}
+		label = " " + loop.label
+	}
+	if kind == flowBreak {
+		return "break" + label + "\n"
+	}
+	if loop.last != "" {
+		// Continue на последней итерации FOR завершает цикл
+		return fmt.Sprintf("if %s {\nbreak%s\n}\ncontinue\n", loop.last, label)
+	}
+	return "continue\n"
+}
+
+func (g *goGenerator) caseStatement(b *strings.Builder, s *CaseStatement) {
+	outer := g.fn.scope
+	g.fn.scope = newGoScope(outer)
+	defer func() { g.fn.scope = outer }()
+
+	selector := g.expr(s.Expr)
+	header := ""
+	if !g.simple(s.Expr) {
+		name := g.fn.scope.declare("selector")
+		header = name + " := " + selector.text + "; "
+		selector = goPrimary(name)
+	}
+	ranges := false
+	for _, branch := range s.Branches {
+		for _, label := range branch.Labels {
+			ranges = ranges || label.High != nil
+		}
+	}
+	if ranges && g.typeOf(s.Expr).Kind == TypeBoolean {
+		g.unsupported(s.Pos, "диапазон меток CASE логического типа")
+		return
+	}
+	if ranges {
+		fmt.Fprintf(b, "switch %s{\n", header)
+	} else {
+		fmt.Fprintf(b, "switch %s%s {\n", header, selector.text)
+	}
+	g.fn.switches++
+	for _, branch := range s.Branches {
+		labels := make([]string, len(branch.Labels))
+		for n, label := range branch.Labels {
+			low := g.expr(label.Low).text
+			switch {
+			case !ranges:
+				labels[n] = low
+			case label.High == nil:
+				labels[n] = selector.wrap(goPrecPrimary) + " == " + low
+			default:
+				labels[n] = fmt.Sprintf("%s >= %s && %s <= %s", selector.wrap(goPrecPrimary), low, selector.wrap(goPrecPrimary), g.expr(label.High).text)
+			}
+		}
+		fmt.Fprintf(b, "case %s:\n%s", strings.Join(labels, ", "), g.nested(branch.Body))
+	}
+	b.WriteString("default:\n")
+	if s.Else != nil {
+		b.WriteString(g.block(s.Else.Statements))
+	} else {
+		fmt.Fprintf(b, "caseError(%s, %s)\n", selector.text, g.where(s.Pos))
+	}
+	g.fn.switches--
+	b.WriteString("}\n")
+}
+
+// simple сообщает, можно ли вычислять выражение несколько раз: это переменная или константа
+func (g *goGenerator) simple(expr Expression) bool {
+	switch e := expr.(type) {
+	case *Number, *Literal:
+		return true
+	case *Identifier:
+		symbol := g.info.Symbols[e]
+		return symbol != nil && symbol.Kind == SymbolVar
+	}
+	return false
+}
+
+// tryStatement транслирует TRY: тело и обработчики выполняются в функциях tryExcept и tryFinally
+func (g *goGenerator) tryStatement(b *strings.Builder, s *TryStatement) {
+	fn := g.fn
+	var escapes [flowExit + 1]bool
+	closure := func(generate func() string) string {
+		c := &goClosure{loops: len(fn.loops)}
+		fn.closures = append(fn.closures, c)
+		switches := fn.switches
+		code := generate()
+		fn.switches = switches
+		fn.closures = fn.closures[:len(fn.closures)-1]
+		for kind, escaped := range c.escapes {
+			escapes[kind] = escapes[kind] || escaped
+		}
+		return code
+	}
+	body := closure(func() string { return g.block(s.Body.Statements) + "return flowNormal\n" })
+
+	var call string
+	if s.Finally != nil {
+		final := g.block(s.Finally.Statements)
+		call = fmt.Sprintf("tryFinally(func() flow {\n%s}, func() {\n%s})", body, final)
+	} else {
+		outer := fn.scope
+		fn.scope = newGoScope(outer)
+		e := fn.scope.declare("e")
+		fn.handlers = append(fn.handlers, e)
+		handler := closure(func() string { return g.handlers(s, e) })
+		fn.handlers = fn.handlers[:len(fn.handlers)-1]
+		fn.scope = outer
+		call = fmt.Sprintf("tryExcept(func() flow {\n%s}, func(%s *pascalError) flow {\n%s})", body, e, handler)
+	}
+	if !escapes[flowBreak] && !escapes[flowContinue] && !escapes[flowExit] {
+		b.WriteString(call + "\n")
+		return
+	}
+	fmt.Fprintf(b, "switch %s {\n", call)
+	fn.switches++
+	for _, kind := range []flow{flowBreak, flowContinue, flowExit} {
+		if escapes[kind] {
+			fmt.Fprintf(b, "case %s:\n%s", [...]string{"", "flowBreak", "flowContinue", "flowExit"}[kind], g.jump(kind))
+		}
+	}
+	fn.switches--
+	b.WriteString("}\n")
+}
+
+// handlers транслирует обработчики EXCEPT исключения e
+func (g *goGenerator) handlers(s *TryStatement, e string) string {
+	var b strings.Builder
+	fallback := "panic(" + e + ")\n"
+	if s.Default != nil {
+		fallback = g.block(s.Default.Statements)
+	}
+	if len(s.Handlers) == 0 {
+		return fallback + "return flowNormal\n"
+	}
+	b.WriteString("switch {\n")
+	g.fn.switches++
+	for _, handler := range s.Handlers {
+		symbol := g.info.Symbols[handler]
+		if symbol == nil {
+			g.fail(handler.Pos, "неизвестный класс исключения %s", handler.Class)
+			continue
+		}
+		fmt.Fprintf(&b, "case %s.is(%s):\n", e, g.typeName(symbol.Type))
+		outer := g.fn.scope
+		g.fn.scope = newGoScope(outer)
+		if symbol.Kind == SymbolVar {
+			name := g.fn.scope.declare(handler.Variable)
+			g.names[symbol] = name
+			body := g.nested(handler.Body)
+			fmt.Fprintf(&b, "%s := %s.exception()\n", name, e)
+			if !g.used[symbol] {
+				b.WriteString("_ = " + name + "\n")
+			}
+			b.WriteString(body)
+		} else {
+			b.WriteString(g.nested(handler.Body))
+		}
+		g.fn.scope = outer
+	}
+	b.WriteString("default:\n" + fallback)
+	g.fn.switches--
+	b.WriteString("}\nreturn flowNormal\n")
+	return b.String()
+}
+
+// Вызовы процедур
+
+func (g *goGenerator) callStatement(b *strings.Builder, s *CallStatement) {
+	if symbol := g.info.Symbols[s]; symbol != nil {
+		b.WriteString(g.call(symbol, s.Args, s.Pos) + "\n")
+		return
+	}
+	where := g.where(s.Pos)
+	switch name := strings.ToLower(s.Name); name {
+	case "write", "writeln":
+		file, args := g.fileArgument(s.Args, "output")
+		parts := []string{strconv.Quote(s.Name), where}
+		for _, arg := range args {
+			parts = append(parts, g.writeArgument(s, arg))
+		}
+		if name == "writeln" {
+			parts = append(parts, `"\n"`)
+		}
+		if file != "output" {
+			fmt.Fprintf(b, "%s.writing(%q, %s)\n", file, s.Name, where)
+		}
+		fmt.Fprintf(b, "%s.write(%s)\n", file, strings.Join(parts, ", "))
+	case "read", "readln":
+		file, args := g.fileArgument(s.Args, "input")
+		for _, arg := range args {
+			g.read(b, s, file, arg)
+		}
+		if name == "readln" {
+			fmt.Fprintf(b, "%s.readLn(%q, %s)\n", file, s.Name, where)
+		} else if len(args) == 0 {
+			fmt.Fprintf(b, "%s.reading(%q, %s)\n", file, s.Name, where)
+		}
+	case "new":
+		t := g.designatorType(s.Args[0])
+		fmt.Fprintf(b, "%s = allocate(%s, %s)\n", g.target(s.Args[0]), g.zero(t.Elem), where)
+	case "dispose":
+		fmt.Fprintf(b, "dispose(%s, %q, %q, %s)\n", g.expr(s.Args[0]).text, s.Name, g.referenceName(s.Args[0]), where)
+	case "assign":
+		fmt.Fprintf(b, "%s.assign(%s, %s)\n", g.expr(s.Args[0]).wrap(goPrecPrimary), g.convert(s.Args[1], stringType).text, where)
+	case "reset", "rewrite", "append":
+		fmt.Fprintf(b, "%s.%s(%q, %q, %s)\n", g.expr(s.Args[0]).wrap(goPrecPrimary), name, s.Name, designatorName(s.Args[0]), where)
+	case "close":
+		fmt.Fprintf(b, "%s.close(%s)\n", g.expr(s.Args[0]).wrap(goPrecPrimary), where)
+	case "halt":
+		code := "0"
+		if len(s.Args) == 1 {
+			code = g.expr(s.Args[0]).text
+		}
+		fmt.Fprintf(b, "halt(%s)\n", code)
+	case "assert":
+		message := strconv.Quote(assertionMessage)
+		if len(s.Args) == 2 {
+			message = g.convert(s.Args[1], stringType).text
+		}
+		fmt.Fprintf(b, "if !%s {\nraise(EAssertionFailed, %s, \"%%s\", %s)\n}\n", g.expr(s.Args[0]).wrap(goPrecUnary), where, message)
+	default:
+		g.fail(s.Pos, "неизвестная процедура %s", s.Name)
+	}
+}
+
+// referenceName возвращает запись переменной для сообщений, как интерпретатор: имя
+// переменной в том виде, как оно описано, или запись поля и p^ в программе
+func (g *goGenerator) referenceName(expr Expression) string {
+	if id, ok := expr.(*Identifier); ok {
+		if symbol := g.info.Symbols[id]; symbol != nil {
+			return symbol.Name
+		}
+	}
+	return designatorName(expr)
+}
+
+// fileArgument возвращает файл процедуры ввода-вывода, если он передан первым аргументом,
+// иначе standard, и остальные аргументы
+func (g *goGenerator) fileArgument(args []Expression, standard string) (string, []Expression) {
+	if len(args) == 0 || !isDesignator(args[0]) {
+		return standard, args
+	}
+	if id, ok := args[0].(*Identifier); ok && g.info.Symbols[id] == nil {
+		return standard, args
+	}
+	if t := baseType(g.designatorType(args[0])); t == nil || t.Kind != TypeText {
+		return standard, args
+	}
+	return g.expr(args[0]).wrap(goPrecPrimary), args[1:]
+}
+
+// writeArgument возвращает отформатированный параметр Write
+func (g *goGenerator) writeArgument(s *CallStatement, arg Expression) string {
+	format, _ := arg.(*FormatExpr)
+	value := arg
+	if format != nil {
+		value = format.Value
+	}
+	code := g.expr(value)
+	if t := g.typeOf(value); format != nil && format.Precision != nil && t.Kind != TypeReal {
+		// Ошибка обнаруживается после вычисления значения и ширины поля
+		message := fmt.Sprintf("%s: точность задается только для вещественных значений, получено %s", s.Name, t)
+		return fmt.Sprintf("invalid[string](%s, %q, %s, %s)", g.where(s.Pos), message, code.text, g.formatParameter(s, format.Width, "ширина поля"))
+	}
+	var text string
+	switch g.typeOf(value).Kind {
+	case TypeReal:
+		if format != nil && format.Precision != nil {
+			text = fmt.Sprintf("fixed(%s, %s)", code.text, g.formatParameter(s, format.Precision, "точность"))
+		} else {
+			text = "formatReal(" + code.text + ")"
+		}
+	case TypeBoolean:
+		text = "formatBool(" + code.text + ")"
+	case TypeEnum:
+		text = code.wrap(goPrecPrimary) + ".String()"
+	case TypeChar, TypeString:
+		text = g.convert(value, stringType).text
+	default:
+		text = "formatInt(" + code.text + ")"
+	}
+	if format != nil {
+		text = fmt.Sprintf("pad(%s, %s)", text, g.formatParameter(s, format.Width, "ширина поля"))
+	}
+	return text
+}
+
+// formatParameter возвращает ширину поля или точность Write; вычисляемое значение проверяется
+func (g *goGenerator) formatParameter(s *CallStatement, expr Expression, what string) string {
+	if value, ok := constantValue(expr); ok {
+		if n, ok := value.(IntegerValue); ok && n >= 0 {
+			return g.literal(value).text
+		}
+	}
+	return fmt.Sprintf("formatParameter(%s, %q, %q, %s)", g.expr(expr).text, s.Name, what, g.where(s.Pos))
+}
+
+// read транслирует чтение одной переменной процедурой Read или ReadLn
+func (g *goGenerator) read(b *strings.Builder, s *CallStatement, file string, arg Expression) {
+	t := g.designatorType(arg)
+	method := "readInt"
+	if base := baseType(t); base != nil {
+		switch base.Kind {
+		case TypeReal:
+			method = "readReal"
+		case TypeChar:
+			method = "readChar"
+		case TypeString:
+			method = "readString"
+		}
+	}
+	value := goPrimary(fmt.Sprintf("%s.%s(%q, %s)", file, method, s.Name, g.where(s.Pos)))
+	value = g.checked(value, nil, t, g.referenceName(arg), s.Pos)
+	fmt.Fprintf(b, "%s = %s\n", g.target(arg), value.text)
+	if id, ok := arg.(*Identifier); ok {
+		if symbol := g.info.Symbols[id]; symbol != nil {
+			g.markAssigned(b, symbol)
+		}
+	}
+}
+
+// call возвращает вызов подпрограммы программы
+func (g *goGenerator) call(symbol *Symbol, args []Expression, pos Position) string {
+	g.used[symbol] = true
+	if symbol.Signature == nil || len(symbol.Signature.Params) != len(args) {
+		g.fail(pos, "вызов %s: неизвестная сигнатура", symbol.Name)
+		return g.names[symbol] + "()"
+	}
+	codes := make([]string, len(args))
+	for n, param := range symbol.Signature.Params {
+		if param.ByRef && baseType(param.Type).Kind != TypeText {
+			codes[n] = g.address(args[n])
+			continue
+		}
+		codes[n] = g.checked(g.convert(args[n], param.Type), args[n], param.Type, param.Name, pos).text
+	}
+	return g.names[symbol] + "(" + strings.Join(codes, ", ") + ")"
+}
+
+// address возвращает указатель на переменную, переданную в VAR-параметр
+func (g *goGenerator) address(arg Expression) string {
+	switch a := arg.(type) {
+	case *Identifier:
+		symbol := g.info.Symbols[a]
+		if symbol == nil {
+			g.fail(a.Pos, "VAR-параметр: неизвестная переменная %s", a.Name)
+			return "nil"
+		}
+		if g.marked[symbol] {
+			g.pre = append(g.pre, fmt.Sprintf("assigned[%q] = true\n", symbol.Name))
+		}
+		g.used[symbol] = true
+		if g.byRef[symbol] {
+			return g.names[symbol]
+		}
+		return "&" + g.names[symbol]
+	case *Dereference:
+		return g.deref(a)
+	default:
+		return "&" + g.target(arg)
+	}
+}
+
+// Места хранения
+
+// designatorType возвращает описанный тип переменной, поля или p^
+func (g *goGenerator) designatorType(expr Expression) *Type {
+	switch e := expr.(type) {
+	case *Identifier:
+		if symbol := g.info.Symbols[e]; symbol != nil {
+			if symbol.Kind == SymbolVar {
+				return g.varType(symbol)
+			}
+			return symbol.Type
+		}
+	case *Dereference:
+		if t := g.typeOf(e.Pointer); t.Kind == TypePointer {
+			return t.Elem
+		}
+	case *FieldAccess:
+		t := g.typeOf(e.Record)
+		if t.Kind == TypeClass {
+			return stringType
+		}
+		if n := t.Field(e.Field); n >= 0 {
+			return t.Fields[n].Type
+		}
+	}
+	return nil
+}
+
+// variableTarget возвращает переменную как место присваивания
+func (g *goGenerator) variableTarget(symbol *Symbol) string {
+	if g.byRef[symbol] {
+		return "*" + g.names[symbol]
+	}
+	return g.names[symbol]
+}
+
+// target возвращает переменную, поле или p^ как место присваивания
+func (g *goGenerator) target(expr Expression) string {
+	switch e := expr.(type) {
+	case *Identifier:
+		symbol := g.info.Symbols[e]
+		if symbol == nil {
+			g.fail(e.Pos, "неизвестная переменная %s", e.Name)
+			return "_"
+		}
+		if symbol.isRoutine() {
+			return g.fn.result
+		}
+		return g.variableTarget(symbol)
+	case *Dereference:
+		return "*" + g.deref(e)
+	case *FieldAccess:
+		return g.field(e)
+	}
+	g.fail(Position{}, "%s не является переменной", expr)
+	return "_"
+}
+
+// deref возвращает указатель Go на динамическую переменную p^
+func (g *goGenerator) deref(e *Dereference) string {
+	t := g.typeOf(e.Pointer)
+	if t.Kind != TypePointer {
+		// Неописанная переменная - не указатель
+		return fmt.Sprintf("invalid[*int64](%s, %q, %s)", g.where(e.Pos), "операция ^ неприменима к типу "+t.String(), g.expr(e.Pointer).text)
+	}
+	return fmt.Sprintf("deref[%s](%s, %q, %s)", g.goType(t.Elem), g.expr(e.Pointer).text, designatorName(e), g.where(e.Pos))
+}
+
+// field возвращает поле записи r.f
+func (g *goGenerator) field(e *FieldAccess) string {
+	t := g.typeOf(e.Record)
+	n := t.Field(e.Field)
+	if t.Kind != TypeRecord || n < 0 {
+		g.fail(e.Pos, "у %s нет поля %s", t, e.Field)
+		return "_"
+	}
+	var record string
+	switch r := e.Record.(type) {
+	case *Dereference:
+		record = g.deref(r)
+	case *Identifier:
+		// Поле доступно и через указатель на запись - VAR-параметр
+		if symbol := g.info.Symbols[r]; symbol != nil && g.byRef[symbol] {
+			g.used[symbol] = true
+			record = g.names[symbol]
+		} else {
+			record = g.expr(r).wrap(goPrecPrimary)
+		}
+	default:
+		record = g.expr(e.Record).wrap(goPrecPrimary)
+	}
+	return record + "." + g.fieldName[t][n]
+}
+
+// Выражения
+
+// typeOf возвращает базовый тип выражения; неизвестный тип (например, неописанной
+// переменной) вычисляется из типов операндов, а в остальных случаях считается целым
+func (g *goGenerator) typeOf(expr Expression) *Type {
+	t := g.info.Types[expr]
+	if id, ok := expr.(*Identifier); ok && t == nil {
+		if symbol := g.info.Symbols[id]; symbol != nil && symbol.Kind == SymbolVar {
+			t = g.varType(symbol)
+		}
+	}
+	if t == nil {
+		switch e := expr.(type) {
+		case *BinaryOp:
+			t, _ = binaryType(e.Operator, g.typeOf(e.Left), g.typeOf(e.Right))
+		case *CallExpr:
+			if fn, ok := builtinFunctions[strings.ToLower(e.Name)]; ok && len(e.Args) == fn.arity {
+				args := make([]*Type, len(e.Args))
+				for n, arg := range e.Args {
+					args[n] = g.typeOf(arg)
+				}
+				t, _ = fn.result(args)
+			}
+		case *SetConstructor:
+			t = &Type{Kind: TypeSet, Elem: integerType}
+		}
+	}
+	if t == nil {
+		return integerType
+	}
+	return baseType(t)
+}
+
+// expr транслирует выражение
+func (g *goGenerator) expr(expr Expression) goCode {
+	return VisitExpression[goCode](goExpression{g}, expr)
+}
+
+// goExpression транслирует выражения как ExpressionVisitor
+type goExpression struct {
+	*goGenerator
+}
+
+func (g goExpression) VisitNumber(e *Number) goCode {
+	if e.IsReal {
+		return g.literal(RealValue(e.Value))
+	}
+	return g.literal(IntegerValue(e.IntValue()))
+}
+
+func (g goExpression) VisitLiteral(e *Literal) goCode               { return g.literal(e.Value) }
+func (g goExpression) VisitIdentifier(e *Identifier) goCode         { return g.identifier(e) }
+func (g goExpression) VisitBinaryOp(e *BinaryOp) goCode             { return g.binary(e) }
+func (g goExpression) VisitSetConstructor(e *SetConstructor) goCode { return g.set(e) }
+
+func (g goExpression) VisitUnaryOp(e *UnaryOp) goCode {
+	return goCode{"!" + g.expr(e.Operand).wrap(goPrecUnary), goPrecUnary}
+}
+
+func (g goExpression) VisitDereference(e *Dereference) goCode {
+	return goCode{"*" + g.deref(e), goPrecUnary}
+}
+
+func (g goExpression) VisitFieldAccess(e *FieldAccess) goCode {
+	if t := g.typeOf(e.Record); t.Kind == TypeClass {
+		return goPrimary(fmt.Sprintf("exceptionField(%s, %q, %s)", g.expr(e.Record).text, e.Field, g.where(e.Pos)))
+	}
+	return goPrimary(g.field(e))
+}
+
+func (g goExpression) VisitCallExpr(e *CallExpr) goCode {
+	if symbol := g.info.Symbols[e]; symbol != nil {
+		return goPrimary(g.call(symbol, e.Args, e.Pos))
+	}
+	return g.builtin(e)
+}
+
+func (g goExpression) VisitCreateExpr(e *CreateExpr) goCode {
+	class := g.info.Types[e]
+	if class == nil || len(e.Args) != 1 {
+		g.fail(e.Pos, "%s.Create: неизвестный класс", e.Class)
+		return goPrimary("nil")
+	}
+	return goPrimary(fmt.Sprintf("%s.Create(%s)", g.typeName(class), g.convert(e.Args[0], stringType).text))
+}
+
+// VisitFormatExpr: формат ширины и точности транслирует только Write и WriteLn
+func (g goExpression) VisitFormatExpr(e *FormatExpr) goCode { return g.VisitOtherExpression(e) }
+
+func (g goExpression) VisitOtherExpression(expr Expression) goCode {
+	g.fail(Position{}, "выражение %s не поддерживается", expr)
+	return goPrimary("nil")
+}
+
+// literal транслирует значение константы
+func (g *goGenerator) literal(value Value) goCode {
+	switch v := value.(type) {
+	case IntegerValue:
+		if v < 0 {
+			return goCode{strconv.FormatInt(int64(v), 10), goPrecUnary}
+		}
+		return goPrimary(strconv.FormatInt(int64(v), 10))
+	case RealValue:
+		text := strconv.FormatFloat(float64(v), 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eEIN") {
+			text += ".0"
+		}
+		if v < 0 {
+			return goCode{text, goPrecUnary}
+		}
+		return goPrimary(text)
+	case BooleanValue:
+		return goPrimary(strconv.FormatBool(bool(v)))
+	case EnumValue:
+		g.typeName(v.Type)
+		return goPrimary(g.values[v.Type][v.Ordinal])
+	case CharValue:
+		return goPrimary(strconv.QuoteRune(rune(v)))
+	case StringValue:
+		return goPrimary(strconv.Quote(string(v)))
+	case SetValue:
+		return g.setLiteral(v)
+	case PointerValue:
+		return goPrimary("null")
+	}
+	g.fail(Position{}, "константа %s не поддерживается", value)
+	return goPrimary("nil")
+}
+
+// setLiteral транслирует константное множество: отрезки из трех и более элементов
+// записываются диапазонами
+func (g *goGenerator) setLiteral(v SetValue) goCode {
+	members := v.members()
+	if len(members) == 0 {
+		return goPrimary("set{}")
+	}
+	element := func(ordinal int64) string {
+		if v.Elem == nil {
+			return strconv.FormatInt(ordinal, 10)
+		}
+		switch baseType(v.Elem).Kind {
+		case TypeBoolean:
+			return strconv.FormatInt(ordinal, 10)
+		default:
+			return g.literal(ordinalValue(v.Elem, ordinal)).text
+		}
+	}
+	var parts, single []string
+	flush := func() {
+		if len(single) > 0 {
+			parts = append(parts, "setOf("+strings.Join(single, ", ")+")")
+			single = nil
+		}
+	}
+	for n := 0; n < len(members); {
+		end := n
+		for end+1 < len(members) && members[end+1] == members[end]+1 {
+			end++
+		}
+		if end-n >= 2 {
+			flush()
+			parts = append(parts, fmt.Sprintf("setRange(%s, %s)", element(members[n]), element(members[end])))
+		} else {
+			for k := n; k <= end; k++ {
+				single = append(single, element(members[k]))
+			}
+		}
+		n = end + 1
+	}
+	flush()
+	code := parts[0]
+	for _, part := range parts[1:] {
+		code += ".union(" + part + ")"
+	}
+	return goPrimary(code)
+}
+
+// set транслирует конструктор множества с вычисляемыми элементами
+func (g *goGenerator) set(e *SetConstructor) goCode {
+	args := []string{g.where(e.Pos)}
+	for _, element := range e.Elements {
+		if element.High == nil {
+			args = append(args, g.expr(element.Low).text)
+		} else {
+			args = append(args, fmt.Sprintf("span{%s, %s}", g.expr(element.Low).text, g.expr(element.High).text))
+		}
+	}
+	return goPrimary("makeSet(" + strings.Join(args, ", ") + ")")
+}
+
+// identifier транслирует обращение к переменной, результату или функции без аргументов
+func (g *goGenerator) identifier(e *Identifier) goCode {
+	symbol := g.info.Symbols[e]
+	if symbol == nil {
+		// Переменная, которая к этому месту еще не описана присваиванием: интерпретатор
+		// находит ее, если присваивание уже выполнено, и возвращает 0, если нет
+		symbol = g.implicitVariable(strings.ToLower(e.Name))
+		if symbol == nil {
+			return goPrimary("0")
+		}
+		if t := baseType(g.varType(symbol)); t != nil && t.Kind != TypeInteger {
+			g.unsupported(e.Pos, "чтение неописанной переменной "+e.Name+" до присваивания")
+		}
+	}
+	switch symbol.Kind {
+	case SymbolConst:
+		return g.literal(symbol.Value)
+	case SymbolFunction:
+		if g.fn != nil && symbol == g.fn.symbol {
+			return goPrimary(g.fn.result)
+		}
+		return goPrimary(g.call(symbol, nil, e.Pos))
+	}
+	g.used[symbol] = true
+	code := goPrimary(g.names[symbol])
+	if g.byRef[symbol] {
+		code = goCode{"*" + g.names[symbol], goPrecUnary}
+	}
+	if recorded := baseType(g.info.Types[e]); symbol.Implicit && recorded != nil && symbol.Type != nil && recorded.Kind != baseType(symbol.Type).Kind {
+		// Неописанная целая переменная становится вещественной, получив вещественное значение
+		if recorded.Kind == TypeInteger && baseType(symbol.Type).Kind == TypeReal {
+			return goPrimary("int64(" + code.text + ")")
+		}
+		g.unsupported(e.Pos, "неописанная переменная "+e.Name+", меняющая тип,")
+	}
+	return code
+}
+
+// convert приводит значение к типу переменной: целое к вещественному, символ к строке
+func (g *goGenerator) convert(expr Expression, t *Type) goCode {
+	to := baseType(t)
+	if to != nil {
+		from := g.typeOf(expr)
+		switch {
+		case to.Kind == TypeReal && from.Kind == TypeInteger:
+			return g.real(expr)
+		case to.Kind == TypeString && from.Kind == TypeChar:
+			return g.text(expr)
+		}
+	}
+	return g.expr(expr)
+}
+
+// real возвращает числовое выражение как вещественное
+func (g *goGenerator) real(expr Expression) goCode {
+	if g.typeOf(expr).Kind != TypeInteger {
+		return g.expr(expr)
+	}
+	if value, ok := constantValue(expr); ok {
+		if n, ok := value.(IntegerValue); ok {
+			return g.literal(RealValue(n))
+		}
+	}
+	return goPrimary("float64(" + g.expr(expr).text + ")")
+}
+
+// text возвращает символьное или строковое выражение как строку
+func (g *goGenerator) text(expr Expression) goCode {
+	if g.typeOf(expr).Kind != TypeChar {
+		return g.expr(expr)
+	}
+	if value, ok := constantValue(expr); ok {
+		if c, ok := value.(CharValue); ok {
+			return goPrimary(strconv.Quote(string(rune(c))))
+		}
+	}
+	return goPrimary("string(" + g.expr(expr).text + ")")
+}
+
+// ordinal возвращает порядковый номер значения как int64
+func (g *goGenerator) ordinal(expr Expression) string {
+	switch g.typeOf(expr).Kind {
+	case TypeInteger:
+		return g.expr(expr).text
+	case TypeBoolean:
+		return "ord(" + g.expr(expr).text + ")"
+	}
+	if _, ok := constantValue(expr); ok && g.typeOf(expr).Kind == TypeChar {
+		return g.expr(expr).text
+	}
+	return "int64(" + g.expr(expr).text + ")"
+}
+
+// goComparisons содержит операции сравнения Go
+var goComparisons = map[TokenType]string{
+	TokenEQUAL:        "==",
+	TokenNOTEQUAL:     "!=",
+	TokenLESS:         "<",
+	TokenLESSEQUAL:    "<=",
+	TokenGREATER:      ">",
+	TokenGREATEREQUAL: ">=",
+}
+
+// binary транслирует бинарную операцию с контролем переполнения и деления на ноль
+func (g *goGenerator) binary(e *BinaryOp) goCode {
+	lt, rt := g.typeOf(e.Left), g.typeOf(e.Right)
+	where := g.where(e.Pos)
+	switch e.Operator {
+	case TokenAND:
+		return goCode{g.expr(e.Left).wrap(goPrecAnd) + " && " + g.expr(e.Right).wrap(goPrecAnd+1), goPrecAnd}
+	case TokenOR:
+		return goCode{g.expr(e.Left).wrap(goPrecOr) + " || " + g.expr(e.Right).wrap(goPrecOr+1), goPrecOr}
+	case TokenDIV:
+		return goPrimary(fmt.Sprintf("divInt(%s, %s, %s)", g.expr(e.Left).text, g.expr(e.Right).text, where))
+	case TokenMOD:
+		return goPrimary(fmt.Sprintf("modInt(%s, %s, %s)", g.expr(e.Left).text, g.expr(e.Right).text, where))
+	case TokenIN:
+		return goPrimary(fmt.Sprintf("%s.contains(%s)", g.expr(e.Right).wrap(goPrecPrimary), g.ordinal(e.Left)))
+	}
+	if op, ok := goComparisons[e.Operator]; ok {
+		return g.comparison(e, op, lt, rt)
+	}
+	if lt.Kind == TypeSet || rt.Kind == TypeSet {
+		method := map[TokenType]string{TokenPLUS: "union", TokenMULTIPLY: "intersection", TokenMINUS: "difference"}[e.Operator]
+		return goPrimary(fmt.Sprintf("%s.%s(%s)", g.expr(e.Left).wrap(goPrecPrimary), method, g.expr(e.Right).text))
+	}
+	if isText(lt) || isText(rt) {
+		return goCode{g.text(e.Left).wrap(goPrecAdd) + " + " + g.text(e.Right).wrap(goPrecAdd+1), goPrecAdd}
+	}
+	if lt.Kind == TypeInteger && rt.Kind == TypeInteger && e.Operator != TokenDIVIDE {
+		function := map[TokenType]string{TokenPLUS: "addInt", TokenMINUS: "subInt", TokenMULTIPLY: "mulInt"}[e.Operator]
+		return goPrimary(fmt.Sprintf("%s(%s, %s, %s)", function, g.expr(e.Left).text, g.expr(e.Right).text, where))
+	}
+	if e.Operator == TokenDIVIDE {
+		return goPrimary(fmt.Sprintf("divide(%s, %s, %s)", g.real(e.Left).text, g.real(e.Right).text, where))
+	}
+	if value, ok := constantValue(e.Left); ok && e.Operator == TokenMINUS && toFloat(value) == 0 {
+		// Унарный минус - вычитание из нуля; он не переполняет вещественное число
+		return goCode{"-" + g.real(e.Right).wrap(goPrecUnary), goPrecUnary}
+	}
+	op, prec := map[TokenType]string{TokenPLUS: "+", TokenMINUS: "-", TokenMULTIPLY: "*"}[e.Operator], goPrecAdd
+	if e.Operator == TokenMULTIPLY {
+		prec = goPrecMultiply
+	}
+	return goPrimary(fmt.Sprintf("finite(%s %s %s, %s)", g.real(e.Left).wrap(prec), op, g.real(e.Right).wrap(prec+1), where))
+}
+
+// comparison транслирует сравнение
+func (g *goGenerator) comparison(e *BinaryOp, op string, lt, rt *Type) goCode {
+	var left, right goCode
+	switch {
+	case lt.Kind == TypeSet || rt.Kind == TypeSet:
+		left, right = g.expr(e.Left), g.expr(e.Right)
+		switch e.Operator {
+		case TokenLESSEQUAL:
+			return goPrimary(left.wrap(goPrecPrimary) + ".subsetOf(" + right.text + ")")
+		case TokenGREATEREQUAL:
+			return goPrimary(right.wrap(goPrecPrimary) + ".subsetOf(" + left.text + ")")
+		}
+	case lt.Kind == TypeReal || rt.Kind == TypeReal:
+		left, right = g.real(e.Left), g.real(e.Right)
+	case lt.Kind != rt.Kind && isText(lt) && isText(rt):
+		left, right = g.text(e.Left), g.text(e.Right)
+	case lt.Kind == TypeBoolean && op != "==" && op != "!=":
+		left, right = goPrimary("ord("+g.expr(e.Left).text+")"), goPrimary("ord("+g.expr(e.Right).text+")")
+	default:
+		left, right = g.expr(e.Left), g.expr(e.Right)
+	}
+	return goCode{left.wrap(goPrecCompare+1) + " " + op + " " + right.wrap(goPrecCompare+1), goPrecCompare}
+}
+
+// builtin транслирует вызов стандартной функции
+func (g *goGenerator) builtin(e *CallExpr) goCode {
+	name := strings.ToLower(e.Name)
+	where := g.where(e.Pos)
+	if fileFunctions[name] {
+		file := "input"
+		if len(e.Args) == 1 {
+			file = g.expr(e.Args[0]).wrap(goPrecPrimary)
+		}
+		return goPrimary(fmt.Sprintf("%s.%s(%q, %s)", file, name, e.Name, where))
+	}
+	if len(e.Args) != 1 {
+		g.fail(e.Pos, "неизвестная функция %s", e.Name)
+		return goPrimary("0")
+	}
+	arg := e.Args[0]
+	t := g.typeOf(arg)
+	checked := func(function string, value goCode) goCode {
+		return goPrimary(fmt.Sprintf("%s(%s, %q, %s)", function, value.text, e.Name, where))
+	}
+	switch name {
+	case "abs":
+		if t.Kind == TypeInteger {
+			return checked("absInt", g.expr(arg))
+		}
+		return goPrimary("math.Abs(" + g.expr(arg).text + ")")
+	case "sqr":
+		if t.Kind == TypeInteger {
+			return checked("sqrInt", g.expr(arg))
+		}
+		return checked("sqrReal", g.expr(arg))
+	case "sqrt", "exp", "ln", "trunc", "round":
+		return checked(name, g.real(arg))
+	case "sin", "cos", "arctan":
+		function := map[string]string{"sin": "math.Sin", "cos": "math.Cos", "arctan": "math.Atan"}[name]
+		return checked("checkResult", goPrimary(function+"("+g.real(arg).text+")"))
+	case "odd":
+		return goCode{g.expr(arg).wrap(goPrecMultiply) + "%2 != 0", goPrecCompare}
+	case "ord":
+		return goPrimary(g.ordinal(arg))
+	case "chr":
+		return checked("chr", g.expr(arg))
+	case "length":
+		return goPrimary("length(" + g.text(arg).text + ")")
+	case "succ", "pred":
+		return g.step(e, name, arg, t)
+	}
+	g.fail(e.Pos, "неизвестная функция %s", e.Name)
+	return goPrimary("0")
+}
+
+// step транслирует Succ и Pred
+func (g *goGenerator) step(e *CallExpr, name string, arg Expression, t *Type) goCode {
+	value := g.expr(arg).text
+	where := g.where(e.Pos)
+	switch t.Kind {
+	case TypeInteger:
+		return goPrimary(fmt.Sprintf("%sInt(%s, %q, %s)", name, value, e.Name, where))
+	case TypeBoolean:
+		return goPrimary(fmt.Sprintf("%sBool(%s, %q, %s)", name, value, e.Name, where))
+	}
+	if name == "pred" {
+		return goPrimary(fmt.Sprintf("pred(%s, %q, %s)", value, e.Name, where))
+	}
+	high := "255"
+	if t.Kind == TypeEnum {
+		high = g.literal(EnumValue{Type: t, Ordinal: int64(len(t.Values)) - 1}).text
+	}
+	return goPrimary(fmt.Sprintf("succ(%s, %s, %q, %s)", value, high, e.Name, where))
+}
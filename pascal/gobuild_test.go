@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	goast "go/ast"
+	goformat "go/format"
+	goparser "go/parser"
+	gotoken "go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// buildCode транслирует программу в исходный текст на Go
+func buildCode(t *testing.T, code string) (string, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.pas")
+	if err := os.WriteFile(filename, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := NewInfo()
+	program, err := load(filename, "", info)
+	if err != nil {
+		t.Fatalf("%q: неожиданная ошибка: %v", code, err)
+	}
+	source, err := buildGo(program, info, "test.pas")
+	return string(source), err
+}
+
+// goBuildPrograms дополняют программы тестов интерпретатора для сравнения транслированных
+// программ с интерпретатором: переходы из TRY и CASE, вложенные функции, куча и исключения
+var goBuildPrograms = []string{
+	// Присваивание переменной цикла не изменяет число итераций FOR
+	"VAR i, c: INTEGER; BEGIN c := 0; FOR i := 1 TO 5 DO BEGIN i := i + 10; c := c + 1 END END.",
+	"VAR i, s: INTEGER;\nBEGIN\n  s := 0;\n  FOR i := 1 TO 10 DO\n  BEGIN\n    CASE i MOD 3 OF\n      0: Continue;\n" +
+		"      1: IF i > 7 THEN Break\n    ELSE s := s - 1\n    END;\n    s := s + i\n  END\nEND.",
+	"FUNCTION Find(n: INTEGER): INTEGER;\nVAR i: INTEGER;\nBEGIN\n  Find := -1;\n  FOR i := 1 TO 100 DO\n    TRY\n" +
+		"      IF i * i > n THEN Exit(i);\n      IF i = 5 THEN Continue;\n      IF i = 50 THEN Break\n    EXCEPT\n" +
+		"      ON E: Exception DO WriteLn(E.Message)\n    END\nEND;\nBEGIN\n  a := Find(30);\n  b := Find(100000)\nEND.",
+	"VAR n: INTEGER; d: 1..5;\nBEGIN\n  n := 0;\n  REPEAT\n    n := n + 1;\n    IF Odd(n) THEN Continue;\n    WriteLn(n:4)\n" +
+		"  UNTIL n >= 6;\n  TRY\n    FOR d := 1 TO n DO WriteLn(d)\n  EXCEPT\n    ON E: ERangeError DO WriteLn('range: ', E.Message)\n" +
+		"  END\nEND.",
+	"TYPE Point = RECORD x, y: REAL END;\n  PNode = ^Node;\n  Node = RECORD value: INTEGER; next: PNode END;\n" +
+		"VAR head, p: PNode; total: INTEGER; pt: Point; r: REAL;\nPROCEDURE Push(VAR list: PNode; v: INTEGER);\nVAR n: PNode;\n" +
+		"BEGIN\n  New(n); n^.value := v; n^.next := list; list := n\nEND;\nFUNCTION Sum(list: PNode): INTEGER;\n" +
+		"  FUNCTION Go(q: PNode): INTEGER;\n  BEGIN\n    IF q = NIL THEN Go := 0 ELSE Go := q^.value + Go(q^.next)\n  END;\n" +
+		"BEGIN\n  Sum := Go(list)\nEND;\nBEGIN\n  head := NIL;\n  Push(head, 1); Push(head, 2); Push(head, 3);\n" +
+		"  total := Sum(head);\n  pt.x := 1.5; pt.y := -2;\n  r := Sqrt(pt.x * pt.x + pt.y * pt.y);\n" +
+		"  WriteLn(r:0:3, ' ', Round(r), ' ', Trunc(-r));\n  p := head;\n  WHILE p <> NIL DO\n  BEGIN\n" +
+		"    head := p^.next; Dispose(p); p := head\n  END\nEND.",
+	"TYPE Color = (Red, Green, Blue);\nVAR c: Color; s: SET OF Color; ch: CHAR; t: STRING; digits: SET OF '0'..'9';\n" +
+		"BEGIN\n  s := [];\n  FOR c := Red TO Blue DO\n    IF c <> Green THEN s := s + [c];\n  c := Succ(Red);\n" +
+		"  ch := Chr(Ord('a') + 2);\n  t := 'x' + ch + 'yz';\n  digits := ['1'..'3', '7'];\n" +
+		"  WriteLn(t, Length(t):3, Blue IN s, c);\n  CASE ch OF\n    'a'..'b': WriteLn('ab');\n    'c', 'd': WriteLn('cd')\n" +
+		"  ELSE\n    WriteLn('other')\n  END;\n  c := Pred(c);\n  c := Pred(c)\nEND.",
+	"TYPE EMy = CLASS(Exception) END;\nVAR log: STRING;\nPROCEDURE Fail(n: INTEGER);\nBEGIN\n" +
+		"  IF n = 0 THEN RAISE EMy.Create('ноль');\n  Fail(n - 1)\nEND;\nBEGIN\n  log := '';\n  TRY\n    TRY\n      Fail(3)\n" +
+		"    FINALLY\n      log := log + 'f'\n    END\n  EXCEPT\n    ON E: EMy DO log := log + E.Message\n  END;\n  TRY\n" +
+		"    x := 10 DIV (x - x)\n  EXCEPT\n    ON EDivByZero DO log := log + '/0';\n    ELSE log := log + '?'\n  END;\n" +
+		"  TRY\n    TRY\n      y := MAXINT + 1\n    EXCEPT\n      ON E: EIntOverflow DO RAISE\n    END\n  EXCEPT\n" +
+		"    ON E: Exception DO log := log + ' ' + E.ClassName\n  END\nEND.",
+}
+
+// TestBuildGo тестирует трансляцию в отформатированный исходный текст на Go
+func TestBuildGo(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"BEGIN x := 2; y := x * 3 END.",
+			[]string{"// Code generated by pascal build from test.pas. DO NOT EDIT.", "func main() {", "defer finish()",
+				`y = mulInt(x, 3, "строка 1, столбец 22")`, `{"x", formatInt(x)}`}},
+		{"TYPE Color = (Red, Green); VAR c: Color; BEGIN c := Green END.",
+			[]string{"type Color int64", "Red Color = iota", `return [...]string{"Red", "Green"}[v]`, "c = Green"}},
+		{"VAR d: 1..5; n: INTEGER; BEGIN n := 3; d := n END.",
+			[]string{"d int64 = 1", `d = checkRange(n, 1, 5, "d", "1..5", "строка 1, столбец 40")`}},
+		{"VAR s: SET OF CHAR; BEGIN s := ['a'..'z', '_'] END.",
+			[]string{"s = setOf('_').union(setRange('a', 'z'))"}},
+		{"VAR k: INTEGER; FUNCTION F(VAR n: INTEGER): INTEGER; BEGIN n := n + 1; F := n END; BEGIN k := F(k) END.",
+			[]string{"func F(n *int64) (result int64) {", "defer enter()()", "*n = addInt(*n, 1,", "k = F(&k)"}},
+		{"BEGIN IF TRUE THEN Exit; x := 1 END.",
+			[]string{`assigned["x"] = true`, `if assigned["x"] {`}},
+		{"BEGIN WHILE TRUE DO BEGIN CASE 1 OF 1: Break END END END.",
+			[]string{"loop1:\n\tfor {", "break loop1"}},
+	}
+	for _, tt := range tests {
+		source, err := buildCode(t, tt.code)
+		if err != nil {
+			t.Errorf("%q: неожиданная ошибка: %v", tt.code, err)
+			continue
+		}
+		formatted, err := goformat.Source([]byte(source))
+		if err != nil || string(formatted) != source {
+			t.Errorf("%q: код не отформатирован gofmt: %v", tt.code, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(source, want) {
+				t.Errorf("%q: в коде нет %q:\n%s", tt.code, want, source)
+			}
+		}
+	}
+}
+
+// TestBuildGoNames тестирует имена, совпадающие с ключевыми словами и именами среды выполнения
+func TestBuildGoNames(t *testing.T) {
+	source, err := buildCode(t, "VAR go, len, map, formatInt: INTEGER; BEGIN go := 1; len := 2; map := 3 END.")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for _, want := range []string{"go2 ", "len2 ", "map2 ", "formatInt2 int64", "go2 = 1", `{"go", formatInt(go2)}`} {
+		if !strings.Contains(source, want) {
+			t.Errorf("в коде нет %q:\n%s", want, source)
+		}
+	}
+	if _, err := goparser.ParseFile(gotoken.NewFileSet(), "main.go", source, 0); err != nil {
+		t.Errorf("код не разбирается: %v", err)
+	}
+}
+
+// TestBuildGoErrors тестирует конструкции, которые транслятор не поддерживает
+func TestBuildGoErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		// Интерпретатор присваивает глобальной x, если она уже создана, иначе локальной
+		{"PROCEDURE P; BEGIN x := 1 END; BEGIN x := 2; P END.",
+			"строка 1, столбец 20: неописанная переменная x в нескольких областях видимости не поддерживается"},
+		{"BEGIN IF a THEN b := 1; a := TRUE END.",
+			"строка 1, столбец 10: чтение неописанной переменной a до присваивания не поддерживается"},
+	}
+	for _, tt := range tests {
+		_, err := buildCode(t, tt.code)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestMainBuild тестирует команду pascal build
+func TestMainBuild(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "prog.pas")
+	if err := os.WriteFile(program, []byte("BEGIN x := 1 END."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.pas")
+	if err := os.WriteFile(bad, []byte("PROCEDURE P; BEGIN x := 1 END; BEGIN x := 2; P END."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "prog.go")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"build", program}, 1, "Использование: pascal build [-units каталоги] -o файл.go <файл.pas>\n", ""},
+		{[]string{"build", "-o", output, bad}, 1, "", "ошибка трансляции: строка 1, столбец 20: неописанная переменная x"},
+		{[]string{"build", "-o", output, filepath.Join(dir, "missing.pas")}, 1, "", "ошибка чтения файла:"},
+		{[]string{"build", "-o", output, program}, 0, "", ""},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.HasPrefix(stderr.String(), tt.stderr) || tt.stderr == "" && stderr.Len() > 0 {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+	source, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("файл не записан: %v", err)
+	}
+	if !strings.Contains(string(source), "// Code generated by pascal build from prog.pas. DO NOT EDIT.") {
+		t.Errorf("неожиданный заголовок:\n%s", source)
+	}
+}
+
+// goCorpus возвращает программы из строковых литералов тестов пакета: литералы, которые
+// заканчиваются на END. и не являются модулями
+func goCorpus(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("*_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var literal func(expr goast.Expr) (string, bool)
+	literal = func(expr goast.Expr) (string, bool) {
+		switch e := expr.(type) {
+		case *goast.BasicLit:
+			if e.Kind == gotoken.STRING {
+				text, err := strconv.Unquote(e.Value)
+				return text, err == nil
+			}
+		case *goast.BinaryExpr:
+			if e.Op == gotoken.ADD {
+				left, leftOK := literal(e.X)
+				right, rightOK := literal(e.Y)
+				return left + right, leftOK && rightOK
+			}
+		}
+		return "", false
+	}
+	seen := make(map[string]bool)
+	var programs []string
+	for _, name := range files {
+		file, err := goparser.ParseFile(gotoken.NewFileSet(), name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		goast.Inspect(file, func(node goast.Node) bool {
+			expr, ok := node.(goast.Expr)
+			if !ok {
+				return true
+			}
+			text, ok := literal(expr)
+			if !ok {
+				return true
+			}
+			upper := strings.ToUpper(strings.TrimSpace(text))
+			if strings.HasSuffix(upper, "END.") && !strings.HasPrefix(upper, "UNIT") && !seen[text] {
+				seen[text] = true
+				programs = append(programs, text)
+			}
+			return false
+		})
+	}
+	return programs
+}
+
+// stackOverflowPosition - позиция вызова, переполнившего стек, которую транслированная
+// программа не сообщает
+var stackOverflowPosition = regexp.MustCompile(`^(ошибка выполнения: )строка \d+, столбец \d+: (.*\(EStackOverflow\))$`)
+
+// TestBuildGoMatchesInterpreter сравнивает вывод транслированных программ примеров и тестов
+// с выводом интерпретатора: значения переменных, вывод программы, код выхода и ошибку
+func TestBuildGoMatchesInterpreter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("сборка программ на Go занимает время")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("компилятор go не найден")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module corpus\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	examples, err := filepath.Abs("examples")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type goProgram struct {
+		name    string
+		source  string // файл программы
+		workdir string // текущий каталог при выполнении; пустая строка - новый временный каталог
+		units   string
+	}
+	var programs []goProgram
+	files, _ := filepath.Glob(filepath.Join(examples, "*.pas"))
+	for _, file := range files {
+		programs = append(programs, goProgram{"example_" + strings.TrimSuffix(filepath.Base(file), ".pas"), file, examples, filepath.Join(examples, "units")})
+	}
+	if err := os.Mkdir(filepath.Join(dir, "pas"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for n, code := range goCorpus(t) {
+		file := filepath.Join(dir, "pas", "p"+strconv.Itoa(n)+".pas")
+		if err := os.WriteFile(file, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		programs = append(programs, goProgram{name: "p" + strconv.Itoa(n), source: file})
+	}
+
+	var built []goProgram
+	for _, p := range programs {
+		info := NewInfo()
+		program, err := load(p.source, p.units, info)
+		if err != nil {
+			// Программы тестов ошибок анализа
+			continue
+		}
+		source, err := buildGo(program, info, filepath.Base(p.source))
+		if err != nil {
+			code, _ := os.ReadFile(p.source)
+			if strings.Contains(err.Error(), "не поддерживается") {
+				t.Logf("%q: %v", code, err)
+			} else {
+				t.Errorf("%q: ошибка трансляции: %v", code, err)
+			}
+			continue
+		}
+		if err := os.Mkdir(filepath.Join(dir, p.name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, p.name, "main.go"), source, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		built = append(built, p)
+	}
+	if len(built) < len(files) {
+		t.Fatalf("транслировано программ: %d", len(built))
+	}
+
+	interpreter := filepath.Join(dir, "bin", "pascal")
+	buildCorpus := exec.Command(goTool, "build", "-o", filepath.Join(dir, "bin")+string(filepath.Separator), "./...")
+	buildCorpus.Dir = dir
+	for _, command := range []*exec.Cmd{exec.Command(goTool, "build", "-o", interpreter, "."), buildCorpus} {
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %v\n%s", command.Args, err, output)
+		}
+	}
+
+	execute := func(p goProgram, name string, args ...string) (stdout, stderr string, code int) {
+		command := exec.Command(name, args...)
+		command.Dir = p.workdir
+		if command.Dir == "" {
+			command.Dir = t.TempDir()
+		}
+		var out, errs bytes.Buffer
+		command.Stdout, command.Stderr = &out, &errs
+		if err := command.Run(); err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatalf("%s: %v", name, err)
+			}
+			code = exitErr.ExitCode()
+		}
+		stderr, _, _ = strings.Cut(errs.String(), "\n")
+		return out.String(), stackOverflowPosition.ReplaceAllString(stderr, "$1$2"), code
+	}
+	for _, p := range built {
+		args := []string{"-root", ".", p.source}
+		if p.units != "" {
+			args = append([]string{"-units", p.units}, args...)
+		}
+		wantStdout, wantStderr, wantCode := execute(p, interpreter, args...)
+		stdout, stderr, code := execute(p, filepath.Join(dir, "bin", p.name))
+		if stdout != wantStdout || stderr != wantStderr || code != wantCode {
+			source, _ := os.ReadFile(p.source)
+			t.Errorf("%s %q:\nинтерпретатор: %q, %q, код %d\nпрограмма на Go: %q, %q, код %d",
+				p.name, source, wantStdout, wantStderr, wantCode, stdout, stderr, code)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Эталонные файлы программы prog.pas набора тестов лежат рядом с ней:
+// prog.in - стандартный ввод, prog.out - ожидаемый вывод программы, prog.expected -
+// ожидаемый словарь переменных или сообщение об ошибке. Файлы .in и .out необязательны.
+const (
+	goldenInput    = ".in"
+	goldenOutput   = ".out"
+	goldenExpected = ".expected"
+)
+
+// goldenOptions задает режимы команды pascal test
+type goldenOptions struct {
+	units   string        // каталоги поиска модулей
+	update  bool          // перезаписать эталоны полученными результатами
+	jobs    int           // число программ, выполняемых одновременно
+	timeout time.Duration // время выполнения одной программы; 0 - без ограничения
+}
+
+// testResult - результат теста: программы набора эталонных тестов или тестовой процедуры
+type testResult struct {
+	Name     string
+	Passed   bool
+	Updated  bool   // эталоны перезаписаны флагом -update
+	Failure  string // причина неудачи: различия с эталоном или ошибка
+	Duration time.Duration
+}
+
+// runGoldenSuite выполняет программы *.pas каталога dir пулом из options.jobs горутин,
+// сравнивает результаты с эталонами и возвращает их в порядке имен программ
+func runGoldenSuite(dir string, options goldenOptions) ([]testResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога: %v", err)
+	}
+	var programs []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".pas") {
+			programs = append(programs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(programs) == 0 {
+		return nil, fmt.Errorf("в каталоге %s нет программ *.pas", dir)
+	}
+	sort.Strings(programs)
+
+	results := make([]testResult, len(programs))
+	jobs := make(chan int)
+	var workers sync.WaitGroup
+	for w := 0; w < max(1, options.jobs); w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for n := range jobs {
+				results[n] = runGolden(programs[n], options)
+			}
+		}()
+	}
+	for n := range programs {
+		jobs <- n
+	}
+	close(jobs)
+	workers.Wait()
+	return results, nil
+}
+
+// runGolden выполняет программу и сравнивает ее вывод и итоговый результат с эталонами
+func runGolden(program string, options goldenOptions) (result testResult) {
+	start := time.Now()
+	base := strings.TrimSuffix(program, filepath.Ext(program))
+	result = testResult{Name: filepath.Base(base)}
+	defer func() { result.Duration = time.Since(start) }()
+
+	input, _, err := readGolden(base + goldenInput)
+	if err != nil {
+		result.Failure = err.Error()
+		return result
+	}
+	output, outcome := executeGolden(program, input, options)
+
+	expectedOutput, hasOutput, err := readGolden(base + goldenOutput)
+	if err != nil {
+		result.Failure = err.Error()
+		return result
+	}
+	if options.update {
+		// Вывод записывается, если программа что-то вывела или эталон вывода уже есть
+		if output != "" || hasOutput {
+			err = os.WriteFile(base+goldenOutput, []byte(output), 0o644)
+		}
+		if err == nil {
+			err = os.WriteFile(base+goldenExpected, []byte(outcome), 0o644)
+		}
+		if err != nil {
+			result.Failure = fmt.Sprintf("ошибка записи эталона: %v", err)
+			return result
+		}
+		result.Passed, result.Updated = true, true
+		return result
+	}
+
+	expected, hasExpected, err := readGolden(base + goldenExpected)
+	if err != nil {
+		result.Failure = err.Error()
+		return result
+	}
+	if !hasOutput && !hasExpected {
+		result.Failure = fmt.Sprintf("нет эталонов %s%s и %s%s (создайте их флагом -update)", result.Name, goldenOutput, result.Name, goldenExpected)
+		return result
+	}
+	var diffs []string
+	if hasOutput {
+		if diff := unifiedDiff(result.Name+goldenOutput, result.Name+goldenOutput+" (получено)", expectedOutput, output); diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	if hasExpected {
+		if diff := unifiedDiff(result.Name+goldenExpected, result.Name+goldenExpected+" (получено)", expected, outcome); diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	result.Passed = len(diffs) == 0
+	result.Failure = strings.Join(diffs, "")
+	return result
+}
+
+// readGolden читает эталонный файл; ok ложно, если файла нет
+func readGolden(path string) (text string, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("ошибка чтения эталона: %v", err)
+	}
+	return string(data), true, nil
+}
+
+// executeGolden выполняет программу со стандартным вводом input и возвращает ее вывод
+// и итоговый результат: словарь переменных или сообщение об ошибке, как их выводит
+// pascal. Программа открывает файлы своего каталога, а записанные ею файлы остаются
+// в памяти, чтобы тесты не изменяли набор.
+func executeGolden(program, input string, options goldenOptions) (output, outcome string) {
+	parsed, err := loadChecked(program, options.units, DialectLenient, NewChecker())
+	if err != nil {
+		return "", err.Error() + "\n"
+	}
+	var stdout bytes.Buffer
+	interpreter := NewInterpreterWithOptions(Options{
+		Stdin:  strings.NewReader(input),
+		Stdout: &stdout,
+		Files:  NewOverlayFS(os.DirFS(filepath.Dir(program))),
+	})
+	if err := interpretWithTimeout(interpreter, parsed, options.timeout); err != nil {
+		return stdout.String(), describeTestError(err) + "\n"
+	}
+	return stdout.String(), formatVariables(interpreter.Values()) + "\n"
+}
+
+// timeoutError сообщает, что программа выполнялась дольше отведенного времени
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("превышено время выполнения %v", e.timeout)
+}
+
+// interpretWithTimeout выполняет программу и останавливает ее, если она выполняется
+// дольше timeout; timeout 0 - без ограничения
+func interpretWithTimeout(interpreter *Interpreter, program *Program, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- interpreter.Interpret(program) }()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-expired:
+		// Выполнение останавливается перед следующим оператором
+		interpreter.Suspend()
+		<-done
+		return &timeoutError{timeout}
+	}
+}
+
+// describeTestError описывает ошибку выполнения теста так, как ее выводит pascal
+func describeTestError(err error) string {
+	var timeout *timeoutError
+	if errors.As(err, &timeout) {
+		return err.Error()
+	}
+	return fmt.Sprintf("ошибка выполнения: %v", describeError(err))
+}
+
+// writeTestResults выводит результаты тестов с различиями для неудачных и итог
+func writeTestResults(w io.Writer, results []testResult) {
+	failed := 0
+	for _, result := range results {
+		status := "PASS"
+		switch {
+		case result.Updated:
+			status = "UPDATE"
+		case !result.Passed:
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "%-6s %s (%v)\n", status, result.Name, result.Duration.Round(time.Millisecond))
+		if !result.Passed {
+			for _, line := range splitLines(result.Failure) {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+	fmt.Fprintf(w, "пройдено: %d, не пройдено: %d, всего: %d\n", len(results)-failed, failed, len(results))
+}
+
+// junitSuite и junitCase - отчет о тестах в формате JUnit XML
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit записывает результаты тестов набора name в формате JUnit XML
+func writeJUnit(w io.Writer, name string, results []testResult) error {
+	suite := junitSuite{Name: name, Tests: len(results)}
+	for _, result := range results {
+		c := junitCase{Name: result.Name, Classname: name, Time: result.Duration.Seconds()}
+		if !result.Passed {
+			suite.Failures++
+			message, _, _ := strings.Cut(result.Failure, "\n")
+			c.Failure = &junitFailure{Message: message, Text: result.Failure}
+		}
+		suite.Time += c.Time
+		suite.Cases = append(suite.Cases, c)
+	}
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// testUsage - строки использования команды pascal test
+const testUsage = `Использование: pascal test [-update] [-j N] [-timeout время] [-junit файл.xml] [-units каталоги] <каталог>
+       pascal test -unit [-j N] [-timeout время] [-junit файл.xml] [-units каталоги] <файл.pas>`
+
+// testWithExitCode выполняет команду pascal test и возвращает код выхода
+func testWithExitCode(args []string) int {
+	var options goldenOptions
+	var junit string
+	var unit bool
+	flags := flag.NewFlagSet("pascal test", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.BoolVar(&options.update, "update", false, "перезаписать эталоны .out и .expected полученными результатами")
+	flags.IntVar(&options.jobs, "j", runtime.NumCPU(), "число программ, выполняемых одновременно")
+	flags.DurationVar(&options.timeout, "timeout", 10*time.Second, "время выполнения одной программы (0 - без ограничения)")
+	flags.BoolVar(&unit, "unit", false, "выполнить тестовые процедуры Test* программы, каждую в новом интерпретаторе")
+	flags.StringVar(&junit, "junit", "", "записать результаты в файл в формате JUnit XML")
+	flags.StringVar(&options.units, "units", "", "каталоги поиска модулей USES, разделенные '"+string(os.PathListSeparator)+"'")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), testUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() < 1 {
+		fmt.Println(testUsage)
+		return 1
+	}
+	if unit && options.update {
+		fmt.Fprintln(os.Stderr, "флаг -update не применяется к тестовым процедурам")
+		return 1
+	}
+
+	target := flags.Arg(0)
+	suite := filepath.Base(filepath.Clean(target))
+	var results []testResult
+	var err error
+	if unit {
+		suite = strings.TrimSuffix(suite, filepath.Ext(suite))
+		results, err = runUnitTests(target, options)
+	} else {
+		results, err = runGoldenSuite(target, options)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	writeTestResults(os.Stdout, results)
+	if junit != "" {
+		var report bytes.Buffer
+		if err := writeJUnit(&report, suite, results); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка записи отчета: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(junit, report.Bytes(), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка записи файла: %v\n", err)
+			return 1
+		}
+	}
+	for _, result := range results {
+		if !result.Passed {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSuite создает в каталоге файлы набора эталонных тестов
+func writeSuite(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Бесконечные циклы, в том числе пустой; точка добавляется отдельно, чтобы программа не
+// попала в корпус сравнения с транслированными программами
+const (
+	loopForever = "BEGIN WHILE TRUE DO x := x + 1 END"
+	spinForever = "BEGIN REPEAT UNTIL FALSE END"
+)
+
+// TestGoldenSuite тестирует сравнение программ набора с эталонами
+func TestGoldenSuite(t *testing.T) {
+	dir := t.TempDir()
+	dot := "."
+	writeSuite(t, dir, map[string]string{
+		"echo.pas":      "VAR s: STRING; BEGIN ReadLn(s); WriteLn('got ', s) END.",
+		"echo.in":       "hello\n",
+		"echo.out":      "got hello\n",
+		"echo.expected": "{s: 'hello'}\n",
+		// Файл, записанный программой, не появляется в каталоге набора
+		"files.pas":      "VAR f: TEXT; BEGIN Assign(f, 'note.txt'); Rewrite(f); WriteLn(f, 1); Close(f) END.",
+		"files.expected": "{f: TEXT('note.txt')}\n",
+		"wrong.pas":      "BEGIN WriteLn(1); WriteLn(2); x := 3 END.",
+		"wrong.out":      "1\n3\n",
+		"wrong.expected": "{x: 4}\n",
+		"crash.pas":      "BEGIN x := 0; y := 1 DIV x END.",
+		"crash.expected": "ошибка выполнения: строка 1, столбец 22: деление на ноль (EDivByZero)\n",
+		"syntax.pas":     "BEGIN x := 1 +",
+		"loop.pas":       loopForever + dot,
+		"loop.expected":  "превышено время выполнения 100ms\n",
+		"spin.pas":       spinForever + dot,
+		"spin.expected":  "превышено время выполнения 100ms\n",
+		"new.pas":        "BEGIN x := 1 END.",
+		"notes.txt":      "не программа",
+	})
+
+	results, err := runGoldenSuite(dir, goldenOptions{jobs: 3, timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("ошибка выполнения набора: %v", err)
+	}
+	expected := []struct {
+		name    string
+		passed  bool
+		failure string
+	}{
+		{"crash", true, ""},
+		{"echo", true, ""},
+		{"files", true, ""},
+		{"loop", true, ""},
+		{"new", false, "нет эталонов new.out и new.expected (создайте их флагом -update)"},
+		{"spin", true, ""},
+		{"syntax", false, "нет эталонов syntax.out и syntax.expected (создайте их флагом -update)"},
+		{"wrong", false, "--- wrong.out\n+++ wrong.out (получено)\n@@ -1,2 +1,2 @@\n 1\n-3\n+2\n" +
+			"--- wrong.expected\n+++ wrong.expected (получено)\n@@ -1 +1 @@\n-{x: 4}\n+{x: 3}\n"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("ожидалось результатов: %d, получено %d", len(expected), len(results))
+	}
+	for n, want := range expected {
+		got := results[n]
+		if got.Name != want.name || got.Passed != want.passed || got.Failure != want.failure {
+			t.Errorf("ожидался результат %s %v %q, получено %s %v %q", want.name, want.passed, want.failure, got.Name, got.Passed, got.Failure)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "note.txt")); err == nil {
+		t.Errorf("программа записала файл в каталог набора")
+	}
+
+	// -update записывает эталоны, в том числе сообщение об ошибке анализа; пустой вывод не записывается
+	if _, err := runGoldenSuite(dir, goldenOptions{update: true, timeout: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("ошибка обновления эталонов: %v", err)
+	}
+	for name, text := range map[string]string{
+		"new.expected":    "{x: 1}\n",
+		"wrong.out":       "1\n2\n",
+		"wrong.expected":  "{x: 3}\n",
+		"syntax.expected": "ошибка синтаксического анализа: ",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || !strings.HasPrefix(string(data), text) {
+			t.Errorf("%s: ожидалось %q, получено %q (ошибка %v)", name, text, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.out")); err == nil {
+		t.Errorf("записан эталон пустого вывода new.out")
+	}
+	results, err = runGoldenSuite(dir, goldenOptions{timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("ошибка выполнения набора: %v", err)
+	}
+	for _, result := range results {
+		if !result.Passed {
+			t.Errorf("%s: после обновления эталонов тест не пройден: %s", result.Name, result.Failure)
+		}
+	}
+
+	if _, err := runGoldenSuite(t.TempDir(), goldenOptions{}); err == nil || !strings.Contains(err.Error(), "нет программ *.pas") {
+		t.Errorf("ожидалась ошибка пустого набора, получено %v", err)
+	}
+}
+
+// TestExamplesSuite тестирует, что примеры проходят проверку по своим эталонам
+func TestExamplesSuite(t *testing.T) {
+	results, err := runGoldenSuite("examples", goldenOptions{units: filepath.Join("examples", "units"), jobs: 4})
+	if err != nil {
+		t.Fatalf("ошибка выполнения набора: %v", err)
+	}
+	for _, result := range results {
+		if !result.Passed {
+			t.Errorf("%s:\n%s", result.Name, result.Failure)
+		}
+	}
+}
+
+// durations - длительности выполнения в выводе pascal test
+var durations = regexp.MustCompile(`\(\d[\d.]*[µm]?s\)`)
+
+// TestMainTest тестирует команду pascal test
+func TestMainTest(t *testing.T) {
+	dir := t.TempDir()
+	writeSuite(t, dir, map[string]string{
+		"good.pas":      "BEGIN x := 2 * 21 END.",
+		"good.expected": "{x: 42}\n",
+		"bad.pas":       "BEGIN WriteLn('a < b') END.",
+		"bad.out":       "a > b\n",
+	})
+	junit := filepath.Join(dir, "report.xml")
+	unit := filepath.Join(t.TempDir(), "unit.pas")
+	writeSuite(t, filepath.Dir(unit), map[string]string{
+		"unit.pas": "PROCEDURE TestOk;\nBEGIN Assert(TRUE) END;\nPROCEDURE TestBad;\nBEGIN Assert(FALSE, 'плохо') END;\nBEGIN END.",
+	})
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"test", "-junit", junit, dir}, 1, "FAIL   bad ()\n    --- bad.out\n    +++ bad.out (получено)\n    @@ -1 +1 @@\n    -a > b\n    +a < b\n" +
+			"PASS   good ()\nпройдено: 1, не пройдено: 1, всего: 2\n", ""},
+		{[]string{"test", "-update", dir}, 0, "UPDATE bad ()\nUPDATE good ()\nпройдено: 2, не пройдено: 0, всего: 2\n", ""},
+		{[]string{"test", "-j", "1", dir}, 0, "PASS   bad ()\nPASS   good ()\nпройдено: 2, не пройдено: 0, всего: 2\n", ""},
+		{[]string{"test", filepath.Join(dir, "missing")}, 1, "", "ошибка чтения каталога: open " + filepath.Join(dir, "missing") + ": no such file or directory\n"},
+		{[]string{"test", "-unit", unit}, 1, "PASS   TestOk ()\nFAIL   TestBad ()\n    ошибка выполнения: строка 4, столбец 7: плохо (EAssertionFailed)\n" +
+			"    стек вызовов:\n      TestBad (строка 4, столбец 7)\nпройдено: 1, не пройдено: 1, всего: 2\n", ""},
+		{[]string{"test", "-unit", "-update", unit}, 1, "", "флаг -update не применяется к тестовым процедурам\n"},
+		{[]string{"test"}, 1, testUsage + "\n", ""},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if output := durations.ReplaceAllString(stdout.String(), "()"); output != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, output)
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+
+	report, err := os.ReadFile(junit)
+	if err != nil {
+		t.Fatalf("отчет JUnit не записан: %v", err)
+	}
+	for _, fragment := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<testsuite name="` + filepath.Base(dir) + `" tests="2" failures="1"`,
+		`<testcase name="bad" classname="` + filepath.Base(dir) + `"`,
+		`<failure message="--- bad.out">--- bad.out&#xA;+++ bad.out (получено)&#xA;@@ -1 +1 @@&#xA;-a &gt; b&#xA;+a &lt; b&#xA;</failure>`,
+		`<testcase name="good" classname="` + filepath.Base(dir) + `"`,
+	} {
+		if !strings.Contains(string(report), fragment) {
+			t.Errorf("в отчете JUnit нет %q:\n%s", fragment, report)
+		}
+	}
+}
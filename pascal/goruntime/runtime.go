@@ -0,0 +1,1158 @@
+//go:build ignore
+
+// Среда выполнения программ, транслированных в Go командой pascal build.
+// Генератор (gobuild.go) дописывает этот файл после кода программы, избегая в
+// программе имен, описанных здесь. Сообщения об ошибках и формат значений совпадают
+// с интерпретатором, поэтому транслированная программа выводит то же, что и pascal.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// class - класс исключения
+type class struct {
+	name   string
+	parent *class
+}
+
+// Стандартные классы исключений
+var (
+	Exception        = &class{name: "Exception"}
+	EIntError        = &class{name: "EIntError", parent: Exception}
+	EDivByZero       = &class{name: "EDivByZero", parent: EIntError}
+	ERangeError      = &class{name: "ERangeError", parent: EIntError}
+	EIntOverflow     = &class{name: "EIntOverflow", parent: EIntError}
+	EMathError       = &class{name: "EMathError", parent: Exception}
+	EInvalidOp       = &class{name: "EInvalidOp", parent: EMathError}
+	EOverflow        = &class{name: "EOverflow", parent: EMathError}
+	EInOutError      = &class{name: "EInOutError", parent: Exception}
+	EAccessViolation = &class{name: "EAccessViolation", parent: Exception}
+	EInvalidPointer  = &class{name: "EInvalidPointer", parent: Exception}
+	EStackOverflow   = &class{name: "EStackOverflow", parent: Exception}
+	EAssertionFailed = &class{name: "EAssertionFailed", parent: Exception}
+)
+
+// is сообщает, совпадает ли класс c с классом base или унаследован от него
+func (c *class) is(base *class) bool {
+	for ; c != nil; c = c.parent {
+		if c == base {
+			return true
+		}
+	}
+	return false
+}
+
+// Create создает исключение, как Класс.Create(сообщение)
+func (c *class) Create(message string) *exception {
+	return &exception{class: c, message: message}
+}
+
+// exception - объект исключения; переменная класса ссылается на объект, nil - NIL
+type exception struct {
+	class   *class
+	message string
+}
+
+func (x *exception) String() string {
+	if x == nil {
+		return "NIL"
+	}
+	return x.class.name + "(" + formatString(x.message) + ")"
+}
+
+// exceptionField возвращает поле Message или ClassName исключения
+func exceptionField(x *exception, field, where string) string {
+	if x == nil {
+		raise(EAccessViolation, where, "обращение к полю %s исключения NIL", field)
+	}
+	if strings.EqualFold(field, "classname") {
+		return x.class.name
+	}
+	return x.message
+}
+
+// pascalError - возбужденное исключение; передается вверх по стеку через panic
+type pascalError struct {
+	class   *class // nil для ошибок без класса: их перехватывают обработчики Exception
+	message string
+	where   string // позиция в исходном тексте Pascal; пустая, если неизвестна
+}
+
+// raise возбуждает исключение класса class в позиции where
+func raise(class *class, where, format string, args ...any) {
+	panic(&pascalError{class: class, message: fmt.Sprintf(format, args...), where: where})
+}
+
+// raiseException выполняет RAISE исключение
+func raiseException(x *exception, where string) {
+	if x == nil {
+		raise(EAccessViolation, where, "RAISE: исключение NIL")
+	}
+	panic(&pascalError{class: x.class, message: x.message, where: where})
+}
+
+// is сообщает, перехватывает ли обработчик класса c это исключение
+func (e *pascalError) is(c *class) bool {
+	if e.class == nil {
+		return Exception.is(c)
+	}
+	return e.class.is(c)
+}
+
+// exception возвращает объект исключения для переменной обработчика
+func (e *pascalError) exception() *exception {
+	if e.class == nil {
+		return Exception.Create(e.message)
+	}
+	return e.class.Create(e.message)
+}
+
+func (e *pascalError) Error() string {
+	var description strings.Builder
+	if e.where != "" {
+		description.WriteString(e.where + ": ")
+	}
+	description.WriteString(e.message)
+	if e.class != nil {
+		description.WriteString(" (" + e.class.name + ")")
+	}
+	return description.String()
+}
+
+// flow - передача управления Break, Continue или Exit из блока TRY, который
+// выполняется в отдельной функции
+type flow int
+
+const (
+	flowNormal flow = iota
+	flowBreak
+	flowContinue
+	flowExit
+)
+
+// tryExcept выполняет TRY ... EXCEPT: исключение из body передается handler
+func tryExcept(body func() flow, handler func(e *pascalError) flow) (result flow) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*pascalError)
+			if !ok {
+				panic(r)
+			}
+			result = handler(e)
+		}
+	}()
+	return body()
+}
+
+// tryFinally выполняет TRY ... FINALLY: final выполняется всегда, кроме завершения
+// программы процедурой Halt, а исключение из final заменяет исключение body
+func tryFinally(body func() flow, final func()) flow {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, halted := r.(haltSignal); !halted {
+				final()
+			}
+			panic(r)
+		}
+	}()
+	result := body()
+	final()
+	return result
+}
+
+// haltSignal - паника процедуры Halt с кодом выхода программы
+type haltSignal int64
+
+// halt выполняет Halt: программа завершается сразу, не выполняя блоков FINALLY
+func halt(code int64) {
+	panic(haltSignal(code))
+}
+
+// exitCode - код выхода, заданный Halt; finish завершает с ним программу
+var exitCode int
+
+// run выполняет инициализацию модулей и тело программы; Halt прекращает выполнение
+// и задает код выхода
+func run(program func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			signal, ok := r.(haltSignal)
+			if !ok {
+				panic(r)
+			}
+			exitCode = int(signal)
+		}
+	}()
+	program()
+}
+
+// maxCallDepth ограничивает глубину вызовов, как в интерпретаторе
+const maxCallDepth = 10000
+
+var depth int
+
+// enter учитывает вызов подпрограммы и возвращает функцию, завершающую его:
+// defer enter()()
+func enter() func() {
+	if depth++; depth > maxCallDepth {
+		depth--
+		raise(EStackOverflow, "", "переполнение стека: глубина вызовов превысила %d", maxCallDepth)
+	}
+	return leave
+}
+
+func leave() {
+	depth--
+}
+
+// addInt, subInt и mulInt выполняют целочисленные операции с контролем переполнения
+func addInt(a, b int64, where string) int64 {
+	c := a + b
+	if (c > a) != (b > 0) {
+		raise(EIntOverflow, where, "целочисленное переполнение")
+	}
+	return c
+}
+
+func subInt(a, b int64, where string) int64 {
+	c := a - b
+	if (c < a) != (b > 0) {
+		raise(EIntOverflow, where, "целочисленное переполнение")
+	}
+	return c
+}
+
+func mulInt(a, b int64, where string) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		raise(EIntOverflow, where, "целочисленное переполнение")
+	}
+	return c
+}
+
+// divInt и modInt выполняют DIV и MOD
+func divInt(a, b int64, where string) int64 {
+	if b == 0 {
+		raise(EDivByZero, where, "деление на ноль")
+	}
+	if a == math.MinInt64 && b == -1 {
+		raise(EIntOverflow, where, "целочисленное переполнение")
+	}
+	return a / b
+}
+
+func modInt(a, b int64, where string) int64 {
+	if b == 0 {
+		raise(EDivByZero, where, "деление на ноль")
+	}
+	if b == -1 {
+		return 0
+	}
+	return a % b
+}
+
+// divide выполняет вещественное деление /
+func divide(a, b float64, where string) float64 {
+	if b == 0 {
+		raise(EDivByZero, where, "деление на ноль")
+	}
+	return finite(a/b, where)
+}
+
+// finite не допускает попадания бесконечности и NaN в переменные
+func finite(x float64, where string) float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		raise(EOverflow, where, "вещественное переполнение")
+	}
+	return x
+}
+
+// Стандартные функции, которые могут завершиться ошибкой; name - имя функции,
+// как оно записано в программе, для сообщения
+
+func absInt(x int64, name, where string) int64 {
+	if x == math.MinInt64 {
+		raise(EIntOverflow, where, "%s: целочисленное переполнение", name)
+	}
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sqrInt(x int64, name, where string) int64 {
+	if x != 0 && (x*x/x != x || x == math.MinInt64) {
+		raise(EIntOverflow, where, "%s: целочисленное переполнение", name)
+	}
+	return x * x
+}
+
+func sqrReal(x float64, name, where string) float64 {
+	return checkResult(x*x, name, where)
+}
+
+func sqrt(x float64, name, where string) float64 {
+	if x < 0 {
+		raise(EInvalidOp, where, "%s: корень из отрицательного числа %g", name, x)
+	}
+	return math.Sqrt(x)
+}
+
+func exp(x float64, name, where string) float64 {
+	return checkResult(math.Exp(x), name, where)
+}
+
+func ln(x float64, name, where string) float64 {
+	if x <= 0 {
+		raise(EInvalidOp, where, "%s: логарифм неположительного числа %g", name, x)
+	}
+	return math.Log(x)
+}
+
+func checkResult(x float64, name, where string) float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		raise(EOverflow, where, "%s: вещественное переполнение", name)
+	}
+	return x
+}
+
+// trunc и round преобразуют вещественное число в целое; round округляет половины от нуля
+func trunc(x float64, name, where string) int64 {
+	return toInteger(math.Trunc(x), name, where)
+}
+
+func round(x float64, name, where string) int64 {
+	return toInteger(math.Round(x), name, where)
+}
+
+func toInteger(x float64, name, where string) int64 {
+	if math.IsNaN(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		raise(ERangeError, where, "%s: значение %g вне диапазона INTEGER", name, x)
+	}
+	return int64(x)
+}
+
+// chr возвращает символ с кодом 0..255
+func chr(n int64, name, where string) rune {
+	if n < 0 || n > maxSetOrdinal {
+		raise(ERangeError, where, "%s: код символа %d вне диапазона 0..%d", name, n, maxSetOrdinal)
+	}
+	return rune(n)
+}
+
+// length возвращает число символов строки
+func length(s string) int64 {
+	return int64(utf8.RuneCountInString(s))
+}
+
+// ord возвращает порядковый номер логического значения
+func ord(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ordinal - порядковые типы, представленные целыми: INTEGER, CHAR и перечисления;
+// int - тип целых констант без явного типа
+type ordinal interface {
+	~int64 | ~int32 | ~int
+}
+
+// succ и pred возвращают следующее и предыдущее значение перечисления или символа
+// с порядковыми номерами 0..high
+func succ[T ordinal](x, high T, name, where string) T {
+	if x == high {
+		raise(ERangeError, where, "%s: у значения %s нет следующего", name, formatOrdinal(x))
+	}
+	return x + 1
+}
+
+func pred[T ordinal](x T, name, where string) T {
+	if x == 0 {
+		raise(ERangeError, where, "%s: у значения %s нет предыдущего", name, formatOrdinal(x))
+	}
+	return x - 1
+}
+
+func succInt(x int64, name, where string) int64 {
+	if x == math.MaxInt64 {
+		raise(EIntOverflow, where, "%s: целочисленное переполнение", name)
+	}
+	return x + 1
+}
+
+func predInt(x int64, name, where string) int64 {
+	if x == math.MinInt64 {
+		raise(EIntOverflow, where, "%s: целочисленное переполнение", name)
+	}
+	return x - 1
+}
+
+func succBool(b bool, name, where string) bool {
+	if b {
+		raise(ERangeError, where, "%s: у значения TRUE нет следующего", name)
+	}
+	return true
+}
+
+func predBool(b bool, name, where string) bool {
+	if !b {
+		raise(ERangeError, where, "%s: у значения FALSE нет предыдущего", name)
+	}
+	return false
+}
+
+// checkRange проверяет, что значение переменной name диапазонного типа лежит в low..high
+func checkRange[T ordinal](value T, low, high int64, name, bounds, where string) T {
+	if int64(value) < low || int64(value) > high {
+		raise(ERangeError, where, "нарушение диапазона: значение %s переменной %s вне диапазона %s", formatOrdinal(value), name, bounds)
+	}
+	return value
+}
+
+// checkBoolRange проверяет, что логическое значение лежит в диапазоне low..high
+func checkBoolRange(value bool, low, high int64, name, bounds, where string) bool {
+	if ord(value) < low || ord(value) > high {
+		raise(ERangeError, where, "нарушение диапазона: значение %s переменной %s вне диапазона %s", formatBool(value), name, bounds)
+	}
+	return value
+}
+
+// checkSetRange проверяет, что элементы множества лежат в диапазоне low..high его базового типа
+func checkSetRange(value set, low, high int64, format func(int64) string, name, bounds, where string) set {
+	for _, n := range value.members() {
+		if n < low || n > high {
+			raise(ERangeError, where, "нарушение диапазона: значение %s переменной %s вне диапазона %s", value.format(format), name, bounds)
+		}
+	}
+	return value
+}
+
+// invalid сообщает об ошибке операции, которую интерпретатор обнаруживает только при
+// выполнении; операнды вычисляются до ошибки
+func invalid[T any](where, message string, operands ...any) T {
+	raise(nil, where, "%s", message)
+	panic("недостижимо")
+}
+
+// caseError сообщает, что значение выражения CASE не соответствует ни одной метке
+func caseError(value any, where string) {
+	raise(nil, where, "значение %s не соответствует ни одной метке CASE", formatOrdinal(value))
+}
+
+// Форматирование значений, как в выводе переменных интерпретатора
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// formatReal выводит целое вещественное число без дробной части
+func formatReal(x float64) string {
+	if x == math.Trunc(x) && math.Abs(x) < 1e18 {
+		return strconv.FormatInt(int64(x), 10)
+	}
+	return fmt.Sprintf("%g", x)
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// formatChar выводит символ в апострофах, а непечатаемый символ - в виде #код
+func formatChar(c rune) string {
+	if !unicode.IsPrint(c) {
+		return fmt.Sprintf("#%d", c)
+	}
+	return formatString(string(c))
+}
+
+// formatString выводит строку в апострофах, удваивая апострофы внутри нее
+func formatString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// formatOrdinal форматирует порядковое значение: перечисления описывают метод String,
+// rune - символ
+func formatOrdinal(value any) string {
+	switch v := value.(type) {
+	case fmt.Stringer:
+		return v.String()
+	case bool:
+		return formatBool(v)
+	case rune:
+		return formatChar(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// intElement, charElement, boolElement и element форматируют элементы множеств целых чисел,
+// символов, логических значений и перечислений
+func intElement(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func element[T interface {
+	~int64
+	String() string
+}](n int64) string {
+	return T(n).String()
+}
+
+func charElement(n int64) string {
+	return formatChar(rune(n))
+}
+
+func boolElement(n int64) string {
+	return formatBool(n != 0)
+}
+
+// maxSetOrdinal - наибольший порядковый номер элемента множества
+const maxSetOrdinal = 255
+
+// set - множество: битовая шкала порядковых номеров 0..255
+type set [(maxSetOrdinal + 1) / 64]uint64
+
+// setOf создает множество из значений с порядковыми номерами 0..255
+func setOf[T ordinal](elements ...T) set {
+	var s set
+	for _, element := range elements {
+		s[element/64] |= 1 << (element % 64)
+	}
+	return s
+}
+
+// setRange создает множество значений low..high с порядковыми номерами 0..255
+func setRange[T ordinal](low, high T) set {
+	var s set
+	for n := low; n <= high; n++ {
+		s[n/64] |= 1 << (n % 64)
+	}
+	return s
+}
+
+// span - диапазон low..high в конструкторе множества
+type span struct {
+	low, high any
+}
+
+// makeSet вычисляет конструктор множества из порядковых значений и диапазонов span
+func makeSet(where string, elements ...any) set {
+	var s set
+	for _, element := range elements {
+		low, high := element, element
+		if r, ok := element.(span); ok {
+			low, high = r.low, r.high
+		}
+		from, to := setOrdinal(low, where), setOrdinal(high, where)
+		for n := from; n <= to; n++ {
+			s[n/64] |= 1 << (n % 64)
+		}
+	}
+	return s
+}
+
+// setOrdinal возвращает порядковый номер элемента множества
+func setOrdinal(element any, where string) int64 {
+	v := reflect.ValueOf(element)
+	var n int64
+	if v.Kind() == reflect.Bool {
+		n = ord(v.Bool())
+	} else {
+		n = v.Int()
+	}
+	if n < 0 || n > maxSetOrdinal {
+		raise(ERangeError, where, "элемент множества %s вне диапазона 0..%d", formatOrdinal(element), maxSetOrdinal)
+	}
+	return n
+}
+
+func (s set) union(t set) set {
+	for n := range s {
+		s[n] |= t[n]
+	}
+	return s
+}
+
+func (s set) intersection(t set) set {
+	for n := range s {
+		s[n] &= t[n]
+	}
+	return s
+}
+
+func (s set) difference(t set) set {
+	for n := range s {
+		s[n] &^= t[n]
+	}
+	return s
+}
+
+// contains сообщает, входит ли в множество элемент с порядковым номером n
+func (s set) contains(n int64) bool {
+	return n >= 0 && n <= maxSetOrdinal && s[n/64]&(1<<(n%64)) != 0
+}
+
+// subsetOf сообщает, является ли s подмножеством t
+func (s set) subsetOf(t set) bool {
+	for n := range s {
+		if s[n]&^t[n] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// members возвращает порядковые номера элементов по возрастанию
+func (s set) members() []int64 {
+	var result []int64
+	for n := int64(0); n <= maxSetOrdinal; n++ {
+		if s.contains(n) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// format выводит элементы по возрастанию, объединяя три и более подряд идущих в диапазон
+func (s set) format(element func(int64) string) string {
+	members := s.members()
+	var parts []string
+	for n := 0; n < len(members); {
+		end := n
+		for end+1 < len(members) && members[end+1] == members[end]+1 {
+			end++
+		}
+		if end-n >= 2 {
+			parts = append(parts, element(members[n])+".."+element(members[end]))
+		} else {
+			for k := n; k <= end; k++ {
+				parts = append(parts, element(members[k]))
+			}
+		}
+		n = end + 1
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// pointer - адрес динамической переменной; адреса выдаются подряд с 1 и не используются повторно
+type pointer int
+
+// null - указатель NIL
+const null pointer = 0
+
+func (p pointer) String() string {
+	if p == null {
+		return "NIL"
+	}
+	return "@" + strconv.Itoa(int(p))
+}
+
+// heapBlock - динамическая переменная, созданная New
+type heapBlock struct {
+	value     any // *T
+	allocated string
+	disposed  string
+	freed     bool
+}
+
+var heap []*heapBlock
+
+// allocate выполняет New: создает динамическую переменную с начальным значением value
+func allocate[T any](value T, where string) pointer {
+	heap = append(heap, &heapBlock{value: &value, allocated: where})
+	return pointer(len(heap))
+}
+
+// deref возвращает динамическую переменную p^; name - запись p^ в программе
+func deref[T any](p pointer, name, where string) *T {
+	return block(p, name, where).value.(*T)
+}
+
+func block(p pointer, name, where string) *heapBlock {
+	if p == null {
+		raise(EAccessViolation, where, "разыменование указателя NIL (%s)", name)
+	}
+	if p < 0 || int(p) > len(heap) {
+		raise(EAccessViolation, where, "недопустимый адрес %s (%s)", p, name)
+	}
+	b := heap[p-1]
+	if b.freed {
+		raise(EAccessViolation, where, "обращение к освобожденной памяти %s (выделена: %s, освобождена: %s) (%s)", p, b.allocated, b.disposed, name)
+	}
+	return b
+}
+
+// dispose выполняет Dispose(p); procedure - имя процедуры в программе, name - запись p
+func dispose(p pointer, procedure, name, where string) {
+	if p == null {
+		raise(EInvalidPointer, where, "%s: освобождение указателя NIL (%s)", procedure, name)
+	}
+	if p > 0 && int(p) <= len(heap) && heap[p-1].freed {
+		b := heap[p-1]
+		raise(EInvalidPointer, where, "%s: повторное освобождение памяти %s (выделена: %s, освобождена: %s) (%s)", procedure, p, b.allocated, b.disposed, name)
+	}
+	if p < 0 || int(p) > len(heap) {
+		raise(EAccessViolation, where, "%s: недопустимый адрес %s (%s)", procedure, p, name)
+	}
+	b := heap[p-1]
+	b.freed, b.disposed, b.value = true, where, nil
+}
+
+// fileMode - состояние файловой переменной
+type fileMode int
+
+const (
+	fileClosed fileMode = iota
+	fileReading
+	fileWriting
+)
+
+// textFile - файловая переменная типа TEXT; стандартные ввод и вывод - тоже textFile
+type textFile struct {
+	name   string
+	mode   fileMode
+	reader *textReader
+	writer io.Writer
+	data   *bytes.Buffer // содержимое файла, открытого для записи; сохраняется при закрытии
+}
+
+func (f *textFile) String() string {
+	if f.name == "" {
+		return "TEXT"
+	}
+	return "TEXT(" + formatString(f.name) + ")"
+}
+
+var (
+	stdout = bufio.NewWriter(os.Stdout)
+	input  = &textFile{name: "INPUT", mode: fileReading, reader: newTextReader(os.Stdin)}
+	output = &textFile{mode: fileWriting, writer: stdout}
+	open   []*textFile // открытые файлы в порядке открытия
+)
+
+// writing проверяет, что файл открыт для записи; как и интерпретатор, Write проверяет
+// это до вычисления параметров
+func (f *textFile) writing(procedure, where string) {
+	if f.mode != fileWriting {
+		raise(EInOutError, where, "%s: файл %s не открыт для записи", procedure, f)
+	}
+}
+
+// write выполняет Write в файл: parts - отформатированные параметры
+func (f *textFile) write(procedure, where string, parts ...string) {
+	if _, err := io.WriteString(f.writer, strings.Join(parts, "")); err != nil {
+		raise(EInOutError, where, "%s: %v", procedure, err)
+	}
+}
+
+// pad дополняет текст пробелами слева до ширины поля width
+func pad(text string, width int64) string {
+	if n := int(width) - utf8.RuneCountInString(text); n > 0 {
+		return strings.Repeat(" ", n) + text
+	}
+	return text
+}
+
+// fixed выводит вещественное число с precision знаками после точки
+func fixed(x float64, precision int64) string {
+	return strconv.FormatFloat(x, 'f', int(precision), 64)
+}
+
+// formatParameter проверяет ширину поля или точность Write, заданную выражением
+func formatParameter(n int64, procedure, what, where string) int64 {
+	if n < 0 {
+		raise(nil, where, "%s: %s должна быть неотрицательным целым, получено %d", procedure, what, n)
+	}
+	return n
+}
+
+// reading возвращает читатель файла, открытого для чтения
+func (f *textFile) reading(procedure, where string) *textReader {
+	if f == input {
+		// Приглашение к вводу должно появиться до чтения
+		stdout.Flush()
+	}
+	if f.mode != fileReading {
+		raise(EInOutError, where, "%s: файл %s не открыт для чтения", procedure, f)
+	}
+	return f.reader
+}
+
+// readChar, readString, readInt и readReal выполняют Read переменной соответствующего типа
+func (f *textFile) readChar(procedure, where string) rune {
+	r, ok := f.reading(procedure, where).readChar()
+	if !ok {
+		raise(EInOutError, where, "%s: чтение за концом файла %s", procedure, f.name)
+	}
+	return r
+}
+
+func (f *textFile) readString(procedure, where string) string {
+	return f.reading(procedure, where).readLine()
+}
+
+func (f *textFile) readInt(procedure, where string) int64 {
+	word := f.word(procedure, where)
+	n, err := strconv.ParseInt(word, 10, 64)
+	if err != nil {
+		raise(EInOutError, where, "%s: ожидалось целое число, прочитано %s", procedure, formatString(word))
+	}
+	return n
+}
+
+func (f *textFile) readReal(procedure, where string) float64 {
+	word := f.word(procedure, where)
+	if n, err := strconv.ParseInt(word, 10, 64); err == nil {
+		return float64(n)
+	}
+	x, err := strconv.ParseFloat(word, 64)
+	if err != nil || math.IsInf(x, 0) || math.IsNaN(x) {
+		raise(EInOutError, where, "%s: ожидалось число, прочитано %s", procedure, formatString(word))
+	}
+	return x
+}
+
+func (f *textFile) word(procedure, where string) string {
+	word := f.reading(procedure, where).word()
+	if word == "" {
+		raise(EInOutError, where, "%s: чтение за концом файла %s", procedure, f.name)
+	}
+	return word
+}
+
+// readLn завершает ReadLn: пропускает остаток строки
+func (f *textFile) readLn(procedure, where string) {
+	f.reading(procedure, where).skipLine()
+}
+
+// eof и eoln выполняют Eof и Eoln
+func (f *textFile) eof(name, where string) bool {
+	return f.reading(name, where).eof()
+}
+
+func (f *textFile) eoln(name, where string) bool {
+	return f.reading(name, where).eoln()
+}
+
+// assign выполняет Assign
+func (f *textFile) assign(name, where string) {
+	if f.mode != fileClosed {
+		raise(EInOutError, where, "Assign: файл %s открыт", f)
+	}
+	f.name = name
+}
+
+// reset, rewrite и append выполняют Reset, Rewrite и Append; variable - запись
+// файловой переменной в программе
+func (f *textFile) reset(procedure, variable, where string) {
+	filename := f.reopen(procedure, variable, where)
+	data, err := readFile(filename)
+	if err != nil {
+		raise(EInOutError, where, "Reset: %v", describeFileError(f.name, err))
+	}
+	f.reader = newTextReader(bytes.NewReader(data))
+	f.mode = fileReading
+	open = append(open, f)
+}
+
+func (f *textFile) rewrite(procedure, variable, where string) {
+	filename := f.reopen(procedure, variable, where)
+	// Файл создается или очищается сразу, а записанное сохраняется при закрытии
+	if err := writeFile(filename, nil); err != nil {
+		raise(EInOutError, where, "Rewrite: %v", describeFileError(f.name, err))
+	}
+	f.data = &bytes.Buffer{}
+	f.writer, f.mode = f.data, fileWriting
+	open = append(open, f)
+}
+
+func (f *textFile) append(procedure, variable, where string) {
+	filename := f.reopen(procedure, variable, where)
+	// Несуществующий файл создается, как при Rewrite
+	data, err := readFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = writeFile(filename, nil)
+	}
+	if err != nil {
+		raise(EInOutError, where, "Append: %v", describeFileError(f.name, err))
+	}
+	f.data = bytes.NewBuffer(data)
+	f.writer, f.mode = f.data, fileWriting
+	open = append(open, f)
+}
+
+// reopen проверяет имя файла перед открытием и закрывает открытый файл
+func (f *textFile) reopen(procedure, variable, where string) string {
+	if f.name == "" {
+		raise(EInOutError, where, "%s: файлу %s не назначено имя процедурой Assign", procedure, variable)
+	}
+	filename, err := filePath(f.name)
+	if err != nil {
+		raise(EInOutError, where, "%s: %v", procedure, err)
+	}
+	if f.mode != fileClosed {
+		if err := f.closeFile(); err != nil {
+			raise(EInOutError, where, "%s: %v", procedure, err)
+		}
+	}
+	return filename
+}
+
+// close выполняет Close
+func (f *textFile) close(where string) {
+	if f.mode == fileClosed {
+		raise(EInOutError, where, "Close: файл %s не открыт", f)
+	}
+	if err := f.closeFile(); err != nil {
+		raise(EInOutError, where, "Close: %v", err)
+	}
+}
+
+// closeFile закрывает файл; содержимое файла, открытого для записи, сохраняется на диске
+func (f *textFile) closeFile() error {
+	for n, file := range open {
+		if file == f {
+			open = append(open[:n], open[n+1:]...)
+			break
+		}
+	}
+	mode := f.mode
+	f.mode, f.reader = fileClosed, nil
+	if mode != fileWriting {
+		return nil
+	}
+	data := f.data.Bytes()
+	f.data, f.writer = nil, nil
+	filename, err := filePath(f.name)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filename, data); err != nil {
+		return describeFileError(f.name, err)
+	}
+	return nil
+}
+
+// closeFiles закрывает файлы, оставшиеся открытыми к концу программы
+func closeFiles() {
+	for len(open) > 0 {
+		f := open[0]
+		if err := f.closeFile(); err != nil {
+			raise(EInOutError, "", "закрытие файла %s: %v", f, err)
+		}
+	}
+}
+
+// flushFiles закрывает открытые файлы после ошибки выполнения: записанное до ошибки
+// сохраняется, а ошибки закрытия не заменяют ошибку программы
+func flushFiles() {
+	for len(open) > 0 {
+		open[0].closeFile()
+	}
+}
+
+// filePath приводит имя внешнего файла к пути внутри текущего каталога
+func filePath(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if !fs.ValidPath(clean) || clean == "." {
+		return "", fmt.Errorf("недопустимое имя файла %s: путь должен быть относительным и не выходить за пределы корневого каталога", formatString(name))
+	}
+	return clean, nil
+}
+
+// readFile и writeFile читают и записывают файл текущего каталога, не выходя за его
+// пределы по символическим ссылкам
+func readFile(filename string) ([]byte, error) {
+	resolved, err := resolveLinks(".", filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(resolved)
+}
+
+func writeFile(filename string, data []byte) error {
+	resolved, err := resolveLinks(".", filename)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(resolved, data, 0o644)
+}
+
+// resolveLinks раскрывает символические ссылки в пути name внутри каталога root и
+// проверяет, что файл остается внутри каталога. Несуществующий файл допустим, чтобы его
+// можно было создать, а ссылка на несуществующий файл - нет.
+func resolveLinks(root, name string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(root, filepath.FromSlash(name))
+	resolved, err := filepath.EvalSymlinks(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Lstat(filename); err == nil {
+			return "", fs.ErrPermission
+		}
+		dir, err := filepath.EvalSymlinks(filepath.Dir(filename))
+		if err != nil {
+			return "", err
+		}
+		resolved = filepath.Join(dir, filepath.Base(filename))
+	} else if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fs.ErrPermission
+	}
+	return resolved, nil
+}
+
+// describeFileError переводит ошибку файловой системы в сообщение для программы
+func describeFileError(name string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("файл %s не найден", formatString(name))
+	case errors.Is(err, fs.ErrInvalid), errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("нет доступа к файлу %s", formatString(name))
+	default:
+		return err
+	}
+}
+
+// textReader читает текст по символам, строкам и числам, как процедуры Read и ReadLn
+type textReader struct {
+	r *bufio.Reader
+}
+
+func newTextReader(r io.Reader) *textReader {
+	return &textReader{r: bufio.NewReader(r)}
+}
+
+func (t *textReader) peek() (rune, bool) {
+	r, _, err := t.r.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	t.r.UnreadRune()
+	return r, true
+}
+
+func (t *textReader) eof() bool {
+	_, ok := t.peek()
+	return !ok
+}
+
+func (t *textReader) eoln() bool {
+	r, ok := t.peek()
+	return !ok || r == '\n' || r == '\r'
+}
+
+// readChar читает символ; в конце строки возвращается пробел, а чтение переходит на следующую строку
+func (t *textReader) readChar() (rune, bool) {
+	if t.eof() {
+		return 0, false
+	}
+	if t.eoln() {
+		t.skipLine()
+		return ' ', true
+	}
+	r, _, _ := t.r.ReadRune()
+	return r, true
+}
+
+func (t *textReader) readLine() string {
+	var line strings.Builder
+	for !t.eoln() {
+		r, _, _ := t.r.ReadRune()
+		line.WriteRune(r)
+	}
+	return line.String()
+}
+
+func (t *textReader) skipLine() {
+	t.readLine()
+	if r, ok := t.peek(); ok && r == '\r' {
+		t.r.ReadRune()
+	}
+	if r, ok := t.peek(); ok && r == '\n' {
+		t.r.ReadRune()
+	}
+}
+
+func (t *textReader) word() string {
+	for r, ok := t.peek(); ok && unicode.IsSpace(r); r, ok = t.peek() {
+		t.r.ReadRune()
+	}
+	var word strings.Builder
+	for r, ok := t.peek(); ok && !unicode.IsSpace(r); r, ok = t.peek() {
+		t.r.ReadRune()
+		word.WriteRune(r)
+	}
+	return word.String()
+}
+
+// assigned отмечает неописанные переменные программы, которым было присвоено значение:
+// как и в интерпретаторе, только они попадают в вывод значений
+var assigned = map[string]bool{}
+
+// variable - переменная программы в выводе значений
+type variable struct {
+	name, value string
+}
+
+// printVariables выводит значения переменных программы, упорядоченные по имени
+func printVariables(variables []variable) {
+	sort.Slice(variables, func(a, b int) bool { return variables[a].name < variables[b].name })
+	parts := make([]string, len(variables))
+	for n, v := range variables {
+		parts[n] = v.name + ": " + v.value
+	}
+	fmt.Fprintln(stdout, "{"+strings.Join(parts, ", ")+"}")
+}
+
+// finish завершает программу: выводит буферизованный вывод и сообщает о необработанном
+// исключении с кодом выхода 1 или завершает программу с кодом выхода Halt
+func finish() {
+	r := recover()
+	stdout.Flush()
+	if r == nil {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+		return
+	}
+	e, ok := r.(*pascalError)
+	if !ok {
+		panic(r)
+	}
+	flushFiles()
+	fmt.Fprintf(os.Stderr, "ошибка выполнения: %v\n", e)
+	os.Exit(1)
+}
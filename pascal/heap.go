@@ -0,0 +1,70 @@
+package main
+
+// HeapBlock представляет динамическую переменную, созданную процедурой New
+type HeapBlock struct {
+	Addr      int
+	Type      *Type
+	Value     Value
+	Allocated Position // позиция вызова New
+	Disposed  Position // позиция вызова Dispose
+	Freed     bool
+}
+
+// Heap представляет управляемую кучу интерпретатора. Адреса не используются повторно,
+// а освобожденные блоки остаются в куче с отметкой Freed, поэтому обращение к
+// освобожденной памяти и повторное освобождение всегда обнаруживаются.
+type Heap struct {
+	blocks []*HeapBlock // блок с адресом n хранится в blocks[n-1]
+}
+
+// Allocate создает динамическую переменную типа t и возвращает указатель на нее
+func (h *Heap) Allocate(t *Type, pos Position) PointerValue {
+	block := &HeapBlock{Addr: len(h.blocks) + 1, Type: t, Value: zeroValue(t), Allocated: pos}
+	h.blocks = append(h.blocks, block)
+	return PointerValue{Elem: t, Addr: block.Addr}
+}
+
+// Block возвращает динамическую переменную, на которую ссылается указатель
+func (h *Heap) Block(p PointerValue) (*HeapBlock, error) {
+	if p.Addr == 0 {
+		return nil, classifiedErrorf(accessViolationClass, "разыменование указателя NIL")
+	}
+	if p.Addr < 0 || p.Addr > len(h.blocks) {
+		return nil, classifiedErrorf(accessViolationClass, "недопустимый адрес %s", p)
+	}
+	block := h.blocks[p.Addr-1]
+	if block.Freed {
+		return nil, classifiedErrorf(accessViolationClass, "обращение к освобожденной памяти %s (выделена: %s, освобождена: %s)", p, block.Allocated, block.Disposed)
+	}
+	return block, nil
+}
+
+// Dispose освобождает динамическую переменную
+func (h *Heap) Dispose(p PointerValue, pos Position) error {
+	if p.Addr == 0 {
+		return classifiedErrorf(invalidPointerClass, "освобождение указателя NIL")
+	}
+	if p.Addr > 0 && p.Addr <= len(h.blocks) && h.blocks[p.Addr-1].Freed {
+		block := h.blocks[p.Addr-1]
+		return classifiedErrorf(invalidPointerClass, "повторное освобождение памяти %s (выделена: %s, освобождена: %s)", p, block.Allocated, block.Disposed)
+	}
+	block, err := h.Block(p)
+	if err != nil {
+		return err
+	}
+	block.Freed = true
+	block.Disposed = pos
+	block.Value = nil
+	return nil
+}
+
+// Leaks возвращает неосвобожденные блоки в порядке выделения
+func (h *Heap) Leaks() []*HeapBlock {
+	var leaks []*HeapBlock
+	for _, block := range h.blocks {
+		if !block.Freed {
+			leaks = append(leaks, block)
+		}
+	}
+	return leaks
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+const linkedListProgram = `PROGRAM List;
+TYPE
+  PNode = ^TNode;
+  TNode = RECORD
+    value: INTEGER;
+    next: PNode
+  END;
+VAR
+  head, node: PNode;
+  i, sum: INTEGER;
+BEGIN
+  head := NIL;
+  FOR i := 1 TO 4 DO
+  BEGIN
+    New(node);
+    node^.value := i * 10;
+    node^.next := head;
+    head := node
+  END;
+  sum := 0;
+  node := head;
+  WHILE node <> NIL DO
+  BEGIN
+    sum := sum + node^.value;
+    node := node^.next
+  END;
+  WHILE head <> NIL DO
+  BEGIN
+    node := head;
+    head := head^.next;
+    Dispose(node)
+  END
+END.`
+
+// TestHeap тестирует выделение, освобождение и поиск блоков управляемой кучи
+func TestHeap(t *testing.T) {
+	var heap Heap
+	p := heap.Allocate(integerType, Position{Line: 1, Column: 1})
+	q := heap.Allocate(integerType, Position{Line: 2, Column: 1})
+	if p.Addr == q.Addr || p.Addr == 0 {
+		t.Fatalf("Ожидались различные ненулевые адреса, получено %v и %v", p, q)
+	}
+	block, err := heap.Block(p)
+	if err != nil || block.Value != IntegerValue(0) {
+		t.Fatalf("Ожидался блок со значением 0, получено %v, %v", block, err)
+	}
+	if err := heap.Dispose(p, Position{Line: 3, Column: 1}); err != nil {
+		t.Fatalf("Неожиданная ошибка освобождения: %v", err)
+	}
+	if leaks := heap.Leaks(); len(leaks) != 1 || leaks[0].Addr != q.Addr {
+		t.Errorf("Ожидалась утечка блока %v, получено %v", q, leaks)
+	}
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{func() error { _, err := heap.Block(PointerValue{}); return err }(), "разыменование указателя NIL"},
+		{func() error { _, err := heap.Block(PointerValue{Addr: 99}); return err }(), "недопустимый адрес @99"},
+		{func() error { _, err := heap.Block(p); return err }(), "обращение к освобожденной памяти @1 (выделена: строка 1, столбец 1, освобождена: строка 3, столбец 1)"},
+		{heap.Dispose(p, Position{Line: 4, Column: 1}), "повторное освобождение памяти @1"},
+		{heap.Dispose(PointerValue{}, Position{}), "освобождение указателя NIL"},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || !strings.Contains(tt.err.Error(), tt.want) {
+			t.Errorf("Ожидалась ошибка %q, получено %v", tt.want, tt.err)
+		}
+	}
+}
+
+// TestLinkedList тестирует построение и освобождение связного списка
+func TestLinkedList(t *testing.T) {
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, linkedListProgram)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		values := interpreter.Values()
+		if values["sum"] != IntegerValue(100) {
+			t.Errorf("Ожидалось sum = 100, получено %v", values["sum"])
+		}
+		if values["head"].String() != "NIL" {
+			t.Errorf("Ожидалось head = NIL, получено %v", values["head"])
+		}
+		if leaks := interpreter.Leaks(); len(leaks) != 0 {
+			t.Errorf("Ожидалось отсутствие утечек, получено %d", len(leaks))
+		}
+	}
+}
+
+// TestBinaryTree тестирует дерево поиска с вложенными указателями в записях
+func TestBinaryTree(t *testing.T) {
+	code := `TYPE
+  PTree = ^TTree;
+  TTree = RECORD key: INTEGER; left, right: PTree END;
+VAR root, node, parent: PTree; k, n, depth, maxDepth: INTEGER; keys: SET OF 0..9;
+BEGIN
+  root := NIL;
+  FOR k := 0 TO 6 DO
+  BEGIN
+    n := (k * 5 + 3) MOD 7;
+    New(node);
+    node^.key := n;
+    IF root = NIL THEN root := node
+    ELSE
+    BEGIN
+      parent := root;
+      depth := 1;
+      REPEAT
+        depth := depth + 1;
+        IF n < parent^.key THEN
+          IF parent^.left = NIL THEN BEGIN parent^.left := node; parent := NIL END
+          ELSE parent := parent^.left
+        ELSE
+          IF parent^.right = NIL THEN BEGIN parent^.right := node; parent := NIL END
+          ELSE parent := parent^.right
+      UNTIL parent = NIL;
+      IF depth > maxDepth THEN maxDepth := depth
+    END
+  END;
+  keys := [];
+  node := root;
+  WHILE node <> NIL DO
+  BEGIN
+    keys := keys + [node^.key];
+    node := node^.left
+  END
+END.`
+	interpreter, err := runChecked(t, code)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	values := interpreter.Values()
+	if values["keys"].String() != "[0, 1, 3]" {
+		t.Errorf("Ожидалась левая ветвь [0, 1, 3], получено %v", values["keys"])
+	}
+	if values["maxDepth"] != IntegerValue(4) {
+		t.Errorf("Ожидалась глубина 4, получено %v", values["maxDepth"])
+	}
+	if leaks := interpreter.Leaks(); len(leaks) != 7 {
+		t.Errorf("Ожидалось 7 неосвобожденных узлов, получено %d", len(leaks))
+	}
+}
+
+// TestPointerRuntimeErrors тестирует обнаружение ошибок работы с памятью
+func TestPointerRuntimeErrors(t *testing.T) {
+	decls := "TYPE PInt = ^INTEGER; TRec = RECORD next: ^TRec END; VAR p, q: PInt; r: ^TRec;\n"
+	tests := []struct {
+		code string
+		want string
+		pos  Position
+	}{
+		{"BEGIN p^ := 1 END.", "разыменование указателя NIL (p^)", Position{Line: 2, Column: 8}},
+		{"BEGIN New(r); x := r^.next^.next END.", "разыменование указателя NIL (r^.next^)", Position{Line: 2, Column: 27}},
+		{"BEGIN New(p); q := p; Dispose(p); x := q^ END.", "обращение к освобожденной памяти @1 (выделена: строка 2, столбец 7, освобождена: строка 2, столбец 23)", Position{Line: 2, Column: 41}},
+		{"BEGIN New(p); q := p; Dispose(p); Dispose(q) END.", "Dispose: повторное освобождение памяти @1", Position{Line: 2, Column: 35}},
+		{"BEGIN Dispose(p) END.", "Dispose: освобождение указателя NIL (p)", Position{Line: 2, Column: 7}},
+	}
+	for _, tt := range tests {
+		for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+			_, err := run(t, decls+tt.code)
+			runtimeErr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("%q: ожидалась ошибка выполнения, получено %v", tt.code, err)
+			}
+			if !strings.Contains(runtimeErr.Message, tt.want) {
+				t.Errorf("%q: ожидалась ошибка %q, получено %q", tt.code, tt.want, runtimeErr.Message)
+			}
+			if runtimeErr.Pos != tt.pos {
+				t.Errorf("%q: ожидалась позиция %v, получено %v", tt.code, tt.pos, runtimeErr.Pos)
+			}
+		}
+	}
+}
+
+// TestInterpreterPointerErrors тестирует ошибки указателей при выполнении без семантического анализа
+func TestInterpreterPointerErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN x := 1; y := x^ END.", "операция ^ неприменима к типу INTEGER"},
+		{"BEGIN New(p) END.", "неизвестная переменная p"},
+		{"VAR n: INTEGER; BEGIN New(n) END.", "New: переменная n должна быть указателем"},
+		{"BEGIN Release(p) END.", "неизвестная процедура Release"},
+		{"VAR p: ^INTEGER; BEGIN New(p, 1) END.", "процедура New ожидает 1 аргумент(ов), получено 2"},
+		{"VAR p: ^INTEGER; BEGIN x := p < p END.", "операция < неприменима к указателям"},
+		{"VAR r: RECORD a: INTEGER END; BEGIN r.b := 1 END.", "у записи RECORD a: INTEGER END нет поля b"},
+		{"BEGIN x := 1; x.a := 2 END.", "x не является записью"},
+		{"TYPE P = ^Missing; BEGIN END.", "неизвестный тип Missing"},
+	}
+	for _, tt := range tests {
+		_, err := interpretCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestCheckerPointersAndRecords тестирует семантические ошибки указателей и записей
+func TestCheckerPointersAndRecords(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"TYPE P = ^Missing; BEGIN END.", "неизвестный тип Missing"},
+		{"VAR n: INTEGER; BEGIN n := n^ END.", "операция ^ неприменима к типу INTEGER"},
+		{"CONST none = NIL; BEGIN x := none^ END.", "разыменование указателя NIL"},
+		{"VAR p: ^INTEGER; BEGIN p^ := TRUE END.", "нельзя присвоить BOOLEAN переменной p^ типа INTEGER"},
+		{"VAR p: ^INTEGER; q: ^BOOLEAN; BEGIN p := q END.", "нельзя присвоить ^BOOLEAN переменной p типа ^INTEGER"},
+		{"VAR p: ^INTEGER; BEGIN p := 0 END.", "нельзя присвоить INTEGER переменной p"},
+		{"VAR p: ^INTEGER; b: BOOLEAN; BEGIN b := p < NIL END.", "операция < неприменима к указателям"},
+		{"VAR p: ^INTEGER; n: INTEGER; BEGIN n := p + 1 END.", "арифметическая операция неприменима к типу ^INTEGER"},
+		{"TYPE TRec = RECORD a: INTEGER END; VAR r, s: TRec; BEGIN x := r = s END.", "операция = неприменима к записям"},
+		{"TYPE R = RECORD a: INTEGER; a: REAL END; BEGIN END.", "повторное описание поля a"},
+		{"VAR r: RECORD a: 1..5 END; BEGIN r.a := 7 END.", "значение 7 вне диапазона 1..5 переменной r.a"},
+		{"VAR r: RECORD a: INTEGER END; BEGIN r.b := 1 END.", "нет поля b"},
+		{"VAR n: INTEGER; BEGIN n.a := 1 END.", "n не является записью"},
+		{"VAR n: INTEGER; BEGIN New(n) END.", "New: переменная n должна быть указателем, получено INTEGER"},
+		{"BEGIN New(p) END.", "New: переменная p не описана"},
+		{"CONST c = 1; BEGIN Dispose(c) END.", "Dispose: c не является переменной"},
+		{"VAR p: ^INTEGER; BEGIN Dispose(p^ + 1) END.", "должен быть переменной"},
+		{"VAR p: ^INTEGER; BEGIN New() END.", "процедура New ожидает 1 аргумент(ов), получено 0"},
+		{"BEGIN Print(1) END.", "неизвестная процедура Print"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestRecordCopySemantics тестирует, что присваивание записи копирует ее значение
+func TestRecordCopySemantics(t *testing.T) {
+	code := `TYPE
+  TPoint = RECORD x, y: INTEGER END;
+  TLine = RECORD a, b: TPoint END;
+VAR p, q: TPoint; line: TLine; ptr: ^TPoint;
+BEGIN
+  p.x := 1; p.y := 2;
+  q := p;
+  q.x := 10;
+  line.a := p;
+  line.b := q;
+  line.b.y := 20;
+  New(ptr);
+  ptr^ := line.b;
+  ptr^.x := 100;
+  total := p.x + q.x + line.b.y + ptr^.x + ptr^.y
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		values := interpreter.Values()
+		want := map[string]string{
+			"p":     "(x: 1; y: 2)",
+			"q":     "(x: 10; y: 2)",
+			"line":  "(a: (x: 1; y: 2); b: (x: 10; y: 20))",
+			"ptr":   "@1",
+			"total": "151",
+		}
+		for name, value := range want {
+			if values[name].String() != value {
+				t.Errorf("Ожидалось %s = %s, получено %v", name, value, values[name])
+			}
+		}
+	}
+}
+
+// TestParsePointersAndRecords тестирует разбор указательных типов, записей и обращений к ним
+func TestParsePointersAndRecords(t *testing.T) {
+	program := parseCode(t, `TYPE P = ^T; T = RECORD a, b: INTEGER; next: P END;
+BEGIN p^.next^.a := NIL; New(p) END.`)
+	if got := program.Declarations.Types[0].Type.String(); got != "^T" {
+		t.Errorf("Ожидался тип ^T, получено %s", got)
+	}
+	if got := program.Declarations.Types[1].Type.String(); got != "RECORD a: INTEGER; b: INTEGER; next: P END" {
+		t.Errorf("Неожиданная запись: %s", got)
+	}
+	if got := program.Statements[0].String(); got != "Assignment(p^.next^.a := Literal(NIL))" {
+		t.Errorf("Ожидалось присваивание p^.next^.a := NIL, получено %s", got)
+	}
+	if got := program.Statements[1].String(); got != "CallStatement(New(Identifier(p)))" {
+		t.Errorf("Ожидался вызов New(p), получено %s", got)
+	}
+
+	for _, code := range []string{
+		"TYPE P = ^; BEGIN END.",
+		"TYPE R = RECORD a INTEGER END; BEGIN END.",
+		"TYPE R = RECORD a: INTEGER; BEGIN END.",
+		"BEGIN p^. := 1 END.",
+		"BEGIN p^ 1 END.",
+	} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", code, err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestReportLeaks тестирует отчет о неосвобожденной памяти
+func TestReportLeaks(t *testing.T) {
+	var out bytes.Buffer
+	reportLeaks(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("Ожидался пустой отчет, получено %q", out.String())
+	}
+
+	var heap Heap
+	p := heap.Allocate(integerType, Position{Line: 3, Column: 5})
+	heap.Allocate(booleanType, Position{Line: 4, Column: 5})
+	if err := heap.Dispose(p, Position{Line: 5, Column: 1}); err != nil {
+		t.Fatal(err)
+	}
+	reportLeaks(&out, heap.Leaks())
+	want := "утечка памяти: не освобождено блоков: 1\n  @2: BOOLEAN = FALSE (выделен: строка 4, столбец 5)\n"
+	if out.String() != want {
+		t.Errorf("Ожидался отчет %q, получено %q", want, out.String())
+	}
+}
+
+// TestMainLeaksFlag тестирует флаг -leaks командной строки
+func TestMainLeaksFlag(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "leaks*.pas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.WriteString("VAR p: ^INTEGER; BEGIN New(p); p^ := 7 END."); err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = w, w
+
+	os.Args = []string{"pascal", "-leaks", tmpfile.Name()}
+	exitCode := mainWithExitCode()
+	os.Args = []string{"pascal", "-unknown", tmpfile.Name()}
+	badFlagCode := mainWithExitCode()
+	w.Close()
+	var out bytes.Buffer
+	out.ReadFrom(r)
+
+	if exitCode != 0 {
+		t.Errorf("Ожидался код выхода 0, получено %d", exitCode)
+	}
+	if badFlagCode != 1 {
+		t.Errorf("Ожидался код выхода 1 для неизвестного флага, получено %d", badFlagCode)
+	}
+	for _, want := range []string{"{p: @1}", "не освобождено блоков: 1", "@1: INTEGER = 7 (выделен: строка 1, столбец 24)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Ожидался вывод %q, получено %q", want, out.String())
+		}
+	}
+}
@@ -1,24 +1,533 @@
 package main
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync/atomic"
 )
 
+// RuntimeError представляет ошибку времени выполнения с позицией в исходном тексте.
+// Error возвращает только текст ошибки, позицию добавляет вызывающая сторона.
+// Ошибка выполнения возбуждает исключение, которое программа может перехватить в TRY ... EXCEPT.
+type RuntimeError struct {
+	Message string
+	Pos     Position
+	Class   *Type        // класс исключения; nil для ошибок без особого класса (Exception)
+	Trace   []StackFrame // стек вызовов от места возбуждения; пуст, если исключение не покидало подпрограмм
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+// runtimeError создает ошибку времени выполнения в позиции pos
+func runtimeError(pos Position, format string, args ...interface{}) *RuntimeError {
+	return &RuntimeError{Message: fmt.Sprintf(format, args...), Pos: pos}
+}
+
+// Variable представляет переменную или константу во время выполнения
+type Variable struct {
+	Name  string // написание имени из описания или первого присваивания
+	Type  *Type  // nil для неописанной переменной, тип которой задает присваивание
+	Value Value
+	Const bool
+
+	ref *Value // значение переменной, переданной в VAR-параметр; nil для собственного значения
+}
+
+// slot возвращает место хранения значения переменной
+func (v *Variable) slot() *Value {
+	if v.ref != nil {
+		return v.ref
+	}
+	return &v.Value
+}
+
 // Interpreter представляет интерпретатор Pascal
 type Interpreter struct {
-	variables map[string]float64
+	// Ключи variables и types - имена в нижнем регистре: регистр букв в именах не различается
+	variables map[string]*Variable
+	types     map[string]*Type
+	resolved  map[TypeSpec]*Type // типы записей и классов по их записям (typeResolver.resolved)
+	heap      Heap               // динамические переменные, созданные New
+
+	input  *textReader     // стандартный ввод для Read, ReadLn, Eof и Eoln
+	output *countingWriter // стандартный вывод для Write и WriteLn
+	files  FileSystem      // внешние файлы для файловых переменных
+	open   []*FileValue    // открытые файлы в порядке открытия
+
+	globals     *Frame           // глобальная область видимости программы или инициализируемого модуля
+	frame       *Frame           // активация выполняемой подпрограммы; nil в теле программы
+	units       map[*Unit]*Frame // интерфейсы инициализированных модулей
+	unitGlobals []*Frame         // глобальные области видимости модулей в порядке инициализации
+	handling    []*RuntimeError  // исключения, обрабатываемые в EXCEPT, для RAISE без выражения
+
+	flow   flow        // незавершенная передача управления Break, Continue или Exit
+	loops  int         // число выполняемых циклов в текущей подпрограмме
+	active []Statement // выполняемые операторы от внешнего к внутреннему во всех активациях
+	memo   []memoEntry // вычисленные части выполняемых операторов для продолжения со снимка
+
+	indivisible  bool                 // выполняемый оператор неделим: его части не запоминаются
+	indivisibles map[*Assignment]bool // найденные неделимые и делимые присваивания
+
+	program  *Program      // выполняемая программа
+	pending  *Continuation // продолжение со снимка (Options.Resume), которое еще не подготовлено
+	resume   *resumption   // продолжение со снимка, путь которого еще не пройден
+	restored *State        // состояние, с которым сверяется первый оператор после продолжения
+
+	profile  *Profile // счетчики выполнения и время подпрограмм; nil, если профиль не собирается
+	observer Observer // наблюдатель за выполнением; nil, если не задан
+	observed error    // последняя ошибка, о которой сообщено наблюдателю
+
+	bigint   bool      // режим длинных целых (Options.BigInt)
+	bindings []Binding // начальные значения глобальных переменных (Options.Bindings)
+
+	steps       int64       // число начатых операторов
+	budget      int64       // бюджет операторов (Options.Steps); 0 - без ограничения
+	checkpoint  int64       // номер оператора, перед которым нужна остановка; 0 - не нужна
+	interrupted atomic.Bool // запрошена остановка вызовом Suspend
+	halt        error       // ошибка, после которой не выполняется ни один оператор
+	exitCode    int         // код выхода, заданный Halt
 }
 
+// flow представляет передачу управления операторами Break, Continue и Exit. Она не является
+// ошибкой: оператор устанавливает i.flow, executeStatements прекращает выполнение операторов
+// блока, а сигнал принимает ближайший цикл (Break, Continue) или подпрограмма (Exit).
+type flow int
+
+const (
+	flowNormal flow = iota
+	flowBreak
+	flowContinue
+	flowExit
+)
+
+// Options задает окружение, в котором выполняется программа
+type Options struct {
+	Stdin  io.Reader  // стандартный ввод; по умолчанию os.Stdin
+	Stdout io.Writer  // стандартный вывод; по умолчанию os.Stdout
+	Files  FileSystem // внешние файлы; по умолчанию пустая файловая система в памяти
+
+	Profile  *Profile // профиль, в который записываются счетчики выполнения; nil - не собирать
+	Observer Observer // наблюдатель за выполнением, например трассировка присваиваний
+
+	// Bindings - начальные значения глобальных переменных, которые присваиваются после
+	// раздела описаний программы
+	Bindings []Binding
+
+	// BigInt включает режим длинных целых: результат целой операции, который не помещается
+	// в int64, становится BigIntValue вместо целочисленного переполнения
+	BigInt bool
+
+	// Steps - бюджет выполнения: после Steps операторов Interpret останавливается перед
+	// следующим и возвращает *SuspendedError с состоянием программы; 0 - без ограничения
+	Steps int64
+	// Resume задает продолжение выполнения, остановленного SuspendedError; nil - выполнение
+	// с начала. Бюджет Steps отсчитывается вместе с операторами, выполненными до остановки.
+	Resume *Continuation
+}
+
+// Observer получает события выполнения программы от интерпретатора, которому он задан
+// в Options. Встроив BaseObserver, можно реализовать только нужные методы.
+type Observer interface {
+	// OnStatement вызывается перед выполнением оператора
+	OnStatement(stmt Statement)
+	// OnAssign вызывается после присваивания с прежним и новым значением места присваивания;
+	// before равно nil, если неописанная переменная создана этим присваиванием
+	OnAssign(stmt *Assignment, before, after Value)
+	// OnCall вызывается при вызове подпрограммы после вычисления аргументов
+	OnCall(routine *Routine, pos Position)
+	// OnError вызывается один раз для каждой ошибки выполнения с оператором, в котором она
+	// возникла, в том числе для исключений, перехваченных затем в TRY. Для ошибки вне
+	// операторов, например в разделе описаний, stmt равен nil.
+	OnError(stmt Statement, err error)
+}
+
+// BaseObserver - наблюдатель, который пропускает все события
+type BaseObserver struct{}
+
+func (BaseObserver) OnStatement(Statement)              {}
+func (BaseObserver) OnAssign(*Assignment, Value, Value) {}
+func (BaseObserver) OnCall(*Routine, Position)          {}
+func (BaseObserver) OnError(Statement, error)           {}
+
 // NewInterpreter создает новый интерпретатор
 func NewInterpreter() *Interpreter {
-	return &Interpreter{
-		variables: make(map[string]float64),
+	return NewInterpreterWithOptions(Options{})
+}
+
+// NewInterpreterWithOptions создает интерпретатор с заданными вводом, выводом и файловой системой
+func NewInterpreterWithOptions(options Options) *Interpreter {
+	if options.Stdin == nil {
+		options.Stdin = os.Stdin
 	}
+	if options.Stdout == nil {
+		options.Stdout = os.Stdout
+	}
+	if options.Files == nil {
+		options.Files = NewOverlayFS(nil)
+	}
+	i := &Interpreter{
+		variables:    make(map[string]*Variable),
+		types:        make(map[string]*Type),
+		resolved:     make(map[TypeSpec]*Type),
+		indivisibles: make(map[*Assignment]bool),
+		input:        newTextReader(options.Stdin),
+		output:       &countingWriter{w: options.Stdout},
+		files:        options.Files,
+		units:        make(map[*Unit]*Frame),
+		profile:      options.Profile,
+		observer:     options.Observer,
+		bigint:       options.BigInt,
+		bindings:     options.Bindings,
+		budget:       options.Steps,
+		pending:      options.Resume,
+	}
+	i.checkpoint = i.nextCheckpoint()
+	i.globals = &Frame{variables: i.variables, types: i.types, routines: make(map[string]*Routine)}
+	return i
 }
 
 // Interpret выполняет программу
 func (i *Interpreter) Interpret(program *Program) error {
-	return i.executeStatements(program.Statements)
+	i.globals.Name = program.Name
+	i.program = program
+	if i.pending != nil {
+		c := i.pending
+		i.pending = nil
+		if err := i.prepareResume(c); err != nil {
+			return err
+		}
+	}
+	err := i.run(program)
+	if i.resume != nil && i.halt == nil {
+		// Записанные файлы не сохраняются: программа не дошла до состояния, в котором они записаны
+		i.halt = fmt.Errorf("снимок не соответствует программе: выполнение не дошло до оператора %s", i.resume.state.Pos)
+	}
+	var halted *haltError
+	if errors.As(i.halt, &halted) {
+		// Halt завершает программу без ошибки; записанные файлы сохраняются
+		i.exitCode = int(halted.code)
+		return i.closeFiles()
+	}
+	if i.halt != nil {
+		// Ошибка остановки могла быть обернута по пути через вызовы подпрограмм
+		return i.halt
+	}
+	if err != nil {
+		i.flushFiles()
+	}
+	if err != nil && i.observer != nil && err != i.observed {
+		i.observer.OnError(nil, err)
+	}
+	return err
+}
+
+// run выполняет модули, описания и тело программы
+func (i *Interpreter) run(program *Program) error {
+	uses, err := i.useUnits(program.Uses)
+	if err != nil {
+		return err
+	}
+	i.globals.Uses = uses
+	if err := i.declare(&program.Declarations); err != nil {
+		return err
+	}
+	if err := i.bind(); err != nil {
+		return err
+	}
+	if err := i.restoreScope(scopeGlobals, program.Name, i.variables); err != nil {
+		return err
+	}
+	if err := i.executeStatements(program.Statements); err != nil || i.resume != nil {
+		return err
+	}
+	// Exit в теле программы завершает ее
+	i.flow = flowNormal
+	// Файлы, не закрытые программой, закрываются с сохранением записанного
+	return i.closeFiles()
+}
+
+// declare выполняет раздел описаний: вычисляет константы, типы и создает переменные
+func (i *Interpreter) declare(declarations *Declarations) error {
+	for _, decl := range declarations.Consts {
+		value, err := i.evaluateExpression(decl.Value)
+		if err != nil {
+			return err
+		}
+		if err := i.define(decl.Name, decl.Pos, &Variable{Name: decl.Name, Value: value, Const: true}); err != nil {
+			return err
+		}
+	}
+	types := i.scope().types
+	resolver := &typeResolver{lookup: i.lookupType, evaluate: i.evaluateExpression, resolved: i.resolved}
+	for _, decl := range declarations.Types {
+		key := strings.ToLower(decl.Name)
+		if _, exists := types[key]; exists {
+			return runtimeError(decl.Pos, "повторное описание %s", decl.Name)
+		}
+		t, err := resolver.resolve(decl.Type)
+		if err != nil {
+			return runtimeError(decl.Pos, "%v", err)
+		}
+		if _, alias := decl.Type.(*NamedType); !alias {
+			t.Name = decl.Name
+		}
+		types[key] = t
+		if err := i.defineEnum(decl.Type, t); err != nil {
+			return err
+		}
+	}
+	if pos, err := resolver.finish(); err != nil {
+		return runtimeError(pos, "%v", err)
+	}
+	var spec TypeSpec
+	var t *Type
+	for _, decl := range declarations.Vars {
+		// Переменные из одного списка a, b: T имеют общую запись типа, которая вычисляется один раз
+		if decl.Type != spec {
+			var err error
+			spec = decl.Type
+			if t, err = resolver.resolve(spec); err != nil {
+				return runtimeError(decl.Pos, "%v", err)
+			}
+			if pos, err := resolver.finish(); err != nil {
+				return runtimeError(pos, "%v", err)
+			}
+			if err := i.defineEnum(spec, t); err != nil {
+				return err
+			}
+		}
+		if err := i.define(decl.Name, decl.Pos, &Variable{Name: decl.Name, Type: t, Value: zeroValue(t)}); err != nil {
+			return err
+		}
+	}
+	for _, decl := range declarations.Routines {
+		if decl.Body == nil {
+			// Заголовок из раздела INTERFACE: подпрограмма описывается в разделе IMPLEMENTATION
+			continue
+		}
+		if err := i.defineRoutine(decl, resolver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defineEnum описывает имена значений перечисления как константы
+func (i *Interpreter) defineEnum(spec TypeSpec, t *Type) error {
+	switch s := spec.(type) {
+	case *SetType:
+		return i.defineEnum(s.Elem, t.Elem)
+	case *RecordType:
+		for n, field := range s.Fields {
+			if err := i.defineEnum(field.Type, t.Fields[n].Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	enum, ok := spec.(*EnumType)
+	if !ok {
+		return nil
+	}
+	for n, name := range enum.Names {
+		value := EnumValue{Type: t, Ordinal: int64(n)}
+		if err := i.define(name, enum.Pos, &Variable{Name: name, Type: t, Value: value, Const: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// define добавляет описанную переменную или константу в текущую область видимости
+func (i *Interpreter) define(name string, pos Position, variable *Variable) error {
+	variables := i.scope().variables
+	key := strings.ToLower(name)
+	if _, exists := variables[key]; exists {
+		return runtimeError(pos, "повторное описание %s", name)
+	}
+	variables[key] = variable
+	return nil
+}
+
+// variable ищет переменную или константу по правилам видимости
+func (i *Interpreter) variable(key string) (*Variable, bool) {
+	variable := i.resolve(key).variable
+	return variable, variable != nil
+}
+
+// lookupType ищет тип, описанный в программе или в объемлющих подпрограммах
+func (i *Interpreter) lookupType(name string) *Type {
+	return i.resolve(name).typ
+}
+
+// routine ищет процедуру или функцию, описанную в программе или в объемлющих подпрограммах.
+// В теле функции ее имя обозначает результат, но вызов с аргументами остается вызовом.
+func (i *Interpreter) routine(key string) (*Routine, bool) {
+	if i.frame != nil && i.frame.isResult(key) {
+		return i.frame.Routine, true
+	}
+	routine := i.resolve(key).routine
+	return routine, routine != nil
+}
+
+// namedType ищет тип по имени среди описанных и стандартных типов
+func (i *Interpreter) namedType(name string) *Type {
+	key := strings.ToLower(name)
+	if t := i.lookupType(key); t != nil {
+		return t
+	}
+	return predeclaredTypes[key]
+}
+
+// assign присваивает значение переменной, создавая неописанную переменную при первом присваивании.
+// Если rangeCheck установлен, значение переменной диапазонного типа проверяется на выход за границы.
+func (i *Interpreter) assign(name string, pos Position, value Value, rangeCheck bool) error {
+	key := strings.ToLower(name)
+	variable, ok := i.variable(key)
+	if !ok {
+		if i.lookupType(key) != nil {
+			return runtimeError(pos, "%s - имя типа, а не переменной", name)
+		}
+		if _, isConst := predeclaredConstants[key]; isConst {
+			return runtimeError(pos, "присваивание константе %s", name)
+		}
+		if _, isRoutine := i.routine(key); isRoutine {
+			return runtimeError(pos, "%s - имя подпрограммы, а не переменной", name)
+		}
+		// Неописанная переменная создается в текущей области видимости
+		variable = &Variable{Name: name}
+		i.scope().variables[key] = variable
+	}
+	if variable.Const {
+		return runtimeError(pos, "присваивание константе %s", name)
+	}
+	return i.store(reference{name: variable.Name, typ: variable.Type, value: variable.slot()}, pos, value, rangeCheck)
+}
+
+// reference представляет место хранения значения: переменную, поле записи или динамическую переменную
+type reference struct {
+	name  string // запись в исходном тексте для сообщений, например p^.next
+	typ   *Type  // nil для неописанной переменной, тип которой задает присваивание
+	value *Value
+}
+
+// store записывает значение по ссылке, приводя его к типу места хранения
+func (i *Interpreter) store(ref reference, pos Position, value Value, rangeCheck bool) error {
+	if ref.typ != nil {
+		converted, err := convertValue(ref.typ, value)
+		if err != nil {
+			return runtimeError(pos, "%v", err)
+		}
+		value = converted
+		if rangeCheck && !inRange(ref.typ, value) {
+			return raiseError(rangeErrorClass, pos, "нарушение диапазона: значение %s переменной %s вне диапазона %s",
+				value, ref.name, ref.typ.Range())
+		}
+	}
+	*ref.value = copyValue(value)
+	return nil
+}
+
+// locate находит место хранения, обозначенное переменной, полем записи или p^
+func (i *Interpreter) locate(expr Expression) (reference, error) {
+	switch e := expr.(type) {
+	case *Identifier:
+		variable, ok := i.variable(strings.ToLower(e.Name))
+		if !ok {
+			return reference{}, runtimeError(e.Pos, "неизвестная переменная %s", e.Name)
+		}
+		if variable.Const {
+			return reference{}, runtimeError(e.Pos, "присваивание константе %s", e.Name)
+		}
+		return reference{name: variable.Name, typ: variable.Type, value: variable.slot()}, nil
+	case *Dereference:
+		block, err := i.dereference(e)
+		if err != nil {
+			return reference{}, err
+		}
+		return reference{name: designatorName(e), typ: block.Type, value: &block.Value}, nil
+	case *FieldAccess:
+		record, n, err := i.field(e)
+		if err != nil {
+			return reference{}, err
+		}
+		return reference{name: designatorName(e), typ: record.Type.Fields[n].Type, value: &record.Fields[n]}, nil
+	default:
+		return reference{}, fmt.Errorf("%s не является переменной", expr)
+	}
+}
+
+// dereference возвращает динамическую переменную p^
+func (i *Interpreter) dereference(e *Dereference) (*HeapBlock, error) {
+	value, err := i.evaluateExpression(e.Pointer)
+	if err != nil {
+		return nil, err
+	}
+	pointer, ok := value.(PointerValue)
+	if !ok {
+		return nil, runtimeError(e.Pos, "операция ^ неприменима к типу %s", typeOfValue(value))
+	}
+	block, err := i.heap.Block(pointer)
+	if err != nil {
+		return nil, raiseError(classOf(err), e.Pos, "%v (%s)", err, designatorName(e))
+	}
+	return block, nil
+}
+
+// field возвращает запись и номер поля для обращения r.Field
+func (i *Interpreter) field(e *FieldAccess) (*RecordValue, int, error) {
+	value, err := i.evaluateExpression(e.Record)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, ok := value.(*ExceptionValue); ok && exceptionFields[strings.ToLower(e.Field)] {
+		return nil, 0, runtimeError(e.Pos, "поле %s исключения доступно только для чтения", e.Field)
+	}
+	return i.recordField(e, value)
+}
+
+// recordField возвращает номер поля e.Field вычисленной записи value
+func (i *Interpreter) recordField(e *FieldAccess, value Value) (*RecordValue, int, error) {
+	record, ok := value.(*RecordValue)
+	if !ok {
+		return nil, 0, runtimeError(e.Pos, "%s не является записью", designatorName(e.Record))
+	}
+	n := record.Type.Field(e.Field)
+	if n < 0 {
+		return nil, 0, runtimeError(e.Pos, "у записи %s нет поля %s", record.Type, e.Field)
+	}
+	return record, n, nil
+}
+
+// lookup возвращает значение переменной или константы по имени
+func (i *Interpreter) lookup(name string, pos Position) (Value, error) {
+	key := strings.ToLower(name)
+	if variable, ok := i.variable(key); ok {
+		return *variable.slot(), nil
+	}
+	if value, ok := predeclaredConstants[key]; ok {
+		return value, nil
+	}
+	if i.lookupType(key) != nil {
+		return nil, runtimeError(pos, "%s - имя типа, а не значение", name)
+	}
+	if routine, ok := i.routine(key); ok {
+		// Вызов функции без параметров
+		return i.callFunction(routine, nil, name, pos)
+	}
+	if fileFunctions[key] {
+		return i.evaluateFileFunction(&CallExpr{Name: name, Pos: pos})
+	}
+	// Переменная не инициализирована, считаем её равной 0
+	return IntegerValue(0), nil
 }
 
 // executeStatements выполняет список операторов
@@ -28,79 +537,881 @@ func (i *Interpreter) executeStatements(statements []Statement) error {
 		if err != nil {
 			return err
 		}
+		if i.flow != flowNormal {
+			return nil
+		}
 	}
 	return nil
 }
 
-// executeStatement выполняет оператор
+// loop выполняет тело цикла и сообщает, нужно ли завершить цикл: Break принимается и
+// завершает цикл, Continue принимается и переходит к следующей итерации, а Exit
+// завершает цикл и передается дальше
+func (i *Interpreter) loop(body func() error) (bool, error) {
+	i.loops++
+	err := body()
+	i.loops--
+	if err != nil {
+		return true, err
+	}
+	switch i.flow {
+	case flowBreak:
+		i.flow = flowNormal
+		return true, nil
+	case flowContinue:
+		i.flow = flowNormal
+	}
+	return i.flow == flowExit, nil
+}
+
+// executeStatement выполняет оператор, сообщая о нем профилю и наблюдателю. Вычисленные
+// части оператора нужны продолжению со снимка, только пока он выполняется.
 func (i *Interpreter) executeStatement(stmt Statement) error {
-	switch s := stmt.(type) {
-	case *Assignment:
-		value, err := i.evaluateExpression(s.Value)
+	if i.resume != nil {
+		if started, err := i.reenter(stmt); !started {
+			return err
+		}
+	}
+	mark, indivisible := len(i.memo), i.indivisible
+	i.indivisible = i.isIndivisible(stmt)
+	i.active = append(i.active, stmt)
+	err := i.runStatement(stmt)
+	i.active = i.active[:len(i.active)-1]
+	i.memo, i.indivisible = i.memo[:mark], indivisible
+	return err
+}
+
+// runStatement выполняет оператор, который уже стал выполняемым
+func (i *Interpreter) runStatement(stmt Statement) error {
+	if i.resume != nil {
+		// Оператор на пути к точке остановки снимка уже отсчитан до остановки
+		return i.execute(stmt)
+	}
+	if err := i.step(stmt); err != nil {
+		return err
+	}
+	if i.profile != nil {
+		i.profile.Counts[stmt]++
+	}
+	if i.observer == nil {
+		return i.execute(stmt)
+	}
+	i.observer.OnStatement(stmt)
+	err := i.execute(stmt)
+	// Ошибка передается через объемлющие операторы; сообщается только самый внутренний
+	if err != nil && err != i.observed && i.halt == nil {
+		i.observed = err
+		i.observer.OnError(stmt, err)
+	}
+	return err
+}
+
+// execute выполняет оператор
+func (i *Interpreter) execute(stmt Statement) error {
+	return VisitStatement[error](executor{i}, stmt)
+}
+
+// executor выполняет операторы интерпретатора как StatementVisitor; результат - ошибка выполнения
+type executor struct {
+	*Interpreter
+}
+
+func (i executor) VisitAssignment(s *Assignment) error { return i.executeAssignment(s) }
+func (i executor) VisitBlock(s *Block) error           { return i.executeStatements(s.Statements) }
+
+func (i executor) VisitIfStatement(s *IfStatement) error {
+	cond, err := i.condition(s.Cond, "IF", s.Pos)
+	if err != nil {
+		return err
+	}
+	if cond {
+		return i.executeStatement(s.Then)
+	}
+	if s.Else != nil {
+		return i.executeStatement(s.Else)
+	}
+	return nil
+}
+
+func (i executor) VisitWhileStatement(s *WhileStatement) error {
+	mark := len(i.memo)
+	for {
+		i.memo = i.memo[:mark]
+		cond, err := i.condition(s.Cond, "WHILE", s.Pos)
+		if err != nil || !cond {
+			return err
+		}
+		if done, err := i.loop(func() error { return i.executeStatement(s.Body) }); done {
+			return err
+		}
+	}
+}
+
+func (i executor) VisitRepeatStatement(s *RepeatStatement) error {
+	mark := len(i.memo)
+	for {
+		i.memo = i.memo[:mark]
+		// Тело выполняется как оператор-блок, чтобы каждая итерация, даже пустая,
+		// отсчитывалась бюджетом и проверяла запрос остановки
+		if done, err := i.loop(func() error { return i.executeStatement(s.Body) }); done {
+			return err
+		}
+		cond, err := i.condition(s.Cond, "UNTIL", s.Pos)
+		if err != nil || cond {
+			return err
+		}
+	}
+}
+
+func (i executor) VisitForStatement(s *ForStatement) error     { return i.executeFor(s) }
+func (i executor) VisitCallStatement(s *CallStatement) error   { return i.executeCall(s) }
+func (i executor) VisitCaseStatement(s *CaseStatement) error   { return i.executeCase(s) }
+func (i executor) VisitTryStatement(s *TryStatement) error     { return i.executeTry(s) }
+func (i executor) VisitRaiseStatement(s *RaiseStatement) error { return i.executeRaise(s) }
+func (i executor) VisitExitStatement(s *ExitStatement) error   { return i.executeExit(s) }
+
+func (i executor) VisitBreakStatement(s *BreakStatement) error {
+	return i.jump(flowBreak, "Break", s.Pos)
+}
+
+func (i executor) VisitContinueStatement(s *ContinueStatement) error {
+	return i.jump(flowContinue, "Continue", s.Pos)
+}
+
+func (i executor) VisitOtherStatement(stmt Statement) error {
+	return fmt.Errorf("неизвестный тип оператора: %T", stmt)
+}
+
+// executeAssignment выполняет присваивание; наблюдателю сообщаются прежнее и новое значение
+func (i *Interpreter) executeAssignment(s *Assignment) error {
+	value, err := i.evaluateExpression(s.Value)
+	if err != nil {
+		return err
+	}
+	if s.Target != nil {
+		ref, err := i.locate(s.Target)
 		if err != nil {
 			return err
 		}
-		i.variables[s.Variable] = value
+		before := *ref.value
+		if err := i.store(ref, s.Pos, value, !s.Unchecked); err != nil {
+			return err
+		}
+		if i.observer != nil {
+			i.observer.OnAssign(s, before, *ref.value)
+		}
 		return nil
-	case *Block:
-		return i.executeStatements(s.Statements)
-	default:
-		return fmt.Errorf("неизвестный тип оператора: %T", stmt)
 	}
+	if i.observer == nil {
+		return i.assign(s.Variable, s.Pos, value, !s.Unchecked)
+	}
+	key := strings.ToLower(s.Variable)
+	var before Value
+	if variable, ok := i.variable(key); ok {
+		before = *variable.slot()
+	}
+	if err := i.assign(s.Variable, s.Pos, value, !s.Unchecked); err != nil {
+		return err
+	}
+	variable, _ := i.variable(key)
+	i.observer.OnAssign(s, before, *variable.slot())
+	return nil
 }
 
-// evaluateExpression вычисляет значение выражения
-func (i *Interpreter) evaluateExpression(expr Expression) (float64, error) {
-	switch e := expr.(type) {
-	case *Number:
-		return e.Value, nil
-	case *Identifier:
-		value, ok := i.variables[e.Name]
+// condition вычисляет условие оператора statement, которое должно быть логическим
+func (i *Interpreter) condition(expr Expression, statement string, pos Position) (bool, error) {
+	value, err := i.evaluateExpression(expr)
+	if err != nil {
+		return false, err
+	}
+	cond, ok := value.(BooleanValue)
+	if !ok {
+		return false, runtimeError(pos, "условие оператора %s должно быть логического типа, получено %s", statement, typeOfValue(value))
+	}
+	return bool(cond), nil
+}
+
+// executeFor выполняет цикл FOR. Границы вычисляются один раз до начала цикла;
+// если начальное значение больше конечного (меньше для DOWNTO), тело не выполняется.
+func (i *Interpreter) executeFor(s *ForStatement) error {
+	bounds := make([]int64, 2)
+	var t *Type
+	for n, expr := range []Expression{s.Start, s.End} {
+		value, err := i.evaluateExpression(expr)
+		if err != nil {
+			return err
+		}
+		if err := bigOrdinalError(value, s.Pos); err != nil {
+			return err
+		}
+		ordinal, ok := ordinalOf(value)
 		if !ok {
-			// Переменная не инициализирована, считаем её равной 0
-			return 0, nil
+			return runtimeError(s.Pos, "границы цикла FOR должны быть порядкового типа, получено %s", typeOfValue(value))
 		}
-		return value, nil
-	case *BinaryOp:
-		left, err := i.evaluateExpression(e.Left)
+		if t == nil {
+			t = typeOfValue(value)
+		} else if !sameType(t, typeOfValue(value)) {
+			return runtimeError(s.Pos, "границы цикла FOR имеют разные типы %s и %s", t, typeOfValue(value))
+		}
+		bounds[n] = ordinal
+	}
+
+	step := int64(1)
+	if s.Down {
+		step = -1
+	}
+	if bounds[0]*step > bounds[1]*step {
+		return nil
+	}
+	mark := len(i.memo)
+	for ordinal := bounds[0]; ; ordinal += step {
+		i.memo = i.memo[:mark]
+		if entry, ok := i.recall(s, memoOrdinal); ok {
+			// Продолжение со снимка: итерация уже начата, переменная цикла восстановлена
+			ordinal = entry.ordinal
+		} else if err := i.assign(s.Variable, s.Pos, ordinalValue(t, ordinal), true); err != nil {
+			return err
+		}
+		i.remember(memoEntry{node: s, kind: memoOrdinal, ordinal: ordinal})
+		if done, err := i.loop(func() error { return i.executeStatement(s.Body) }); done {
+			return err
+		}
+		// Сравнение до приращения не допускает переполнения на границе MAXINT
+		if ordinal == bounds[1] {
+			return nil
+		}
+	}
+}
+
+// jump выполняет Break или Continue; вне цикла это ошибка, которую обычно находит семантический анализ
+func (i *Interpreter) jump(f flow, name string, pos Position) error {
+	if i.loops == 0 {
+		return runtimeError(pos, "%s вне цикла", name)
+	}
+	i.flow = f
+	return nil
+}
+
+// executeExit выполняет Exit: значение Exit(x) присваивается результату функции
+func (i *Interpreter) executeExit(s *ExitStatement) error {
+	if s.Value != nil {
+		if i.frame == nil || i.frame.Routine.Result == nil {
+			return runtimeError(s.Pos, "Exit со значением допустим только в функции")
+		}
+		value, err := i.evaluateExpression(s.Value)
 		if err != nil {
-			return 0, err
+			return err
+		}
+		routine := i.frame.Routine
+		result := i.frame.variables[strings.ToLower(routine.Decl.Name)]
+		ref := reference{name: routine.Decl.Name, typ: routine.Result, value: &result.Value}
+		if err := i.store(ref, s.Pos, value, true); err != nil {
+			return err
+		}
+	}
+	i.flow = flowExit
+	return nil
+}
+
+// haltError останавливает выполнение программы процедурой Halt
+type haltError struct {
+	code int64
+}
+
+func (e *haltError) Error() string {
+	return fmt.Sprintf("программа завершена процедурой Halt(%d)", e.code)
+}
+
+// executeHalt выполняет Halt и Halt(код): программа завершается сразу, не выполняя
+// ни блоков FINALLY, ни оставшихся операторов
+func (i *Interpreter) executeHalt(s *CallStatement) error {
+	if len(s.Args) > 1 {
+		return runtimeError(s.Pos, "процедура Halt ожидает не более 1 аргумента, получено %d", len(s.Args))
+	}
+	var code int64
+	if len(s.Args) == 1 {
+		value, err := i.evaluateExpression(s.Args[0])
+		if err != nil {
+			return err
+		}
+		n, ok := value.(IntegerValue)
+		if !ok {
+			return runtimeError(s.Pos, "Halt: код выхода должен быть целым, получено %s", typeOfValue(value))
+		}
+		code = int64(n)
+	}
+	i.halt = &haltError{code: code}
+	return i.halt
+}
+
+// ExitCode возвращает код выхода, заданный процедурой Halt; 0, если программа не вызвала Halt
+func (i *Interpreter) ExitCode() int {
+	return i.exitCode
+}
+
+// executeCall выполняет вызов процедуры. Подпрограммы программы скрывают одноименные
+// стандартные процедуры; функцию также можно вызвать как процедуру, отбросив результат.
+func (i *Interpreter) executeCall(s *CallStatement) error {
+	if routine, ok := i.routine(strings.ToLower(s.Name)); ok {
+		_, err := i.callRoutine(routine, s.Args, s.Pos)
+		return err
+	}
+	switch strings.ToLower(s.Name) {
+	case "new", "dispose":
+		return i.executeMemoryProcedure(s)
+	case "write", "writeln":
+		return i.executeWrite(s)
+	case "read", "readln":
+		return i.executeRead(s)
+	case "assign", "reset", "rewrite", "append", "close":
+		return i.executeFileProcedure(s)
+	case "assert":
+		return i.executeAssert(s)
+	case "halt":
+		return i.executeHalt(s)
+	default:
+		return runtimeError(s.Pos, "неизвестная процедура %s", s.Name)
+	}
+}
+
+// executeMemoryProcedure выполняет New и Dispose
+func (i *Interpreter) executeMemoryProcedure(s *CallStatement) error {
+	if len(s.Args) != 1 {
+		return runtimeError(s.Pos, "процедура %s ожидает 1 аргумент(ов), получено %d", s.Name, len(s.Args))
+	}
+
+	ref, err := i.locate(s.Args[0])
+	if err != nil {
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			return err
+		}
+		return runtimeError(s.Pos, "%s: %v", s.Name, err)
+	}
+	pointer, ok := (*ref.value).(PointerValue)
+	if !ok || ref.typ == nil || ref.typ.Kind != TypePointer {
+		return runtimeError(s.Pos, "%s: переменная %s должна быть указателем", s.Name, ref.name)
+	}
+
+	if strings.EqualFold(s.Name, "new") {
+		*ref.value = i.heap.Allocate(ref.typ.Elem, s.Pos)
+		return nil
+	}
+	if err := i.heap.Dispose(pointer, s.Pos); err != nil {
+		return raiseError(classOf(err), s.Pos, "%s: %v (%s)", s.Name, err, ref.name)
+	}
+	return nil
+}
+
+// executeCase выполняет ветвь оператора CASE, метка которой содержит значение выражения.
+// Если такой ветви нет и нет ветви ELSE, это ошибка выполнения, как требует стандарт.
+func (i *Interpreter) executeCase(s *CaseStatement) error {
+	value, err := i.evaluateExpression(s.Expr)
+	if err != nil {
+		return err
+	}
+	// Длинное целое сравнивается с метками точно, без перевода в порядковый номер: не
+	// совпав ни с одной, оно выбирает ветвь ELSE
+	if _, ok := ordinalOf(value); !ok && bigOf(value) == nil {
+		return runtimeError(s.Pos, "выражение CASE должно быть порядкового типа, получено %s", value.Kind())
+	}
+
+	for _, branch := range s.Branches {
+		for _, label := range branch.Labels {
+			matched, err := i.matchCaseLabel(label, value)
+			if err != nil {
+				return err
+			}
+			if matched {
+				return i.executeStatement(branch.Body)
+			}
 		}
-		
-		right, err := i.evaluateExpression(e.Right)
+	}
+
+	if s.Else != nil {
+		return i.executeStatements(s.Else.Statements)
+	}
+	return runtimeError(s.Pos, "значение %s не соответствует ни одной метке CASE", value)
+}
+
+// matchCaseLabel проверяет, попадает ли значение в метку варианта
+func (i *Interpreter) matchCaseLabel(label *CaseLabel, value Value) (bool, error) {
+	high := label.High
+	if high == nil {
+		high = label.Low
+	}
+	bounds := make([]Value, 2)
+	for n, expr := range []Expression{label.Low, high} {
+		bound, err := i.evaluateExpression(expr)
 		if err != nil {
-			return 0, err
+			return false, err
+		}
+		if !sameType(typeOfValue(bound), typeOfValue(value)) {
+			return false, runtimeError(label.Pos, "тип метки CASE %s не совпадает с типом выражения %s", typeOfValue(bound), typeOfValue(value))
 		}
-		
+		bounds[n] = bound
+	}
+	if in, ok := bigInRange(value, bounds[0], bounds[1]); ok {
+		return in, nil
+	}
+	lowOrdinal, _ := ordinalOf(bounds[0])
+	highOrdinal, _ := ordinalOf(bounds[1])
+	ordinal, _ := ordinalOf(value)
+	return lowOrdinal <= ordinal && ordinal <= highOrdinal, nil
+}
+
+// evaluateExpression вычисляет значение выражения
+// Значение запоминается, пока выполняется оператор, которому принадлежит выражение:
+// при продолжении со снимка выражение не вычисляется повторно.
+func (i *Interpreter) evaluateExpression(expr Expression) (Value, error) {
+	if i.resume != nil {
+		if entry, ok := i.recall(expr, memoValue); ok {
+			i.remember(entry)
+			return entry.value, nil
+		}
+	}
+	if i.profile != nil && i.resume == nil {
+		i.profile.Counts[expr]++
+	}
+	mark := len(i.memo)
+	result := VisitExpression[evaluation](evaluator{i}, expr)
+	i.memo = i.memo[:mark]
+	if result.err == nil && !i.indivisible {
+		i.remember(memoEntry{node: expr, kind: memoValue, value: result.value})
+	}
+	return result.value, result.err
+}
+
+// evaluation - результат вычисления выражения: значение или ошибка
+type evaluation struct {
+	value Value
+	err   error
+}
+
+// evaluated составляет результат из значения и ошибки, возвращенных функцией вычисления
+func evaluated(value Value, err error) evaluation {
+	return evaluation{value, err}
+}
+
+// evaluator вычисляет выражения интерпретатора как ExpressionVisitor
+type evaluator struct {
+	*Interpreter
+}
+
+func (i evaluator) VisitNumber(e *Number) evaluation {
+	if e.IsReal {
+		return evaluation{value: RealValue(e.Value)}
+	}
+	return evaluation{value: IntegerValue(e.IntValue())}
+}
+
+func (i evaluator) VisitLiteral(e *Literal) evaluation { return evaluation{value: e.Value} }
+
+func (i evaluator) VisitIdentifier(e *Identifier) evaluation {
+	return evaluated(i.lookup(e.Name, e.Pos))
+}
+
+func (i evaluator) VisitBinaryOp(e *BinaryOp) evaluation {
+	left, err := i.evaluateExpression(e.Left)
+	if err != nil {
+		return evaluation{err: err}
+	}
+
+	// Логические операции вычисляются по короткой схеме
+	if b, ok := left.(BooleanValue); ok && (e.Operator == TokenAND && !bool(b) || e.Operator == TokenOR && bool(b)) {
+		return evaluation{value: b}
+	}
+
+	right, err := i.evaluateExpression(e.Right)
+	if err != nil {
+		return evaluation{err: err}
+	}
+	return evaluated(i.evaluateBinary(e, left, right))
+}
+
+func (i evaluator) VisitUnaryOp(e *UnaryOp) evaluation {
+	operand, err := i.evaluateExpression(e.Operand)
+	if err != nil {
+		return evaluation{err: err}
+	}
+	return evaluated(i.evaluateUnary(e, operand))
+}
+
+func (i evaluator) VisitSetConstructor(e *SetConstructor) evaluation {
+	return evaluated(i.evaluateSet(e))
+}
+
+func (i evaluator) VisitDereference(e *Dereference) evaluation {
+	block, err := i.dereference(e)
+	if err != nil {
+		return evaluation{err: err}
+	}
+	return evaluation{value: block.Value}
+}
+
+func (i evaluator) VisitFieldAccess(e *FieldAccess) evaluation {
+	value, err := i.evaluateExpression(e.Record)
+	if err != nil {
+		return evaluation{err: err}
+	}
+	if exception, ok := value.(*ExceptionValue); ok && exceptionFields[strings.ToLower(e.Field)] {
+		if exception == nil {
+			return evaluation{err: raiseError(accessViolationClass, e.Pos, "обращение к полю %s исключения NIL", e.Field)}
+		}
+		return evaluation{value: exception.field(e.Field)}
+	}
+	record, n, err := i.recordField(e, value)
+	if err != nil {
+		return evaluation{err: err}
+	}
+	return evaluation{value: record.Fields[n]}
+}
+
+func (i evaluator) VisitCallExpr(e *CallExpr) evaluation     { return evaluated(i.evaluateCall(e)) }
+func (i evaluator) VisitCreateExpr(e *CreateExpr) evaluation { return evaluated(i.evaluateCreate(e)) }
+
+// VisitFormatExpr: формат ширины и точности допустим только в параметрах Write и WriteLn,
+// которые разбирают его сами
+func (i evaluator) VisitFormatExpr(e *FormatExpr) evaluation { return i.VisitOtherExpression(e) }
+
+func (i evaluator) VisitOtherExpression(expr Expression) evaluation {
+	return evaluation{err: fmt.Errorf("неизвестный тип выражения: %T", expr)}
+}
+
+// evaluateBinary применяет бинарную операцию к вычисленным операндам.
+// В арифметике целые операнды дают целый результат (кроме '/'), иначе результат вещественный;
+// '+', '*' и '-' над множествами - объединение, пересечение и разность.
+func (i *Interpreter) evaluateBinary(e *BinaryOp, left, right Value) (Value, error) {
+	switch e.Operator {
+	case TokenEQUAL, TokenNOTEQUAL, TokenLESS, TokenLESSEQUAL, TokenGREATER, TokenGREATEREQUAL:
+		return i.evaluateComparison(e, left, right)
+	case TokenIN:
+		return i.evaluateIn(e, left, right)
+	case TokenAND, TokenOR:
+		l, lok := left.(BooleanValue)
+		r, rok := right.(BooleanValue)
+		if !lok || !rok {
+			return nil, runtimeError(e.Pos, "операция %s неприменима к типам %s и %s", operatorSymbol(e.Operator), left.Kind(), right.Kind())
+		}
+		if e.Operator == TokenAND {
+			return l && r, nil
+		}
+		return l || r, nil
+	case TokenDIV, TokenMOD:
+		return i.evaluateIntegerDivision(e, left, right)
+	}
+	if set, ok := left.(SetValue); ok {
+		return i.evaluateSetOperation(e, set, right)
+	}
+	if l, ok := textOf(left); ok {
+		r, ok := textOf(right)
+		if !ok || e.Operator != TokenPLUS {
+			return nil, runtimeError(e.Pos, "операция %s неприменима к типам %s и %s", operatorSymbol(e.Operator), typeOfValue(left), typeOfValue(right))
+		}
+		return StringValue(l + r), nil
+	}
+
+	l, lok := left.(IntegerValue)
+	r, rok := right.(IntegerValue)
+	if lok && rok && e.Operator != TokenDIVIDE {
+		var result int64
+		ok := true
 		switch e.Operator {
 		case TokenPLUS:
-			return left + right, nil
+			result, ok = addInt(int64(l), int64(r))
 		case TokenMINUS:
-			return left - right, nil
+			result, ok = subInt(int64(l), int64(r))
 		case TokenMULTIPLY:
-			return left * right, nil
-		case TokenDIVIDE:
-			if right == 0 {
-				return 0, fmt.Errorf("деление на ноль")
+			result, ok = mulInt(int64(l), int64(r))
+		default:
+			return nil, fmt.Errorf("неизвестный оператор: %v", e.Operator)
+		}
+		if !ok {
+			if i.bigint {
+				return bigArithmetic(e, big.NewInt(int64(l)), big.NewInt(int64(r)))
 			}
-			return left / right, nil
+			return nil, raiseError(intOverflowClass, e.Pos, "целочисленное переполнение")
+		}
+		return IntegerValue(result), nil
+	}
+	if l, r, ok := bigOperands(left, right); ok && e.Operator != TokenDIVIDE {
+		return bigArithmetic(e, l, r)
+	}
+
+	lf, lok := toReal(left)
+	rf, rok := toReal(right)
+	if !lok || !rok {
+		return nil, runtimeError(e.Pos, "несовместимые типы операндов: %s и %s", left.Kind(), right.Kind())
+	}
+	var result float64
+	switch e.Operator {
+	case TokenPLUS:
+		result = lf + rf
+	case TokenMINUS:
+		result = lf - rf
+	case TokenMULTIPLY:
+		result = lf * rf
+	case TokenDIVIDE:
+		if rf == 0 {
+			return nil, raiseError(divByZeroClass, e.Pos, "деление на ноль")
+		}
+		result = lf / rf
+	default:
+		return nil, fmt.Errorf("неизвестный оператор: %v", e.Operator)
+	}
+	value, err := checkReal(result)
+	if err != nil {
+		return nil, raiseError(classOf(err), e.Pos, "%v", err)
+	}
+	return value, nil
+}
+
+// evaluateIntegerDivision вычисляет DIV (частное с отбрасыванием дробной части)
+// и MOD (остаток со знаком делимого) над целыми операндами
+func (i *Interpreter) evaluateIntegerDivision(e *BinaryOp, left, right Value) (Value, error) {
+	if l, r, ok := bigOperands(left, right); ok {
+		return bigDivision(e, l, r)
+	}
+	l, lok := left.(IntegerValue)
+	r, rok := right.(IntegerValue)
+	if !lok || !rok {
+		return nil, runtimeError(e.Pos, "операция %s неприменима к типам %s и %s", operatorSymbol(e.Operator), left.Kind(), right.Kind())
+	}
+	if r == 0 {
+		return nil, raiseError(divByZeroClass, e.Pos, "деление на ноль")
+	}
+	if l == math.MinInt64 && r == -1 {
+		if e.Operator == TokenMOD {
+			return IntegerValue(0), nil
+		}
+		if i.bigint {
+			return bigDivision(e, big.NewInt(int64(l)), big.NewInt(int64(r)))
+		}
+		return nil, raiseError(intOverflowClass, e.Pos, "целочисленное переполнение")
+	}
+	if e.Operator == TokenDIV {
+		return l / r, nil
+	}
+	return l % r, nil
+}
+
+// evaluateComparison вычисляет операцию отношения. Числа сравниваются по значению,
+// остальные порядковые значения - по порядковому номеру; для множеств '=' и '<>'
+// означают равенство, '<=' и '>=' - включение.
+func (i *Interpreter) evaluateComparison(e *BinaryOp, left, right Value) (Value, error) {
+	if l, ok := left.(SetValue); ok {
+		r, ok := right.(SetValue)
+		if !ok || !sameType(typeOfValue(l), typeOfValue(r)) {
+			return nil, runtimeError(e.Pos, "несравнимые типы операндов: %s и %s", typeOfValue(left), typeOfValue(right))
+		}
+		switch e.Operator {
+		case TokenEQUAL:
+			return BooleanValue(l.Bits == r.Bits), nil
+		case TokenNOTEQUAL:
+			return BooleanValue(l.Bits != r.Bits), nil
+		case TokenLESSEQUAL:
+			return BooleanValue(l.subsetOf(r)), nil
+		case TokenGREATEREQUAL:
+			return BooleanValue(r.subsetOf(l)), nil
 		default:
-			return 0, fmt.Errorf("неизвестный оператор: %v", e.Operator)
+			return nil, runtimeError(e.Pos, "операция %s неприменима к множествам", operatorSymbol(e.Operator))
 		}
+	}
+
+	if l, ok := left.(PointerValue); ok {
+		r, ok := right.(PointerValue)
+		if !ok || !sameType(typeOfValue(l), typeOfValue(r)) {
+			return nil, runtimeError(e.Pos, "несравнимые типы операндов: %s и %s", typeOfValue(left), typeOfValue(right))
+		}
+		switch e.Operator {
+		case TokenEQUAL:
+			return BooleanValue(l.Addr == r.Addr), nil
+		case TokenNOTEQUAL:
+			return BooleanValue(l.Addr != r.Addr), nil
+		default:
+			return nil, runtimeError(e.Pos, "операция %s неприменима к указателям", operatorSymbol(e.Operator))
+		}
+	}
+
+	var order int
+	l, lok := left.(IntegerValue)
+	r, rok := right.(IntegerValue)
+	lf, lnum := toReal(left)
+	rf, rnum := toReal(right)
+	lo, lord := ordinalOf(left)
+	ro, rord := ordinalOf(right)
+	ls, lstr := textOf(left)
+	rs, rstr := textOf(right)
+	switch {
+	case lok && rok:
+		order = cmp.Compare(l, r)
+	case left.Kind() == KindInteger && right.Kind() == KindInteger:
+		order = bigOf(left).Cmp(bigOf(right))
+	case lnum && rnum:
+		order = cmp.Compare(lf, rf)
+	case lstr && rstr:
+		order = strings.Compare(ls, rs)
+	case lord && rord && sameType(typeOfValue(left), typeOfValue(right)):
+		order = cmp.Compare(lo, ro)
+	default:
+		return nil, runtimeError(e.Pos, "несравнимые типы операндов: %s и %s", typeOfValue(left), typeOfValue(right))
+	}
+
+	switch e.Operator {
+	case TokenEQUAL:
+		return BooleanValue(order == 0), nil
+	case TokenNOTEQUAL:
+		return BooleanValue(order != 0), nil
+	case TokenLESS:
+		return BooleanValue(order < 0), nil
+	case TokenLESSEQUAL:
+		return BooleanValue(order <= 0), nil
+	case TokenGREATER:
+		return BooleanValue(order > 0), nil
 	default:
-		return 0, fmt.Errorf("неизвестный тип выражения: %T", expr)
+		return BooleanValue(order >= 0), nil
+	}
+}
+
+// evaluateIn проверяет принадлежность порядкового значения множеству
+func (i *Interpreter) evaluateIn(e *BinaryOp, left, right Value) (Value, error) {
+	set, ok := right.(SetValue)
+	if _, long := left.(BigIntValue); long && ok {
+		// Длинное целое вне диапазона элементов любого множества
+		return BooleanValue(false), nil
+	}
+	ordinal, isOrdinal := ordinalOf(left)
+	if !ok || !isOrdinal {
+		return nil, runtimeError(e.Pos, "операция IN неприменима к типам %s и %s", typeOfValue(left), typeOfValue(right))
+	}
+	if set.Elem != nil && !sameType(typeOfValue(left), set.Elem) {
+		return nil, runtimeError(e.Pos, "тип %s не совпадает с типом элементов множества %s", typeOfValue(left), set.Elem)
+	}
+	return BooleanValue(set.contains(ordinal)), nil
+}
+
+// evaluateSetOperation вычисляет объединение (+), пересечение (*) или разность (-) множеств
+func (i *Interpreter) evaluateSetOperation(e *BinaryOp, left SetValue, right Value) (Value, error) {
+	r, ok := right.(SetValue)
+	if !ok || !sameType(typeOfValue(left), typeOfValue(r)) {
+		return nil, runtimeError(e.Pos, "несовместимые типы операндов: %s и %s", typeOfValue(left), typeOfValue(right))
+	}
+	switch e.Operator {
+	case TokenPLUS:
+		return left.combine(r, func(a, b uint64) uint64 { return a | b }), nil
+	case TokenMULTIPLY:
+		return left.combine(r, func(a, b uint64) uint64 { return a & b }), nil
+	case TokenMINUS:
+		return left.combine(r, func(a, b uint64) uint64 { return a &^ b }), nil
+	default:
+		return nil, runtimeError(e.Pos, "операция %s неприменима к множествам", operatorSymbol(e.Operator))
+	}
+}
+
+// evaluateUnary вычисляет унарную операцию NOT
+func (i *Interpreter) evaluateUnary(e *UnaryOp, operand Value) (Value, error) {
+	if e.Operator != TokenNOT {
+		return nil, fmt.Errorf("неизвестный оператор: %v", e.Operator)
+	}
+	b, ok := operand.(BooleanValue)
+	if !ok {
+		return nil, runtimeError(e.Pos, "операция NOT неприменима к типу %s", operand.Kind())
+	}
+	return !b, nil
+}
+
+// evaluateSet вычисляет конструктор множества; диапазон low..high при low > high пуст
+func (i *Interpreter) evaluateSet(e *SetConstructor) (Value, error) {
+	set := SetValue{}
+	for _, element := range e.Elements {
+		high := element.High
+		if high == nil {
+			high = element.Low
+		}
+		bounds := make([]int64, 2)
+		for n, expr := range []Expression{element.Low, high} {
+			value, err := i.evaluateExpression(expr)
+			if err != nil {
+				return nil, err
+			}
+			if err := bigOrdinalError(value, e.Pos); err != nil {
+				return nil, err
+			}
+			ordinal, ok := ordinalOf(value)
+			if !ok {
+				return nil, runtimeError(e.Pos, "элемент множества должен быть порядкового типа, получено %s", value.Kind())
+			}
+			if set.Elem == nil {
+				set.Elem = typeOfValue(value)
+			} else if !sameType(set.Elem, typeOfValue(value)) {
+				return nil, runtimeError(e.Pos, "элементы множества имеют разные типы %s и %s", set.Elem, typeOfValue(value))
+			}
+			if ordinal < 0 || ordinal > maxSetOrdinal {
+				return nil, raiseError(rangeErrorClass, e.Pos, "элемент множества %s вне диапазона 0..%d", value, maxSetOrdinal)
+			}
+			bounds[n] = ordinal
+		}
+		for ordinal := bounds[0]; ordinal <= bounds[1]; ordinal++ {
+			set.include(ordinal)
+		}
 	}
+	return set, nil
+}
+
+// evaluateCall вычисляет вызов функции программы или встроенной функции
+func (i *Interpreter) evaluateCall(e *CallExpr) (Value, error) {
+	if routine, ok := i.routine(strings.ToLower(e.Name)); ok {
+		return i.callFunction(routine, e.Args, e.Name, e.Pos)
+	}
+	if fileFunctions[strings.ToLower(e.Name)] {
+		return i.evaluateFileFunction(e)
+	}
+	fn, ok := lookupBuiltin(e.Name, i.bigint)
+	if !ok {
+		return nil, runtimeError(e.Pos, "неизвестная функция %s", e.Name)
+	}
+	if len(e.Args) != fn.arity {
+		return nil, runtimeError(e.Pos, "функция %s ожидает %d аргумент(ов), получено %d", e.Name, fn.arity, len(e.Args))
+	}
+
+	args := make([]Value, len(e.Args))
+	for n, arg := range e.Args {
+		value, err := i.evaluateExpression(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[n] = value
+	}
+
+	result, err := fn.call(args)
+	if err != nil {
+		return nil, raiseError(classOf(err), e.Pos, "%s: %v", e.Name, err)
+	}
+	return result, nil
+}
+
+// Leaks возвращает динамические переменные, не освобожденные процедурой Dispose
+func (i *Interpreter) Leaks() []*HeapBlock {
+	return i.heap.Leaks()
 }
 
 // GetVariables возвращает словарь всех переменных
 func (i *Interpreter) GetVariables() map[string]float64 {
 	// Создаем копию, чтобы избежать изменений извне
 	result := make(map[string]float64)
-	for k, v := range i.variables {
-		result[k] = v
+	for name, value := range i.Values() {
+		result[name] = toFloat(value)
 	}
 	return result
 }
 
-
-
+// Values возвращает словарь всех переменных (без констант) с их типизированными значениями
+func (i *Interpreter) Values() map[string]Value {
+	result := make(map[string]Value)
+	for _, variable := range i.variables {
+		if !variable.Const {
+			result[variable.Name] = variable.Value
+		}
+	}
+	return result
+}
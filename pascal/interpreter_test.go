@@ -1,6 +1,9 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -460,16 +463,54 @@ func TestLexerErrors(t *testing.T) {
 		t.Error("Ожидалась ошибка для неожиданного символа @")
 	}
 
-	// Неправильный формат := (только :)
+	// Неправильный формат := (только :) - двоеточие допустимо в описаниях,
+	// поэтому ошибку сообщает парсер
 	code2 := `BEGIN x : 5; END.`
 	lexer2 := NewLexer(code2)
-	_, err2 := lexer2.Tokenize()
-	if err2 == nil {
+	tokens2, err2 := lexer2.Tokenize()
+	if err2 != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err2)
+	}
+	if _, err2 = NewParser(tokens2).Parse(); err2 == nil {
 		t.Error("Ожидалась ошибка для неправильного формата :")
 	}
 }
 
 // TestParserErrors тестирует обработку ошибок парсера
+// TestSyntaxErrorPosition тестирует позиции ошибок лексического и синтаксического анализа
+func TestSyntaxErrorPosition(t *testing.T) {
+	tests := []struct {
+		code    string
+		message string
+		offset  int
+		pos     Position
+	}{
+		{"BEGIN\n  x := 1 @ END.", "неожиданный символ '@'", 15, Position{Line: 2, Column: 10}},
+		{"BEGIN\n  { текст", "незакрытый комментарий", 8, Position{Line: 2, Column: 3}},
+		{"BEGIN\n  x := 1;\n  y := \nEND.", "неожиданный токен {2 END", 24, Position{Line: 4, Column: 1}},
+		{"BEGIN x := 5; END. x", "неожиданные токены после точки", 19, Position{Line: 1, Column: 20}},
+	}
+	for _, tt := range tests {
+		lexer := NewLexer(tt.code)
+		tokens, err := lexer.Tokenize()
+		if err == nil {
+			_, err = NewParser(tokens).Parse()
+		}
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: ожидалась SyntaxError, получено %v", tt.code, err)
+			continue
+		}
+		if !strings.HasPrefix(syntaxErr.Message, tt.message) || syntaxErr.Offset != tt.offset || syntaxErr.Pos != tt.pos {
+			t.Errorf("%q: ожидалось %q на позиции %d (%s), получено %q на позиции %d (%s)", tt.code,
+				tt.message, tt.offset, tt.pos, syntaxErr.Message, syntaxErr.Offset, syntaxErr.Pos)
+		}
+		if want := fmt.Sprintf("%s на позиции %d", syntaxErr.Message, tt.offset); err.Error() != want {
+			t.Errorf("%q: ожидался текст %q, получено %q", tt.code, want, err.Error())
+		}
+	}
+}
+
 func TestParserErrors(t *testing.T) {
 	// Отсутствие BEGIN
 	code := `x := 5; END.`
@@ -1766,8 +1807,14 @@ func TestLexerSkipWhitespaceInvalidUTF8(t *testing.T) {
 func TestLexerTokenizeColonWithoutEquals(t *testing.T) {
 	code := `BEGIN x : 5; END.`
 	lexer := NewLexer(code)
-	_, err := lexer.Tokenize()
-	if err == nil {
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	if tokens[2].Type != TokenCOLON {
+		t.Errorf("Ожидался TokenCOLON, получен %v", tokens[2].Type)
+	}
+	if _, err := NewParser(tokens).Parse(); err == nil {
 		t.Error("Ожидалась ошибка для ':' без '='")
 	}
 }
@@ -1965,3 +2012,137 @@ func TestLexerSkipWhitespaceAllCases(t *testing.T) {
 	// Не должно быть паники
 }
 
+
+// TestCaseStatement тестирует выбор ветви оператора CASE по отдельным меткам и диапазонам
+func TestCaseStatement(t *testing.T) {
+	code := `BEGIN
+	CASE n OF
+		1, 2: x := 10;
+		3..5: BEGIN x := 20; y := n END;
+		-3..-1: x := -1
+	ELSE
+		x := 0;
+		y := -n
+	END
+END.`
+	tests := []struct {
+		n    int64
+		x, y Value
+	}{
+		{1, IntegerValue(10), nil},
+		{2, IntegerValue(10), nil},
+		{3, IntegerValue(20), IntegerValue(3)},
+		{5, IntegerValue(20), IntegerValue(5)},
+		{-2, IntegerValue(-1), nil},
+		{6, IntegerValue(0), IntegerValue(-6)},
+	}
+	for _, tt := range tests {
+		program := parseCode(t, code)
+		interpreter := NewInterpreter()
+		interpreter.variables["n"] = &Variable{Name: "n", Value: IntegerValue(tt.n)}
+		if err := interpreter.Interpret(program); err != nil {
+			t.Fatalf("n = %d: ошибка выполнения: %v", tt.n, err)
+		}
+		values := interpreter.Values()
+		if values["x"] != tt.x || values["y"] != tt.y {
+			t.Errorf("n = %d: ожидалось x = %v, y = %v, получено x = %v, y = %v", tt.n, tt.x, tt.y, values["x"], values["y"])
+		}
+	}
+}
+
+// TestCaseStatementOtherwise тестирует ветвь OTHERWISE и выбор по логическому значению
+func TestCaseStatementOtherwise(t *testing.T) {
+	values := mustInterpret(t, `BEGIN
+	case 7 of
+		1: x := 1
+	otherwise
+		x := 2
+	end;
+	CASE Odd(7) OF
+		TRUE: y := 1;
+		FALSE: y := 0;
+	END;
+	z := 3
+END.`)
+	if values["x"] != IntegerValue(2) || values["y"] != IntegerValue(1) || values["z"] != IntegerValue(3) {
+		t.Errorf("Ожидалось x = 2, y = 1, z = 3, получено %v", values)
+	}
+}
+
+// TestCaseStatementNoMatch тестирует ошибку выполнения, если ни одна метка не подходит
+func TestCaseStatementNoMatch(t *testing.T) {
+	_, err := interpretCode(t, "BEGIN\n  x := 4;\n  CASE x OF 1: y := 1; 2..3: y := 2 END\nEND.")
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("Ожидалась ошибка выполнения, получено %v", err)
+	}
+	if runtimeErr.Pos != (Position{Line: 3, Column: 3}) {
+		t.Errorf("Ожидалась позиция оператора CASE 3:3, получено %v", runtimeErr.Pos)
+	}
+
+	for _, code := range []string{
+		"BEGIN CASE 1.5 OF 1: x := 1 END END.",
+		"BEGIN CASE 1 OF TRUE: x := 1 END END.",
+		"BEGIN CASE 1 OF Foo(1): x := 1 END END.",
+		"BEGIN CASE Foo(1) OF 1: x := 1 END END.",
+		"BEGIN CASE 1 OF 1: x := 1 / 0 END END.",
+		"BEGIN CASE 2 OF 1: x := 1 ELSE x := 1 / 0 END END.",
+	} {
+		if _, err := interpretCode(t, code); err == nil {
+			t.Errorf("%q: ожидалась ошибка выполнения", code)
+		}
+	}
+}
+
+// TestCaseStatementParserErrors тестирует синтаксические ошибки оператора CASE
+func TestCaseStatementParserErrors(t *testing.T) {
+	for _, code := range []string{
+		"BEGIN CASE x 1: y := 1 END END.",
+		"BEGIN CASE x OF END END.",
+		"BEGIN CASE x OF 1 y := 1 END END.",
+		"BEGIN CASE x OF 1..: y := 1 END END.",
+		"BEGIN CASE x OF 1: y := 1 ELSE y := END END.",
+		"BEGIN CASE x OF 1: y := 1 . END.",
+		"BEGIN CASE OF 1: y := 1 END END.",
+		"BEGIN CASE x OF , 1: y := 1 END END.",
+		"BEGIN CASE x OF 1: := 1 END END.",
+	} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", code, err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestLexerDotDot тестирует различение '..', '.' и вещественных чисел
+func TestLexerDotDot(t *testing.T) {
+	tokens, err := NewLexer("1..5 END.").Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	want := []TokenType{TokenNUMBER, TokenDOTDOT, TokenNUMBER, TokenEND, TokenDOT, TokenEOF}
+	for n, tt := range want {
+		if tokens[n].Type != tt {
+			t.Errorf("Токен %d: ожидался тип %v, получен %v", n, tt, tokens[n].Type)
+		}
+	}
+	if tokens[0].Value != "1" || tokens[2].Value != "5" {
+		t.Errorf("Ожидались числа 1 и 5, получено %q и %q", tokens[0].Value, tokens[2].Value)
+	}
+}
+
+// TestCaseStringMethods тестирует методы String() узлов CASE
+func TestCaseStringMethods(t *testing.T) {
+	program := parseCode(t, "BEGIN CASE x OF 1, 2..3: y := 1 ELSE y := 2 END END.")
+	stmt := program.Statements[0].(*CaseStatement)
+	stmt.statementNode()
+	if stmt.String() == "" || stmt.Branches[0].String() == "" {
+		t.Error("String() должен возвращать непустую строку")
+	}
+	if got := stmt.Branches[0].Labels[1].String(); got != "Number(2)..Number(3)" {
+		t.Errorf("Ожидалась метка Number(2)..Number(3), получено %s", got)
+	}
+}
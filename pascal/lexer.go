@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -24,21 +25,156 @@ const (
 	TokenRPAREN
 	TokenIDENTIFIER
 	TokenNUMBER
+	TokenCOMMA
+	TokenCOLON
+	TokenEQUAL
+	TokenPROGRAM
+	TokenCONST
+	TokenTYPE
+	TokenVAR
+	TokenDOTDOT
+	TokenCASE
+	TokenOF
+	TokenELSE
+	TokenOTHERWISE
+	TokenDIRECTIVE
+	TokenLBRACKET
+	TokenRBRACKET
+	TokenNOTEQUAL
+	TokenLESS
+	TokenLESSEQUAL
+	TokenGREATER
+	TokenGREATEREQUAL
+	TokenSET
+	TokenIN
+	TokenAND
+	TokenOR
+	TokenNOT
+	TokenDIV
+	TokenMOD
+	TokenCARET
+	TokenNIL
+	TokenRECORD
+	TokenIF
+	TokenTHEN
+	TokenWHILE
+	TokenDO
+	TokenREPEAT
+	TokenUNTIL
+	TokenFOR
+	TokenTO
+	TokenDOWNTO
+	TokenSTRING
+	TokenPROCEDURE
+	TokenFUNCTION
+	TokenTRY
+	TokenEXCEPT
+	TokenFINALLY
+	TokenRAISE
+	TokenUNIT
+	TokenUSES
+	TokenINTERFACE
+	TokenIMPLEMENTATION
+	TokenINITIALIZATION
 )
 
+// keywords содержит зарезервированные слова; регистр букв в них не различается
+var keywords = map[string]TokenType{
+	"BEGIN":     TokenBEGIN,
+	"END":       TokenEND,
+	"PROGRAM":   TokenPROGRAM,
+	"CONST":     TokenCONST,
+	"TYPE":      TokenTYPE,
+	"VAR":       TokenVAR,
+	"CASE":      TokenCASE,
+	"OF":        TokenOF,
+	"ELSE":      TokenELSE,
+	"OTHERWISE": TokenOTHERWISE,
+	"SET":       TokenSET,
+	"IN":        TokenIN,
+	"AND":       TokenAND,
+	"OR":        TokenOR,
+	"NOT":       TokenNOT,
+	"DIV":       TokenDIV,
+	"MOD":       TokenMOD,
+	"NIL":       TokenNIL,
+	"RECORD":    TokenRECORD,
+	"IF":        TokenIF,
+	"THEN":      TokenTHEN,
+	"WHILE":     TokenWHILE,
+	"DO":        TokenDO,
+	"REPEAT":    TokenREPEAT,
+	"UNTIL":     TokenUNTIL,
+	"FOR":       TokenFOR,
+	"TO":        TokenTO,
+	"DOWNTO":    TokenDOWNTO,
+	"PROCEDURE": TokenPROCEDURE,
+	"FUNCTION":  TokenFUNCTION,
+	"TRY":       TokenTRY,
+	"EXCEPT":    TokenEXCEPT,
+	"FINALLY":   TokenFINALLY,
+	"RAISE":     TokenRAISE,
+
+	"UNIT":           TokenUNIT,
+	"USES":           TokenUSES,
+	"INTERFACE":      TokenINTERFACE,
+	"IMPLEMENTATION": TokenIMPLEMENTATION,
+	"INITIALIZATION": TokenINITIALIZATION,
+}
+
+// Position представляет позицию в исходном тексте (строка и столбец с единицы)
+type Position struct {
+	File   string // файл модуля; пустая строка для основной программы
+	Line   int
+	Column int
+}
+
+// IsValid сообщает, известна ли позиция
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s: строка %d, столбец %d", p.File, p.Line, p.Column)
+	}
+	return fmt.Sprintf("строка %d, столбец %d", p.Line, p.Column)
+}
+
 // Token представляет токен с типом и значением
 type Token struct {
-	Type  TokenType
-	Value string
-	Pos   int
+	Type   TokenType
+	Value  string
+	Pos    int
+	File   string
+	Line   int
+	Column int
+}
+
+// Position возвращает позицию токена в исходном тексте
+func (t Token) Position() Position {
+	return Position{File: t.File, Line: t.Line, Column: t.Column}
 }
 
 // Lexer представляет лексер для Pascal
 type Lexer struct {
+	// File - имя файла модуля, которое попадает в позиции токенов
+	File string
+	// Dialect - вариант языка; в строгих диалектах отклонения от стандарта являются ошибками
+	Dialect Dialect
+	// Deviations - отклонения от стандарта, найденные Tokenize. Разбор на них не
+	// прекращается, чтобы парсер сообщил обо всех отклонениях сразу.
+	Deviations []*DialectError
+
 	input  string
 	pos    int
 	start  int
 	tokens []Token
+
+	// Счетчики для вычисления строки и столбца токенов
+	line      int
+	lineStart int
+	scanned   int
 }
 
 // NewLexer создает новый лексер
@@ -54,7 +190,9 @@ func NewLexer(input string) *Lexer {
 // Tokenize разбивает входную строку на токены
 func (l *Lexer) Tokenize() ([]Token, error) {
 	for l.pos < len(l.input) {
-		l.skipWhitespace()
+		if err := l.skipTrivia(); err != nil {
+			return nil, err
+		}
 		if l.pos >= len(l.input) {
 			break
 		}
@@ -65,12 +203,19 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 		}
 
 		switch {
+		case r == '.' && l.peekNext() == '.':
+			l.advance()
+			l.advance()
+			l.emit(TokenDOTDOT)
 		case r == '.':
 			l.emit(TokenDOT)
 			l.advance()
 		case r == ';':
 			l.emit(TokenSEMICOLON)
 			l.advance()
+		case r == ',':
+			l.emit(TokenCOMMA)
+			l.advance()
 		case r == ':':
 			l.advance() // пропускаем ':'
 			// Пропускаем пробелы между ':' и '='
@@ -82,11 +227,48 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 				l.advance()
 			}
 			if l.pos < len(l.input) && l.input[l.pos] == '=' {
+				if l.pos > l.start+1 && l.Dialect.strictSyntax() {
+					l.deviate(ruleTokenSeparators, l.position(l.start),
+						"знак присваивания := записан с разделителем между ':' и '='")
+				}
 				l.advance() // пропускаем '='
 				l.emit(TokenASSIGN)
 			} else {
-				return nil, fmt.Errorf("ожидался '=' после ':' на позиции %d", l.pos)
+				// Одиночное двоеточие разделяет имена и тип в описаниях
+				l.pos = l.start + 1
+				l.emit(TokenCOLON)
+			}
+		case r == '=':
+			l.emit(TokenEQUAL)
+			l.advance()
+		case r == '<' && (l.peekNext() == '=' || l.peekNext() == '>'):
+			l.advance()
+			if r, _ := l.peekRune(); r == '=' {
+				l.advance()
+				l.emit(TokenLESSEQUAL)
+			} else {
+				l.advance()
+				l.emit(TokenNOTEQUAL)
 			}
+		case r == '<':
+			l.emit(TokenLESS)
+			l.advance()
+		case r == '>' && l.peekNext() == '=':
+			l.advance()
+			l.advance()
+			l.emit(TokenGREATEREQUAL)
+		case r == '>':
+			l.emit(TokenGREATER)
+			l.advance()
+		case r == '^':
+			l.emit(TokenCARET)
+			l.advance()
+		case r == '[':
+			l.emit(TokenLBRACKET)
+			l.advance()
+		case r == ']':
+			l.emit(TokenRBRACKET)
+			l.advance()
 		case r == '+':
 			l.emit(TokenPLUS)
 			l.advance()
@@ -105,12 +287,16 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 		case r == ')':
 			l.emit(TokenRPAREN)
 			l.advance()
+		case r == '\'':
+			if err := l.readString(); err != nil {
+				return nil, err
+			}
 		case unicode.IsDigit(r):
 			l.readNumber()
-		case unicode.IsLetter(r):
+		case unicode.IsLetter(r) || r == '_':
 			l.readIdentifier()
 		default:
-			return nil, fmt.Errorf("неожиданный символ '%c' на позиции %d", r, l.pos)
+			return nil, l.errorf(l.pos, "неожиданный символ '%c'", r)
 		}
 	}
 
@@ -132,7 +318,93 @@ func (l *Lexer) skipWhitespace() {
 	l.start = l.pos
 }
 
+// skipTrivia пропускает пробелы и комментарии вида { ... }, (* ... *) и // ...
+// Комментарий, начинающийся с '$' ({$R+}), является директивой компилятора
+// и передается парсеру токеном TokenDIRECTIVE.
+func (l *Lexer) skipTrivia() error {
+	for {
+		l.skipWhitespace()
+		rest := l.input[l.pos:]
+		var end int
+		switch {
+		case strings.HasPrefix(rest, "{"):
+			end = strings.Index(rest, "}") + 1
+		case strings.HasPrefix(rest, "(*"):
+			end = strings.Index(rest[2:], "*)") + 4
+			if end == 3 {
+				end = 0
+			}
+		case strings.HasPrefix(rest, "//"):
+			if l.Dialect == DialectISO {
+				l.deviate(ruleComment, l.position(l.pos), "комментарий // не предусмотрен стандартом")
+			}
+			end = strings.IndexByte(rest, '\n') + 1
+			if end == 0 {
+				end = len(rest)
+			}
+		default:
+			return nil
+		}
+		if end == 0 {
+			return l.errorf(l.pos, "незакрытый комментарий")
+		}
+		l.pos += end
+		if strings.HasPrefix(rest, "{$") || strings.HasPrefix(rest, "(*$") {
+			l.emit(TokenDIRECTIVE)
+		}
+	}
+}
+
 func (l *Lexer) readNumber() {
+	l.skipDigits()
+
+	// Дробная часть: точка считается частью числа, только если за ней идет цифра,
+	// иначе это точка в конце программы
+	if r, _ := l.peekRune(); r == '.' && unicode.IsDigit(l.peekNext()) {
+		l.advance()
+		l.skipDigits()
+	}
+
+	// Порядок: E или e, необязательный знак и цифры
+	if r, _ := l.peekRune(); r == 'E' || r == 'e' {
+		save := l.pos
+		l.advance()
+		if r, _ := l.peekRune(); r == '+' || r == '-' {
+			l.advance()
+		}
+		if r, _ := l.peekRune(); unicode.IsDigit(r) {
+			l.skipDigits()
+		} else {
+			l.pos = save
+		}
+	}
+
+	l.emit(TokenNUMBER)
+}
+
+// readString читает строковый литерал в апострофах; апостроф внутри строки удваивается: 'it''s'.
+// Значение токена - исходный текст литерала вместе с апострофами.
+func (l *Lexer) readString() error {
+	l.advance() // пропускаем открывающий апостроф
+	for {
+		r, size := l.peekRune()
+		if size == 0 || r == '\n' {
+			return l.errorf(l.start, "незакрытая строка")
+		}
+		l.advance()
+		if r != '\'' {
+			continue
+		}
+		if next, _ := l.peekRune(); next != '\'' {
+			break
+		}
+		l.advance() // удвоенный апостроф
+	}
+	l.emit(TokenSTRING)
+	return nil
+}
+
+func (l *Lexer) skipDigits() {
 	for l.pos < len(l.input) {
 		r, size := l.peekRune()
 		if size == 0 || !unicode.IsDigit(r) {
@@ -140,30 +412,53 @@ func (l *Lexer) readNumber() {
 		}
 		l.advance()
 	}
-	l.emit(TokenNUMBER)
 }
 
+// readIdentifier читает идентификатор или ключевое слово. Знак подчеркивания
+// стандарт ISO 7185 в идентификаторах не допускает.
 func (l *Lexer) readIdentifier() {
 	for l.pos < len(l.input) {
 		r, size := l.peekRune()
-		if size == 0 || (!unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+		if size == 0 || (!unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_') {
 			break
 		}
 		l.advance()
 	}
 	value := l.input[l.start:l.pos]
+	if l.Dialect == DialectISO && strings.ContainsRune(value, '_') {
+		l.deviate(ruleIdentifier, l.position(l.start), "знак подчеркивания в идентификаторе %s", value)
+	}
 	
 	// Проверяем ключевые слова
-	switch value {
-	case "BEGIN":
-		l.emit(TokenBEGIN)
-	case "END":
-		l.emit(TokenEND)
-	default:
+	if t, ok := keywords[strings.ToUpper(value)]; ok {
+		l.emit(t)
+	} else {
 		l.emit(TokenIDENTIFIER)
 	}
 }
 
+// SyntaxError - ошибка лексического или синтаксического анализа в тексте. Error добавляет
+// к сообщению смещение в байтах, а Pos - та же позиция в строках и столбцах для редактора.
+type SyntaxError struct {
+	Message string
+	Offset  int
+	Pos     Position
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s на позиции %d", e.Message, e.Offset)
+}
+
+// errorf создает ошибку лексического анализа по смещению offset
+func (l *Lexer) errorf(offset int, format string, args ...interface{}) error {
+	return &SyntaxError{Message: fmt.Sprintf(format, args...), Offset: offset, Pos: l.position(offset)}
+}
+
+// deviate запоминает отклонение от правила rule в позиции pos
+func (l *Lexer) deviate(rule isoRule, pos Position, format string, args ...interface{}) {
+	l.Deviations = append(l.Deviations, deviation(rule, pos, format, args...))
+}
+
 func (l *Lexer) peekRune() (rune, int) {
 	if l.pos >= len(l.input) {
 		return 0, 0
@@ -188,11 +483,35 @@ func (l *Lexer) advance() {
 
 func (l *Lexer) emit(t TokenType) {
 	value := l.input[l.start:l.pos]
+	position := l.position(l.start)
 	l.tokens = append(l.tokens, Token{
-		Type:  t,
-		Value: value,
-		Pos:   l.start,
+		Type:   t,
+		Value:  value,
+		Pos:    l.start,
+		File:   l.File,
+		Line:   position.Line,
+		Column: position.Column,
 	})
 	l.start = l.pos
 }
 
+// position вычисляет строку и столбец для смещения offset.
+// Смещения токенов возрастают, поэтому входная строка просматривается один раз.
+func (l *Lexer) position(offset int) Position {
+	if offset < l.scanned {
+		l.line, l.lineStart, l.scanned = 0, 0, 0
+	}
+	for l.scanned < offset && l.scanned < len(l.input) {
+		if l.input[l.scanned] == '\n' {
+			l.line++
+			l.lineStart = l.scanned + 1
+		}
+		l.scanned++
+	}
+	return Position{
+		File:   l.File,
+		Line:   l.line + 1,
+		Column: utf8.RuneCountInString(l.input[l.lineStart:l.scanned]) + 1,
+	}
+}
+
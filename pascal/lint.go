@@ -0,0 +1,554 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// lintRule - правило проверки стиля и подозрительных мест программы
+type lintRule struct {
+	Name        string
+	Description string
+}
+
+// lintRules - правила pascal lint в порядке вывода справки
+var lintRules = []lintRule{
+	{"unread-variable", "локальной переменной присваивается значение, которое не читается"},
+	{"self-assignment", "присваивание переменной самой себе"},
+	{"division-by-zero", "деление на константу ноль"},
+	{"unreachable-code", "операторы после Exit или Halt не выполняются"},
+	{"assign-spacing", "пробел между ':' и '=' в знаке присваивания"},
+	{"shadowed-identifier", "описание скрывает одноименное описание объемлющей области видимости"},
+}
+
+// knownLintRule сообщает, есть ли правило с именем name
+func knownLintRule(name string) bool {
+	for _, rule := range lintRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lintConfig - файл настроек pascal lint: {"rules": {"self-assignment": false}}.
+// Правила, не упомянутые в файле, включены.
+type lintConfig struct {
+	Rules map[string]bool `json:"rules"`
+}
+
+// enabled сообщает, включено ли правило настройками
+func (c lintConfig) enabled(rule string) bool {
+	enabled, ok := c.Rules[rule]
+	return !ok || enabled
+}
+
+// readLintConfig читает файл настроек и проверяет имена правил
+func readLintConfig(path string) (lintConfig, error) {
+	var config lintConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %v", path, err)
+	}
+	for name := range config.Rules {
+		if !knownLintRule(name) {
+			return config, fmt.Errorf("%s: неизвестное правило %q", path, name)
+		}
+	}
+	return config, nil
+}
+
+// lintDiagnostic - замечание pascal lint
+type lintDiagnostic struct {
+	Rule    string
+	Pos     Position
+	Message string
+}
+
+func (d lintDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s [%s]", d.Pos, d.Message, d.Rule)
+}
+
+// linter проверяет программу правилами pascal lint. Проверки используют результаты
+// семантического анализа: имена связываются с описаниями через Info.Symbols.
+type linter struct {
+	info        *Info
+	diagnostics []lintDiagnostic
+}
+
+func (l *linter) report(rule string, pos Position, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, lintDiagnostic{Rule: rule, Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// lintProgram проверяет проверенную анализатором программу и токены ее текста и возвращает
+// замечания включенных правил, упорядоченные по позиции. Директивы {$lint-disable правила}
+// и {$lint-enable правила} выключают и включают правила до конца файла или следующей
+// директивы; директива без имен правил относится ко всем правилам.
+func lintProgram(tokens []Token, program *Program, info *Info, config lintConfig) ([]lintDiagnostic, error) {
+	l := &linter{info: info}
+	l.unreadVariables(program)
+	l.assignments(program)
+	l.unreachableCode(program)
+	l.assignSpacing(tokens)
+	l.shadowedIdentifiers(program)
+
+	directives, err := parseLintDirectives(tokens)
+	if err != nil {
+		return nil, err
+	}
+	var diagnostics []lintDiagnostic
+	for _, d := range l.diagnostics {
+		if config.enabled(d.Rule) && !directives.disabled(d.Rule, d.Pos) {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	sort.SliceStable(diagnostics, func(a, b int) bool {
+		return positionBefore(diagnostics[a].Pos, diagnostics[b].Pos)
+	})
+	return diagnostics, nil
+}
+
+// lintDirective - директива {$lint-disable} или {$lint-enable}
+type lintDirective struct {
+	pos    Position
+	enable bool
+	rules  []string // пустой список - все правила
+}
+
+type lintDirectives []lintDirective
+
+// parseLintDirectives собирает директивы pascal lint из токенов; прочие директивы пропускаются
+func parseLintDirectives(tokens []Token) (lintDirectives, error) {
+	var directives lintDirectives
+	for _, token := range tokens {
+		if token.Type != TokenDIRECTIVE {
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimSuffix(token.Value, "}"), "*)")
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "{$"), "(*$")
+		fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' })
+		if len(fields) == 0 {
+			continue
+		}
+		var directive lintDirective
+		switch strings.ToLower(fields[0]) {
+		case "lint-disable":
+		case "lint-enable":
+			directive.enable = true
+		default:
+			continue
+		}
+		directive.pos = token.Position()
+		for _, rule := range fields[1:] {
+			if !knownLintRule(rule) {
+				return nil, fmt.Errorf("%s: неизвестное правило %q в директиве %s", directive.pos, rule, fields[0])
+			}
+			directive.rules = append(directive.rules, rule)
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+// disabled сообщает, выключено ли правило директивами в позиции pos
+func (directives lintDirectives) disabled(rule string, pos Position) bool {
+	disabled := false
+	for _, directive := range directives {
+		if !positionBefore(directive.pos, pos) {
+			break
+		}
+		if len(directive.rules) == 0 {
+			disabled = !directive.enable
+			continue
+		}
+		for _, name := range directive.rules {
+			if name == rule {
+				disabled = !directive.enable
+			}
+		}
+	}
+	return disabled
+}
+
+// unreadVariables находит локальные переменные подпрограмм, которым присваиваются
+// значения, но которые нигде не читаются. Глобальные переменные программы не
+// проверяются: их значения выводятся после выполнения.
+func (l *linter) unreadVariables(program *Program) {
+	locals := make(map[*Symbol]bool)
+	Inspect(program, func(node Node) bool {
+		decl, ok := node.(*RoutineDecl)
+		if !ok {
+			return true
+		}
+		params := make(map[*Symbol]bool)
+		for _, param := range decl.Params {
+			params[l.info.Symbols[param]] = true
+		}
+		if scope := l.info.Scopes[decl]; scope != nil {
+			for _, symbol := range scope.symbols {
+				if symbol.Kind == SymbolVar && !params[symbol] {
+					locals[symbol] = true
+				}
+			}
+		}
+		return true
+	})
+
+	// Места, в которые записывается значение: переменная присваивания без Target, начало
+	// записи Target вида r.x и аргументы Read и ReadLn
+	written := make(map[*Identifier]bool)
+	assigned := make(map[*Symbol]Position)
+	write := func(symbol *Symbol, pos Position) {
+		if _, ok := assigned[symbol]; !ok && symbol != nil {
+			assigned[symbol] = pos
+		}
+	}
+	Inspect(program, func(node Node) bool {
+		switch n := node.(type) {
+		case *Assignment:
+			if n.Target == nil {
+				write(l.info.Symbols[n], n.Pos)
+			} else if id := fieldRoot(n.Target); id != nil {
+				written[id] = true
+				write(l.info.Symbols[id], n.Pos)
+			}
+		case *CallStatement:
+			name := strings.ToLower(n.Name)
+			if l.info.Symbols[n] == nil && (name == "read" || name == "readln") {
+				for _, arg := range n.Args {
+					if id, ok := arg.(*Identifier); ok {
+						written[id] = true
+						write(l.info.Symbols[id], n.Pos)
+					}
+				}
+			}
+		}
+		return true
+	})
+	read := make(map[*Symbol]bool)
+	Inspect(program, func(node Node) bool {
+		if id, ok := node.(*Identifier); ok && !written[id] {
+			read[l.info.Symbols[id]] = true
+		}
+		return true
+	})
+	for symbol, pos := range assigned {
+		if locals[symbol] && !read[symbol] {
+			l.report("unread-variable", pos, "значение переменной %s не читается", symbol.Name)
+		}
+	}
+}
+
+// fieldRoot возвращает переменную, с которой начинается запись поля r.x.y; nil, если
+// запись проходит через указатель: присваивание p^.x читает p
+func fieldRoot(expr Expression) *Identifier {
+	for {
+		switch e := expr.(type) {
+		case *FieldAccess:
+			expr = e.Record
+		case *Identifier:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// assignments проверяет присваивания самой себе и деление на константу ноль
+func (l *linter) assignments(program *Program) {
+	Inspect(program, func(node Node) bool {
+		switch n := node.(type) {
+		case *Assignment:
+			if n.Target == nil {
+				if id, ok := n.Value.(*Identifier); ok && l.sameVariable(n, id, n.Variable) {
+					l.report("self-assignment", n.Pos, "присваивание переменной %s самой себе", n.Variable)
+				}
+			} else if isDesignator(n.Value) && strings.EqualFold(n.Target.String(), n.Value.String()) {
+				l.report("self-assignment", n.Pos, "присваивание %s самому себе", designatorName(n.Target))
+			}
+		case *BinaryOp:
+			if n.Operator != TokenDIV && n.Operator != TokenMOD && n.Operator != TokenDIVIDE {
+				return true
+			}
+			if value, ok := constantValue(n.Right); ok && isZero(value) {
+				l.report("division-by-zero", n.Pos, "деление на константу ноль в операции %s", operatorSymbol(n.Operator))
+			}
+		}
+		return true
+	})
+}
+
+// sameVariable сообщает, обозначает ли идентификатор id ту же переменную name, что и
+// присваивание. Неописанная переменная, прочитанная до первого присваивания, не имеет
+// символа, поэтому тогда имена сравниваются без учета регистра.
+func (l *linter) sameVariable(assignment *Assignment, id *Identifier, name string) bool {
+	target, value := l.info.Symbols[assignment], l.info.Symbols[id]
+	if target != nil && value != nil {
+		return target == value && target.Kind == SymbolVar
+	}
+	return strings.EqualFold(id.Name, name)
+}
+
+// isZero сообщает, равно ли числовое значение нулю
+func isZero(value Value) bool {
+	switch v := value.(type) {
+	case IntegerValue:
+		return v == 0
+	case RealValue:
+		return v == 0
+	}
+	return false
+}
+
+// unreachableCode находит операторы, следующие в списке за Exit или Halt
+func (l *linter) unreachableCode(program *Program) {
+	check := func(statements []Statement) {
+		for n, stmt := range statements[:max(0, len(statements)-1)] {
+			if jump := l.terminator(stmt); jump != "" {
+				l.report("unreachable-code", statementPos(statements[n+1]), "недостижимый код после %s", jump)
+				return
+			}
+		}
+	}
+	check(program.Statements)
+	Inspect(program, func(node Node) bool {
+		if block, ok := node.(*Block); ok {
+			check(block.Statements)
+		}
+		return true
+	})
+}
+
+// terminator возвращает имя оператора Exit или стандартной процедуры Halt, после которых
+// операторы списка не выполняются; пустая строка - оператор не завершает список
+func (l *linter) terminator(stmt Statement) string {
+	switch s := stmt.(type) {
+	case *ExitStatement:
+		return "Exit"
+	case *CallStatement:
+		if l.info.Symbols[s] == nil && strings.EqualFold(s.Name, "halt") {
+			return "Halt"
+		}
+	}
+	return ""
+}
+
+// assignSpacing находит знаки присваивания, записанные с пробелом: ': ='
+func (l *linter) assignSpacing(tokens []Token) {
+	for _, token := range tokens {
+		if token.Type == TokenASSIGN && token.Value != ":=" {
+			l.report("assign-spacing", token.Position(), "знак присваивания записан как %q, ожидалось \":=\"", token.Value)
+		}
+	}
+}
+
+// shadowedIdentifiers находит описания подпрограмм и программы, скрывающие одноименные
+// описания объемлющих областей видимости: подпрограмм, программы и модулей USES.
+// Стандартные имена не учитываются.
+func (l *linter) shadowedIdentifiers(program *Program) {
+	check := func(scope *Scope) {
+		if scope == nil || scope.parent == nil {
+			return
+		}
+		for _, symbol := range scope.symbols {
+			if symbol.Implicit || !symbol.Pos.IsValid() {
+				continue
+			}
+			outer := scope.parent.Lookup(symbol.Name)
+			if outer != nil && outer != symbol && outer.Pos.IsValid() {
+				l.report("shadowed-identifier", symbol.Pos, "%s скрывает одноименное описание (%s)", symbol.Name, outer.Pos)
+			}
+		}
+	}
+	check(l.info.Scopes[program])
+	Inspect(program, func(node Node) bool {
+		if decl, ok := node.(*RoutineDecl); ok {
+			check(l.info.Scopes[decl])
+		}
+		return true
+	})
+}
+
+// lintFile проверяет программу из файла: замечания и ошибку загрузки
+func lintFile(filename, units string, config lintConfig) ([]lintDiagnostic, error) {
+	code, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	tokens, err := NewLexer(string(code)).Tokenize()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка лексического анализа: %v", err)
+	}
+	info := NewInfo()
+	program, err := load(filename, units, info)
+	if err != nil {
+		return nil, err
+	}
+	return lintProgram(tokens, program, info, config)
+}
+
+// writeLintText выводит замечания по одному в строке: файл, позиция, сообщение и правило
+func writeLintText(w io.Writer, filename string, diagnostics []lintDiagnostic) {
+	for _, d := range diagnostics {
+		fmt.Fprintf(w, "%s: %s\n", filename, d)
+	}
+}
+
+// Отчет SARIF 2.1.0 (Static Analysis Results Interchange Format)
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// writeLintSARIF записывает замечания файлов в формате SARIF; files - замечания по именам
+// файлов в порядке names
+func writeLintSARIF(w io.Writer, names []string, files map[string][]lintDiagnostic) error {
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "pascal lint"}}, Results: []sarifResult{}}
+	for _, rule := range lintRules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: rule.Name, ShortDescription: sarifMessage{rule.Description}})
+	}
+	for _, name := range names {
+		for _, d := range files[name] {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  d.Rule,
+				Level:   "warning",
+				Message: sarifMessage{d.Message},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifact{URI: filepath.ToSlash(name)},
+					Region:           sarifRegion{StartLine: d.Pos.Line, StartColumn: d.Pos.Column},
+				}}},
+			})
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// lintUsage - строка использования команды pascal lint
+const lintUsage = "Использование: pascal lint [-config файл.json] [-format text|sarif] [-units каталоги] <файл.pas>..."
+
+// lintWithExitCode выполняет команду pascal lint и возвращает код выхода: 1, если есть
+// замечания или ошибки
+func lintWithExitCode(args []string) int {
+	var configPath, format, units string
+	flags := flag.NewFlagSet("pascal lint", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&configPath, "config", "", "файл настроек JSON, включающий и выключающий правила")
+	flags.StringVar(&format, "format", "text", "формат вывода: text или sarif")
+	flags.StringVar(&units, "units", "", "каталоги поиска модулей USES, разделенные '"+string(os.PathListSeparator)+"'")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), lintUsage)
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "Правила:")
+		for _, rule := range lintRules {
+			fmt.Fprintf(flags.Output(), "  %-20s %s\n", rule.Name, rule.Description)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() < 1 {
+		fmt.Println(lintUsage)
+		return 1
+	}
+	if format != "text" && format != "sarif" {
+		fmt.Fprintf(os.Stderr, "неизвестный формат %q: ожидался text или sarif\n", format)
+		return 1
+	}
+	var config lintConfig
+	if configPath != "" {
+		var err error
+		if config, err = readLintConfig(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+	}
+
+	code := 0
+	files := make(map[string][]lintDiagnostic)
+	for _, name := range flags.Args() {
+		diagnostics, err := lintFile(name, units, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			code = 1
+			continue
+		}
+		if len(diagnostics) > 0 {
+			code = 1
+		}
+		files[name] = diagnostics
+		if format == "text" {
+			writeLintText(os.Stdout, name, diagnostics)
+		}
+	}
+	if format == "sarif" {
+		if err := writeLintSARIF(os.Stdout, flags.Args(), files); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка записи отчета: %v\n", err)
+			return 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lintCode проверяет программу правилами pascal lint с настройками config
+func lintCode(t *testing.T, code string, config lintConfig) ([]lintDiagnostic, error) {
+	t.Helper()
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	program := parseCode(t, code)
+	checker := NewChecker()
+	checker.Info = NewInfo()
+	if err := checker.Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	return lintProgram(tokens, program, checker.Info, config)
+}
+
+// diagnosticLines форматирует замечания по одному в строке
+func diagnosticLines(diagnostics []lintDiagnostic) string {
+	var lines strings.Builder
+	for _, d := range diagnostics {
+		lines.WriteString(d.String() + "\n")
+	}
+	return lines.String()
+}
+
+// TestLintRules тестирует правила pascal lint по отдельности
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"unread-variable", `PROCEDURE P(n: INTEGER);
+VAR unused, used, field: INTEGER; r: RECORD x: INTEGER END; p: ^INTEGER;
+BEGIN
+  unused := n; unused := 2;
+  used := n; WriteLn(used);
+  r.x := 1;
+  New(p); p^ := 1;
+  ReadLn(field);
+  n := 3
+END;
+BEGIN global := 1; P(1) END.`,
+			"строка 4, столбец 3: значение переменной unused не читается [unread-variable]\n" +
+				"строка 6, столбец 3: значение переменной r не читается [unread-variable]\n" +
+				"строка 8, столбец 3: значение переменной field не читается [unread-variable]\n"},
+		// Чтение во вложенной подпрограмме и передача в VAR-параметр - тоже чтение
+		{"unread-variable", `PROCEDURE Inc(VAR n: INTEGER); BEGIN n := n + 1 END;
+PROCEDURE Outer;
+VAR a, b: INTEGER;
+  PROCEDURE Show; BEGIN WriteLn(a) END;
+BEGIN
+  a := 1; b := 2; Inc(b); Show
+END;
+BEGIN Outer END.`, ""},
+		{"self-assignment", `TYPE Pair = RECORD x: INTEGER END;
+VAR a, b: INTEGER; r: Pair; p: ^Pair;
+BEGIN
+  a := a; A := a; a := b;
+  r.x := r.x; r.x := a;
+  New(p); p^.x := p^.x;
+  Dispose(p)
+END.`,
+			"строка 4, столбец 3: присваивание переменной a самой себе [self-assignment]\n" +
+				"строка 4, столбец 11: присваивание переменной A самой себе [self-assignment]\n" +
+				"строка 5, столбец 3: присваивание r.x самому себе [self-assignment]\n" +
+				"строка 6, столбец 11: присваивание p^.x самому себе [self-assignment]\n"},
+		{"division-by-zero", `CONST Zero = 0; Half = 0.5;
+VAR a: INTEGER; x: REAL;
+BEGIN
+  a := 7;
+  x := a / Half;
+  a := a DIV 1;
+  IF a > 100 THEN a := a MOD Zero;
+  IF a > 100 THEN x := a / 0.0
+END.`,
+			"строка 7, столбец 26: деление на константу ноль в операции MOD [division-by-zero]\n" +
+				"строка 8, столбец 26: деление на константу ноль в операции / [division-by-zero]\n"},
+		{"unreachable-code", `PROCEDURE P;
+BEGIN
+  WriteLn(1);
+  Exit;
+  WriteLn(2);
+  WriteLn(3)
+END;
+BEGIN
+  P;
+  IF FALSE THEN BEGIN Halt(2); WriteLn(4) END;
+  REPEAT Halt UNTIL TRUE;
+  WriteLn(5)
+END.`,
+			"строка 5, столбец 3: недостижимый код после Exit [unreachable-code]\n" +
+				"строка 10, столбец 32: недостижимый код после Halt [unreachable-code]\n"},
+		{"assign-spacing", "VAR a: INTEGER;\nBEGIN\n  a :=1;\n  a : = 2;\n  a :\n= 3\nEND.",
+			"строка 4, столбец 5: знак присваивания записан как \": =\", ожидалось \":=\" [assign-spacing]\n" +
+				"строка 5, столбец 5: знак присваивания записан как \":\\n=\", ожидалось \":=\" [assign-spacing]\n"},
+		{"shadowed-identifier", `CONST Limit = 10;
+TYPE Color = (Red, Green);
+VAR count: INTEGER;
+PROCEDURE Outer(count: INTEGER);
+VAR Limit: INTEGER;
+  FUNCTION Inner(x: INTEGER): INTEGER;
+  TYPE Color = INTEGER;
+  VAR Red: INTEGER;
+  BEGIN Inner := x; Red := 0; Limit := Red END;
+BEGIN
+  Limit := Inner(count)
+END;
+FUNCTION Length(s: STRING): INTEGER; BEGIN Length := 0 END;
+BEGIN count := Limit; Outer(1) END.`,
+			"строка 4, столбец 17: count скрывает одноименное описание (строка 3, столбец 5) [shadowed-identifier]\n" +
+				"строка 5, столбец 5: Limit скрывает одноименное описание (строка 1, столбец 7) [shadowed-identifier]\n" +
+				"строка 7, столбец 8: Color скрывает одноименное описание (строка 2, столбец 6) [shadowed-identifier]\n" +
+				"строка 8, столбец 7: Red скрывает одноименное описание (строка 2, столбец 14) [shadowed-identifier]\n"},
+	}
+	for _, tt := range tests {
+		config := lintConfig{Rules: make(map[string]bool)}
+		for _, rule := range lintRules {
+			config.Rules[rule.Name] = rule.Name == tt.name
+		}
+		diagnostics, err := lintCode(t, tt.code, config)
+		if err != nil {
+			t.Fatalf("%s: неожиданная ошибка: %v", tt.name, err)
+		}
+		if got := diagnosticLines(diagnostics); got != tt.want {
+			t.Errorf("%s %q: ожидалось\n%s\nполучено\n%s", tt.name, tt.code, tt.want, got)
+		}
+	}
+}
+
+// TestLintDirectives тестирует выключение правил директивами и настройками
+func TestLintDirectives(t *testing.T) {
+	code := `VAR a: INTEGER;
+BEGIN
+  a := a;
+  {$lint-disable self-assignment}
+  a := a;
+  a : = 1;
+  {$lint-disable}
+  a : = a;
+  {$lint-enable assign-spacing, self-assignment}
+  a : = a;
+  {$lint-enable}{$R-}
+  a := a DIV 0
+END.`
+	diagnostics, err := lintCode(t, code, lintConfig{})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	want := "строка 3, столбец 3: присваивание переменной a самой себе [self-assignment]\n" +
+		"строка 6, столбец 5: знак присваивания записан как \": =\", ожидалось \":=\" [assign-spacing]\n" +
+		"строка 10, столбец 3: присваивание переменной a самой себе [self-assignment]\n" +
+		"строка 10, столбец 5: знак присваивания записан как \": =\", ожидалось \":=\" [assign-spacing]\n" +
+		"строка 12, столбец 10: деление на константу ноль в операции DIV [division-by-zero]\n"
+	if got := diagnosticLines(diagnostics); got != want {
+		t.Errorf("Ожидалось\n%s\nполучено\n%s", want, got)
+	}
+
+	// Правило, выключенное настройками, не включается директивой lint-enable без имен
+	diagnostics, err = lintCode(t, code, lintConfig{Rules: map[string]bool{"self-assignment": false, "division-by-zero": true}})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if got := diagnosticLines(diagnostics); strings.Contains(got, "self-assignment") || !strings.Contains(got, "division-by-zero") {
+		t.Errorf("Ожидались замечания без self-assignment, получено\n%s", got)
+	}
+
+	if _, err := lintCode(t, "BEGIN {$lint-disable no-such-rule} x := 1 END.", lintConfig{}); err == nil ||
+		err.Error() != `строка 1, столбец 7: неизвестное правило "no-such-rule" в директиве lint-disable` {
+		t.Errorf("Ожидалась ошибка неизвестного правила, получено %v", err)
+	}
+}
+
+// TestMainLint тестирует команду pascal lint: вывод text и SARIF, настройки и ошибки
+func TestMainLint(t *testing.T) {
+	dir := t.TempDir()
+	writeSuite(t, dir, map[string]string{
+		"bad.pas":     "BEGIN\n  x := x;\n  y : = 1\nEND.",
+		"good.pas":    "BEGIN x := 1 END.",
+		"broken.pas":  "BEGIN x := END.",
+		"config.json": `{"rules": {"assign-spacing": false}}`,
+		"wrong.json":  `{"rules": {"spacing": false}}`,
+	})
+	bad, good, broken := filepath.Join(dir, "bad.pas"), filepath.Join(dir, "good.pas"), filepath.Join(dir, "broken.pas")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	runLint := func(args ...string) (int, string, string) {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal", "lint"}, args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+		return code, stdout.String(), stderr.String()
+	}
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{good}, 0, "", ""},
+		{[]string{good, bad}, 1, bad + ": строка 2, столбец 3: присваивание переменной x самой себе [self-assignment]\n" +
+			bad + ": строка 3, столбец 5: знак присваивания записан как \": =\", ожидалось \":=\" [assign-spacing]\n", ""},
+		{[]string{"-config", filepath.Join(dir, "config.json"), bad}, 1,
+			bad + ": строка 2, столбец 3: присваивание переменной x самой себе [self-assignment]\n", ""},
+		{[]string{"-config", filepath.Join(dir, "wrong.json"), bad}, 1, "", filepath.Join(dir, "wrong.json") + ": неизвестное правило \"spacing\"\n"},
+		{[]string{broken, good}, 1, "", broken + ": ошибка синтаксического анализа: "},
+		{[]string{"-format", "xml", good}, 1, "", "неизвестный формат \"xml\": ожидался text или sarif\n"},
+		{nil, 1, lintUsage + "\n", ""},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runLint(tt.args...)
+		if code != tt.code || stdout != tt.stdout || !strings.HasPrefix(stderr, tt.stderr) || (tt.stderr == "") != (stderr == "") {
+			t.Errorf("%v: ожидались код %d, вывод %q, ошибки %q; получено %d, %q, %q", tt.args, tt.code, tt.stdout, tt.stderr, code, stdout, stderr)
+		}
+	}
+
+	code, stdout, _ := runLint("-format", "sarif", good, bad)
+	if code != 1 {
+		t.Errorf("ожидался код выхода 1, получено %d", code)
+	}
+	var report struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("отчет SARIF не разбирается: %v\n%s", err, stdout)
+	}
+	if report.Version != "2.1.0" || len(report.Runs) != 1 || len(report.Runs[0].Tool.Driver.Rules) != len(lintRules) || len(report.Runs[0].Results) != 2 {
+		t.Fatalf("неожиданный отчет SARIF:\n%s", stdout)
+	}
+	result := report.Runs[0].Results[1]
+	location := result.Locations[0].PhysicalLocation
+	if result.RuleID != "assign-spacing" || location.ArtifactLocation.URI != filepath.ToSlash(bad) ||
+		location.Region.StartLine != 3 || location.Region.StartColumn != 5 {
+		t.Errorf("неожиданное замечание SARIF: %+v", result)
+	}
+}
@@ -0,0 +1,885 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Сервер языка (Language Server Protocol) для редакторов: JSON-RPC 2.0 через stdin и stdout.
+// Каждое изменение документа применяется к его тексту, после чего документ заново проходит
+// лексический, синтаксический и семантический анализ; результаты анализа отвечают на
+// запросы подсказок, перехода к описанию, структуры документа и автодополнения.
+
+// Коды ошибок JSON-RPC
+const (
+	lspParseError     = -32700
+	lspInvalidRequest = -32600
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// Значения перечислений LSP
+const (
+	lspSeverityError   = 1
+	lspSyncIncremental = 2
+	lspMarkdown        = "markdown"
+)
+
+// lspMessage - запрос или уведомление JSON-RPC
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // в кодовых единицах UTF-16
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspCompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type lspTextDocument struct {
+	URI     string `json:"uri"`
+	Text    string `json:"text"`
+	Version int    `json:"version"`
+}
+
+type lspContentChange struct {
+	Range *lspRange `json:"range"` // nil - новый текст всего документа
+	Text  string    `json:"text"`
+}
+
+// lspDocumentParams - параметры уведомлений об открытии, изменении и закрытии документа
+type lspDocumentParams struct {
+	TextDocument   lspTextDocument    `json:"textDocument"`
+	ContentChanges []lspContentChange `json:"contentChanges"`
+}
+
+// lspPositionParams - параметры запросов о позиции в документе
+type lspPositionParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Position     lspPosition     `json:"position"`
+}
+
+// Виды символов документа и элементов автодополнения (SymbolKind и CompletionItemKind LSP)
+const (
+	lspSymbolClass         = 5
+	lspSymbolEnum          = 10
+	lspSymbolFunction      = 12
+	lspSymbolVariable      = 13
+	lspSymbolConstant      = 14
+	lspSymbolEnumMember    = 22
+	lspSymbolStruct        = 23
+	lspSymbolTypeParameter = 26
+
+	lspCompletionFunction = 3
+	lspCompletionVariable = 6
+	lspCompletionClass    = 7
+	lspCompletionKeyword  = 14
+	lspCompletionConstant = 21
+)
+
+// lspStandardProcedures - стандартные процедуры, которые не описаны в областях видимости
+var lspStandardProcedures = []string{"Write", "WriteLn", "Read", "ReadLn", "New", "Dispose",
+	"Assign", "Reset", "Rewrite", "Append", "Close", "Assert", "Halt", "Break", "Continue", "Exit"}
+
+// lspDocument - открытый в редакторе документ и результаты его последнего анализа
+type lspDocument struct {
+	uri   string
+	text  string
+	lines []string
+
+	tokens  []Token
+	program *Program // nil для модуля или если документ не разобран
+	unit    *Unit
+	info    *Info
+	symbols map[Position]*Symbol // имена по позициям их записей в документе
+
+	diagnostics []lspDiagnostic
+}
+
+// lspServer обслуживает один сеанс редактора
+type lspServer struct {
+	in        *bufio.Reader
+	out       io.Writer
+	units     []string // каталоги поиска модулей после каталога документа
+	documents map[string]*lspDocument
+	shutdown  bool
+}
+
+// serveLSP обслуживает редактор до уведомления exit или конца ввода и возвращает код выхода:
+// 0, если exit получено после запроса shutdown
+func serveLSP(in io.Reader, out io.Writer, units []string) int {
+	s := &lspServer{in: bufio.NewReader(in), out: out, units: units, documents: make(map[string]*lspDocument)}
+	for {
+		body, err := s.read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				fmt.Fprintf(os.Stderr, "pascal lsp: %v\n", err)
+			}
+			return 1
+		}
+		message := &lspMessage{}
+		if err := json.Unmarshal(body, message); err != nil {
+			s.reply(nil, nil, &lspError{lspParseError, err.Error()})
+			continue
+		}
+		if message.Method == "exit" {
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+		s.handle(message)
+	}
+}
+
+// read читает тело сообщения с заголовком Content-Length
+func (s *lspServer) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("неверный заголовок %q", line)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("нет заголовка Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// send записывает сообщение с заголовком Content-Length
+func (s *lspServer) send(message interface{}) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// reply отвечает на запрос с идентификатором id результатом или ошибкой
+func (s *lspServer) reply(id *json.RawMessage, result interface{}, err *lspError) {
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if err != nil {
+		response["error"] = err
+	} else {
+		response["result"] = result
+	}
+	s.send(response)
+}
+
+// notify отправляет редактору уведомление
+func (s *lspServer) notify(method string, params interface{}) {
+	s.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// handle выполняет запрос или уведомление
+func (s *lspServer) handle(message *lspMessage) {
+	var result interface{}
+	var err *lspError
+	switch message.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":       map[string]interface{}{"openClose": true, "change": lspSyncIncremental},
+				"hoverProvider":          true,
+				"definitionProvider":     true,
+				"documentSymbolProvider": true,
+				"completionProvider":     map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "pascal lsp"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didClose":
+		var params lspDocumentParams
+		if json.Unmarshal(message.Params, &params) == nil {
+			s.update(message.Method, params)
+		}
+	case "textDocument/hover", "textDocument/definition", "textDocument/documentSymbol", "textDocument/completion":
+		var params lspPositionParams
+		if e := json.Unmarshal(message.Params, &params); e != nil {
+			err = &lspError{lspInvalidParams, e.Error()}
+			break
+		}
+		doc := s.documents[params.TextDocument.URI]
+		if doc == nil {
+			err = &lspError{lspInvalidParams, "документ не открыт: " + params.TextDocument.URI}
+			break
+		}
+		switch message.Method {
+		case "textDocument/hover":
+			result = doc.hover(params.Position)
+		case "textDocument/definition":
+			result = doc.definition(params.Position)
+		case "textDocument/documentSymbol":
+			result = doc.documentSymbols()
+		default:
+			result = doc.completion(params.Position)
+		}
+	default:
+		if message.ID != nil {
+			err = &lspError{lspMethodNotFound, "неизвестный метод " + message.Method}
+		}
+	}
+	if message.ID != nil {
+		if message.Method == "" {
+			err = &lspError{lspInvalidRequest, "нет метода"}
+		}
+		s.reply(message.ID, result, err)
+	}
+}
+
+// update открывает, изменяет или закрывает документ и публикует его диагностику
+func (s *lspServer) update(method string, params lspDocumentParams) {
+	uri := params.TextDocument.URI
+	doc := s.documents[uri]
+	switch method {
+	case "textDocument/didOpen":
+		doc = &lspDocument{uri: uri, text: params.TextDocument.Text}
+		s.documents[uri] = doc
+	case "textDocument/didChange":
+		if doc == nil {
+			return
+		}
+		for _, change := range params.ContentChanges {
+			doc.edit(change)
+		}
+	default:
+		delete(s.documents, uri)
+		s.notify("textDocument/publishDiagnostics", map[string]interface{}{"uri": uri, "diagnostics": []lspDiagnostic{}})
+		return
+	}
+	doc.analyze(s.units)
+	s.notify("textDocument/publishDiagnostics", map[string]interface{}{"uri": uri, "diagnostics": doc.diagnostics})
+}
+
+// edit применяет к тексту документа изменение из didChange
+func (d *lspDocument) edit(change lspContentChange) {
+	if change.Range == nil {
+		d.text = change.Text
+	} else {
+		d.lines = strings.Split(d.text, "\n")
+		start, end := d.offset(change.Range.Start), d.offset(change.Range.End)
+		if end < start {
+			start, end = end, start
+		}
+		d.text = d.text[:start] + change.Text + d.text[end:]
+	}
+	d.lines = strings.Split(d.text, "\n")
+}
+
+// utf16Len возвращает число кодовых единиц UTF-16, которыми записывается символ
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// offset возвращает смещение в байтах позиции LSP
+func (d *lspDocument) offset(pos lspPosition) int {
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	offset := 0
+	for _, line := range d.lines[:pos.Line] {
+		offset += len(line) + 1
+	}
+	units := 0
+	for n, r := range d.lines[pos.Line] {
+		if units >= pos.Character {
+			return offset + n
+		}
+		units += utf16Len(r)
+	}
+	return offset + len(d.lines[pos.Line])
+}
+
+// position переводит позицию в исходном тексте в позицию LSP
+func (d *lspDocument) position(pos Position) lspPosition {
+	line := pos.Line - 1
+	if line < 0 || line >= len(d.lines) {
+		return lspPosition{Line: max(line, 0)}
+	}
+	character, column := 0, 1
+	for _, r := range d.lines[line] {
+		if column >= pos.Column {
+			break
+		}
+		character += utf16Len(r)
+		column++
+	}
+	return lspPosition{Line: line, Character: character}
+}
+
+// cursor переводит позицию LSP в позицию в исходном тексте
+func (d *lspDocument) cursor(pos lspPosition) Position {
+	if pos.Line >= len(d.lines) {
+		return Position{Line: pos.Line + 1, Column: 1}
+	}
+	units, column := 0, 1
+	for _, r := range d.lines[pos.Line] {
+		if units >= pos.Character {
+			break
+		}
+		units += utf16Len(r)
+		column++
+	}
+	return Position{Line: pos.Line + 1, Column: column}
+}
+
+// tokenAt возвращает индекс токена, который начинается в позиции pos, или -1
+func (d *lspDocument) tokenAt(pos Position) int {
+	n := sort.Search(len(d.tokens), func(n int) bool {
+		t := d.tokens[n]
+		return t.Line > pos.Line || t.Line == pos.Line && t.Column >= pos.Column
+	})
+	if n < len(d.tokens) && d.tokens[n].Line == pos.Line && d.tokens[n].Column == pos.Column && d.tokens[n].Type != TokenEOF {
+		return n
+	}
+	return -1
+}
+
+// span возвращает диапазон токена, который начинается в позиции pos; если токена нет -
+// диапазон одного символа
+func (d *lspDocument) span(pos Position) lspRange {
+	start := d.position(pos)
+	length := 1
+	if n := d.tokenAt(pos); n >= 0 {
+		length = utf8.RuneCountInString(d.tokens[n].Value)
+	}
+	return lspRange{Start: start, End: d.position(Position{Line: pos.Line, Column: pos.Column + length})}
+}
+
+// identifierAt возвращает позицию идентификатора, на котором стоит курсор
+func (d *lspDocument) identifierAt(pos lspPosition) (Position, bool) {
+	cursor := d.cursor(pos)
+	for _, t := range d.tokens {
+		if t.Line == cursor.Line && t.Type == TokenIDENTIFIER &&
+			t.Column <= cursor.Column && cursor.Column <= t.Column+utf8.RuneCountInString(t.Value) {
+			return t.Position(), true
+		}
+	}
+	return Position{}, false
+}
+
+// analyze заново разбирает и проверяет документ. Если документ не удалось разобрать,
+// подсказки и переходы используют результаты предыдущего анализа.
+func (d *lspDocument) analyze(units []string) {
+	d.lines = strings.Split(d.text, "\n")
+	d.diagnostics = []lspDiagnostic{}
+	defer func() {
+		// Внутренняя ошибка анализатора не должна завершать сеанс редактора
+		if r := recover(); r != nil {
+			d.report(Position{Line: 1, Column: 1}, fmt.Sprintf("внутренняя ошибка анализатора: %v", r))
+		}
+	}()
+
+	lexer := NewLexer(d.text)
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		d.reportError(err)
+		return
+	}
+	d.tokens = tokens
+	path := d.uri
+	if u, err := url.Parse(d.uri); err == nil && u.Scheme == "file" {
+		path = u.Path
+	}
+	loader := NewUnitLoader(append([]string{filepath.Dir(path)}, units...)...)
+	parser := NewParserWithUnits(tokens, loader)
+	var program *Program
+	var unit *Unit
+	if len(tokens) > 0 && tokens[0].Type == TokenUNIT {
+		unit, err = parser.ParseUnit()
+	} else {
+		program, err = parser.Parse()
+	}
+	if err != nil {
+		d.reportError(err)
+		return
+	}
+
+	info := NewInfo()
+	checker := NewChecker()
+	checker.Info = info
+	if program != nil {
+		checker.Check(program)
+	} else {
+		checker.checkUnit(unit)
+	}
+	for _, e := range checker.Errors() {
+		if e.Pos.File != "" {
+			// Ошибка в подключенном модуле отмечается в предложении USES
+			d.report(d.usesPosition(program, unit, e.Pos.File), e.Error())
+			continue
+		}
+		d.report(e.Pos, e.Message)
+	}
+
+	d.program, d.unit, d.info = program, unit, info
+	d.index()
+}
+
+// reportError добавляет диагностики ошибки лексического или синтаксического анализа в
+// позициях, которые хранят сами ошибки
+func (d *lspDocument) reportError(err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			d.reportError(err)
+		}
+		return
+	}
+	var unitErr *unitError
+	var syntaxErr *SyntaxError
+	var deviation *DialectError
+	switch {
+	case errors.As(err, &unitErr) && unitErr.pos.IsValid() && unitErr.pos.File == "":
+		// Модуль не удалось подключить: ошибка отмечается на его имени в USES
+		d.report(unitErr.pos, unitErr.err.Error())
+	case errors.As(err, &unitErr):
+		// Ошибка в тексте модуля: ее позиция относится к файлу модуля
+		d.report(d.nextToken(d.usesToken()), err.Error())
+	case errors.As(err, &syntaxErr):
+		d.report(syntaxErr.Pos, syntaxErr.Message)
+	case errors.As(err, &deviation):
+		d.report(deviation.Pos, deviation.Message)
+	default:
+		d.report(Position{}, err.Error())
+	}
+}
+
+// report добавляет диагностику ошибки в позиции pos
+func (d *lspDocument) report(pos Position, message string) {
+	if !pos.IsValid() {
+		pos = Position{Line: 1, Column: 1}
+	}
+	d.diagnostics = append(d.diagnostics, lspDiagnostic{Range: d.span(pos), Severity: lspSeverityError, Source: "pascal", Message: message})
+}
+
+// usesToken возвращает позицию слова USES в документе
+func (d *lspDocument) usesToken() Position {
+	for _, t := range d.tokens {
+		if t.Type == TokenUSES {
+			return t.Position()
+		}
+	}
+	return Position{}
+}
+
+// usesPosition возвращает позицию имени модуля в USES, через который подключен файл
+func (d *lspDocument) usesPosition(program *Program, unit *Unit, file string) Position {
+	uses := []*UnitRef{}
+	if program != nil {
+		uses = program.Uses
+	} else if unit != nil {
+		uses = unit.allUses()
+	}
+	for _, ref := range uses {
+		if ref.Unit != nil && ref.Unit.Pos.File == file {
+			return ref.Pos
+		}
+	}
+	if len(uses) > 0 {
+		return uses[0].Pos
+	}
+	return Position{}
+}
+
+// index запоминает имена по позициям их записей в документе: описаний и обращений
+func (d *lspDocument) index() {
+	d.symbols = make(map[Position]*Symbol)
+	add := func(pos Position, symbol *Symbol) {
+		if pos.IsValid() && pos.File == "" {
+			d.symbols[pos] = symbol
+		}
+	}
+	for node, symbol := range d.info.Symbols {
+		switch n := node.(type) {
+		case *Identifier:
+			add(n.Pos, symbol)
+		case *CallExpr:
+			add(n.Pos, symbol)
+		case *CallStatement:
+			add(n.Pos, symbol)
+		case *Assignment:
+			add(n.Pos, symbol)
+		case *ConstDecl:
+			add(n.Pos, symbol)
+		case *VarDecl:
+			add(n.Pos, symbol)
+		case *TypeDecl:
+			add(n.Pos, symbol)
+		case *RoutineDecl:
+			add(n.Pos, symbol)
+		case *ParamDecl:
+			add(n.Pos, symbol)
+		case *ForStatement:
+			add(d.nameAt(n.Pos, symbol.Name), symbol)
+		case *ExceptHandler:
+			add(d.nameAt(n.Pos, symbol.Name), symbol)
+		}
+		add(d.declaration(symbol), symbol)
+	}
+}
+
+// nameAt возвращает позицию имени name в записи, которая начинается в позиции pos: позиция
+// цикла FOR и обработчика ON - позиция их ключевого слова, а значений перечисления - скобки
+func (d *lspDocument) nameAt(pos Position, name string) Position {
+	n := d.tokenAt(pos)
+	if n < 0 {
+		return pos
+	}
+	for ; n < len(d.tokens); n++ {
+		switch t := d.tokens[n]; {
+		case t.Type == TokenIDENTIFIER && strings.EqualFold(t.Value, name):
+			return t.Position()
+		case t.Type == TokenSEMICOLON || t.Type == TokenRPAREN || t.Type == TokenDO:
+			return pos
+		}
+	}
+	return pos
+}
+
+// declaration возвращает позицию имени в его описании
+func (d *lspDocument) declaration(symbol *Symbol) Position {
+	if symbol.Pos.File != "" {
+		return symbol.Pos
+	}
+	return d.nameAt(symbol.Pos, symbol.Name)
+}
+
+// nextToken возвращает позицию токена, следующего за токеном в позиции pos
+func (d *lspDocument) nextToken(pos Position) Position {
+	if n := d.tokenAt(pos); n >= 0 && n+1 < len(d.tokens) {
+		return d.tokens[n+1].Position()
+	}
+	return Position{}
+}
+
+// symbolAt возвращает имя, на котором стоит курсор, и позицию его записи
+func (d *lspDocument) symbolAt(pos lspPosition) (*Symbol, Position) {
+	at, ok := d.identifierAt(pos)
+	if !ok || d.symbols == nil {
+		return nil, Position{}
+	}
+	if symbol := d.symbols[at]; symbol != nil {
+		return symbol, at
+	}
+	// Имена типов в описаниях анализатор не запоминает: они ищутся в области видимости,
+	// кроме имен полей после точки
+	n := d.tokenAt(at)
+	if n > 0 && d.tokens[n-1].Type == TokenDOT {
+		return nil, Position{}
+	}
+	if scope := d.scopeAt(at); scope != nil {
+		if symbol := scope.Lookup(d.tokens[n].Value); symbol != nil && symbol.Kind == SymbolType {
+			return symbol, at
+		}
+	}
+	return nil, Position{}
+}
+
+// hover возвращает описание имени под курсором: вид, тип и место описания
+func (d *lspDocument) hover(pos lspPosition) interface{} {
+	symbol, at := d.symbolAt(pos)
+	if symbol == nil {
+		return nil
+	}
+	text := "```pascal\n" + describeSymbol(symbol) + "\n```\n\n"
+	switch {
+	case !symbol.Pos.IsValid():
+		text += "Стандартное имя"
+	case symbol.Implicit:
+		text += "Описана присваиванием: " + d.declaration(symbol).String()
+	default:
+		text += "Описание: " + d.declaration(symbol).String()
+	}
+	return map[string]interface{}{
+		"contents": map[string]string{"kind": lspMarkdown, "value": text},
+		"range":    d.span(at),
+	}
+}
+
+// describeSymbol записывает имя в виде, близком к его описанию в программе
+func describeSymbol(symbol *Symbol) string {
+	switch symbol.Kind {
+	case SymbolConst:
+		if symbol.Value != nil {
+			return fmt.Sprintf("константа %s = %s", symbol.Name, symbol.Value)
+		}
+	case SymbolType:
+		if symbol.Type != nil && symbol.Type.Name != symbol.Name {
+			return fmt.Sprintf("тип %s = %s", symbol.Name, symbol.Type)
+		}
+		return "тип " + symbol.Name
+	case SymbolProcedure, SymbolFunction:
+		text := symbol.Kind.String() + " " + symbol.Name
+		if symbol.Signature == nil {
+			return text
+		}
+		params := make([]string, len(symbol.Signature.Params))
+		for n, param := range symbol.Signature.Params {
+			params[n] = param.Name + ": " + param.Type.String()
+			if param.ByRef {
+				params[n] = "VAR " + params[n]
+			}
+		}
+		if len(params) > 0 {
+			text += "(" + strings.Join(params, "; ") + ")"
+		}
+		if symbol.Signature.Result != nil {
+			text += ": " + symbol.Signature.Result.String()
+		}
+		return text
+	}
+	if symbol.Type == nil {
+		return symbol.Kind.String() + " " + symbol.Name
+	}
+	return fmt.Sprintf("%s %s: %s", symbol.Kind, symbol.Name, symbol.Type)
+}
+
+// definition возвращает место описания имени под курсором
+func (d *lspDocument) definition(pos lspPosition) interface{} {
+	symbol, _ := d.symbolAt(pos)
+	if symbol == nil || !symbol.Pos.IsValid() {
+		return nil
+	}
+	if symbol.Pos.File == "" {
+		return lspLocation{URI: d.uri, Range: d.span(d.declaration(symbol))}
+	}
+	// Описание в модуле: его текст не открыт, поэтому столбец отсчитывается в символах
+	file, err := filepath.Abs(symbol.Pos.File)
+	if err != nil {
+		return nil
+	}
+	start := lspPosition{Line: symbol.Pos.Line - 1, Character: symbol.Pos.Column - 1}
+	end := lspPosition{Line: start.Line, Character: start.Character + utf8.RuneCountInString(symbol.Name)}
+	return lspLocation{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String(), Range: lspRange{start, end}}
+}
+
+// documentSymbols возвращает описания программы или модуля с вложенными описаниями подпрограмм
+func (d *lspDocument) documentSymbols() []lspDocumentSymbol {
+	symbols := []lspDocumentSymbol{}
+	if d.program != nil {
+		symbols = d.declarationSymbols(&d.program.Declarations)
+	} else if d.unit != nil {
+		symbols = append(d.declarationSymbols(&d.unit.Interface), d.declarationSymbols(&d.unit.Implementation)...)
+	}
+	return symbols
+}
+
+// declarationSymbols возвращает символы раздела описаний в порядке их записи
+func (d *lspDocument) declarationSymbols(decls *Declarations) []lspDocumentSymbol {
+	var symbols []lspDocumentSymbol
+	symbol := func(name string, kind int, pos Position, node Node) lspDocumentSymbol {
+		s := lspDocumentSymbol{Name: name, Kind: kind, Range: d.span(pos), SelectionRange: d.span(pos)}
+		if described := d.info.Symbols[node]; described != nil && described.Type != nil && kind != lspSymbolFunction {
+			s.Detail = described.Type.String()
+		}
+		return s
+	}
+	for _, decl := range decls.Consts {
+		symbols = append(symbols, symbol(decl.Name, lspSymbolConstant, decl.Pos, decl))
+	}
+	for _, decl := range decls.Types {
+		kind := lspSymbolTypeParameter
+		s := symbol(decl.Name, kind, decl.Pos, decl)
+		switch spec := decl.Type.(type) {
+		case *RecordType:
+			s.Kind = lspSymbolStruct
+		case *ClassType:
+			s.Kind = lspSymbolClass
+		case *EnumType:
+			s.Kind = lspSymbolEnum
+			for _, name := range spec.Names {
+				member := d.span(d.nameAt(spec.Pos, name))
+				s.Children = append(s.Children, lspDocumentSymbol{Name: name, Kind: lspSymbolEnumMember, Range: member, SelectionRange: member})
+			}
+		}
+		symbols = append(symbols, s)
+	}
+	for _, decl := range decls.Vars {
+		symbols = append(symbols, symbol(decl.Name, lspSymbolVariable, decl.Pos, decl))
+	}
+	for _, decl := range decls.Routines {
+		s := symbol(decl.Name, lspSymbolFunction, decl.Pos, decl)
+		if described := d.info.Symbols[decl]; described != nil {
+			s.Detail = describeSymbol(described)
+		}
+		if decl.End.IsValid() && decl.End.File == "" {
+			s.Range.End = d.span(decl.End).End
+		}
+		for _, param := range decl.Params {
+			s.Children = append(s.Children, symbol(param.Name, lspSymbolVariable, param.Pos, param))
+		}
+		s.Children = append(s.Children, d.declarationSymbols(&decl.Declarations)...)
+		symbols = append(symbols, s)
+	}
+	sort.SliceStable(symbols, func(a, b int) bool {
+		x, y := symbols[a].Range.Start, symbols[b].Range.Start
+		return x.Line < y.Line || x.Line == y.Line && x.Character < y.Character
+	})
+	return symbols
+}
+
+// completion возвращает ключевые слова, стандартные подпрограммы и имена, видимые в позиции курсора
+func (d *lspDocument) completion(pos lspPosition) []lspCompletionItem {
+	var items []lspCompletionItem
+	for keyword := range keywords {
+		items = append(items, lspCompletionItem{Label: keyword, Kind: lspCompletionKeyword})
+	}
+	for _, name := range lspStandardProcedures {
+		items = append(items, lspCompletionItem{Label: name, Kind: lspCompletionFunction, Detail: "стандартная процедура"})
+	}
+	for name := range builtinFunctions {
+		items = append(items, lspCompletionItem{Label: strings.ToUpper(name[:1]) + name[1:], Kind: lspCompletionFunction, Detail: "стандартная функция"})
+	}
+
+	seen := make(map[string]bool)
+	for scope := d.scopeAt(d.cursor(pos)); scope != nil; scope = scope.parent {
+		for key, symbol := range scope.symbols {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kind := lspCompletionVariable
+			switch symbol.Kind {
+			case SymbolConst:
+				kind = lspCompletionConstant
+			case SymbolType:
+				kind = lspCompletionClass
+			case SymbolProcedure, SymbolFunction:
+				kind = lspCompletionFunction
+			}
+			items = append(items, lspCompletionItem{Label: symbol.Name, Kind: kind, Detail: describeSymbol(symbol)})
+		}
+	}
+	sort.Slice(items, func(a, b int) bool {
+		if items[a].Label != items[b].Label {
+			return items[a].Label < items[b].Label
+		}
+		return items[a].Kind < items[b].Kind
+	})
+	return items
+}
+
+// scopeAt возвращает область видимости самой вложенной подпрограммы, в теле или описаниях
+// которой находится позиция, иначе область видимости программы или модуля
+func (d *lspDocument) scopeAt(pos Position) *Scope {
+	if d.info == nil {
+		return nil
+	}
+	var node Node = d.program
+	var routines []*RoutineDecl
+	if d.program != nil {
+		routines = d.program.Routines
+	} else if d.unit != nil {
+		node = d.unit
+		routines = append(append(routines, d.unit.Interface.Routines...), d.unit.Implementation.Routines...)
+	}
+	for found := true; found; {
+		found = false
+		for _, routine := range routines {
+			if routine.End.IsValid() && !positionBefore(pos, routine.Pos) && !positionBefore(routine.End, pos) {
+				node, routines, found = routine, routine.Routines, true
+				break
+			}
+		}
+	}
+	return d.info.Scopes[node]
+}
+
+// positionBefore сообщает, предшествует ли позиция a позиции b
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// lspWithExitCode выполняет команду pascal lsp и возвращает код выхода
+func lspWithExitCode(args []string) int {
+	var units string
+	flags := flag.NewFlagSet("pascal lsp", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&units, "units", "", "каталоги поиска модулей USES, разделенные '"+string(os.PathListSeparator)+"'")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: pascal lsp [-units каталоги]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() > 0 {
+		fmt.Println("Использование: pascal lsp [-units каталоги]")
+		return 1
+	}
+	var path []string
+	if units != "" {
+		path = filepath.SplitList(units)
+	}
+	return serveLSP(os.Stdin, os.Stdout, path)
+}
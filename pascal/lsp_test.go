@@ -0,0 +1,539 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lspSession записывает сообщения редактора и разбирает ответы сервера
+type lspSession struct {
+	t     *testing.T
+	input bytes.Buffer
+	id    int
+}
+
+// request добавляет запрос и возвращает его идентификатор
+func (s *lspSession) request(method string, params interface{}) int {
+	s.id++
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "id": s.id, "method": method, "params": params})
+	return s.id
+}
+
+// notify добавляет уведомление
+func (s *lspSession) notify(method string, params interface{}) {
+	s.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *lspSession) write(message interface{}) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	fmt.Fprintf(&s.input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// lspReply - ответ или уведомление сервера
+type lspReply struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *lspError       `json:"error"`
+}
+
+// run обслуживает записанные сообщения и возвращает код выхода и сообщения сервера
+func (s *lspSession) run(units ...string) (int, []lspReply) {
+	s.t.Helper()
+	var output bytes.Buffer
+	code := serveLSP(&s.input, &output, units)
+	var replies []lspReply
+	reader := bufio.NewReader(&output)
+	for {
+		header, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			s.t.Fatalf("неверный заголовок %q", header)
+		}
+		reader.ReadString('\n')
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			s.t.Fatal(err)
+		}
+		var reply lspReply
+		if err := json.Unmarshal(body, &reply); err != nil {
+			s.t.Fatalf("%s: %v", body, err)
+		}
+		replies = append(replies, reply)
+	}
+	return code, replies
+}
+
+// lspResult находит ответ на запрос id и разбирает его результат в value
+func lspResult(t *testing.T, replies []lspReply, id int, value interface{}) {
+	t.Helper()
+	for _, reply := range replies {
+		if reply.ID != nil && *reply.ID == id {
+			if reply.Error != nil {
+				t.Fatalf("запрос %d: ошибка %v", id, reply.Error)
+			}
+			if err := json.Unmarshal(reply.Result, value); err != nil {
+				t.Fatalf("запрос %d: %s: %v", id, reply.Result, err)
+			}
+			return
+		}
+	}
+	t.Fatalf("нет ответа на запрос %d", id)
+}
+
+// lspDiagnostics возвращает опубликованные диагностики документа по порядку публикации
+func lspDiagnostics(t *testing.T, replies []lspReply) [][]lspDiagnostic {
+	t.Helper()
+	var published [][]lspDiagnostic
+	for _, reply := range replies {
+		if reply.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params struct {
+			Diagnostics []lspDiagnostic `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(reply.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, params.Diagnostics)
+	}
+	return published
+}
+
+func lspOpen(uri, text string) map[string]interface{} {
+	return map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri, "languageId": "pascal", "version": 1, "text": text}}
+}
+
+func lspAt(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}, "position": lspPosition{line, character}}
+}
+
+func TestLSPLifecycle(t *testing.T) {
+	s := &lspSession{t: t}
+	initialize := s.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	s.notify("initialized", map[string]interface{}{})
+	unknown := s.request("workspace/unknown", nil)
+	s.notify("$/cancelRequest", map[string]interface{}{"id": 1})
+	shutdown := s.request("shutdown", nil)
+	s.notify("exit", nil)
+	code, replies := s.run()
+	if code != 0 {
+		t.Errorf("ожидался код выхода 0, получено %d", code)
+	}
+
+	var capabilities struct {
+		Capabilities struct {
+			TextDocumentSync struct {
+				OpenClose bool `json:"openClose"`
+				Change    int  `json:"change"`
+			} `json:"textDocumentSync"`
+			HoverProvider          bool `json:"hoverProvider"`
+			DefinitionProvider     bool `json:"definitionProvider"`
+			DocumentSymbolProvider bool `json:"documentSymbolProvider"`
+		} `json:"capabilities"`
+	}
+	lspResult(t, replies, initialize, &capabilities)
+	c := capabilities.Capabilities
+	if !c.TextDocumentSync.OpenClose || c.TextDocumentSync.Change != lspSyncIncremental || !c.HoverProvider || !c.DefinitionProvider || !c.DocumentSymbolProvider {
+		t.Errorf("неожиданные возможности сервера: %+v", c)
+	}
+	var null interface{}
+	lspResult(t, replies, shutdown, &null)
+	if null != nil {
+		t.Errorf("shutdown: ожидался null, получено %v", null)
+	}
+	for _, reply := range replies {
+		if reply.ID != nil && *reply.ID == unknown && (reply.Error == nil || reply.Error.Code != lspMethodNotFound) {
+			t.Errorf("неизвестный метод: ожидалась ошибка %d, получено %+v", lspMethodNotFound, reply.Error)
+		}
+	}
+	if len(replies) != 3 {
+		t.Errorf("ожидалось 3 ответа, получено %d", len(replies))
+	}
+}
+
+// TestLSPExitWithoutShutdown тестирует коды выхода без запроса shutdown и при конце ввода
+func TestLSPExitWithoutShutdown(t *testing.T) {
+	s := &lspSession{t: t}
+	s.notify("exit", nil)
+	if code, _ := s.run(); code != 1 {
+		t.Errorf("exit без shutdown: ожидался код выхода 1, получено %d", code)
+	}
+	s = &lspSession{t: t}
+	s.input.WriteString("Content-Length: 5\r\n\r\n{bad}")
+	s.request("shutdown", nil)
+	code, replies := s.run()
+	if code != 1 {
+		t.Errorf("конец ввода: ожидался код выхода 1, получено %d", code)
+	}
+	if len(replies) != 2 || replies[0].Error == nil || replies[0].Error.Code != lspParseError {
+		t.Errorf("ожидалась ошибка разбора %d, получено %+v", lspParseError, replies)
+	}
+}
+
+func TestLSPDiagnostics(t *testing.T) {
+	tests := []struct {
+		text    string
+		message string
+		want    lspRange
+	}{
+		{"BEGIN x := 1 END.", "", lspRange{}},
+		{"BEGIN\n  x := 1 @ END.", "неожиданный символ '@'", lspRange{lspPosition{1, 9}, lspPosition{1, 10}}},
+		{"BEGIN\n  x := 1;\n  y := \nEND.", "неожиданный токен", lspRange{lspPosition{3, 0}, lspPosition{3, 3}}},
+		{"VAR n: INTEGER;\nBEGIN\n  n := 'текст'\nEND.", "несовместимые типы", lspRange{lspPosition{2, 2}, lspPosition{2, 3}}},
+		// Столбцы LSP считаются в кодовых единицах UTF-16: символ вне BMP занимает две
+		{"BEGIN { 😀 } Foo(1) END.", "неизвестная процедура Foo", lspRange{lspPosition{0, 13}, lspPosition{0, 16}}},
+		{"BEGIN x := 1 END", "ожидалась точка", lspRange{lspPosition{0, 16}, lspPosition{0, 16}}},
+		{"BEGIN x := 1 END. y", "неожиданные токены после точки", lspRange{lspPosition{0, 18}, lspPosition{0, 19}}},
+		// Модуль, который не удалось подключить, отмечается на его имени в USES
+		{"PROGRAM P;\nUSES Nowhere;\nBEGIN END.", "Nowhere", lspRange{lspPosition{1, 5}, lspPosition{1, 12}}},
+	}
+	for _, tt := range tests {
+		s := &lspSession{t: t}
+		s.notify("textDocument/didOpen", lspOpen("file:///tmp/test.pas", tt.text))
+		_, replies := s.run()
+		published := lspDiagnostics(t, replies)
+		if len(published) != 1 {
+			t.Fatalf("%q: ожидалась одна публикация диагностики, получено %d", tt.text, len(published))
+		}
+		if tt.message == "" {
+			if len(published[0]) != 0 {
+				t.Errorf("%q: неожиданная диагностика %+v", tt.text, published[0])
+			}
+			continue
+		}
+		if len(published[0]) == 0 {
+			t.Errorf("%q: нет диагностики", tt.text)
+			continue
+		}
+		got := published[0][0]
+		if !strings.Contains(got.Message, tt.message) || got.Range != tt.want || got.Severity != lspSeverityError {
+			t.Errorf("%q: ожидалось %q в %+v, получено %q в %+v", tt.text, tt.message, tt.want, got.Message, got.Range)
+		}
+		if strings.Contains(got.Message, "на позиции") || strings.Contains(got.Message, "строка ") {
+			t.Errorf("%q: позиция осталась в сообщении %q", tt.text, got.Message)
+		}
+	}
+}
+
+// TestLSPIncrementalChange тестирует применение изменений didChange и повторный анализ
+func TestLSPIncrementalChange(t *testing.T) {
+	const uri = "file:///tmp/test.pas"
+	s := &lspSession{t: t}
+	s.notify("textDocument/didOpen", lspOpen(uri, "VAR число: INTEGER;\nBEGIN\n  число := 1 +\nEND."))
+	change := func(changes ...map[string]interface{}) {
+		s.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": changes,
+		})
+	}
+	// Дописываем операнд, затем заменяем имя переменной в присваивании
+	change(map[string]interface{}{"range": lspRange{lspPosition{2, 14}, lspPosition{2, 14}}, "text": " 2"})
+	change(map[string]interface{}{"range": lspRange{lspPosition{2, 2}, lspPosition{2, 7}}, "text": "итог"},
+		map[string]interface{}{"range": lspRange{lspPosition{2, 0}, lspPosition{2, 0}}, "text": "  итог := 0;\n"})
+	hover := s.request("textDocument/hover", lspAt(uri, 3, 3))
+	change(map[string]interface{}{"text": "BEGIN END."})
+	symbols := s.request("textDocument/documentSymbol", lspAt(uri, 0, 0))
+	s.notify("textDocument/didClose", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	closed := s.request("textDocument/hover", lspAt(uri, 0, 0))
+	_, replies := s.run()
+
+	published := lspDiagnostics(t, replies)
+	if len(published) != 5 {
+		t.Fatalf("ожидалось 5 публикаций диагностики, получено %d", len(published))
+	}
+	if len(published[0]) != 1 || len(published[1]) != 0 || len(published[2]) != 0 || len(published[3]) != 0 || len(published[4]) != 0 {
+		t.Errorf("неожиданные диагностики: %+v", published)
+	}
+	var h struct {
+		Contents struct{ Value string } `json:"contents"`
+	}
+	lspResult(t, replies, hover, &h)
+	if !strings.Contains(h.Contents.Value, "переменная итог: INTEGER") || !strings.Contains(h.Contents.Value, "Описана присваиванием: строка 3, столбец 3") {
+		t.Errorf("неожиданная подсказка %q", h.Contents.Value)
+	}
+	var docSymbols []lspDocumentSymbol
+	lspResult(t, replies, symbols, &docSymbols)
+	if len(docSymbols) != 0 {
+		t.Errorf("после замены текста ожидался пустой список символов, получено %+v", docSymbols)
+	}
+	for _, reply := range replies {
+		if reply.ID != nil && *reply.ID == closed && (reply.Error == nil || reply.Error.Code != lspInvalidParams) {
+			t.Errorf("закрытый документ: ожидалась ошибка %d, получено %+v", lspInvalidParams, reply.Error)
+		}
+	}
+}
+
+// lspProgram - программа для тестов подсказок, переходов, символов и автодополнения
+const lspProgram = `CONST Limit = 10;
+TYPE Color = (Red, Green);
+  Point = RECORD x, y: INTEGER END;
+VAR total: INTEGER; c: Color;
+
+FUNCTION Square(n: INTEGER): INTEGER;
+VAR local: INTEGER;
+BEGIN
+  local := n * n;
+  Square := local
+END;
+
+PROCEDURE Add(VAR sum: INTEGER; k: INTEGER);
+BEGIN
+  sum := sum + k
+END;
+
+BEGIN
+  total := 0;
+  FOR i := 1 TO Limit DO
+    Add(total, Square(i));
+  c := Green
+END.`
+
+func TestLSPHoverAndDefinition(t *testing.T) {
+	const uri = "file:///tmp/prog.pas"
+	tests := []struct {
+		line, character int
+		hover           string
+		definition      *lspRange // nil - перехода нет
+	}{
+		{18, 3, "переменная total: INTEGER", &lspRange{lspPosition{3, 4}, lspPosition{3, 9}}},
+		{3, 6, "переменная total: INTEGER", &lspRange{lspPosition{3, 4}, lspPosition{3, 9}}},
+		{20, 4, "процедура Add(VAR sum: INTEGER; k: INTEGER)", &lspRange{lspPosition{12, 10}, lspPosition{12, 13}}},
+		{20, 16, "функция Square(n: INTEGER): INTEGER", &lspRange{lspPosition{5, 9}, lspPosition{5, 15}}},
+		{20, 23, "переменная i: INTEGER", &lspRange{lspPosition{19, 6}, lspPosition{19, 7}}},
+		{19, 6, "переменная i: INTEGER", &lspRange{lspPosition{19, 6}, lspPosition{19, 7}}},
+		{19, 16, "константа Limit = 10", &lspRange{lspPosition{0, 6}, lspPosition{0, 11}}},
+		{21, 8, "константа Green = Green", &lspRange{lspPosition{1, 19}, lspPosition{1, 24}}},
+		{8, 11, "переменная n: INTEGER", &lspRange{lspPosition{5, 16}, lspPosition{5, 17}}},
+		{9, 2, "функция Square(n: INTEGER): INTEGER", &lspRange{lspPosition{5, 9}, lspPosition{5, 15}}},
+		{3, 26, "тип Color", &lspRange{lspPosition{1, 5}, lspPosition{1, 10}}},
+		{5, 23, "тип INTEGER", nil},
+		{17, 1, "", nil},
+	}
+	s := &lspSession{t: t}
+	s.notify("textDocument/didOpen", lspOpen(uri, lspProgram))
+	var hovers, definitions []int
+	for _, tt := range tests {
+		hovers = append(hovers, s.request("textDocument/hover", lspAt(uri, tt.line, tt.character)))
+		definitions = append(definitions, s.request("textDocument/definition", lspAt(uri, tt.line, tt.character)))
+	}
+	_, replies := s.run()
+	if published := lspDiagnostics(t, replies); len(published) != 1 || len(published[0]) != 0 {
+		t.Fatalf("неожиданные диагностики: %+v", published)
+	}
+	for n, tt := range tests {
+		var h *struct {
+			Contents struct{ Kind, Value string } `json:"contents"`
+		}
+		lspResult(t, replies, hovers[n], &h)
+		switch {
+		case tt.hover == "" && h != nil:
+			t.Errorf("%d:%d: неожиданная подсказка %q", tt.line, tt.character, h.Contents.Value)
+		case tt.hover != "" && (h == nil || !strings.HasPrefix(h.Contents.Value, "```pascal\n"+tt.hover+"\n```")):
+			t.Errorf("%d:%d: ожидалась подсказка %q, получено %+v", tt.line, tt.character, tt.hover, h)
+		}
+		var location *lspLocation
+		lspResult(t, replies, definitions[n], &location)
+		switch {
+		case tt.definition == nil && location != nil:
+			t.Errorf("%d:%d: неожиданный переход %+v", tt.line, tt.character, location)
+		case tt.definition != nil && (location == nil || location.URI != uri || location.Range != *tt.definition):
+			t.Errorf("%d:%d: ожидался переход к %+v, получено %+v", tt.line, tt.character, *tt.definition, location)
+		}
+	}
+}
+
+func TestLSPDocumentSymbols(t *testing.T) {
+	const uri = "file:///tmp/prog.pas"
+	s := &lspSession{t: t}
+	s.notify("textDocument/didOpen", lspOpen(uri, lspProgram))
+	id := s.request("textDocument/documentSymbol", lspAt(uri, 0, 0))
+	_, replies := s.run()
+	var symbols []lspDocumentSymbol
+	lspResult(t, replies, id, &symbols)
+
+	var describe func(symbols []lspDocumentSymbol) string
+	describe = func(symbols []lspDocumentSymbol) string {
+		parts := make([]string, len(symbols))
+		for n, symbol := range symbols {
+			parts[n] = fmt.Sprintf("%s/%d", symbol.Name, symbol.Kind)
+			if symbol.Detail != "" {
+				parts[n] += " " + symbol.Detail
+			}
+			if len(symbol.Children) > 0 {
+				parts[n] += " [" + describe(symbol.Children) + "]"
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	want := "Limit/14 INTEGER, Color/10 Color [Red/22, Green/22], Point/23 Point, total/13 INTEGER, c/13 Color, " +
+		"Square/12 функция Square(n: INTEGER): INTEGER [n/13 INTEGER, local/13 INTEGER], " +
+		"Add/12 процедура Add(VAR sum: INTEGER; k: INTEGER) [sum/13 INTEGER, k/13 INTEGER]"
+	if got := describe(symbols); got != want {
+		t.Errorf("ожидались символы\n%s\nполучено\n%s", want, got)
+	}
+	if green := symbols[1].Children[1]; green.Range != (lspRange{lspPosition{1, 19}, lspPosition{1, 24}}) {
+		t.Errorf("неожиданный диапазон Green: %+v", green.Range)
+	}
+	square := symbols[5]
+	if square.Range != (lspRange{lspPosition{5, 9}, lspPosition{10, 3}}) || square.SelectionRange != (lspRange{lspPosition{5, 9}, lspPosition{5, 15}}) {
+		t.Errorf("неожиданные диапазоны Square: %+v, %+v", square.Range, square.SelectionRange)
+	}
+}
+
+func TestLSPCompletion(t *testing.T) {
+	const uri = "file:///tmp/prog.pas"
+	s := &lspSession{t: t}
+	s.notify("textDocument/didOpen", lspOpen(uri, lspProgram))
+	inside := s.request("textDocument/completion", lspAt(uri, 8, 2))
+	outside := s.request("textDocument/completion", lspAt(uri, 18, 2))
+	_, replies := s.run()
+
+	labels := func(id int) map[string]int {
+		var items []lspCompletionItem
+		lspResult(t, replies, id, &items)
+		kinds := make(map[string]int)
+		for _, item := range items {
+			kinds[item.Label] = item.Kind
+		}
+		return kinds
+	}
+	in, out := labels(inside), labels(outside)
+	for label, kind := range map[string]int{"BEGIN": lspCompletionKeyword, "WHILE": lspCompletionKeyword, "WriteLn": lspCompletionFunction,
+		"Abs": lspCompletionFunction, "total": lspCompletionVariable, "Limit": lspCompletionConstant, "Color": lspCompletionClass,
+		"Red": lspCompletionConstant, "Square": lspCompletionFunction, "INTEGER": lspCompletionClass, "MAXINT": lspCompletionConstant} {
+		if in[label] != kind || out[label] != kind {
+			t.Errorf("%s: ожидался вид %d, получено %d в функции и %d в программе", label, kind, in[label], out[label])
+		}
+	}
+	for _, local := range []string{"local", "n"} {
+		if in[local] != lspCompletionVariable {
+			t.Errorf("в функции нет локального имени %s", local)
+		}
+		if _, ok := out[local]; ok {
+			t.Errorf("в программе видно локальное имя %s", local)
+		}
+	}
+	if _, ok := in["sum"]; ok {
+		t.Errorf("в функции Square видно имя параметра процедуры Add")
+	}
+}
+
+// TestLSPUnits тестирует модули из USES: переход к описанию в файле модуля и ошибки модуля
+func TestLSPUnits(t *testing.T) {
+	dir := t.TempDir()
+	units := filepath.Join(dir, "units")
+	if err := os.Mkdir(units, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(units, "geometry.pas"): "UNIT Geometry;\nINTERFACE\nFUNCTION Area(w, h: INTEGER): INTEGER;\nIMPLEMENTATION\n" +
+			"FUNCTION Area(w, h: INTEGER): INTEGER;\nBEGIN\n  Area := w * h\nEND;\nEND.",
+		filepath.Join(units, "broken.pas"): "UNIT Broken;\nINTERFACE\nIMPLEMENTATION\nBEGIN\n  x := 'a' + 1\nEND.",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	program := "file://" + filepath.ToSlash(filepath.Join(dir, "main.pas"))
+	broken := "file://" + filepath.ToSlash(filepath.Join(dir, "broken.pas"))
+	missing := "file://" + filepath.ToSlash(filepath.Join(dir, "missing.pas"))
+	unit := "file://" + filepath.ToSlash(filepath.Join(units, "geometry.pas"))
+
+	s := &lspSession{t: t}
+	s.notify("textDocument/didOpen", lspOpen(program, "USES Geometry;\nBEGIN\n  s := Area(2, 3)\nEND."))
+	definition := s.request("textDocument/definition", lspAt(program, 2, 8))
+	s.notify("textDocument/didOpen", lspOpen(broken, "USES Geometry, Broken;\nBEGIN END."))
+	s.notify("textDocument/didOpen", lspOpen(missing, "USES Nothing;\nBEGIN END."))
+	s.notify("textDocument/didOpen", lspOpen(unit, files[filepath.Join(units, "geometry.pas")]))
+	symbols := s.request("textDocument/documentSymbol", lspAt(unit, 0, 0))
+	_, replies := s.run(units)
+
+	published := lspDiagnostics(t, replies)
+	if len(published) != 4 {
+		t.Fatalf("ожидалось 4 публикации диагностики, получено %d", len(published))
+	}
+	if len(published[0]) != 0 || len(published[3]) != 0 {
+		t.Errorf("неожиданные диагностики: %+v", published)
+	}
+	if len(published[1]) != 1 || published[1][0].Range.Start != (lspPosition{0, 15}) || !strings.Contains(published[1][0].Message, "broken.pas: строка 5, столбец 12") {
+		t.Errorf("ошибка модуля: неожиданная диагностика %+v", published[1])
+	}
+	if len(published[2]) != 1 || published[2][0].Range.Start != (lspPosition{0, 5}) || !strings.Contains(published[2][0].Message, "модуль Nothing не найден") {
+		t.Errorf("отсутствующий модуль: неожиданная диагностика %+v", published[2])
+	}
+
+	var location lspLocation
+	lspResult(t, replies, definition, &location)
+	if location.URI != unit || location.Range != (lspRange{lspPosition{2, 9}, lspPosition{2, 13}}) {
+		t.Errorf("ожидался переход к описанию в модуле, получено %+v", location)
+	}
+	var unitSymbols []lspDocumentSymbol
+	lspResult(t, replies, symbols, &unitSymbols)
+	if len(unitSymbols) != 2 || unitSymbols[0].Name != "Area" || unitSymbols[1].Name != "Area" || len(unitSymbols[1].Children) != 2 {
+		t.Errorf("неожиданные символы модуля: %+v", unitSymbols)
+	}
+}
+
+// TestMainLSP тестирует разбор аргументов команды pascal lsp
+func TestMainLSP(t *testing.T) {
+	oldArgs, oldStdout, oldStdin := os.Args, os.Stdout, os.Stdin
+	defer func() { os.Args, os.Stdout, os.Stdin = oldArgs, oldStdout, oldStdin }()
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{"pascal", "lsp", "prog.pas"}
+	os.Stdout = stdoutWriter
+	code := mainWithExitCode()
+	os.Stdout = oldStdout
+	stdoutWriter.Close()
+	var stdout bytes.Buffer
+	stdout.ReadFrom(stdoutReader)
+	if code != 1 || stdout.String() != "Использование: pascal lsp [-units каталоги]\n" {
+		t.Errorf("ожидалась подсказка и код 1, получено %q, код %d", stdout.String(), code)
+	}
+
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdoutReader, stdoutWriter, err = os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &lspSession{t: t}
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+	go func() {
+		stdinWriter.Write(s.input.Bytes())
+		stdinWriter.Close()
+	}()
+	os.Args = []string{"pascal", "lsp", "-units", t.TempDir()}
+	os.Stdin, os.Stdout = stdinReader, stdoutWriter
+	code = mainWithExitCode()
+	os.Stdin, os.Stdout = oldStdin, oldStdout
+	stdoutWriter.Close()
+	stdout.Reset()
+	stdout.ReadFrom(stdoutReader)
+	if code != 0 || !strings.Contains(stdout.String(), `"result":null`) {
+		t.Errorf("ожидался ответ на shutdown и код 0, получено %q, код %d", stdout.String(), code)
+	}
+}
@@ -1,78 +1,563 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
+// runOptions задает режимы выполнения программы, выбранные флагами командной строки
+type runOptions struct {
+	leaks   bool    // сообщать о неосвобожденной динамической памяти
+	bigint  bool    // режим длинных целых: целые операции не переполняются
+	root    string  // каталог, которым ограничен доступ к файлам; пустая строка - текущий каталог
+	units   string  // каталоги поиска модулей через разделитель списка путей ОС
+	dialect Dialect // вариант языка, который принимают лексер и парсер
+	asm     string  // файл, в который записывается программа на ассемблере NASM вместо выполнения
+	dot     string  // файл, в который записывается AST в формате Graphviz DOT вместо выполнения
+	watch   bool    // выполнять программу заново при каждом изменении ее файлов
+
+	// Файлы отчетов о профиле и покрытии операторов; пустая строка - отчет не нужен
+	profile string
+	cover   string
+
+	trace       bool   // выводить в stderr каждое выполненное присваивание
+	traceFormat string // формат трассировки: text или json (строки JSON)
+
+	input     string   // файл JSON с начальными значениями переменных
+	inputData []byte   // содержимое файла input, если оно уже известно (из снимка)
+	defines   []string // начальные значения переменных вида имя=значение; задаются после input
+	evals     []string // выражения, значения которых выводятся вместо словаря переменных
+
+	steps    int64     // бюджет операторов; 0 - без ограничения
+	snapshot string    // файл, в который записывается снимок при остановке
+	resume   *Snapshot // снимок, с которого продолжается выполнение (pascal resume)
+}
+
+// stringList - значение флага, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 // runInterpreter выполняет интерпретацию Pascal программы из файла
 func runInterpreter(filename string) error {
+	return run(filename, runOptions{})
+}
+
+// run выполняет программу из файла с заданными режимами
+func run(filename string, options runOptions) error {
+	var observer Observer
+	if options.trace {
+		switch options.traceFormat {
+		case "", "text":
+			observer = NewTracer(os.Stderr, false, filename)
+		case "json":
+			observer = NewTracer(os.Stderr, true, filename)
+		default:
+			return fmt.Errorf("неизвестный формат трассировки %q: ожидался text или json", options.traceFormat)
+		}
+	}
+
+	if options.input != "" && options.inputData == nil {
+		data, err := os.ReadFile(options.input)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+		options.inputData = data
+	}
+	bindings, err := loadBindings(options.input, options.inputData, options.defines, options.bigint)
+	if err != nil {
+		return err
+	}
+	evals := make([]Expression, len(options.evals))
+	for n, code := range options.evals {
+		if evals[n], err = ParseExpression(code, options.bigint); err != nil {
+			return fmt.Errorf("-e %s: %v", code, err)
+		}
+	}
+
+	checker := NewChecker()
+	checker.BigInt = options.bigint
+	program, err := loadChecked(filename, options.units, options.dialect, checker)
+	if err != nil {
+		return err
+	}
+	var sources []SourceFile
+	if options.snapshot != "" || options.resume != nil {
+		if sources, err = sourceFiles(filename, program); err != nil {
+			return err
+		}
+	}
+	if options.resume != nil {
+		if err := options.resume.checkSources(sources); err != nil {
+			return err
+		}
+	}
+
+	// Интерпретация
+	root := options.root
+	if root == "" {
+		root = "."
+	}
+	var profile *Profile
+	if options.profile != "" || options.cover != "" {
+		profile = NewProfile()
+	}
+	steps := options.steps
+	if options.resume != nil && steps > 0 {
+		// Бюджет продолжения отсчитывается от точки снимка
+		steps += options.resume.Continuation.State.Steps
+	}
+	var resume *Continuation
+	if options.resume != nil {
+		resume = options.resume.Continuation
+	}
+	interpreter := NewInterpreterWithOptions(Options{
+		Files:    NewDirFS(root),
+		Profile:  profile,
+		Observer: observer,
+		BigInt:   options.bigint,
+		Bindings: bindings,
+		Steps:    steps,
+		Resume:   resume,
+	})
+	if options.snapshot != "" {
+		defer notifySuspend(interpreter)()
+	}
+	err = interpreter.Interpret(program)
+	// Отчеты записываются и после ошибки выполнения: они показывают, докуда дошла программа
+	if profile != nil {
+		if err := writeReports(profile.Report(program), filename, options); err != nil {
+			return err
+		}
+	}
+	var suspended *SuspendedError
+	if errors.As(err, &suspended) && options.snapshot != "" {
+		if suspended.Continuation == nil {
+			return fmt.Errorf("%w; снимок не записан: выполнение нельзя продолжить", err)
+		}
+		snapshot, serr := newSnapshot(filename, root, sources, options, suspended.Continuation)
+		if serr == nil {
+			serr = writeSnapshot(options.snapshot, snapshot)
+		}
+		if serr != nil {
+			return serr
+		}
+		return fmt.Errorf("%w; снимок записан в %s", err, options.snapshot)
+	}
+	if suspended != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка выполнения: %v", describeError(err))
+	}
+
+	if len(evals) > 0 {
+		// Вывод значений выражений -e вместо словаря переменных
+		for n, expr := range evals {
+			value, err := interpreter.Evaluate(expr)
+			if err != nil {
+				return fmt.Errorf("-e %s: %v", options.evals[n], describeError(err))
+			}
+			fmt.Println(value)
+		}
+	} else {
+		// Вывод значений всех переменных
+		fmt.Println(formatVariables(interpreter.Values()))
+	}
+	if options.leaks {
+		reportLeaks(os.Stderr, interpreter.Leaks())
+	}
+	if code := interpreter.ExitCode(); code != 0 {
+		return exitStatus(code)
+	}
+	return nil
+}
+
+// exitStatus - код выхода, заданный программой процедурой Halt
+type exitStatus int
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("код выхода %d", int(s))
+}
+
+// loadBindings собирает начальные значения переменных из содержимого data файла JSON input
+// и флагов -D; значение из -D заменяет значение той же переменной из файла. bigint - режим
+// длинных целых, в котором целые значения не ограничены int64.
+func loadBindings(input string, data []byte, defines []string, bigint bool) ([]Binding, error) {
+	var bindings []Binding
+	if input != "" {
+		var err error
+		if bindings, err = parseBindingsJSON(data, bigint); err != nil {
+			return nil, fmt.Errorf("%s: %v", input, err)
+		}
+	}
+	for _, define := range defines {
+		binding, err := parseDefine(define, bigint)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+// writeReports записывает отчеты о профиле и покрытии, заданные флагами -profile и -cover
+func writeReports(report *ProfileReport, filename string, options runOptions) error {
+	if options.profile != "" {
+		if err := writeReport(options.profile, report, filename, writeProfileText); err != nil {
+			return err
+		}
+	}
+	if options.cover != "" {
+		if err := writeReport(options.cover, report.Coverage(), filename, writeCoverText); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReport записывает отчет в файл path в формате, который задает расширение имени:
+// .json - JSON, .html - размеченный исходный текст программы, иначе - текст, который
+// формирует writeText. Путь "-" выводит текстовый отчет в stderr.
+func writeReport(path string, report *ProfileReport, filename string, writeText func(io.Writer, *ProfileReport) error) error {
+	if path == "-" {
+		return writeText(os.Stderr, report)
+	}
+	write := writeText
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		write = writeReportJSON
+	case ".html", ".htm":
+		write = func(w io.Writer, report *ProfileReport) error {
+			return writeReportHTML(w, report, filename)
+		}
+	}
+	var output bytes.Buffer
+	if err := write(&output, report); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, output.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла: %v", err)
+	}
+	return nil
+}
+
+// load читает, разбирает и проверяет программу из файла; модули из USES ищутся в каталоге
+// программы, затем в каталогах units. Если info задан, он заполняется при проверке.
+func load(filename, units string, info *Info) (*Program, error) {
+	checker := NewChecker()
+	checker.Info = info
+	return loadChecked(filename, units, DialectLenient, checker)
+}
+
+// loadChecked читает и разбирает программу из файла в диалекте dialect и проверяет ее
+// анализатором checker
+func loadChecked(filename, units string, dialect Dialect, checker *Checker) (*Program, error) {
+	return loadWith(filename, sourceLoader(filename, units, dialect, checker.BigInt), checker)
+}
+
+// loadWith читает и разбирает программу из файла, загружая модули загрузчиком loader, и
+// проверяет ее анализатором checker в диалекте загрузчика
+func loadWith(filename string, loader *UnitLoader, checker *Checker) (*Program, error) {
+	program, err := parseWith(filename, loader)
+	if err != nil {
+		return nil, err
+	}
+	checker.Dialect = loader.Dialect
+
+	// Семантический анализ
+	if err := checker.Check(program); err != nil {
+		return nil, fmt.Errorf("ошибка семантического анализа: %v", err)
+	}
+	return program, nil
+}
+
+// parseFile читает и разбирает программу из файла в диалекте dialect без семантического
+// анализа; bigint - режим длинных целых
+func parseFile(filename, units string, dialect Dialect, bigint bool) (*Program, error) {
+	return parseWith(filename, sourceLoader(filename, units, dialect, bigint))
+}
+
+// sourceLoader создает загрузчик модулей программы из файла filename: модули ищутся в
+// каталоге программы, затем в каталогах units
+func sourceLoader(filename, units string, dialect Dialect, bigint bool) *UnitLoader {
+	path := []string{filepath.Dir(filename)}
+	if units != "" {
+		path = append(path, filepath.SplitList(units)...)
+	}
+	loader := NewUnitLoader(path...)
+	loader.Dialect, loader.BigInt = dialect, bigint
+	return loader
+}
+
+// parseWith читает и разбирает программу из файла без семантического анализа в диалекте
+// и режиме длинных целых загрузчика loader, который загружает модули из USES
+func parseWith(filename string, loader *UnitLoader) (*Program, error) {
 	code, err := os.ReadFile(filename)
 	if err != nil {
-		return fmt.Errorf("ошибка чтения файла: %v", err)
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
 	}
 
 	// Лексический анализ
 	lexer := NewLexer(string(code))
+	lexer.Dialect = loader.Dialect
 	tokens, err := lexer.Tokenize()
 	if err != nil {
-		return fmt.Errorf("ошибка лексического анализа: %v", err)
+		return nil, fmt.Errorf("ошибка лексического анализа: %v", err)
 	}
 
 	// Синтаксический анализ
-	parser := NewParser(tokens)
+	parser := NewParserWithUnits(tokens, loader)
+	parser.Dialect, parser.BigInt = loader.Dialect, loader.BigInt
+	parser.Deviations = lexer.Deviations
 	program, err := parser.Parse()
+	var unitErr *unitError
+	if errors.As(err, &unitErr) {
+		return nil, fmt.Errorf("ошибка загрузки модуля: %v", err)
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка синтаксического анализа: %v", err)
+		return nil, fmt.Errorf("ошибка синтаксического анализа: %v", err)
 	}
+	return program, nil
+}
 
-	// Интерпретация
-	interpreter := NewInterpreter()
-	err = interpreter.Interpret(program)
+// renderDOT записывает AST программы из файла на языке DOT программы Graphviz в output;
+// путь "-" выводит граф в stdout. Дерево строится до семантического анализа, поэтому
+// константные выражения показываются в том виде, в котором записаны в программе.
+func renderDOT(filename, output, units string, dialect Dialect, bigint bool) error {
+	program, err := parseFile(filename, units, dialect, bigint)
 	if err != nil {
-		return fmt.Errorf("ошибка выполнения: %v", err)
+		return err
 	}
+	if output == "-" {
+		return writeDOT(os.Stdout, program)
+	}
+	var graph bytes.Buffer
+	if err := writeDOT(&graph, program); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, graph.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла: %v", err)
+	}
+	return nil
+}
 
-	// Вывод значений всех переменных
-	variables := interpreter.GetVariables()
-	if len(variables) == 0 {
-		fmt.Println("{}")
-	} else {
-		fmt.Print("{")
-		first := true
-		for name, value := range variables {
-			if !first {
-				fmt.Print(", ")
-			}
-			// Выводим целое число, если оно целое
-			if value == float64(int64(value)) {
-				fmt.Printf("%s: %d", name, int64(value))
-			} else {
-				fmt.Printf("%s: %g", name, value)
-			}
-			first = false
-		}
-		fmt.Println("}")
+// build транслирует программу из файла в исходный текст на Go и записывает его в output
+func build(filename, output, units string) error {
+	info := NewInfo()
+	program, err := load(filename, units, info)
+	if err != nil {
+		return err
+	}
+	code, err := buildGo(program, info, filepath.Base(filename))
+	if err != nil {
+		return fmt.Errorf("ошибка трансляции: %v", err)
+	}
+	if err := os.WriteFile(output, code, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла: %v", err)
+	}
+	return nil
+}
+
+// assemble транслирует программу из файла в исходный текст на ассемблере NASM для
+// x86-64 Linux и записывает его в output
+func assemble(filename, output, units string, dialect Dialect) error {
+	info := NewInfo()
+	checker := NewChecker()
+	checker.Info = info
+	program, err := loadChecked(filename, units, dialect, checker)
+	if err != nil {
+		return err
+	}
+	code, err := buildNASM(program, info, filepath.Base(filename))
+	if err != nil {
+		return fmt.Errorf("ошибка трансляции: %v", err)
+	}
+	if err := os.WriteFile(output, code, 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла: %v", err)
 	}
 	return nil
 }
 
+// buildWithExitCode выполняет команду pascal build и возвращает код выхода
+func buildWithExitCode(args []string) int {
+	var output, units string
+	flags := flag.NewFlagSet("pascal build", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&output, "o", "", "файл, в который записывается программа на Go")
+	flags.StringVar(&units, "units", "", "каталоги поиска модулей USES, разделенные '"+string(os.PathListSeparator)+"'")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: pascal build [-units каталоги] -o файл.go <файл.pas>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() < 1 || output == "" {
+		fmt.Println("Использование: pascal build [-units каталоги] -o файл.go <файл.pas>")
+		return 1
+	}
+
+	if err := build(flags.Arg(0), output, units); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// reportLeaks выводит динамические переменные, не освобожденные к концу программы
+func reportLeaks(w io.Writer, leaks []*HeapBlock) {
+	if len(leaks) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "утечка памяти: не освобождено блоков: %d\n", len(leaks))
+	for _, block := range leaks {
+		fmt.Fprintf(w, "  %s: %s = %s (выделен: %s)\n", PointerValue{Elem: block.Type, Addr: block.Addr}, block.Type, block.Value, block.Allocated)
+	}
+}
+
+// describeError дополняет ошибку выполнения позицией в исходном тексте, классом
+// необработанного исключения и стеком вызовов, если исключение покинуло подпрограмму
+func describeError(err error) string {
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		return err.Error()
+	}
+	var description strings.Builder
+	if runtimeErr.Pos.IsValid() {
+		fmt.Fprintf(&description, "%s: ", runtimeErr.Pos)
+	}
+	description.WriteString(runtimeErr.Message)
+	if runtimeErr.Class != nil {
+		fmt.Fprintf(&description, " (%s)", runtimeErr.Class)
+	}
+	if len(runtimeErr.Trace) > 0 {
+		description.WriteString("\nстек вызовов:")
+		for _, line := range traceLines(runtimeErr.Trace) {
+			fmt.Fprintf(&description, "\n  %s", line)
+		}
+	}
+	return description.String()
+}
+
+// formatVariables форматирует словарь переменных, упорядоченный по имени
+func formatVariables(variables map[string]Value) string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for n, name := range names {
+		parts[n] = fmt.Sprintf("%s: %s", name, variables[name])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
 func main() {
 	os.Exit(mainWithExitCode())
 }
 
 // mainWithExitCode выполняет основную логику и возвращает код выхода
 func mainWithExitCode() int {
-	if len(os.Args) < 2 {
-		fmt.Println("Использование: pascal <файл.pas>")
-		return 1
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		return buildWithExitCode(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		return lspWithExitCode(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "resume" {
+		return resumeWithExitCode(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		return testWithExitCode(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		return lintWithExitCode(os.Args[2:])
 	}
 
-	err := runInterpreter(os.Args[1])
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+	var options runOptions
+	flags := flag.NewFlagSet("pascal", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.BoolVar(&options.leaks, "leaks", false, "сообщить о неосвобожденной динамической памяти")
+	flags.BoolVar(&options.bigint, "bigint", false, "длинные целые без переполнения (math/big)")
+	flags.StringVar(&options.root, "root", ".", "каталог, вне которого программа не может открывать файлы")
+	flags.StringVar(&options.units, "units", "", "каталоги поиска модулей USES, разделенные '"+string(os.PathListSeparator)+"'")
+	flags.StringVar(&options.profile, "profile", "", "записать профиль выполнения в файл (.json, .html или текст; - для stderr)")
+	flags.StringVar(&options.cover, "cover", "", "записать покрытие операторов в файл (.json, .html или текст; - для stderr)")
+	flags.BoolVar(&options.trace, "trace", false, "выводить в stderr каждое присваивание с прежним и новым значением")
+	flags.StringVar(&options.traceFormat, "trace-format", "text", "формат трассировки: text или json")
+	flags.StringVar(&options.asm, "S", "", "записать программу на ассемблере NASM (x86-64 Linux) в файл вместо выполнения")
+	flags.Var((*stringList)(&options.defines), "D", "задать начальное значение переменной: имя=выражение (можно указать несколько раз)")
+	flags.StringVar(&options.input, "input", "", "файл JSON с начальными значениями переменных {\"имя\": значение}")
+	flags.Var((*stringList)(&options.evals), "e", "вывести значение выражения после выполнения вместо словаря переменных (можно указать несколько раз)")
+	flags.Int64Var(&options.steps, "steps", 0, "остановить выполнение после указанного числа операторов (0 - без ограничения)")
+	flags.StringVar(&options.snapshot, "snapshot", "", "записать снимок выполнения в файл при остановке по -steps или сигналу SIGUSR1")
+	flags.StringVar(&options.dot, "dot", "", "записать AST программы в формате Graphviz DOT в файл (- для stdout) вместо выполнения")
+	flags.BoolVar(&options.watch, "watch", false, "выполнять программу заново при каждом сохранении файла и выделять изменившиеся значения")
+	flags.Var(&options.dialect, "dialect", "вариант языка: iso (строгий ISO 7185), turbo или lenient")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: pascal [-leaks] [-bigint] [-dialect iso|turbo|lenient] [-root каталог] [-units каталоги] [-profile файл] [-cover файл] [-trace] [-input файл.json] [-D имя=значение] [-e выражение] [-steps N] [-snapshot файл] <файл.pas>")
+		fmt.Fprintln(flags.Output(), "       pascal -watch [-bigint] [-dialect iso|turbo|lenient] [-root каталог] [-units каталоги] [-input файл.json] [-D имя=значение] <файл.pas>")
+		fmt.Fprintln(flags.Output(), "       pascal [-dialect iso|turbo|lenient] [-units каталоги] -S файл.asm <файл.pas>")
+		fmt.Fprintln(flags.Output(), "       pascal [-dialect iso|turbo|lenient] [-units каталоги] -dot файл.dot <файл.pas>")
+		fmt.Fprintln(flags.Output(), "       pascal build [-units каталоги] -o файл.go <файл.pas>")
+		fmt.Fprintln(flags.Output(), "       pascal resume [-steps N] [-snapshot файл] [-show] <снимок>")
+		fmt.Fprintln(flags.Output(), "       pascal test [-update] [-j N] [-timeout время] [-junit файл.xml] [-units каталоги] <каталог>")
+		fmt.Fprintln(flags.Output(), "       pascal test -unit [-j N] [-timeout время] [-junit файл.xml] [-units каталоги] <файл.pas>")
+		fmt.Fprintln(flags.Output(), "       pascal lint [-config файл.json] [-format text|sarif] [-units каталоги] <файл.pas>...")
+		fmt.Fprintln(flags.Output(), "       pascal lsp [-units каталоги]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil {
 		return 1
 	}
-	return 0
+	if flags.NArg() < 1 {
+		fmt.Println("Использование: pascal [-leaks] [-bigint] [-dialect iso|turbo|lenient] [-root каталог] [-units каталоги] [-profile файл] [-cover файл] [-trace] [-input файл.json] [-D имя=значение] [-e выражение] [-steps N] [-snapshot файл] <файл.pas>")
+		return 1
+	}
+
+	var err error
+	switch {
+	case options.dot != "":
+		err = renderDOT(flags.Arg(0), options.dot, options.units, options.dialect, options.bigint)
+	case options.asm != "":
+		err = assemble(flags.Arg(0), options.asm, options.units, options.dialect)
+	case options.watch:
+		err = watch(flags.Arg(0), options, os.Stdout, nil)
+	default:
+		err = run(flags.Arg(0), options)
+	}
+	return exitCode(err)
 }
 
+// exitCode выводит ошибку и возвращает код выхода: 0 - успех, 2 - выполнение остановлено
+// по -steps или сигналу, 1 - другая ошибка
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var status exitStatus
+	if errors.As(err, &status) {
+		return int(status)
+	}
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	var suspended *SuspendedError
+	if errors.As(err, &suspended) {
+		return 2
+	}
+	return 1
+}
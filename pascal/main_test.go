@@ -49,6 +49,24 @@ func TestRunInterpreterParserError(t *testing.T) {
 	}
 }
 
+// TestRunInterpreterCheckerError тестирует runInterpreter с ошибкой семантического анализа
+func TestRunInterpreterCheckerError(t *testing.T) {
+	// Создаем временный файл с присваиванием константе
+	tmpfile, err := os.CreateTemp("", "test_*.pas")
+	if err != nil {
+		t.Fatalf("Ошибка создания временного файла: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString("CONST N = 1; BEGIN N := 5; END.")
+	tmpfile.Close()
+
+	err = runInterpreter(tmpfile.Name())
+	if err == nil {
+		t.Error("Ожидалась ошибка семантического анализа")
+	}
+}
+
 // TestRunInterpreterInterpreterError тестирует runInterpreter с ошибкой интерпретатора
 func TestRunInterpreterInterpreterError(t *testing.T) {
 	// Создаем временный файл с делением на ноль
@@ -0,0 +1,858 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nasmRuntime - подпрограммы вывода и сообщений об ошибках, которые транслятор дописывает
+// в конец каждой программы на ассемблере
+//
+//go:embed nasmruntime/runtime.asm
+var nasmRuntime string
+
+// nasmLoop - цикл, в котором транслируются Break и Continue
+type nasmLoop struct {
+	exit, next string // метки выхода из цикла и перехода к следующей итерации
+}
+
+// nasmGenerator транслирует программу над целыми и логическими значениями в ассемблер
+// NASM для x86-64 Linux. Значение выражения вычисляется в rax, левый операнд бинарной
+// операции сохраняется в стеке.
+type nasmGenerator struct {
+	info     *Info
+	labels   map[*Symbol]string // метки переменных
+	implicit []*Symbol          // неописанные переменные: в словарь выводятся только получившие значение
+	byName   map[string]*Symbol // неописанные переменные по имени для чтения до первого присваивания
+	temps    int                // вспомогательные переменные циклов FOR
+	strings  map[string]string  // строки сообщений и вывода и их метки
+	data     strings.Builder
+	text     strings.Builder
+	errors   strings.Builder // переходы к сообщениям об ошибках выполнения
+	failures map[string]string
+	loops    []nasmLoop
+	count    int // счетчик меток
+	err      error
+}
+
+// buildNASM транслирует программу, проверенную семантическим анализатором с заполнением info,
+// в исходный текст на ассемблере NASM. Поддерживаются переменные INTEGER и BOOLEAN, выражения,
+// присваивания, составные операторы, IF, WHILE, REPEAT, FOR, CASE, Break, Continue, Write и
+// WriteLn; остальное - ошибка трансляции. source - имя исходного файла для заголовка.
+func buildNASM(program *Program, info *Info, source string) ([]byte, error) {
+	g := &nasmGenerator{
+		info:     info,
+		labels:   make(map[*Symbol]string),
+		byName:   make(map[string]*Symbol),
+		strings:  make(map[string]string),
+		failures: make(map[string]string),
+	}
+	code := g.program(program, source)
+	if g.err != nil {
+		return nil, g.err
+	}
+	return []byte(code), nil
+}
+
+// fail запоминает первую ошибку трансляции
+func (g *nasmGenerator) fail(pos Position, format string, args ...interface{}) {
+	if g.err != nil {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	if pos.IsValid() {
+		g.err = fmt.Errorf("%s: %s", pos, message)
+	} else {
+		g.err = fmt.Errorf("%s", message)
+	}
+}
+
+// unsupported сообщает о конструкции, которую транслятор в ассемблер не поддерживает
+func (g *nasmGenerator) unsupported(pos Position, what string) {
+	g.fail(pos, "%s не поддерживается в ассемблере", what)
+}
+
+// emit добавляет команду в код программы
+func (g *nasmGenerator) emit(format string, args ...interface{}) {
+	fmt.Fprintf(&g.text, "    "+format+"\n", args...)
+}
+
+// label ставит метку в коде программы
+func (g *nasmGenerator) label(name string) {
+	g.text.WriteString(name + ":\n")
+}
+
+// newLabel возвращает новую локальную метку с префиксом kind
+func (g *nasmGenerator) newLabel(kind string) string {
+	g.count++
+	return "." + kind + strconv.Itoa(g.count)
+}
+
+// nasmString возвращает операнды db для строки: печатаемые символы в кавычках, остальные байты числами
+func nasmString(text string) string {
+	var parts []string
+	var quoted strings.Builder
+	flush := func() {
+		if quoted.Len() > 0 {
+			parts = append(parts, `"`+quoted.String()+`"`)
+			quoted.Reset()
+		}
+	}
+	for n := 0; n < len(text); n++ {
+		c := text[n]
+		if c < ' ' || c == '"' || c == 127 {
+			flush()
+			parts = append(parts, strconv.Itoa(int(c)))
+			continue
+		}
+		quoted.WriteByte(c)
+	}
+	flush()
+	return strings.Join(parts, ", ")
+}
+
+// str возвращает метку строки в разделе данных; длина строки - метка с суффиксом _len
+func (g *nasmGenerator) str(text string) string {
+	if name, ok := g.strings[text]; ok {
+		return name
+	}
+	name := "s" + strconv.Itoa(len(g.strings)+1)
+	g.strings[text] = name
+	fmt.Fprintf(&g.data, "    %-15s db %s\n    %-15s equ $ - %s\n", name, nasmString(text), name+"_len", name)
+	return name
+}
+
+// failure возвращает метку перехода к сообщению об ошибке выполнения, как в интерпретаторе
+func (g *nasmGenerator) failure(pos Position, class *Type, format string, args ...interface{}) string {
+	message := "ошибка выполнения: "
+	if pos.IsValid() {
+		message += pos.String() + ": "
+	}
+	message += fmt.Sprintf(format, args...)
+	if class != nil {
+		message += " (" + class.Name + ")"
+	}
+	if label, ok := g.failures[message]; ok {
+		return label
+	}
+	label := ".error" + strconv.Itoa(len(g.failures)+1)
+	g.failures[message] = label
+	text := g.str(message + "\n")
+	fmt.Fprintf(&g.errors, "%s:\n    lea rsi, [%s]\n    mov rdx, %s_len\n    jmp rt_fail\n", label, text, text)
+	return label
+}
+
+// overflow возвращает метку сообщения о целочисленном переполнении
+func (g *nasmGenerator) overflow(pos Position, name string) string {
+	if name != "" {
+		return g.failure(pos, intOverflowClass, "%s: целочисленное переполнение", name)
+	}
+	return g.failure(pos, intOverflowClass, "целочисленное переполнение")
+}
+
+// program транслирует программу: переменные, операторы, вывод словаря переменных и среду выполнения
+func (g *nasmGenerator) program(program *Program, source string) string {
+	if len(program.Uses) > 0 {
+		g.unsupported(program.Uses[0].Pos, "модуль "+program.Uses[0].Name)
+	}
+	if len(program.Routines) > 0 {
+		g.unsupported(program.Routines[0].Pos, "процедура "+program.Routines[0].Name)
+	}
+
+	var variables []*Symbol
+	for _, decl := range program.Vars {
+		if symbol := g.info.Symbols[decl]; symbol != nil {
+			if g.kind(symbol.Type) == nil {
+				g.unsupported(decl.Pos, "переменная "+decl.Name+" типа "+symbol.Type.String())
+			}
+			variables = append(variables, symbol)
+		}
+	}
+	seen := make(map[*Symbol]bool)
+	for _, stmt := range program.Statements {
+		Inspect(stmt, func(node Node) bool {
+			switch node.(type) {
+			case *Assignment, *ForStatement, *Identifier:
+			default:
+				return true
+			}
+			symbol := g.info.Symbols[node]
+			if symbol != nil && symbol.Implicit && symbol.Kind == SymbolVar && !seen[symbol] {
+				seen[symbol] = true
+				if g.kind(symbol.Type) == nil {
+					g.unsupported(symbol.Pos, "переменная "+symbol.Name+" типа "+symbol.Type.String())
+				}
+				g.implicit = append(g.implicit, symbol)
+				g.byName[strings.ToLower(symbol.Name)] = symbol
+				variables = append(variables, symbol)
+			}
+			return true
+		})
+	}
+	if g.err != nil {
+		return ""
+	}
+	for n, symbol := range variables {
+		g.labels[symbol] = "v_" + nasmName(symbol.Name, n+1)
+	}
+
+	g.statements(program.Statements)
+	g.emit("call rt_list_open")
+	sort.SliceStable(variables, func(a, b int) bool { return variables[a].Name < variables[b].Name })
+	for _, symbol := range variables {
+		g.dump(symbol)
+	}
+	g.emit("call rt_list_close")
+	g.emit("jmp rt_exit")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "; Сгенерировано pascal -S из %s. Не редактировать.\n", source)
+	b.WriteString("; Сборка (x86-64 Linux): nasm -f elf64 программа.asm && ld программа.o -o программа\n\n")
+	b.WriteString("default rel\nglobal _start\n\nsection .data\n")
+	b.WriteString(g.data.String())
+	b.WriteString("\nsection .bss\n")
+	for _, symbol := range variables {
+		fmt.Fprintf(&b, "    %-15s resq 1              ; %s\n", g.labels[symbol], symbol.Name)
+	}
+	for n := 1; n <= g.temps; n++ {
+		fmt.Fprintf(&b, "    %-15s resq 1\n", "t"+strconv.Itoa(n))
+	}
+	for _, symbol := range g.implicit {
+		fmt.Fprintf(&b, "    %-15s resb 1              ; %s получила значение\n", nasmFlag(g.labels[symbol]), symbol.Name)
+	}
+	b.WriteString("\nsection .text\n\n_start:\n")
+	b.WriteString(g.text.String())
+	if g.errors.Len() > 0 {
+		b.WriteString("\n; Ошибки выполнения\n")
+		b.WriteString(g.errors.String())
+	}
+	b.WriteString("\n")
+	b.WriteString(nasmRuntime)
+	return b.String()
+}
+
+// nasmName возвращает имя переменной для метки; имя, которое NASM не примет, заменяется номером
+func nasmName(name string, n int) string {
+	for _, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return strconv.Itoa(n)
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// nasmFlag возвращает метку признака того, что неописанная переменная получила значение
+func nasmFlag(label string) string {
+	return "f" + strings.TrimPrefix(label, "v")
+}
+
+// kind возвращает базовый тип значения, если это INTEGER или BOOLEAN, иначе nil
+func (g *nasmGenerator) kind(t *Type) *Type {
+	if t == nil {
+		// Тип неописанной переменной, не определенный анализатором; интерпретатор
+		// читает такую переменную как 0
+		return integerType
+	}
+	switch t.Kind {
+	case TypeInteger, TypeBoolean:
+		return t
+	}
+	return nil
+}
+
+// typeOf возвращает тип выражения, если он поддерживается, иначе сообщает об ошибке
+func (g *nasmGenerator) typeOf(expr Expression, pos Position) *Type {
+	t := g.info.Types[expr]
+	if id, ok := expr.(*Identifier); ok && t == nil {
+		if symbol := g.info.Symbols[id]; symbol != nil && symbol.Kind == SymbolVar {
+			t = symbol.Type
+		}
+	}
+	if t == nil {
+		if e, ok := expr.(*BinaryOp); ok {
+			t, _ = binaryType(e.Operator, g.typeOf(e.Left, pos), g.typeOf(e.Right, pos))
+		}
+	}
+	kind := g.kind(t)
+	if kind == nil {
+		g.unsupported(pos, "значение типа "+t.String())
+		return integerType
+	}
+	return kind
+}
+
+// dump выводит переменную в словарь; неописанная переменная выводится, если получила значение
+func (g *nasmGenerator) dump(symbol *Symbol) {
+	label := g.labels[symbol]
+	skip := ""
+	if symbol.Implicit {
+		skip = g.newLabel("dump")
+		g.emit("cmp byte [%s], 0", nasmFlag(label))
+		g.emit("je %s", skip)
+	}
+	g.emit("call rt_list_next")
+	name := g.str(symbol.Name + ": ")
+	g.emit("lea rsi, [%s]", name)
+	g.emit("mov rdx, %s_len", name)
+	g.emit("call rt_write")
+	g.emit("mov rax, [%s]", label)
+	if g.kind(symbol.Type).Kind == TypeBoolean {
+		g.emit("call rt_print_bool")
+	} else {
+		g.emit("call rt_print_int")
+	}
+	if skip != "" {
+		g.label(skip)
+	}
+}
+
+// Операторы
+
+func (g *nasmGenerator) statements(statements []Statement) {
+	for _, stmt := range statements {
+		g.statement(stmt)
+	}
+}
+
+func (g *nasmGenerator) statement(stmt Statement) {
+	VisitStatement[struct{}](nasmStatement{g}, stmt)
+}
+
+// nasmStatement транслирует операторы как StatementVisitor
+type nasmStatement struct {
+	*nasmGenerator
+}
+
+func (g nasmStatement) VisitAssignment(s *Assignment) struct{} {
+	symbol := g.info.Symbols[s]
+	if s.Target != nil {
+		g.unsupported(s.Pos, "присваивание "+designatorName(s.Target))
+		return struct{}{}
+	}
+	if symbol == nil || symbol.Kind != SymbolVar {
+		g.unsupported(s.Pos, "присваивание "+s.Variable)
+		return struct{}{}
+	}
+	fmt.Fprintf(&g.text, "    ; %s: %s :=\n", s.Pos, s.Variable)
+	g.expression(s.Value, s.Pos)
+	g.store(symbol)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitBlock(s *Block) struct{} {
+	g.statements(s.Statements)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitIfStatement(s *IfStatement) struct{} {
+	otherwise, end := g.newLabel("else"), g.newLabel("endif")
+	g.condition(s.Cond, otherwise)
+	g.statement(s.Then)
+	if s.Else != nil {
+		g.emit("jmp %s", end)
+		g.label(otherwise)
+		g.statement(s.Else)
+		g.label(end)
+	} else {
+		g.label(otherwise)
+	}
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitWhileStatement(s *WhileStatement) struct{} {
+	start, end := g.newLabel("while"), g.newLabel("endwhile")
+	g.label(start)
+	g.condition(s.Cond, end)
+	g.loop(nasmLoop{exit: end, next: start}, s.Body)
+	g.emit("jmp %s", start)
+	g.label(end)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitRepeatStatement(s *RepeatStatement) struct{} {
+	start, until, end := g.newLabel("repeat"), g.newLabel("until"), g.newLabel("endrepeat")
+	g.label(start)
+	g.loop(nasmLoop{exit: end, next: until}, s.Body)
+	g.label(until)
+	g.condition(s.Cond, start)
+	g.label(end)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitForStatement(s *ForStatement) struct{} {
+	g.forStatement(s)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitCaseStatement(s *CaseStatement) struct{} {
+	g.caseStatement(s)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitBreakStatement(*BreakStatement) struct{} {
+	g.emit("jmp %s", g.loops[len(g.loops)-1].exit)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitContinueStatement(*ContinueStatement) struct{} {
+	g.emit("jmp %s", g.loops[len(g.loops)-1].next)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitCallStatement(s *CallStatement) struct{} {
+	g.callStatement(s)
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitTryStatement(s *TryStatement) struct{} {
+	g.unsupported(s.Pos, "оператор TRY")
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitRaiseStatement(s *RaiseStatement) struct{} {
+	g.unsupported(s.Pos, "оператор RAISE")
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitExitStatement(s *ExitStatement) struct{} {
+	g.unsupported(s.Pos, "Exit")
+	return struct{}{}
+}
+
+func (g nasmStatement) VisitOtherStatement(Statement) struct{} {
+	g.unsupported(Position{}, "оператор")
+	return struct{}{}
+}
+
+// store сохраняет rax в переменной и отмечает, что неописанная переменная получила значение
+func (g *nasmGenerator) store(symbol *Symbol) {
+	g.emit("mov [%s], rax", g.labels[symbol])
+	if symbol.Implicit {
+		g.emit("mov byte [%s], 1", nasmFlag(g.labels[symbol]))
+	}
+}
+
+// loop транслирует тело цикла, в котором Break переходит к exit, а Continue - к next
+func (g *nasmGenerator) loop(loop nasmLoop, body Statement) {
+	g.loops = append(g.loops, loop)
+	g.statement(body)
+	g.loops = g.loops[:len(g.loops)-1]
+}
+
+// condition вычисляет логическое выражение и переходит к метке, если оно ложно
+func (g *nasmGenerator) condition(cond Expression, otherwise string) {
+	g.expression(cond, Position{})
+	g.emit("test rax, rax")
+	g.emit("jz %s", otherwise)
+}
+
+// forStatement транслирует FOR: границы вычисляются один раз, итерации считает отдельный
+// счетчик, а последняя итерация определяется сравнением до приращения, как в интерпретаторе
+func (g *nasmGenerator) forStatement(s *ForStatement) {
+	symbol := g.info.Symbols[s]
+	if symbol == nil || symbol.Kind != SymbolVar || g.kind(symbol.Type) == nil || g.kind(symbol.Type).Kind != TypeInteger {
+		g.unsupported(s.Pos, "цикл FOR по переменной "+s.Variable)
+		return
+	}
+	counter, last := "t"+strconv.Itoa(g.temps+1), "t"+strconv.Itoa(g.temps+2)
+	g.temps += 2
+	start, next, end := g.newLabel("for"), g.newLabel("next"), g.newLabel("endfor")
+	jump, step := "jg", "inc"
+	if s.Down {
+		jump, step = "jl", "dec"
+	}
+	fmt.Fprintf(&g.text, "    ; %s: FOR %s\n", s.Pos, s.Variable)
+	g.expression(s.Start, s.Pos)
+	g.emit("push rax")
+	g.expression(s.End, s.Pos)
+	g.emit("mov [%s], rax", last)
+	g.emit("pop rax")
+	g.emit("cmp rax, [%s]", last)
+	g.emit("%s %s", jump, end)
+	g.emit("mov [%s], rax", counter)
+	g.label(start)
+	g.emit("mov rax, [%s]", counter)
+	g.store(symbol)
+	g.loop(nasmLoop{exit: end, next: next}, s.Body)
+	g.label(next)
+	g.emit("mov rax, [%s]", counter)
+	g.emit("cmp rax, [%s]", last)
+	g.emit("je %s", end)
+	g.emit("%s qword [%s]", step, counter)
+	g.emit("jmp %s", start)
+	g.label(end)
+}
+
+// caseStatement транслирует CASE последовательным сравнением выражения с метками
+func (g *nasmGenerator) caseStatement(s *CaseStatement) {
+	if t := g.typeOf(s.Expr, s.Pos); t.Kind != TypeInteger {
+		g.unsupported(s.Pos, "CASE по значению типа "+t.String())
+		return
+	}
+	end := g.newLabel("endcase")
+	g.expression(s.Expr, s.Pos)
+	branches := make([]string, len(s.Branches))
+	for n, branch := range s.Branches {
+		branches[n] = g.newLabel("case")
+		for _, label := range branch.Labels {
+			low := g.constant(label.Low)
+			if label.High == nil {
+				g.compare(low)
+				g.emit("je %s", branches[n])
+				continue
+			}
+			skip := g.newLabel("label")
+			g.compare(low)
+			g.emit("jl %s", skip)
+			g.compare(g.constant(label.High))
+			g.emit("jle %s", branches[n])
+			g.label(skip)
+		}
+	}
+	if s.Else != nil {
+		g.statements(s.Else.Statements)
+		g.emit("jmp %s", end)
+	} else {
+		prefix := g.str(fmt.Sprintf("ошибка выполнения: %s: значение ", s.Pos))
+		suffix := g.str(" не соответствует ни одной метке CASE\n")
+		g.emit("lea rsi, [%s]", prefix)
+		g.emit("mov rdx, %s_len", prefix)
+		g.emit("lea r9, [%s]", suffix)
+		g.emit("mov r10, %s_len", suffix)
+		g.emit("jmp rt_fail_value")
+	}
+	for n, branch := range s.Branches {
+		g.label(branches[n])
+		g.statement(branch.Body)
+		g.emit("jmp %s", end)
+	}
+	g.label(end)
+}
+
+// constant возвращает значение метки CASE
+func (g *nasmGenerator) constant(expr Expression) int64 {
+	value, ok := constantValue(expr)
+	n, isInteger := value.(IntegerValue)
+	if !ok || !isInteger {
+		g.unsupported(Position{}, "нецелая метка CASE")
+	}
+	return int64(n)
+}
+
+// compare сравнивает rax с константой; 64-разрядная константа загружается в rcx
+func (g *nasmGenerator) compare(value int64) {
+	if value >= math.MinInt32 && value <= math.MaxInt32 {
+		g.emit("cmp rax, %d", value)
+		return
+	}
+	g.emit("mov rcx, %s", nasmInteger(value))
+	g.emit("cmp rax, rcx")
+}
+
+// nasmInteger записывает целую константу; -MAXINT-1 записывается шестнадцатеричной
+func nasmInteger(value int64) string {
+	if value == math.MinInt64 {
+		return "0x8000000000000000"
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// callStatement транслирует Write и WriteLn. Как и в интерпретаторе, все параметры
+// вычисляются до вывода; их значения сохраняются в стеке.
+func (g *nasmGenerator) callStatement(s *CallStatement) {
+	name := strings.ToLower(s.Name)
+	if g.info.Symbols[s] != nil || name != "write" && name != "writeln" {
+		g.unsupported(s.Pos, "процедура "+s.Name)
+		return
+	}
+	fmt.Fprintf(&g.text, "    ; %s: %s\n", s.Pos, s.Name)
+	type argument struct {
+		text string // строка или символ
+		t    *Type
+	}
+	args := make([]argument, len(s.Args))
+	pushed := 0
+	for n, arg := range s.Args {
+		if value, ok := constantValue(arg); ok {
+			switch v := value.(type) {
+			case StringValue:
+				args[n].text = string(v)
+				continue
+			case CharValue:
+				args[n].text = string(rune(v))
+				continue
+			}
+		}
+		if format, ok := arg.(*FormatExpr); ok {
+			g.unsupported(format.Pos, "формат вывода")
+			return
+		}
+		args[n].t = g.typeOf(arg, s.Pos)
+		g.expression(arg, s.Pos)
+		g.emit("push rax")
+		pushed++
+	}
+	values := pushed
+	for _, arg := range args {
+		if arg.t == nil {
+			if arg.text != "" {
+				label := g.str(arg.text)
+				g.emit("lea rsi, [%s]", label)
+				g.emit("mov rdx, %s_len", label)
+				g.emit("call rt_write")
+			}
+			continue
+		}
+		pushed--
+		g.emit("mov rax, [rsp + %d]", pushed*8)
+		if arg.t.Kind == TypeBoolean {
+			g.emit("call rt_print_bool")
+		} else {
+			g.emit("call rt_print_int")
+		}
+	}
+	if values > 0 {
+		g.emit("add rsp, %d", values*8)
+	}
+	if name == "writeln" {
+		g.emit("call rt_print_newline")
+	}
+}
+
+// Выражения
+
+// expression вычисляет выражение в rax; логические значения - 0 и 1
+func (g *nasmGenerator) expression(expr Expression, pos Position) {
+	if value, ok := constantValue(expr); ok {
+		switch v := value.(type) {
+		case IntegerValue:
+			g.emit("mov rax, %s", nasmInteger(int64(v)))
+			return
+		case BooleanValue:
+			if v {
+				g.emit("mov rax, 1")
+			} else {
+				g.emit("xor rax, rax")
+			}
+			return
+		}
+		g.unsupported(pos, "значение "+value.String())
+		return
+	}
+	VisitExpression[struct{}](nasmExpression{g, pos}, expr)
+}
+
+// nasmExpression вычисляет неконстантные выражения в rax как ExpressionVisitor; pos -
+// позиция для сообщения о неподдерживаемом выражении без своей позиции
+type nasmExpression struct {
+	*nasmGenerator
+	pos Position
+}
+
+func (g nasmExpression) VisitIdentifier(e *Identifier) struct{} {
+	symbol := g.info.Symbols[e]
+	if symbol == nil {
+		// Интерпретатор читает неописанную переменную до первого присваивания как целое 0
+		symbol = g.byName[strings.ToLower(e.Name)]
+		if symbol != nil && (g.kind(symbol.Type) == nil || g.kind(symbol.Type).Kind != TypeInteger) {
+			g.unsupported(e.Pos, "чтение неописанной переменной "+e.Name+" до присваивания")
+			return struct{}{}
+		}
+	}
+	if symbol == nil {
+		// Переменная, которой нигде не присваивается значение; интерпретатор читает 0
+		g.emit("xor rax, rax")
+		return struct{}{}
+	}
+	if symbol.Kind != SymbolVar || g.labels[symbol] == "" {
+		g.unsupported(e.Pos, "обращение к "+e.Name)
+		return struct{}{}
+	}
+	if g.kind(symbol.Type) == nil {
+		g.unsupported(e.Pos, "переменная "+e.Name+" типа "+symbol.Type.String())
+		return struct{}{}
+	}
+	g.emit("mov rax, [%s]", g.labels[symbol])
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitBinaryOp(e *BinaryOp) struct{} {
+	g.binary(e)
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitUnaryOp(e *UnaryOp) struct{} {
+	if g.typeOf(e.Operand, e.Pos).Kind != TypeBoolean {
+		g.unsupported(e.Pos, "операция NOT над целым")
+		return struct{}{}
+	}
+	g.expression(e.Operand, e.Pos)
+	g.emit("xor rax, 1")
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitCallExpr(e *CallExpr) struct{} {
+	g.builtin(e)
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitDereference(e *Dereference) struct{} {
+	g.unsupported(e.Pos, "операция ^")
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitFieldAccess(e *FieldAccess) struct{} {
+	g.unsupported(e.Pos, "обращение к полю "+e.Field)
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitSetConstructor(e *SetConstructor) struct{} {
+	g.unsupported(e.Pos, "конструктор множества")
+	return struct{}{}
+}
+
+func (g nasmExpression) VisitCreateExpr(e *CreateExpr) struct{} {
+	g.unsupported(e.Pos, "создание объекта "+e.Class)
+	return struct{}{}
+}
+
+// Числа и литералы - константы, которые expression транслирует до посетителя; формат
+// ширины и точности допустим только в Write и WriteLn
+func (g nasmExpression) VisitNumber(e *Number) struct{}         { return g.VisitOtherExpression(e) }
+func (g nasmExpression) VisitLiteral(e *Literal) struct{}       { return g.VisitOtherExpression(e) }
+func (g nasmExpression) VisitFormatExpr(e *FormatExpr) struct{} { return g.VisitOtherExpression(e) }
+
+func (g nasmExpression) VisitOtherExpression(Expression) struct{} {
+	g.unsupported(g.pos, "выражение")
+	return struct{}{}
+}
+
+// nasmConditions содержит команды setcc для операций сравнения
+var nasmConditions = map[TokenType]string{
+	TokenEQUAL:        "sete",
+	TokenNOTEQUAL:     "setne",
+	TokenLESS:         "setl",
+	TokenLESSEQUAL:    "setle",
+	TokenGREATER:      "setg",
+	TokenGREATEREQUAL: "setge",
+}
+
+// binary вычисляет бинарную операцию с контролем переполнения и деления на ноль
+func (g *nasmGenerator) binary(e *BinaryOp) {
+	lt, rt := g.typeOf(e.Left, e.Pos), g.typeOf(e.Right, e.Pos)
+	if e.Operator == TokenAND || e.Operator == TokenOR {
+		// Правый операнд не вычисляется, если результат известен по левому
+		end := g.newLabel("and")
+		jump := "jz"
+		if e.Operator == TokenOR {
+			end, jump = g.newLabel("or"), "jnz"
+		}
+		g.expression(e.Left, e.Pos)
+		g.emit("test rax, rax")
+		g.emit("%s %s", jump, end)
+		g.expression(e.Right, e.Pos)
+		g.label(end)
+		return
+	}
+	if setcc, ok := nasmConditions[e.Operator]; ok {
+		if lt.Kind != rt.Kind {
+			g.unsupported(e.Pos, "сравнение "+lt.String()+" и "+rt.String())
+			return
+		}
+		g.operands(e)
+		g.emit("cmp rax, rcx")
+		g.emit("%s al", setcc)
+		g.emit("movzx rax, al")
+		return
+	}
+	if lt.Kind != TypeInteger || rt.Kind != TypeInteger {
+		g.unsupported(e.Pos, "операция "+operatorSymbol(e.Operator)+" над "+lt.String()+" и "+rt.String())
+		return
+	}
+	g.operands(e)
+	switch e.Operator {
+	case TokenPLUS:
+		g.emit("add rax, rcx")
+		g.emit("jo %s", g.overflow(e.Pos, ""))
+	case TokenMINUS:
+		g.emit("sub rax, rcx")
+		g.emit("jo %s", g.overflow(e.Pos, ""))
+	case TokenMULTIPLY:
+		g.emit("imul rax, rcx")
+		g.emit("jo %s", g.overflow(e.Pos, ""))
+	case TokenDIV, TokenMOD:
+		// idiv не делит -MAXINT-1 на -1: DIV на -1 - смена знака, MOD на -1 - ноль
+		negative, end := g.newLabel("divide"), g.newLabel("enddivide")
+		g.emit("test rcx, rcx")
+		g.emit("jz %s", g.failure(e.Pos, divByZeroClass, "деление на ноль"))
+		g.emit("cmp rcx, -1")
+		g.emit("je %s", negative)
+		g.emit("cqo")
+		g.emit("idiv rcx")
+		if e.Operator == TokenMOD {
+			g.emit("mov rax, rdx")
+		}
+		g.emit("jmp %s", end)
+		g.label(negative)
+		if e.Operator == TokenDIV {
+			g.emit("neg rax")
+			g.emit("jo %s", g.overflow(e.Pos, ""))
+		} else {
+			g.emit("xor rax, rax")
+		}
+		g.label(end)
+	default:
+		g.unsupported(e.Pos, "операция "+operatorSymbol(e.Operator))
+	}
+}
+
+// operands вычисляет левый операнд в rax, а правый - в rcx
+func (g *nasmGenerator) operands(e *BinaryOp) {
+	g.expression(e.Left, e.Pos)
+	g.emit("push rax")
+	g.expression(e.Right, e.Pos)
+	g.emit("mov rcx, rax")
+	g.emit("pop rax")
+}
+
+// builtin вычисляет стандартную функцию целого аргумента
+func (g *nasmGenerator) builtin(e *CallExpr) {
+	name := strings.ToLower(e.Name)
+	if g.info.Symbols[e] != nil || len(e.Args) != 1 {
+		g.unsupported(e.Pos, "функция "+e.Name)
+		return
+	}
+	t := g.typeOf(e.Args[0], e.Pos)
+	if t.Kind != TypeInteger && name != "ord" {
+		g.unsupported(e.Pos, "функция "+e.Name+" от "+t.String())
+		return
+	}
+	g.expression(e.Args[0], e.Pos)
+	switch name {
+	case "abs":
+		end := g.newLabel("abs")
+		g.emit("test rax, rax")
+		g.emit("jns %s", end)
+		g.emit("neg rax")
+		g.emit("jo %s", g.overflow(e.Pos, e.Name))
+		g.label(end)
+	case "sqr":
+		g.emit("imul rax, rax")
+		g.emit("jo %s", g.overflow(e.Pos, e.Name))
+	case "odd":
+		g.emit("and rax, 1")
+	case "succ":
+		g.emit("add rax, 1")
+		g.emit("jo %s", g.overflow(e.Pos, e.Name))
+	case "pred":
+		g.emit("sub rax, 1")
+		g.emit("jo %s", g.overflow(e.Pos, e.Name))
+	case "ord":
+	default:
+		g.unsupported(e.Pos, "функция "+e.Name)
+	}
+}
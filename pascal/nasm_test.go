@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// assembleCode транслирует программу в исходный текст на ассемблере NASM
+func assembleCode(t *testing.T, code string) (string, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.pas")
+	if err := os.WriteFile(filename, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := NewInfo()
+	program, err := load(filename, "", info)
+	if err != nil {
+		t.Fatalf("%q: неожиданная ошибка: %v", code, err)
+	}
+	source, err := buildNASM(program, info, "test.pas")
+	return string(source), err
+}
+
+// nasmPrograms дополняют программы тестов интерпретатора для сравнения программ на
+// ассемблере с интерпретатором: крайние значения, переходы в циклах и ошибки выполнения.
+// Как и все программы тестов, они входят в goCorpus.
+var nasmPrograms = []string{
+	"VAR a, b: INTEGER; ok: BOOLEAN;\nBEGIN\n  a := 7; b := -3;\n  WriteLn('a=', a, ' b=', b, ' ', a DIV b, ' ', a MOD b, ' ', a * b);\n" +
+		"  ok := (a > b) AND NOT (b = 0);\n  IF ok THEN c := a + b ELSE c := 0;\n" +
+		"  WHILE a > 0 DO BEGIN a := a - 2; IF a = 3 THEN Continue END;\n  REPEAT b := b + 1 UNTIL b >= 2;\n" +
+		"  FOR i := 10 DOWNTO 1 DO BEGIN IF i = 4 THEN Break; s := s + i END;\n" +
+		"  CASE c OF 1..3: d := 1; 4: d := 2 ELSE d := 3 END\nEND.",
+	"VAR m: INTEGER; BEGIN m := -9223372036854775807 - 1; WriteLn(m, ' ', m MOD -1, ' ', Odd(m), ' ', Ord(TRUE)); n := m DIV -1 END.",
+	"VAR x: INTEGER; BEGIN x := 9223372036854775807; WriteLn(Pred(x)); y := Succ(x) END.",
+	"VAR x: INTEGER; BEGIN x := -9223372036854775807 - 1; y := Abs(x) END.",
+	"VAR x: INTEGER; BEGIN x := 3000000000; y := Sqr(x) END.",
+	"VAR x, y: INTEGER; BEGIN x := 5; y := 0; Write('до'); WriteLn(x DIV y) END.",
+	"VAR x: INTEGER; BEGIN x := 12; CASE x OF 1: x := 2; 5..10: x := 3 END END.",
+	"VAR i, k: INTEGER; BEGIN FOR i := 5 TO 1 DO k := k + 1; FOR i := 1 TO 1 DO k := k + 10 END.",
+	"VAR i, n: INTEGER; BEGIN n := 0; FOR i := 1 TO 3 DO BEGIN i := i + 10; n := n + 1 END END.",
+	"VAR b: BOOLEAN; BEGIN b := (1 < 2) OR (1 DIV 0 = 1); c := (1 > 2) AND (1 DIV 0 = 1); WriteLn('" + `"` + "', b, c, '" + `"` + "') END.",
+}
+
+func TestBuildNASM(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"VAR a: INTEGER; BEGIN a := 2; b := a * 3 END.",
+			[]string{"; Сгенерировано pascal -S из test.pas. Не редактировать.", "default rel", "global _start", "_start:",
+				"v_a             resq 1              ; a", "f_b             resb 1              ; b получила значение",
+				"    imul rax, rcx\n    jo .error1", "    mov [v_b], rax\n    mov byte [f_b], 1", "    cmp byte [f_b], 0",
+				`db "ошибка выполнения: строка 1, столбец 38: целочисленное переполнение (EIntOverflow)", 10`,
+				"rt_print_int:", "jmp rt_exit"}},
+		{"BEGIN x := -9223372036854775807 - 1; y := 3000000000 END.",
+			[]string{"mov rax, 0x8000000000000000", "mov rax, 3000000000"}},
+		{"VAR n: INTEGER; BEGIN CASE n OF 1, 3: n := 2; 4000000000: n := 5 END END.",
+			[]string{"    cmp rax, 1\n", "    cmp rax, 3\n", "    mov rcx, 4000000000\n    cmp rax, rcx", "jmp rt_fail_value",
+				`db "ошибка выполнения: строка 1, столбец 23: значение "`}},
+		{`BEGIN WriteLn('"Привет"', 1 < 2) END.`,
+			[]string{`db 34, "Привет", 34`, "call rt_print_bool", "call rt_print_newline", "add rsp, 8"}},
+		{"BEGIN FOR i := 1 TO 3 DO IF i = 2 THEN Break ELSE Continue END.",
+			[]string{"t1              resq 1", "t2              resq 1", "    je .endfor", "    jmp .endfor", "    jmp .next"}},
+		{"VAR MyVar, ДлинноеИмя: INTEGER; BEGIN MyVar := 1; ДлинноеИмя := 2 END.",
+			[]string{"v_myvar", "v_2 ", `db "MyVar: "`, `db "ДлинноеИмя: "`}},
+	}
+	for _, tt := range tests {
+		source, err := assembleCode(t, tt.code)
+		if err != nil {
+			t.Errorf("%q: неожиданная ошибка: %v", tt.code, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(source, want) {
+				t.Errorf("%q: в коде нет %q:\n%s", tt.code, want, source)
+			}
+		}
+	}
+}
+
+// TestBuildNASMErrors тестирует конструкции, которые транслятор в ассемблер не поддерживает
+func TestBuildNASMErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"PROCEDURE P; BEGIN END; BEGIN P END.", "строка 1, столбец 11: процедура P не поддерживается в ассемблере"},
+		{"VAR r: REAL; BEGIN r := 1.5 END.", "строка 1, столбец 5: переменная r типа REAL не поддерживается в ассемблере"},
+		{"BEGIN s := 'abc' END.", "строка 1, столбец 7: переменная s типа STRING не поддерживается в ассемблере"},
+		{"VAR d: 1..5; BEGIN d := 2 END.", "строка 1, столбец 5: переменная d типа 1..5 не поддерживается в ассемблере"},
+		{"VAR n: INTEGER; BEGIN WriteLn(n:4) END.", "строка 1, столбец 32: формат вывода не поддерживается в ассемблере"},
+		{"VAR n: INTEGER; BEGIN ReadLn(n) END.", "строка 1, столбец 23: процедура ReadLn не поддерживается в ассемблере"},
+		{"VAR n: INTEGER; BEGIN n := Ord(Chr(n)) END.", "строка 1, столбец 28: значение типа CHAR не поддерживается в ассемблере"},
+		{"VAR b: BOOLEAN; BEGIN CASE b OF TRUE: b := FALSE END END.", "строка 1, столбец 23: CASE по значению типа BOOLEAN не поддерживается в ассемблере"},
+		{"BEGIN IF a THEN b := 1; a := TRUE END.", "строка 1, столбец 10: чтение неописанной переменной a до присваивания не поддерживается в ассемблере"},
+	}
+	for _, tt := range tests {
+		_, err := assembleCode(t, tt.code)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestMainAssemble тестирует флаг -S
+func TestMainAssemble(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "prog.pas")
+	if err := os.WriteFile(program, []byte("BEGIN x := 1 END."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.pas")
+	if err := os.WriteFile(bad, []byte("BEGIN x := 1.5 END."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "prog.asm")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"-S", output, bad}, 1, "", "ошибка трансляции: строка 1, столбец 7: переменная x типа REAL не поддерживается в ассемблере"},
+		{[]string{"-S", output, filepath.Join(dir, "missing.pas")}, 1, "", "ошибка чтения файла:"},
+		{[]string{"-S", filepath.Join(dir, "missing", "prog.asm"), program}, 1, "", "ошибка записи файла:"},
+		{[]string{"-S", output, program}, 0, "", ""},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.HasPrefix(stderr.String(), tt.stderr) || tt.stderr == "" && stderr.Len() > 0 {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+	source, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("файл не записан: %v", err)
+	}
+	if !strings.HasPrefix(string(source), "; Сгенерировано pascal -S из prog.pas. Не редактировать.\n") {
+		t.Errorf("неожиданный заголовок:\n%s", source)
+	}
+}
+
+// nasmAssemble собирает программу на ассемблере утилитами nasm и ld и возвращает путь
+// к исполняемому файлу
+func nasmAssemble(t *testing.T, source string) string {
+	t.Helper()
+	object := strings.TrimSuffix(source, ".asm") + ".o"
+	binary := strings.TrimSuffix(source, ".asm")
+	for _, command := range []*exec.Cmd{exec.Command("nasm", "-f", "elf64", "-o", object, source), exec.Command("ld", "-o", binary, object)} {
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %v\n%s", command.Args, err, output)
+		}
+	}
+	return binary
+}
+
+// TestNASMMatchesInterpreter собирает примеры и программы тестов интерпретатора, которые
+// транслятор поддерживает, и сравнивает их вывод, ошибку и код выхода с интерпретатором
+func TestNASMMatchesInterpreter(t *testing.T) {
+	for _, tool := range []string{"nasm", "ld"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s не найден", tool)
+		}
+	}
+	dir := t.TempDir()
+	files, _ := filepath.Glob(filepath.Join("examples", "*.pas"))
+	for n, code := range goCorpus(t) {
+		file := filepath.Join(dir, "p"+strconv.Itoa(n)+".pas")
+		if err := os.WriteFile(file, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	assembled := 0
+	for _, file := range files {
+		info := NewInfo()
+		program, err := load(file, filepath.Join("examples", "units"), info)
+		if err != nil {
+			// Программы тестов ошибок анализа
+			continue
+		}
+		source, err := buildNASM(program, info, filepath.Base(file))
+		if err != nil {
+			if !strings.Contains(err.Error(), "не поддерживается") {
+				t.Errorf("%s: ошибка трансляции: %v", file, err)
+			}
+			continue
+		}
+		name := filepath.Join(dir, "asm"+strconv.Itoa(assembled)+".asm")
+		if err := os.WriteFile(name, source, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		assembled++
+
+		var want bytes.Buffer
+		wantStderr, wantCode := "", 0
+		interpreter := NewInterpreterWithOptions(Options{Stdin: strings.NewReader(""), Stdout: &want})
+		if err := interpreter.Interpret(program); err != nil {
+			wantStderr, _, _ = strings.Cut("ошибка выполнения: "+describeError(err), "\n")
+			wantCode = 1
+		} else {
+			want.WriteString(formatVariables(interpreter.Values()) + "\n")
+		}
+
+		command := exec.Command(nasmAssemble(t, name))
+		var stdout, stderr bytes.Buffer
+		command.Stdout, command.Stderr = &stdout, &stderr
+		code := 0
+		if err := command.Run(); err != nil {
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok {
+				t.Fatalf("%s: %v", name, err)
+			}
+			code = exitErr.ExitCode()
+		}
+		gotStderr, _, _ := strings.Cut(stderr.String(), "\n")
+		if stdout.String() != want.String() || gotStderr != wantStderr || code != wantCode {
+			text, _ := os.ReadFile(file)
+			t.Errorf("%q:\nинтерпретатор: %q, %q, код %d\nпрограмма на ассемблере: %q, %q, код %d",
+				text, want.String(), wantStderr, wantCode, stdout.String(), gotStderr, code)
+		}
+	}
+	if assembled < len(nasmPrograms) {
+		t.Errorf("собрано программ: %d", assembled)
+	}
+	t.Logf("собрано программ: %d", assembled)
+}
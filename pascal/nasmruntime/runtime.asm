@@ -0,0 +1,134 @@
+; runtime.asm - среда выполнения программ, транслированных pascal -S в ассемблер NASM
+; (x86-64, Linux). Транслятор дописывает ее в конец каждой программы: вывод строк,
+; целых и логических значений, словарь переменных и ошибки выполнения.
+;
+; Подпрограммы не сохраняют rax, rcx, rdx, rsi, rdi, r8-r11.
+
+section .data
+    rt_open         db "{"
+    rt_close        db "}", 10
+    rt_separator    db ", "
+    rt_newline      db 10
+    rt_true         db "TRUE"
+    rt_false        db "FALSE"
+
+section .bss
+    rt_buffer       resb 24             ; цифры целого числа со знаком
+    rt_listed       resb 1              ; в словарь уже выведена переменная
+
+section .text
+
+; rt_write выводит rdx байт по адресу rsi в stdout
+rt_write:
+    mov rax, 1                          ; syscall write
+    mov rdi, 1                          ; stdout
+    syscall
+    ret
+
+; rt_write_error выводит rdx байт по адресу rsi в stderr
+rt_write_error:
+    mov rax, 1                          ; syscall write
+    mov rdi, 2                          ; stderr
+    syscall
+    ret
+
+; rt_format_int записывает целое rax десятичными цифрами в rt_buffer:
+; rsi - адрес первого символа, rdx - длина
+rt_format_int:
+    lea rsi, [rt_buffer + 24]
+    mov r8, rax                         ; r8 - исходное значение для знака
+    test rax, rax
+    jns .digits
+    neg rax                             ; -MAXINT-1 остается собой и верно делится без знака
+.digits:
+    mov rcx, 10
+.next:
+    xor rdx, rdx
+    div rcx
+    add dl, '0'
+    dec rsi
+    mov [rsi], dl
+    test rax, rax
+    jnz .next
+    test r8, r8
+    jns .done
+    dec rsi
+    mov byte [rsi], '-'
+.done:
+    lea rdx, [rt_buffer + 24]
+    sub rdx, rsi
+    ret
+
+; rt_print_int выводит целое rax
+rt_print_int:
+    call rt_format_int
+    jmp rt_write
+
+; rt_print_bool выводит логическое значение rax (0 или 1) как FALSE или TRUE
+rt_print_bool:
+    test rax, rax
+    jz .false
+    lea rsi, [rt_true]
+    mov rdx, 4
+    jmp rt_write
+.false:
+    lea rsi, [rt_false]
+    mov rdx, 5
+    jmp rt_write
+
+; rt_print_newline завершает строку вывода
+rt_print_newline:
+    lea rsi, [rt_newline]
+    mov rdx, 1
+    jmp rt_write
+
+; rt_list_open и rt_list_close выводят скобки словаря переменных, а rt_list_next -
+; разделитель перед каждой переменной, кроме первой
+rt_list_open:
+    mov byte [rt_listed], 0
+    lea rsi, [rt_open]
+    mov rdx, 1
+    jmp rt_write
+
+rt_list_next:
+    cmp byte [rt_listed], 0
+    mov byte [rt_listed], 1
+    je .first
+    lea rsi, [rt_separator]
+    mov rdx, 2
+    jmp rt_write
+.first:
+    ret
+
+rt_list_close:
+    lea rsi, [rt_close]
+    mov rdx, 2
+    jmp rt_write
+
+; rt_exit завершает программу с кодом 0
+rt_exit:
+    mov rax, 60                         ; syscall exit
+    xor rdi, rdi
+    syscall
+
+; rt_fail выводит в stderr сообщение об ошибке выполнения (rsi - адрес, rdx - длина,
+; вместе с переводом строки) и завершает программу с кодом 1
+rt_fail:
+    call rt_write_error
+    mov rax, 60                         ; syscall exit
+    mov rdi, 1
+    syscall
+
+; rt_fail_value выводит сообщение об ошибке, в которое входит значение rax:
+; rsi, rdx - начало сообщения, r9, r10 - его окончание
+rt_fail_value:
+    push r10
+    push r9
+    push rax
+    call rt_write_error
+    pop rax
+    call rt_format_int
+    call rt_write_error
+    pop rsi
+    pop rdx
+    jmp rt_fail
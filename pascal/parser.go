@@ -2,6 +2,11 @@ package main
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Node представляет узел AST
@@ -11,9 +16,255 @@ type Node interface {
 
 // Program представляет программу
 type Program struct {
+	Name   string     // имя из заголовка PROGRAM, пустое если заголовка нет
+	Params []string   // параметры программы, например (input, output)
+	Uses   []*UnitRef // модули из предложения USES
+	Declarations
 	Statements []Statement
 }
 
+// Unit представляет модуль: UNIT Name; INTERFACE ... IMPLEMENTATION ... END.
+// Раздел INTERFACE описывает имена, доступные программам и модулям, подключившим модуль,
+// IMPLEMENTATION - тела подпрограмм и скрытые имена, необязательный раздел
+// инициализации выполняется один раз до тела программы.
+type Unit struct {
+	Name           string
+	Uses           []*UnitRef
+	Interface      Declarations // подпрограммы раздела представлены только заголовками
+	ImplUses       []*UnitRef   // предложение USES раздела IMPLEMENTATION: модули видны только реализации
+	Implementation Declarations
+	Statements     []Statement // раздел инициализации
+	Pos            Position
+
+	exports *Scope // имена раздела INTERFACE после семантического анализа
+}
+
+func (u *Unit) String() string {
+	return fmt.Sprintf("Unit(%s)", u.Name)
+}
+
+// allUses возвращает модули из обоих предложений USES: раздела INTERFACE и раздела IMPLEMENTATION
+func (u *Unit) allUses() []*UnitRef {
+	return append(slices.Clip(u.Uses), u.ImplUses...)
+}
+
+// UnitRef представляет имя модуля в предложении USES
+type UnitRef struct {
+	Name string
+	Unit *Unit // загруженный модуль; nil, если парсеру не задан загрузчик модулей
+	Pos  Position
+}
+
+func (r *UnitRef) String() string {
+	return fmt.Sprintf("Uses(%s)", r.Name)
+}
+
+// UnitResolver загружает модуль, указанный в предложении USES
+type UnitResolver interface {
+	LoadUnit(name string, pos Position) (*Unit, error)
+}
+
+// Declarations представляет раздел описаний блока
+type Declarations struct {
+	Consts []*ConstDecl
+	Types  []*TypeDecl
+	Vars   []*VarDecl
+
+	Routines []*RoutineDecl // процедуры и функции в порядке описания
+}
+
+// ConstDecl представляет описание константы Name = Value
+type ConstDecl struct {
+	Name  string
+	Value Expression
+	Pos   Position
+}
+
+func (c *ConstDecl) String() string {
+	return fmt.Sprintf("Const(%s = %s)", c.Name, c.Value)
+}
+
+// TypeDecl представляет описание типа Name = Type
+type TypeDecl struct {
+	Name string
+	Type TypeSpec
+	Pos  Position
+}
+
+func (t *TypeDecl) String() string {
+	return fmt.Sprintf("Type(%s = %s)", t.Name, t.Type)
+}
+
+// VarDecl представляет описание переменной Name: Type
+type VarDecl struct {
+	Name string
+	Type TypeSpec
+	Pos  Position
+}
+
+func (v *VarDecl) String() string {
+	return fmt.Sprintf("Var(%s: %s)", v.Name, v.Type)
+}
+
+// RoutineDecl представляет описание процедуры или функции с ее разделом описаний и телом
+type RoutineDecl struct {
+	Name   string
+	Params []*ParamDecl
+	Result TypeSpec // тип результата функции; nil для процедуры
+	Declarations
+	Body *Block
+	Pos  Position
+	End  Position // позиция END тела; нулевая для заголовка из раздела INTERFACE
+}
+
+// IsFunction сообщает, является ли подпрограмма функцией
+func (r *RoutineDecl) IsFunction() bool {
+	return r.Result != nil
+}
+
+func (r *RoutineDecl) String() string {
+	params := make([]string, len(r.Params))
+	for i, param := range r.Params {
+		params[i] = param.String()
+	}
+	if r.IsFunction() {
+		return fmt.Sprintf("Function(%s(%s): %s)", r.Name, strings.Join(params, "; "), r.Result)
+	}
+	return fmt.Sprintf("Procedure(%s(%s))", r.Name, strings.Join(params, "; "))
+}
+
+// ParamDecl представляет формальный параметр подпрограммы: значение или VAR-параметр
+type ParamDecl struct {
+	Name  string
+	Type  TypeSpec
+	ByRef bool // VAR-параметр: подпрограмма получает ссылку на переменную
+	Pos   Position
+}
+
+func (p *ParamDecl) String() string {
+	if p.ByRef {
+		return fmt.Sprintf("VAR %s: %s", p.Name, p.Type)
+	}
+	return fmt.Sprintf("%s: %s", p.Name, p.Type)
+}
+
+// TypeSpec представляет запись типа в описаниях
+type TypeSpec interface {
+	Node
+	typeNode()
+}
+
+// NamedType представляет ссылку на тип по имени
+type NamedType struct {
+	Name string
+	Pos  Position
+}
+
+func (n *NamedType) typeNode() {
+	_ = n // маркерный метод
+}
+func (n *NamedType) String() string {
+	return n.Name
+}
+
+// EnumType представляет перечислимый тип (Red, Green, Blue).
+// Каждое описание перечисления задает отдельный тип, поэтому тип хранится в узле
+// и общий для семантического анализа и выполнения.
+type EnumType struct {
+	Names []string
+	Pos   Position
+
+	resolved *Type
+}
+
+func (e *EnumType) typeNode() {
+	_ = e // маркерный метод
+}
+func (e *EnumType) String() string {
+	return "(" + strings.Join(e.Names, ", ") + ")"
+}
+
+// SubrangeType представляет диапазонный тип Low..High с константными границами
+type SubrangeType struct {
+	Low  Expression
+	High Expression
+	Pos  Position
+}
+
+func (s *SubrangeType) typeNode() {
+	_ = s // маркерный метод
+}
+func (s *SubrangeType) String() string {
+	return fmt.Sprintf("%s..%s", s.Low, s.High)
+}
+
+// SetType представляет множественный тип SET OF базовый_тип
+type SetType struct {
+	Elem TypeSpec
+	Pos  Position
+}
+
+func (s *SetType) typeNode() {
+	_ = s // маркерный метод
+}
+func (s *SetType) String() string {
+	return fmt.Sprintf("SET OF %s", s.Elem)
+}
+
+// PointerType представляет ссылочный тип ^Name; Name может быть описан ниже в том же разделе TYPE
+type PointerType struct {
+	Name string
+	Pos  Position
+}
+
+func (p *PointerType) typeNode() {
+	_ = p // маркерный метод
+}
+func (p *PointerType) String() string {
+	return "^" + p.Name
+}
+
+// RecordType представляет комбинированный тип RECORD поле: тип; ... END
+type RecordType struct {
+	Fields []*FieldDecl
+	Pos    Position
+}
+
+func (r *RecordType) typeNode() {
+	_ = r // маркерный метод
+}
+func (r *RecordType) String() string {
+	fields := make([]string, len(r.Fields))
+	for i, field := range r.Fields {
+		fields[i] = field.String()
+	}
+	return fmt.Sprintf("RECORD %s END", strings.Join(fields, "; "))
+}
+
+// ClassType представляет класс исключения CLASS(Parent) END
+type ClassType struct {
+	Parent string
+	Pos    Position
+}
+
+func (c *ClassType) typeNode() {
+	_ = c // маркерный метод
+}
+func (c *ClassType) String() string {
+	return fmt.Sprintf("CLASS(%s) END", c.Parent)
+}
+
+// FieldDecl представляет описание поля записи Name: Type
+type FieldDecl struct {
+	Name string
+	Type TypeSpec
+	Pos  Position
+}
+
+func (f *FieldDecl) String() string {
+	return fmt.Sprintf("%s: %s", f.Name, f.Type)
+}
+
 func (p *Program) String() string {
 	return fmt.Sprintf("Program(%d statements)", len(p.Statements))
 }
@@ -26,14 +277,24 @@ type Statement interface {
 
 // Assignment представляет присваивание
 type Assignment struct {
-	Variable string
-	Value    Expression
+	Variable  string
+	Value     Expression
+	Pos       Position
+	Unchecked bool // присваивание в области действия {$R-}: диапазон значения не проверяется
+
+	// Target - составное место присваивания, например p^.next или r.x; nil, если
+	// значение присваивается переменной Variable. Variable в этом случае - имя,
+	// с которого начинается запись Target.
+	Target Expression
 }
 
 func (a *Assignment) statementNode() {
 	_ = a // маркерный метод
 }
 func (a *Assignment) String() string {
+	if a.Target != nil {
+		return fmt.Sprintf("Assignment(%s := %s)", designatorName(a.Target), a.Value)
+	}
 	return fmt.Sprintf("Assignment(%s := %s)", a.Variable, a.Value)
 }
 
@@ -49,200 +310,1715 @@ func (b *Block) String() string {
 	return fmt.Sprintf("Block(%d statements)", len(b.Statements))
 }
 
+// IfStatement представляет условный оператор IF условие THEN оператор ELSE оператор
+type IfStatement struct {
+	Cond Expression
+	Then Statement
+	Else Statement // nil, если ветви ELSE нет
+	Pos  Position
+}
+
+func (s *IfStatement) statementNode() {
+	_ = s // маркерный метод
+}
+func (s *IfStatement) String() string {
+	return fmt.Sprintf("If(%s, else: %t)", s.Cond, s.Else != nil)
+}
+
+// WhileStatement представляет цикл WHILE условие DO оператор
+type WhileStatement struct {
+	Cond Expression
+	Body Statement
+	Pos  Position
+}
+
+func (s *WhileStatement) statementNode() {
+	_ = s // маркерный метод
+}
+func (s *WhileStatement) String() string {
+	return fmt.Sprintf("While(%s)", s.Cond)
+}
+
+// RepeatStatement представляет цикл REPEAT операторы UNTIL условие
+type RepeatStatement struct {
+	Body *Block
+	Cond Expression
+	Pos  Position
+}
+
+func (s *RepeatStatement) statementNode() {
+	_ = s // маркерный метод
+}
+func (s *RepeatStatement) String() string {
+	return fmt.Sprintf("Repeat(%d statements, until %s)", len(s.Body.Statements), s.Cond)
+}
+
+// ForStatement представляет цикл FOR переменная := начало TO (DOWNTO) конец DO оператор
+type ForStatement struct {
+	Variable string
+	Start    Expression
+	End      Expression
+	Down     bool // DOWNTO
+	Body     Statement
+	Pos      Position
+}
+
+func (s *ForStatement) statementNode() {
+	_ = s // маркерный метод
+}
+func (s *ForStatement) String() string {
+	direction := "to"
+	if s.Down {
+		direction = "downto"
+	}
+	return fmt.Sprintf("For(%s := %s %s %s)", s.Variable, s.Start, direction, s.End)
+}
+
+// CallStatement представляет вызов процедуры, например New(p)
+type CallStatement struct {
+	Name string
+	Args []Expression
+	Pos  Position
+}
+
+func (c *CallStatement) statementNode() {
+	_ = c // маркерный метод
+}
+func (c *CallStatement) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("CallStatement(%s(%s))", c.Name, strings.Join(args, ", "))
+}
+
+// CaseStatement представляет оператор CASE выражение OF метки: оператор; ... ELSE ... END
+type CaseStatement struct {
+	Expr     Expression
+	Branches []*CaseBranch
+	Else     *Block // nil, если ветви ELSE (OTHERWISE) нет
+	Pos      Position
+}
+
+func (c *CaseStatement) statementNode() {
+	_ = c // маркерный метод
+}
+func (c *CaseStatement) String() string {
+	return fmt.Sprintf("Case(%s, %d branches, else: %t)", c.Expr, len(c.Branches), c.Else != nil)
+}
+
+// CaseBranch представляет ветвь оператора CASE с ее метками
+type CaseBranch struct {
+	Labels []*CaseLabel
+	Body   Statement
+}
+
+func (b *CaseBranch) String() string {
+	labels := make([]string, len(b.Labels))
+	for i, label := range b.Labels {
+		labels[i] = label.String()
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(labels, ", "), b.Body)
+}
+
+// CaseLabel представляет метку варианта: одно значение или диапазон Low..High
+type CaseLabel struct {
+	Low  Expression
+	High Expression // nil для метки из одного значения
+	Pos  Position
+}
+
+func (l *CaseLabel) String() string {
+	if l.High == nil {
+		return l.Low.String()
+	}
+	return fmt.Sprintf("%s..%s", l.Low, l.High)
+}
+
+// TryStatement представляет оператор TRY ... EXCEPT ... END или TRY ... FINALLY ... END
+type TryStatement struct {
+	Body     *Block
+	Handlers []*ExceptHandler // обработчики ON E: Класс DO оператор
+	Default  *Block           // операторы EXCEPT без ON или ветвь ELSE; nil, если их нет
+	Finally  *Block           // nil для оператора TRY ... EXCEPT
+	Pos      Position
+}
+
+func (t *TryStatement) statementNode() {
+	_ = t // маркерный метод
+}
+func (t *TryStatement) String() string {
+	if t.Finally != nil {
+		return fmt.Sprintf("Try(%d statements, finally: %d statements)", len(t.Body.Statements), len(t.Finally.Statements))
+	}
+	return fmt.Sprintf("Try(%d statements, except: %d handlers, else: %t)", len(t.Body.Statements), len(t.Handlers), t.Default != nil)
+}
+
+// ExceptHandler представляет обработчик ON E: Класс DO оператор; имя переменной E необязательно
+type ExceptHandler struct {
+	Variable string
+	Class    string
+	Body     Statement
+	Pos      Position
+}
+
+func (h *ExceptHandler) String() string {
+	if h.Variable == "" {
+		return fmt.Sprintf("On(%s)", h.Class)
+	}
+	return fmt.Sprintf("On(%s: %s)", h.Variable, h.Class)
+}
+
+// RaiseStatement представляет оператор RAISE исключение или RAISE без выражения,
+// который в обработчике повторно возбуждает обрабатываемое исключение
+type RaiseStatement struct {
+	Exception Expression // nil для повторного возбуждения
+	Pos       Position
+}
+
+func (r *RaiseStatement) statementNode() {
+	_ = r // маркерный метод
+}
+func (r *RaiseStatement) String() string {
+	if r.Exception == nil {
+		return "Raise()"
+	}
+	return fmt.Sprintf("Raise(%s)", r.Exception)
+}
+
+// BreakStatement представляет оператор Break, завершающий ближайший цикл
+type BreakStatement struct {
+	Pos Position
+}
+
+func (b *BreakStatement) statementNode() {
+	_ = b // маркерный метод
+}
+func (b *BreakStatement) String() string {
+	return "Break"
+}
+
+// ContinueStatement представляет оператор Continue, переходящий к следующей итерации ближайшего цикла
+type ContinueStatement struct {
+	Pos Position
+}
+
+func (c *ContinueStatement) statementNode() {
+	_ = c // маркерный метод
+}
+func (c *ContinueStatement) String() string {
+	return "Continue"
+}
+
+// ExitStatement представляет оператор Exit, завершающий подпрограмму или программу;
+// Exit(значение) в функции задает ее результат
+type ExitStatement struct {
+	Value Expression // nil, если значение не указано
+	Pos   Position
+}
+
+func (e *ExitStatement) statementNode() {
+	_ = e // маркерный метод
+}
+func (e *ExitStatement) String() string {
+	if e.Value == nil {
+		return "Exit()"
+	}
+	return fmt.Sprintf("Exit(%s)", e.Value)
+}
+
 // Expression представляет выражение
 type Expression interface {
 	Node
 	expressionNode()
 }
 
-// Number представляет число
+// Number представляет число; IsReal отличает вещественный литерал от целого.
+// Для целых литералов Int хранит точное значение, недоступное через float64.
 type Number struct {
-	Value float64
+	Value  float64
+	IsReal bool
+	Int    int64
+	Pos    Position
+}
+
+// IntValue возвращает значение целого литерала
+func (n *Number) IntValue() int64 {
+	if float64(n.Int) == n.Value {
+		return n.Int
+	}
+	return int64(n.Value)
+}
+
+func (n *Number) expressionNode() {
+	_ = n // маркерный метод
+}
+func (n *Number) String() string {
+	return fmt.Sprintf("Number(%g)", n.Value)
+}
+
+// Identifier представляет переменную
+type Identifier struct {
+	Name string
+	Pos  Position
+}
+
+func (i *Identifier) expressionNode() {
+	_ = i // маркерный метод
+}
+func (i *Identifier) String() string {
+	return fmt.Sprintf("Identifier(%s)", i.Name)
+}
+
+// Literal представляет готовое значение, например результат свертки констант
+type Literal struct {
+	Value Value
+	Pos   Position
+}
+
+func (l *Literal) expressionNode() {
+	_ = l // маркерный метод
+}
+func (l *Literal) String() string {
+	return fmt.Sprintf("Literal(%s)", l.Value)
+}
+
+// BinaryOp представляет бинарную операцию
+type BinaryOp struct {
+	Left     Expression
+	Operator TokenType
+	Right    Expression
+	Pos      Position
+}
+
+func (b *BinaryOp) expressionNode() {
+	_ = b // маркерный метод
+}
+func (b *BinaryOp) String() string {
+	return fmt.Sprintf("BinaryOp(%s %s %s)", b.Left, operatorSymbol(b.Operator), b.Right)
+}
+
+// Dereference представляет обращение к динамической переменной p^
+type Dereference struct {
+	Pointer Expression
+	Pos     Position
+}
+
+func (d *Dereference) expressionNode() {
+	_ = d // маркерный метод
+}
+func (d *Dereference) String() string {
+	return fmt.Sprintf("Deref(%s)", d.Pointer)
+}
+
+// FieldAccess представляет обращение к полю записи r.Field
+type FieldAccess struct {
+	Record Expression
+	Field  string
+	Pos    Position
+}
+
+func (f *FieldAccess) expressionNode() {
+	_ = f // маркерный метод
+}
+func (f *FieldAccess) String() string {
+	return fmt.Sprintf("Field(%s.%s)", f.Record, f.Field)
+}
+
+// designatorName восстанавливает запись переменной в исходном тексте, например p^.next
+func designatorName(expr Expression) string {
+	switch e := expr.(type) {
+	case *Identifier:
+		return e.Name
+	case *Dereference:
+		return designatorName(e.Pointer) + "^"
+	case *FieldAccess:
+		return designatorName(e.Record) + "." + e.Field
+	default:
+		return expr.String()
+	}
+}
+
+// UnaryOp представляет унарную операцию NOT
+type UnaryOp struct {
+	Operator TokenType
+	Operand  Expression
+	Pos      Position
+}
+
+func (u *UnaryOp) expressionNode() {
+	_ = u // маркерный метод
+}
+func (u *UnaryOp) String() string {
+	return fmt.Sprintf("UnaryOp(%s %s)", operatorSymbol(u.Operator), u.Operand)
+}
+
+// operatorSymbols содержит запись операций в исходном тексте для сообщений и String()
+var operatorSymbols = map[TokenType]string{
+	TokenPLUS:         "+",
+	TokenMINUS:        "-",
+	TokenMULTIPLY:     "*",
+	TokenDIVIDE:       "/",
+	TokenDIV:          "DIV",
+	TokenMOD:          "MOD",
+	TokenAND:          "AND",
+	TokenOR:           "OR",
+	TokenNOT:          "NOT",
+	TokenEQUAL:        "=",
+	TokenNOTEQUAL:     "<>",
+	TokenLESS:         "<",
+	TokenLESSEQUAL:    "<=",
+	TokenGREATER:      ">",
+	TokenGREATEREQUAL: ">=",
+	TokenIN:           "IN",
+}
+
+// operatorSymbol возвращает запись операции; для неизвестной операции - пустую строку
+func operatorSymbol(op TokenType) string {
+	return operatorSymbols[op]
+}
+
+// SetConstructor представляет конструктор множества [1, 3..5]
+type SetConstructor struct {
+	Elements []*SetElement
+	Pos      Position
+}
+
+func (s *SetConstructor) expressionNode() {
+	_ = s // маркерный метод
+}
+func (s *SetConstructor) String() string {
+	elements := make([]string, len(s.Elements))
+	for i, element := range s.Elements {
+		elements[i] = element.String()
+	}
+	return fmt.Sprintf("Set[%s]", strings.Join(elements, ", "))
+}
+
+// SetElement представляет элемент конструктора множества: значение или диапазон Low..High
+type SetElement struct {
+	Low  Expression
+	High Expression // nil для одного значения
+}
+
+func (e *SetElement) String() string {
+	if e.High == nil {
+		return e.Low.String()
+	}
+	return fmt.Sprintf("%s..%s", e.Low, e.High)
+}
+
+// CallExpr представляет вызов функции в выражении
+type CallExpr struct {
+	Name string
+	Args []Expression
+	Pos  Position
+}
+
+func (c *CallExpr) expressionNode() {
+	_ = c // маркерный метод
+}
+func (c *CallExpr) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("Call(%s(%s))", c.Name, strings.Join(args, ", "))
+}
+
+// CreateExpr представляет создание исключения Класс.Create(сообщение)
+type CreateExpr struct {
+	Class string
+	Args  []Expression
+	Pos   Position
+}
+
+func (c *CreateExpr) expressionNode() {
+	_ = c // маркерный метод
+}
+func (c *CreateExpr) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("Create(%s(%s))", c.Class, strings.Join(args, ", "))
+}
+
+// FormatExpr представляет параметр Write с шириной поля и точностью: x:8 или x:8:2
+type FormatExpr struct {
+	Value     Expression
+	Width     Expression
+	Precision Expression // nil, если точность не задана
+	Pos       Position
+}
+
+func (f *FormatExpr) expressionNode() {
+	_ = f // маркерный метод
+}
+func (f *FormatExpr) String() string {
+	if f.Precision != nil {
+		return fmt.Sprintf("Format(%s:%s:%s)", f.Value, f.Width, f.Precision)
+	}
+	return fmt.Sprintf("Format(%s:%s)", f.Value, f.Width)
+}
+
+// Parser представляет парсер
+type Parser struct {
+	tokens []Token
+	pos    int
+
+	// rangeChecksOff - действует директива {$R-}; по умолчанию проверка диапазонов включена
+	rangeChecksOff bool
+
+	procedures map[string]bool // процедуры, видимые в разбираемом блоке, которые можно вызвать без скобок
+
+	units    UnitResolver // загрузчик модулей из USES; nil - модули не загружаются
+	headings bool         // разбирается раздел INTERFACE: подпрограммы описываются только заголовками
+
+	// Dialect - вариант языка; в строгих диалектах отклонения от стандарта являются ошибками
+	Dialect Dialect
+	// Deviations - отклонения от стандарта: сначала найденные лексером (Lexer.Deviations),
+	// затем парсером. Разбор на них не прекращается, а Parse и ParseUnit сообщают обо всех
+	// отклонениях сразу, если в тексте нет других ошибок.
+	Deviations []*DialectError
+	// BigInt - режим длинных целых: целый литерал вне диапазона int64 не является ошибкой
+	BigInt bool
+}
+
+// NewParser создает новый парсер
+func NewParser(tokens []Token) *Parser {
+	return NewParserWithUnits(tokens, nil)
+}
+
+// ParseExpression разбирает отдельное выражение, например значение флага -D или -e;
+// bigint - режим длинных целых
+func ParseExpression(code string, bigint bool) (Expression, error) {
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	p := NewParser(tokens)
+	p.BigInt = bigint
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(TokenEOF) {
+		return nil, p.errorf(p.current().Pos, "лишний текст после выражения")
+	}
+	return expr, nil
+}
+
+// NewParserWithUnits создает парсер, который загружает модули из USES через units
+func NewParserWithUnits(tokens []Token, units UnitResolver) *Parser {
+	return &Parser{
+		tokens:     tokens,
+		pos:        0,
+		procedures: make(map[string]bool),
+		units:      units,
+	}
+}
+
+// Parse разбирает токены в AST
+func (p *Parser) Parse() (*Program, error) {
+	program := &Program{}
+	p.skipDirectives()
+	if p.check(TokenUNIT) {
+		return nil, p.errorf(p.current().Pos, "ожидалась программа, а не модуль")
+	}
+	
+	// Необязательный заголовок PROGRAM Name(params);
+	if p.match(TokenPROGRAM) {
+		if err := p.parseProgramHeading(program); err != nil {
+			return nil, err
+		}
+	} else if p.Dialect == DialectISO {
+		p.deviate(ruleProgram, p.current().Position(), "программа должна начинаться с заголовка PROGRAM")
+	}
+	if p.Dialect == DialectISO && p.check(TokenUSES) {
+		p.deviate(ruleProgram, p.current().Position(), "предложение USES не предусмотрено стандартом")
+	}
+	if p.match(TokenUSES) {
+		uses, err := p.parseUses(nil)
+		if err != nil {
+			return nil, err
+		}
+		program.Uses = uses
+	}
+	
+	// Раздел описаний
+	declarations, err := p.parseDeclarations()
+	if err != nil {
+		return nil, err
+	}
+	program.Declarations = *declarations
+	
+	// Ожидаем BEGIN
+	if !p.match(TokenBEGIN) {
+		return nil, p.errorf(p.current().Pos, "ожидался BEGIN")
+	}
+	
+	// Парсим блок
+	block, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	
+	program.Statements = block.Statements
+	
+	// Ожидаем END
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END")
+	}
+	
+	// Ожидаем точку
+	if !p.match(TokenDOT) {
+		return nil, p.errorf(p.current().Pos, "ожидалась точка")
+	}
+	
+	// Проверяем EOF
+	if !p.match(TokenEOF) {
+		return nil, p.errorf(p.current().Pos, "неожиданные токены после точки")
+	}
+	if err := dialectErrors(p.Deviations); err != nil {
+		return nil, err
+	}
+	
+	return program, nil
+}
+
+// parseProgramHeading парсит заголовок программы после слова PROGRAM
+func (p *Parser) parseProgramHeading(program *Program) error {
+	if !p.check(TokenIDENTIFIER) {
+		return p.errorf(p.current().Pos, "ожидалось имя программы")
+	}
+	program.Name = p.current().Value
+	p.advance()
+	
+	if p.match(TokenLPAREN) {
+		names, err := p.parseIdentifierList()
+		if err != nil {
+			return err
+		}
+		program.Params = names
+		if !p.match(TokenRPAREN) {
+			return p.errorf(p.current().Pos, "ожидалась закрывающая скобка")
+		}
+	}
+	
+	if !p.match(TokenSEMICOLON) {
+		return p.errorf(p.current().Pos, "ожидалась ';' после заголовка программы")
+	}
+	return nil
+}
+
+// ParseUnit разбирает модуль: UNIT Name; INTERFACE [USES ...;] описания
+// IMPLEMENTATION [USES ...;] описания [INITIALIZATION операторы | BEGIN операторы] END.
+func (p *Parser) ParseUnit() (*Unit, error) {
+	p.skipDirectives()
+	if !p.match(TokenUNIT) {
+		return nil, p.errorf(p.current().Pos, "ожидалось UNIT")
+	}
+	if !p.check(TokenIDENTIFIER) {
+		return nil, p.errorf(p.current().Pos, "ожидалось имя модуля")
+	}
+	unit := &Unit{Name: p.current().Value, Pos: p.current().Position()}
+	p.advance()
+	if !p.match(TokenSEMICOLON) {
+		return nil, p.errorf(p.current().Pos, "ожидалась ';' после заголовка модуля")
+	}
+	
+	if !p.match(TokenINTERFACE) {
+		return nil, p.errorf(p.current().Pos, "ожидался раздел INTERFACE")
+	}
+	if p.match(TokenUSES) {
+		uses, err := p.parseUses(nil)
+		if err != nil {
+			return nil, err
+		}
+		unit.Uses = uses
+	}
+	p.headings = true
+	declarations, err := p.parseDeclarations()
+	p.headings = false
+	if err != nil {
+		return nil, err
+	}
+	unit.Interface = *declarations
+	
+	if !p.match(TokenIMPLEMENTATION) {
+		return nil, p.errorf(p.current().Pos, "ожидался раздел IMPLEMENTATION")
+	}
+	if p.match(TokenUSES) {
+		uses, err := p.parseUses(unit.Uses)
+		if err != nil {
+			return nil, err
+		}
+		unit.ImplUses = uses
+	}
+	declarations, err = p.parseDeclarations()
+	if err != nil {
+		return nil, err
+	}
+	unit.Implementation = *declarations
+	
+	if p.match(TokenINITIALIZATION) || p.match(TokenBEGIN) {
+		block, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		unit.Statements = block.Statements
+	}
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END модуля")
+	}
+	if !p.match(TokenDOT) {
+		return nil, p.errorf(p.current().Pos, "ожидалась точка")
+	}
+	if !p.match(TokenEOF) {
+		return nil, p.errorf(p.current().Pos, "неожиданные токены после точки")
+	}
+	if err := dialectErrors(p.Deviations); err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// parseUses парсит список модулей после слова USES и загружает их. Процедуры
+// из раздела INTERFACE загруженного модуля можно вызывать без скобок. outer - модули,
+// уже подключенные предложением USES раздела INTERFACE: повторять их нельзя.
+func (p *Parser) parseUses(outer []*UnitRef) ([]*UnitRef, error) {
+	var uses []*UnitRef
+	for {
+		if !p.check(TokenIDENTIFIER) {
+			return nil, p.errorf(p.current().Pos, "ожидалось имя модуля")
+		}
+		ref := &UnitRef{Name: p.current().Value, Pos: p.current().Position()}
+		for _, previous := range append(slices.Clip(outer), uses...) {
+			if strings.EqualFold(previous.Name, ref.Name) {
+				return nil, p.errorf(p.current().Pos, "модуль %s повторно указан в USES", ref.Name)
+			}
+		}
+		p.advance()
+		if p.units != nil {
+			unit, err := p.units.LoadUnit(ref.Name, ref.Pos)
+			if err != nil {
+				return nil, err
+			}
+			ref.Unit = unit
+			for _, routine := range unit.Interface.Routines {
+				if !routine.IsFunction() {
+					p.procedures[strings.ToLower(routine.Name)] = true
+				}
+			}
+		}
+		uses = append(uses, ref)
+		if !p.match(TokenCOMMA) {
+			break
+		}
+	}
+	if !p.match(TokenSEMICOLON) {
+		return nil, p.errorf(p.current().Pos, "ожидалась ';' после списка модулей")
+	}
+	return uses, nil
+}
+
+// parseIdentifierList парсит список имен через запятую
+func (p *Parser) parseIdentifierList() ([]string, error) {
+	var names []string
+	for {
+		if !p.check(TokenIDENTIFIER) {
+			return nil, p.errorf(p.current().Pos, "ожидался идентификатор")
+		}
+		names = append(names, p.current().Value)
+		p.advance()
+		if !p.match(TokenCOMMA) {
+			return names, nil
+		}
+	}
+}
+
+// declarationSections задает порядок разделов описаний по стандарту ISO 7185
+var declarationSections = []TokenType{TokenCONST, TokenTYPE, TokenVAR}
+
+// parseDeclarations парсит разделы CONST, TYPE и VAR в порядке, требуемом стандартом,
+// и следующие за ними описания процедур и функций
+func (p *Parser) parseDeclarations() (*Declarations, error) {
+	declarations := &Declarations{}
+	next := 0 // индекс первого раздела, который еще может встретиться
+	routines := false
+	
+	for {
+		if p.check(TokenPROCEDURE) || p.check(TokenFUNCTION) {
+			routine, err := p.parseRoutine()
+			if err != nil {
+				return nil, err
+			}
+			declarations.Routines = append(declarations.Routines, routine)
+			// После подпрограмм разделы CONST, TYPE и VAR не допускаются
+			routines = true
+			continue
+		}
+		section := -1
+		for n, t := range declarationSections {
+			if p.check(t) {
+				section = n
+			}
+		}
+		if section < 0 {
+			return declarations, nil
+		}
+		if routines {
+			return nil, p.errorf(p.current().Pos, "раздел %s должен предшествовать описаниям процедур и функций",
+				strings.ToUpper(p.current().Value))
+		}
+		if section < next {
+			return nil, p.errorf(p.current().Pos, "раздел %s нарушает порядок описаний CONST, TYPE, VAR",
+				strings.ToUpper(p.current().Value))
+		}
+		next = section + 1
+		p.advance()
+		
+		var err error
+		switch declarationSections[section] {
+		case TokenCONST:
+			err = p.parseConstSection(declarations)
+		case TokenTYPE:
+			err = p.parseTypeSection(declarations)
+		case TokenVAR:
+			err = p.parseVarSection(declarations)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseConstSection парсит описания вида Name = выражение;
+func (p *Parser) parseConstSection(declarations *Declarations) error {
+	for {
+		if !p.check(TokenIDENTIFIER) {
+			return p.errorf(p.current().Pos, "ожидалось имя константы")
+		}
+		decl := &ConstDecl{Name: p.current().Value, Pos: p.current().Position()}
+		p.advance()
+		if !p.match(TokenEQUAL) {
+			return p.errorf(p.current().Pos, "ожидался '=' в описании константы")
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return err
+		}
+		decl.Value = value
+		if !p.match(TokenSEMICOLON) {
+			return p.errorf(p.current().Pos, "ожидалась ';' после описания константы")
+		}
+		declarations.Consts = append(declarations.Consts, decl)
+		if !p.check(TokenIDENTIFIER) {
+			return nil
+		}
+	}
+}
+
+// parseTypeSection парсит описания вида Name = тип;
+func (p *Parser) parseTypeSection(declarations *Declarations) error {
+	for {
+		if !p.check(TokenIDENTIFIER) {
+			return p.errorf(p.current().Pos, "ожидалось имя типа")
+		}
+		decl := &TypeDecl{Name: p.current().Value, Pos: p.current().Position()}
+		p.advance()
+		if !p.match(TokenEQUAL) {
+			return p.errorf(p.current().Pos, "ожидался '=' в описании типа")
+		}
+		spec, err := p.parseTypeSpec()
+		if err != nil {
+			return err
+		}
+		decl.Type = spec
+		if !p.match(TokenSEMICOLON) {
+			return p.errorf(p.current().Pos, "ожидалась ';' после описания типа")
+		}
+		declarations.Types = append(declarations.Types, decl)
+		if !p.check(TokenIDENTIFIER) {
+			return nil
+		}
+	}
+}
+
+// parseVarSection парсит описания вида a, b: тип;
+func (p *Parser) parseVarSection(declarations *Declarations) error {
+	for {
+		var decls []*VarDecl
+		for {
+			if !p.check(TokenIDENTIFIER) {
+				return p.errorf(p.current().Pos, "ожидалось имя переменной")
+			}
+			decls = append(decls, &VarDecl{Name: p.current().Value, Pos: p.current().Position()})
+			p.advance()
+			if !p.match(TokenCOMMA) {
+				break
+			}
+		}
+		if !p.match(TokenCOLON) {
+			return p.errorf(p.current().Pos, "ожидалось ':' в описании переменных")
+		}
+		spec, err := p.parseTypeSpec()
+		if err != nil {
+			return err
+		}
+		for _, decl := range decls {
+			decl.Type = spec
+		}
+		if !p.match(TokenSEMICOLON) {
+			return p.errorf(p.current().Pos, "ожидалась ';' после описания переменных")
+		}
+		declarations.Vars = append(declarations.Vars, decls...)
+		if !p.check(TokenIDENTIFIER) {
+			return nil
+		}
+	}
+}
+
+// parseRoutine парсит описание процедуры или функции: заголовок, раздел описаний и тело
+func (p *Parser) parseRoutine() (*RoutineDecl, error) {
+	function := p.check(TokenFUNCTION)
+	kind := "процедуры"
+	if function {
+		kind = "функции"
+	}
+	p.advance()
+	if !p.check(TokenIDENTIFIER) {
+		return nil, p.errorf(p.current().Pos, "ожидалось имя %s", kind)
+	}
+	routine := &RoutineDecl{Name: p.current().Value, Pos: p.current().Position()}
+	p.advance()
+	if !function {
+		// Имя известно уже в теле процедуры, что позволяет рекурсивный вызов без скобок
+		p.procedures[strings.ToLower(routine.Name)] = true
+	}
+	
+	if p.match(TokenLPAREN) {
+		params, err := p.parseParams()
+		if err != nil {
+			return nil, err
+		}
+		routine.Params = params
+	}
+	if function {
+		if !p.match(TokenCOLON) || !p.check(TokenIDENTIFIER) {
+			return nil, p.errorf(p.current().Pos, "ожидался тип результата функции %s", routine.Name)
+		}
+		routine.Result = &NamedType{Name: p.current().Value, Pos: p.current().Position()}
+		p.advance()
+	}
+	if !p.match(TokenSEMICOLON) {
+		return nil, p.errorf(p.current().Pos, "ожидалась ';' после заголовка %s", kind)
+	}
+	if p.headings {
+		// Тело подпрограммы из раздела INTERFACE описывается в разделе IMPLEMENTATION
+		return routine, nil
+	}
+	
+	// Вложенные процедуры видны только в блоке подпрограммы, в которой описаны
+	outer := p.procedures
+	p.procedures = make(map[string]bool, len(outer))
+	for name := range outer {
+		p.procedures[name] = true
+	}
+	defer func() { p.procedures = outer }()
+
+	declarations, err := p.parseDeclarations()
+	if err != nil {
+		return nil, err
+	}
+	routine.Declarations = *declarations
+	
+	if !p.match(TokenBEGIN) {
+		return nil, p.errorf(p.current().Pos, "ожидался BEGIN тела %s %s", kind, routine.Name)
+	}
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	routine.Body = body
+	routine.End = p.current().Position()
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END")
+	}
+	if !p.match(TokenSEMICOLON) {
+		return nil, p.errorf(p.current().Pos, "ожидалась ';' после тела %s %s", kind, routine.Name)
+	}
+	return routine, nil
+}
+
+// parseParams парсит список формальных параметров после '(': VAR a, b: T; c: T)
+func (p *Parser) parseParams() ([]*ParamDecl, error) {
+	var params []*ParamDecl
+	if p.match(TokenRPAREN) {
+		return params, nil
+	}
+	for {
+		byRef := p.match(TokenVAR)
+		var group []*ParamDecl
+		for {
+			if !p.check(TokenIDENTIFIER) {
+				return nil, p.errorf(p.current().Pos, "ожидалось имя параметра")
+			}
+			group = append(group, &ParamDecl{Name: p.current().Value, ByRef: byRef, Pos: p.current().Position()})
+			p.advance()
+			if !p.match(TokenCOMMA) {
+				break
+			}
+		}
+		// Тип параметра задается только именем типа
+		if !p.match(TokenCOLON) {
+			return nil, p.errorf(p.current().Pos, "ожидалось ':' после имени параметра")
+		}
+		if !p.check(TokenIDENTIFIER) {
+			return nil, p.errorf(p.current().Pos, "ожидалось имя типа параметра")
+		}
+		spec := &NamedType{Name: p.current().Value, Pos: p.current().Position()}
+		p.advance()
+		for _, param := range group {
+			param.Type = spec
+		}
+		params = append(params, group...)
+		if p.match(TokenRPAREN) {
+			return params, nil
+		}
+		if !p.match(TokenSEMICOLON) {
+			return nil, p.errorf(p.current().Pos, "ожидалась ';' или ')' в списке параметров")
+		}
+	}
+}
+
+// parseTypeSpec парсит запись типа: имя типа, перечисление (A, B, C), диапазон Low..High
+// или множество SET OF тип
+func (p *Parser) parseTypeSpec() (TypeSpec, error) {
+	pos := p.current().Position()
+	if p.match(TokenSET) {
+		if !p.match(TokenOF) {
+			return nil, p.errorf(p.current().Pos, "ожидалось OF после SET")
+		}
+		elem, err := p.parseTypeSpec()
+		if err != nil {
+			return nil, err
+		}
+		return &SetType{Elem: elem, Pos: pos}, nil
+	}
+	
+	if p.match(TokenCARET) {
+		if !p.check(TokenIDENTIFIER) {
+			return nil, p.errorf(p.current().Pos, "ожидалось имя типа после '^'")
+		}
+		spec := &PointerType{Name: p.current().Value, Pos: pos}
+		p.advance()
+		return spec, nil
+	}
+	
+	if p.match(TokenRECORD) {
+		return p.parseRecordType(pos)
+	}
+	if p.match(TokenLPAREN) {
+		names, err := p.parseIdentifierList()
+		if err != nil {
+			return nil, err
+		}
+		if !p.match(TokenRPAREN) {
+			return nil, p.errorf(p.current().Pos, "ожидалась закрывающая скобка перечисления")
+		}
+		return &EnumType{Names: names, Pos: pos}, nil
+	}
+	
+	if p.startsClass() {
+		if p.Dialect == DialectISO {
+			p.deviate(ruleTypeDenoter, pos, "классы не предусмотрены стандартом")
+		}
+		return p.parseClassType(pos)
+	}
+	if p.check(TokenIDENTIFIER) && !p.startsSubrange() {
+		spec := &NamedType{Name: p.current().Value, Pos: pos}
+		p.advance()
+		return spec, nil
+	}
+	if !p.check(TokenIDENTIFIER) && !p.check(TokenNUMBER) && !p.check(TokenMINUS) && !p.check(TokenSTRING) {
+		return nil, p.errorf(p.current().Pos, "ожидался тип")
+	}
+	
+	low, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.match(TokenDOTDOT) {
+		return nil, p.errorf(p.current().Pos, "ожидалось '..' в описании диапазона")
+	}
+	high, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &SubrangeType{Low: low, High: high, Pos: pos}, nil
+}
+
+// parseRecordType парсит список полей записи после RECORD до END
+func (p *Parser) parseRecordType(pos Position) (TypeSpec, error) {
+	record := &RecordType{Pos: pos}
+	for p.check(TokenIDENTIFIER) {
+		var fields []*FieldDecl
+		for {
+			if !p.check(TokenIDENTIFIER) {
+				return nil, p.errorf(p.current().Pos, "ожидалось имя поля")
+			}
+			fields = append(fields, &FieldDecl{Name: p.current().Value, Pos: p.current().Position()})
+			p.advance()
+			if !p.match(TokenCOMMA) {
+				break
+			}
+		}
+		if !p.match(TokenCOLON) {
+			return nil, p.errorf(p.current().Pos, "ожидалось ':' в описании полей")
+		}
+		spec, err := p.parseTypeSpec()
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			field.Type = spec
+		}
+		record.Fields = append(record.Fields, fields...)
+		if !p.match(TokenSEMICOLON) {
+			break
+		}
+	}
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END описания записи")
+	}
+	return record, nil
+}
+
+// startsClass сообщает, начинается ли с текущего токена описание CLASS(Parent);
+// слово CLASS не зарезервировано и распознается только перед скобкой
+func (p *Parser) startsClass() bool {
+	return p.check(TokenIDENTIFIER) && strings.EqualFold(p.current().Value, "class") &&
+		p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == TokenLPAREN
+}
+
+// parseClassType парсит класс исключения CLASS(Parent) END. Классы не имеют собственных
+// полей и методов и служат только для различения исключений в обработчиках ON.
+func (p *Parser) parseClassType(pos Position) (TypeSpec, error) {
+	p.advance() // пропускаем CLASS
+	p.advance() // пропускаем '('
+	if !p.check(TokenIDENTIFIER) {
+		return nil, p.errorf(p.current().Pos, "ожидалось имя базового класса")
+	}
+	spec := &ClassType{Parent: p.current().Value, Pos: pos}
+	p.advance()
+	if !p.match(TokenRPAREN) {
+		return nil, p.errorf(p.current().Pos, "ожидалась закрывающая скобка")
+	}
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END описания класса")
+	}
+	return spec, nil
+}
+
+// startsSubrange сообщает, начинает ли текущий идентификатор выражение границы диапазона
+// (Red..Blue, N - 1..N), а не имя типа
+func (p *Parser) startsSubrange() bool {
+	if p.pos+1 >= len(p.tokens) {
+		return false
+	}
+	switch p.tokens[p.pos+1].Type {
+	case TokenDOTDOT, TokenPLUS, TokenMINUS, TokenMULTIPLY, TokenDIVIDE, TokenDIV, TokenMOD, TokenLPAREN:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseBlock парсит блок BEGIN ... END
+func (p *Parser) parseBlock() (*Block, error) {
+	return p.parseStatementsUntil(TokenEND)
+}
+
+// parseStatementsUntil парсит последовательность операторов до одного из токенов end
+// (END, UNTIL, EXCEPT или FINALLY)
+func (p *Parser) parseStatementsUntil(end ...TokenType) (*Block, error) {
+	block := &Block{Statements: []Statement{}}
+	
+	for {
+		// Проверяем, не конец ли блока
+		if p.checkAny(end) {
+			break
+		}
+		
+		// Парсим оператор
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		
+		block.Statements = append(block.Statements, stmt)
+		
+		// Проверяем, не конец ли блока после точки с запятой
+		if p.checkAny(end) {
+			break
+		}
+		if err := p.checkSeparator(); err != nil {
+			return nil, err
+		}
+	}
+	
+	return block, nil
+}
+
+// checkSeparator проверяет в строгих диалектах, что оператор последовательности отделен
+// от следующего точкой с запятой и что за ';' не следует ELSE. Ветвь ELSE после ';' не
+// относится ни к одному оператору: она разбирается только для поиска следующих отклонений.
+func (p *Parser) checkSeparator() error {
+	if !p.Dialect.strictSyntax() {
+		return nil
+	}
+	separated := p.previous().Type == TokenSEMICOLON
+	switch {
+	case separated && p.check(TokenELSE):
+		p.deviate(ruleIfStatement, p.previous().Position(), "точка с запятой перед ELSE завершает оператор IF")
+		p.advance() // пропускаем ELSE
+		_, err := p.parseBody()
+		return err
+	case !separated && p.startsStatement():
+		p.deviate(ruleStatementSequence, p.current().Position(), "пропущена ';' между операторами")
+	}
+	return nil
+}
+
+// errorf создает ошибку синтаксического анализа по смещению offset. Позицию в строках и
+// столбцах дает токен с этим смещением или, если такого нет, ближайший предшествующий.
+func (p *Parser) errorf(offset int, format string, args ...interface{}) error {
+	err := &SyntaxError{Message: fmt.Sprintf(format, args...), Offset: offset}
+	n := sort.Search(len(p.tokens), func(n int) bool { return p.tokens[n].Pos > offset })
+	if n > 0 {
+		err.Pos = p.tokens[n-1].Position()
+	}
+	return err
+}
+
+// deviate запоминает отклонение от правила rule в позиции pos
+func (p *Parser) deviate(rule isoRule, pos Position, format string, args ...interface{}) {
+	p.Deviations = append(p.Deviations, deviation(rule, pos, format, args...))
+}
+
+// startsStatement сообщает, может ли с текущего токена начинаться оператор
+func (p *Parser) startsStatement() bool {
+	switch p.current().Type {
+	case TokenIDENTIFIER, TokenBEGIN, TokenIF, TokenCASE, TokenWHILE, TokenREPEAT, TokenFOR, TokenTRY, TokenRAISE:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseStatement парсит оператор
+func (p *Parser) parseStatement() (Statement, error) {
+	// Проверяем, не вложенный ли блок
+	if p.match(TokenBEGIN) {
+		block, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		
+		if !p.match(TokenEND) {
+			return nil, p.errorf(p.current().Pos, "ожидался END")
+		}
+		
+		// Точка с запятой после END (если не последний оператор)
+		if p.check(TokenSEMICOLON) {
+			p.advance()
+		}
+		
+		return block, nil
+	}
+	
+	if p.Dialect == DialectISO && (p.check(TokenTRY) || p.check(TokenRAISE)) {
+		p.deviate(ruleStatement, p.current().Position(), "оператор %s не предусмотрен стандартом",
+			strings.ToUpper(p.current().Value))
+	}
+	switch p.current().Type {
+	case TokenCASE:
+		return p.parseCase()
+	case TokenIF:
+		return p.parseIf()
+	case TokenWHILE:
+		return p.parseWhile()
+	case TokenREPEAT:
+		return p.parseRepeat()
+	case TokenFOR:
+		return p.parseFor()
+	case TokenTRY:
+		return p.parseTry()
+	case TokenRAISE:
+		return p.parseRaise()
+	}
+	
+	// Парсим присваивание или вызов процедуры
+	if p.check(TokenIDENTIFIER) {
+		varName := p.current().Value
+		pos := p.current().Position()
+		unchecked := p.rangeChecksOff
+		p.advance()
+		
+		key := strings.ToLower(varName)
+		if jump, ok, err := p.parseJump(key, pos); ok || err != nil {
+			return jump, err
+		}
+		if p.check(TokenLPAREN) {
+			return p.parseCallStatement(varName, pos)
+		}
+		if (parameterlessProcedures[key] || p.procedures[key]) && p.atStatementEnd() {
+			// Вызов процедуры без параметров
+			if p.check(TokenSEMICOLON) {
+				p.advance()
+			}
+			return &CallStatement{Name: varName, Pos: pos}, nil
+		}
+		
+		target, err := p.parseSelectors(&Identifier{Name: varName, Pos: pos})
+		if err != nil {
+			return nil, err
+		}
+		
+		if !p.match(TokenASSIGN) {
+			return nil, p.errorf(p.current().Pos, "ожидался :=")
+		}
+		
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		
+		// Точка с запятой после присваивания
+		if p.check(TokenSEMICOLON) {
+			p.advance()
+		}
+		
+		assignment := &Assignment{
+			Variable:  varName,
+			Value:     expr,
+			Pos:       pos,
+			Unchecked: unchecked,
+		}
+		if _, simple := target.(*Identifier); !simple {
+			assignment.Target = target
+		}
+		return assignment, nil
+	}
+	
+	return nil, p.errorf(p.current().Pos, "неожиданный токен %v", p.current())
+}
+
+// parseJump парсит операторы Break, Continue, Exit и Exit(значение). Как и в Turbo Pascal, эти
+// слова не зарезервированы: одноименная процедура программы или присваивание переменной
+// с таким именем остаются обычными операторами.
+func (p *Parser) parseJump(key string, pos Position) (Statement, bool, error) {
+	if p.procedures[key] {
+		return nil, false, nil
+	}
+	var stmt Statement
+	switch {
+	case key == "break" && p.atStatementEnd():
+		stmt = &BreakStatement{Pos: pos}
+	case key == "continue" && p.atStatementEnd():
+		stmt = &ContinueStatement{Pos: pos}
+	case key == "exit" && p.atStatementEnd():
+		stmt = &ExitStatement{Pos: pos}
+	case key == "exit" && p.match(TokenLPAREN):
+		exit := &ExitStatement{Pos: pos}
+		if !p.check(TokenRPAREN) {
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, false, err
+			}
+			exit.Value = value
+		}
+		if !p.match(TokenRPAREN) {
+			return nil, false, p.errorf(p.current().Pos, "ожидалась ')' после значения Exit")
+		}
+		stmt = exit
+	default:
+		return nil, false, nil
+	}
+	if p.Dialect == DialectISO {
+		p.deviate(ruleStatement, pos, "оператор %s не предусмотрен стандартом", strings.ToUpper(key[:1])+key[1:])
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
+	}
+	return stmt, true, nil
+}
+
+// parameterlessProcedures содержит стандартные процедуры, которые можно вызвать без скобок.
+// Кроме них без скобок вызываются описанные в программе процедуры; для остальных
+// имен одиночный идентификатор - незаконченное присваивание.
+var parameterlessProcedures = map[string]bool{"writeln": true, "readln": true, "halt": true}
+
+// atStatementEnd сообщает, завершается ли оператор на текущем токене
+func (p *Parser) atStatementEnd() bool {
+	switch p.current().Type {
+	case TokenSEMICOLON, TokenEND, TokenELSE, TokenUNTIL, TokenEXCEPT, TokenFINALLY, TokenEOF:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseCallStatement парсит вызов процедуры name(аргументы)
+func (p *Parser) parseCallStatement(name string, pos Position) (Statement, error) {
+	call, err := p.parseCall(name, pos)
+	if err != nil {
+		return nil, err
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
+	}
+	return &CallStatement{Name: name, Args: call.(*CallExpr).Args, Pos: pos}, nil
+}
+
+// parseTry парсит оператор TRY операторы EXCEPT обработчики END или TRY операторы FINALLY операторы END.
+// Раздел EXCEPT содержит либо операторы, обрабатывающие любое исключение, либо обработчики
+// ON E: Класс DO оператор с необязательной ветвью ELSE.
+func (p *Parser) parseTry() (Statement, error) {
+	stmt := &TryStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем TRY
+	
+	// END и конец файла завершают разбор, чтобы сообщить о пропущенном EXCEPT, FINALLY или END
+	body, err := p.parseStatementsUntil(TokenEXCEPT, TokenFINALLY, TokenEND, TokenEOF)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body = body
+	
+	switch {
+	case p.match(TokenFINALLY):
+		if stmt.Finally, err = p.parseStatementsUntil(TokenEND, TokenEOF); err != nil {
+			return nil, err
+		}
+	case p.match(TokenEXCEPT):
+		for p.startsHandler() {
+			handler, err := p.parseHandler()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Handlers = append(stmt.Handlers, handler)
+		}
+		if len(stmt.Handlers) == 0 || p.match(TokenELSE) {
+			if stmt.Default, err = p.parseStatementsUntil(TokenEND, TokenEOF); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, p.errorf(p.current().Pos, "ожидалось EXCEPT или FINALLY")
+	}
+	
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END оператора TRY")
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
+	}
+	return stmt, nil
 }
 
-func (n *Number) expressionNode() {
-	_ = n // маркерный метод
-}
-func (n *Number) String() string {
-	return fmt.Sprintf("Number(%g)", n.Value)
+// startsHandler сообщает, начинается ли с текущего токена обработчик ON;
+// слово ON не зарезервировано и распознается только перед именем
+func (p *Parser) startsHandler() bool {
+	return p.check(TokenIDENTIFIER) && strings.EqualFold(p.current().Value, "on") &&
+		p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == TokenIDENTIFIER
 }
 
-// Identifier представляет переменную
-type Identifier struct {
-	Name string
+// parseHandler парсит обработчик исключения ON [E:] Класс DO оператор
+func (p *Parser) parseHandler() (*ExceptHandler, error) {
+	handler := &ExceptHandler{Pos: p.current().Position()}
+	p.advance() // пропускаем ON
+	handler.Class = p.current().Value
+	p.advance()
+	if p.match(TokenCOLON) {
+		if !p.check(TokenIDENTIFIER) {
+			return nil, p.errorf(p.current().Pos, "ожидалось имя класса исключения")
+		}
+		handler.Variable = handler.Class
+		handler.Class = p.current().Value
+		p.advance()
+	}
+	if !p.match(TokenDO) {
+		return nil, p.errorf(p.current().Pos, "ожидалось DO в обработчике исключения")
+	}
+	body, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	handler.Body = body
+	return handler, nil
 }
 
-func (i *Identifier) expressionNode() {
-	_ = i // маркерный метод
-}
-func (i *Identifier) String() string {
-	return fmt.Sprintf("Identifier(%s)", i.Name)
+// parseRaise парсит оператор RAISE [исключение]
+func (p *Parser) parseRaise() (Statement, error) {
+	stmt := &RaiseStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем RAISE
+	if !p.atStatementEnd() {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Exception = expr
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
+	}
+	return stmt, nil
 }
 
-// BinaryOp представляет бинарную операцию
-type BinaryOp struct {
-	Left     Expression
-	Operator TokenType
-	Right    Expression
+// parseIf парсит оператор IF; ELSE относится к ближайшему IF
+func (p *Parser) parseIf() (Statement, error) {
+	stmt := &IfStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем IF
+	
+	cond, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Cond = cond
+	if !p.match(TokenTHEN) {
+		return nil, p.errorf(p.current().Pos, "ожидалось THEN после условия IF")
+	}
+	if stmt.Then, err = p.parseBody(); err != nil {
+		return nil, err
+	}
+	if p.Dialect.strictSyntax() && p.previous().Type == TokenSEMICOLON {
+		// Точка с запятой завершает оператор IF; ELSE за ней относится к объемлющему оператору
+		return stmt, nil
+	}
+	if p.match(TokenELSE) {
+		if stmt.Else, err = p.parseBody(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
 }
 
-func (b *BinaryOp) expressionNode() {
-	_ = b // маркерный метод
-}
-func (b *BinaryOp) String() string {
-	op := ""
-	switch b.Operator {
-	case TokenPLUS:
-		op = "+"
-	case TokenMINUS:
-		op = "-"
-	case TokenMULTIPLY:
-		op = "*"
-	case TokenDIVIDE:
-		op = "/"
+// parseBody парсит оператор ветви IF или тела цикла, который может быть пустым:
+// WHILE i > 5 DO; или IF a THEN ELSE b := 1. Пустой оператор представлен пустым блоком.
+func (p *Parser) parseBody() (Statement, error) {
+	if !p.atStatementEnd() || p.check(TokenEOF) {
+		return p.parseStatement()
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
 	}
-	return fmt.Sprintf("BinaryOp(%s %s %s)", b.Left, op, b.Right)
+	return &Block{Statements: []Statement{}}, nil
 }
 
-// Parser представляет парсер
-type Parser struct {
-	tokens []Token
-	pos    int
+// parseWhile парсит цикл WHILE
+func (p *Parser) parseWhile() (Statement, error) {
+	stmt := &WhileStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем WHILE
+	
+	cond, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Cond = cond
+	if !p.match(TokenDO) {
+		return nil, p.errorf(p.current().Pos, "ожидалось DO после условия WHILE")
+	}
+	if stmt.Body, err = p.parseBody(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
 }
 
-// NewParser создает новый парсер
-func NewParser(tokens []Token) *Parser {
-	return &Parser{
-		tokens: tokens,
-		pos:    0,
+// parseRepeat парсит цикл REPEAT ... UNTIL
+func (p *Parser) parseRepeat() (Statement, error) {
+	stmt := &RepeatStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем REPEAT
+	
+	body, err := p.parseStatementsUntil(TokenUNTIL)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body = body
+	if !p.match(TokenUNTIL) {
+		return nil, p.errorf(p.current().Pos, "ожидалось UNTIL")
+	}
+	if stmt.Cond, err = p.parseExpression(); err != nil {
+		return nil, err
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
 	}
+	return stmt, nil
 }
 
-// Parse разбирает токены в AST
-func (p *Parser) Parse() (*Program, error) {
-	program := &Program{}
+// parseFor парсит цикл FOR
+func (p *Parser) parseFor() (Statement, error) {
+	stmt := &ForStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем FOR
 	
-	// Ожидаем BEGIN
-	if !p.match(TokenBEGIN) {
-		return nil, fmt.Errorf("ожидался BEGIN на позиции %d", p.current().Pos)
+	if !p.check(TokenIDENTIFIER) {
+		return nil, p.errorf(p.current().Pos, "ожидалась переменная цикла FOR")
+	}
+	stmt.Variable = p.current().Value
+	p.advance()
+	if !p.match(TokenASSIGN) {
+		return nil, p.errorf(p.current().Pos, "ожидался :=")
 	}
 	
-	// Парсим блок
-	block, err := p.parseBlock()
-	if err != nil {
+	var err error
+	if stmt.Start, err = p.parseExpression(); err != nil {
 		return nil, err
 	}
-	
-	program.Statements = block.Statements
-	
-	// Ожидаем END
-	if !p.match(TokenEND) {
-		return nil, fmt.Errorf("ожидался END на позиции %d", p.current().Pos)
+	switch {
+	case p.match(TokenTO):
+	case p.match(TokenDOWNTO):
+		stmt.Down = true
+	default:
+		return nil, p.errorf(p.current().Pos, "ожидалось TO или DOWNTO")
 	}
-	
-	// Ожидаем точку
-	if !p.match(TokenDOT) {
-		return nil, fmt.Errorf("ожидалась точка на позиции %d", p.current().Pos)
+	if stmt.End, err = p.parseExpression(); err != nil {
+		return nil, err
 	}
-	
-	// Проверяем EOF
-	if !p.match(TokenEOF) {
-		return nil, fmt.Errorf("неожиданные токены после точки")
+	if !p.match(TokenDO) {
+		return nil, p.errorf(p.current().Pos, "ожидалось DO в цикле FOR")
 	}
-	
-	return program, nil
+	if stmt.Body, err = p.parseBody(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
 }
 
-// parseBlock парсит блок BEGIN ... END
-func (p *Parser) parseBlock() (*Block, error) {
-	block := &Block{Statements: []Statement{}}
+// parseCase парсит оператор CASE
+func (p *Parser) parseCase() (Statement, error) {
+	stmt := &CaseStatement{Pos: p.current().Position()}
+	p.advance() // пропускаем CASE
 	
-	for {
-		// Проверяем, не конец ли блока
-		if p.check(TokenEND) {
-			break
-		}
-		
-		// Парсим оператор
-		stmt, err := p.parseStatement()
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Expr = expr
+	if !p.match(TokenOF) {
+		return nil, p.errorf(p.current().Pos, "ожидалось OF после выражения CASE")
+	}
+	
+	for !p.check(TokenEND) && !p.check(TokenELSE) && !p.check(TokenOTHERWISE) {
+		branch, err := p.parseCaseBranch()
 		if err != nil {
 			return nil, err
 		}
-		
-		block.Statements = append(block.Statements, stmt)
-		
-		// Проверяем, не конец ли блока после точки с запятой
-		if p.check(TokenEND) {
-			break
+		stmt.Branches = append(stmt.Branches, branch)
+		if p.Dialect.strictSyntax() && p.previous().Type != TokenSEMICOLON &&
+			!p.check(TokenEND) && !p.check(TokenELSE) && !p.check(TokenOTHERWISE) {
+			p.deviate(ruleCaseStatement, p.current().Position(), "пропущена ';' между ветвями CASE")
 		}
 	}
+	if len(stmt.Branches) == 0 {
+		return nil, p.errorf(p.current().Pos, "оператор CASE должен содержать хотя бы одну ветвь")
+	}
 	
-	return block, nil
+	// Ветвь ELSE (или OTHERWISE) содержит последовательность операторов до END
+	if p.Dialect == DialectISO && (p.check(TokenELSE) || p.check(TokenOTHERWISE)) {
+		p.deviate(ruleCaseStatement, p.current().Position(), "ветвь %s оператора CASE не предусмотрена стандартом",
+			strings.ToUpper(p.current().Value))
+	}
+	if p.match(TokenELSE) || p.match(TokenOTHERWISE) {
+		block, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Else = block
+	}
+	
+	if !p.match(TokenEND) {
+		return nil, p.errorf(p.current().Pos, "ожидался END оператора CASE")
+	}
+	if p.check(TokenSEMICOLON) {
+		p.advance()
+	}
+	return stmt, nil
 }
 
-// parseStatement парсит оператор
-func (p *Parser) parseStatement() (Statement, error) {
-	// Проверяем, не вложенный ли блок
-	if p.match(TokenBEGIN) {
-		block, err := p.parseBlock()
+// parseCaseBranch парсит ветвь CASE: список меток, двоеточие и оператор
+func (p *Parser) parseCaseBranch() (*CaseBranch, error) {
+	branch := &CaseBranch{}
+	for {
+		label := &CaseLabel{Pos: p.current().Position()}
+		low, err := p.parseExpression()
 		if err != nil {
 			return nil, err
 		}
-		
-		if !p.match(TokenEND) {
-			return nil, fmt.Errorf("ожидался END на позиции %d", p.current().Pos)
+		label.Low = low
+		if p.match(TokenDOTDOT) {
+			high, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			label.High = high
 		}
-		
-		// Точка с запятой после END (если не последний оператор)
-		if p.check(TokenSEMICOLON) {
-			p.advance()
+		branch.Labels = append(branch.Labels, label)
+		if !p.match(TokenCOMMA) {
+			break
 		}
-		
-		return block, nil
+	}
+	if !p.match(TokenCOLON) {
+		return nil, p.errorf(p.current().Pos, "ожидалось ':' после меток CASE")
+	}
+	body, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	branch.Body = body
+	return branch, nil
+}
+
+// parseExpression парсит выражение (с учетом приоритета операций): простое выражение,
+// за которым может следовать операция отношения и второе простое выражение
+func (p *Parser) parseExpression() (Expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
 	}
 	
-	// Парсим присваивание
-	if p.check(TokenIDENTIFIER) {
-		varName := p.current().Value
+	switch p.current().Type {
+	case TokenEQUAL, TokenNOTEQUAL, TokenLESS, TokenLESSEQUAL, TokenGREATER, TokenGREATEREQUAL, TokenIN:
+		op := p.current().Type
+		pos := p.current().Position()
 		p.advance()
 		
-		if !p.match(TokenASSIGN) {
-			return nil, fmt.Errorf("ожидался := на позиции %d", p.current().Pos)
-		}
-		
-		expr, err := p.parseExpression()
+		right, err := p.parseAdditive()
 		if err != nil {
 			return nil, err
 		}
 		
-		// Точка с запятой после присваивания
-		if p.check(TokenSEMICOLON) {
-			p.advance()
-		}
-		
-		return &Assignment{
-			Variable: varName,
-			Value:    expr,
+		return &BinaryOp{
+			Left:     left,
+			Operator: op,
+			Right:    right,
+			Pos:      pos,
 		}, nil
 	}
 	
-	return nil, fmt.Errorf("неожиданный токен на позиции %d: %v", p.current().Pos, p.current())
-}
-
-// parseExpression парсит выражение (с учетом приоритета операций)
-func (p *Parser) parseExpression() (Expression, error) {
-	return p.parseAdditive()
+	return left, nil
 }
 
-// parseAdditive парсит аддитивные операции (+ и -)
+// parseAdditive парсит аддитивные операции (+, - и OR)
 func (p *Parser) parseAdditive() (Expression, error) {
 	left, err := p.parseMultiplicative()
 	if err != nil {
 		return nil, err
 	}
 	
-	for p.check(TokenPLUS) || p.check(TokenMINUS) {
+	for p.check(TokenPLUS) || p.check(TokenMINUS) || p.check(TokenOR) {
 		op := p.current().Type
+		pos := p.current().Position()
 		p.advance()
 		
 		right, err := p.parseMultiplicative()
@@ -254,21 +2030,23 @@ func (p *Parser) parseAdditive() (Expression, error) {
 			Left:     left,
 			Operator: op,
 			Right:    right,
+			Pos:      pos,
 		}
 	}
 	
 	return left, nil
 }
 
-// parseMultiplicative парсит мультипликативные операции (* и /)
+// parseMultiplicative парсит мультипликативные операции (*, /, DIV, MOD и AND)
 func (p *Parser) parseMultiplicative() (Expression, error) {
 	left, err := p.parseUnary()
 	if err != nil {
 		return nil, err
 	}
 	
-	for p.check(TokenMULTIPLY) || p.check(TokenDIVIDE) {
+	for p.check(TokenMULTIPLY) || p.check(TokenDIVIDE) || p.check(TokenDIV) || p.check(TokenMOD) || p.check(TokenAND) {
 		op := p.current().Type
+		pos := p.current().Position()
 		p.advance()
 		
 		right, err := p.parseUnary()
@@ -280,6 +2058,7 @@ func (p *Parser) parseMultiplicative() (Expression, error) {
 			Left:     left,
 			Operator: op,
 			Right:    right,
+			Pos:      pos,
 		}
 	}
 	
@@ -289,34 +2068,62 @@ func (p *Parser) parseMultiplicative() (Expression, error) {
 // parseUnary парсит унарные выражения и первичные выражения
 func (p *Parser) parseUnary() (Expression, error) {
 	if p.check(TokenMINUS) {
+		pos := p.current().Position()
 		p.advance()
 		expr, err := p.parseUnary()
 		if err != nil {
 			return nil, err
 		}
 		return &BinaryOp{
-			Left:     &Number{Value: 0},
+			Left:     &Number{Value: 0, Pos: pos},
 			Operator: TokenMINUS,
 			Right:    expr,
+			Pos:      pos,
 		}, nil
 	}
 	
+	if p.check(TokenNOT) {
+		pos := p.current().Position()
+		p.advance()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryOp{Operator: TokenNOT, Operand: expr, Pos: pos}, nil
+	}
+	
 	return p.parsePrimary()
 }
 
-// parsePrimary парсит первичные выражения (числа, переменные, скобки)
+// parsePrimary парсит первичные выражения (числа, переменные, вызовы функций, скобки,
+// конструкторы множеств)
 func (p *Parser) parsePrimary() (Expression, error) {
 	if p.check(TokenNUMBER) {
-		value := 0.0
-		fmt.Sscanf(p.current().Value, "%f", &value)
-		p.advance()
-		return &Number{Value: value}, nil
+		return p.parseNumber()
+	}
+	
+	if p.check(TokenLBRACKET) {
+		return p.parseSetConstructor()
 	}
 	
 	if p.check(TokenIDENTIFIER) {
 		name := p.current().Value
+		pos := p.current().Position()
+		p.advance()
+		if p.check(TokenLPAREN) {
+			return p.parseCall(name, pos)
+		}
+		return p.parseSelectors(&Identifier{Name: name, Pos: pos})
+	}
+	
+	if p.check(TokenNIL) {
+		pos := p.current().Position()
 		p.advance()
-		return &Identifier{Name: name}, nil
+		return &Literal{Value: PointerValue{}, Pos: pos}, nil
+	}
+	
+	if p.check(TokenSTRING) {
+		return p.parseString(), nil
 	}
 	
 	if p.match(TokenLPAREN) {
@@ -326,13 +2133,154 @@ func (p *Parser) parsePrimary() (Expression, error) {
 		}
 		
 		if !p.match(TokenRPAREN) {
-			return nil, fmt.Errorf("ожидалась закрывающая скобка на позиции %d", p.current().Pos)
+			return nil, p.errorf(p.current().Pos, "ожидалась закрывающая скобка")
 		}
 		
 		return expr, nil
 	}
 	
-	return nil, fmt.Errorf("неожиданный токен на позиции %d: %v", p.current().Pos, p.current())
+	return nil, p.errorf(p.current().Pos, "неожиданный токен %v", p.current())
+}
+
+// parseSelectors парсит обращения к динамической переменной '^', к полям записи '.поле'
+// и создание исключения Класс.Create(...)
+func (p *Parser) parseSelectors(expr Expression) (Expression, error) {
+	for {
+		pos := p.current().Position()
+		switch {
+		case p.match(TokenCARET):
+			expr = &Dereference{Pointer: expr, Pos: pos}
+		case p.check(TokenDOT) && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == TokenIDENTIFIER:
+			p.advance() // пропускаем '.'
+			field := p.current().Value
+			p.advance()
+			if class, ok := expr.(*Identifier); ok && strings.EqualFold(field, "create") && p.check(TokenLPAREN) {
+				// Создание исключения Класс.Create(сообщение)
+				call, err := p.parseCall(class.Name, class.Pos)
+				if err != nil {
+					return nil, err
+				}
+				expr = &CreateExpr{Class: class.Name, Args: call.(*CallExpr).Args, Pos: class.Pos}
+				continue
+			}
+			expr = &FieldAccess{Record: expr, Field: field, Pos: pos}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// parseNumber парсит числовой литерал: целый, если в нем нет дробной части и порядка.
+// В режиме длинных целых литерал вне диапазона int64 становится литералом BigIntValue.
+func (p *Parser) parseNumber() (Expression, error) {
+	token := p.current()
+	p.advance()
+	if !strings.ContainsAny(token.Value, ".eE") {
+		value, err := strconv.ParseInt(token.Value, 10, 64)
+		if err != nil && p.BigInt {
+			if big, ok := parseBigInteger(token.Value); ok {
+				return &Literal{Value: big, Pos: token.Position()}, nil
+			}
+		}
+		if err != nil {
+			return nil, p.errorf(token.Pos, "целое число %s вне допустимого диапазона", token.Value)
+		}
+		return &Number{Value: float64(value), Int: value, Pos: token.Position()}, nil
+	}
+	value, err := strconv.ParseFloat(token.Value, 64)
+	if err != nil {
+		return nil, p.errorf(token.Pos, "некорректное вещественное число %s", token.Value)
+	}
+	return &Number{Value: value, IsReal: true, Pos: token.Position()}, nil
+}
+
+// parseString парсит строковый литерал. Литерал из одного символа имеет тип CHAR,
+// остальные - тип STRING.
+func (p *Parser) parseString() Expression {
+	token := p.current()
+	p.advance()
+	text := strings.ReplaceAll(token.Value[1:len(token.Value)-1], "''", "'")
+	if utf8.RuneCountInString(text) == 1 {
+		r, _ := utf8.DecodeRuneInString(text)
+		return &Literal{Value: CharValue(r), Pos: token.Position()}
+	}
+	return &Literal{Value: StringValue(text), Pos: token.Position()}
+}
+
+// parseSetConstructor парсит конструктор множества [элемент, низ..верх, ...]
+func (p *Parser) parseSetConstructor() (Expression, error) {
+	set := &SetConstructor{Pos: p.current().Position()}
+	p.advance() // пропускаем '['
+	if p.match(TokenRBRACKET) {
+		return set, nil
+	}
+	for {
+		low, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		element := &SetElement{Low: low}
+		if p.match(TokenDOTDOT) {
+			high, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			element.High = high
+		}
+		set.Elements = append(set.Elements, element)
+		if p.match(TokenRBRACKET) {
+			return set, nil
+		}
+		if !p.match(TokenCOMMA) {
+			return nil, p.errorf(p.current().Pos, "ожидалась ',' или ']' в конструкторе множества")
+		}
+	}
+}
+
+// parseCall парсит список аргументов вызова name(арг1, арг2, ...).
+// Аргументы Write и WriteLn могут задавать ширину поля и точность: x:8:2.
+func (p *Parser) parseCall(name string, pos Position) (Expression, error) {
+	p.advance() // пропускаем '('
+	call := &CallExpr{Name: name, Pos: pos}
+	if p.match(TokenRPAREN) {
+		return call, nil
+	}
+	write := strings.EqualFold(name, "write") || strings.EqualFold(name, "writeln")
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if write && p.check(TokenCOLON) {
+			if arg, err = p.parseFormat(arg); err != nil {
+				return nil, err
+			}
+		}
+		call.Args = append(call.Args, arg)
+		if p.match(TokenRPAREN) {
+			return call, nil
+		}
+		if !p.match(TokenCOMMA) {
+			return nil, p.errorf(p.current().Pos, "ожидалась ',' или ')' в вызове %s", name)
+		}
+	}
+}
+
+// parseFormat парсит ширину поля и точность параметра Write после значения value
+func (p *Parser) parseFormat(value Expression) (Expression, error) {
+	format := &FormatExpr{Value: value, Pos: p.current().Position()}
+	p.advance() // пропускаем ':'
+	width, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	format.Width = width
+	if p.match(TokenCOLON) {
+		if format.Precision, err = p.parseExpression(); err != nil {
+			return nil, err
+		}
+	}
+	return format, nil
 }
 
 func (p *Parser) current() Token {
@@ -342,16 +2290,59 @@ func (p *Parser) current() Token {
 	return p.tokens[p.pos]
 }
 
+// previous возвращает последний разобранный токен, не считая директив
+func (p *Parser) previous() Token {
+	for n := p.pos - 1; n >= 0; n-- {
+		if p.tokens[n].Type != TokenDIRECTIVE {
+			return p.tokens[n]
+		}
+	}
+	return Token{}
+}
+
 func (p *Parser) advance() {
 	if p.pos < len(p.tokens) {
 		p.pos++
 	}
+	p.skipDirectives()
+}
+
+// skipDirectives применяет директивы компилятора, стоящие перед текущим токеном
+func (p *Parser) skipDirectives() {
+	for p.pos < len(p.tokens) && p.tokens[p.pos].Type == TokenDIRECTIVE {
+		p.applyDirective(p.tokens[p.pos].Value)
+		p.pos++
+	}
+}
+
+// applyDirective применяет директиву вида {$R+} или {$R-,I+}; неизвестные директивы игнорируются
+func (p *Parser) applyDirective(text string) {
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "}"), "*)")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "{$"), "(*$")
+	for _, option := range strings.Split(text, ",") {
+		switch strings.ToUpper(strings.TrimSpace(option)) {
+		case "R+":
+			p.rangeChecksOff = false
+		case "R-":
+			p.rangeChecksOff = true
+		}
+	}
 }
 
 func (p *Parser) check(t TokenType) bool {
 	return p.current().Type == t
 }
 
+// checkAny сообщает, совпадает ли текущий токен с одним из типов types
+func (p *Parser) checkAny(types []TokenType) bool {
+	for _, t := range types {
+		if p.check(t) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) match(t TokenType) bool {
 	if p.check(t) {
 		p.advance()
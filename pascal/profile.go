@@ -0,0 +1,574 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Profile накапливает при выполнении программы число выполнений операторов и выражений
+// и время работы подпрограмм. Интерпретатор заполняет его, если профиль задан в Options.
+type Profile struct {
+	Counts   map[Node]int64 // число выполнений операторов и вычислений выражений
+	Routines map[*RoutineDecl]*RoutineProfile
+
+	now   func() time.Time // часы; подменяются в тестах
+	calls []*profileCall   // выполняемые подпрограммы, последняя - текущая
+}
+
+// RoutineProfile содержит число вызовов подпрограммы и время ее работы. Общее время
+// включает время вызванных подпрограмм, собственное - нет; время рекурсивных вызовов
+// входит в общее время только один раз, в самом внешнем вызове.
+type RoutineProfile struct {
+	Calls int64
+	Total time.Duration
+	Self  time.Duration
+
+	active int // число незавершенных вызовов подпрограммы
+}
+
+// profileCall описывает незавершенный вызов подпрограммы
+type profileCall struct {
+	routine  *RoutineProfile
+	start    time.Time
+	children time.Duration // общее время вызванных из него подпрограмм
+}
+
+// NewProfile создает пустой профиль
+func NewProfile() *Profile {
+	return &Profile{
+		Counts:   make(map[Node]int64),
+		Routines: make(map[*RoutineDecl]*RoutineProfile),
+		now:      time.Now,
+	}
+}
+
+// enter отмечает начало вызова подпрограммы и возвращает функцию, отмечающую его окончание
+func (p *Profile) enter(decl *RoutineDecl) func() {
+	routine := p.Routines[decl]
+	if routine == nil {
+		routine = &RoutineProfile{}
+		p.Routines[decl] = routine
+	}
+	routine.Calls++
+	routine.active++
+	call := &profileCall{routine: routine, start: p.now()}
+	p.calls = append(p.calls, call)
+	return func() {
+		elapsed := p.now().Sub(call.start)
+		p.calls = p.calls[:len(p.calls)-1]
+		routine.Self += elapsed - call.children
+		if routine.active == 1 {
+			routine.Total += elapsed
+		}
+		routine.active--
+		if len(p.calls) > 0 {
+			p.calls[len(p.calls)-1].children += elapsed
+		}
+	}
+}
+
+// NodeCount содержит число выполнений оператора или выражения
+type NodeCount struct {
+	Pos   Position
+	Node  string // краткая запись узла, например "x := …" или "WHILE"
+	Count int64
+}
+
+// RoutineReport содержит профиль подпрограммы для отчета
+type RoutineReport struct {
+	Name string
+	Pos  Position
+	RoutineProfile
+}
+
+// ProfileReport - отчет о выполнении программы: операторы с числом выполнений, включая
+// невыполненные, а для профиля также выражения и подпрограммы
+type ProfileReport struct {
+	Statements  []NodeCount
+	Expressions []NodeCount     // только вычислявшиеся выражения; пусто в отчете о покрытии
+	Routines    []RoutineReport // пусто в отчете о покрытии
+}
+
+// Report строит отчет о выполнении программы program и подключенных ею модулей
+func (p *Profile) Report(program *Program) *ProfileReport {
+	report := &ProfileReport{}
+	for _, stmt := range profileStatements(program) {
+		report.Statements = append(report.Statements, NodeCount{Pos: statementPos(stmt), Node: describeStatement(stmt), Count: p.Counts[stmt]})
+	}
+	for node, count := range p.Counts {
+		if expr, ok := node.(Expression); ok {
+			report.Expressions = append(report.Expressions, NodeCount{Pos: expressionPos(expr), Node: describeExpression(expr), Count: count})
+		}
+	}
+	for decl, routine := range p.Routines {
+		report.Routines = append(report.Routines, RoutineReport{Name: decl.Name, Pos: decl.Pos, RoutineProfile: *routine})
+	}
+	slices.SortStableFunc(report.Statements, compareNodeCounts)
+	slices.SortStableFunc(report.Expressions, compareNodeCounts)
+	slices.SortFunc(report.Routines, func(a, b RoutineReport) int {
+		if a.Total != b.Total {
+			return cmp.Compare(b.Total, a.Total)
+		}
+		return comparePositions(a.Pos, b.Pos)
+	})
+	return report
+}
+
+// Coverage возвращает отчет о покрытии: только операторы
+func (r *ProfileReport) Coverage() *ProfileReport {
+	return &ProfileReport{Statements: r.Statements}
+}
+
+// Covered возвращает число выполненных операторов и общее число операторов
+func (r *ProfileReport) Covered() (covered, total int) {
+	for _, stmt := range r.Statements {
+		if stmt.Count > 0 {
+			covered++
+		}
+	}
+	return covered, len(r.Statements)
+}
+
+// compareNodeCounts упорядочивает узлы по позиции в исходном тексте
+func compareNodeCounts(a, b NodeCount) int {
+	return comparePositions(a.Pos, b.Pos)
+}
+
+// comparePositions упорядочивает позиции: сначала основная программа, затем модули по имени файла
+func comparePositions(a, b Position) int {
+	if a.File != b.File {
+		return cmp.Compare(a.File, b.File)
+	}
+	if a.Line != b.Line {
+		return cmp.Compare(a.Line, b.Line)
+	}
+	return cmp.Compare(a.Column, b.Column)
+}
+
+// profileStatements возвращает операторы программы, ее подпрограмм и подключенных модулей,
+// кроме блоков BEGIN ... END, у которых нет своей позиции
+func profileStatements(program *Program) []Statement {
+	var statements []Statement
+	collect := func(node Node) {
+		Inspect(node, func(n Node) bool {
+			if stmt, ok := n.(Statement); ok {
+				if _, isBlock := stmt.(*Block); !isBlock {
+					statements = append(statements, stmt)
+				}
+			}
+			return true
+		})
+	}
+	seen := make(map[*Unit]bool)
+	var units func([]*UnitRef)
+	units = func(refs []*UnitRef) {
+		for _, ref := range refs {
+			if ref.Unit == nil || seen[ref.Unit] {
+				continue
+			}
+			seen[ref.Unit] = true
+			units(ref.Unit.allUses())
+			collect(ref.Unit)
+		}
+	}
+	units(program.Uses)
+	collect(program)
+	return statements
+}
+
+// statementPos возвращает позицию начала оператора
+func statementPos(stmt Statement) Position {
+	switch s := stmt.(type) {
+	case *Assignment:
+		return s.Pos
+	case *IfStatement:
+		return s.Pos
+	case *WhileStatement:
+		return s.Pos
+	case *RepeatStatement:
+		return s.Pos
+	case *ForStatement:
+		return s.Pos
+	case *CallStatement:
+		return s.Pos
+	case *CaseStatement:
+		return s.Pos
+	case *TryStatement:
+		return s.Pos
+	case *RaiseStatement:
+		return s.Pos
+	case *BreakStatement:
+		return s.Pos
+	case *ContinueStatement:
+		return s.Pos
+	case *ExitStatement:
+		return s.Pos
+	default:
+		return Position{}
+	}
+}
+
+// expressionPos возвращает позицию выражения: для операций - позицию знака операции
+func expressionPos(expr Expression) Position {
+	switch e := expr.(type) {
+	case *Number:
+		return e.Pos
+	case *Identifier:
+		return e.Pos
+	case *Literal:
+		return e.Pos
+	case *BinaryOp:
+		return e.Pos
+	case *UnaryOp:
+		return e.Pos
+	case *Dereference:
+		return e.Pos
+	case *FieldAccess:
+		return e.Pos
+	case *SetConstructor:
+		return e.Pos
+	case *CallExpr:
+		return e.Pos
+	case *CreateExpr:
+		return e.Pos
+	case *FormatExpr:
+		return e.Pos
+	default:
+		return Position{}
+	}
+}
+
+// formatPercent форматирует долю выполненных операторов
+func formatPercent(covered, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(covered)*100/float64(total))
+}
+
+// writeCoverText записывает отчет о покрытии в текстовом виде: долю выполненных
+// операторов и список невыполненных
+func writeCoverText(w io.Writer, report *ProfileReport) error {
+	covered, total := report.Covered()
+	fmt.Fprintf(w, "покрытие операторов: %d из %d (%s)\n", covered, total, formatPercent(covered, total))
+	if covered < total {
+		fmt.Fprintln(w, "не выполнялись:")
+	}
+	for _, stmt := range report.Statements {
+		if stmt.Count == 0 {
+			fmt.Fprintf(w, "  %s: %s\n", stmt.Pos, stmt.Node)
+		}
+	}
+	return nil
+}
+
+// writeProfileText записывает профиль в текстовом виде: подпрограммы по убыванию общего
+// времени, затем операторы и выражения в порядке их записи с числом выполнений
+func writeProfileText(w io.Writer, report *ProfileReport) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "подпрограммы:")
+	fmt.Fprintln(table, "вызовов\tобщее время\tсобственное время\t  подпрограмма")
+	for _, routine := range report.Routines {
+		fmt.Fprintf(table, "%d\t%s\t%s\t  %s (%s)\n", routine.Calls, routine.Total, routine.Self, routine.Name, routine.Pos)
+	}
+	table.Flush()
+	for _, section := range []struct {
+		title string
+		nodes []NodeCount
+	}{{"операторы:", report.Statements}, {"выражения:", report.Expressions}} {
+		fmt.Fprintln(w, section.title)
+		for _, node := range section.nodes {
+			fmt.Fprintf(table, "%d\t  %s: %s\n", node.Count, node.Pos, node.Node)
+		}
+		table.Flush()
+	}
+	return nil
+}
+
+// jsonNodeCount и jsonRoutine - записи отчета в формате JSON
+type jsonNodeCount struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Node   string `json:"node"`
+	Count  int64  `json:"count"`
+}
+
+type jsonRoutine struct {
+	Name   string `json:"name"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Calls  int64  `json:"calls"`
+	Total  int64  `json:"total_ns"`
+	Self   int64  `json:"self_ns"`
+}
+
+// writeReportJSON записывает отчет в формате JSON
+func writeReportJSON(w io.Writer, report *ProfileReport) error {
+	nodes := func(counts []NodeCount) []jsonNodeCount {
+		result := make([]jsonNodeCount, len(counts))
+		for n, c := range counts {
+			result[n] = jsonNodeCount{File: c.Pos.File, Line: c.Pos.Line, Column: c.Pos.Column, Node: c.Node, Count: c.Count}
+		}
+		return result
+	}
+	covered, total := report.Covered()
+	output := struct {
+		Covered     int             `json:"covered"`
+		Total       int             `json:"total"`
+		Statements  []jsonNodeCount `json:"statements"`
+		Expressions []jsonNodeCount `json:"expressions,omitempty"`
+		Routines    []jsonRoutine   `json:"routines,omitempty"`
+	}{Covered: covered, Total: total, Statements: nodes(report.Statements), Expressions: nodes(report.Expressions)}
+	for _, r := range report.Routines {
+		output.Routines = append(output.Routines, jsonRoutine{
+			Name: r.Name, File: r.Pos.File, Line: r.Pos.Line, Column: r.Pos.Column,
+			Calls: r.Calls, Total: int64(r.Total), Self: int64(r.Self),
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// htmlFile - исходный текст файла с разметкой числа выполнений операторов
+type htmlFile struct {
+	Name    string
+	Percent string
+	Source  template.HTML
+}
+
+// writeReportHTML записывает отчет в виде исходного текста программы и модулей, в котором
+// каждый оператор окрашен по числу выполнений, как в отчете go tool cover -html:
+// невыполненные операторы - красным, остальные - тем ярче, чем чаще они выполнялись.
+// Фрагмент оператора продолжается до начала следующего оператора в той же строке или до
+// конца строки. Исходный текст основной программы читается из файла filename.
+func writeReportHTML(w io.Writer, report *ProfileReport, filename string) error {
+	var files []string
+	byFile := make(map[string][]NodeCount)
+	var highest int64
+	for _, stmt := range report.Statements {
+		if _, ok := byFile[stmt.Pos.File]; !ok {
+			files = append(files, stmt.Pos.File)
+		}
+		byFile[stmt.Pos.File] = append(byFile[stmt.Pos.File], stmt)
+		highest = max(highest, stmt.Count)
+	}
+	if len(files) == 0 {
+		files = append(files, "")
+	}
+
+	data := struct {
+		Title    string
+		Files    []htmlFile
+		Routines []RoutineReport
+	}{Title: filename, Routines: report.Routines}
+	for _, file := range files {
+		name := file
+		if name == "" {
+			name = filename
+		}
+		source, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+		statements := byFile[file]
+		covered := 0
+		for _, stmt := range statements {
+			if stmt.Count > 0 {
+				covered++
+			}
+		}
+		data.Files = append(data.Files, htmlFile{
+			Name:    name,
+			Percent: formatPercent(covered, len(statements)),
+			Source:  annotateSource(string(source), statements, highest),
+		})
+	}
+	return profileTemplate.Execute(w, data)
+}
+
+// annotateSource размечает исходный текст фрагментами операторов statements,
+// упорядоченных по позиции
+func annotateSource(source string, statements []NodeCount, highest int64) template.HTML {
+	var out strings.Builder
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	next := 0
+	for n, line := range lines {
+		if n > 0 {
+			out.WriteByte('\n')
+		}
+		runes := []rune(line)
+		var starts []NodeCount
+		for ; next < len(statements) && statements[next].Pos.Line <= n+1; next++ {
+			if statements[next].Pos.Line == n+1 {
+				starts = append(starts, statements[next])
+			}
+		}
+		column := 0
+		for k, stmt := range starts {
+			begin := clamp(stmt.Pos.Column-1, column, len(runes))
+			end := len(runes)
+			if k+1 < len(starts) {
+				end = clamp(starts[k+1].Pos.Column-1, begin, len(runes))
+			}
+			out.WriteString(template.HTMLEscapeString(string(runes[column:begin])))
+			fmt.Fprintf(&out, `<span class="cov%d" title="%d">%s</span>`, coverLevel(stmt.Count, highest), stmt.Count, template.HTMLEscapeString(string(runes[begin:end])))
+			column = end
+		}
+		out.WriteString(template.HTMLEscapeString(string(runes[column:])))
+	}
+	return template.HTML(out.String())
+}
+
+// coverLevel возвращает класс окраски от cov0 (не выполнялся) до cov10 (выполнялся чаще
+// всех); промежуточные классы распределены по логарифму числа выполнений
+func coverLevel(count, highest int64) int {
+	switch {
+	case count == 0:
+		return 0
+	case highest <= 1:
+		return 8
+	}
+	return 1 + int(math.Floor(9*math.Log(float64(count))/math.Log(float64(highest))))
+}
+
+// clamp ограничивает n отрезком [low, high]
+func clamp(n, low, high int) int {
+	return max(low, min(n, high))
+}
+
+// profileTemplate - страница отчета; оформление повторяет отчет go tool cover -html
+var profileTemplate = template.Must(template.New("profile").Parse(`<!DOCTYPE html>
+<html>
+	<head>
+		<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+		<title>{{.Title}}: покрытие Pascal</title>
+		<style>
+			body {
+				background: black;
+				color: rgb(80, 80, 80);
+			}
+			body, pre, table, #legend span {
+				font-family: Menlo, monospace;
+				font-weight: bold;
+			}
+			#topbar {
+				background: black;
+				position: fixed;
+				top: 0; left: 0; right: 0;
+				height: 42px;
+				border-bottom: 1px solid rgb(80, 80, 80);
+			}
+			#content {
+				margin-top: 50px;
+			}
+			#nav, #legend {
+				float: left;
+				margin-left: 10px;
+			}
+			#legend {
+				margin-top: 12px;
+			}
+			#nav {
+				margin-top: 10px;
+			}
+			#legend span {
+				margin: 0 5px;
+			}
+			#routines {
+				color: rgb(160, 160, 160);
+			}
+			#routines td {
+				padding: 0 10px;
+			}
+			.cov0 { color: rgb(192, 0, 0) }
+			.cov1 { color: rgb(128, 128, 128) }
+			.cov2 { color: rgb(116, 140, 131) }
+			.cov3 { color: rgb(104, 152, 134) }
+			.cov4 { color: rgb(92, 164, 137) }
+			.cov5 { color: rgb(80, 176, 140) }
+			.cov6 { color: rgb(68, 188, 143) }
+			.cov7 { color: rgb(56, 200, 146) }
+			.cov8 { color: rgb(44, 212, 149) }
+			.cov9 { color: rgb(32, 224, 152) }
+			.cov10 { color: rgb(20, 236, 155) }
+		</style>
+	</head>
+	<body>
+		<div id="topbar">
+			<div id="nav">
+				<select id="files">
+				{{range $n, $f := .Files}}
+				<option value="file{{$n}}">{{$f.Name}} ({{$f.Percent}})</option>
+				{{end}}
+				</select>
+			</div>
+			<div id="legend">
+				<span>не выполнялся</span>
+				<span class="cov0">не выполнялся</span>
+				<span class="cov1">мало выполнений</span>
+				<span class="cov2">*</span>
+				<span class="cov3">*</span>
+				<span class="cov4">*</span>
+				<span class="cov5">*</span>
+				<span class="cov6">*</span>
+				<span class="cov7">*</span>
+				<span class="cov8">*</span>
+				<span class="cov9">*</span>
+				<span class="cov10">много выполнений</span>
+			</div>
+		</div>
+		<div id="content">
+		{{if .Routines}}
+		<table id="routines">
+			<tr><th>подпрограмма</th><th>вызовов</th><th>общее время</th><th>собственное время</th></tr>
+			{{range .Routines}}
+			<tr><td>{{.Name}} ({{.Pos}})</td><td>{{.Calls}}</td><td>{{.Total}}</td><td>{{.Self}}</td></tr>
+			{{end}}
+		</table>
+		{{end}}
+		{{range $n, $f := .Files}}
+		<pre class="file" id="file{{$n}}" style="display: none">{{$f.Source}}</pre>
+		{{end}}
+		</div>
+	</body>
+	<script>
+	(function() {
+		var files = document.getElementById('files');
+		var visible;
+		files.addEventListener('change', onChange, false);
+		function select(part) {
+			if (visible)
+				visible.style.display = 'none';
+			visible = document.getElementById(part);
+			if (!visible)
+				return;
+			files.value = part;
+			visible.style.display = 'block';
+			location.hash = part;
+		}
+		function onChange() {
+			select(files.value);
+			window.scrollTo(0, 0);
+		}
+		if (location.hash != "") {
+			select(location.hash.substr(1));
+		}
+		if (!visible) {
+			select("file0");
+		}
+	})();
+	</script>
+</html>
+`))
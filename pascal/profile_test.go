@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// profileProgram - программа, в которой часть ветвей не выполняется
+const profileProgram = `FUNCTION Sign(n: INTEGER): INTEGER;
+BEGIN
+  IF n > 0 THEN Sign := 1 ELSE IF n < 0 THEN Sign := -1 ELSE Sign := 0
+END;
+
+BEGIN
+  s := 0;
+  FOR i := 1 TO 3 DO
+    s := s + Sign(i);
+  IF (s > 0) OR (s = 100) THEN
+    WriteLn('положительно')
+  ELSE
+    WriteLn('нет')
+END.`
+
+// profileCode выполняет программу со сбором профиля
+func profileCode(t *testing.T, code string) (*Program, *Profile) {
+	t.Helper()
+	program, err := checkCode(t, code)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	profile := NewProfile()
+	var output bytes.Buffer
+	if err := NewInterpreterWithOptions(Options{Stdout: &output, Profile: profile}).Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	return program, profile
+}
+
+// fakeClock возвращает часы, которые при каждом обращении продвигаются на 1 нс
+func fakeClock() func() time.Time {
+	var tick int64
+	return func() time.Time {
+		tick++
+		return time.Unix(0, tick)
+	}
+}
+
+// TestProfileCounts тестирует счетчики выполнения операторов и выражений
+func TestProfileCounts(t *testing.T) {
+	program, profile := profileCode(t, profileProgram)
+	report := profile.Report(program)
+
+	statements := make(map[string]int64)
+	for _, stmt := range report.Statements {
+		statements[stmt.Pos.String()+": "+stmt.Node] = stmt.Count
+	}
+	expected := map[string]int64{
+		"строка 3, столбец 3: IF":         3,
+		"строка 3, столбец 17: Sign := …": 3,
+		"строка 3, столбец 32: IF":        0,
+		"строка 3, столбец 46: Sign := …": 0,
+		"строка 3, столбец 62: Sign := …": 0,
+		"строка 7, столбец 3: s := …":     1,
+		"строка 8, столбец 3: FOR i":      1,
+		"строка 9, столбец 5: s := …":     3,
+		"строка 10, столбец 3: IF":        1,
+		"строка 11, столбец 5: WriteLn":   1,
+		"строка 13, столбец 5: WriteLn":   0,
+	}
+	if len(statements) != len(expected) {
+		t.Errorf("ожидалось операторов: %d, получено %d: %v", len(expected), len(statements), statements)
+	}
+	for stmt, count := range expected {
+		if got, ok := statements[stmt]; !ok || got != count {
+			t.Errorf("%s: ожидалось выполнений %d, получено %d (найден: %t)", stmt, count, got, ok)
+		}
+	}
+
+	expressions := make(map[string]int64)
+	for _, expr := range report.Expressions {
+		expressions[expr.Pos.String()+": "+expr.Node] = expr.Count
+	}
+	for expr, count := range map[string]int64{
+		"строка 3, столбец 8: >":        3,
+		"строка 9, столбец 12: +":       3,
+		"строка 9, столбец 14: Sign(…)": 3,
+		"строка 10, столбец 14: OR":     1,
+	} {
+		if expressions[expr] != count {
+			t.Errorf("%s: ожидалось вычислений %d, получено %d", expr, count, expressions[expr])
+		}
+	}
+	// Правый операнд OR не вычислялся: логические операции вычисляются по короткой схеме
+	if count, ok := expressions["строка 10, столбец 20: ="]; ok {
+		t.Errorf("правый операнд OR не должен вычисляться, получено вычислений: %d", count)
+	}
+
+	if covered, total := report.Covered(); covered != 7 || total != 11 {
+		t.Errorf("ожидалось покрытие 7 из 11, получено %d из %d", covered, total)
+	}
+	if len(report.Routines) != 1 || report.Routines[0].Name != "Sign" || report.Routines[0].Calls != 3 {
+		t.Errorf("неожиданный профиль подпрограмм: %+v", report.Routines)
+	}
+}
+
+// TestProfileRoutineTime тестирует общее и собственное время подпрограмм, в том числе
+// рекурсивных и покинутых исключением
+func TestProfileRoutineTime(t *testing.T) {
+	const code = `VAR x: INTEGER;
+
+FUNCTION Fact(n: INTEGER): INTEGER;
+BEGIN
+  IF n <= 1 THEN Fact := 1 ELSE Fact := n * Fact(n - 1)
+END;
+
+PROCEDURE Run;
+BEGIN
+  x := Fact(3)
+END;
+
+PROCEDURE Fail;
+BEGIN
+  RAISE Exception.Create('сбой')
+END;
+
+BEGIN
+  Run;
+  TRY
+    Fail
+  EXCEPT
+    x := x + 1
+  END
+END.`
+	program, err := checkCode(t, code)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	profile := NewProfile()
+	profile.now = fakeClock()
+	if err := NewInterpreterWithOptions(Options{Profile: profile}).Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if len(profile.calls) != 0 {
+		t.Errorf("остались незавершенные вызовы: %d", len(profile.calls))
+	}
+
+	var text bytes.Buffer
+	if err := writeProfileText(&text, profile.Report(program)); err != nil {
+		t.Fatal(err)
+	}
+	// Часы продвигаются на 1 нс при каждом обращении: Run начинается в 1 нс и заканчивается
+	// в 8 нс, Fact(3), Fact(2) и Fact(1) - в 2..7, 3..6 и 4..5 нс
+	expected := `подпрограммы:
+  вызовов  общее время  собственное время  подпрограмма
+        1          7ns                2ns  Run (строка 8, столбец 11)
+        3          5ns                5ns  Fact (строка 3, столбец 10)
+        1          1ns                1ns  Fail (строка 13, столбец 11)
+операторы:
+`
+	if !strings.HasPrefix(text.String(), expected) {
+		t.Errorf("ожидался профиль:\n%s\nполучено:\n%s", expected, text.String())
+	}
+	if !strings.Contains(text.String(), "\n  1  строка 23, столбец 5: x := …\n") {
+		t.Errorf("в профиле нет обработчика исключения:\n%s", text.String())
+	}
+}
+
+// TestProfileCoverText тестирует текстовый отчет о покрытии
+func TestProfileCoverText(t *testing.T) {
+	program, profile := profileCode(t, profileProgram)
+	var text bytes.Buffer
+	if err := writeCoverText(&text, profile.Report(program).Coverage()); err != nil {
+		t.Fatal(err)
+	}
+	expected := `покрытие операторов: 7 из 11 (63.6%)
+не выполнялись:
+  строка 3, столбец 32: IF
+  строка 3, столбец 46: Sign := …
+  строка 3, столбец 62: Sign := …
+  строка 13, столбец 5: WriteLn
+`
+	if text.String() != expected {
+		t.Errorf("ожидался отчет:\n%s\nполучено:\n%s", expected, text.String())
+	}
+
+	program, profile = profileCode(t, "BEGIN x := 1 END.")
+	text.Reset()
+	writeCoverText(&text, profile.Report(program).Coverage())
+	if text.String() != "покрытие операторов: 1 из 1 (100.0%)\n" {
+		t.Errorf("неожиданный отчет о полном покрытии: %q", text.String())
+	}
+}
+
+// TestProfileReportJSON тестирует отчет в формате JSON
+func TestProfileReportJSON(t *testing.T) {
+	program, profile := profileCode(t, profileProgram)
+	report := profile.Report(program)
+
+	var output bytes.Buffer
+	if err := writeReportJSON(&output, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Covered    int
+		Total      int
+		Statements []struct {
+			Line, Column int
+			Node         string
+			Count        int64
+		}
+		Expressions []json.RawMessage
+		Routines    []struct {
+			Name  string
+			Calls int64
+			Total int64 `json:"total_ns"`
+			Self  int64 `json:"self_ns"`
+		}
+	}
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil {
+		t.Fatalf("некорректный JSON: %v\n%s", err, output.String())
+	}
+	if decoded.Covered != 7 || decoded.Total != 11 || len(decoded.Statements) != 11 {
+		t.Errorf("неожиданное покрытие: %+v", decoded)
+	}
+	if stmt := decoded.Statements[0]; stmt.Line != 3 || stmt.Column != 3 || stmt.Node != "IF" || stmt.Count != 3 {
+		t.Errorf("неожиданный первый оператор: %+v", stmt)
+	}
+	if len(decoded.Expressions) != len(report.Expressions) {
+		t.Errorf("ожидалось выражений: %d, получено %d", len(report.Expressions), len(decoded.Expressions))
+	}
+	if len(decoded.Routines) != 1 || decoded.Routines[0].Name != "Sign" || decoded.Routines[0].Calls != 3 || decoded.Routines[0].Total < decoded.Routines[0].Self {
+		t.Errorf("неожиданные подпрограммы: %+v", decoded.Routines)
+	}
+
+	// В отчете о покрытии нет выражений и подпрограмм
+	output.Reset()
+	writeReportJSON(&output, report.Coverage())
+	if strings.Contains(output.String(), `"expressions"`) || strings.Contains(output.String(), `"routines"`) {
+		t.Errorf("в отчете о покрытии лишние разделы:\n%s", output.String())
+	}
+}
+
+// TestProfileReportHTML тестирует размеченный исходный текст
+func TestProfileReportHTML(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sign.pas")
+	if err := os.WriteFile(filename, []byte(profileProgram), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	program, profile := profileCode(t, profileProgram)
+	report := profile.Report(program)
+
+	var output bytes.Buffer
+	if err := writeReportHTML(&output, report.Coverage(), filename); err != nil {
+		t.Fatal(err)
+	}
+	page := output.String()
+	for _, fragment := range []string{
+		`<option value="file0">` + filename + ` (63.6%)</option>`,
+		"  <span class=\"cov10\" title=\"3\">IF n &gt; 0 THEN </span>" +
+			"<span class=\"cov10\" title=\"3\">Sign := 1 ELSE </span>" +
+			"<span class=\"cov0\" title=\"0\">IF n &lt; 0 THEN </span>" +
+			"<span class=\"cov0\" title=\"0\">Sign := -1 ELSE </span>" +
+			"<span class=\"cov0\" title=\"0\">Sign := 0</span>\nEND;",
+		"\n  <span class=\"cov1\" title=\"1\">FOR i := 1 TO 3 DO</span>\n",
+		"\n    <span class=\"cov0\" title=\"0\">WriteLn(&#39;нет&#39;)</span>\nEND.",
+	} {
+		if !strings.Contains(page, fragment) {
+			t.Errorf("в отчете нет фрагмента %q:\n%s", fragment, page)
+		}
+	}
+	if strings.Contains(page, `id="routines"`) {
+		t.Error("в отчете о покрытии не должно быть таблицы подпрограмм")
+	}
+
+	output.Reset()
+	if err := writeReportHTML(&output, report, filename); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "<td>Sign (строка 1, столбец 10)</td><td>3</td>") {
+		t.Errorf("в профиле нет таблицы подпрограмм:\n%s", output.String())
+	}
+
+	if err := writeReportHTML(&output, report, filepath.Join(t.TempDir(), "missing.pas")); err == nil || !strings.HasPrefix(err.Error(), "ошибка чтения файла:") {
+		t.Errorf("ожидалась ошибка чтения исходного текста, получено %v", err)
+	}
+}
+
+// TestCoverLevel тестирует распределение числа выполнений по классам окраски
+func TestCoverLevel(t *testing.T) {
+	tests := []struct {
+		count, highest int64
+		level          int
+	}{
+		{0, 10, 0},
+		{1, 1, 8},
+		{1, 10, 1},
+		{10, 10, 10},
+		{3, 9, 5},
+		{100, 1000, 7},
+	}
+	for _, tt := range tests {
+		if level := coverLevel(tt.count, tt.highest); level != tt.level {
+			t.Errorf("coverLevel(%d, %d): ожидался класс %d, получено %d", tt.count, tt.highest, tt.level, level)
+		}
+	}
+}
+
+// TestMainProfile тестирует флаги -profile и -cover
+func TestMainProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{
+		"prog.pas": "USES Tools;\nBEGIN\n  x := Twice(2)\nEND.",
+		"tools.pas": `UNIT Tools;
+INTERFACE
+FUNCTION Twice(n: INTEGER): INTEGER;
+FUNCTION Half(n: INTEGER): INTEGER;
+IMPLEMENTATION
+FUNCTION Twice(n: INTEGER): INTEGER;
+BEGIN
+  Twice := 2 * n
+END;
+FUNCTION Half(n: INTEGER): INTEGER;
+BEGIN
+  Half := n DIV 2
+END;
+END.`,
+		"fail.pas": "BEGIN\n  x := 1;\n  x := x DIV 0;\n  x := 2\nEND.",
+	})
+	program := filepath.Join(dir, "prog.pas")
+	fail := filepath.Join(dir, "fail.pas")
+	tools := filepath.Join(dir, "tools.pas")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+		report string // файл отчета
+		text   string // ожидаемое начало отчета
+	}{
+		{
+			args:   []string{"-cover", filepath.Join(dir, "cover.txt"), program},
+			stdout: "{x: 4}\n",
+			report: filepath.Join(dir, "cover.txt"),
+			text:   "покрытие операторов: 2 из 3 (66.7%)\nне выполнялись:\n  " + tools + ": строка 12, столбец 3: Half := …\n",
+		},
+		{
+			args:   []string{"-profile", filepath.Join(dir, "profile.json"), program},
+			stdout: "{x: 4}\n",
+			report: filepath.Join(dir, "profile.json"),
+			text:   "{\n  \"covered\": 2,\n  \"total\": 3,\n",
+		},
+		{
+			args:   []string{"-cover", filepath.Join(dir, "cover.html"), program},
+			stdout: "{x: 4}\n",
+			report: filepath.Join(dir, "cover.html"),
+			text:   "<!DOCTYPE html>",
+		},
+		{
+			args:   []string{"-cover", "-", program},
+			stdout: "{x: 4}\n",
+			stderr: "покрытие операторов: 2 из 3 (66.7%)\n",
+		},
+		{
+			args:   []string{"-cover", filepath.Join(dir, "fail.txt"), fail},
+			code:   1,
+			stderr: "ошибка выполнения: строка 3, столбец 10: деление на ноль",
+			report: filepath.Join(dir, "fail.txt"),
+			text:   "покрытие операторов: 2 из 3 (66.7%)\nне выполнялись:\n  строка 4, столбец 3: x := …\n",
+		},
+		{
+			args:   []string{"-profile", filepath.Join(dir, "missing", "profile.txt"), program},
+			code:   1,
+			stderr: "ошибка записи файла:",
+		},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if !strings.HasPrefix(stderr.String(), tt.stderr) || tt.stderr == "" && stderr.Len() > 0 {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+		if tt.report == "" {
+			continue
+		}
+		report, err := os.ReadFile(tt.report)
+		if err != nil {
+			t.Errorf("%v: отчет не записан: %v", tt.args, err)
+			continue
+		}
+		if !strings.HasPrefix(string(report), tt.text) {
+			t.Errorf("%v: ожидался отчет, начинающийся с\n%s\nполучено:\n%s", tt.args, tt.text, report)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxCallDepth ограничивает глубину вложенных вызовов: бесконечная рекурсия возбуждает
+// исключение EStackOverflow, а не переполняет стек интерпретатора
+const maxCallDepth = 10000
+
+// Param описывает формальный параметр подпрограммы
+type Param struct {
+	Name  string
+	Type  *Type
+	ByRef bool
+}
+
+// Signature описывает параметры и тип результата подпрограммы
+type Signature struct {
+	Params []Param
+	Result *Type // nil для процедуры
+}
+
+// resolveSignature вычисляет типы параметров и результата подпрограммы;
+// при ошибке возвращает позицию записи ошибочного типа
+func resolveSignature(decl *RoutineDecl, resolver *typeResolver) (*Signature, Position, error) {
+	signature := &Signature{}
+	for _, param := range decl.Params {
+		t, err := resolver.resolve(param.Type)
+		if err != nil {
+			return nil, param.Pos, err
+		}
+		signature.Params = append(signature.Params, Param{Name: param.Name, Type: t, ByRef: param.ByRef})
+	}
+	if decl.Result != nil {
+		t, err := resolver.resolve(decl.Result)
+		if err != nil {
+			return nil, decl.Pos, err
+		}
+		if t.Kind == TypeText {
+			return nil, decl.Pos, fmt.Errorf("функция %s не может возвращать файл", decl.Name)
+		}
+		signature.Result = t
+	}
+	return signature, Position{}, nil
+}
+
+// sameSignature сообщает, совпадают ли виды и типы параметров и тип результата подпрограмм
+func sameSignature(a, b *Signature) bool {
+	if len(a.Params) != len(b.Params) || (a.Result == nil) != (b.Result == nil) {
+		return false
+	}
+	for n, param := range a.Params {
+		if param.ByRef != b.Params[n].ByRef || !sameVarType(param.Type, b.Params[n].Type) {
+			return false
+		}
+	}
+	return a.Result == nil || sameVarType(a.Result, b.Result)
+}
+
+// Routine представляет процедуру или функцию во время выполнения. Вложенная подпрограмма
+// создается при каждой активации объемлющей и запоминает ее как область видимости.
+type Routine struct {
+	Decl *RoutineDecl
+	*Signature
+	Scope *Frame // область видимости, в которой описана подпрограмма
+}
+
+// Frame представляет область видимости: глобальные имена программы или активацию подпрограммы
+// с ее параметрами, локальными именами и местом вызова. Parent (статическая связь) ведет
+// к активации объемлющей подпрограммы и определяет видимость имен, Caller (динамическая связь)
+// ведет к вызвавшей активации и определяет стек вызовов.
+type Frame struct {
+	Name      string   // имя программы или модуля глобальной области видимости
+	Routine   *Routine // nil для глобальной области видимости
+	variables map[string]*Variable
+	types     map[string]*Type
+	routines  map[string]*Routine
+	Uses      []*Frame // интерфейсы модулей из USES; просматриваются после собственных имен
+	Parent    *Frame   // объемлющая область видимости; nil для глобальной
+	Caller    *Frame   // активация вызвавшей подпрограммы; nil, если вызов из тела программы
+	Pos       Position // позиция вызова
+	depth     int      // число активаций подпрограмм в стеке, включая эту
+}
+
+// binding описывает, что обозначает имя в ближайшей объемлющей области видимости, где оно описано
+type binding struct {
+	variable *Variable
+	typ      *Type
+	routine  *Routine
+}
+
+// resolve ищет имя по статическим связям от текущей области видимости к глобальной:
+// локальное имя скрывает одноименные имена объемлющих подпрограмм и программы
+func (i *Interpreter) resolve(key string) binding {
+	for frame := i.scope(); frame != nil; frame = frame.Parent {
+		if variable, ok := frame.variables[key]; ok {
+			if frame != i.frame && frame.isResult(key) {
+				// Во вложенной подпрограмме имя функции обозначает вызов, а не результат
+				return binding{routine: frame.Routine}
+			}
+			return binding{variable: variable}
+		}
+		if t, ok := frame.types[key]; ok {
+			return binding{typ: t}
+		}
+		if routine, ok := frame.routines[key]; ok {
+			return binding{routine: routine}
+		}
+		// Модуль, указанный в USES позже, скрывает одноименные имена предыдущих
+		for n := len(frame.Uses) - 1; n >= 0; n-- {
+			if found := frame.Uses[n].lookup(key); found != (binding{}) {
+				return found
+			}
+		}
+	}
+	return binding{}
+}
+
+// lookup ищет имя только среди имен области видимости f
+func (f *Frame) lookup(key string) binding {
+	if variable, ok := f.variables[key]; ok {
+		return binding{variable: variable}
+	}
+	if t, ok := f.types[key]; ok {
+		return binding{typ: t}
+	}
+	if routine, ok := f.routines[key]; ok {
+		return binding{routine: routine}
+	}
+	return binding{}
+}
+
+// isResult сообщает, является ли имя key переменной результата функции активации
+func (f *Frame) isResult(key string) bool {
+	return f.Routine != nil && f.Routine.Result != nil && strings.EqualFold(f.Routine.Decl.Name, key)
+}
+
+// scope возвращает текущую область видимости, в которой создаются описанные и неописанные переменные
+func (i *Interpreter) scope() *Frame {
+	if i.frame != nil {
+		return i.frame
+	}
+	return i.globals
+}
+
+// frameName возвращает имя подпрограммы активации для стека вызовов
+func (i *Interpreter) frameName(frame *Frame) string {
+	if frame != nil {
+		return frame.Routine.Decl.Name
+	}
+	if i.globals.Name != "" {
+		return i.globals.Name
+	}
+	return "основная программа"
+}
+
+// defineRoutine описывает процедуру или функцию в текущей области видимости
+func (i *Interpreter) defineRoutine(decl *RoutineDecl, resolver *typeResolver) error {
+	scope := i.scope()
+	key := strings.ToLower(decl.Name)
+	_, isVariable := scope.variables[key]
+	_, isType := scope.types[key]
+	_, isRoutine := scope.routines[key]
+	if isVariable || isType || isRoutine {
+		return runtimeError(decl.Pos, "повторное описание %s", decl.Name)
+	}
+	signature, pos, err := resolveSignature(decl, resolver)
+	if err != nil {
+		return runtimeError(pos, "%v", err)
+	}
+	scope.routines[key] = &Routine{Decl: decl, Signature: signature, Scope: scope}
+	return nil
+}
+
+// callFunction вызывает функцию в выражении и возвращает ее результат
+func (i *Interpreter) callFunction(routine *Routine, args []Expression, name string, pos Position) (Value, error) {
+	if routine.Result == nil {
+		return nil, runtimeError(pos, "процедура %s не возвращает значения", name)
+	}
+	return i.callRoutine(routine, args, pos)
+}
+
+// callRoutine вызывает подпрограмму: вычисляет аргументы в области видимости вызывающего,
+// создает активацию, выполняет описания и тело. Для функции возвращает значение, присвоенное
+// ее имени, для процедуры - nil. Исключение, покидающее подпрограмму, дополняется стеком вызовов.
+func (i *Interpreter) callRoutine(routine *Routine, args []Expression, pos Position) (Value, error) {
+	decl := routine.Decl
+	if len(args) != len(routine.Params) {
+		return nil, runtimeError(pos, "подпрограмма %s ожидает %d аргумент(ов), получено %d", decl.Name, len(routine.Params), len(args))
+	}
+	frame := &Frame{
+		Routine:   routine,
+		variables: make(map[string]*Variable),
+		types:     make(map[string]*Type),
+		routines:  make(map[string]*Routine),
+		Parent:    routine.Scope,
+		Caller:    i.frame,
+		Pos:       pos,
+		depth:     1,
+	}
+	if i.frame != nil {
+		frame.depth = i.frame.depth + 1
+	}
+	if frame.depth > maxCallDepth {
+		return nil, raiseError(stackOverflowClass, pos, "переполнение стека: глубина вызовов превысила %d", maxCallDepth)
+	}
+	var result *Variable
+	if routine.Result != nil {
+		// Результат функции - переменная с именем функции
+		result = &Variable{Name: decl.Name, Type: routine.Result, Value: zeroValue(routine.Result)}
+		frame.variables[strings.ToLower(decl.Name)] = result
+	}
+	for n, param := range routine.Params {
+		variable, err := i.bindArgument(param, args[n], pos)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(param.Name)
+		if _, exists := frame.variables[key]; exists {
+			return nil, runtimeError(decl.Params[n].Pos, "повторное описание %s", param.Name)
+		}
+		frame.variables[key] = variable
+	}
+
+	caller, loops := i.frame, i.loops
+	i.frame, i.loops = frame, 0
+	if i.profile != nil {
+		defer i.profile.enter(decl)()
+	}
+	if i.observer != nil && i.resume == nil {
+		i.observer.OnCall(routine, pos)
+	}
+	err := i.declare(&decl.Declarations)
+	if err == nil {
+		err = i.restoreScope(scopeRoutine, decl.Name, frame.variables)
+	}
+	if err == nil {
+		err = i.executeStatements(decl.Body.Statements)
+	}
+	i.frame, i.loops = caller, loops
+	// Exit завершает только эту подпрограмму
+	i.flow = flowNormal
+
+	if err != nil {
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			if len(runtimeErr.Trace) == 0 {
+				runtimeErr.Trace = append(runtimeErr.Trace, StackFrame{Routine: decl.Name, Pos: runtimeErr.Pos})
+			}
+			runtimeErr.Trace = append(runtimeErr.Trace, StackFrame{Routine: i.frameName(caller), Pos: pos})
+		}
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result.Value, nil
+}
+
+// bindArgument создает переменную параметра. Параметр-значение получает копию значения
+// аргумента, VAR-параметр ссылается на место хранения аргумента.
+func (i *Interpreter) bindArgument(param Param, arg Expression, pos Position) (*Variable, error) {
+	variable := &Variable{Name: param.Name, Type: param.Type, Value: zeroValue(param.Type)}
+	if !param.ByRef {
+		value, err := i.evaluateExpression(arg)
+		if err != nil {
+			return nil, err
+		}
+		ref := reference{name: param.Name, typ: param.Type, value: &variable.Value}
+		return variable, i.store(ref, pos, value, true)
+	}
+
+	if id, ok := arg.(*Identifier); ok {
+		// Неописанная переменная, переданная в VAR-параметр, создается со значением по умолчанию
+		if _, declared := i.variable(strings.ToLower(id.Name)); !declared {
+			if err := i.assign(id.Name, id.Pos, zeroValue(param.Type), false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	ref, err := i.locate(arg)
+	if err != nil {
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) {
+			return nil, err
+		}
+		return nil, runtimeError(pos, "VAR-параметр %s: %v", param.Name, err)
+	}
+	if ref.typ != nil && !sameVarType(ref.typ, param.Type) {
+		return nil, runtimeError(pos, "VAR-параметр %s: тип переменной %s не совпадает с типом параметра %s", param.Name, ref.typ, param.Type)
+	}
+	if ref.typ == nil {
+		// Неописанная переменная получает тип параметра
+		converted, err := convertValue(param.Type, *ref.value)
+		if err != nil {
+			return nil, runtimeError(pos, "VAR-параметр %s: %v", param.Name, err)
+		}
+		*ref.value = converted
+	}
+	variable.ref = ref.value
+	return variable, nil
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRoutines тестирует процедуры и функции: параметры-значения, VAR-параметры,
+// результат функции, рекурсию и локальные переменные
+func TestRoutines(t *testing.T) {
+	code := `PROGRAM Routines;
+TYPE TPoint = RECORD x, y: INTEGER END;
+VAR a, b, f, g, calls: INTEGER; p: TPoint;
+
+PROCEDURE Swap(VAR u, v: INTEGER);
+VAR tmp: INTEGER;
+BEGIN
+  tmp := u; u := v; v := tmp
+END;
+
+FUNCTION Fact(n: INTEGER): INTEGER;
+BEGIN
+  IF n <= 1 THEN Fact := 1 ELSE Fact := n * Fact(n - 1)
+END;
+
+FUNCTION Twice(n: INTEGER): INTEGER;
+BEGIN
+  Twice := n;
+  Twice := Twice + n
+END;
+
+FUNCTION Next: INTEGER;
+BEGIN
+  calls := calls + 1;
+  Next := calls
+END;
+
+PROCEDURE Move(VAR pt: TPoint; dx: INTEGER);
+BEGIN
+  pt.x := pt.x + dx;
+  dx := 0
+END;
+
+PROCEDURE Clear(n: INTEGER);
+BEGIN
+  a := 0;
+  local := n
+END;
+
+BEGIN
+  a := 1; b := 2;
+  Swap(a, b);
+  f := Fact(10);
+  g := Twice(21) + Next + Next;
+  p.x := 5;
+  Move(p, 3);
+  Swap(p.x, p.y);
+  Clear(7)
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		values := interpreter.Values()
+		want := map[string]string{
+			"a":     "0",
+			"b":     "1",
+			"f":     "3628800",
+			"g":     "45",
+			"calls": "2",
+			"p":     "(x: 0; y: 8)",
+		}
+		for name, value := range want {
+			if got := values[name]; got == nil || got.String() != value {
+				t.Errorf("%s: ожидалось %s, получено %v", name, value, got)
+			}
+		}
+		if _, ok := values["local"]; ok {
+			t.Errorf("Неописанная переменная подпрограммы не должна попадать в глобальные: %v", values)
+		}
+	}
+}
+
+// TestRoutineShadowsBuiltin тестирует, что подпрограмма программы скрывает стандартную
+func TestRoutineShadowsBuiltin(t *testing.T) {
+	code := `VAR n: INTEGER;
+FUNCTION Abs(x: INTEGER): INTEGER;
+BEGIN
+  Abs := 100 + x
+END;
+PROCEDURE Print(x: INTEGER);
+BEGIN
+  n := n + x
+END;
+BEGIN
+  n := Abs(-1);
+  Print(1)
+END.`
+	interpreter, err := runChecked(t, code)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if got := interpreter.Values()["n"]; got != IntegerValue(100) {
+		t.Errorf("Ожидалось n = 100, получено %v", got)
+	}
+}
+
+// TestRoutineRuntimeErrors тестирует ошибки вызова подпрограмм при выполнении без семантического анализа
+func TestRoutineRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"PROCEDURE P(n: INTEGER); BEGIN END; BEGIN P(1, 2) END.", "подпрограмма P ожидает 1 аргумент(ов), получено 2"},
+		{"PROCEDURE P; BEGIN END; BEGIN x := P() END.", "процедура P не возвращает значения"},
+		{"PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(1 + 2) END.", "VAR-параметр n"},
+		{"VAR r: REAL; PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(r) END.", "тип переменной REAL не совпадает с типом параметра INTEGER"},
+		{"TYPE S = 1..5; VAR v: S; PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(v) END.", "тип переменной S не совпадает с типом параметра INTEGER"},
+		{"TYPE S = 1..5; PROCEDURE P(n: S); BEGIN END; BEGIN P(7) END.", "вне диапазона"},
+		{"PROCEDURE P; BEGIN P END; BEGIN P END.", "переполнение стека: глубина вызовов превысила 10000"},
+		{"VAR P: INTEGER; PROCEDURE P; BEGIN END; BEGIN END.", "повторное описание P"},
+		{"FUNCTION F(f: INTEGER): INTEGER; BEGIN END; BEGIN x := F(1) END.", "повторное описание f"},
+		{"PROCEDURE P; BEGIN END; BEGIN P := 1 END.", "P - имя подпрограммы, а не переменной"},
+		{"FUNCTION F: TEXT; BEGIN END; BEGIN END.", "функция F не может возвращать файл"},
+	}
+	for _, tt := range tests {
+		_, err := interpretCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestCheckerRoutines тестирует семантические ошибки процедур и функций
+func TestCheckerRoutines(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"PROCEDURE P(n: INTEGER); BEGIN END; BEGIN P END.", "процедура P ожидает 1 аргумент(ов), получено 0"},
+		{"FUNCTION F(n: INTEGER): INTEGER; BEGIN END; BEGIN x := F(1, 2) END.", "функция F ожидает 1 аргумент(ов), получено 2"},
+		{"FUNCTION F(n: INTEGER): INTEGER; BEGIN END; BEGIN x := F END.", "функция F ожидает 1 аргумент(ов), получено 0"},
+		{"PROCEDURE P; BEGIN END; BEGIN x := P END.", "процедура P не возвращает значения"},
+		{"PROCEDURE P; BEGIN END; BEGIN x := P() + 1 END.", "процедура P не возвращает значения"},
+		{"PROCEDURE P(n: INTEGER); BEGIN END; BEGIN P(TRUE) END.", "P: нельзя передать BOOLEAN в параметр n типа INTEGER"},
+		{"TYPE S = 1..5; PROCEDURE P(n: S); BEGIN END; BEGIN P(7) END.", "P: значение 7 вне диапазона 1..5 параметра n"},
+		{"PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(1) END.", "P: в VAR-параметр n можно передать только переменную"},
+		{"CONST c = 1; PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(c) END.", "в VAR-параметр n можно передать только переменную, c не является переменной"},
+		{"VAR r: REAL; PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(r) END.", "тип переменной r (REAL) не совпадает с типом VAR-параметра n (INTEGER)"},
+		{"TYPE S = 1..5; VAR v: S; PROCEDURE P(VAR n: INTEGER); BEGIN END; BEGIN P(v) END.", "тип переменной v (S) не совпадает с типом VAR-параметра n (INTEGER)"},
+		{"PROCEDURE P; BEGIN END; BEGIN P := 1 END.", "P - имя подпрограммы, а не переменной"},
+		{"FUNCTION F: INTEGER; BEGIN END; PROCEDURE P; BEGIN F := 1 END; BEGIN END.", "F - имя подпрограммы, а не переменной"},
+		{"FUNCTION F: INTEGER; BEGIN F := TRUE END; BEGIN END.", "нельзя присвоить BOOLEAN переменной F типа INTEGER"},
+		{"FUNCTION F(f: INTEGER): INTEGER; BEGIN END; BEGIN END.", "повторное описание f"},
+		{"VAR P: INTEGER; PROCEDURE P; BEGIN END; BEGIN END.", "повторное описание P"},
+		{"PROCEDURE P(n: Missing); BEGIN END; BEGIN END.", "неизвестный тип Missing"},
+		{"PROCEDURE P; VAR n: INTEGER; BEGIN n := TRUE END; BEGIN END.", "нельзя присвоить BOOLEAN переменной n типа INTEGER"},
+		{"PROCEDURE P; VAR n: INTEGER; BEGIN END; BEGIN n := TRUE; x := n + 1 END.", "арифметическая операция неприменима к типу BOOLEAN"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// Неописанная переменная, переданная в VAR-параметр, получает тип параметра
+	program, err := checkCode(t, "PROCEDURE P(VAR b: BOOLEAN); BEGIN b := TRUE END; BEGIN P(flag); x := flag AND TRUE END.")
+	if err != nil || program == nil {
+		t.Errorf("Неожиданная ошибка: %v", err)
+	}
+}
+
+// TestParseRoutines тестирует разбор описаний процедур и функций
+func TestParseRoutines(t *testing.T) {
+	program := parseCode(t, `PROCEDURE Log; BEGIN END;
+FUNCTION Max(a, b: INTEGER; VAR c: REAL): INTEGER; BEGIN Max := a END;
+BEGIN Log; Log() END.`)
+	routines := program.Declarations.Routines
+	if len(routines) != 2 {
+		t.Fatalf("Ожидалось 2 подпрограммы, получено %d", len(routines))
+	}
+	wants := []string{
+		"Procedure(Log())",
+		"Function(Max(a: INTEGER; b: INTEGER; VAR c: REAL): INTEGER)",
+	}
+	for n, want := range wants {
+		if got := routines[n].String(); got != want {
+			t.Errorf("Ожидалось %s, получено %s", want, got)
+		}
+	}
+	if len(program.Statements) != 2 {
+		t.Errorf("Ожидалось 2 вызова Log, получено %v", program.Statements)
+	}
+
+	errorTests := []struct {
+		code string
+		want string
+	}{
+		{"PROCEDURE P; PROCEDURE Q; BEGIN END; BEGIN END; BEGIN Q END.", "ожидался :="},
+		{"PROCEDURE P; BEGIN END; VAR x: INTEGER; BEGIN END.", "раздел VAR должен предшествовать описаниям процедур и функций"},
+		{"PROCEDURE P(n: 1..5); BEGIN END; BEGIN END.", "ожидалось имя типа параметра"},
+		{"PROCEDURE P(n); BEGIN END; BEGIN END.", "ожидалось ':' после имени параметра"},
+		{"FUNCTION F; BEGIN END; BEGIN END.", "ожидался тип результата функции F"},
+		{"PROCEDURE P BEGIN END; BEGIN END.", "ожидалась ';'"},
+		{"PROCEDURE P; BEGIN END BEGIN END.", "ожидалась ';'"},
+	}
+	for _, tt := range errorTests {
+		tokens, err := NewLexer(tt.code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", tt.code, err)
+		}
+		_, err = NewParser(tokens).Parse()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestNestedRoutines тестирует вложенные подпрограммы: доступ к переменным объемлющих
+// подпрограмм через несколько уровней, скрытие имен и рекурсию во вложенных подпрограммах
+func TestNestedRoutines(t *testing.T) {
+	code := `PROGRAM Nested;
+VAR x, depth, total, sum, fib10, tail: INTEGER; trace: STRING;
+
+PROCEDURE Level1;
+VAR a: INTEGER;
+  PROCEDURE Level2;
+  VAR b: INTEGER;
+    PROCEDURE Level3;
+    BEGIN
+      x := x + a + b;
+      depth := 3
+    END;
+  BEGIN
+    b := 20;
+    Level3
+  END;
+BEGIN
+  a := 100;
+  Level2
+END;
+
+PROCEDURE Shadow;
+VAR x: INTEGER;
+  PROCEDURE Inner;
+  VAR x: INTEGER;
+  BEGIN
+    x := 7;
+    trace := trace + 'inner '
+  END;
+BEGIN
+  x := 5;
+  Inner;
+  total := x
+END;
+
+FUNCTION SumTo(n: INTEGER): INTEGER;
+VAR acc: INTEGER;
+  PROCEDURE Add(k: INTEGER);
+  BEGIN
+    IF k > 0 THEN
+    BEGIN
+      acc := acc + k;
+      Add(k - 1)
+    END
+  END;
+BEGIN
+  acc := 0;
+  Add(n);
+  SumTo := acc
+END;
+
+FUNCTION Fib(n: INTEGER): INTEGER;
+  FUNCTION Prev(k: INTEGER): INTEGER;
+  BEGIN
+    Prev := Fib(k - 1) + Fib(k - 2)
+  END;
+BEGIN
+  IF n < 2 THEN Fib := n ELSE Fib := Prev(n)
+END;
+
+PROCEDURE Outer(n: INTEGER);
+  PROCEDURE Show;
+  BEGIN
+    trace := trace + Chr(Ord('0') + n)
+  END;
+BEGIN
+  IF n > 0 THEN Outer(n - 1);
+  Show
+END;
+
+FUNCTION Count(n: INTEGER): INTEGER;
+  PROCEDURE Again;
+  BEGIN
+    IF n > 0 THEN tail := Count(n - 1) + tail
+  END;
+BEGIN
+  Count := 1;
+  Again
+END;
+
+BEGIN
+  x := 1;
+  Level1;
+  Shadow;
+  sum := SumTo(10);
+  fib10 := Fib(10);
+  Outer(3);
+  x := x + Count(3)
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		values := interpreter.Values()
+		want := map[string]Value{
+			"x":     IntegerValue(122),
+			"depth": IntegerValue(3),
+			"total": IntegerValue(5),
+			"sum":   IntegerValue(55),
+			"fib10": IntegerValue(55),
+			"trace": StringValue("inner 0123"),
+			"tail":  IntegerValue(3),
+		}
+		for name, value := range want {
+			if got := values[name]; got != value {
+				t.Errorf("%s: ожидалось %v, получено %v", name, value, got)
+			}
+		}
+	}
+}
+
+// TestNestedRoutineErrors тестирует видимость вложенных подпрограмм и их локальных имен
+func TestNestedRoutineErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"PROCEDURE P; PROCEDURE Q; BEGIN END; BEGIN END; BEGIN Q() END.", "неизвестная процедура Q"},
+		{"PROCEDURE P; VAR a: INTEGER; PROCEDURE Q; BEGIN END; BEGIN END; BEGIN a := TRUE; x := a + 1 END.", "арифметическая операция неприменима к типу BOOLEAN"},
+		{"PROCEDURE P; VAR q: INTEGER; PROCEDURE Q; BEGIN END; BEGIN END; BEGIN END.", "повторное описание Q"},
+		// Локальная переменная может скрывать имя самой процедуры
+		{"PROCEDURE P; PROCEDURE Q; VAR q: BOOLEAN; BEGIN q := 1 END; BEGIN END; BEGIN END.", "нельзя присвоить INTEGER переменной q типа BOOLEAN"},
+		{"FUNCTION F: INTEGER; PROCEDURE G; BEGIN F := 1 END; BEGIN END; BEGIN END.", "F - имя подпрограммы, а не переменной"},
+		{"PROCEDURE P; TYPE T = (A, B); PROCEDURE Q; VAR v: T; BEGIN v := 1 END; BEGIN END; BEGIN END.", "нельзя присвоить INTEGER переменной v типа T"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	runtimeTests := []struct {
+		code string
+		want string
+	}{
+		{"PROCEDURE P; PROCEDURE Q; BEGIN END; BEGIN END; BEGIN Q() END.", "неизвестная процедура Q"},
+		{"PROCEDURE P; PROCEDURE P; BEGIN END; BEGIN END; BEGIN P END.", ""},
+		{"FUNCTION F: INTEGER; PROCEDURE F; BEGIN END; BEGIN END; BEGIN x := F END.", "повторное описание F"},
+		{"FUNCTION F: INTEGER; PROCEDURE G; BEGIN F := 1 END; BEGIN G END; BEGIN x := F END.", "F - имя подпрограммы, а не переменной"},
+	}
+	for _, tt := range runtimeTests {
+		_, err := interpretCode(t, tt.code)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%q: неожиданная ошибка %v", tt.code, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// Стек вызовов проходит по динамическим связям, а не по статическим
+	_, err := runChecked(t, `PROCEDURE Outer;
+  PROCEDURE Inner(n: INTEGER);
+  BEGIN
+    IF n = 0 THEN x := 1 DIV n ELSE Inner(n - 1)
+  END;
+BEGIN
+  Inner(1)
+END;
+BEGIN
+  Outer
+END.`)
+	want := "строка 4, столбец 26: деление на ноль (EDivByZero)\nстек вызовов:\n" +
+		"  Inner (строка 4, столбец 26)\n  Inner (строка 4, столбец 37)\n  Outer (строка 7, столбец 3)\n  основная программа (строка 10, столбец 3)"
+	if got := describeError(err); got != want {
+		t.Errorf("Ожидалось\n%s\nполучено\n%s", want, got)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotMagic начинает файл снимка; за ним следует Snapshot в формате gob
+const snapshotMagic = "pascal snapshot 2\n"
+
+// Snapshot - снимок остановленного выполнения, который записывает флаг -snapshot
+// и продолжает команда pascal resume. Продолжение восстанавливает переменные, стек
+// вызовов, кучу, открытые файлы и позиции ввода и вывода и выполняет программу с того
+// оператора, перед которым она остановлена; уже выполненные операторы не повторяются.
+type Snapshot struct {
+	Program      string          // абсолютный путь к программе
+	Sources      []SourceFile    // программа и модули с хешами исходного текста
+	Options      SnapshotOptions // режимы выполнения
+	Continuation *Continuation
+}
+
+// SnapshotOptions - режимы выполнения, с которыми программа продолжается после снимка
+type SnapshotOptions struct {
+	Leaks, BigInt bool
+	Dialect       Dialect
+	Root, Units   string // абсолютные пути
+	Input         string // файл JSON с начальными значениями переменных и его содержимое
+	InputData     []byte
+	Defines       []string
+	Evals         []string
+}
+
+// SourceFile - файл исходного текста программы или модуля и хеш SHA-256 его содержимого
+type SourceFile struct {
+	Path string
+	Hash [sha256.Size]byte
+}
+
+// writeSnapshot записывает снимок в файл
+func writeSnapshot(filename string, snapshot *Snapshot) error {
+	var data bytes.Buffer
+	data.WriteString(snapshotMagic)
+	if err := gob.NewEncoder(&data).Encode(snapshot); err != nil {
+		return fmt.Errorf("ошибка записи снимка: %v", err)
+	}
+	if err := os.WriteFile(filename, data.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("ошибка записи файла: %v", err)
+	}
+	return nil
+}
+
+// readSnapshot читает снимок из файла
+func readSnapshot(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	rest, ok := bytes.CutPrefix(data, []byte(snapshotMagic))
+	if !ok {
+		return nil, fmt.Errorf("%s: файл не является снимком выполнения", filename)
+	}
+	var snapshot Snapshot
+	if err := gob.NewDecoder(bytes.NewReader(rest)).Decode(&snapshot); err != nil || snapshot.Continuation == nil || snapshot.Continuation.State == nil {
+		return nil, fmt.Errorf("%s: поврежденный снимок выполнения", filename)
+	}
+	return &snapshot, nil
+}
+
+// usedUnits возвращает модули, которые подключает программа, в порядке обхода USES в глубину
+func usedUnits(program *Program) []*Unit {
+	var units []*Unit
+	seen := make(map[*Unit]bool)
+	var visit func(uses []*UnitRef)
+	visit = func(uses []*UnitRef) {
+		for _, ref := range uses {
+			if ref.Unit == nil || seen[ref.Unit] {
+				continue
+			}
+			seen[ref.Unit] = true
+			units = append(units, ref.Unit)
+			visit(ref.Unit.allUses())
+		}
+	}
+	visit(program.Uses)
+	return units
+}
+
+// sourcePaths возвращает файл программы и файлы модулей, которые она подключает
+func sourcePaths(filename string, program *Program) []string {
+	paths := []string{filename}
+	for _, unit := range usedUnits(program) {
+		paths = append(paths, unit.Pos.File)
+	}
+	return paths
+}
+
+// sourceFiles возвращает файлы программы и модулей, которые она подключает, с хешами
+func sourceFiles(filename string, program *Program) ([]SourceFile, error) {
+	paths := sourcePaths(filename, program)
+	sources := make([]SourceFile, len(paths))
+	for n, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+		sources[n] = SourceFile{Path: path, Hash: sha256.Sum256(data)}
+	}
+	return sources, nil
+}
+
+// checkSources проверяет, что исходный текст программы и модулей не изменился после снимка:
+// иначе продолжение не соответствует программе
+func (s *Snapshot) checkSources(sources []SourceFile) error {
+	for n, source := range sources {
+		if n >= len(s.Sources) || s.Sources[n].Hash != source.Hash {
+			return fmt.Errorf("%s: исходный текст изменился после снимка", source.Path)
+		}
+	}
+	if len(sources) != len(s.Sources) {
+		return fmt.Errorf("%s: набор модулей изменился после снимка", s.Program)
+	}
+	return nil
+}
+
+// newSnapshot составляет снимок выполнения программы filename, остановленного
+// с продолжением continuation
+func newSnapshot(filename, root string, sources []SourceFile, options runOptions, continuation *Continuation) (*Snapshot, error) {
+	program, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return nil, err
+	}
+	var units []string
+	for _, dir := range filepath.SplitList(options.units) {
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+		units = append(units, dir)
+	}
+	return &Snapshot{
+		Program: program,
+		Sources: sources,
+		Options: SnapshotOptions{
+			Leaks:     options.leaks,
+			BigInt:    options.bigint,
+			Dialect:   options.dialect,
+			Root:      root,
+			Units:     strings.Join(units, string(os.PathListSeparator)),
+			Input:     options.input,
+			InputData: options.inputData,
+			Defines:   options.defines,
+			Evals:     options.evals,
+		},
+		Continuation: continuation,
+	}, nil
+}
+
+// resumeWithExitCode выполняет команду pascal resume и возвращает код выхода
+func resumeWithExitCode(args []string) int {
+	var steps int64
+	var output string
+	var show bool
+	flags := flag.NewFlagSet("pascal resume", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.Int64Var(&steps, "steps", 0, "остановиться снова после указанного числа операторов (0 - без ограничения)")
+	flags.StringVar(&output, "snapshot", "", "файл для нового снимка; по умолчанию снимок заменяется")
+	flags.BoolVar(&show, "show", false, "вывести состояние из снимка вместо продолжения выполнения")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: pascal resume [-steps N] [-snapshot файл] [-show] <снимок>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() < 1 {
+		fmt.Println("Использование: pascal resume [-steps N] [-snapshot файл] [-show] <снимок>")
+		return 1
+	}
+
+	snapshot, err := readSnapshot(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if show {
+		fmt.Printf("программа: %s\n%s\n", snapshot.Program, snapshot.Continuation.State)
+		return 0
+	}
+	if output == "" {
+		output = flags.Arg(0)
+	}
+	options := runOptions{
+		leaks:     snapshot.Options.Leaks,
+		bigint:    snapshot.Options.BigInt,
+		dialect:   snapshot.Options.Dialect,
+		root:      snapshot.Options.Root,
+		units:     snapshot.Options.Units,
+		input:     snapshot.Options.Input,
+		inputData: snapshot.Options.InputData,
+		defines:   snapshot.Options.Defines,
+		evals:     snapshot.Options.Evals,
+		steps:     steps,
+		snapshot:  output,
+		resume:    snapshot,
+	}
+	return exitCode(run(snapshot.Program, options))
+}
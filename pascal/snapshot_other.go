@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// notifySuspend ничего не делает: в этой ОС нет сигнала SIGUSR1, и выполнение
+// останавливается только по бюджету -steps
+func notifySuspend(interpreter *Interpreter) func() {
+	return func() {}
+}
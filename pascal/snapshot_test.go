@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// suspendedState выполняет программу с бюджетом steps операторов и возвращает состояние
+// в точке остановки
+func suspendedState(t *testing.T, code string, options Options) *State {
+	t.Helper()
+	_, err := runWithOptions(t, code, options)
+	var suspended *SuspendedError
+	if !errors.As(err, &suspended) {
+		t.Fatalf("ожидалась остановка выполнения, получено %v", err)
+	}
+	return suspended.State
+}
+
+// fibProgram - программа с рекурсией, выводом, файлами и динамической памятью
+const fibProgram = `TYPE PInt = ^INTEGER;
+VAR g: TEXT; p: PInt;
+FUNCTION Fib(n: INTEGER): INTEGER;
+BEGIN
+  IF n < 2 THEN Fib := n ELSE Fib := Fib(n - 1) + Fib(n - 2)
+END;
+BEGIN
+  New(p);
+  Assign(g, 'out.txt'); Rewrite(g);
+  FOR i := 1 TO 6 DO
+  BEGIN
+    p^ := Fib(i);
+    WriteLn(g, p^);
+    WriteLn('fib ', i, ' = ', p^)
+  END;
+  Close(g);
+  Dispose(p)
+END.`
+
+// TestSuspendSteps тестирует остановку по бюджету операторов и состояние в точке остановки
+func TestSuspendSteps(t *testing.T) {
+	var output bytes.Buffer
+	state := suspendedState(t, fibProgram, Options{Stdout: &output, Steps: 26})
+	expected := `выполнено операторов: 26, следующий оператор: строка 5, столбец 3
+Fib (вызов: строка 5, столбец 38): {Fib: 0, n: 1}
+Fib (вызов: строка 5, столбец 38): {Fib: 0, n: 2}
+Fib (вызов: строка 12, столбец 11): {Fib: 0, n: 3}
+основная программа: {g: TEXT('out.txt'), i: 3, p: @1}
+куча ^INTEGER(1): 1 (выделен: строка 8, столбец 3)
+файл 'out.txt': запись, позиция 4
+стандартный ввод: позиция 0, стандартный вывод: 20 байт`
+	if state.String() != expected {
+		t.Errorf("ожидалось состояние\n%s\nполучено\n%s", expected, state)
+	}
+	if output.String() != "fib 1 = 1\nfib 2 = 1\n" {
+		t.Errorf("неожиданный вывод до остановки %q", output.String())
+	}
+
+	// Без бюджета программа выполняется до конца
+	if _, err := runWithOptions(t, fibProgram, Options{Stdout: &output}); err != nil {
+		t.Errorf("Ошибка выполнения: %v", err)
+	}
+}
+
+// TestSuspendUnwinding тестирует, что остановка не перехватывается EXCEPT и не выполняет
+// FINALLY и обработчики ошибок наблюдателя
+func TestSuspendUnwinding(t *testing.T) {
+	var output bytes.Buffer
+	state := suspendedState(t, `BEGIN
+  TRY
+    TRY
+      FOR i := 1 TO 10 DO x := i
+    FINALLY
+      WriteLn('finally')
+    END
+  EXCEPT
+    WriteLn('except')
+  END
+END.`, Options{Stdout: &output, Steps: 6})
+	if output.Len() != 0 {
+		t.Errorf("после остановки выполнены операторы: %q", output.String())
+	}
+	if line := state.lines()[1]; line != "основная программа: {i: 4, x: 3}" {
+		t.Errorf("неожиданные переменные: %s", line)
+	}
+
+	// Остановка без бюджета по вызову Suspend происходит перед следующим оператором
+	program, err := checkCode(t, `BEGIN x := 1; y := 2 END.`)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	interpreter := NewInterpreterWithOptions(Options{})
+	interpreter.Suspend()
+	var suspended *SuspendedError
+	if err = interpreter.Interpret(program); !errors.As(err, &suspended) || suspended.State.Steps != 0 {
+		t.Fatalf("ожидалась остановка перед первым оператором, получено %v", err)
+	}
+	if err.Error() != "выполнение остановлено: выполнено операторов: 0, следующий оператор: строка 1, столбец 7" {
+		t.Errorf("неожиданное сообщение %q", err)
+	}
+}
+
+// TestSuspendEmptyLoops тестирует бюджет операторов и Suspend в циклах с пустым телом:
+// каждая итерация отсчитывается как оператор. Точка добавляется к программам отдельно,
+// чтобы бесконечные циклы не попали в корпус трансляторов.
+func TestSuspendEmptyLoops(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"BEGIN REPEAT UNTIL FALSE END", "выполнено операторов: 5, следующий оператор: строка 1, столбец 7"},
+		{"BEGIN WHILE TRUE DO; END", "выполнено операторов: 5, следующий оператор: строка 1, столбец 7"},
+		{"BEGIN x := 1; REPEAT IF x > 5 THEN ELSE UNTIL x = 0 END", "выполнено операторов: 5, следующий оператор: строка 1, столбец 22"},
+		{"BEGIN FOR i := 1 TO MAXINT DO BEGIN END END", "выполнено операторов: 5, следующий оператор: строка 1, столбец 7"},
+	}
+	for _, tt := range tests {
+		state := suspendedState(t, tt.code+".", Options{Steps: 5})
+		if line := state.lines()[0]; line != tt.expected {
+			t.Errorf("%s: ожидалось %q, получено %q", tt.code, tt.expected, line)
+		}
+
+		program, err := checkCode(t, tt.code+".")
+		if err != nil {
+			t.Fatalf("Ошибка семантического анализа: %v", err)
+		}
+		interpreter := NewInterpreterWithOptions(Options{})
+		done := make(chan error, 1)
+		go func() { done <- interpreter.Interpret(program) }()
+		time.Sleep(10 * time.Millisecond)
+		interpreter.Suspend()
+		select {
+		case err := <-done:
+			var suspended *SuspendedError
+			if !errors.As(err, &suspended) {
+				t.Errorf("%s: ожидалась остановка, получено %v", tt.code, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: Suspend не остановил выполнение", tt.code)
+		}
+	}
+}
+
+// suspend выполняет программу до остановки после steps операторов и возвращает
+// продолжение, прошедшее через запись в формате gob, как в снимке
+func suspend(t *testing.T, program *Program, options Options) *Continuation {
+	t.Helper()
+	err := NewInterpreterWithOptions(options).Interpret(program)
+	var suspended *SuspendedError
+	if !errors.As(err, &suspended) || suspended.Continuation == nil {
+		t.Fatalf("ожидалась остановка с продолжением, получено %v", err)
+	}
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(suspended.Continuation); err != nil {
+		t.Fatalf("ошибка записи продолжения: %v", err)
+	}
+	var c Continuation
+	if err := gob.NewDecoder(&data).Decode(&c); err != nil {
+		t.Fatalf("ошибка чтения продолжения: %v", err)
+	}
+	return &c
+}
+
+// statementCounter считает выполненные операторы
+type statementCounter struct {
+	BaseObserver
+	n int64
+}
+
+func (c *statementCounter) OnStatement(Statement) { c.n++ }
+
+// TestResume тестирует продолжение выполнения со снимка: программа продолжается
+// с точки остановки, не выполняя заново ни одного оператора
+func TestResume(t *testing.T) {
+	var full bytes.Buffer
+	complete, err := runWithOptions(t, fibProgram, Options{Stdout: &full})
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	total := complete.steps
+
+	for _, steps := range []int64{1, 20, 26, 57} {
+		files := NewOverlayFS(nil)
+		var before, after bytes.Buffer
+		program, _ := checkCode(t, fibProgram)
+		c := suspend(t, program, Options{Stdout: &before, Files: files, Steps: steps})
+		counter := &statementCounter{}
+		program, _ = checkCode(t, fibProgram)
+		resumed := NewInterpreterWithOptions(Options{Stdout: &after, Files: files, Observer: counter, Resume: c})
+		if err := resumed.Interpret(program); err != nil {
+			t.Errorf("%d: Ошибка выполнения: %v", steps, err)
+			continue
+		}
+		if before.String()+after.String() != full.String() || formatVariables(resumed.Values()) != formatVariables(complete.Values()) {
+			t.Errorf("%d: продолжение не совпадает с выполнением без остановки:\n%s", steps, after.String())
+		}
+		if counter.n != total-steps || resumed.steps != total {
+			t.Errorf("%d: после продолжения выполнено операторов: %d, всего %d, ожидалось %d из %d", steps, counter.n, resumed.steps, total-steps, total)
+		}
+		if data, _ := fs.ReadFile(files, "out.txt"); string(data) != "1\n1\n2\n3\n5\n8\n" {
+			t.Errorf("%d: неожиданный файл %q", steps, data)
+		}
+	}
+
+	// Бюджет продолжения отсчитывается вместе с операторами до остановки
+	program, _ := checkCode(t, fibProgram)
+	c := suspend(t, program, Options{Stdout: &bytes.Buffer{}, Steps: 20})
+	program, _ = checkCode(t, fibProgram)
+	c = suspend(t, program, Options{Stdout: &bytes.Buffer{}, Steps: 30, Resume: c})
+	if c.State.Steps != 30 {
+		t.Errorf("ожидалась остановка после 30 операторов, получено %d", c.State.Steps)
+	}
+}
+
+// TestResumeStdin тестирует продолжение с тем же стандартным вводом, который больше буфера
+// чтения: прочитанное до остановки пропускается ровно до позиции снимка
+func TestResumeStdin(t *testing.T) {
+	const code = `BEGIN c := 0; WHILE NOT Eof DO BEGIN ReadLn(n); c := c + 1 END END.`
+	var input bytes.Buffer
+	for n := 1; n <= 20000; n++ {
+		fmt.Fprintln(&input, n)
+	}
+	program, _ := checkCode(t, code)
+	c := suspend(t, program, Options{Stdin: bytes.NewReader(input.Bytes()), Steps: 100})
+	if c.State.Stdin == 0 || c.State.Stdin >= 4096 {
+		t.Fatalf("неожиданная позиция ввода %d", c.State.Stdin)
+	}
+	resumed, err := runWithOptions(t, code, Options{Stdin: bytes.NewReader(input.Bytes()), Resume: c})
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if result := formatVariables(resumed.Values()); result != "{c: 20000, n: 20000}" {
+		t.Errorf("неожиданный результат продолжения %s", result)
+	}
+
+	// Ввод короче прочитанного до остановки - ошибка, а не молчаливое завершение
+	_, err = runWithOptions(t, code, Options{Stdin: strings.NewReader("1\n"), Resume: c})
+	expected := fmt.Sprintf("стандартный ввод короче, чем прочитано до остановки: 2 байт вместо %d", c.State.Stdin)
+	if err == nil || err.Error() != expected {
+		t.Errorf("ожидалась ошибка %q, получено %v", expected, err)
+	}
+}
+
+// TestResumeMismatch тестирует продолжение, которое не соответствует программе или
+// состояние которого изменено
+func TestResumeMismatch(t *testing.T) {
+	const code = `BEGIN FOR i := 1 TO 5 DO s := s + i END.`
+	program, _ := checkCode(t, code)
+	c := suspend(t, program, Options{Steps: 3})
+	c.Globals.Variables[1].Value.Int = 10
+	_, err := runWithOptions(t, code, Options{Resume: c})
+	expected := `состояние после восстановления не совпадает со снимком: ожидалось "основная программа: {i: 3, s: 3}", получено "основная программа: {i: 3, s: 10}"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("ожидалась ошибка %q, получено %v", expected, err)
+	}
+
+	var output bytes.Buffer
+	c = suspend(t, program, Options{Steps: 3})
+	_, err = runWithOptions(t, `BEGIN WriteLn(1); WriteLn(2) END.`, Options{Stdout: &output, Resume: c})
+	if err == nil || !strings.HasPrefix(err.Error(), "снимок не соответствует программе: ") || output.Len() > 0 {
+		t.Errorf("ожидалась ошибка несоответствия программе, получено %v, вывод %q", err, output.String())
+	}
+}
+
+// TestResumeCorpus тестирует продолжение программ примеров и тестов пакета: выполнение,
+// остановленное в нескольких точках и продолженное со снимка, выводит то же, приходит
+// к тем же значениям переменных и той же ошибке, что и выполнение без остановки
+func TestResumeCorpus(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for n, code := range goCorpus(t) {
+		file := filepath.Join(dir, fmt.Sprintf("p%d.pas", n))
+		if err := os.WriteFile(file, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	examples, _ := filepath.Glob(filepath.Join("examples", "*.pas"))
+	units := filepath.Join("examples", "units")
+
+	// execute выполняет программу и возвращает вывод, значения переменных, ошибку и интерпретатор
+	execute := func(program *Program, options Options) (string, string, *Interpreter) {
+		var output bytes.Buffer
+		options.Stdout = &output
+		interpreter := NewInterpreterWithOptions(options)
+		err := interpreter.Interpret(program)
+		result := fmt.Sprintf("%s\nкод %d", formatVariables(interpreter.Values()), interpreter.ExitCode())
+		if err != nil {
+			result = "ошибка: " + describeError(err)
+		}
+		return output.String(), result, interpreter
+	}
+	tested := 0
+	for _, file := range append(files, examples...) {
+		program, err := load(file, units, NewInfo())
+		if err != nil {
+			// Программы тестов ошибок анализа
+			continue
+		}
+		input, _ := os.ReadFile(strings.TrimSuffix(file, ".pas") + ".in")
+		base := os.DirFS(filepath.Dir(file))
+		output, result, complete := execute(program, Options{Stdin: bytes.NewReader(input), Files: NewOverlayFS(base)})
+		total := complete.steps
+		for _, steps := range []int64{1, total / 3, total / 2, total - 1} {
+			if steps < 1 || steps >= total {
+				continue
+			}
+			// Продолжение получает тот же ввод и файлы в том виде, в каком их оставило
+			// остановленное выполнение
+			files := NewOverlayFS(base)
+			var before bytes.Buffer
+			c := suspend(t, program, Options{Stdin: bytes.NewReader(input), Stdout: &before, Files: files, Steps: steps})
+			resumedProgram, err := load(file, units, NewInfo())
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, resumedResult, resumed := execute(resumedProgram, Options{Stdin: bytes.NewReader(input), Files: files, Resume: c})
+			if before.String()+after != output || resumedResult != result || resumed.steps != total {
+				code, _ := os.ReadFile(file)
+				t.Errorf("%q, остановка после %d операторов:\nбез остановки: %q, %q, операторов %d\nс продолжением: %q, %q, операторов %d",
+					code, steps, output, result, total, before.String()+after, resumedResult, resumed.steps)
+			}
+		}
+		tested++
+	}
+	if tested < len(examples) {
+		t.Fatalf("проверено программ: %d", tested)
+	}
+}
+
+// TestMainSnapshot тестирует флаги -steps и -snapshot и команду pascal resume
+func TestMainSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "sim.pas")
+	if err := os.WriteFile(program, []byte(`VAR f: TEXT; total: INTEGER;
+BEGIN
+  Assign(f, 'data.txt'); Reset(f);
+  WHILE NOT Eof(f) DO
+  BEGIN
+    ReadLn(f, n);
+    total := total + n;
+    WriteLn('total = ', total)
+  END
+END.`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.txt"), []byte("1\n2\n3\n4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(dir, "sim.bin")
+	next := filepath.Join(dir, "next.bin")
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"-root", dir, "-steps", "7", program}, 2, "total = 1\n", "выполнение остановлено: выполнено операторов: 7, следующий оператор: строка 6, столбец 5\n"},
+		{[]string{"-root", dir, "-steps", "7", "-D", "total=100", "-snapshot", snapshot, program}, 2, "total = 101\n",
+			"выполнение остановлено: выполнено операторов: 7, следующий оператор: строка 6, столбец 5; снимок записан в " + snapshot + "\n"},
+		{[]string{"resume", "-show", snapshot}, 0, "программа: " + program + `
+выполнено операторов: 7, следующий оператор: строка 6, столбец 5
+основная программа: {f: TEXT('data.txt'), n: 1, total: 101}
+файл 'data.txt': чтение, позиция 2
+стандартный ввод: позиция 0, стандартный вывод: 12 байт
+`, ""},
+		// Продолжение выводит только новое и может снова остановиться в другой снимок
+		{[]string{"resume", "-steps", "4", "-snapshot", next, snapshot}, 2, "total = 103\n",
+			"выполнение остановлено: выполнено операторов: 11, следующий оператор: строка 6, столбец 5; снимок записан в " + next + "\n"},
+		{[]string{"resume", next}, 0, "total = 106\ntotal = 110\n{f: TEXT('data.txt'), n: 4, total: 110}\n", ""},
+		{[]string{"resume", snapshot}, 0, "total = 103\ntotal = 106\ntotal = 110\n{f: TEXT('data.txt'), n: 4, total: 110}\n", ""},
+		{[]string{"resume", program}, 1, "", program + ": файл не является снимком выполнения\n"},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+
+	// Снимок нельзя продолжить после изменения программы
+	if err := os.WriteFile(program, []byte("BEGIN END."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resume, err := readSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run(program, runOptions{snapshot: snapshot, resume: resume}); err == nil || err.Error() != program+": исходный текст изменился после снимка" {
+		t.Errorf("ожидалась ошибка изменения программы, получено %v", err)
+	}
+}
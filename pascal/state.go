@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// State - состояние выполнения программы перед очередным оператором: переменные, стек
+// вызовов, динамическая память, открытые файлы и позиции стандартного ввода и вывода.
+// Значения записаны текстом, как их выводит программа, поэтому состояние можно сравнивать,
+// сохранять в снимок и показывать человеку.
+type State struct {
+	Steps   int64        // число выполненных операторов
+	Pos     Position     // позиция оператора, перед которым остановлено выполнение
+	Stack   []FrameState // активации подпрограмм, начиная с последней вызванной
+	Globals FrameState   // глобальные переменные программы
+	Units   []FrameState // глобальные переменные модулей в порядке инициализации
+	Heap    []BlockState // неосвобожденные динамические переменные
+	Files   []FileState  // открытые файлы в порядке открытия
+	Stdin   int64        // число байтов, прочитанных из стандартного ввода
+	Stdout  int64        // число байтов, выведенных в стандартный вывод
+}
+
+// FrameState - переменные подпрограммы, модуля или программы
+type FrameState struct {
+	Name      string
+	Pos       Position // позиция вызова подпрограммы
+	Variables []VariableState
+}
+
+// VariableState - переменная; Type пуст для неописанной переменной
+type VariableState struct {
+	Name, Type, Value string
+}
+
+// BlockState - динамическая переменная, созданная New
+type BlockState struct {
+	Addr        int
+	Type, Value string
+	Allocated   Position
+}
+
+// FileState - открытый файл и позиция в нем: для чтения - число прочитанных байтов,
+// для записи - число записанных
+type FileState struct {
+	Name    string
+	Writing bool
+	Offset  int64
+}
+
+// lines возвращает состояние построчно в виде, удобном для чтения и сравнения
+func (s *State) lines() []string {
+	lines := []string{fmt.Sprintf("выполнено операторов: %d, следующий оператор: %s", s.Steps, s.Pos)}
+	for _, frame := range s.Stack {
+		lines = append(lines, fmt.Sprintf("%s (вызов: %s): %s", frame.Name, frame.Pos, formatVariableStates(frame.Variables)))
+	}
+	lines = append(lines, fmt.Sprintf("%s: %s", s.Globals.Name, formatVariableStates(s.Globals.Variables)))
+	for _, unit := range s.Units {
+		lines = append(lines, fmt.Sprintf("модуль %s: %s", unit.Name, formatVariableStates(unit.Variables)))
+	}
+	for _, block := range s.Heap {
+		lines = append(lines, fmt.Sprintf("куча ^%s(%d): %s (выделен: %s)", block.Type, block.Addr, block.Value, block.Allocated))
+	}
+	for _, file := range s.Files {
+		mode := "чтение"
+		if file.Writing {
+			mode = "запись"
+		}
+		lines = append(lines, fmt.Sprintf("файл %s: %s, позиция %d", StringValue(file.Name), mode, file.Offset))
+	}
+	return append(lines, fmt.Sprintf("стандартный ввод: позиция %d, стандартный вывод: %d байт", s.Stdin, s.Stdout))
+}
+
+func (s *State) String() string {
+	return strings.Join(s.lines(), "\n")
+}
+
+// diff возвращает первое различие состояний в виде "ожидалось ..., получено ..."; пустая
+// строка - состояния совпадают
+func (s *State) diff(other *State) string {
+	expected, actual := s.lines(), other.lines()
+	for n := 0; n < max(len(expected), len(actual)); n++ {
+		var want, got string
+		if n < len(expected) {
+			want = expected[n]
+		}
+		if n < len(actual) {
+			got = actual[n]
+		}
+		if want != got {
+			return fmt.Sprintf("ожидалось %q, получено %q", want, got)
+		}
+	}
+	return ""
+}
+
+// formatVariableStates форматирует переменные, как formatVariables
+func formatVariableStates(variables []VariableState) string {
+	parts := make([]string, len(variables))
+	for n, variable := range variables {
+		parts[n] = fmt.Sprintf("%s: %s", variable.Name, variable.Value)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// SuspendedError сообщает, что выполнение остановлено перед оператором по бюджету
+// Options.Steps или вызовом Suspend. Это не исключение программы: его не перехватывает
+// EXCEPT, а блоки FINALLY и закрытие файлов пропускаются, чтобы State осталось таким,
+// каким оно было в точке остановки. Continuation продолжает выполнение с этой точки
+// (Options.Resume); nil, если выполнение нельзя продолжить, например если оператор
+// не входит в программу, которую выполняет Interpret.
+type SuspendedError struct {
+	State        *State
+	Continuation *Continuation
+}
+
+func (e *SuspendedError) Error() string {
+	return fmt.Sprintf("выполнение остановлено: выполнено операторов: %d, следующий оператор: %s", e.State.Steps, e.State.Pos)
+}
+
+// Suspend останавливает выполнение перед следующим оператором: Interpret вернет
+// *SuspendedError. Метод можно вызывать из другой горутины, например при получении сигнала.
+func (i *Interpreter) Suspend() {
+	i.interrupted.Store(true)
+}
+
+// nextCheckpoint возвращает номер оператора, перед которым нужно остановиться
+func (i *Interpreter) nextCheckpoint() int64 {
+	if i.budget > 0 {
+		return i.budget + 1
+	}
+	return 0
+}
+
+// step отсчитывает оператор stmt перед его выполнением и останавливает выполнение,
+// если достигнута точка остановки
+func (i *Interpreter) step(stmt Statement) error {
+	if i.halt != nil {
+		return i.halt
+	}
+	i.steps++
+	if i.restored != nil {
+		// Первый оператор после продолжения со снимка: восстановленное состояние должно
+		// совпасть с записанным в снимок
+		restored := i.restored
+		i.restored = nil
+		if diff := restored.diff(i.state(stmt)); diff != "" {
+			i.halt = fmt.Errorf("состояние после восстановления не совпадает со снимком: %s", diff)
+			return i.halt
+		}
+	}
+	if i.steps != i.checkpoint && !i.interrupted.Load() {
+		return nil
+	}
+	i.interrupted.Store(false)
+	state := i.state(stmt)
+	i.halt = &SuspendedError{State: state, Continuation: i.continuation(state)}
+	return i.halt
+}
+
+// state возвращает состояние выполнения перед оператором stmt
+func (i *Interpreter) state(stmt Statement) *State {
+	state := &State{
+		Steps:   i.steps - 1,
+		Pos:     i.pausePos(stmt),
+		Globals: FrameState{Name: i.frameName(nil), Variables: variableStates(i.variables)},
+		Stdin:   i.input.offset(),
+		Stdout:  i.output.n,
+	}
+	for frame := i.frame; frame != nil; frame = frame.Caller {
+		state.Stack = append(state.Stack, FrameState{Name: frame.Routine.Decl.Name, Pos: frame.Pos, Variables: variableStates(frame.variables)})
+	}
+	for _, unit := range i.unitGlobals {
+		state.Units = append(state.Units, FrameState{Name: unit.Name, Variables: variableStates(unit.variables)})
+	}
+	for _, block := range i.heap.Leaks() {
+		state.Heap = append(state.Heap, BlockState{Addr: block.Addr, Type: block.Type.String(), Value: block.Value.String(), Allocated: block.Allocated})
+	}
+	for _, file := range i.open {
+		if file.mode == fileWriting {
+			state.Files = append(state.Files, FileState{Name: file.Name, Writing: true, Offset: int64(file.writer.Len())})
+		} else {
+			state.Files = append(state.Files, FileState{Name: file.Name, Offset: file.reader.offset()})
+		}
+	}
+	return state
+}
+
+// pausePos возвращает позицию оператора, перед которым остановлено выполнение. У блока
+// BEGIN ... END нет своей позиции, поэтому берется позиция его первого оператора, а у пустого
+// блока, например тела REPEAT UNTIL FALSE, - позиция ближайшего объемлющего оператора.
+func (i *Interpreter) pausePos(stmt Statement) Position {
+	for {
+		block, ok := stmt.(*Block)
+		if !ok {
+			return statementPos(stmt)
+		}
+		if len(block.Statements) > 0 {
+			stmt = block.Statements[0]
+			continue
+		}
+		for n := len(i.active) - 1; n >= 0; n-- {
+			if _, ok := i.active[n].(*Block); !ok {
+				return statementPos(i.active[n])
+			}
+		}
+		return Position{}
+	}
+}
+
+// variableStates возвращает переменные области видимости, кроме констант, в порядке имен
+func variableStates(variables map[string]*Variable) []VariableState {
+	var states []VariableState
+	for _, variable := range variables {
+		if variable.Const {
+			continue
+		}
+		state := VariableState{Name: variable.Name}
+		if variable.Type != nil {
+			state.Type = variable.Type.String()
+		}
+		if value := *variable.slot(); value != nil {
+			state.Value = value.String()
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(a, b int) bool { return strings.ToLower(states[a].Name) < strings.ToLower(states[b].Name) })
+	return states
+}
+
+// countingWriter считает байты, записанные в w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReader считает байты, прочитанные из r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
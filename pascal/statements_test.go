@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestControlStatements тестирует операторы IF, WHILE, REPEAT и FOR
+func TestControlStatements(t *testing.T) {
+	tests := []struct {
+		code string
+		want map[string]string
+	}{
+		{"BEGIN x := 5; IF x > 3 THEN y := 1 ELSE y := 2; IF x < 3 THEN z := 1 END.",
+			map[string]string{"y": "1", "z": "0"}},
+		// ELSE относится к ближайшему IF
+		{"BEGIN x := 5; y := 0; IF x > 3 THEN IF x > 10 THEN y := 1 ELSE y := 2 END.",
+			map[string]string{"y": "2"}},
+		{"BEGIN n := 0; s := 0; WHILE n < 10 DO BEGIN n := n + 1; s := s + n END END.",
+			map[string]string{"n": "10", "s": "55"}},
+		{"BEGIN n := 10; WHILE n < 5 DO n := n + 1 END.",
+			map[string]string{"n": "10"}},
+		{"BEGIN n := 10; REPEAT n := n + 1; m := n UNTIL n >= 5 END.",
+			map[string]string{"n": "11", "m": "11"}},
+		{"BEGIN f := 1; FOR i := 1 TO 5 DO f := f * i END.",
+			map[string]string{"f": "120", "i": "5"}},
+		{"BEGIN s := 0; FOR i := 5 DOWNTO 1 DO s := s * 10 + i END.",
+			map[string]string{"s": "54321", "i": "1"}},
+		// Пустой диапазон: тело не выполняется, переменная цикла не изменяется
+		{"BEGIN i := 7; s := 0; FOR i := 5 TO 1 DO s := 1 END.",
+			map[string]string{"s": "0", "i": "7"}},
+		// Границы вычисляются один раз
+		{"BEGIN n := 3; c := 0; FOR i := 1 TO n DO BEGIN n := n + 1; c := c + 1 END END.",
+			map[string]string{"c": "3", "n": "6"}},
+		{"TYPE T = (A, B, C, D); VAR v: T; s: SET OF T; BEGIN FOR v := B TO D DO s := s + [v] END.",
+			map[string]string{"s": "[B..D]", "v": "D"}},
+		{"VAR i: INTEGER; BEGIN c := 0; FOR i := MAXINT - 2 TO MAXINT DO c := c + 1 END.",
+			map[string]string{"c": "3", "i": "9223372036854775807"}},
+		// Пустой оператор в ветвях IF и теле цикла
+		{"BEGIN i := 3; WHILE i > 5 DO; FOR k := 1 TO 3 DO ; IF i > 5 THEN ELSE i := 0; IF i < 5 THEN; n := i + 1 END.",
+			map[string]string{"i": "0", "k": "3", "n": "1"}},
+	}
+	for _, tt := range tests {
+		for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+			interpreter, err := run(t, tt.code)
+			if err != nil {
+				t.Fatalf("%q: неожиданная ошибка: %v", tt.code, err)
+			}
+			values := interpreter.Values()
+			for name, want := range tt.want {
+				got := "0"
+				if value, ok := values[name]; ok {
+					got = value.String()
+				}
+				if got != want {
+					t.Errorf("%q: ожидалось %s = %s, получено %s", tt.code, name, want, got)
+				}
+			}
+		}
+	}
+}
+
+// TestControlStatementErrors тестирует ошибки типов условий и границ циклов
+func TestControlStatementErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN IF 1 THEN x := 1 END.", "условие оператора IF должно быть логического типа, получено INTEGER"},
+		{"BEGIN WHILE 1.5 DO x := 1 END.", "условие оператора WHILE должно быть логического типа, получено REAL"},
+		{"BEGIN REPEAT x := 1 UNTIL x END.", "условие оператора UNTIL должно быть логического типа, получено INTEGER"},
+		{"BEGIN FOR i := 1.5 TO 2 DO x := 1 END.", "границы цикла FOR должны быть порядкового типа, получено REAL"},
+		{"VAR i: 1..3; BEGIN FOR i := 1 TO 5 DO x := i END.", "вне диапазона 1..3"},
+	}
+	for _, tt := range tests {
+		_, err := interpretCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка выполнения %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// count - число ошибок: каждая неверная граница цикла FOR дает свою ошибку, а
+	// неверная переменная цикла - одну
+	checks := []struct {
+		code  string
+		want  string
+		count int
+	}{
+		{"BEGIN IF 1 THEN x := 1 END.", "условие оператора IF должно быть логического типа, получено INTEGER", 1},
+		{"BEGIN WHILE 1 + 2 DO x := 1 END.", "условие оператора WHILE должно быть логического типа", 1},
+		{"VAR n: INTEGER; BEGIN REPEAT n := 1 UNTIL n END.", "условие оператора UNTIL должно быть логического типа", 1},
+		{"VAR r: REAL; BEGIN FOR r := 1 TO 2 DO x := 1 END.", "переменная цикла FOR r должна быть порядкового типа, получено REAL", 1},
+		{"BEGIN FOR i := 1.5 TO 2 DO x := 1 END.", "границы цикла FOR должны быть порядкового типа, получено REAL", 1},
+		{"TYPE T = (A, B); VAR i: INTEGER; BEGIN FOR i := A TO B DO x := 1 END.", "нельзя присвоить T переменной i", 2},
+		{"VAR i: 1..3; BEGIN FOR i := 1 TO 5 DO x := 1 END.", "значение 5 вне диапазона 1..3 переменной i", 1},
+		{"CONST c = 1; BEGIN FOR c := 1 TO 2 DO x := 1 END.", "присваивание константе c", 1},
+		{"TYPE T = INTEGER; BEGIN FOR T := 1 TO 2 DO x := 1 END.", "T - имя типа, а не переменной", 1},
+	}
+	for _, tt := range checks {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		} else if count := strings.Count(err.Error(), "\n") + 1; count != tt.count {
+			t.Errorf("%q: ожидалось ошибок: %d, получено %d:\n%v", tt.code, tt.count, count, err)
+		}
+	}
+}
+
+// TestParseControlStatements тестирует разбор и строковое представление операторов управления
+func TestParseControlStatements(t *testing.T) {
+	program := parseCode(t, `BEGIN
+  IF a THEN b := 1 ELSE b := 2;
+  WHILE a DO a := FALSE;
+  REPEAT a := TRUE; b := 3 UNTIL a;
+  FOR i := 1 TO 3 DO b := i;
+  FOR i := 3 DOWNTO 1 DO b := i
+END.`)
+	want := []string{
+		"If(Identifier(a), else: true)",
+		"While(Identifier(a))",
+		"Repeat(2 statements, until Identifier(a))",
+		"For(i := Number(1) to Number(3))",
+		"For(i := Number(3) downto Number(1))",
+	}
+	if len(program.Statements) != len(want) {
+		t.Fatalf("Ожидалось %d операторов, получено %d", len(want), len(program.Statements))
+	}
+	for n, stmt := range program.Statements {
+		if stmt.String() != want[n] {
+			t.Errorf("Оператор %d: ожидалось %q, получено %q", n, want[n], stmt.String())
+		}
+	}
+
+	for _, code := range []string{
+		"BEGIN IF a b := 1 END.",
+		"BEGIN WHILE a b := 1 END.",
+		"BEGIN REPEAT b := 1 END.",
+		"BEGIN FOR 1 := 1 TO 2 DO b := 1 END.",
+		"BEGIN FOR i := 1 UPTO 2 DO b := 1 END.",
+		"BEGIN FOR i = 1 TO 2 DO b := 1 END.",
+	} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", code, err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestJumpStatements тестирует Break, Continue и Exit, в том числе во вложенных блоках,
+// обработчиках исключений и блоках FINALLY
+func TestJumpStatements(t *testing.T) {
+	tests := []struct {
+		code string
+		want map[string]string
+	}{
+		{"BEGIN s := 0; FOR i := 1 TO 10 DO BEGIN IF i > 4 THEN Break; s := s + i END END.",
+			map[string]string{"s": "10", "i": "5"}},
+		{"BEGIN s := 0; FOR i := 1 TO 6 DO BEGIN IF Odd(i) THEN BEGIN BEGIN Continue END END; s := s + i END END.",
+			map[string]string{"s": "12", "i": "6"}},
+		{"BEGIN n := 0; WHILE TRUE DO BEGIN n := n + 1; IF n = 3 THEN break END END.",
+			map[string]string{"n": "3"}},
+		// Continue в REPEAT переходит к проверке условия
+		{"BEGIN n := 0; c := 0; REPEAT n := n + 1; IF n < 3 THEN Continue; c := c + 1 UNTIL n >= 5 END.",
+			map[string]string{"n": "5", "c": "3"}},
+		// Break завершает только ближайший цикл
+		{"BEGIN c := 0; FOR i := 1 TO 3 DO FOR j := 1 TO 3 DO BEGIN IF j = 2 THEN Break; c := c + 1 END END.",
+			map[string]string{"c": "3"}},
+		{"BEGIN c := 0; FOR i := 1 TO 3 DO CASE i OF 2: Continue ELSE c := c + i END END.",
+			map[string]string{"c": "4"}},
+		// FINALLY выполняется при выходе из цикла
+		{"BEGIN f := 0; FOR i := 1 TO 5 DO TRY IF i = 2 THEN Break FINALLY f := f + 1 END END.",
+			map[string]string{"f": "2", "i": "2"}},
+		{"BEGIN c := 0; FOR i := 1 TO 3 DO BEGIN TRY x := 1 DIV (i - 2) EXCEPT ON EDivByZero DO Continue END; c := c + 1 END END.",
+			map[string]string{"c": "2"}},
+		{"BEGIN x := 1; Exit; x := 2 END.",
+			map[string]string{"x": "1"}},
+		{`FUNCTION Find(n: INTEGER): INTEGER;
+VAR i: INTEGER;
+BEGIN
+  Find := -1;
+  FOR i := 1 TO 100 DO
+    IF i * i >= n THEN Exit(i);
+  Find := 0
+END;
+PROCEDURE Count(VAR c: INTEGER);
+BEGIN
+  WHILE TRUE DO BEGIN c := c + 1; IF c = 4 THEN Exit END
+END;
+BEGIN
+  a := Find(50); b := Find(100000); Count(c); d := 1
+END.`, map[string]string{"a": "8", "b": "0", "c": "4", "d": "1"}},
+	}
+	for _, tt := range tests {
+		for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+			interpreter, err := run(t, tt.code)
+			if err != nil {
+				t.Fatalf("%q: неожиданная ошибка: %v", tt.code, err)
+			}
+			values := interpreter.Values()
+			for name, want := range tt.want {
+				got := "0"
+				if value, ok := values[name]; ok {
+					got = value.String()
+				}
+				if got != want {
+					t.Errorf("%q: ожидалось %s = %s, получено %s", tt.code, name, want, got)
+				}
+			}
+		}
+	}
+}
+
+// TestHalt тестирует Halt: программа завершается сразу, из любой подпрограммы, без блоков
+// FINALLY и обработчиков исключений, с кодом выхода из аргумента
+func TestHalt(t *testing.T) {
+	tests := []struct {
+		code   string
+		want   string
+		output string
+		exit   int
+	}{
+		{"BEGIN x := 1; Halt; x := 2 END.", "{x: 1}", "", 0},
+		{"BEGIN x := 1; IF x > 0 THEN Halt(3); x := 2 END.", "{x: 1}", "", 3},
+		{`VAR n, x: INTEGER;
+FUNCTION Stop(code: INTEGER): INTEGER;
+BEGIN
+  TRY
+    Halt(code)
+  FINALLY
+    WriteLn('finally')
+  END;
+  Stop := 1
+END;
+BEGIN
+  TRY
+    FOR n := 1 TO 10 DO
+      IF n = 4 THEN x := Stop(n)
+  EXCEPT
+    WriteLn('except')
+  END;
+  n := 0
+END.`, "{n: 4, x: 0}", "", 4},
+	}
+	for _, tt := range tests {
+		for _, checked := range []bool{false, true} {
+			var output bytes.Buffer
+			interpreter := NewInterpreterWithOptions(Options{Stdout: &output})
+			program := parseCode(t, tt.code)
+			if checked {
+				if err := NewChecker().Check(program); err != nil {
+					t.Fatalf("%q: ошибка семантического анализа: %v", tt.code, err)
+				}
+			}
+			if err := interpreter.Interpret(program); err != nil {
+				t.Fatalf("%q: неожиданная ошибка: %v", tt.code, err)
+			}
+			if got := formatVariables(interpreter.Values()); got != tt.want || output.String() != tt.output || interpreter.ExitCode() != tt.exit {
+				t.Errorf("%q: ожидалось %s, вывод %q, код %d; получено %s, вывод %q, код %d",
+					tt.code, tt.want, tt.output, tt.exit, got, output.String(), interpreter.ExitCode())
+			}
+		}
+	}
+
+	// Код выхода Halt становится кодом выхода pascal без сообщения об ошибке
+	if code := exitCode(exitStatus(5)); code != 5 {
+		t.Errorf("ожидался код выхода 5, получено %d", code)
+	}
+}
+
+// TestJumpStatementErrors тестирует Break, Continue и Exit вне допустимого контекста
+func TestJumpStatementErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN Break END.", "Break вне цикла"},
+		{"BEGIN IF TRUE THEN Continue END.", "Continue вне цикла"},
+		{"PROCEDURE P; BEGIN Break END; BEGIN WHILE TRUE DO P END.", "Break вне цикла"},
+		{"BEGIN Exit(1) END.", "Exit со значением допустим только в функции"},
+		{"TYPE S = 1..5; FUNCTION F: S; BEGIN Exit(9) END; BEGIN x := F END.", "вне диапазона 1..5"},
+		{"BEGIN Halt(TRUE) END.", "Halt: код выхода должен быть целым, получено BOOLEAN"},
+		{"BEGIN Halt(1, 2) END.", "процедура Halt ожидает не более 1 аргумента, получено 2"},
+	}
+	for _, tt := range tests {
+		_, err := interpretCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка выполнения %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	checks := []struct {
+		code string
+		want string
+	}{
+		{"BEGIN Break END.", "Break допустим только в цикле"},
+		{"BEGIN IF TRUE THEN Continue END.", "Continue допустим только в цикле"},
+		{"PROCEDURE P; BEGIN Break END; BEGIN WHILE TRUE DO P END.", "Break допустим только в цикле"},
+		{"BEGIN WHILE TRUE DO TRY x := 1 FINALLY Break END END.", "Break не может покидать блок FINALLY"},
+		{"BEGIN TRY x := 1 FINALLY Exit END END.", "Exit не может покидать блок FINALLY"},
+		{"BEGIN Exit(1) END.", "Exit со значением допустим только в функции"},
+		{"PROCEDURE P; BEGIN Exit(1) END; BEGIN END.", "Exit со значением допустим только в функции"},
+		{"FUNCTION F: INTEGER; BEGIN Exit(TRUE) END; BEGIN END.", "Exit: нельзя вернуть BOOLEAN из функции F типа INTEGER"},
+		{"TYPE S = 1..5; FUNCTION F: S; BEGIN Exit(9) END; BEGIN END.", "Exit: значение 9 вне диапазона 1..5 результата функции F"},
+		{"BEGIN Halt('ab') END.", "Halt: код выхода должен быть целым, получено STRING"},
+		{"BEGIN Halt(1, 2) END.", "процедура Halt ожидает не более 1 аргумента, получено 2"},
+	}
+	for _, tt := range checks {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// Цикл внутри FINALLY может использовать Break
+	if _, err := checkCode(t, "BEGIN TRY x := 1 FINALLY WHILE TRUE DO Break END END."); err != nil {
+		t.Errorf("Неожиданная ошибка: %v", err)
+	}
+}
+
+// TestParseJumpStatements тестирует разбор Break, Continue и Exit, слова которых не зарезервированы
+func TestParseJumpStatements(t *testing.T) {
+	program := parseCode(t, `PROCEDURE Exit(n: INTEGER); BEGIN END;
+BEGIN
+  WHILE TRUE DO BEGIN Break; Continue END;
+  Exit(1);
+  break := 1
+END.`)
+	want := []string{
+		"While(Identifier(TRUE))",
+		"CallStatement(Exit(Number(1)))",
+		"Assignment(break := Number(1))",
+	}
+	for n, stmt := range program.Statements {
+		if stmt.String() != want[n] {
+			t.Errorf("Оператор %d: ожидалось %q, получено %q", n, want[n], stmt.String())
+		}
+	}
+
+	program = parseCode(t, "BEGIN Exit; Exit(); Exit(x + 1); Break; Continue END.")
+	want = []string{"Exit()", "Exit()", "Exit(BinaryOp(Identifier(x) + Number(1)))", "Break", "Continue"}
+	for n, stmt := range program.Statements {
+		if stmt.String() != want[n] {
+			t.Errorf("Оператор %d: ожидалось %q, получено %q", n, want[n], stmt.String())
+		}
+	}
+
+	tokens, err := NewLexer("BEGIN Exit(1 END.").Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewParser(tokens).Parse(); err == nil || !strings.Contains(err.Error(), "ожидалась ')' после значения Exit") {
+		t.Errorf("Ожидалась ошибка о пропущенной ')', получено %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Tracer - наблюдатель, который выводит каждое выполненное присваивание: позицию, переменную,
+// прежнее и новое значение и строку исходного текста, в которой записано присваивание
+type Tracer struct {
+	BaseObserver
+
+	w        io.Writer
+	json     bool                // выводить строки JSON вместо текста
+	filename string              // файл основной программы; пустая строка - исходный текст неизвестен
+	sources  map[string][]string // строки прочитанных файлов; nil для файла, который не удалось прочитать
+}
+
+// NewTracer создает трассировку в w в виде текста или, если json установлен, строк JSON.
+// Строки исходного текста основной программы читаются из файла filename, модулей - из их файлов.
+func NewTracer(w io.Writer, json bool, filename string) *Tracer {
+	return &Tracer{w: w, json: json, filename: filename, sources: make(map[string][]string)}
+}
+
+// traceEvent - запись трассировки в формате JSON; Old равно nil для переменной, созданной присваиванием
+type traceEvent struct {
+	File     string  `json:"file,omitempty"`
+	Line     int     `json:"line"`
+	Column   int     `json:"column"`
+	Variable string  `json:"variable"`
+	Old      *string `json:"old"`
+	New      string  `json:"new"`
+	Source   string  `json:"source,omitempty"`
+}
+
+// OnAssign выводит присваивание
+func (t *Tracer) OnAssign(stmt *Assignment, before, after Value) {
+	variable := stmt.Variable
+	if stmt.Target != nil {
+		variable = designatorName(stmt.Target)
+	}
+	source := t.line(stmt.Pos)
+	if t.json {
+		event := traceEvent{
+			File: stmt.Pos.File, Line: stmt.Pos.Line, Column: stmt.Pos.Column,
+			Variable: variable, New: traceValue(after), Source: source,
+		}
+		if before != nil {
+			old := traceValue(before)
+			event.Old = &old
+		}
+		line, _ := json.Marshal(event)
+		fmt.Fprintf(t.w, "%s\n", line)
+		return
+	}
+	old := "не задано"
+	if before != nil {
+		old = traceValue(before)
+	}
+	fmt.Fprintf(t.w, "%s: %s: %s -> %s", stmt.Pos, variable, old, traceValue(after))
+	if source != "" {
+		fmt.Fprintf(t.w, " | %s", source)
+	}
+	fmt.Fprintln(t.w)
+}
+
+// traceValue форматирует значение так же, как вывод переменных после выполнения программы
+func traceValue(value Value) string {
+	if value == nil {
+		return "не задано"
+	}
+	return value.String()
+}
+
+// line возвращает строку исходного текста в позиции pos без начальных и конечных пробелов;
+// пустую строку, если файл не удалось прочитать
+func (t *Tracer) line(pos Position) string {
+	name := pos.File
+	if name == "" {
+		name = t.filename
+	}
+	if name == "" {
+		return ""
+	}
+	lines, ok := t.sources[name]
+	if !ok {
+		if code, err := os.ReadFile(name); err == nil {
+			lines = strings.Split(strings.ReplaceAll(string(code), "\r\n", "\n"), "\n")
+		}
+		t.sources[name] = lines
+	}
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[pos.Line-1])
+}
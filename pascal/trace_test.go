@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingObserver записывает события выполнения в виде строк
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) OnStatement(stmt Statement) {
+	o.events = append(o.events, fmt.Sprintf("оператор %s", stmt))
+}
+
+func (o *recordingObserver) OnAssign(stmt *Assignment, before, after Value) {
+	o.events = append(o.events, fmt.Sprintf("присваивание %s: %s -> %s", stmt.Variable, traceValue(before), traceValue(after)))
+}
+
+func (o *recordingObserver) OnCall(routine *Routine, pos Position) {
+	o.events = append(o.events, fmt.Sprintf("вызов %s (%s)", routine.Decl.Name, pos))
+}
+
+func (o *recordingObserver) OnError(stmt Statement, err error) {
+	o.events = append(o.events, fmt.Sprintf("ошибка в %v: %v", stmt, err))
+}
+
+// TestObserverEvents тестирует события, которые интерпретатор сообщает наблюдателю
+func TestObserverEvents(t *testing.T) {
+	const code = `PROCEDURE Bump(VAR n: INTEGER);
+BEGIN
+  n := n + 1
+END;
+
+BEGIN
+  k := 1;
+  Bump(k);
+  TRY
+    k := k DIV 0
+  EXCEPT
+    k := 0
+  END
+END.`
+	program, err := checkCode(t, code)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	observer := &recordingObserver{}
+	if err := NewInterpreterWithOptions(Options{Observer: observer}).Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	expected := []string{
+		"оператор Assignment(k := Number(1))",
+		"присваивание k: не задано -> 1",
+		"оператор CallStatement(Bump(Identifier(k)))",
+		"вызов Bump (строка 8, столбец 3)",
+		"оператор Assignment(n := BinaryOp(Identifier(n) + Number(1)))",
+		"присваивание n: 1 -> 2",
+		"оператор Try(1 statements, except: 0 handlers, else: true)",
+		"оператор Assignment(k := BinaryOp(Identifier(k) DIV Number(0)))",
+		"ошибка в Assignment(k := BinaryOp(Identifier(k) DIV Number(0))): деление на ноль",
+		"оператор Block(1 statements)",
+		"оператор Assignment(k := Number(0))",
+		"присваивание k: 2 -> 0",
+	}
+	if strings.Join(observer.events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("ожидались события:\n%s\nполучено:\n%s", strings.Join(expected, "\n"), strings.Join(observer.events, "\n"))
+	}
+}
+
+// TestObserverErrors тестирует сообщения об ошибках, покидающих программу
+func TestObserverErrors(t *testing.T) {
+	tests := []struct {
+		code   string
+		events []string
+	}{
+		{
+			// Ошибка передается через объемлющие операторы и подпрограмму, но сообщается один раз
+			`PROCEDURE P; BEGIN IF TRUE THEN x := 1 DIV 0 END; BEGIN P END.`,
+			[]string{"ошибка в Assignment(x := BinaryOp(Number(1) DIV Number(0))): деление на ноль"},
+		},
+		{
+			// Ошибка в разделе описаний возникает вне операторов
+			`VAR x: INTEGER; x: REAL; BEGIN END.`,
+			[]string{"ошибка в <nil>: повторное описание x"},
+		},
+	}
+	for _, tt := range tests {
+		observer := &recordingObserver{}
+		err := NewInterpreterWithOptions(Options{Observer: observer}).Interpret(parseCode(t, tt.code))
+		if err == nil {
+			t.Errorf("%s: ожидалась ошибка выполнения", tt.code)
+			continue
+		}
+		var errors []string
+		for _, event := range observer.events {
+			if strings.HasPrefix(event, "ошибка") {
+				errors = append(errors, event)
+			}
+		}
+		if strings.Join(errors, "\n") != strings.Join(tt.events, "\n") {
+			t.Errorf("%s: ожидались ошибки %q, получено %q", tt.code, tt.events, errors)
+		}
+	}
+}
+
+// TestBaseObserver тестирует наблюдателя, реализующего только часть событий
+func TestBaseObserver(t *testing.T) {
+	observer := &callObserver{}
+	_, err := runWithOptions(t, `FUNCTION Sq(n: INTEGER): INTEGER; BEGIN Sq := n * n END;
+BEGIN x := Sq(Sq(2)) END.`, Options{Observer: observer})
+	if err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if strings.Join(observer.calls, ", ") != "Sq, Sq" {
+		t.Errorf("ожидались вызовы Sq, Sq, получено %v", observer.calls)
+	}
+}
+
+// callObserver записывает имена вызванных подпрограмм и пропускает остальные события
+type callObserver struct {
+	BaseObserver
+	calls []string
+}
+
+func (o *callObserver) OnCall(routine *Routine, pos Position) {
+	o.calls = append(o.calls, routine.Decl.Name)
+}
+
+// traceProgram - программа, ошибку в которой помогает найти трассировка
+const traceProgram = `TYPE TPoint = RECORD x, y: REAL END;
+VAR p: TPoint; a, y: INTEGER; b: REAL;
+BEGIN
+  a := 2; y := 3;
+  b := 10 + a + 10 * y / 4;
+  p.x := b;
+  c := 'abc'
+END.`
+
+// traceCode выполняет программу из файла с трассировкой и возвращает ее вывод
+func traceCode(t *testing.T, code string, json bool) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "prog.pas")
+	if err := os.WriteFile(filename, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var trace bytes.Buffer
+	if _, err := runWithOptions(t, code, Options{Observer: NewTracer(&trace, json, filename)}); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	return trace.String()
+}
+
+// TestTracerText тестирует текстовую трассировку присваиваний
+func TestTracerText(t *testing.T) {
+	expected := `строка 4, столбец 3: a: 0 -> 2 | a := 2; y := 3;
+строка 4, столбец 11: y: 0 -> 3 | a := 2; y := 3;
+строка 5, столбец 3: b: 0 -> 19.5 | b := 10 + a + 10 * y / 4;
+строка 6, столбец 3: p.x: 0 -> 19.5 | p.x := b;
+строка 7, столбец 3: c: не задано -> 'abc' | c := 'abc'
+`
+	if trace := traceCode(t, traceProgram, false); trace != expected {
+		t.Errorf("ожидалась трассировка:\n%s\nполучено:\n%s", expected, trace)
+	}
+
+	// Без исходного текста строка программы не выводится
+	var trace bytes.Buffer
+	if _, err := runWithOptions(t, "BEGIN x := 1; x := x * 2 END.", Options{Observer: NewTracer(&trace, false, "")}); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	if trace.String() != "строка 1, столбец 7: x: не задано -> 1\nстрока 1, столбец 15: x: 1 -> 2\n" {
+		t.Errorf("неожиданная трассировка без исходного текста: %q", trace.String())
+	}
+}
+
+// TestTracerJSON тестирует трассировку в виде строк JSON
+func TestTracerJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(traceCode(t, traceProgram, true), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("ожидалось 5 строк, получено %d: %q", len(lines), lines)
+	}
+	if lines[2] != `{"line":5,"column":3,"variable":"b","old":"0","new":"19.5","source":"b := 10 + a + 10 * y / 4;"}` {
+		t.Errorf("неожиданная запись присваивания b: %s", lines[2])
+	}
+	var event traceEvent
+	if err := json.Unmarshal([]byte(lines[4]), &event); err != nil {
+		t.Fatalf("некорректная строка JSON: %v", err)
+	}
+	if event.Variable != "c" || event.Old != nil || event.New != "'abc'" {
+		t.Errorf("неожиданная запись присваивания неописанной переменной: %+v", event)
+	}
+}
+
+// TestTracerUnits тестирует трассировку присваиваний в модулях
+func TestTracerUnits(t *testing.T) {
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{"counter.pas": `UNIT Counter;
+INTERFACE
+PROCEDURE Inc;
+IMPLEMENTATION
+VAR count: INTEGER;
+PROCEDURE Inc;
+BEGIN
+  count := count + 1
+END;
+END.`})
+	program, err := parseWithUnits(t, "USES Counter;\nBEGIN\n  Inc\nEND.", dir)
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if err := NewChecker().Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	var trace bytes.Buffer
+	if err := NewInterpreterWithOptions(Options{Observer: NewTracer(&trace, false, "")}).Interpret(program); err != nil {
+		t.Fatalf("Ошибка выполнения: %v", err)
+	}
+	expected := filepath.Join(dir, "counter.pas") + ": строка 8, столбец 3: count: 0 -> 1 | count := count + 1\n"
+	if trace.String() != expected {
+		t.Errorf("ожидалась трассировка %q, получено %q", expected, trace.String())
+	}
+}
+
+// TestMainTrace тестирует флаги -trace и -trace-format
+func TestMainTrace(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "prog.pas")
+	if err := os.WriteFile(program, []byte("BEGIN\n  x := 1;\n  x := x + 1\nEND."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{program}, 0, "{x: 2}\n", ""},
+		{[]string{"-trace", program}, 0, "{x: 2}\n", "строка 2, столбец 3: x: не задано -> 1 | x := 1;\nстрока 3, столбец 3: x: 1 -> 2 | x := x + 1\n"},
+		{[]string{"-trace", "-trace-format", "json", program}, 0, "{x: 2}\n", `{"line":2,"column":3,"variable":"x","old":null,"new":"1","source":"x := 1;"}` + "\n" +
+			`{"line":3,"column":3,"variable":"x","old":"1","new":"2","source":"x := x + 1"}` + "\n"},
+		{[]string{"-trace", "-trace-format", "xml", program}, 1, "", "неизвестный формат трассировки \"xml\": ожидался text или json\n"},
+	}
+	for _, tt := range tests {
+		stdoutReader, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stderrReader, stderrWriter, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Args = append([]string{"pascal"}, tt.args...)
+		os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+		code := mainWithExitCode()
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		var stdout, stderr bytes.Buffer
+		stdout.ReadFrom(stdoutReader)
+		stderr.ReadFrom(stderrReader)
+
+		if code != tt.code {
+			t.Errorf("%v: ожидался код выхода %d, получено %d", tt.args, tt.code, code)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: ожидался вывод %q, получено %q", tt.args, tt.stdout, stdout.String())
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("%v: ожидались ошибки %q, получено %q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TypeKind представляет вид типа
+type TypeKind int
+
+const (
+	TypeInteger TypeKind = iota
+	TypeReal
+	TypeBoolean
+	TypeEnum
+	TypeSubrange
+	TypeSet
+	TypePointer
+	TypeRecord
+	TypeChar
+	TypeString
+	TypeText
+	TypeClass
+)
+
+// Type представляет тип данных Pascal
+type Type struct {
+	Kind TypeKind
+	Name string // пустое для анонимного типа, например в описании переменной
+
+	Values    []string // имена значений перечисления
+	Base      *Type    // базовый тип диапазона: INTEGER, BOOLEAN или перечисление
+	Low, High int64    // порядковые номера границ диапазона
+	Elem      *Type    // базовый тип множества или тип, на который ссылается указатель; nil для [] и NIL
+	Fields    []Field  // поля записи
+	Parent    *Type    // базовый класс исключения; nil для Exception
+}
+
+// Field представляет поле записи
+type Field struct {
+	Name string
+	Type *Type
+}
+
+// Field ищет поле записи по имени без учета регистра и возвращает его номер или -1
+func (t *Type) Field(name string) int {
+	for n, field := range t.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return n
+		}
+	}
+	return -1
+}
+
+func (t *Type) String() string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.Kind == TypeEnum:
+		return "(" + strings.Join(t.Values, ", ") + ")"
+	case t.Kind == TypeSubrange:
+		return t.Range()
+	case t.Kind == TypeSet && t.Elem == nil:
+		return "[]"
+	case t.Kind == TypeSet:
+		return fmt.Sprintf("SET OF %s", t.Elem)
+	case t.Kind == TypePointer && t.Elem == nil:
+		return "NIL"
+	case t.Kind == TypePointer:
+		return "^" + t.Elem.String()
+	case t.Kind == TypeRecord:
+		fields := make([]string, len(t.Fields))
+		for n, field := range t.Fields {
+			fields[n] = fmt.Sprintf("%s: %s", field.Name, field.Type)
+		}
+		return fmt.Sprintf("RECORD %s END", strings.Join(fields, "; "))
+	case t.Kind == TypeClass:
+		return fmt.Sprintf("CLASS(%s) END", t.Parent)
+	default:
+		return t.Name
+	}
+}
+
+// Range возвращает допустимый диапазон значений диапазонного типа, например 0..100 или Red..Green;
+// для множества - диапазон его элементов
+func (t *Type) Range() string {
+	if t.Kind == TypeSet {
+		t = t.Elem
+	}
+	low, high := ordinalBounds(t)
+	return fmt.Sprintf("%s..%s", ordinalValue(t, low), ordinalValue(t, high))
+}
+
+// Предопределенные типы
+var (
+	integerType = &Type{Kind: TypeInteger, Name: "INTEGER"}
+	realType    = &Type{Kind: TypeReal, Name: "REAL"}
+	booleanType = &Type{Kind: TypeBoolean, Name: "BOOLEAN"}
+	charType    = &Type{Kind: TypeChar, Name: "CHAR"}
+	stringType  = &Type{Kind: TypeString, Name: "STRING"}
+	textType    = &Type{Kind: TypeText, Name: "TEXT"}
+)
+
+// isoTypes - стандартные типы, предусмотренные ISO 7185
+var isoTypes = map[string]bool{"integer": true, "real": true, "boolean": true, "char": true, "text": true}
+
+// predeclaredTypes содержит стандартные имена типов, ключ - имя в нижнем регистре
+var predeclaredTypes = map[string]*Type{
+	"integer": integerType,
+	"real":    realType,
+	"boolean": booleanType,
+	"char":    charType,
+	"string":  stringType,
+	"text":    textType,
+
+	"exception":        exceptionClass,
+	"einterror":        intErrorClass,
+	"edivbyzero":       divByZeroClass,
+	"erangeerror":      rangeErrorClass,
+	"eintoverflow":     intOverflowClass,
+	"ematherror":       mathErrorClass,
+	"einvalidop":       invalidOpClass,
+	"eoverflow":        overflowClass,
+	"einouterror":      inOutErrorClass,
+	"eaccessviolation": accessViolationClass,
+	"einvalidpointer":  invalidPointerClass,
+	"estackoverflow":   stackOverflowClass,
+	"eassertionfailed": assertionClass,
+}
+
+// predeclaredConstants содержит стандартные константы, ключ - имя в нижнем регистре
+var predeclaredConstants = map[string]Value{
+	"true":   BooleanValue(true),
+	"false":  BooleanValue(false),
+	"maxint": IntegerValue(math.MaxInt64),
+}
+
+// typeResolver вычисляет типы по их записям. Указатель может ссылаться на тип, описанный
+// ниже в том же разделе TYPE, поэтому такие ссылки разрешаются после раздела методом finish.
+type typeResolver struct {
+	lookup   func(name string) *Type         // ищет описанные пользователем типы по имени в нижнем регистре
+	evaluate func(Expression) (Value, error) // вычисляет константные границы диапазона
+	forward  []forwardPointer
+
+	// resolved запоминает типы записей и классов по их записям, чтобы запись типа в каждой
+	// активации подпрограммы и после продолжения со снимка обозначала один и тот же тип;
+	// nil - каждое вычисление создает новый тип
+	resolved map[TypeSpec]*Type
+
+	// typeName, если задана, вызывается для каждого разрешенного имени типа в записи
+	typeName func(name string, pos Position, t *Type)
+}
+
+// forwardPointer представляет указатель на еще не описанный тип
+type forwardPointer struct {
+	t    *Type
+	spec *PointerType
+}
+
+// named ищет тип по имени среди описанных и стандартных типов
+func (r *typeResolver) named(name string) *Type {
+	if t := r.lookup(strings.ToLower(name)); t != nil {
+		return t
+	}
+	return predeclaredTypes[strings.ToLower(name)]
+}
+
+// use сообщает typeName об имени типа name в позиции pos, обозначающем тип t
+func (r *typeResolver) use(name string, pos Position, t *Type) {
+	if r.typeName != nil {
+		r.typeName(name, pos, t)
+	}
+}
+
+// resolve вычисляет тип по его записи
+func (r *typeResolver) resolve(spec TypeSpec) (*Type, error) {
+	switch s := spec.(type) {
+	case *NamedType:
+		if t := r.named(s.Name); t != nil {
+			r.use(s.Name, s.Pos, t)
+			return t, nil
+		}
+		return nil, fmt.Errorf("неизвестный тип %s", s.Name)
+	case *EnumType:
+		if s.resolved == nil {
+			s.resolved = &Type{Kind: TypeEnum, Values: s.Names}
+		}
+		return s.resolved, nil
+	case *SubrangeType:
+		return resolveSubrange(s, r.evaluate)
+	case *SetType:
+		elem, err := r.resolve(s.Elem)
+		if err != nil {
+			return nil, err
+		}
+		if !isOrdinal(elem) {
+			return nil, fmt.Errorf("базовый тип множества должен быть порядковым, получен %s", elem)
+		}
+		if low, high := ordinalBounds(elem); low < 0 || high > maxSetOrdinal {
+			return nil, fmt.Errorf("базовый тип множества %s должен иметь порядковые номера в диапазоне 0..%d", elem, maxSetOrdinal)
+		}
+		return &Type{Kind: TypeSet, Elem: elem}, nil
+	case *PointerType:
+		t := &Type{Kind: TypePointer, Elem: r.named(s.Name)}
+		if t.Elem == nil {
+			r.forward = append(r.forward, forwardPointer{t: t, spec: s})
+		} else {
+			r.use(s.Name, s.Pos, t.Elem)
+		}
+		return t, nil
+	case *RecordType:
+		if t := r.resolved[s]; t != nil {
+			return t, nil
+		}
+		t, err := r.resolveRecord(s)
+		if err == nil && r.resolved != nil {
+			r.resolved[s] = t
+		}
+		return t, err
+	case *ClassType:
+		if t := r.resolved[s]; t != nil {
+			return t, nil
+		}
+		parent := r.named(s.Parent)
+		if parent == nil || parent.Kind != TypeClass {
+			return nil, fmt.Errorf("базовый класс %s не является классом исключения", s.Parent)
+		}
+		t := &Type{Kind: TypeClass, Parent: parent}
+		if r.resolved != nil {
+			r.resolved[s] = t
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("неизвестная запись типа: %T", spec)
+	}
+}
+
+// resolveRecord вычисляет тип записи; имена полей не должны повторяться
+func (r *typeResolver) resolveRecord(s *RecordType) (*Type, error) {
+	t := &Type{Kind: TypeRecord}
+	var spec TypeSpec
+	var fieldType *Type
+	for _, field := range s.Fields {
+		if t.Field(field.Name) >= 0 {
+			return nil, fmt.Errorf("повторное описание поля %s", field.Name)
+		}
+		// Поля из одного списка a, b: T имеют общую запись типа
+		if field.Type != spec {
+			var err error
+			spec = field.Type
+			if fieldType, err = r.resolve(spec); err != nil {
+				return nil, err
+			}
+		}
+		t.Fields = append(t.Fields, Field{Name: field.Name, Type: fieldType})
+	}
+	return t, nil
+}
+
+// finish разрешает ссылки указателей на типы, описанные позже; при ошибке
+// возвращает позицию записи указателя
+func (r *typeResolver) finish() (Position, error) {
+	forward := r.forward
+	r.forward = nil
+	for _, f := range forward {
+		if f.t.Elem = r.named(f.spec.Name); f.t.Elem == nil {
+			return f.spec.Pos, fmt.Errorf("неизвестный тип %s", f.spec.Name)
+		}
+		r.use(f.spec.Name, f.spec.Pos, f.t.Elem)
+	}
+	return Position{}, nil
+}
+
+// resolveSubrange вычисляет границы диапазонного типа Low..High
+func resolveSubrange(s *SubrangeType, evaluate func(Expression) (Value, error)) (*Type, error) {
+	bounds := make([]Value, 2)
+	for n, expr := range []Expression{s.Low, s.High} {
+		value, err := evaluate(expr)
+		if err != nil {
+			return nil, fmt.Errorf("граница диапазона: %v", err)
+		}
+		if _, ok := ordinalOf(value); !ok {
+			return nil, fmt.Errorf("граница диапазона должна быть порядкового типа, получено %s", typeOfValue(value))
+		}
+		bounds[n] = value
+	}
+	base := typeOfValue(bounds[0])
+	if !sameType(base, typeOfValue(bounds[1])) {
+		return nil, fmt.Errorf("границы диапазона %s..%s имеют разные типы", bounds[0], bounds[1])
+	}
+	low, _ := ordinalOf(bounds[0])
+	high, _ := ordinalOf(bounds[1])
+	if low > high {
+		return nil, fmt.Errorf("пустой диапазон %s..%s", bounds[0], bounds[1])
+	}
+	return &Type{Kind: TypeSubrange, Base: base, Low: low, High: high}, nil
+}
+
+// baseType возвращает базовый тип диапазона; для остальных типов - сам тип.
+// Выражения всегда имеют базовый тип, диапазон ограничивает только переменные.
+func baseType(t *Type) *Type {
+	if t != nil && t.Kind == TypeSubrange {
+		return t.Base
+	}
+	return t
+}
+
+// sameType сообщает, совпадают ли типы с точностью до диапазона.
+// Перечисления и записи совпадают, только если это один и тот же тип; множества
+// и указатели - если совпадают типы элементов, а [] и NIL совместимы с любыми.
+func sameType(a, b *Type) bool {
+	a, b = baseType(a), baseType(b)
+	if a == b {
+		return true
+	}
+	if a.Kind == TypeSet && b.Kind == TypeSet || a.Kind == TypePointer && b.Kind == TypePointer {
+		return a.Elem == nil || b.Elem == nil || sameType(a.Elem, b.Elem)
+	}
+	if a.Kind == TypeRecord || b.Kind == TypeRecord || a.Kind == TypeClass || b.Kind == TypeClass {
+		return false
+	}
+	if a.Kind == TypeEnum || b.Kind == TypeEnum {
+		return a == b
+	}
+	return a.Kind == b.Kind
+}
+
+// isOrdinal сообщает, является ли тип порядковым; неизвестный тип считается допустимым
+func isOrdinal(t *Type) bool {
+	t = baseType(t)
+	return t == nil || t.Kind == TypeInteger || t.Kind == TypeBoolean || t.Kind == TypeEnum || t.Kind == TypeChar
+}
+
+// isText сообщает, является ли тип символьным или строковым
+func isText(t *Type) bool {
+	t = baseType(t)
+	return t != nil && (t.Kind == TypeChar || t.Kind == TypeString)
+}
+
+// ordinalBounds возвращает наименьший и наибольший порядковые номера порядкового типа
+func ordinalBounds(t *Type) (int64, int64) {
+	switch t.Kind {
+	case TypeBoolean:
+		return 0, 1
+	case TypeEnum:
+		return 0, int64(len(t.Values)) - 1
+	case TypeChar:
+		return 0, maxSetOrdinal
+	case TypeSubrange:
+		return t.Low, t.High
+	default:
+		return math.MinInt64, math.MaxInt64
+	}
+}
+
+// ordinalValue возвращает значение порядкового типа t с порядковым номером ordinal
+func ordinalValue(t *Type, ordinal int64) Value {
+	switch baseType(t).Kind {
+	case TypeBoolean:
+		return BooleanValue(ordinal != 0)
+	case TypeEnum:
+		return EnumValue{Type: baseType(t), Ordinal: ordinal}
+	case TypeChar:
+		return CharValue(ordinal)
+	default:
+		return IntegerValue(ordinal)
+	}
+}
+
+// inRange сообщает, допустимо ли значение для переменной типа t.
+// Все элементы множества должны принадлежать его базовому типу.
+func inRange(t *Type, v Value) bool {
+	if t != nil && t.Kind == TypeSet {
+		low, high := ordinalBounds(t.Elem)
+		for _, ordinal := range v.(SetValue).members() {
+			if ordinal < low || ordinal > high {
+				return false
+			}
+		}
+		return true
+	}
+	if t == nil || t.Kind != TypeSubrange {
+		return true
+	}
+	ordinal, ok := ordinalOf(v)
+	return ok && t.Low <= ordinal && ordinal <= t.High
+}
+
+// zeroValue возвращает начальное значение переменной типа t;
+// для перечисления и диапазона это их наименьшее значение
+func zeroValue(t *Type) Value {
+	switch t.Kind {
+	case TypeReal:
+		return RealValue(0)
+	case TypeBoolean:
+		return BooleanValue(false)
+	case TypeEnum:
+		return EnumValue{Type: t, Ordinal: 0}
+	case TypeChar:
+		return CharValue(0)
+	case TypeString:
+		return StringValue("")
+	case TypeText:
+		return &FileValue{}
+	case TypeClass:
+		return (*ExceptionValue)(nil)
+	case TypeSubrange:
+		return ordinalValue(t.Base, t.Low)
+	case TypeSet:
+		return SetValue{Elem: baseType(t.Elem)}
+	case TypePointer:
+		return PointerValue{Elem: t.Elem}
+	case TypeRecord:
+		fields := make([]Value, len(t.Fields))
+		for n, field := range t.Fields {
+			fields[n] = zeroValue(field.Type)
+		}
+		return &RecordValue{Type: t, Fields: fields}
+	default:
+		return IntegerValue(0)
+	}
+}
+
+// typeOfValue возвращает тип значения
+func typeOfValue(v Value) *Type {
+	switch v := v.(type) {
+	case RealValue:
+		return realType
+	case BooleanValue:
+		return booleanType
+	case EnumValue:
+		return v.Type
+	case SetValue:
+		return &Type{Kind: TypeSet, Elem: v.Elem}
+	case PointerValue:
+		return &Type{Kind: TypePointer, Elem: v.Elem}
+	case *RecordValue:
+		return v.Type
+	case CharValue:
+		return charType
+	case StringValue:
+		return stringType
+	case *FileValue:
+		return textType
+	case *ExceptionValue:
+		if v == nil {
+			return exceptionClass
+		}
+		return v.Class
+	default:
+		return integerType
+	}
+}
+
+// assignable сообщает, можно ли присвоить значение типа from переменной типа to.
+// Файловые переменные присваивать нельзя, символ можно присвоить строковой переменной,
+// а исключение - переменной его класса или любого базового класса.
+func assignable(to, from *Type) bool {
+	if baseType(to).Kind == TypeText {
+		return false
+	}
+	if sameType(to, from) {
+		return true
+	}
+	switch baseType(to).Kind {
+	case TypeReal:
+		return baseType(from).Kind == TypeInteger
+	case TypeString:
+		return baseType(from).Kind == TypeChar
+	case TypeClass:
+		return from.Kind == TypeClass && isSubclass(from, to)
+	default:
+		return false
+	}
+}
+
+// convertValue приводит значение к типу переменной при присваивании
+func convertValue(t *Type, v Value) (Value, error) {
+	if !assignable(t, typeOfValue(v)) {
+		return nil, fmt.Errorf("несовместимые типы: нельзя присвоить %s переменной типа %s", typeOfValue(v), t)
+	}
+	switch baseType(t).Kind {
+	case TypeReal:
+		if n, ok := v.(IntegerValue); ok {
+			return RealValue(n), nil
+		}
+		if n, ok := v.(BigIntValue); ok {
+			x, _ := toReal(n)
+			return checkReal(x)
+		}
+	case TypeString:
+		if c, ok := v.(CharValue); ok {
+			return StringValue(string(rune(c))), nil
+		}
+	case TypeSet:
+		// Пустое множество получает тип элементов переменной
+		set := v.(SetValue)
+		set.Elem = baseType(t.Elem)
+		return set, nil
+	case TypePointer:
+		// NIL получает тип указателя переменной
+		pointer := v.(PointerValue)
+		pointer.Elem = t.Elem
+		return pointer, nil
+	}
+	return v, nil
+}
+
+// sameVarType сообщает, можно ли передать переменную типа a в VAR-параметр типа b:
+// диапазон совпадает только с диапазоном тех же границ, иначе запись через параметр
+// могла бы нарушить границы переменной
+func sameVarType(a, b *Type) bool {
+	if a.Kind == TypeSubrange || b.Kind == TypeSubrange {
+		return a == b || a.Kind == b.Kind && a.Low == b.Low && a.High == b.High && sameType(a.Base, b.Base)
+	}
+	return sameType(a, b)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// runChecked выполняет программу после семантического анализа, как это делает main
+func runChecked(t *testing.T, code string) (*Interpreter, error) {
+	t.Helper()
+	program, err := checkCode(t, code)
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	interpreter := NewInterpreter()
+	return interpreter, interpreter.Interpret(program)
+}
+
+// TestEnumeratedTypes тестирует перечисления и функции Ord, Succ, Pred
+func TestEnumeratedTypes(t *testing.T) {
+	const code = `TYPE
+	TColor = (Red, Green, Blue);
+VAR
+	c, last: TColor;
+	d: (North, East, South, West);
+	first: TColor;
+BEGIN
+	c := Succ(Red);
+	last := Succ(Pred(Blue));
+	d := Pred(West);
+	n := Ord(c) + Ord(d);
+	b := Succ(FALSE);
+	m := Pred(Ord(Blue))
+END.`
+
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения: %v", err)
+		}
+		values := interpreter.Values()
+		color := interpreter.types["tcolor"]
+		if color == nil || color.Kind != TypeEnum {
+			t.Fatalf("Ожидался перечислимый тип TColor, получено %v", color)
+		}
+		tests := []struct {
+			name string
+			want Value
+		}{
+			{"c", EnumValue{Type: color, Ordinal: 1}},
+			{"last", EnumValue{Type: color, Ordinal: 2}},
+			{"first", EnumValue{Type: color, Ordinal: 0}},
+			{"n", IntegerValue(3)},
+			{"b", BooleanValue(true)},
+			{"m", IntegerValue(1)},
+		}
+		for _, tt := range tests {
+			if values[tt.name] != tt.want {
+				t.Errorf("%s: ожидалось %v, получено %v", tt.name, tt.want, values[tt.name])
+			}
+		}
+		if got := formatVariables(values); got != "{b: TRUE, c: Green, d: South, first: Red, last: Blue, m: 1, n: 3}" {
+			t.Errorf("Неожиданный вывод переменных: %s", got)
+		}
+	}
+}
+
+// TestSubrangeTypes тестирует диапазоны целых, логических значений и перечислений
+func TestSubrangeTypes(t *testing.T) {
+	values := mustInterpret(t, `CONST
+	Max = 10;
+TYPE
+	TDay = (Mon, Tue, Wed, Thu, Fri, Sat, Sun);
+	TWorkDay = Mon..Fri;
+	TDigit = 0..9;
+VAR
+	day: TWorkDay;
+	digit: TDigit;
+	small: -Max..Max - 1;
+	positive: 1..MAXINT;
+	r: REAL;
+BEGIN
+	day := Succ(Thu);
+	digit := 9;
+	small := -10;
+	r := digit / 2
+END.`)
+	if got := values["day"].String(); got != "Fri" {
+		t.Errorf("Ожидалось day = Fri, получено %s", got)
+	}
+	if values["digit"] != IntegerValue(9) || values["small"] != IntegerValue(-10) || values["r"] != RealValue(4.5) {
+		t.Errorf("Неожиданные значения переменных: %v", values)
+	}
+	if values["positive"] != IntegerValue(1) {
+		t.Errorf("Начальное значение диапазона должно быть его нижней границей, получено %v", values["positive"])
+	}
+}
+
+// TestRangeCheckErrors тестирует ошибку выполнения при выходе значения за границы диапазона
+func TestRangeCheckErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		pos  Position
+	}{
+		{"VAR x: 0..100; BEGIN\n  n := 60;\n  x := n * 2\nEND.",
+			"нарушение диапазона: значение 120 переменной x вне диапазона 0..100", Position{Line: 3, Column: 3}},
+		{"TYPE T = (A, B, C, D); U = B..C; VAR v: U; BEGIN v := B; v := Pred(v) END.",
+			"значение A переменной v вне диапазона B..C", Position{Line: 1, Column: 58}},
+		{"VAR b: TRUE..TRUE; BEGIN x := FALSE; b := x END.",
+			"значение FALSE переменной b вне диапазона TRUE..TRUE", Position{Line: 1, Column: 38}},
+	}
+	for _, tt := range tests {
+		for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+			_, err := run(t, tt.code)
+			runtimeErr, ok := err.(*RuntimeError)
+			if !ok {
+				t.Fatalf("%q: ожидалась ошибка выполнения, получено %v", tt.code, err)
+			}
+			if !strings.Contains(runtimeErr.Message, tt.want) {
+				t.Errorf("%q: ожидалась ошибка %q, получено %q", tt.code, tt.want, runtimeErr.Message)
+			}
+			if runtimeErr.Pos != tt.pos {
+				t.Errorf("%q: ожидалась позиция %v, получено %v", tt.code, tt.pos, runtimeErr.Pos)
+			}
+		}
+	}
+}
+
+// TestRangeCheckDirective тестирует включение и отключение проверки директивами {$R-} и {$R+}
+func TestRangeCheckDirective(t *testing.T) {
+	values := mustInterpret(t, `{$R-}
+VAR x: 0..10;
+BEGIN
+	n := 20;
+	x := n;
+	{$R+}
+	y := 1
+END.`)
+	if values["x"] != IntegerValue(20) {
+		t.Errorf("При {$R-} значение должно присваиваться без проверки, получено %v", values["x"])
+	}
+
+	for _, code := range []string{
+		"VAR x: 0..10; BEGIN n := 20; {$R-} {$R+} x := n END.",
+		"VAR x: 0..10; BEGIN n := 20; (*$R-*) x := 0; (*$I+, r+*) x := n END.",
+		"VAR x: 0..10; BEGIN n := 20; {$R-} x := 0 {$R+}; x := n END.",
+	} {
+		if _, err := interpretCode(t, code); err == nil {
+			t.Errorf("%q: ожидалась ошибка нарушения диапазона", code)
+		}
+	}
+	for _, code := range []string{
+		"VAR x: 0..10; BEGIN n := 20; {$I-,R-} x := n END.",
+		"VAR x: 0..10; BEGIN n := 20; {$R-} x := n; {$X+} x := n + 1 END.",
+	} {
+		if _, err := interpretCode(t, code); err != nil {
+			t.Errorf("%q: неожиданная ошибка %v", code, err)
+		}
+	}
+}
+
+// TestOrdinalFunctionErrors тестирует ошибки Succ, Pred и Ord
+func TestOrdinalFunctionErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"TYPE T = (A, B); BEGIN x := B; y := Succ(x) END.", "Succ: у значения B нет следующего"},
+		{"BEGIN x := FALSE; y := Pred(x) END.", "Pred: у значения FALSE нет предыдущего"},
+		{"BEGIN x := MAXINT; y := Succ(x) END.", "Succ: целочисленное переполнение"},
+		{"BEGIN y := Ord(1.5) END.", "Ord: ожидался аргумент порядкового типа, получен REAL"},
+		{"BEGIN y := Pred(0.5) END.", "Pred: ожидался аргумент порядкового типа"},
+	}
+	for _, tt := range tests {
+		_, err := interpretCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+}
+
+// TestCheckerEnumAndSubrange тестирует статические ошибки перечислений и диапазонов
+func TestCheckerEnumAndSubrange(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"VAR x: 1..10; BEGIN x := 11 END.", "значение 11 вне диапазона 1..10 переменной x"},
+		{"TYPE T = (A, B, C); VAR x: A..B; BEGIN x := C END.", "значение C вне диапазона A..B переменной x"},
+		{"{$R-} VAR x: 1..10; BEGIN x := 0 END.", "вне диапазона"},
+		{"TYPE T = (A, B); VAR n: INTEGER; BEGIN n := A END.", "нельзя присвоить T переменной n типа INTEGER"},
+		{"TYPE T = (A, B); U = (C, D); VAR x: T; BEGIN x := C END.", "нельзя присвоить U"},
+		{"TYPE T = (A, B); VAR x: T; BEGIN x := 1 END.", "нельзя присвоить INTEGER"},
+		{"TYPE T = (A, B); BEGIN x := A + 1 END.", "арифметическая операция неприменима к типу T"},
+		{"TYPE T = (A, B); U = (B, C); BEGIN END.", "повторное описание B"},
+		{"TYPE T = (A, B); VAR A: INTEGER; BEGIN END.", "повторное описание A"},
+		{"TYPE T = 10..1; BEGIN END.", "пустой диапазон 10..1"},
+		{"TYPE T = 1..TRUE; BEGIN END.", "границы диапазона 1..TRUE имеют разные типы"},
+		{"TYPE T = 1.5..2; BEGIN END.", "должна быть порядкового типа, получено REAL"},
+		{"TYPE T = x..10; BEGIN END.", "ожидалось константное выражение"},
+		{"TYPE T = 1..1 / 0; BEGIN END.", "граница диапазона: деление на ноль"},
+		{"TYPE T = (A, B); U = (C, D); BEGIN CASE A OF C: x := 1 END END.", "тип метки CASE U не совпадает с типом выражения T"},
+		{"BEGIN x := Ord(1.5) END.", "Ord: ожидался аргумент порядкового типа, получен REAL"},
+		{"BEGIN x := Succ(2.5) END.", "Succ: ожидался аргумент порядкового типа"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	// Свертка Ord, Succ и Pred над константами
+	program, err := checkCode(t, "TYPE T = (A, B, C); BEGIN x := Ord(Succ(A)) + Ord(Pred(C)); y := Pred(C) END.")
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	if literal, ok := program.Statements[0].(*Assignment).Value.(*Literal); !ok || literal.Value != IntegerValue(2) {
+		t.Errorf("Ожидалась свертка в Literal(2), получено %v", program.Statements[0])
+	}
+	if literal, ok := program.Statements[1].(*Assignment).Value.(*Literal); !ok || literal.Value.String() != "B" {
+		t.Errorf("Ожидалась свертка в Literal(B), получено %v", program.Statements[1])
+	}
+}
+
+// TestInterpreterEnumAndSubrangeErrors тестирует ошибки описаний при выполнении без семантического анализа
+func TestInterpreterEnumAndSubrangeErrors(t *testing.T) {
+	for _, code := range []string{
+		"TYPE T = (A, B); U = (B, C); BEGIN END.",
+		"TYPE T = 5..1; BEGIN END.",
+		"TYPE T = 1..1 / 0; BEGIN END.",
+		"VAR x: (A, A); BEGIN END.",
+		"TYPE T = (A, B); U = (C, D); VAR x: T; BEGIN x := C END.",
+		"TYPE T = (A, B); VAR x: T; BEGIN CASE x OF 1: y := 1 END END.",
+	} {
+		if _, err := interpretCode(t, code); err == nil {
+			t.Errorf("%q: ожидалась ошибка выполнения", code)
+		}
+	}
+}
+
+// TestParseTypeSpecs тестирует разбор записей перечислимых и диапазонных типов
+func TestParseTypeSpecs(t *testing.T) {
+	program := parseCode(t, `CONST N = 5;
+TYPE
+	A = (X, Y, Z);
+	B = X..Y;
+	C = -N..N - 1;
+	D = Low(1)..2;
+	E = INTEGER;
+VAR v, w: (P, Q);
+BEGIN END.`)
+	want := []string{
+		"Type(A = (X, Y, Z))",
+		"Type(B = Identifier(X)..Identifier(Y))",
+		"Type(C = BinaryOp(Number(0) - Identifier(N))..BinaryOp(Identifier(N) - Number(1)))",
+		"Type(D = Call(Low(Number(1)))..Number(2))",
+		"Type(E = INTEGER)",
+	}
+	for n, decl := range program.Types {
+		decl.Type.typeNode()
+		if got := decl.String(); got != want[n] {
+			t.Errorf("Ожидалось %s, получено %s", want[n], got)
+		}
+	}
+	if program.Vars[0].Type != program.Vars[1].Type {
+		t.Error("Переменные одного списка должны иметь общую запись типа")
+	}
+
+	for _, code := range []string{
+		"TYPE T = (A, ); BEGIN END.",
+		"TYPE T = (A B); BEGIN END.",
+		"TYPE T = (); BEGIN END.",
+		"TYPE T = 1; BEGIN END.",
+		"TYPE T = 1..; BEGIN END.",
+		"TYPE T = ; BEGIN END.",
+		"VAR x: 1 2; BEGIN END.",
+	} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", code, err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestLexerDirectives тестирует выделение директив компилятора из комментариев
+func TestLexerDirectives(t *testing.T) {
+	tokens, err := NewLexer("{$R-} { R+ } (*$R+*) BEGIN").Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	want := []Token{
+		{Type: TokenDIRECTIVE, Value: "{$R-}", Pos: 0, Line: 1, Column: 1},
+		{Type: TokenDIRECTIVE, Value: "(*$R+*)", Pos: 13, Line: 1, Column: 14},
+		{Type: TokenBEGIN, Value: "BEGIN", Pos: 21, Line: 1, Column: 22},
+		{Type: TokenEOF, Value: "", Pos: 26, Line: 1, Column: 27},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("Ожидалось %d токенов, получено %d: %v", len(want), len(tokens), tokens)
+	}
+	for n := range want {
+		if tokens[n] != want[n] {
+			t.Errorf("Токен %d: ожидался %v, получен %v", n, want[n], tokens[n])
+		}
+	}
+}
+
+// TestTypeStrings тестирует имена анонимных типов и строковое представление значений
+func TestTypeStrings(t *testing.T) {
+	enum := &Type{Kind: TypeEnum, Values: []string{"A", "B"}}
+	subrange := &Type{Kind: TypeSubrange, Base: enum, Low: 0, High: 1}
+	tests := []struct {
+		got, want string
+	}{
+		{enum.String(), "(A, B)"},
+		{subrange.String(), "A..B"},
+		{(&Type{Kind: TypeSubrange, Base: integerType, Low: -1, High: 1}).String(), "-1..1"},
+		{(&Type{Kind: TypeSubrange, Name: "T", Base: booleanType, High: 1}).String(), "T"},
+		{EnumValue{Type: enum, Ordinal: 5}.String(), "(A, B)(5)"},
+		{KindEnum.String(), "перечисление"},
+		{zeroValue(subrange).String(), "A"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("Ожидалось %q, получено %q", tt.want, tt.got)
+		}
+	}
+	if toFloat(EnumValue{Type: enum, Ordinal: 1}) != 1 {
+		t.Error("Перечисление должно передаваться в GetVariables порядковым номером")
+	}
+}
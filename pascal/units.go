@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// UnitLoader находит модули из предложений USES в каталогах поиска и разбирает их.
+// Разобранные модули хранятся в кэше, поэтому модуль, подключенный несколькими
+// модулями программы, читается и разбирается один раз.
+type UnitLoader struct {
+	Path    []string // каталоги поиска в порядке просмотра; пустой список - текущий каталог
+	Dialect Dialect  // вариант языка, в котором разбираются модули
+	BigInt  bool     // режим длинных целых: целые литералы модулей не ограничены int64
+
+	// Files - файлы, в которых загрузчик искал модули, в том числе несуществующие и
+	// файлы модулей с ошибками: режим -watch следит за ними, даже если загрузка не удалась
+	Files []string
+
+	units   map[string]*Unit // разобранные модули по имени в нижнем регистре
+	loading []string         // модули, которые разбираются сейчас: цепочка USES для обнаружения циклов
+}
+
+// NewUnitLoader создает загрузчик, который ищет модули в каталогах path
+func NewUnitLoader(path ...string) *UnitLoader {
+	return &UnitLoader{Path: path, units: make(map[string]*Unit)}
+}
+
+// LoadUnit возвращает модуль name из файла name.pas (имя файла в нижнем регистре),
+// найденного в каталогах поиска. Модули, которые подключает загружаемый модуль,
+// загружаются при разборе его предложения USES.
+func (l *UnitLoader) LoadUnit(name string, pos Position) (*Unit, error) {
+	key := strings.ToLower(name)
+	for n, loading := range l.loading {
+		if strings.ToLower(loading) == key {
+			chain := append(append([]string{}, l.loading[n:]...), name)
+			return nil, &unitError{pos, fmt.Errorf("циклическая зависимость модулей: %s", strings.Join(chain, " -> "))}
+		}
+	}
+	if unit, ok := l.units[key]; ok {
+		return unit, nil
+	}
+
+	filename, err := l.find(name)
+	if err != nil {
+		return nil, &unitError{pos, err}
+	}
+	code, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, &unitError{pos, fmt.Errorf("ошибка чтения модуля %s: %v", name, err)}
+	}
+	l.loading = append(l.loading, name)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	lexer := NewLexer(string(code))
+	lexer.File = filename
+	lexer.Dialect = l.Dialect
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		return nil, fileError(filename, err)
+	}
+	parser := NewParserWithUnits(tokens, l)
+	parser.Dialect = l.Dialect
+	parser.Deviations = lexer.Deviations
+	parser.BigInt = l.BigInt
+	unit, err := parser.ParseUnit()
+	if err != nil {
+		// Ошибка загрузки модуля из USES этого модуля уже содержит имя своего файла
+		var nested *unitError
+		if errors.As(err, &nested) {
+			return nil, err
+		}
+		return nil, fileError(filename, err)
+	}
+	if !strings.EqualFold(unit.Name, name) {
+		return nil, &unitError{pos, fmt.Errorf("файл %s содержит модуль %s, а не %s", filename, unit.Name, name)}
+	}
+	l.units[key] = unit
+	return unit, nil
+}
+
+// fileError дополняет ошибку разбора модуля именем его файла. Отклонение от диалекта
+// содержит позицию токена, в которую имя файла уже входит.
+func fileError(filename string, err error) error {
+	var deviation *DialectError
+	if errors.As(err, &deviation) {
+		return &unitError{Position{}, err}
+	}
+	return &unitError{Position{}, fmt.Errorf("%s: %v", filename, err)}
+}
+
+// unitError - ошибка загрузки модуля: pos - позиция имени модуля в USES, если ошибка
+// относится к самому подключению, а не к тексту модуля, который уже указывает файл и позицию
+type unitError struct {
+	pos Position
+	err error
+}
+
+func (e *unitError) Error() string {
+	if e.pos.IsValid() {
+		return fmt.Sprintf("%s: %v", e.pos, e.err)
+	}
+	return e.err.Error()
+}
+
+func (e *unitError) Unwrap() error {
+	return e.err
+}
+
+// find возвращает путь к файлу модуля в первом каталоге поиска, где он есть
+func (l *UnitLoader) find(name string) (string, error) {
+	path := l.Path
+	if len(path) == 0 {
+		path = []string{"."}
+	}
+	for _, dir := range path {
+		for _, base := range []string{strings.ToLower(name) + ".pas", name + ".pas"} {
+			filename := filepath.Join(dir, base)
+			if !slices.Contains(l.Files, filename) {
+				l.Files = append(l.Files, filename)
+			}
+			info, err := os.Stat(filename)
+			if err == nil && !info.IsDir() {
+				return filename, nil
+			}
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("модуль %s: %v", name, err)
+			}
+		}
+	}
+	return "", fmt.Errorf("модуль %s не найден (каталоги поиска: %s)", name, strings.Join(path, ", "))
+}
+
+// imports создает область видимости с именами разделов INTERFACE модулей uses, вложенную
+// в parent. Имена модуля, указанного в списке позже, скрывают одноименные имена предыдущих.
+func (c *Checker) imports(uses []*UnitRef, parent *Scope) *Scope {
+	if len(uses) == 0 {
+		return parent
+	}
+	scope := NewScope(parent)
+	for _, ref := range uses {
+		if ref.Unit == nil {
+			c.errorf(ref.Pos, "модуль %s не загружен", ref.Name)
+			continue
+		}
+		for key, symbol := range c.unit(ref.Unit).symbols {
+			scope.symbols[key] = symbol
+		}
+	}
+	return scope
+}
+
+// unit проверяет модуль при первом подключении и возвращает имена его раздела INTERFACE
+func (c *Checker) unit(unit *Unit) *Scope {
+	if unit.exports == nil {
+		checker := NewChecker()
+		checker.Info = c.Info
+		checker.checkUnit(unit)
+		c.errors = append(c.errors, checker.errors...)
+	}
+	return unit.exports
+}
+
+// checkUnit проверяет модуль: раздел INTERFACE, реализацию его подпрограмм в разделе
+// IMPLEMENTATION и раздел инициализации
+func (c *Checker) checkUnit(unit *Unit) {
+	unit.exports = NewScope(nil)
+	c.scope.parent = c.imports(unit.Uses, c.scope.parent)
+	c.recordScope(unit)
+	c.declarations(&unit.Interface)
+	for key, symbol := range c.scope.symbols {
+		unit.exports.symbols[key] = symbol
+	}
+	// Модули из USES раздела IMPLEMENTATION видны только реализации
+	c.scope.parent = c.imports(unit.ImplUses, c.scope.parent)
+	c.declarations(&unit.Implementation)
+	for _, decl := range unit.Interface.Routines {
+		if symbol := c.scope.symbols[strings.ToLower(decl.Name)]; symbol != nil && symbol.Forward {
+			c.errorf(decl.Pos, "подпрограмма %s описана в разделе INTERFACE модуля %s, но не реализована", decl.Name, unit.Name)
+		}
+	}
+	c.statements(unit.Statements)
+}
+
+// useUnits инициализирует модули uses и возвращает их интерфейсы
+func (i *Interpreter) useUnits(uses []*UnitRef) ([]*Frame, error) {
+	frames := make([]*Frame, 0, len(uses))
+	for _, ref := range uses {
+		if ref.Unit == nil {
+			return nil, runtimeError(ref.Pos, "модуль %s не загружен", ref.Name)
+		}
+		frame, err := i.initUnit(ref.Unit)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// initUnit выполняет описания и раздел инициализации модуля при первом подключении
+// и возвращает область видимости с именами его раздела INTERFACE. Модули, которые
+// подключает модуль, инициализируются раньше него.
+func (i *Interpreter) initUnit(unit *Unit) (*Frame, error) {
+	if exports, ok := i.units[unit]; ok {
+		return exports, nil
+	}
+	uses, err := i.useUnits(unit.Uses)
+	if err != nil {
+		return nil, err
+	}
+	frame := &Frame{
+		Name:      unit.Name,
+		variables: make(map[string]*Variable),
+		types:     make(map[string]*Type),
+		routines:  make(map[string]*Routine),
+		Uses:      uses,
+	}
+	i.unitGlobals = append(i.unitGlobals, frame)
+	globals := i.globals
+	i.globals = frame
+	defer func() { i.globals = globals }()
+
+	if err := i.declare(&unit.Interface); err != nil {
+		return nil, err
+	}
+	exports := &Frame{
+		Name:      unit.Name,
+		variables: make(map[string]*Variable, len(frame.variables)),
+		types:     make(map[string]*Type, len(frame.types)),
+		routines:  make(map[string]*Routine, len(unit.Interface.Routines)),
+	}
+	for key, variable := range frame.variables {
+		exports.variables[key] = variable
+	}
+	for key, t := range frame.types {
+		exports.types[key] = t
+	}
+	// Модули из USES раздела IMPLEMENTATION инициализируются до раздела инициализации
+	// модуля и видны только его реализации
+	implUses, err := i.useUnits(unit.ImplUses)
+	if err != nil {
+		return nil, err
+	}
+	frame.Uses = append(frame.Uses, implUses...)
+	if err := i.declare(&unit.Implementation); err != nil {
+		return nil, err
+	}
+	for _, decl := range unit.Interface.Routines {
+		key := strings.ToLower(decl.Name)
+		routine, ok := frame.routines[key]
+		if !ok {
+			return nil, runtimeError(decl.Pos, "подпрограмма %s описана в разделе INTERFACE модуля %s, но не реализована", decl.Name, unit.Name)
+		}
+		exports.routines[key] = routine
+	}
+	i.units[unit] = exports
+	if err := i.restoreScope(scopeUnit, unit.Name, frame.variables); err != nil {
+		return nil, err
+	}
+
+	err = i.executeStatements(unit.Statements)
+	// Exit в разделе инициализации завершает только его
+	i.flow = flowNormal
+	return exports, err
+}
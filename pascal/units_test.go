@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeUnits создает в каталоге dir файлы модулей: ключ - имя файла, значение - текст
+func writeUnits(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, code := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// parseWithUnits разбирает программу, загружая модули из каталогов path
+func parseWithUnits(t *testing.T, code string, path ...string) (*Program, error) {
+	t.Helper()
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	return NewParserWithUnits(tokens, NewUnitLoader(path...)).Parse()
+}
+
+// runWithUnits разбирает, проверяет и выполняет программу с модулями из каталога dir
+// и возвращает интерпретатор и вывод программы
+func runWithUnits(t *testing.T, code, dir string) (*Interpreter, string, error) {
+	t.Helper()
+	program, err := parseWithUnits(t, code, dir)
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if err := NewChecker().Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	var out bytes.Buffer
+	interpreter := NewInterpreterWithOptions(Options{Stdout: &out})
+	err = interpreter.Interpret(program)
+	return interpreter, out.String(), err
+}
+
+const (
+	// Модули с общей зависимостью: Shapes и Report подключают Base
+	baseUnit = `UNIT Base;
+INTERFACE
+CONST Scale = 10;
+TYPE Color = (Red, Green, Blue);
+VAR Count: INTEGER;
+PROCEDURE Tick;
+FUNCTION Twice(x: INTEGER): INTEGER;
+IMPLEMENTATION
+VAR hidden: INTEGER;
+PROCEDURE Tick;
+BEGIN
+  Count := Count + 1; hidden := hidden + 1
+END;
+FUNCTION Twice(x: INTEGER): INTEGER;
+BEGIN
+  Twice := 2 * x
+END;
+INITIALIZATION
+  WriteLn('Base')
+END.`
+	shapesUnit = `UNIT Shapes;
+INTERFACE
+USES Base;
+TYPE Point = RECORD x, y: INTEGER END;
+FUNCTION Scaled(p: Point): INTEGER;
+IMPLEMENTATION
+FUNCTION Scaled(p: Point): INTEGER;
+BEGIN
+  Tick;
+  Scaled := (p.x + p.y) * Scale
+END;
+BEGIN
+  WriteLn('Shapes')
+END.`
+	reportUnit = `UNIT Report;
+INTERFACE
+USES Base;
+PROCEDURE Show(c: Color);
+IMPLEMENTATION
+PROCEDURE Show(c: Color);
+BEGIN
+  Tick;
+  WriteLn('color ', Ord(c))
+END;
+END.`
+)
+
+// TestUnits тестирует подключение модулей: экспортируемые константы, типы, переменные
+// и подпрограммы, вызов процедуры модуля без скобок и порядок инициализации
+func TestUnits(t *testing.T) {
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{"base.pas": baseUnit, "shapes.pas": shapesUnit, "report.pas": reportUnit})
+
+	code := `PROGRAM Main;
+USES Shapes, Report, Base;
+VAR p: Point; total: INTEGER; c: Color;
+BEGIN
+  p.x := 1; p.y := 2;
+  total := Scaled(p) + Twice(Scale);
+  c := Blue;
+  Show(c);
+  Tick
+END.`
+	interpreter, out, err := runWithUnits(t, code, dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	// Base инициализируется один раз и раньше подключивших его модулей
+	if want := "Base\nShapes\ncolor 2\n"; out != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out)
+	}
+	want := "{c: Blue, p: (x: 1; y: 2), total: 50}"
+	if got := formatVariables(interpreter.Values()); got != want {
+		t.Errorf("Ожидались переменные %s, получено %s", want, got)
+	}
+
+	// Переменная модуля общая для всех, кто его подключил
+	_, out, err = runWithUnits(t, "USES Base, Report; BEGIN Tick; Show(Red); WriteLn(Count) END.", dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if want := "Base\ncolor 0\n2\n"; out != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out)
+	}
+}
+
+// TestUnitScopes тестирует видимость имен: скрытые имена раздела IMPLEMENTATION,
+// собственные имена программы и порядок модулей в USES
+func TestUnitScopes(t *testing.T) {
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{
+		"base.pas": baseUnit,
+		"first.pas": `UNIT First; INTERFACE CONST Name = 'first'; FUNCTION Which: INTEGER;
+IMPLEMENTATION FUNCTION Which: INTEGER; BEGIN Which := 1 END; END.`,
+		"second.pas": `UNIT Second; INTERFACE CONST Name = 'second'; FUNCTION Which: INTEGER;
+IMPLEMENTATION FUNCTION Helper: INTEGER; BEGIN Helper := 2 END;
+FUNCTION Which: INTEGER; BEGIN Which := Helper END; END.`,
+	})
+
+	// Модуль, указанный позже, скрывает одноименные имена предыдущих
+	interpreter, _, err := runWithUnits(t, "USES First, Second; VAR s: STRING; n: INTEGER; BEGIN s := Name; n := Which END.", dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if got := formatVariables(interpreter.Values()); got != "{n: 2, s: 'second'}" {
+		t.Errorf("Ожидались имена модуля Second, получено %s", got)
+	}
+
+	// Собственное описание программы скрывает имя модуля
+	interpreter, _, err = runWithUnits(t, "USES Base; CONST Scale = 3; VAR x: INTEGER; BEGIN x := Twice(Scale) END.", dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if got := formatVariables(interpreter.Values()); got != "{x: 6}" {
+		t.Errorf("Ожидалось {x: 6}, получено %s", got)
+	}
+
+	// Имена раздела IMPLEMENTATION не экспортируются
+	program, err := parseWithUnits(t, "USES Second; VAR n: INTEGER; BEGIN n := Helper() + Which END.", dir)
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if err := NewChecker().Check(program); err == nil || !strings.Contains(err.Error(), "Helper") {
+		t.Errorf("Ожидалась ошибка скрытой функции Helper, получено %v", err)
+	}
+	program, err = parseWithUnits(t, "USES Base; BEGIN hidden := 5; Tick END.", dir)
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if err := NewChecker().Check(program); err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	interpreter = NewInterpreterWithOptions(Options{Stdout: &bytes.Buffer{}})
+	if err := interpreter.Interpret(program); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	// Присваивание создает переменную программы, а не изменяет скрытую переменную модуля
+	if got := formatVariables(interpreter.Values()); got != "{hidden: 5}" {
+		t.Errorf("Ожидалось {hidden: 5}, получено %s", got)
+	}
+}
+
+// TestUnitImplementationUses тестирует предложение USES раздела IMPLEMENTATION: модули
+// загружаются и инициализируются раньше модуля, но видны только его реализации
+func TestUnitImplementationUses(t *testing.T) {
+	const code = `UNIT Stats;
+INTERFACE
+FUNCTION Total(n: INTEGER): INTEGER;
+IMPLEMENTATION
+USES Base;
+FUNCTION Total(n: INTEGER): INTEGER;
+BEGIN
+  Tick();
+  Total := Twice(n) * Scale
+END;
+BEGIN
+  WriteLn('Stats ', Count)
+END.`
+
+	// Без загрузчика имена запоминаются в ImplUses, но модули не загружаются
+	tokens, err := NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	unit, err := NewParser(tokens).ParseUnit()
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if len(unit.Uses) != 0 || len(unit.ImplUses) != 1 || unit.ImplUses[0].Name != "Base" || unit.ImplUses[0].Unit != nil {
+		t.Errorf("Ожидалось незагруженное имя Base в USES раздела IMPLEMENTATION, получено %v и %v", unit.Uses, unit.ImplUses)
+	}
+	if pos := unit.ImplUses[0].Pos; pos.Line != 5 || pos.Column != 6 {
+		t.Errorf("Ожидалась позиция строка 5, столбец 6, получено %s", pos)
+	}
+
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{"base.pas": baseUnit, "stats.pas": code})
+	interpreter, out, err := runWithUnits(t, "USES Stats; VAR x: INTEGER; BEGIN x := Total(2) END.", dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if want := "Base\nStats 0\n"; out != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out)
+	}
+	if got := formatVariables(interpreter.Values()); got != "{x: 40}" {
+		t.Errorf("Ожидалось {x: 40}, получено %s", got)
+	}
+
+	// Имена модуля из USES раздела IMPLEMENTATION не видны тем, кто подключил Stats
+	program, err := parseWithUnits(t, "USES Stats; VAR x: INTEGER; BEGIN x := Twice(2) END.", dir)
+	if err != nil {
+		t.Fatalf("Ошибка синтаксического анализа: %v", err)
+	}
+	if err := NewChecker().Check(program); err == nil || !strings.Contains(err.Error(), "Twice") {
+		t.Errorf("Ожидалась ошибка функции Twice, скрытой в реализации Stats, получено %v", err)
+	}
+}
+
+// TestUnitLoader тестирует поиск файлов модулей, кэш и обнаружение циклов
+func TestUnitLoader(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeUnits(t, first, map[string]string{
+		"a.pas":     "UNIT A; INTERFACE USES B; IMPLEMENTATION END.",
+		"b.pas":     "UNIT B; INTERFACE USES C; IMPLEMENTATION END.",
+		"c.pas":     "UNIT C; INTERFACE USES A; IMPLEMENTATION END.",
+		"self.pas":  "UNIT Self; INTERFACE USES Self; IMPLEMENTATION END.",
+		"wrong.pas": "UNIT Other; INTERFACE IMPLEMENTATION END.",
+		"bad.pas":   "UNIT Bad; INTERFACE VAR x INTEGER; IMPLEMENTATION END.",
+		"outer.pas": "UNIT Outer; INTERFACE USES Missing; IMPLEMENTATION END.",
+	})
+	writeUnits(t, second, map[string]string{
+		"MixedCase.pas": "UNIT MixedCase; INTERFACE CONST Where = 2; IMPLEMENTATION END.",
+		"self.pas":      "UNIT Self; INTERFACE CONST Where = 2; IMPLEMENTATION END.",
+	})
+
+	loader := NewUnitLoader(first, second)
+	unit, err := loader.LoadUnit("MixedCase", Position{Line: 1, Column: 6})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if unit.Name != "MixedCase" {
+		t.Errorf("Ожидался модуль MixedCase, получено %s", unit.Name)
+	}
+	again, err := loader.LoadUnit("MIXEDCASE", Position{Line: 2, Column: 6})
+	if err != nil || again != unit {
+		t.Errorf("Повторная загрузка должна вернуть модуль из кэша, получено %p, %v", again, err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"A", "циклическая зависимость модулей: A -> B -> C -> A"},
+		{"Self", "циклическая зависимость модулей: Self -> Self"},
+		{"Wrong", "содержит модуль Other, а не Wrong"},
+		{"Bad", filepath.Join(first, "bad.pas") + ": ожидалось ':' в описании переменных"},
+		{"Outer", filepath.Join(first, "outer.pas") + ": строка 1, столбец 28: модуль Missing не найден"},
+		{"Nowhere", "строка 1, столбец 6: модуль Nowhere не найден (каталоги поиска: " + first + ", " + second + ")"},
+	}
+	for _, tt := range tests {
+		_, err := loader.LoadUnit(tt.name, Position{Line: 1, Column: 6})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", tt.name, tt.want, err)
+		}
+	}
+	// Ошибочный модуль не попадает в кэш: загрузка снова сообщает об ошибке
+	if _, err := loader.LoadUnit("A", Position{}); err == nil {
+		t.Error("Ожидалась ошибка повторной загрузки модуля A")
+	}
+}
+
+// TestUnitErrors тестирует ошибки разбора и семантического анализа модулей
+func TestUnitErrors(t *testing.T) {
+	parseTests := []struct {
+		code string
+		want string
+	}{
+		{"UNIT; INTERFACE IMPLEMENTATION END.", "ожидалось имя модуля"},
+		{"UNIT U INTERFACE IMPLEMENTATION END.", "ожидалась ';' после заголовка модуля"},
+		{"UNIT U; IMPLEMENTATION END.", "ожидался раздел INTERFACE"},
+		{"UNIT U; INTERFACE PROCEDURE P; END.", "ожидался раздел IMPLEMENTATION"},
+		{"UNIT U; INTERFACE PROCEDURE P; BEGIN END; IMPLEMENTATION END.", "ожидался раздел IMPLEMENTATION"},
+		{"UNIT U; INTERFACE IMPLEMENTATION VAR x: INTEGER;", "ожидался END модуля"},
+		{"UNIT U; INTERFACE IMPLEMENTATION END", "ожидалась точка"},
+		{"UNIT U; INTERFACE USES A, A; IMPLEMENTATION END.", "модуль A повторно указан в USES"},
+		{"UNIT U; INTERFACE USES A IMPLEMENTATION END.", "ожидалась ';' после списка модулей"},
+		{"UNIT U; INTERFACE USES A; IMPLEMENTATION USES B, A; END.", "модуль A повторно указан в USES"},
+		{"UNIT U; INTERFACE IMPLEMENTATION USES B END.", "ожидалась ';' после списка модулей"},
+		{"PROGRAM P; BEGIN END.", "ожидалось UNIT"},
+	}
+	for _, tt := range parseTests {
+		tokens, err := NewLexer(tt.code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", tt.code, err)
+		}
+		_, err = NewParser(tokens).ParseUnit()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+	tokens, err := NewLexer("UNIT U; INTERFACE IMPLEMENTATION END.").Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	if _, err := NewParser(tokens).Parse(); err == nil || !strings.Contains(err.Error(), "ожидалась программа, а не модуль") {
+		t.Errorf("Ожидалась ошибка разбора модуля как программы, получено %v", err)
+	}
+
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{
+		"missing.pas":  "UNIT Missing;\nINTERFACE\nPROCEDURE P;\nIMPLEMENTATION\nEND.",
+		"mismatch.pas": "UNIT Mismatch;\nINTERFACE\nFUNCTION F(x: INTEGER): INTEGER;\nIMPLEMENTATION\nFUNCTION F(VAR x: INTEGER): INTEGER; BEGIN F := x END;\nEND.",
+		"typo.pas":     "UNIT Typo;\nINTERFACE\nVAR x: Unknown;\nIMPLEMENTATION\nEND.",
+		"twice.pas":    "UNIT Twice;\nINTERFACE\nPROCEDURE P;\nIMPLEMENTATION\nPROCEDURE P; BEGIN END;\nPROCEDURE P; BEGIN END;\nEND.",
+	})
+	checkTests := []struct {
+		name string
+		want string
+	}{
+		{"Missing", filepath.Join(dir, "missing.pas") + ": строка 3, столбец 11: подпрограмма P описана в разделе INTERFACE модуля Missing, но не реализована"},
+		{"Mismatch", filepath.Join(dir, "mismatch.pas") + ": строка 5, столбец 10: заголовок F не совпадает с описанием в разделе INTERFACE"},
+		{"Typo", filepath.Join(dir, "typo.pas") + ": строка 3, столбец 5: неизвестный тип Unknown"},
+		{"Twice", "повторное описание P"},
+	}
+	for _, tt := range checkTests {
+		program, err := parseWithUnits(t, "USES "+tt.name+"; BEGIN END.", dir)
+		if err != nil {
+			t.Fatalf("%s: ошибка синтаксического анализа: %v", tt.name, err)
+		}
+		err = NewChecker().Check(program)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Без загрузчика имена из USES запоминаются, но модули не загружаются
+	program := parseCode(t, "USES Base; BEGIN END.")
+	if len(program.Uses) != 1 || program.Uses[0].Name != "Base" || program.Uses[0].Unit != nil {
+		t.Errorf("Ожидалось незагруженное имя Base в USES, получено %v", program.Uses)
+	}
+	if err := NewChecker().Check(program); err == nil || !strings.Contains(err.Error(), "модуль Base не загружен") {
+		t.Errorf("Ожидалась ошибка незагруженного модуля, получено %v", err)
+	}
+	if err := NewInterpreter().Interpret(program); err == nil || !strings.Contains(err.Error(), "модуль Base не загружен") {
+		t.Errorf("Ожидалась ошибка выполнения незагруженного модуля, получено %v", err)
+	}
+}
+
+// TestUnitRuntime тестирует ошибки выполнения в модулях: позиция указывает файл модуля,
+// а стек вызовов - раздел инициализации модуля
+func TestUnitRuntime(t *testing.T) {
+	dir := t.TempDir()
+	writeUnits(t, dir, map[string]string{
+		"calc.pas": "UNIT Calc;\nINTERFACE\nFUNCTION Divide(a, b: INTEGER): INTEGER;\nIMPLEMENTATION\nFUNCTION Divide(a, b: INTEGER): INTEGER;\nBEGIN\n  Divide := a DIV b\nEND;\nEND.",
+		"boot.pas": "UNIT Boot;\nINTERFACE\nUSES Calc;\nVAR Ready: INTEGER;\nIMPLEMENTATION\nINITIALIZATION\n  Ready := Divide(1, 0)\nEND.",
+		"quit.pas": "UNIT Quit;\nINTERFACE\nVAR Stage: INTEGER;\nIMPLEMENTATION\nBEGIN\n  Stage := 1;\n  Exit;\n  Stage := 2\nEND.",
+	})
+
+	_, _, err := runWithUnits(t, "USES Calc; VAR x: INTEGER;\nBEGIN\n  x := Divide(1, 0)\nEND.", dir)
+	want := "ошибка выполнения: " + filepath.Join(dir, "calc.pas") + ": строка 7, столбец 15: деление на ноль (EDivByZero)\n" +
+		"стек вызовов:\n  Divide (" + filepath.Join(dir, "calc.pas") + ": строка 7, столбец 15)\n  основная программа (строка 3, столбец 8)"
+	if err == nil || "ошибка выполнения: "+describeError(err) != want {
+		t.Errorf("Ожидалась ошибка\n%s\nполучено\n%v", want, describeError(err))
+	}
+
+	_, _, err = runWithUnits(t, "PROGRAM P; USES Boot; BEGIN END.", dir)
+	if err == nil || !strings.Contains(describeError(err), "\n  Boot ("+filepath.Join(dir, "boot.pas")+": строка 7, столбец 12)") {
+		t.Errorf("Ожидался раздел инициализации Boot в стеке вызовов, получено %v", describeError(err))
+	}
+
+	// Exit завершает только раздел инициализации модуля
+	interpreter, _, err := runWithUnits(t, "USES Quit; VAR s: INTEGER; BEGIN s := Stage END.", dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if got := formatVariables(interpreter.Values()); got != "{s: 1}" {
+		t.Errorf("Ожидалось {s: 1}, получено %s", got)
+	}
+}
+
+// TestMainUnitsFlag тестирует поиск модулей в каталоге программы и в каталогах -units
+func TestMainUnitsFlag(t *testing.T) {
+	root, lib := t.TempDir(), t.TempDir()
+	writeUnits(t, root, map[string]string{
+		"prog.pas":  "USES Local, Lib; VAR x: INTEGER; BEGIN x := L + M END.",
+		"local.pas": "UNIT Local; INTERFACE CONST L = 1; IMPLEMENTATION END.",
+		"cycle.pas": "USES Loop; BEGIN END.",
+	})
+	writeUnits(t, lib, map[string]string{
+		"lib.pas":  "UNIT Lib; INTERFACE CONST M = 41; IMPLEMENTATION END.",
+		"loop.pas": "UNIT Loop; INTERFACE USES Loop; IMPLEMENTATION END.",
+	})
+
+	oldArgs, oldStdout, oldStderr := os.Args, os.Stdout, os.Stderr
+	defer func() { os.Args, os.Stdout, os.Stderr = oldArgs, oldStdout, oldStderr }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = w, w
+	os.Args = []string{"pascal", "-units", lib, filepath.Join(root, "prog.pas")}
+	exitCode := mainWithExitCode()
+	os.Args = []string{"pascal", "-units", lib, filepath.Join(root, "cycle.pas")}
+	cycleCode := mainWithExitCode()
+	w.Close()
+	var out bytes.Buffer
+	out.ReadFrom(r)
+
+	if exitCode != 0 || cycleCode != 1 {
+		t.Errorf("Ожидались коды выхода 0 и 1, получено %d и %d", exitCode, cycleCode)
+	}
+	want := "{x: 42}\nошибка загрузки модуля: " + filepath.Join(lib, "loop.pas") +
+		": строка 1, столбец 27: циклическая зависимость модулей: Loop -> Loop\n"
+	if out.String() != want {
+		t.Errorf("Ожидался вывод %q, получено %q", want, out.String())
+	}
+}
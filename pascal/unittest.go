@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// testPrefix - начало имени тестовой процедуры программы
+const testPrefix = "test"
+
+// unitTests возвращает тестовые процедуры программы в порядке описания: процедуры
+// верхнего уровня, имена которых начинаются с Test. Функции с такими именами тестами
+// не считаются.
+func unitTests(program *Program) []*RoutineDecl {
+	var tests []*RoutineDecl
+	for _, decl := range program.Routines {
+		if !decl.IsFunction() && decl.Body != nil && strings.HasPrefix(strings.ToLower(decl.Name), testPrefix) {
+			tests = append(tests, decl)
+		}
+	}
+	return tests
+}
+
+// runUnitTests загружает программу и выполняет ее тестовые процедуры пулом из options.jobs
+// горутин. Каждая процедура выполняется в новом интерпретаторе: глобальные переменные
+// и модули программы инициализируются заново, а тело программы не выполняется.
+func runUnitTests(file string, options goldenOptions) ([]testResult, error) {
+	program, err := loadChecked(file, options.units, DialectLenient, NewChecker())
+	if err != nil {
+		return nil, err
+	}
+	tests := unitTests(program)
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("в программе %s нет тестовых процедур Test*", file)
+	}
+
+	results := make([]testResult, len(tests))
+	jobs := make(chan int)
+	var workers sync.WaitGroup
+	for w := 0; w < max(1, options.jobs); w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for n := range jobs {
+				results[n] = runUnitTest(file, program, tests[n], options)
+			}
+		}()
+	}
+	for n := range tests {
+		jobs <- n
+	}
+	close(jobs)
+	workers.Wait()
+	return results, nil
+}
+
+// runUnitTest выполняет тестовую процедуру test вместо тела программы. Тест не пройден,
+// если процедура завершилась ошибкой выполнения, например невыполненным Assert;
+// тогда к ошибке добавляется вывод теста.
+func runUnitTest(file string, program *Program, test *RoutineDecl, options goldenOptions) (result testResult) {
+	start := time.Now()
+	result = testResult{Name: test.Name}
+	defer func() { result.Duration = time.Since(start) }()
+
+	if len(test.Params) > 0 {
+		result.Failure = fmt.Sprintf("%s: тестовая процедура %s не должна иметь параметров", test.Pos, test.Name)
+		return result
+	}
+	entry := *program
+	entry.Statements = []Statement{&CallStatement{Name: test.Name, Pos: test.Pos}}
+
+	var stdout bytes.Buffer
+	interpreter := NewInterpreterWithOptions(Options{
+		Stdin:  strings.NewReader(""),
+		Stdout: &stdout,
+		Files:  NewOverlayFS(os.DirFS(filepath.Dir(file))),
+	})
+	if err := interpretWithTimeout(interpreter, &entry, options.timeout); err != nil {
+		// Последняя строка стека - вызов теста, которого нет в тексте программы
+		var runtimeErr *RuntimeError
+		if errors.As(err, &runtimeErr) && len(runtimeErr.Trace) > 0 {
+			runtimeErr.Trace = runtimeErr.Trace[:len(runtimeErr.Trace)-1]
+		}
+		result.Failure = describeTestError(err) + "\n"
+		if stdout.Len() > 0 {
+			result.Failure += "вывод теста:\n" + stdout.String()
+		}
+		return result
+	}
+	result.Passed = true
+	return result
+}
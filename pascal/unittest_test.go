@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unitTestProgram - программа с тестовыми процедурами. Каждый тест изменяет глобальную
+// переменную и проверяет, что она получила начальное значение заново.
+const unitTestProgram = `PROGRAM Tests;
+VAR counter: INTEGER;
+
+FUNCTION Square(x: INTEGER): INTEGER;
+BEGIN
+  Square := x * x
+END;
+
+FUNCTION TestHelper: INTEGER;
+BEGIN
+  TestHelper := 1
+END;
+
+PROCEDURE Helper;
+BEGIN
+  counter := counter + 1;
+  Assert(counter = 1, 'состояние не сброшено')
+END;
+
+PROCEDURE TestSquare;
+BEGIN
+  Assert(Square(3) = 9);
+  Helper
+END;
+
+PROCEDURE testFresh;
+BEGIN
+  Helper
+END;
+
+PROCEDURE TestFails;
+BEGIN
+  WriteLn('перед проверкой');
+  Helper;
+  Assert(Square(2) = 5, 'Square(2) <> 5')
+END;
+
+PROCEDURE TestCrash;
+BEGIN
+  counter := 1 DIV (counter - counter)
+END;
+
+PROCEDURE TestLoop;
+BEGIN
+  WHILE TRUE DO counter := counter + 1
+END;
+
+PROCEDURE TestSpin;
+BEGIN
+  REPEAT UNTIL FALSE
+END;
+
+PROCEDURE TestArgs(n: INTEGER);
+BEGIN
+  Helper
+END;
+
+BEGIN
+  WriteLn('тело программы');
+  Assert(FALSE)
+END.`
+
+// TestUnitTests тестирует поиск и выполнение тестовых процедур
+func TestUnitTests(t *testing.T) {
+	dir := t.TempDir()
+	writeSuite(t, dir, map[string]string{"tests.pas": unitTestProgram})
+
+	results, err := runUnitTests(filepath.Join(dir, "tests.pas"), goldenOptions{jobs: 3, timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("ошибка выполнения тестов: %v", err)
+	}
+	expected := []struct {
+		name    string
+		passed  bool
+		failure string
+	}{
+		{"TestSquare", true, ""},
+		{"testFresh", true, ""},
+		{"TestFails", false, "ошибка выполнения: строка 35, столбец 3: Square(2) <> 5 (EAssertionFailed)\nстек вызовов:\n" +
+			"  TestFails (строка 35, столбец 3)\nвывод теста:\nперед проверкой\n"},
+		{"TestCrash", false, "ошибка выполнения: строка 40, столбец 16: деление на ноль (EDivByZero)\nстек вызовов:\n" +
+			"  TestCrash (строка 40, столбец 16)\n"},
+		{"TestLoop", false, "превышено время выполнения 100ms\n"},
+		{"TestSpin", false, "превышено время выполнения 100ms\n"},
+		{"TestArgs", false, "строка 53, столбец 11: тестовая процедура TestArgs не должна иметь параметров"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("ожидалось результатов: %d, получено %d", len(expected), len(results))
+	}
+	for n, want := range expected {
+		got := results[n]
+		if got.Name != want.name || got.Passed != want.passed || got.Failure != want.failure {
+			t.Errorf("ожидался результат %s %v %q, получено %s %v %q", want.name, want.passed, want.failure, got.Name, got.Passed, got.Failure)
+		}
+	}
+
+	writeSuite(t, dir, map[string]string{
+		"none.pas":   "PROCEDURE Check; BEGIN END; BEGIN Check END.",
+		"broken.pas": "PROCEDURE TestA; BEGIN Assert(1) END; BEGIN END.",
+	})
+	for file, want := range map[string]string{
+		"none.pas":    "нет тестовых процедур Test*",
+		"broken.pas":  "условие оператора Assert должно быть логического типа",
+		"missing.pas": "missing.pas",
+	} {
+		if _, err := runUnitTests(filepath.Join(dir, file), goldenOptions{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", file, want, err)
+		}
+	}
+}
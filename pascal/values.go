@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// Value представляет значение времени выполнения
+type Value interface {
+	Kind() ValueKind
+	String() string
+}
+
+// ValueKind представляет вид значения
+type ValueKind int
+
+const (
+	KindInteger ValueKind = iota
+	KindReal
+	KindBoolean
+	KindEnum
+	KindSet
+	KindPointer
+	KindRecord
+	KindChar
+	KindString
+	KindFile
+	KindException
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case KindInteger:
+		return "INTEGER"
+	case KindReal:
+		return "REAL"
+	case KindBoolean:
+		return "BOOLEAN"
+	case KindEnum:
+		return "перечисление"
+	case KindSet:
+		return "множество"
+	case KindPointer:
+		return "указатель"
+	case KindRecord:
+		return "запись"
+	case KindChar:
+		return "CHAR"
+	case KindString:
+		return "STRING"
+	case KindFile:
+		return "файл"
+	case KindException:
+		return "исключение"
+	default:
+		return fmt.Sprintf("ValueKind(%d)", int(k))
+	}
+}
+
+// IntegerValue представляет целое значение
+type IntegerValue int64
+
+func (v IntegerValue) Kind() ValueKind { return KindInteger }
+func (v IntegerValue) String() string  { return fmt.Sprintf("%d", int64(v)) }
+
+// RealValue представляет вещественное значение
+type RealValue float64
+
+func (v RealValue) Kind() ValueKind { return KindReal }
+
+// String выводит целое вещественное число без дробной части, как и раньше
+func (v RealValue) String() string {
+	value := float64(v)
+	if value == math.Trunc(value) && math.Abs(value) < 1e18 {
+		return fmt.Sprintf("%d", int64(value))
+	}
+	return fmt.Sprintf("%g", value)
+}
+
+// BooleanValue представляет логическое значение
+type BooleanValue bool
+
+func (v BooleanValue) Kind() ValueKind { return KindBoolean }
+func (v BooleanValue) String() string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// EnumValue представляет значение перечислимого типа: порядковый номер имени в описании типа
+type EnumValue struct {
+	Type    *Type
+	Ordinal int64
+}
+
+func (v EnumValue) Kind() ValueKind { return KindEnum }
+func (v EnumValue) String() string {
+	if v.Ordinal >= 0 && v.Ordinal < int64(len(v.Type.Values)) {
+		return v.Type.Values[v.Ordinal]
+	}
+	return fmt.Sprintf("%s(%d)", v.Type, v.Ordinal)
+}
+
+// CharValue представляет символ. Порядковые номера символов ограничены диапазоном 0..255,
+// поэтому символы могут быть элементами множеств (SET OF CHAR)
+type CharValue rune
+
+func (v CharValue) Kind() ValueKind { return KindChar }
+
+// String выводит символ в апострофах, а непечатаемый символ - в виде #код
+func (v CharValue) String() string {
+	if !unicode.IsPrint(rune(v)) {
+		return fmt.Sprintf("#%d", int64(v))
+	}
+	return StringValue(string(rune(v))).String()
+}
+
+// StringValue представляет строку
+type StringValue string
+
+func (v StringValue) Kind() ValueKind { return KindString }
+
+// String выводит строку в апострофах, удваивая апострофы внутри нее, как в исходном тексте
+func (v StringValue) String() string {
+	return "'" + strings.ReplaceAll(string(v), "'", "''") + "'"
+}
+
+// maxSetOrdinal - наибольший порядковый номер элемента множества
+const maxSetOrdinal = 255
+
+// SetValue представляет значение множества битовой шкалой порядковых номеров 0..255
+type SetValue struct {
+	Elem *Type // базовый тип элементов; nil для пустого множества [], совместимого с любым
+	Bits [(maxSetOrdinal + 1) / 64]uint64
+}
+
+func (v SetValue) Kind() ValueKind { return KindSet }
+
+// String выводит элементы по возрастанию, объединяя три и более подряд идущих в диапазон
+func (v SetValue) String() string {
+	members := v.members()
+	var parts []string
+	for n := 0; n < len(members); {
+		end := n
+		for end+1 < len(members) && members[end+1] == members[end]+1 {
+			end++
+		}
+		if end-n >= 2 {
+			parts = append(parts, fmt.Sprintf("%s..%s", v.element(members[n]), v.element(members[end])))
+		} else {
+			for k := n; k <= end; k++ {
+				parts = append(parts, v.element(members[k]).String())
+			}
+		}
+		n = end + 1
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// element возвращает элемент множества с порядковым номером ordinal
+func (v SetValue) element(ordinal int64) Value {
+	if v.Elem == nil {
+		return IntegerValue(ordinal)
+	}
+	return ordinalValue(v.Elem, ordinal)
+}
+
+// members возвращает порядковые номера элементов по возрастанию
+func (v SetValue) members() []int64 {
+	var result []int64
+	for word, w := range v.Bits {
+		for ; w != 0; w &= w - 1 {
+			result = append(result, int64(word*64+bits.TrailingZeros64(w)))
+		}
+	}
+	return result
+}
+
+// contains сообщает, входит ли в множество элемент с порядковым номером ordinal
+func (v SetValue) contains(ordinal int64) bool {
+	if ordinal < 0 || ordinal > maxSetOrdinal {
+		return false
+	}
+	return v.Bits[ordinal/64]&(1<<(ordinal%64)) != 0
+}
+
+// include добавляет элемент; порядковый номер должен быть в диапазоне 0..maxSetOrdinal
+func (v *SetValue) include(ordinal int64) {
+	v.Bits[ordinal/64] |= 1 << (ordinal % 64)
+}
+
+// combine применяет op к битовым шкалам двух множеств: объединение, пересечение или разность
+func (v SetValue) combine(w SetValue, op func(a, b uint64) uint64) SetValue {
+	result := SetValue{Elem: v.Elem}
+	if result.Elem == nil {
+		result.Elem = w.Elem
+	}
+	for n := range result.Bits {
+		result.Bits[n] = op(v.Bits[n], w.Bits[n])
+	}
+	return result
+}
+
+// subsetOf сообщает, является ли v подмножеством w
+func (v SetValue) subsetOf(w SetValue) bool {
+	for n := range v.Bits {
+		if v.Bits[n]&^w.Bits[n] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PointerValue представляет значение указателя: адрес динамической переменной в куче
+// интерпретатора; нулевой адрес - NIL
+type PointerValue struct {
+	Elem *Type // тип динамической переменной; nil для NIL, совместимого с любым указателем
+	Addr int
+}
+
+func (v PointerValue) Kind() ValueKind { return KindPointer }
+func (v PointerValue) String() string {
+	if v.Addr == 0 {
+		return "NIL"
+	}
+	return fmt.Sprintf("@%d", v.Addr)
+}
+
+// RecordValue представляет значение записи. Поля изменяются на месте,
+// поэтому при присваивании запись копируется функцией copyValue.
+type RecordValue struct {
+	Type   *Type
+	Fields []Value // в порядке описания полей в типе
+}
+
+func (v *RecordValue) Kind() ValueKind { return KindRecord }
+func (v *RecordValue) String() string {
+	parts := make([]string, len(v.Fields))
+	for n, field := range v.Fields {
+		parts[n] = fmt.Sprintf("%s: %s", v.Type.Fields[n].Name, field)
+	}
+	return "(" + strings.Join(parts, "; ") + ")"
+}
+
+// copyValue возвращает независимую копию значения: записи копируются вместе с вложенными записями
+func copyValue(v Value) Value {
+	record, ok := v.(*RecordValue)
+	if !ok {
+		return v
+	}
+	fields := make([]Value, len(record.Fields))
+	for n, field := range record.Fields {
+		fields[n] = copyValue(field)
+	}
+	return &RecordValue{Type: record.Type, Fields: fields}
+}
+
+// toReal приводит числовое значение к вещественному
+func toReal(v Value) (float64, bool) {
+	switch n := v.(type) {
+	case IntegerValue:
+		return float64(n), true
+	case BigIntValue:
+		f, _ := new(big.Float).SetInt(n.Int).Float64()
+		return f, true
+	case RealValue:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// ordinalOf возвращает порядковый номер значения порядкового типа
+func ordinalOf(v Value) (int64, bool) {
+	switch o := v.(type) {
+	case IntegerValue:
+		return int64(o), true
+	case BooleanValue:
+		if o {
+			return 1, true
+		}
+		return 0, true
+	case EnumValue:
+		return o.Ordinal, true
+	case CharValue:
+		return int64(o), true
+	default:
+		return 0, false
+	}
+}
+
+// textOf возвращает текст символа или строки
+func textOf(v Value) (string, bool) {
+	switch t := v.(type) {
+	case CharValue:
+		return string(rune(t)), true
+	case StringValue:
+		return string(t), true
+	default:
+		return "", false
+	}
+}
+
+// toFloat возвращает числовое представление значения для GetVariables
+func toFloat(v Value) float64 {
+	switch n := v.(type) {
+	case BooleanValue:
+		if n {
+			return 1
+		}
+		return 0
+	case EnumValue:
+		return float64(n.Ordinal)
+	case CharValue:
+		return float64(n)
+	default:
+		f, _ := toReal(v)
+		return f
+	}
+}
+
+// addInt, subInt и mulInt выполняют целочисленные операции с контролем переполнения
+func addInt(a, b int64) (int64, bool) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, false
+	}
+	return c, true
+}
+
+func subInt(a, b int64) (int64, bool) {
+	c := a - b
+	if (c < a) != (b > 0) {
+		return 0, false
+	}
+	return c, true
+}
+
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
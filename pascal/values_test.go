@@ -0,0 +1,369 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// evaluateCode вычисляет выражение expr в программе с описаниями decls
+func evaluateCode(t *testing.T, decls, expr string) (Value, error) {
+	t.Helper()
+	interpreter, err := interpretCode(t, decls+" BEGIN result := "+expr+" END.")
+	if err != nil {
+		return nil, err
+	}
+	return interpreter.Values()["result"], nil
+}
+
+// TestSetOperations тестирует конструкторы множеств, IN, объединение, пересечение и разность
+func TestSetOperations(t *testing.T) {
+	const code = `CONST
+	Vowels = [1, 5, 9, 15, 21];
+TYPE
+	TDay = (Mon, Tue, Wed, Thu, Fri, Sat, Sun);
+	TDays = SET OF TDay;
+VAR
+	week, work, none: TDays;
+	digits: SET OF 0..9;
+	flags: SET OF BOOLEAN;
+BEGIN
+	week := [Mon..Sun];
+	work := week - [Sat, Sun];
+	isWork := Wed IN work;
+	isWeekend := Sun IN work;
+	common := work * [Fri..Sun];
+	digits := [1, 3..5, 9] + [2];
+	n := 7;
+	computed := [n, n - 6, 2 * n] + [];
+	flags := [TRUE];
+	letters := Vowels;
+	reversed := [5..1]
+END.`
+	for _, run := range []func(*testing.T, string) (*Interpreter, error){interpretCode, runChecked} {
+		interpreter, err := run(t, code)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения: %v", err)
+		}
+		want := "{common: [Fri], computed: [1, 7, 14], digits: [1..5, 9], flags: [TRUE], isWeekend: FALSE, " +
+			"isWork: TRUE, letters: [1, 5, 9, 15, 21], n: 7, none: [], reversed: [], week: [Mon..Sun], work: [Mon..Fri]}"
+		if got := formatVariables(interpreter.Values()); got != want {
+			t.Errorf("Ожидалось %s, получено %s", want, got)
+		}
+	}
+}
+
+// TestSetComparisons тестирует равенство множеств и проверку включения
+func TestSetComparisons(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"[1, 2] = [2, 1]", true},
+		{"[1, 2] <> [1..2]", false},
+		{"[] = []", true},
+		{"[1] = []", false},
+		{"[1, 2] <= [1..3]", true},
+		{"[1..3] <= [1, 2]", false},
+		{"[] <= [7]", true},
+		{"[1..3] >= [2]", true},
+		{"[2] >= [1..3]", false},
+		{"3 IN [1..5]", true},
+		{"0 IN [1..5]", false},
+		{"TRUE IN [FALSE]", false},
+		{"NOT (2 IN [])", true},
+	}
+	for _, tt := range tests {
+		got, err := evaluateCode(t, "", tt.expr)
+		if err != nil {
+			t.Errorf("%s: ошибка выполнения: %v", tt.expr, err)
+		} else if got != BooleanValue(tt.want) {
+			t.Errorf("%s: ожидалось %v, получено %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+// TestRelationalOperators тестирует операции отношения над числами, логическими значениями и перечислениями
+func TestRelationalOperators(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"1 < 2", true},
+		{"2 <= 2", true},
+		{"3 > 4", false},
+		{"4 >= 5", false},
+		{"5 = 5", true},
+		{"5 <> 5", false},
+		{"2.5 > 2", true},
+		{"2 = 2.0", true},
+		{"MAXINT > MAXINT - 1", true},
+		{"FALSE < TRUE", true},
+		{"Red < Blue", true},
+		{"Green = Green", true},
+		{"1 + 2 * 3 = 7", true},
+		{"-1 < 0", true},
+	}
+	for _, tt := range tests {
+		got, err := evaluateCode(t, "TYPE TColor = (Red, Green, Blue);", tt.expr)
+		if err != nil {
+			t.Errorf("%s: ошибка выполнения: %v", tt.expr, err)
+		} else if got != BooleanValue(tt.want) {
+			t.Errorf("%s: ожидалось %v, получено %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+// TestBooleanOperators тестирует AND, OR, NOT и их приоритет
+func TestBooleanOperators(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"TRUE AND FALSE", false},
+		{"TRUE OR FALSE", true},
+		{"NOT TRUE", false},
+		{"NOT FALSE AND FALSE", false},
+		{"NOT (FALSE AND FALSE)", true},
+		{"FALSE OR TRUE AND FALSE", false},
+		{"(1 < 2) AND (2 < 3)", true},
+		// Короткая схема: второй операнд не вычисляется
+		{"FALSE AND (1 / 0 > 1)", false},
+		{"TRUE OR (1 / 0 > 1)", true},
+	}
+	for _, tt := range tests {
+		got, err := evaluateCode(t, "", tt.expr)
+		if err != nil {
+			t.Errorf("%s: ошибка выполнения: %v", tt.expr, err)
+		} else if got != BooleanValue(tt.want) {
+			t.Errorf("%s: ожидалось %v, получено %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+// TestIntegerDivisionOperators тестирует DIV и MOD
+func TestIntegerDivisionOperators(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int64
+	}{
+		{"17 DIV 5", 3},
+		{"17 MOD 5", 2},
+		{"-17 DIV 5", -3},
+		{"-17 MOD 5", -2},
+		{"17 div -5", -3},
+		{"2 + 7 DIV 2 * 3", 11},
+		{"(0 - MAXINT - 1) MOD (0 - 1)", 0},
+	}
+	for _, tt := range tests {
+		got, err := evaluateCode(t, "", tt.expr)
+		if err != nil {
+			t.Errorf("%s: ошибка выполнения: %v", tt.expr, err)
+		} else if got != IntegerValue(tt.want) {
+			t.Errorf("%s: ожидалось %d, получено %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+// TestOperatorRuntimeErrors тестирует ошибки выполнения новых операций
+func TestOperatorRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"1 DIV 0", "деление на ноль"},
+		{"1 MOD 0", "деление на ноль"},
+		{"(0 - MAXINT - 1) DIV (0 - 1)", "целочисленное переполнение"},
+		{"1.5 DIV 2", "операция DIV неприменима к типам REAL и INTEGER"},
+		{"TRUE AND 1", "операция AND неприменима к типам BOOLEAN и INTEGER"},
+		{"NOT 1", "операция NOT неприменима к типу INTEGER"},
+		{"1 IN 5", "операция IN неприменима к типам INTEGER и INTEGER"},
+		{"TRUE IN [1]", "тип BOOLEAN не совпадает с типом элементов множества INTEGER"},
+		{"[1] < [2]", "операция < неприменима к множествам"},
+		{"[1] = 1", "несравнимые типы операндов: SET OF INTEGER и INTEGER"},
+		{"[1] + 1", "несовместимые типы операндов: SET OF INTEGER и INTEGER"},
+		{"[1] / [2]", "операция / неприменима к множествам"},
+		{"1 = TRUE", "несравнимые типы операндов: INTEGER и BOOLEAN"},
+		{"[1, TRUE]", "элементы множества имеют разные типы INTEGER и BOOLEAN"},
+		{"[256]", "элемент множества 256 вне диапазона 0..255"},
+		{"[0 - 1]", "элемент множества -1 вне диапазона 0..255"},
+		{"[1.5]", "элемент множества должен быть порядкового типа, получено REAL"},
+		{"[1 / 0]", "деление на ноль"},
+		{"NOT (1 / 0 > 0)", "деление на ноль"},
+	}
+	for _, tt := range tests {
+		_, err := evaluateCode(t, "", tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ожидалась ошибка %q, получено %v", tt.expr, tt.want, err)
+		}
+	}
+
+	interpreter := NewInterpreter()
+	if _, err := interpreter.evaluateUnary(&UnaryOp{Operator: TokenMINUS}, IntegerValue(1)); err == nil {
+		t.Error("Ожидалась ошибка для неизвестной унарной операции")
+	}
+}
+
+// TestCheckerSetsAndOperators тестирует статическую проверку множеств и операций
+func TestCheckerSetsAndOperators(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"VAR s: SET OF INTEGER; BEGIN END.", "базовый тип множества INTEGER должен иметь порядковые номера в диапазоне 0..255"},
+		{"VAR s: SET OF -1..5; BEGIN END.", "в диапазоне 0..255"},
+		{"VAR s: SET OF REAL; BEGIN END.", "базовый тип множества должен быть порядковым, получен REAL"},
+		{"VAR s: SET OF Foo; BEGIN END.", "неизвестный тип Foo"},
+		{"VAR s: SET OF 1..5; BEGIN s := [6] END.", "значение [6] вне диапазона 1..5 переменной s"},
+		{"TYPE T = (A, B); VAR s: SET OF T; BEGIN s := [1] END.", "нельзя присвоить SET OF INTEGER переменной s типа SET OF T"},
+		{"VAR s: SET OF BOOLEAN; BEGIN s := 1 END.", "нельзя присвоить INTEGER"},
+		{"BEGIN x := [1, TRUE] END.", "элементы множества имеют разные типы INTEGER и BOOLEAN"},
+		{"BEGIN x := [1.5] END.", "элемент множества должен быть порядкового типа, получено REAL"},
+		{"BEGIN x := [300] END.", "элемент множества 300 вне диапазона 0..255"},
+		{"BEGIN x := [1] < [2] END.", "операция < неприменима к множествам"},
+		{"BEGIN x := [1] / [2] END.", "операция / неприменима к множествам"},
+		{"BEGIN x := [1] + [TRUE] END.", "несовместимые типы операндов: SET OF INTEGER и SET OF BOOLEAN"},
+		{"BEGIN x := [1] = 1 END.", "несравнимые типы операндов"},
+		{"BEGIN x := 1 = TRUE END.", "несравнимые типы операндов: INTEGER и BOOLEAN"},
+		{"BEGIN x := 1 IN 2 END.", "операция IN неприменима к типам INTEGER и INTEGER"},
+		{"BEGIN x := 1.5 IN [1] END.", "операция IN неприменима"},
+		{"BEGIN x := TRUE IN [1] END.", "тип BOOLEAN не совпадает с типом элементов множества INTEGER"},
+		{"BEGIN x := 1 AND TRUE END.", "операция AND неприменима к типу INTEGER"},
+		{"BEGIN x := NOT 1 END.", "операция NOT неприменима к типу INTEGER"},
+		{"BEGIN x := 7 DIV 2.0 END.", "операция DIV неприменима к типу REAL"},
+		{"BEGIN x := 1 + TRUE END.", "арифметическая операция неприменима к типу BOOLEAN"},
+	}
+	for _, tt := range tests {
+		_, err := checkCode(t, tt.code)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: ожидалась ошибка %q, получено %v", tt.code, tt.want, err)
+		}
+	}
+
+	program, err := checkCode(t, "CONST S = [1..3] * [2..5]; BEGIN x := NOT (2 IN S) OR (7 MOD 4 = 3); y := [z]; w := z < 1 END.")
+	if err != nil {
+		t.Fatalf("Ошибка семантического анализа: %v", err)
+	}
+	if literal, ok := program.Statements[0].(*Assignment).Value.(*Literal); !ok || literal.Value != BooleanValue(true) {
+		t.Errorf("Ожидалась свертка в Literal(TRUE), получено %v", program.Statements[0])
+	}
+	if _, ok := program.Statements[1].(*Assignment).Value.(*SetConstructor); !ok {
+		t.Errorf("Конструктор с переменной не должен сворачиваться, получено %v", program.Statements[1])
+	}
+}
+
+// TestRangeCheckSetAssignment тестирует проверку элементов множества при присваивании
+func TestRangeCheckSetAssignment(t *testing.T) {
+	_, err := interpretCode(t, "VAR s: SET OF 1..5; BEGIN n := 7; s := [1, n] END.")
+	if err == nil || !strings.Contains(err.Error(), "значение [1, 7] переменной s вне диапазона 1..5") {
+		t.Errorf("Ожидалась ошибка нарушения диапазона, получено %v", err)
+	}
+	if _, err := interpretCode(t, "VAR s: SET OF 1..5; BEGIN n := 7; {$R-} s := [1, n] END."); err != nil {
+		t.Errorf("При {$R-} ошибки быть не должно, получено %v", err)
+	}
+}
+
+// TestParseOperators тестирует приоритет операций и разбор конструкторов множеств
+func TestParseOperators(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"a + b * c = d", "BinaryOp(BinaryOp(Identifier(a) + BinaryOp(Identifier(b) * Identifier(c))) = Identifier(d))"},
+		{"NOT a AND b", "BinaryOp(UnaryOp(NOT Identifier(a)) AND Identifier(b))"},
+		{"a OR b AND c", "BinaryOp(Identifier(a) OR BinaryOp(Identifier(b) AND Identifier(c)))"},
+		{"x IN [1, 3..5]", "BinaryOp(Identifier(x) IN Set[Number(1), Number(3)..Number(5)])"},
+		{"a DIV b MOD c", "BinaryOp(BinaryOp(Identifier(a) DIV Identifier(b)) MOD Identifier(c))"},
+		{"[] <> []", "BinaryOp(Set[] <> Set[])"},
+	}
+	for _, tt := range tests {
+		program := parseCode(t, "BEGIN r := "+tt.expr+" END.")
+		value := program.Statements[0].(*Assignment).Value
+		if got := value.String(); got != tt.want {
+			t.Errorf("%s: ожидалось %s, получено %s", tt.expr, tt.want, got)
+		}
+	}
+
+	program := parseCode(t, "VAR s: SET OF (A, B); BEGIN END.")
+	if got := program.Vars[0].Type.String(); got != "SET OF (A, B)" {
+		t.Errorf("Ожидался тип SET OF (A, B), получено %s", got)
+	}
+	program.Vars[0].Type.typeNode()
+	(&UnaryOp{}).expressionNode()
+	(&SetConstructor{}).expressionNode()
+
+	for _, code := range []string{
+		"BEGIN x := [1, END.",
+		"BEGIN x := [1 2] END.",
+		"BEGIN x := [1.. ] END.",
+		"BEGIN x := 1 < 2 < 3 END.",
+		"BEGIN x := NOT END.",
+		"VAR s: SET 1; BEGIN END.",
+		"VAR s: SET OF ; BEGIN END.",
+	} {
+		tokens, err := NewLexer(code).Tokenize()
+		if err != nil {
+			t.Fatalf("%q: ошибка лексического анализа: %v", code, err)
+		}
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%q: ожидалась ошибка синтаксического анализа", code)
+		}
+	}
+}
+
+// TestLexerOperators тестирует лексемы операций отношения и скобок множеств
+func TestLexerOperators(t *testing.T) {
+	tokens, err := NewLexer("< <= <> > >= = [ ] in And DIV mod").Tokenize()
+	if err != nil {
+		t.Fatalf("Ошибка лексического анализа: %v", err)
+	}
+	want := []TokenType{TokenLESS, TokenLESSEQUAL, TokenNOTEQUAL, TokenGREATER, TokenGREATEREQUAL, TokenEQUAL,
+		TokenLBRACKET, TokenRBRACKET, TokenIN, TokenAND, TokenDIV, TokenMOD, TokenEOF}
+	if len(tokens) != len(want) {
+		t.Fatalf("Ожидалось %d токенов, получено %d", len(want), len(tokens))
+	}
+	for n, tt := range want {
+		if tokens[n].Type != tt {
+			t.Errorf("Токен %d: ожидался тип %v, получен %v", n, tt, tokens[n].Type)
+		}
+	}
+	if tokens[1].Value != "<=" || tokens[2].Value != "<>" || tokens[4].Value != ">=" {
+		t.Errorf("Неожиданные значения составных токенов: %q %q %q", tokens[1].Value, tokens[2].Value, tokens[4].Value)
+	}
+}
+
+// TestSetValueString тестирует вывод множеств
+func TestSetValueString(t *testing.T) {
+	enum := &Type{Kind: TypeEnum, Values: []string{"A", "B", "C", "D"}}
+	tests := []struct {
+		set  SetValue
+		want string
+	}{
+		{SetValue{}, "[]"},
+		{setOf(nil, 0, 1), "[0, 1]"},
+		{setOf(nil, 0, 1, 2, 5, 7, 8, 9, 255), "[0..2, 5, 7..9, 255]"},
+		{setOf(enum, 0, 2, 3), "[A, C, D]"},
+		{setOf(booleanType, 0, 1), "[FALSE, TRUE]"},
+	}
+	for _, tt := range tests {
+		if got := tt.set.String(); got != tt.want {
+			t.Errorf("Ожидалось %s, получено %s", tt.want, got)
+		}
+	}
+	if KindSet.String() != "множество" || (&Type{Kind: TypeSet}).String() != "[]" {
+		t.Error("Неожиданные имена вида и типа множества")
+	}
+	set := setOf(nil, 3)
+	if set.contains(-1) || set.contains(math.MaxInt64) || !set.contains(3) {
+		t.Error("Неверная проверка принадлежности множеству")
+	}
+}
+
+// setOf создает множество из порядковых номеров
+func setOf(elem *Type, ordinals ...int64) SetValue {
+	set := SetValue{Elem: elem}
+	for _, ordinal := range ordinals {
+		set.include(ordinal)
+	}
+	return set
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchInterval - период опроса файлов программы в режиме -watch
+const watchInterval = 200 * time.Millisecond
+
+// Управляющие последовательности терминала для режима -watch
+const (
+	clearScreen  = "\x1b[H\x1b[2J"
+	changedStyle = "\x1b[1;33m" // значение изменилось или переменная появилась
+	removedStyle = "\x1b[9;31m" // переменной больше нет
+	resetStyle   = "\x1b[0m"
+)
+
+// fileStamp - время изменения и размер файла; нулевое значение - файла нет
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampFiles опрашивает файлы paths через os.Stat
+func stampFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, len(paths))
+	for n, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[n] = fileStamp{info.ModTime(), info.Size()}
+		}
+	}
+	return stamps
+}
+
+// changed сообщает, изменился ли какой-либо из файлов
+func changed(before, after []fileStamp) bool {
+	for n := range before {
+		if !before[n].modTime.Equal(after[n].modTime) || before[n].size != after[n].size {
+			return true
+		}
+	}
+	return false
+}
+
+// watcher выполняет программу заново при каждом изменении ее файла или модулей
+type watcher struct {
+	filename string
+	options  runOptions
+	bindings []Binding
+	out      io.Writer
+
+	runs     int
+	paths    []string          // файлы программы и модулей, которые искал последний разбор
+	previous map[string]string // словарь переменных последнего успешного запуска
+}
+
+// watch выполняет программу и выполняет ее заново при каждом сохранении, пока не закрыт
+// канал stop; nil - до завершения процесса. Перед каждым запуском экран очищается, затем
+// выводятся ошибки анализа или вывод программы и словарь переменных, в котором выделены
+// значения, изменившиеся с предыдущего успешного запуска. Изменения файлов обнаруживаются
+// опросом os.Stat, поэтому режим не зависит от механизмов уведомления ОС. Если файл
+// изменился во время выполнения, программа останавливается и запускается заново.
+func watch(filename string, options runOptions, out io.Writer, stop <-chan struct{}) error {
+	bindings, err := loadBindings(options.input, options.inputData, options.defines, options.bigint)
+	if err != nil {
+		return err
+	}
+	w := &watcher{filename: filename, options: options, bindings: bindings, out: out, paths: []string{filename}}
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		// Файлы опрашиваются до чтения, чтобы не пропустить сохранение во время разбора
+		paths := w.paths
+		stamps := stampFiles(paths)
+		interpreter, done := w.start()
+		if !slices.Equal(paths, w.paths) {
+			stamps = stampFiles(w.paths)
+		}
+		for modified := false; !modified; {
+			select {
+			case <-stop:
+				if done != nil {
+					interrupt(interpreter, done)
+				}
+				return nil
+			case err := <-done:
+				w.finish(interpreter, err)
+				done = nil
+			case <-ticker.C:
+				modified = changed(stamps, stampFiles(w.paths))
+			}
+		}
+		if done != nil {
+			interrupt(interpreter, done)
+		}
+	}
+}
+
+// interrupt останавливает выполнение перед следующим оператором и закрывает файлы,
+// которые остались открытыми: после остановки Interpret их не закрывает
+func interrupt(interpreter *Interpreter, done <-chan error) {
+	interpreter.Suspend()
+	<-done
+	interpreter.closeFiles()
+}
+
+// start очищает экран, загружает программу и запускает ее выполнение. Если программа не
+// прошла анализ, выводится ошибка, а канал завершения равен nil.
+func (w *watcher) start() (*Interpreter, chan error) {
+	w.runs++
+	fmt.Fprint(w.out, clearScreen)
+	fmt.Fprintf(w.out, "%s: запуск %d\n", w.filename, w.runs)
+
+	checker := NewChecker()
+	checker.BigInt = w.options.bigint
+	loader := sourceLoader(w.filename, w.options.units, w.options.dialect, w.options.bigint)
+	program, err := loadWith(w.filename, loader, checker)
+	// Файлы модулей отслеживаются и после ошибки загрузки: исправление модуля или появление
+	// недостающего файла запускает программу заново
+	w.paths = append([]string{w.filename}, loader.Files...)
+	if err != nil {
+		fmt.Fprintln(w.out, err)
+		return nil, nil
+	}
+
+	root := w.options.root
+	if root == "" {
+		root = "."
+	}
+	interpreter := NewInterpreterWithOptions(Options{
+		Stdin:    strings.NewReader(""),
+		Stdout:   w.out,
+		Files:    NewDirFS(root),
+		BigInt:   w.options.bigint,
+		Bindings: w.bindings,
+	})
+	done := make(chan error, 1)
+	go func() { done <- interpreter.Interpret(program) }()
+	return interpreter, done
+}
+
+// finish выводит результат завершившегося выполнения и запоминает словарь переменных
+func (w *watcher) finish(interpreter *Interpreter, err error) {
+	if err != nil {
+		fmt.Fprintf(w.out, "ошибка выполнения: %v\n", describeError(err))
+		return
+	}
+	current := make(map[string]string)
+	for name, value := range interpreter.Values() {
+		current[name] = value.String()
+	}
+	fmt.Fprintln(w.out, formatVariablesDiff(w.previous, current))
+	w.previous = current
+	if code := interpreter.ExitCode(); code != 0 {
+		fmt.Fprintf(w.out, "код выхода %d\n", code)
+	}
+}
+
+// formatVariablesDiff форматирует словарь переменных current, как formatVariables, и
+// выделяет отличия от словаря previous: новые и изменившиеся значения и исчезнувшие
+// переменные. Без предыдущего словаря ничего не выделяется.
+func formatVariablesDiff(previous, current map[string]string) string {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for n, name := range names {
+		old, existed := previous[name]
+		value, exists := current[name]
+		switch {
+		case !exists:
+			parts[n] = fmt.Sprintf("%s%s: %s%s", removedStyle, name, old, resetStyle)
+		case previous != nil && (!existed || old != value):
+			parts[n] = fmt.Sprintf("%s: %s%s%s", name, changedStyle, value, resetStyle)
+		default:
+			parts[n] = fmt.Sprintf("%s: %s", name, value)
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
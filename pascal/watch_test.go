@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFormatVariablesDiff тестирует выделение изменений словаря переменных
+func TestFormatVariablesDiff(t *testing.T) {
+	tests := []struct {
+		previous, current map[string]string
+		want              string
+	}{
+		{nil, map[string]string{"b": "2", "a": "1"}, "{a: 1, b: 2}"},
+		{map[string]string{}, map[string]string{"a": "1"}, "{a: " + changedStyle + "1" + resetStyle + "}"},
+		{map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "1", "b": "3"}, "{a: 1, b: " + changedStyle + "3" + resetStyle + "}"},
+		{map[string]string{"a": "1", "b": "'x'"}, map[string]string{"c": "TRUE", "a": "1"},
+			"{a: 1, " + removedStyle + "b: 'x'" + resetStyle + ", c: " + changedStyle + "TRUE" + resetStyle + "}"},
+		{map[string]string{"a": "1"}, map[string]string{}, "{" + removedStyle + "a: 1" + resetStyle + "}"},
+	}
+	for _, tt := range tests {
+		if got := formatVariablesDiff(tt.previous, tt.current); got != tt.want {
+			t.Errorf("%v -> %v: ожидалось %q, получено %q", tt.previous, tt.current, tt.want, got)
+		}
+	}
+}
+
+// syncBuffer - буфер, в который можно писать из одной горутины и читать из другой
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// screen возвращает текст, выведенный после последней очистки экрана
+func (b *syncBuffer) screen() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text := b.buf.String()
+	return text[strings.LastIndex(text, clearScreen)+1:]
+}
+
+// TestWatch тестирует повторное выполнение программы при изменении ее файла и модуля
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	program, unit := filepath.Join(dir, "prog.pas"), filepath.Join(dir, "lib.pas")
+	modified := time.Now()
+	save := func(file, code string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Время изменения растет, даже если файловая система хранит его с точностью до секунды
+		modified = modified.Add(time.Second)
+		if err := os.Chtimes(file, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out syncBuffer
+	expect := func(want string) {
+		t.Helper()
+		want = clearScreen[1:] + program + ": " + want
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if out.screen() == want {
+				return
+			}
+		}
+		t.Fatalf("ожидался экран %q, получено %q", want, out.screen())
+	}
+
+	save(unit, "UNIT Lib;\nINTERFACE\nCONST Step = 1;\nIMPLEMENTATION\nEND.")
+	save(program, "USES Lib;\nBEGIN a := Step; b := 2; WriteLn('привет') END.")
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- watch(program, runOptions{root: dir, defines: []string{"b=5"}}, &out, stop) }()
+
+	expect("запуск 1\nпривет\n{a: 1, b: 2}\n")
+	save(program, "USES Lib;\nBEGIN a := Step; b := b + 1; c := 1 END.")
+	expect("запуск 2\n{a: 1, b: " + changedStyle + "6" + resetStyle + ", c: " + changedStyle + "1" + resetStyle + "}\n")
+	save(unit, "UNIT Lib;\nINTERFACE\nCONST Step = 10;\nIMPLEMENTATION\nEND.")
+	expect("запуск 3\n{a: " + changedStyle + "10" + resetStyle + ", b: 6, c: 1}\n")
+	save(program, "BEGIN a := END.")
+	expect("запуск 4\nошибка синтаксического анализа: неожиданный токен {2 END 11  1 12} на позиции 11\n")
+
+	// Бесконечный цикл, в том числе пустой, останавливается при следующем сохранении
+	save(program, loopForever+".")
+	expect("запуск 5\n")
+	save(program, spinForever+".")
+	expect("запуск 6\n")
+	save(program, "BEGIN a := 1 DIV (a - a) END.")
+	expect("запуск 7\nошибка выполнения: строка 1, столбец 14: деление на ноль (EDivByZero)\n")
+	save(program, "BEGIN a := 10; Halt(3) END.")
+	expect("запуск 8\n{a: 10, b: " + changedStyle + "5" + resetStyle + ", " + removedStyle + "c: 1" + resetStyle + "}\nкод выхода 3\n")
+
+	// Остановка режима прерывает выполняющийся пустой цикл
+	save(program, spinForever+".")
+	expect("запуск 9\n")
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("неожиданная ошибка: %v", err)
+	}
+	if err := watch(program, runOptions{defines: []string{"b"}}, &out, stop); err == nil {
+		t.Error("ожидалась ошибка значения -D")
+	}
+}
+
+// TestWatchBrokenUnit тестирует, что режим следит за модулями, загрузка которых не удалась:
+// исправление модуля и появление недостающего модуля запускают программу заново
+func TestWatchBrokenUnit(t *testing.T) {
+	dir := t.TempDir()
+	program, unit, extra := filepath.Join(dir, "prog.pas"), filepath.Join(dir, "lib.pas"), filepath.Join(dir, "extra.pas")
+	modified := time.Now()
+	save := func(file, code string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modified = modified.Add(time.Second)
+		if err := os.Chtimes(file, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out syncBuffer
+	expect := func(want string) {
+		t.Helper()
+		want = clearScreen[1:] + program + ": " + want
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if strings.HasPrefix(out.screen(), want) {
+				return
+			}
+		}
+		t.Fatalf("ожидался экран, начинающийся с %q, получено %q", want, out.screen())
+	}
+
+	save(unit, "UNIT Lib;\nINTERFACE\nCONST Step = ;\nIMPLEMENTATION\nEND.")
+	save(program, "USES Lib;\nBEGIN a := Step END.")
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- watch(program, runOptions{root: dir}, &out, stop) }()
+
+	expect("запуск 1\nошибка загрузки модуля: " + unit + ": ")
+	save(unit, "UNIT Lib;\nINTERFACE\nCONST Step = 1;\nIMPLEMENTATION\nEND.")
+	expect("запуск 2\n{a: 1}\n")
+
+	// Новый модуль еще не создан, затем создан с ошибкой и исправлен
+	save(program, "USES Lib, Extra;\nBEGIN a := Step + More END.")
+	expect("запуск 3\nошибка загрузки модуля: строка 1, столбец 11: модуль Extra не найден")
+	save(extra, "UNIT Extra;\nINTERFACE\nCONST More = ;\nIMPLEMENTATION\nEND.")
+	expect("запуск 4\nошибка загрузки модуля: " + extra + ": ")
+	save(extra, "UNIT Extra;\nINTERFACE\nCONST More = 2;\nIMPLEMENTATION\nEND.")
+	expect("запуск 5\n{a: " + changedStyle + "3" + resetStyle + "}\n")
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("неожиданная ошибка: %v", err)
+	}
+}